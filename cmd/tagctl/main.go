@@ -0,0 +1,438 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/config"
+	"github.com/iamvkosarev/audio-tag-editor/pkg/tagedit"
+	"github.com/joho/godotenv"
+)
+
+const usage = `tagctl edits audio tags without the HTTP server.
+
+Usage:
+  tagctl show [--json] <file|dir>...
+  tagctl set [tag flags] <file|dir>...
+  tagctl cover --image <path|url> <file|dir>...
+
+Directories are searched recursively for supported audio files.
+Run "tagctl <command> -h" for the flags of a command.
+`
+
+var errFailed = errors.New("some files failed")
+
+func main() {
+	godotenv.Load()
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+	flacPadding := cfg.Audio.FLACPadding
+	if flacPadding == 0 {
+		flacPadding = -1 // FLAC_PADDING=0 reserves nothing, unlike an unset option
+	}
+	editor, err := tagedit.New(
+		tagedit.Options{
+			ID3Version:             cfg.Audio.ID3Version,
+			ID3v1:                  tagedit.ID3v1Mode(cfg.Audio.ID3v1),
+			ExactMP3Duration:       cfg.Audio.MP3ExactDuration,
+			FLACPadding:            flacPadding,
+			FLACID3:                tagedit.FLACID3Mode(cfg.Audio.FLACID3),
+			LegacyEncodings:        cfg.Audio.TextEncodings,
+			CoverFetchTimeout:      cfg.Audio.CoverFetchTimeout,
+			CoverMaxSize:           cfg.Audio.CoverMaxSize,
+			CoverAllowPrivateHosts: cfg.Audio.CoverAllowPrivateHosts,
+			CustomTagsAllow:        cfg.Audio.CustomTagsAllow,
+			CustomTagsDeny:         cfg.Audio.CustomTagsDeny,
+		},
+	)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid config: %v\n", err)
+		os.Exit(1)
+	}
+
+	command, args := os.Args[1], os.Args[2:]
+	switch command {
+	case "show":
+		err = runShow(editor, args)
+	case "set":
+		err = runSet(editor, args)
+	case "cover":
+		err = runCover(editor, args)
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", command, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		if !errors.Is(err, errFailed) {
+			fmt.Fprintf(os.Stderr, "tagctl %s: %v\n", command, err)
+		}
+		os.Exit(1)
+	}
+}
+
+func runShow(editor *tagedit.Editor, args []string) error {
+	flags := flag.NewFlagSet("show", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print the metadata as JSON")
+	flags.Parse(args)
+
+	files, err := collectFiles(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	var all []*tagedit.Metadata
+	failed := false
+	for i, path := range files {
+		metadata, err := editor.Open(context.Background(), path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			failed = true
+			continue
+		}
+		if *asJSON {
+			metadata.ID = path
+			all = append(all, metadata)
+			continue
+		}
+		if i > 0 {
+			fmt.Println()
+		}
+		printMetadata(path, metadata)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(all); err != nil {
+			return fmt.Errorf("failed to encode metadata: %w", err)
+		}
+	}
+	if failed {
+		return errFailed
+	}
+	return nil
+}
+
+func printMetadata(path string, metadata *tagedit.Metadata) {
+	fmt.Println(path)
+	field := func(name string, value any) {
+		switch v := value.(type) {
+		case string:
+			if v == "" {
+				return
+			}
+		case int:
+			if v == 0 {
+				return
+			}
+		case bool:
+			if !v {
+				return
+			}
+		}
+		fmt.Printf("  %-15s %v\n", name+":", value)
+	}
+
+	field("format", metadata.Format)
+	field("duration", fmt.Sprintf("%.2fs", metadata.Duration))
+	field("codec", metadata.Codec)
+	if metadata.Bitrate > 0 {
+		field("bitrate", fmt.Sprintf("%d kbps", metadata.Bitrate))
+	}
+	if metadata.SampleRate > 0 {
+		field("sample rate", fmt.Sprintf("%d Hz", metadata.SampleRate))
+	}
+	field("channels", metadata.Channels)
+	field("bit depth", metadata.BitsPerSample)
+	field("title", metadata.Title)
+	field("artist", metadata.Artist)
+	field("album", metadata.Album)
+	field("album artist", metadata.AlbumArtist)
+	field("composer", metadata.Composer)
+	field("title sort", metadata.TitleSort)
+	field("artist sort", metadata.ArtistSort)
+	field("album artist sort", metadata.AlbumArtistSort)
+	field("album sort", metadata.AlbumSort)
+	field("year", metadata.Year)
+	if metadata.ReleaseDate != strconv.Itoa(metadata.Year) {
+		field("release date", metadata.ReleaseDate)
+	}
+	field("genre", metadata.Genre)
+	field("track", metadata.Track)
+	field("total tracks", metadata.TotalTracks)
+	field("disc", metadata.Disc)
+	field("total discs", metadata.TotalDiscs)
+	field("bpm", metadata.BPM)
+	field("compilation", metadata.Compilation)
+	field("comment", metadata.Comment)
+	field("isrc", metadata.ISRC)
+	field("barcode", metadata.Barcode)
+	field("catalog number", metadata.CatalogNumber)
+	field("label", metadata.Label)
+	field("rating", metadata.Rating)
+	field("play count", metadata.PlayCount)
+	if metadata.Lyrics != "" {
+		field("lyrics", fmt.Sprintf("%d lines", strings.Count(metadata.Lyrics, "\n")+1))
+	}
+	if metadata.SyncedLyrics != "" {
+		field("synced lyrics", fmt.Sprintf("%d lines", strings.Count(metadata.SyncedLyrics, "\n")+1))
+	}
+	if info := metadata.CoverArtInfo; info != nil {
+		field("cover", fmt.Sprintf("%s, %dx%d", info.MimeType, info.Width, info.Height))
+	}
+	if len(metadata.Pictures) > 1 {
+		pictures := make([]string, len(metadata.Pictures))
+		for i, picture := range metadata.Pictures {
+			pictures[i] = fmt.Sprintf("%s %dx%d", strings.ToLower(picture.TypeName), picture.Width, picture.Height)
+		}
+		field("pictures", strings.Join(pictures, ", "))
+	}
+
+	keys := make([]string, 0, len(metadata.CustomTags))
+	for key := range metadata.CustomTags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Printf("  %s = %s\n", key, metadata.CustomTags[key])
+	}
+}
+
+type customTagFlag map[string]string
+
+func (f customTagFlag) String() string {
+	return ""
+}
+
+func (f customTagFlag) Set(value string) error {
+	key, tagValue, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("expected KEY=VALUE, got %q", value)
+	}
+	f[key] = tagValue
+	return nil
+}
+
+func runSet(editor *tagedit.Editor, args []string) error {
+	flags := flag.NewFlagSet("set", flag.ExitOnError)
+	title := flags.String("title", "", "title")
+	artist := flags.String("artist", "", "artist")
+	album := flags.String("album", "", "album")
+	albumArtist := flags.String("album-artist", "", "album artist")
+	composer := flags.String("composer", "", "composer")
+	titleSort := flags.String("title-sort", "", "title for sorting")
+	artistSort := flags.String("artist-sort", "", "artist for sorting, e.g. \"Beatles, The\"")
+	albumArtistSort := flags.String("album-artist-sort", "", "album artist for sorting")
+	albumSort := flags.String("album-sort", "", "album for sorting")
+	comment := flags.String("comment", "", "comment")
+	genre := flags.String("genre", "", "genre")
+	year := flags.Int("year", 0, "year")
+	releaseDate := flags.String("date", "", "release date as YYYY-MM-DD, YYYY-MM or YYYY")
+	track := flags.Int("track", 0, "track number")
+	totalTracks := flags.Int("total-tracks", 0, "number of tracks")
+	disc := flags.Int("disc", 0, "disc number")
+	totalDiscs := flags.Int("total-discs", 0, "number of discs")
+	bpm := flags.Int("bpm", 0, "beats per minute")
+	compilation := flags.Bool("compilation", false, "part of a compilation")
+	lyrics := flags.String("lyrics", "", "unsynchronized lyrics")
+	isrc := flags.String("isrc", "", "ISRC of the recording")
+	barcode := flags.String("barcode", "", "UPC or EAN of the release")
+	catalogNumber := flags.String("catalog-number", "", "catalog number of the release")
+	label := flags.String("label", "", "record label")
+	rating := flags.Int("rating", 0, "rating from 0 to 100, 20 per star")
+	playCount := flags.Int("play-count", 0, "number of plays")
+	customTags := customTagFlag{}
+	flags.Var(customTags, "tag", "custom tag as KEY=VALUE, repeatable; an empty value removes the tag")
+	merge := flags.String(
+		"merge", "preserve",
+		"preserve keeps omitted tags, clear removes them and overwrite-if-empty also only fills tags without a value",
+	)
+	flags.Parse(args)
+
+	var update tagedit.Changes
+	changed := 0
+	flags.Visit(
+		func(f *flag.Flag) {
+			changed++
+			switch f.Name {
+			case "title":
+				update.Title = title
+			case "artist":
+				update.Artist = artist
+			case "album":
+				update.Album = album
+			case "album-artist":
+				update.AlbumArtist = albumArtist
+			case "composer":
+				update.Composer = composer
+			case "title-sort":
+				update.TitleSort = titleSort
+			case "artist-sort":
+				update.ArtistSort = artistSort
+			case "album-artist-sort":
+				update.AlbumArtistSort = albumArtistSort
+			case "album-sort":
+				update.AlbumSort = albumSort
+			case "comment":
+				update.Comment = comment
+			case "genre":
+				update.Genre = genre
+			case "year":
+				update.Year = year
+			case "date":
+				update.ReleaseDate = releaseDate
+			case "track":
+				update.Track = track
+			case "total-tracks":
+				update.TotalTracks = totalTracks
+			case "disc":
+				update.Disc = disc
+			case "total-discs":
+				update.TotalDiscs = totalDiscs
+			case "bpm":
+				update.BPM = bpm
+			case "compilation":
+				update.Compilation = compilation
+			case "lyrics":
+				update.Lyrics = lyrics
+			case "isrc":
+				update.ISRC = isrc
+			case "barcode":
+				update.Barcode = barcode
+			case "catalog-number":
+				update.CatalogNumber = catalogNumber
+			case "label":
+				update.Label = label
+			case "rating":
+				update.Rating = rating
+			case "play-count":
+				update.PlayCount = playCount
+			case "tag":
+				update.CustomTags = customTags
+			case "merge":
+				update.MergePolicy = tagedit.MergePolicy(*merge)
+			}
+		},
+	)
+	if changed == 0 {
+		return fmt.Errorf("no tags to set")
+	}
+
+	return updateFiles(editor, flags.Args(), update)
+}
+
+func runCover(editor *tagedit.Editor, args []string) error {
+	flags := flag.NewFlagSet("cover", flag.ExitOnError)
+	image := flags.String("image", "", "image file or http(s) URL to embed as the front cover")
+	flags.Parse(args)
+
+	if *image == "" {
+		return fmt.Errorf("--image is required")
+	}
+
+	coverArt := *image
+	if !strings.HasPrefix(coverArt, "http://") && !strings.HasPrefix(coverArt, "https://") {
+		data, err := os.ReadFile(coverArt)
+		if err != nil {
+			return fmt.Errorf("failed to read image: %w", err)
+		}
+		mimeType := http.DetectContentType(data)
+		if !strings.HasPrefix(mimeType, "image/") {
+			return fmt.Errorf("%s is not an image (%s)", coverArt, mimeType)
+		}
+		coverArt = "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data)
+	}
+
+	resolved, err := editor.ResolveImage(context.Background(), coverArt)
+	if err != nil {
+		return fmt.Errorf("failed to load image: %w", err)
+	}
+
+	return updateFiles(editor, flags.Args(), tagedit.Changes{CoverArt: &resolved})
+}
+
+func updateFiles(editor *tagedit.Editor, paths []string, changes tagedit.Changes) error {
+	files, err := collectFiles(paths)
+	if err != nil {
+		return err
+	}
+
+	failed := false
+	for _, path := range files {
+		if err := editor.Write(context.Background(), path, changes); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			failed = true
+			continue
+		}
+		fmt.Println("updated", path)
+	}
+
+	if failed {
+		return errFailed
+	}
+	return nil
+}
+
+func collectFiles(paths []string) ([]string, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no files given")
+	}
+
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat %s: %w", path, err)
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		var found []string
+		err = filepath.WalkDir(
+			path, func(path string, entry fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if !entry.IsDir() && tagedit.Supported(path) {
+					found = append(found, path)
+				}
+				return nil
+			},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk %s: %w", path, err)
+		}
+		sort.Strings(found)
+		files = append(files, found...)
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no audio files found")
+	}
+	return files, nil
+}
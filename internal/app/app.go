@@ -4,7 +4,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"github.com/iamvkosarev/audio-tag-editor/internal/auth"
+	"github.com/iamvkosarev/audio-tag-editor/internal/service/acoustid"
 	"github.com/iamvkosarev/audio-tag-editor/internal/service/audio"
+	"github.com/iamvkosarev/audio-tag-editor/internal/service/coversearch"
+	"github.com/iamvkosarev/audio-tag-editor/internal/service/genres"
+	"github.com/iamvkosarev/audio-tag-editor/internal/service/integrity"
+	"github.com/iamvkosarev/audio-tag-editor/internal/service/musicbrainz"
+	"github.com/iamvkosarev/audio-tag-editor/internal/service/replaygain"
+	"github.com/iamvkosarev/audio-tag-editor/internal/service/urlimport"
+	"github.com/iamvkosarev/audio-tag-editor/internal/service/waveform"
 	"github.com/iamvkosarev/audio-tag-editor/pkg/logs"
 	"log/slog"
 	"net/http"
@@ -15,33 +24,246 @@ import (
 
 	"github.com/iamvkosarev/audio-tag-editor/internal/config"
 	"github.com/iamvkosarev/audio-tag-editor/internal/handler"
+	"github.com/iamvkosarev/audio-tag-editor/internal/jobs"
+	"github.com/iamvkosarev/audio-tag-editor/internal/library"
+	"github.com/iamvkosarev/audio-tag-editor/internal/naming"
+	"github.com/iamvkosarev/audio-tag-editor/internal/progress"
 	"github.com/iamvkosarev/audio-tag-editor/internal/server"
+	"github.com/iamvkosarev/audio-tag-editor/internal/storage"
+	"github.com/iamvkosarev/audio-tag-editor/internal/tracing"
+	"github.com/iamvkosarev/audio-tag-editor/internal/vfs"
+	"github.com/iamvkosarev/audio-tag-editor/internal/web"
 )
 
 type App struct {
-	server *server.Server
-	config *config.Config
+	server          *server.Server
+	handler         *handler.Handler
+	jobs            *jobs.Queue
+	watcher         *library.Watcher
+	shutdownTracing func(context.Context) error
+	config          *config.Config
 }
 
 func New(cfg *config.Config) (*App, error) {
-	audioService := audio.NewAudioService()
-
-	h := handler.New(audioService)
-
-	srv := server.New(cfg, h)
-
 	log, err := logs.NewSlogLogger(cfg.App.LogMode, os.Stdout)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize slog: %w", err)
 	}
 	slog.SetDefault(log)
 
+	shutdownTracing, err := tracing.Setup(
+		context.Background(), tracing.Options{
+			Endpoint:    cfg.Tracing.Endpoint,
+			Insecure:    cfg.Tracing.Insecure,
+			ServiceName: cfg.Tracing.ServiceName,
+			SampleRatio: cfg.Tracing.SampleRatio,
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize tracing: %w", err)
+	}
+
+	id3Options, err := audio.NewID3Options(cfg.Audio.ID3Version, cfg.Audio.ID3v1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize audio service: %w", err)
+	}
+	flacID3, err := audio.ParseFLACID3Mode(cfg.Audio.FLACID3)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize audio service: %w", err)
+	}
+	tagProfile, err := audio.ParseTagProfile(cfg.Audio.TagProfile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize audio service: %w", err)
+	}
+	textEncodings, err := audio.ParseTextEncodings(cfg.Audio.TextEncodings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize audio service: %w", err)
+	}
+	localFS := vfs.OS{}
+	audioService := audio.NewAudioService(
+		audio.Options{
+			CoverFetchTimeout:      cfg.Audio.CoverFetchTimeout,
+			CoverMaxSize:           cfg.Audio.CoverMaxSize,
+			CoverAllowPrivateHosts: cfg.Audio.CoverAllowPrivateHosts,
+			CoverMaxDimension:      cfg.Audio.CoverMaxDimension,
+			CoverMaxBytes:          cfg.Audio.CoverMaxBytes,
+			CoverQuality:           cfg.Audio.CoverQuality,
+			CustomTagsAllow:        cfg.Audio.CustomTagsAllow,
+			CustomTagsDeny:         cfg.Audio.CustomTagsDeny,
+			ID3:                    id3Options,
+			MP3ExactDuration:       cfg.Audio.MP3ExactDuration,
+			FLACPadding:            cfg.Audio.FLACPadding,
+			FLACID3:                flacID3,
+			TagProfile:             tagProfile,
+			TextEncodings:          textEncodings,
+			ParseCacheSize:         cfg.Audio.ParseCacheSize,
+			PreserveOwnership:      cfg.Audio.PreserveOwnership,
+			FS:                     localFS,
+		},
+	)
+
+	fileStorage, err := newStorage(&cfg.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+
+	uploadSessions, err := storage.NewUploadSessions(cfg.Upload.Dir, cfg.Upload.SessionTTL, cfg.Upload.MaxSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize upload sessions: %w", err)
+	}
+
+	history, err := storage.NewHistory(cfg.History.Dir, cfg.History.Limit, cfg.History.Retention)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize history: %w", err)
+	}
+
+	var originals handler.Originals
+	if cfg.Storage.KeepOriginals {
+		originals, err = storage.NewOriginals(cfg.Storage.OriginalsDir, cfg.Storage.Retention)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize originals: %w", err)
+		}
+	}
+
+	var musicLibrary handler.Library
+	var libraryWatcher *library.Watcher
+	if cfg.Library.MusicDir != "" {
+		lib, err := library.New(cfg.Library.MusicDir, audioService)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize library: %w", err)
+		}
+		go func() {
+			if err := lib.Scan(context.Background()); err != nil {
+				logs.Error("App.New: Failed to scan library", err)
+			}
+		}()
+		musicLibrary = lib
+		if cfg.Library.Watch {
+			libraryWatcher = library.NewWatcher(lib, cfg.Library.WatchDebounce)
+		}
+	}
+
+	musicBrainzClient := musicbrainz.NewClient(
+		cfg.MusicBrainz.URL, cfg.MusicBrainz.UserAgent, cfg.MusicBrainz.Timeout, cfg.MusicBrainz.ResultsLimit,
+	)
+
+	var identifyService handler.IdentifyService
+	if cfg.AcoustID.APIKey != "" {
+		identifyService = acoustid.NewClient(
+			cfg.AcoustID.URL, cfg.AcoustID.APIKey, cfg.AcoustID.Timeout,
+			acoustid.NewFingerprinter(cfg.AcoustID.FpcalcPath),
+		)
+	}
+
+	var genreService handler.GenreService
+	if cfg.Genres.LastFMAPIKey != "" || cfg.Genres.MapFile != "" {
+		var lastFM *genres.LastFM
+		if cfg.Genres.LastFMAPIKey != "" {
+			lastFM = genres.NewLastFM(cfg.Genres.LastFMURL, cfg.Genres.LastFMAPIKey, cfg.Genres.LastFMTimeout)
+		}
+		genreService, err = genres.NewSuggester(lastFM, cfg.Genres.MapFile, cfg.Genres.Limit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize genre suggestions: %w", err)
+		}
+	}
+
+	coverSearch := coversearch.NewSearcher(
+		musicBrainzClient, cfg.CoverSearch.CoverArtArchiveURL, cfg.CoverSearch.ITunesURL, cfg.CoverSearch.Timeout,
+		cfg.CoverSearch.Limit,
+	)
+
+	replayGainAnalyzer := replaygain.NewAnalyzer(cfg.ReplayGain.FfmpegPath)
+	verifier := integrity.NewVerifier(cfg.ReplayGain.FfmpegPath)
+	waveformGenerator := waveform.NewGenerator(cfg.ReplayGain.FfmpegPath)
+
+	filenameTemplate, err := naming.Parse(cfg.App.FilenameTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	progressHub := progress.NewHub(cfg.App.ProgressRetention)
+	jobQueue := jobs.NewQueue(cfg.App.JobWorkers, cfg.App.JobQueueSize, cfg.App.JobRetention, progressHub)
+
+	assets := web.Dist()
+	if cfg.App.StaticDir != "" {
+		assets = os.DirFS(cfg.App.StaticDir)
+	}
+
+	var urlFetcher handler.URLFetcher
+	if cfg.Upload.URLImport {
+		urlFetcher = urlimport.NewFetcher(cfg.Upload.URLTimeout, cfg.Upload.MaxSize, cfg.Upload.URLAllowPrivateHosts)
+	}
+
+	h := handler.New(
+		audioService, fileStorage, musicBrainzClient, identifyService, genreService, coverSearch, replayGainAnalyzer, verifier,
+		waveformGenerator, uploadSessions, progressHub, jobQueue, musicLibrary, filenameTemplate, history, originals,
+		urlFetcher,
+		handler.Options{
+			ParseWorkers:     cfg.Upload.ParseWorkers,
+			FileRetention:    cfg.Storage.Retention,
+			CleanupInterval:  cfg.Storage.CleanupInterval,
+			MaxStoredBytes:   cfg.Storage.MaxBytes,
+			MaxStoredFiles:   cfg.Storage.MaxFiles,
+			MaxUploadSize:    cfg.Upload.MaxRequest,
+			MaxFileSize:      cfg.Upload.MaxSize,
+			MultipartMemory:  cfg.Upload.MemoryLimit,
+			Assets:           assets,
+			SubsonicUser:     cfg.Subsonic.User,
+			SubsonicPassword: cfg.Subsonic.Password,
+			FS:               localFS,
+		},
+	)
+
+	authenticator, err := auth.New(
+		context.Background(), auth.Options{
+			APIKeys:        cfg.Auth.APIKeys,
+			AdminKeys:      cfg.Auth.AdminKeys,
+			OIDCIssuer:     cfg.Auth.OIDCIssuer,
+			OIDCAudience:   cfg.Auth.OIDCAudience,
+			OIDCAdminGroup: cfg.Auth.OIDCAdminGroup,
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize auth: %w", err)
+	}
+
+	srv := server.New(cfg, h, authenticator)
+
 	return &App{
-		server: srv,
-		config: cfg,
+		server:          srv,
+		handler:         h,
+		jobs:            jobQueue,
+		watcher:         libraryWatcher,
+		shutdownTracing: shutdownTracing,
+		config:          cfg,
 	}, nil
 }
 
+func newStorage(cfg *config.StorageConfig) (handler.Storage, error) {
+	switch cfg.Backend {
+	case "memory":
+		return storage.NewMemoryStorage(), nil
+	case "disk":
+		return storage.NewDiskStorage(cfg.Dir)
+	case "s3":
+		return storage.NewS3Storage(
+			storage.S3Options{
+				Endpoint:  cfg.S3.Endpoint,
+				Region:    cfg.S3.Region,
+				Bucket:    cfg.S3.Bucket,
+				AccessKey: cfg.S3.AccessKey,
+				SecretKey: cfg.S3.SecretKey,
+				Prefix:    cfg.S3.Prefix,
+				UseSSL:    cfg.S3.UseSSL,
+				PathStyle: cfg.S3.PathStyle,
+				CacheDir:  cfg.Dir,
+			},
+		)
+	default:
+		return nil, fmt.Errorf("unknown storage backend: %s", cfg.Backend)
+	}
+}
+
 func (a *App) Run() error {
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -55,6 +277,20 @@ func (a *App) Run() error {
 		}
 	}()
 
+	cleanupDone := make(chan struct{})
+	go func() {
+		defer close(cleanupDone)
+		a.handler.RunCleanup(ctx)
+	}()
+
+	if a.watcher != nil {
+		go func() {
+			if err := a.watcher.Run(ctx); err != nil {
+				logs.Error("App.Run: Library watcher stopped", err)
+			}
+		}()
+	}
+
 	slog.Info("start app")
 
 	quit := make(chan os.Signal, 1)
@@ -79,6 +315,22 @@ func (a *App) Run() error {
 			joinedErr = errors.Join(joinedErr, err)
 		}
 		slog.Info("stop server")
+
+		if err := a.jobs.Shutdown(shutdownCtx); err != nil {
+			joinedErr = errors.Join(joinedErr, err)
+		}
+		slog.Info("stop jobs")
+
+		select {
+		case <-cleanupDone:
+			slog.Info("stop cleanup")
+		case <-shutdownCtx.Done():
+		}
+
+		if err := a.shutdownTracing(shutdownCtx); err != nil {
+			joinedErr = errors.Join(joinedErr, fmt.Errorf("failed to flush traces: %w", err))
+		}
+		slog.Info("stop tracing")
 	}()
 
 	go func() {
@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+)
+
+// CookieName is the cookie the web UI keeps its API key in.
+const CookieName = "api_key"
+
+type Options struct {
+	APIKeys        []string
+	AdminKeys      []string
+	OIDCIssuer     string // tokens of this issuer are accepted when set
+	OIDCAudience   string // client ID tokens must be issued for
+	OIDCAdminGroup string // members of this group, from the groups claim, are admins
+}
+
+// Authenticator checks API keys and OpenID Connect ID tokens.
+type Authenticator struct {
+	keys       map[[sha256.Size]byte]*model.Principal
+	verifier   *oidc.IDTokenVerifier
+	adminGroup string
+}
+
+// New returns an authenticator, or nil when the API stays open.
+func New(ctx context.Context, opts Options) (*Authenticator, error) {
+	if len(opts.APIKeys) == 0 && len(opts.AdminKeys) == 0 && opts.OIDCIssuer == "" {
+		return nil, nil
+	}
+
+	a := &Authenticator{
+		keys:       make(map[[sha256.Size]byte]*model.Principal),
+		adminGroup: opts.OIDCAdminGroup,
+	}
+	for _, key := range opts.APIKeys {
+		a.addKey(key, false)
+	}
+	for _, key := range opts.AdminKeys {
+		a.addKey(key, true)
+	}
+
+	if opts.OIDCIssuer != "" {
+		provider, err := oidc.NewProvider(ctx, opts.OIDCIssuer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to discover OIDC issuer: %w", err)
+		}
+		a.verifier = provider.Verifier(
+			&oidc.Config{ClientID: opts.OIDCAudience, SkipClientIDCheck: opts.OIDCAudience == ""},
+		)
+	}
+
+	return a, nil
+}
+
+func (a *Authenticator) addKey(key string, admin bool) {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return
+	}
+	hash := sha256.Sum256([]byte(key))
+	a.keys[hash] = &model.Principal{Name: "key-" + hex.EncodeToString(hash[:4]), Admin: admin}
+}
+
+// Authenticate returns who sent r.
+func (a *Authenticator) Authenticate(r *http.Request) (*model.Principal, error) {
+	credential := credentialOf(r)
+	if credential == "" {
+		return nil, model.ErrUnauthenticated
+	}
+
+	if principal, ok := a.keys[sha256.Sum256([]byte(credential))]; ok {
+		return principal, nil
+	}
+
+	if a.verifier != nil && strings.Count(credential, ".") == 2 {
+		token, err := a.verifier.Verify(r.Context(), credential)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", model.ErrInvalidCredentials, err)
+		}
+		var claims struct {
+			Groups []string `json:"groups"`
+		}
+		if err := token.Claims(&claims); err != nil {
+			return nil, fmt.Errorf("%w: failed to read token claims: %w", model.ErrInvalidCredentials, err)
+		}
+		return &model.Principal{
+			Name:  "oidc:" + token.Subject,
+			Admin: a.adminGroup != "" && slices.Contains(claims.Groups, a.adminGroup),
+		}, nil
+	}
+
+	return nil, model.ErrInvalidCredentials
+}
+
+func credentialOf(r *http.Request) string {
+	if scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	if cookie, err := r.Cookie(CookieName); err == nil {
+		if key, err := url.QueryUnescape(cookie.Value); err == nil {
+			return key
+		}
+	}
+	return ""
+}
@@ -1,27 +1,185 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"github.com/ilyakaznacheev/cleanenv"
+	"slices"
 	"time"
 )
 
 type App struct {
-	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" env-default:"10s"`
-	LogMode         string        `env:"LOG_MODE" env-default:"debug"` // debug, dev or prod
+	ShutdownTimeout   time.Duration `env:"SHUTDOWN_TIMEOUT" env-default:"10s"`
+	LogMode           string        `env:"LOG_MODE" env-default:"debug"`                                                           // debug, dev or prod
+	FilenameTemplate  string        `env:"FILENAME_TEMPLATE" env-default:"[{artist} - ][{album} - ][[{disc}-]{track:02} ]{title}"` // names of downloaded and renamed files
+	ProgressRetention time.Duration `env:"PROGRESS_RETENTION" env-default:"10m"`                                                   // how long progress of idle jobs is kept
+	JobWorkers        int           `env:"JOB_WORKERS" env-default:"2"`                                                            // background jobs running at once
+	JobQueueSize      int           `env:"JOB_QUEUE_SIZE" env-default:"64"`                                                        // jobs waiting for a worker before new ones are rejected
+	JobRetention      time.Duration `env:"JOB_RETENTION" env-default:"1h"`                                                         // how long finished jobs and their results are kept
+	StaticDir         string        `env:"STATIC_DIR"`                                                                             // frontend bundle served at /; the one embedded in the binary when empty
 }
 
 type ServerConfig struct {
-	Host         string        `env:"SERVER_HOST" env-default:"0.0.0.0"`
-	Port         string        `env:"HTTP_PORT" env-default:"8080"`
-	IdleTimeout  time.Duration `env:"SERVER_IDLE_TIMEOUT" env-default:"60s""`
-	ReadTimeout  time.Duration `env:"HTTP_READ_TIMEOUT" env-default:"15s"`
-	WriteTimeout time.Duration `env:"HTTP_WRITE_TIMEOUT" env-default:"15s"`
+	Host             string        `env:"SERVER_HOST" env-default:"0.0.0.0"`
+	Port             string        `env:"HTTP_PORT" env-default:"8080"`
+	IdleTimeout      time.Duration `env:"SERVER_IDLE_TIMEOUT" env-default:"60s"`
+	ReadTimeout      time.Duration `env:"HTTP_READ_TIMEOUT" env-default:"15s"`
+	WriteTimeout     time.Duration `env:"HTTP_WRITE_TIMEOUT" env-default:"15s"`
+	RateLimit        int           `env:"RATE_LIMIT"`                         // requests per minute per client IP to /api/; unlimited when empty
+	RateBurst        int           `env:"RATE_BURST"`                         // requests allowed at once per client IP; RATE_LIMIT when empty
+	TrustProxy       bool          `env:"TRUST_PROXY" env-default:"false"`    // take the client IP from X-Forwarded-For
+	MaxHeavyRequests int           `env:"MAX_HEAVY_REQUESTS" env-default:"8"` // uploads and downloads served at once; unlimited when 0
+}
+
+type S3Config struct {
+	Endpoint  string `env:"S3_ENDPOINT"`
+	Region    string `env:"S3_REGION" env-default:"us-east-1"`
+	Bucket    string `env:"S3_BUCKET"`
+	AccessKey string `env:"S3_ACCESS_KEY"`
+	SecretKey string `env:"S3_SECRET_KEY"`
+	Prefix    string `env:"S3_PREFIX" env-default:"uploads/"`
+	UseSSL    bool   `env:"S3_USE_SSL" env-default:"true"`
+	PathStyle bool   `env:"S3_PATH_STYLE" env-default:"true"` // MinIO and most self-hosted servers need path-style URLs
+}
+
+type StorageConfig struct {
+	Backend         string        `env:"STORAGE_BACKEND" env-default:"memory"`               // memory, disk or s3
+	Dir             string        `env:"STORAGE_DIR" env-default:"data/uploads"`             // files for disk, local cache for s3
+	Retention       time.Duration `env:"STORAGE_RETENTION" env-default:"24h"`                // how long uploaded files are kept
+	CleanupInterval time.Duration `env:"STORAGE_CLEANUP_INTERVAL" env-default:"1h"`          // how often expired files, uploads and history are deleted
+	MaxBytes        int64         `env:"STORAGE_MAX_BYTES"`                                  // total size of kept files; the oldest are evicted above it, unlimited when empty
+	MaxFiles        int           `env:"STORAGE_MAX_FILES"`                                  // files kept at once; the oldest are evicted above it, unlimited when empty
+	KeepOriginals   bool          `env:"STORAGE_KEEP_ORIGINALS" env-default:"false"`         // keep an untouched copy of every upload
+	OriginalsDir    string        `env:"STORAGE_ORIGINALS_DIR" env-default:"data/originals"` // where the untouched copies are kept
+	S3              S3Config
+}
+
+type UploadConfig struct {
+	Dir          string        `env:"UPLOAD_DIR" env-default:"data/partial-uploads"` // chunks of resumable uploads in progress
+	SessionTTL   time.Duration `env:"UPLOAD_SESSION_TTL" env-default:"24h"`
+	MaxSize      int64         `env:"MAX_FILE_SIZE,UPLOAD_MAX_SIZE" env-default:"4294967296"` // bytes per file
+	MaxRequest   int64         `env:"MAX_UPLOAD_SIZE" env-default:"1073741824"`               // bytes per request to POST /api/upload
+	MemoryLimit  int64         `env:"UPLOAD_MEMORY_LIMIT" env-default:"104857600"`            // bytes of an upload kept in memory before spilling to disk
+	ParseWorkers int           `env:"MAX_PARSE_WORKERS"`                                      // files of one upload parsed at once; the number of CPUs when empty
+	// Imports with POST /api/upload-from-url
+	URLImport            bool          `env:"URL_IMPORT" env-default:"true"`
+	URLTimeout           time.Duration `env:"URL_IMPORT_TIMEOUT" env-default:"10m"`               // for the whole download
+	URLAllowPrivateHosts bool          `env:"URL_IMPORT_ALLOW_PRIVATE_HOSTS" env-default:"false"` // allow links to hosts on local networks
+}
+
+type HistoryConfig struct {
+	Dir       string        `env:"HISTORY_DIR" env-default:"data/history"`
+	Limit     int           `env:"HISTORY_LIMIT" env-default:"20"`       // revisions kept per file
+	Retention time.Duration `env:"HISTORY_RETENTION" env-default:"720h"` // since the last edit of a file
+}
+
+type LibraryConfig struct {
+	MusicDir      string        `env:"MUSIC_DIR"`                                    // edit files in this directory in place; library mode is off when empty
+	Watch         bool          `env:"MUSIC_DIR_WATCH" env-default:"true"`           // pick up changes made to the directory by other programs
+	WatchDebounce time.Duration `env:"MUSIC_DIR_WATCH_DEBOUNCE" env-default:"500ms"` // quiet period before changed files are reparsed
+}
+
+// SubsonicConfig enables the Subsonic API at /rest/ in library mode.
+type SubsonicConfig struct {
+	User     string `env:"SUBSONIC_USER" env-default:"admin"`
+	Password string `env:"SUBSONIC_PASSWORD"` // the Subsonic API is off when empty
+}
+
+type MusicBrainzConfig struct {
+	URL          string        `env:"MUSICBRAINZ_URL" env-default:"https://musicbrainz.org/ws/2"`
+	UserAgent    string        `env:"MUSICBRAINZ_USER_AGENT" env-default:"audio-tag-editor/1.0 ( https://github.com/iamvkosarev/audio-tag-editor )"`
+	Timeout      time.Duration `env:"MUSICBRAINZ_TIMEOUT" env-default:"10s"`
+	ResultsLimit int           `env:"MUSICBRAINZ_RESULTS_LIMIT" env-default:"10"`
+}
+
+type AcoustIDConfig struct {
+	URL        string        `env:"ACOUSTID_URL" env-default:"https://api.acoustid.org/v2"`
+	APIKey     string        `env:"ACOUSTID_API_KEY"` // identification is disabled without a key
+	FpcalcPath string        `env:"FPCALC_PATH" env-default:"fpcalc"`
+	Timeout    time.Duration `env:"ACOUSTID_TIMEOUT" env-default:"30s"`
+}
+
+type GenresConfig struct {
+	LastFMURL     string        `env:"LASTFM_URL" env-default:"https://ws.audioscrobbler.com/2.0"`
+	LastFMAPIKey  string        `env:"LASTFM_API_KEY"` // Last.fm genre suggestions are off without a key
+	LastFMTimeout time.Duration `env:"LASTFM_TIMEOUT" env-default:"10s"`
+	MapFile       string        `env:"GENRE_MAP_FILE"`                          // JSON object of artist names to genres, suggested without going online
+	Limit         int           `env:"GENRE_SUGGESTIONS_LIMIT" env-default:"5"` // genres suggested per lookup
+}
+
+type CoverSearchConfig struct {
+	CoverArtArchiveURL string        `env:"COVERARTARCHIVE_URL" env-default:"https://coverartarchive.org"`
+	ITunesURL          string        `env:"ITUNES_SEARCH_URL" env-default:"https://itunes.apple.com"`
+	Timeout            time.Duration `env:"COVER_SEARCH_TIMEOUT" env-default:"10s"`
+	Limit              int           `env:"COVER_SEARCH_LIMIT" env-default:"8"` // covers per source
+}
+
+type AudioConfig struct {
+	CoverFetchTimeout      time.Duration `env:"COVER_FETCH_TIMEOUT" env-default:"15s"`
+	CoverMaxSize           int64         `env:"COVER_MAX_SIZE" env-default:"10485760"`         // bytes
+	CoverAllowPrivateHosts bool          `env:"COVER_ALLOW_PRIVATE_HOSTS" env-default:"false"` // allow cover URLs on local networks
+	CoverMaxDimension      int           `env:"COVER_EMBED_MAX_DIMENSION" env-default:"1600"`  // pixels; larger covers are scaled down before embedding, 0 keeps them
+	CoverMaxBytes          int64         `env:"COVER_EMBED_MAX_BYTES" env-default:"1048576"`   // covers above this size are re-encoded as JPEG before embedding, 0 keeps them
+	CoverQuality           int           `env:"COVER_JPEG_QUALITY" env-default:"90"`           // quality of re-encoded covers, 1 to 100
+	CustomTagsAllow        []string      `env:"CUSTOM_TAGS_ALLOW" env-separator:","`           // custom tag names clients may write, "PREFIX_*" allowed; empty allows all
+	CustomTagsDeny         []string      `env:"CUSTOM_TAGS_DENY" env-separator:","`
+	ID3Version             int           `env:"ID3_VERSION"`                             // ID3v2 version of written MP3 tags, 3 or 4; the file's own version when empty
+	ID3v1                  string        `env:"ID3V1_MODE" env-default:"keep"`           // keep, strip or sync trailing ID3v1 tags
+	MP3ExactDuration       bool          `env:"MP3_EXACT_DURATION" env-default:"false"`  // count every frame of VBR files without a Xing header
+	FLACPadding            int           `env:"FLAC_PADDING" env-default:"8192"`         // bytes reserved after the metadata of rewritten FLAC files
+	FLACID3                string        `env:"FLAC_ID3_MODE" env-default:"keep"`        // keep, strip or sync ID3v2 tags in front of FLAC streams
+	TagProfile             string        `env:"TAG_PROFILE" env-default:"picard"`        // picard, beets or foobar2000: whose Vorbis comment names to write
+	TextEncodings          []string      `env:"LEGACY_TEXT_ENCODINGS" env-separator:","` // code pages tried on mojibake in MP3 and WAV tags, such as windows-1251 or gbk
+	ParseCacheSize         int           `env:"PARSE_CACHE_SIZE" env-default:"256"`      // parsed files kept in memory; 0 turns the cache off
+	PreserveOwnership      bool          `env:"PRESERVE_OWNERSHIP" env-default:"false"`  // keep the owner and setuid, setgid and sticky bits of rewritten files; files whose owner cannot be kept are refused
+}
+
+type ReplayGainConfig struct {
+	FfmpegPath string `env:"FFMPEG_PATH" env-default:"ffmpeg"` // used to decode audio for loudness analysis, waveforms and FLAC verification
+}
+
+type AuthConfig struct {
+	APIKeys        []string `env:"AUTH_API_KEYS" env-separator:","`   // keys that may use the API; the API is open when neither keys nor an issuer are set
+	AdminKeys      []string `env:"AUTH_ADMIN_KEYS" env-separator:","` // keys that may also use /api/admin/
+	OIDCIssuer     string   `env:"AUTH_OIDC_ISSUER"`                  // accept ID tokens of this OpenID Connect issuer as Bearer tokens
+	OIDCAudience   string   `env:"AUTH_OIDC_AUDIENCE"`                // client ID the tokens must be issued for; any when empty
+	OIDCAdminGroup string   `env:"AUTH_OIDC_ADMIN_GROUP"`             // token holders in this group are admins
+	KeyRateLimit   int      `env:"AUTH_RATE_LIMIT"`                   // requests per minute per key or token subject; unlimited when empty
+	KeyRateBurst   int      `env:"AUTH_RATE_BURST"`                   // requests allowed at once; AUTH_RATE_LIMIT when empty
+}
+
+type CORSConfig struct {
+	AllowedOrigins   []string      `env:"CORS_ALLOWED_ORIGINS" env-separator:","`                                                                                         // origins that may call the API, or "*" for any; cross-origin calls are refused when empty
+	AllowedMethods   []string      `env:"CORS_ALLOWED_METHODS" env-separator:"," env-default:"GET,POST,PUT,PATCH,DELETE"`                                                 // methods allowed in preflight requests
+	AllowedHeaders   []string      `env:"CORS_ALLOWED_HEADERS" env-separator:"," env-default:"Authorization,Content-Type,X-API-Key,Upload-Offset,If-None-Match,If-Match"` // request headers allowed in preflight requests
+	AllowCredentials bool          `env:"CORS_ALLOW_CREDENTIALS" env-default:"false"`                                                                                     // let browsers send cookies and HTTP auth along; not allowed with "*"
+	MaxAge           time.Duration `env:"CORS_MAX_AGE" env-default:"10m"`                                                                                                 // how long browsers may cache a preflight answer
+}
+
+type TracingConfig struct {
+	Endpoint    string  `env:"TRACING_ENDPOINT"` // host:port of an OTLP/HTTP collector; tracing is off when empty
+	Insecure    bool    `env:"TRACING_INSECURE" env-default:"false"`
+	ServiceName string  `env:"TRACING_SERVICE_NAME" env-default:"audio-tag-editor"`
+	SampleRatio float64 `env:"TRACING_SAMPLE_RATIO" env-default:"1"` // share of requests traced, between 0 and 1
 }
 
 type Config struct {
-	Server ServerConfig
-	App    App
+	Server      ServerConfig
+	App         App
+	Storage     StorageConfig
+	Upload      UploadConfig
+	Library     LibraryConfig
+	Subsonic    SubsonicConfig
+	History     HistoryConfig
+	MusicBrainz MusicBrainzConfig
+	AcoustID    AcoustIDConfig
+	Genres      GenresConfig
+	CoverSearch CoverSearchConfig
+	Audio       AudioConfig
+	ReplayGain  ReplayGainConfig
+	Auth        AuthConfig
+	CORS        CORSConfig
+	Tracing     TracingConfig
 }
 
 func Load() (*Config, error) {
@@ -29,9 +187,19 @@ func Load() (*Config, error) {
 	if err := cleanenv.ReadEnv(&cfg); err != nil {
 		return nil, err
 	}
+	if err := cfg.CORS.validate(); err != nil {
+		return nil, err
+	}
 	return &cfg, nil
 }
 
+func (c *CORSConfig) validate() error {
+	if c.AllowCredentials && slices.Contains(c.AllowedOrigins, "*") {
+		return errors.New("CORS_ALLOW_CREDENTIALS needs the origins listed in CORS_ALLOWED_ORIGINS, not \"*\"")
+	}
+	return nil
+}
+
 func (c *ServerConfig) Address() string {
 	return fmt.Sprintf("%s:%s", c.Host, c.Port)
 }
@@ -0,0 +1,24 @@
+package config
+
+import "testing"
+
+func TestCORSConfigValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		origins     []string
+		credentials bool
+		wantErr     bool
+	}{
+		{name: "any origin", origins: []string{"*"}},
+		{name: "listed origins with credentials", origins: []string{"https://tags.example.com"}, credentials: true},
+		{name: "any origin with credentials", origins: []string{"https://tags.example.com", "*"}, credentials: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := CORSConfig{AllowedOrigins: tt.origins, AllowCredentials: tt.credentials}
+			if err := cfg.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
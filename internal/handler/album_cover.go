@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/pkg/logs"
+)
+
+type AlbumCoverRequest struct {
+	FileID   string `json:"fileId"`   // any file of the album
+	CoverArt string `json:"coverArt"` // data URI or http(s) URL; the cover of the file when empty
+}
+
+// AlbumCover writes one cover to every file of the album of the given file.
+func (h *Handler) AlbumCover(w http.ResponseWriter, r *http.Request) {
+	var req AlbumCoverRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "Invalid request body")
+		return
+	}
+	if req.FileID == "" {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "File ID required")
+		return
+	}
+
+	source, err := h.getFile(req.FileID)
+	if err != nil {
+		writeFileError(w, req.FileID, err)
+		return
+	}
+	if source.Metadata == nil || source.Metadata.Album == "" {
+		writeError(w, http.StatusUnprocessableEntity, model.ErrorCodeInvalidRequest, "File has no album")
+		return
+	}
+
+	coverArt := req.CoverArt
+	if coverArt == "" {
+		coverArt = source.Metadata.CoverArt
+	}
+	if coverArt == "" {
+		writeFileError(w, req.FileID, model.ErrNoCoverArt)
+		return
+	}
+	coverArt, err = h.audioService.ResolveCoverArt(r.Context(), coverArt)
+	if err != nil {
+		writeAPIError(
+			w, errorStatus(err), &model.APIError{Code: errorCode(err), Message: err.Error(), Field: "coverArt"},
+		)
+		return
+	}
+
+	fileIDs, err := h.albumFileIDs(source.Metadata.AlbumArtist, source.Metadata.Album)
+	if err != nil {
+		logs.ErrorContext(r.Context(), "Handler.AlbumCover: Failed to list files", err)
+		writeError(w, http.StatusInternalServerError, model.ErrorCodeInternal, "Failed to list files")
+		return
+	}
+
+	setCover := func(context.Context, *model.StoredFile) (*model.TagUpdate, error) {
+		return &model.TagUpdate{CoverArt: &coverArt}, nil
+	}
+
+	if isAsync(r) {
+		h.submitJob(
+			w, r, "album-cover", len(fileIDs), func(ctx context.Context, step func(int, string)) (any, error) {
+				return h.rewriteTags(ctx, "AlbumCover", fileIDs, step, setCover), nil
+			},
+		)
+		return
+	}
+
+	progress := h.progressFor(r, len(fileIDs))
+	result := h.rewriteTags(r.Context(), "AlbumCover", fileIDs, progress.step, setCover)
+	progress.finish()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		logs.ErrorContext(r.Context(), "Handler.AlbumCover: Failed to encode response", err)
+	}
+}
+
+func (h *Handler) albumFileIDs(albumArtist, album string) ([]string, error) {
+	storedFiles, err := h.storage.List()
+	if err != nil {
+		return nil, err
+	}
+	sortByUpload(storedFiles)
+
+	var fileIDs []string
+	for _, stored := range storedFiles {
+		if stored.Metadata != nil && stored.Metadata.AlbumArtist == albumArtist && stored.Metadata.Album == album {
+			fileIDs = append(fileIDs, stored.ID)
+		}
+	}
+	return fileIDs, nil
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/pkg/logs"
+)
+
+type albumsResult struct {
+	Albums    []model.Album `json:"albums"`
+	Ungrouped []string      `json:"ungrouped"` // IDs of files without an album
+}
+
+// Albums groups the stored files into albums and checks them.
+func (h *Handler) Albums(w http.ResponseWriter, r *http.Request) {
+	storedFiles, err := h.storage.List()
+	if err != nil {
+		logs.ErrorContext(r.Context(), "Handler.Albums: Failed to list files", err)
+		writeError(w, http.StatusInternalServerError, model.ErrorCodeInternal, "Failed to list files")
+		return
+	}
+	sortByUpload(storedFiles)
+
+	files := make([]model.FileMetadata, 0, len(storedFiles))
+	for _, stored := range storedFiles {
+		if stored.Metadata == nil {
+			continue
+		}
+		metadata := *stored.Metadata
+		metadata.ID = stored.ID
+		files = append(files, metadata)
+	}
+
+	var result albumsResult
+	result.Albums, result.Ungrouped = model.GroupAlbums(files)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		logs.ErrorContext(r.Context(), "Handler.Albums: Failed to encode response", err)
+	}
+}
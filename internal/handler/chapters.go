@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/pkg/logs"
+)
+
+type ChaptersResponse struct {
+	FileID   string          `json:"fileId"`
+	Chapters []model.Chapter `json:"chapters"`
+}
+
+type ChaptersRequest struct {
+	Chapters []model.Chapter `json:"chapters"` // replaces every chapter; empty removes them
+}
+
+// Chapters lists the chapters of a file in order of their start time.
+func (h *Handler) Chapters(w http.ResponseWriter, r *http.Request) {
+	fileID := r.PathValue("fileId")
+	stored, err := h.getFile(fileID)
+	if err != nil {
+		writeFileError(w, fileID, err)
+		return
+	}
+
+	response := ChaptersResponse{FileID: fileID, Chapters: []model.Chapter{}}
+	if stored.Metadata != nil && len(stored.Metadata.Chapters) > 0 {
+		response.Chapters = stored.Metadata.Chapters
+	}
+	writeChapters(w, r, response)
+}
+
+// SetChapters replaces the chapters of a file.
+func (h *Handler) SetChapters(w http.ResponseWriter, r *http.Request) {
+	fileID := r.PathValue("fileId")
+	stored, err := h.getFile(fileID)
+	if err != nil {
+		writeFileError(w, fileID, err)
+		return
+	}
+
+	var req ChaptersRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "Invalid request body")
+		return
+	}
+
+	chapters := append([]model.Chapter{}, req.Chapters...)
+	if stored.Metadata != nil && stored.Metadata.Duration > 0 {
+		end := int64(math.Round(stored.Metadata.Duration * 1000))
+		last := -1
+		for i, chapter := range chapters {
+			if last < 0 || chapter.Start >= chapters[last].Start {
+				last = i
+			}
+		}
+		if last >= 0 && chapters[last].End == 0 && chapters[last].Start < end {
+			chapters[last].End = end
+		}
+	}
+
+	update := model.TagUpdate{Chapters: &chapters}
+	if _, err := h.writeTags(r.Context(), stored, &update); err != nil {
+		logs.ErrorContext(r.Context(), "Handler.SetChapters: Failed to write tags", err)
+		apiErr := fileError(fileID, err)
+		apiErr.Message = fmt.Sprintf("Failed to write chapters: %v", err)
+		writeAPIError(w, http.StatusUnprocessableEntity, &apiErr)
+		return
+	}
+
+	metadata, err := h.audioService.ParseFile(r.Context(), stored.Path)
+	if err != nil {
+		logs.ErrorContext(r.Context(), "Handler.SetChapters: Failed to re-parse file", err)
+		writeError(w, http.StatusInternalServerError, model.ErrorCodeInternal, "Failed to re-parse file")
+		return
+	}
+	metadata.ID = fileID
+	if err := h.saveFile(fileID, metadata); err != nil {
+		logs.ErrorContext(r.Context(), "Handler.SetChapters: Failed to save file", err)
+	}
+
+	response := ChaptersResponse{FileID: fileID, Chapters: []model.Chapter{}}
+	if len(metadata.Chapters) > 0 {
+		response.Chapters = metadata.Chapters
+	}
+	writeChapters(w, r, response)
+}
+
+func writeChapters(w http.ResponseWriter, r *http.Request, response ChaptersResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logs.ErrorContext(r.Context(), "Handler.Chapters: Failed to encode response", err)
+	}
+}
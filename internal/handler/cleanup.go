@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/vfs"
+	"github.com/iamvkosarev/audio-tag-editor/pkg/logs"
+)
+
+var tempFilePrefixes = []string{"audio-", "download-", "flac-"}
+
+// RunCleanup deletes expired files, uploads and history until ctx is done.
+func (h *Handler) RunCleanup(ctx context.Context) {
+	removed, err := sweepTempFiles(h.fs, h.fs.TempDir(), h.startedAt)
+	if err != nil {
+		logs.Error("Handler.RunCleanup: Failed to sweep temp files", err)
+	}
+	if removed > 0 {
+		slog.Info("Handler.RunCleanup: Deleted orphaned temp files", slog.Int("count", removed))
+	}
+
+	ticker := time.NewTicker(h.cleanupInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.deleteExpired()
+		}
+	}
+}
+
+// Cleanup deletes expired files, uploads, history and originals right away.
+func (h *Handler) Cleanup(w http.ResponseWriter, r *http.Request) {
+	h.deleteExpired()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) deleteExpired() {
+	removed, err := h.storage.DeleteExpired()
+	if err != nil {
+		logs.Error("Handler.deleteExpired: Failed to delete expired files", err)
+	}
+	if removed > 0 {
+		slog.Info("Handler.deleteExpired: Deleted expired files", slog.Int("count", removed))
+	}
+
+	removed, err = h.uploadSessions.DeleteExpired()
+	if err != nil {
+		logs.Error("Handler.deleteExpired: Failed to delete expired uploads", err)
+	}
+	if removed > 0 {
+		slog.Info("Handler.deleteExpired: Deleted expired uploads", slog.Int("count", removed))
+	}
+
+	removed, err = h.history.DeleteExpired()
+	if err != nil {
+		logs.Error("Handler.deleteExpired: Failed to delete expired history", err)
+	}
+	if removed > 0 {
+		slog.Info("Handler.deleteExpired: Deleted expired history", slog.Int("count", removed))
+	}
+
+	if h.originals != nil {
+		removed, err = h.originals.DeleteExpired()
+		if err != nil {
+			logs.Error("Handler.deleteExpired: Failed to delete expired originals", err)
+		}
+		if removed > 0 {
+			slog.Info("Handler.deleteExpired: Deleted expired originals", slog.Int("count", removed))
+		}
+	}
+}
+
+func sweepTempFiles(fsys vfs.FS, dir string, before time.Time) (int, error) {
+	entries, err := fsys.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !hasTempFilePrefix(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Before(before) {
+			continue
+		}
+		if err := fsys.Remove(filepath.Join(dir, entry.Name())); err != nil {
+			logs.Error("Handler.sweepTempFiles: Failed to remove temp file", err)
+			continue
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+func hasTempFilePrefix(name string) bool {
+	for _, prefix := range tempFilePrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/imaging"
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/pkg/logs"
+)
+
+// Cover serves the embedded cover of a file as an image.
+func (h *Handler) Cover(w http.ResponseWriter, r *http.Request) {
+	opts, err := coverOptions(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	fileID := r.PathValue("fileId")
+	stored, err := h.getFile(fileID)
+	if err != nil {
+		writeFileError(w, fileID, err)
+		return
+	}
+
+	var data []byte
+	if value := r.URL.Query().Get("type"); value != "" {
+		pictureType, convErr := strconv.Atoi(value)
+		if convErr != nil || pictureType < 0 || pictureType > model.PictureTypeMax {
+			writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, fmt.Sprintf("type must be between 0 and %d", model.PictureTypeMax))
+			return
+		}
+		data, _, err = h.audioService.ExtractPicture(r.Context(), stored.Path, pictureType)
+	} else {
+		data, _, err = h.audioService.ExtractCoverArt(r.Context(), stored.Path)
+	}
+	if errors.Is(err, model.ErrNoCoverArt) {
+		writeError(w, http.StatusNotFound, model.ErrorCodeNotFound, "File has no such picture")
+		return
+	}
+	if err != nil {
+		logs.ErrorContext(r.Context(), "Handler.Cover: Failed to extract cover art", err)
+		writeError(w, http.StatusInternalServerError, model.ErrorCodeInternal, "Failed to read cover art")
+		return
+	}
+
+	rendered, mimeType, err := imaging.Render(data, opts)
+	if errors.Is(err, imaging.ErrTooLarge) {
+		writeError(w, http.StatusRequestEntityTooLarge, model.ErrorCodeTooLarge, err.Error())
+		return
+	}
+	if errors.Is(err, imaging.ErrUnsupportedFormat) {
+		writeError(w, http.StatusUnprocessableEntity, model.ErrorCodeInvalidCoverArt, err.Error())
+		return
+	}
+	if err != nil {
+		logs.ErrorContext(r.Context(), "Handler.Cover: Failed to render cover art", err)
+		writeError(w, http.StatusUnprocessableEntity, model.ErrorCodeInvalidCoverArt, "Failed to render cover art")
+		return
+	}
+
+	sum := sha1.Sum(data)
+	w.Header().Set("Content-Type", mimeType)
+	w.Header().Set("ETag", fmt.Sprintf(`"%s-%d-%s-%d"`, hex.EncodeToString(sum[:8]), opts.Size, opts.Format, opts.Quality))
+	w.Header().Set("Cache-Control", "private, no-cache")
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(rendered))
+}
+
+func coverOptions(r *http.Request) (imaging.Options, error) {
+	var opts imaging.Options
+	query := r.URL.Query()
+
+	if value := query.Get("size"); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil || size < 1 || size > imaging.MaxSize {
+			return opts, fmt.Errorf("size must be between 1 and %d", imaging.MaxSize)
+		}
+		opts.Size = size
+	}
+	if value := query.Get("quality"); value != "" {
+		quality, err := strconv.Atoi(value)
+		if err != nil || quality < 1 || quality > 100 {
+			return opts, fmt.Errorf("quality must be between 1 and 100")
+		}
+		opts.Quality = quality
+	}
+	format, err := imaging.ParseFormat(query.Get("format"))
+	if err != nil {
+		return opts, err
+	}
+	opts.Format = format
+	return opts, nil
+}
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/pkg/logs"
+)
+
+// FixEncoding rewrites the mojibake text of the given files.
+func (h *Handler) FixEncoding(w http.ResponseWriter, r *http.Request) {
+	var req fileIDsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "Invalid request body")
+		return
+	}
+	if len(req.FileIds) == 0 {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "No file IDs provided")
+		return
+	}
+
+	repairs := func(ctx context.Context, stored *model.StoredFile) (*model.TagUpdate, error) {
+		return h.audioService.EncodingRepairs(ctx, stored.Path)
+	}
+
+	if isAsync(r) {
+		h.submitJob(
+			w, r, "fix-encoding", len(req.FileIds), func(ctx context.Context, step func(int, string)) (any, error) {
+				return h.rewriteTags(ctx, "FixEncoding", req.FileIds, step, repairs), nil
+			},
+		)
+		return
+	}
+
+	progress := h.progressFor(r, len(req.FileIds))
+	result := h.rewriteTags(r.Context(), "FixEncoding", req.FileIds, progress.step, repairs)
+	progress.finish()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		logs.ErrorContext(r.Context(), "Handler.FixEncoding: Failed to encode response", err)
+	}
+}
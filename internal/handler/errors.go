@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+)
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeAPIError(w, status, &model.APIError{Code: code, Message: message})
+}
+
+func writeAPIError(w http.ResponseWriter, status int, apiErr *model.APIError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(apiErr)
+}
+
+func writeFileError(w http.ResponseWriter, fileID string, err error) {
+	apiErr := fileError(fileID, err)
+	writeAPIError(w, errorStatus(err), &apiErr)
+}
+
+func fileError(fileID string, err error) model.APIError {
+	apiErr := model.APIError{Code: errorCode(err), Message: err.Error(), FileID: fileID}
+	var fieldErr *model.FieldError
+	if errors.As(err, &fieldErr) {
+		apiErr.Field = fieldErr.Field
+		apiErr.Message = fieldErr.Err.Error()
+	}
+	switch apiErr.Code {
+	case model.ErrorCodeFileNotFound:
+		apiErr.Message = "File not found"
+	case model.ErrorCodeFileExpired:
+		apiErr.Message = "File expired"
+	}
+	return apiErr
+}
+
+func fileErrors(fileID string, err error) []model.APIError {
+	var validationErr *model.ValidationError
+	if !errors.As(err, &validationErr) {
+		return []model.APIError{fileError(fileID, err)}
+	}
+	apiErrs := make([]model.APIError, len(validationErr.Fields))
+	for i, fieldErr := range validationErr.Fields {
+		apiErrs[i] = fileError(fileID, fieldErr)
+	}
+	return apiErrs
+}
+
+func errorCode(err error) string {
+	switch {
+	case errors.Is(err, model.ErrFileExpired):
+		return model.ErrorCodeFileExpired
+	case errors.Is(err, model.ErrFileNotFound):
+		return model.ErrorCodeFileNotFound
+	case errors.Is(err, model.ErrUnsupportedFormat):
+		return model.ErrorCodeUnsupportedFormat
+	case errors.Is(err, model.ErrInvalidCoverArt):
+		return model.ErrorCodeInvalidCoverArt
+	case errors.Is(err, model.ErrInvalidTag):
+		return model.ErrorCodeInvalidTag
+	case errors.Is(err, model.ErrNoCoverArt):
+		return model.ErrorCodeNoCoverArt
+	case errors.Is(err, model.ErrFileExists):
+		return model.ErrorCodeConflict
+	case errors.Is(err, model.ErrPatternMismatch):
+		return model.ErrorCodePatternMismatch
+	case errors.Is(err, model.ErrBatchAborted):
+		return model.ErrorCodeBatchAborted
+	case errors.Is(err, model.ErrETagMismatch):
+		return model.ErrorCodePrecondition
+	case errors.Is(err, model.ErrReadOnly):
+		return model.ErrorCodeReadOnly
+	default:
+		return model.ErrorCodeInternal
+	}
+}
+
+func errorStatus(err error) int {
+	switch errorCode(err) {
+	case model.ErrorCodeFileExpired:
+		return http.StatusGone
+	case model.ErrorCodeFileNotFound, model.ErrorCodeNoCoverArt:
+		return http.StatusNotFound
+	case model.ErrorCodeUnsupportedFormat:
+		return http.StatusUnsupportedMediaType
+	case model.ErrorCodeInvalidCoverArt, model.ErrorCodeInvalidTag, model.ErrorCodePatternMismatch:
+		return http.StatusUnprocessableEntity
+	case model.ErrorCodeConflict, model.ErrorCodeBatchAborted:
+		return http.StatusConflict
+	case model.ErrorCodePrecondition:
+		return http.StatusPreconditionFailed
+	case model.ErrorCodeReadOnly:
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/pkg/logs"
+)
+
+type CopyFieldsRequest struct {
+	FileIds    []string               `json:"fileIds"`
+	Operations []model.FieldOperation `json:"operations"` // applied in order
+}
+
+// CopyFields copies, moves or swaps text fields within each of the given files.
+func (h *Handler) CopyFields(w http.ResponseWriter, r *http.Request) {
+	var req CopyFieldsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "Invalid request body")
+		return
+	}
+	if len(req.FileIds) == 0 {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "No file IDs provided")
+		return
+	}
+	if len(req.Operations) == 0 {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "No operations provided")
+		return
+	}
+	for i, operation := range req.Operations {
+		if err := operation.Validate(); err != nil {
+			writeAPIError(
+				w, http.StatusBadRequest, &model.APIError{
+					Code: model.ErrorCodeInvalidRequest, Message: err.Error(), Field: fmt.Sprintf("operations[%d]", i),
+				},
+			)
+			return
+		}
+	}
+
+	copyFields := func(ctx context.Context, stored *model.StoredFile) (*model.TagUpdate, error) {
+		current, err := h.currentTags(ctx, stored)
+		if err != nil {
+			return nil, err
+		}
+		changed := *current
+		for _, operation := range req.Operations {
+			operation.Apply(&changed)
+		}
+		return changed.TextChanges(current), nil
+	}
+
+	if isAsync(r) {
+		h.submitJob(
+			w, r, "copy-fields", len(req.FileIds), func(ctx context.Context, step func(int, string)) (any, error) {
+				return h.rewriteTags(ctx, "CopyFields", req.FileIds, step, copyFields), nil
+			},
+		)
+		return
+	}
+
+	progress := h.progressFor(r, len(req.FileIds))
+	result := h.rewriteTags(r.Context(), "CopyFields", req.FileIds, progress.step, copyFields)
+	progress.finish()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		logs.ErrorContext(r.Context(), "Handler.CopyFields: Failed to encode response", err)
+	}
+}
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/internal/naming"
+	"github.com/iamvkosarev/audio-tag-editor/pkg/logs"
+)
+
+type TagsFromFilenameRequest struct {
+	FileIds []string `json:"fileIds"`
+	Pattern string   `json:"pattern"` // e.g. "%artist% - %album% - %track% %title%"
+	DryRun  bool     `json:"dryRun"`  // preview the parsed values without writing them
+}
+
+// TagsFromFilename fills tags from the original file names of the given files.
+func (h *Handler) TagsFromFilename(w http.ResponseWriter, r *http.Request) {
+	var req TagsFromFilenameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "Invalid request body")
+		return
+	}
+	if len(req.FileIds) == 0 {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "No file IDs provided")
+		return
+	}
+	pattern, err := naming.ParsePattern(req.Pattern)
+	if err != nil {
+		writeAPIError(
+			w, http.StatusBadRequest, &model.APIError{Code: model.ErrorCodeInvalidRequest, Message: err.Error(), Field: "pattern"},
+		)
+		return
+	}
+
+	match := func(stored *model.StoredFile) (map[string]string, error) {
+		filename := filepath.Base(stored.Filename)
+		values, ok := pattern.Match(filename)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", model.ErrPatternMismatch, filename)
+		}
+		return values, nil
+	}
+
+	if req.DryRun {
+		preview := h.previewChanges(
+			r.Context(), "TagsFromFilename", req.FileIds,
+			func(ctx context.Context, stored *model.StoredFile) (map[string]fieldChange, error) {
+				values, err := match(stored)
+				if err != nil {
+					return nil, err
+				}
+				current, err := h.currentTags(ctx, stored)
+				if err != nil {
+					return nil, err
+				}
+				fields := make(map[string]fieldChange)
+				for name, value := range values {
+					if from := currentValue(current, name); from != value {
+						fields[name] = fieldChange{From: from, To: value}
+					}
+				}
+				return fields, nil
+			},
+		)
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(preview); err != nil {
+			logs.ErrorContext(r.Context(), "Handler.TagsFromFilename: Failed to encode response", err)
+		}
+		return
+	}
+
+	build := func(ctx context.Context, stored *model.StoredFile) (*model.TagUpdate, error) {
+		values, err := match(stored)
+		if err != nil {
+			return nil, err
+		}
+		return naming.TagUpdate(values), nil
+	}
+
+	if isAsync(r) {
+		h.submitJob(
+			w, r, "tags-from-filename", len(req.FileIds), func(ctx context.Context, step func(int, string)) (any, error) {
+				return h.rewriteTags(ctx, "TagsFromFilename", req.FileIds, step, build), nil
+			},
+		)
+		return
+	}
+
+	progress := h.progressFor(r, len(req.FileIds))
+	result := h.rewriteTags(r.Context(), "TagsFromFilename", req.FileIds, progress.step, build)
+	progress.finish()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		logs.ErrorContext(r.Context(), "Handler.TagsFromFilename: Failed to encode response", err)
+	}
+}
+
+func currentValue(metadata *model.FileMetadata, name string) string {
+	number := func(n int) string {
+		if n <= 0 {
+			return ""
+		}
+		return strconv.Itoa(n)
+	}
+	switch name {
+	case "year":
+		return number(metadata.Year)
+	case "track":
+		return number(metadata.Track)
+	case "totalTracks":
+		return number(metadata.TotalTracks)
+	case "disc":
+		return number(metadata.Disc)
+	case "totalDiscs":
+		return number(metadata.TotalDiscs)
+	}
+	return metadata.TextField(name)
+}
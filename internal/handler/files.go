@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sort"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/pkg/logs"
+)
+
+// ListFiles returns the uploaded files that have not expired yet, oldest first.
+func (h *Handler) ListFiles(w http.ResponseWriter, r *http.Request) {
+	storedFiles, err := h.storage.List()
+	if err != nil {
+		logs.ErrorContext(r.Context(), "Handler.ListFiles: Failed to list files", err)
+		writeError(w, http.StatusInternalServerError, model.ErrorCodeInternal, "Failed to list files")
+		return
+	}
+
+	sortByUpload(storedFiles)
+
+	files := make([]model.FileMetadata, 0, len(storedFiles))
+	for _, stored := range storedFiles {
+		if stored.Metadata == nil {
+			continue
+		}
+		metadata := *stored.Metadata
+		metadata.ID = stored.ID
+		files = append(files, metadata)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(filesResult{Files: files}); err != nil {
+		logs.ErrorContext(r.Context(), "Handler.ListFiles: Failed to encode response", err)
+	}
+}
+
+func sortByUpload(storedFiles []*model.StoredFile) {
+	sort.Slice(
+		storedFiles, func(i, j int) bool {
+			if !storedFiles[i].CreatedAt.Equal(storedFiles[j].CreatedAt) {
+				return storedFiles[i].CreatedAt.Before(storedFiles[j].CreatedAt)
+			}
+			return storedFiles[i].ID < storedFiles[j].ID
+		},
+	)
+}
+
+// DeleteFile discards an uploaded file before it expires.
+func (h *Handler) DeleteFile(w http.ResponseWriter, r *http.Request) {
+	fileID := r.PathValue("id")
+	if err := h.storage.Delete(fileID); err != nil {
+		if errors.Is(err, model.ErrFileNotFound) {
+			writeFileError(w, fileID, err)
+			return
+		}
+		logs.ErrorContext(r.Context(), "Handler.DeleteFile: Failed to delete file", err)
+		writeError(w, http.StatusInternalServerError, model.ErrorCodeInternal, "Failed to delete file")
+		return
+	}
+	h.deleteOriginal(r.Context(), fileID)
+
+	slog.InfoContext(r.Context(), "Handler.DeleteFile: File deleted", slog.String("fileID", fileID))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type fileIDsRequest struct {
+	FileIds []string `json:"fileIds"`
+}
+
+type deleteFilesResult struct {
+	Deleted []string         `json:"deleted"`
+	Errors  []model.APIError `json:"errors,omitempty"`
+}
+
+// DeleteSelected discards several uploaded files at once.
+func (h *Handler) DeleteSelected(w http.ResponseWriter, r *http.Request) {
+	var req fileIDsRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logs.ErrorContext(r.Context(), "Handler.DeleteSelected: Failed to decode request", err)
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "Invalid request body")
+		return
+	}
+
+	if len(req.FileIds) == 0 {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "No file IDs provided")
+		return
+	}
+
+	result := deleteFilesResult{Deleted: make([]string, 0, len(req.FileIds))}
+	for _, fileID := range req.FileIds {
+		if err := h.storage.Delete(fileID); err != nil {
+			if !errors.Is(err, model.ErrFileNotFound) {
+				logs.ErrorContext(r.Context(), "Handler.DeleteSelected: Failed to delete file", err)
+			}
+			result.Errors = append(result.Errors, fileError(fileID, err))
+			continue
+		}
+		h.deleteOriginal(r.Context(), fileID)
+		result.Deleted = append(result.Deleted, fileID)
+	}
+
+	slog.InfoContext(r.Context(), "Handler.DeleteSelected: Files deleted", slog.Int("count", len(result.Deleted)))
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		logs.ErrorContext(r.Context(), "Handler.DeleteSelected: Failed to encode response", err)
+	}
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/pkg/logs"
+)
+
+type FindCoverRequest struct {
+	FileID string `json:"fileId"`
+	model.CoverQuery
+	Sources []string `json:"sources"` // coverartarchive, itunes; all when empty
+}
+
+type coverCandidatesResult struct {
+	Candidates []model.CoverCandidate `json:"candidates"`
+}
+
+// FindCover searches the Cover Art Archive and iTunes for covers of an album.
+func (h *Handler) FindCover(w http.ResponseWriter, r *http.Request) {
+	var req FindCoverRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "Invalid request body")
+		return
+	}
+
+	for _, source := range req.Sources {
+		if source != model.CoverSourceCoverArtArchive && source != model.CoverSourceITunes {
+			writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "Unknown cover source: "+source)
+			return
+		}
+	}
+
+	query := req.CoverQuery
+	if req.FileID != "" {
+		stored, err := h.getFile(req.FileID)
+		if err != nil {
+			writeFileError(w, req.FileID, err)
+			return
+		}
+		if stored.Metadata != nil {
+			if query.Artist == "" {
+				query.Artist = stored.Metadata.AlbumArtist
+			}
+			if query.Artist == "" {
+				query.Artist = stored.Metadata.Artist
+			}
+			if query.Album == "" {
+				query.Album = stored.Metadata.Album
+			}
+		}
+	}
+
+	if query.Artist == "" && query.Album == "" {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "Artist or album required")
+		return
+	}
+
+	candidates, err := h.coverSearch.Find(r.Context(), query, req.Sources)
+	if err != nil {
+		logs.ErrorContext(r.Context(), "Handler.FindCover: Cover search failed", err)
+		writeError(w, http.StatusBadGateway, model.ErrorCodeUpstream, "Cover search failed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(coverCandidatesResult{Candidates: candidates})
+}
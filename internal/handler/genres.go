@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/internal/service/genres"
+	"github.com/iamvkosarev/audio-tag-editor/pkg/logs"
+)
+
+type GenresResponse struct {
+	Genres []model.Genre `json:"genres"`
+}
+
+// Genres lists canonical genres for autocomplete.
+func (h *Handler) Genres(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit := 0
+	if value := query.Get("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit < 0 {
+			writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "Invalid limit")
+			return
+		}
+	}
+
+	response := GenresResponse{Genres: genres.List(query.Get("q"), query.Get("lang"), limit)}
+	if response.Genres == nil {
+		response.Genres = []model.Genre{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logs.ErrorContext(r.Context(), "Handler.Genres: Failed to encode response", err)
+	}
+}
+
+func normalizeGenre(fields *model.TagUpdate, stored *model.StoredFile) {
+	genre := fields.Genre
+	if genre == nil {
+		if stored.Metadata == nil {
+			return
+		}
+		genre = &stored.Metadata.Genre
+	}
+	normalized := genres.Normalize(*genre)
+	if fields.Genre != nil || normalized != *genre {
+		fields.Genre = &normalized
+	}
+}
@@ -5,46 +5,277 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"log/slog"
+	"mime/multipart"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/iamvkosarev/audio-tag-editor/internal/jobs"
 	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/internal/naming"
+	"github.com/iamvkosarev/audio-tag-editor/internal/playlist"
 	"github.com/iamvkosarev/audio-tag-editor/internal/templates"
+	"github.com/iamvkosarev/audio-tag-editor/internal/vfs"
 	"github.com/iamvkosarev/audio-tag-editor/pkg/logs"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type AudioService interface {
-	ParseFile(filePath string) (*model.FileMetadata, error)
-	UpdateTags(filePath string, title, artist, album *string, year, track *int, genre *string, coverArt *string) error
+	ParseFile(ctx context.Context, filePath string) (*model.FileMetadata, error)
+	ParseReader(ctx context.Context, r io.ReaderAt, size int64, name string) (*model.FileMetadata, error)
+	ValidateTagUpdate(update *model.TagUpdate) error
+	ReadableExtension(ext string) bool
+	UpdateTags(ctx context.Context, filePath string, update *model.TagUpdate) error
+	UpdateTagsAtomically(ctx context.Context, writes []model.TagWrite) error
+	ResolveCoverArt(ctx context.Context, coverArt string) (string, error)
+	ExtractCoverArt(ctx context.Context, filePath string) ([]byte, string, error)
+	ExtractPicture(ctx context.Context, filePath string, pictureType int) ([]byte, string, error)
+	EncodingRepairs(ctx context.Context, filePath string) (*model.TagUpdate, error)
+	Checksums(ctx context.Context, filePath string) (*model.Checksums, error)
 }
 
-type storedFile struct {
-	Path      string
-	Filename  string
-	Metadata  *model.FileMetadata
-	ExpiresAt time.Time
+// Storage keeps uploaded files between requests.
+type Storage interface {
+	Put(file *model.StoredFile, ttl time.Duration) error
+	Get(id string) (*model.StoredFile, error)
+	List() ([]*model.StoredFile, error)
+	Save(id string, metadata *model.FileMetadata) error
+	Delete(id string) error
+	DeleteExpired() (int, error)
+}
+
+type LookupService interface {
+	Lookup(ctx context.Context, query model.LookupQuery) ([]model.LookupCandidate, error)
+}
+
+type IdentifyService interface {
+	Identify(ctx context.Context, filePath string) ([]model.LookupCandidate, error)
+}
+
+type GenreService interface {
+	Suggest(ctx context.Context, query model.LookupQuery) ([]model.GenreSuggestion, error)
+}
+
+type CoverSearch interface {
+	Find(ctx context.Context, query model.CoverQuery, sources []string) ([]model.CoverCandidate, error)
+}
+
+// UploadSessions keeps chunked uploads until they are complete.
+type UploadSessions interface {
+	Create(filename string, size int64) (*model.UploadSession, error)
+	Get(id string) (*model.UploadSession, error)
+	Append(id string, offset int64, r io.Reader) (*model.UploadSession, error)
+	Finish(id string) (*model.UploadSession, string, error)
+	Delete(id string) error
+	DeleteExpired() (int, error)
+}
+
+type ProgressHub interface {
+	Publish(jobID string, event model.ProgressEvent)
+	Events(jobID string, from int) ([]model.ProgressEvent, <-chan struct{}, bool)
+}
+
+// Library indexes the music directory in library mode.
+type Library interface {
+	Root() string
+	Scan(ctx context.Context) error
+	List() []model.LibraryFile
+	Revision() int64
+	Get(id string) (*model.StoredFile, error)
+	Save(id string, metadata *model.FileMetadata) error
+	Move(id, relPath string) (*model.LibraryFile, error)
+}
+
+// History keeps the tag states of files from before their edits.
+type History interface {
+	Record(fileID string, metadata *model.FileMetadata) (*model.Revision, error)
+	List(fileID string) ([]model.Revision, error)
+	Get(fileID string, revisionID int) (*model.Revision, error)
+	DeleteExpired() (int, error)
+}
+
+// Originals keeps untouched copies of uploads, served by GET /api/download/{id}?original=true.
+type Originals interface {
+	Keep(fileID, path string) error
+	Open(fileID string) (*os.File, error)
+	Delete(fileID string) error
+	DeleteExpired() (int, error)
+}
+
+// URLFetcher downloads files that users link to instead of uploading them.
+type URLFetcher interface {
+	Fetch(ctx context.Context, rawURL string, dst io.Writer) (string, error)
+}
+
+// JobQueue runs long operations in the background for clients that pass ?async=true.
+type JobQueue interface {
+	Submit(kind string, total int, fn jobs.Func) (*model.Job, error)
+	Get(id string) (*model.Job, error)
+	Cancel(id string) (*model.Job, error)
+}
+
+type ReplayGainService interface {
+	Analyze(ctx context.Context, filePaths []string, album bool) ([]model.ReplayGain, error)
+}
+
+type WaveformService interface {
+	Peaks(ctx context.Context, filePath string, points int) ([]float64, error)
+}
+
+// Verifier decodes files to check them against the checksums recorded by their encoder.
+type Verifier interface {
+	DecodedMD5(ctx context.Context, filePath string, bitsPerSample int) (string, error)
+}
+
+const (
+	defaultFileRetention   = 24 * time.Hour
+	defaultCleanupInterval = 1 * time.Hour
+	defaultMultipartMemory = 100 << 20
+)
+
+var tracer = otel.Tracer("github.com/iamvkosarev/audio-tag-editor/internal/handler")
+
+// Options tunes how the handler processes requests.
+type Options struct {
+	ParseWorkers     int           // uploaded files parsed at once; runtime.NumCPU() when zero
+	FileRetention    time.Duration // how long uploaded files are kept; 24h when zero
+	CleanupInterval  time.Duration // how often expired files are deleted; 1h when zero
+	MaxStoredBytes   int64         // total size of uploaded files kept; unlimited when zero
+	MaxStoredFiles   int           // uploaded files kept at once; unlimited when zero
+	MaxUploadSize    int64         // bytes per upload request; unlimited when zero
+	MaxFileSize      int64         // bytes per uploaded file; unlimited when zero
+	MultipartMemory  int64         // bytes of an upload kept in memory before spilling to disk; 100 MiB when zero
+	Assets           fs.FS         // frontend bundle served at /; the built-in page when nil or without index.html
+	SubsonicUser     string        // user name of Subsonic clients
+	SubsonicPassword string        // password of Subsonic clients; the Subsonic API is off when empty
+	FS               vfs.FS        // where audio and temp files are read and written; the local disk when nil
 }
 
 type Handler struct {
-	audioService AudioService
-	files        map[string]*storedFile
-	mu           sync.RWMutex
+	audioService      AudioService
+	storage           Storage
+	lookupService     LookupService
+	identifyService   IdentifyService
+	genreService      GenreService
+	coverSearch       CoverSearch
+	replayGainService ReplayGainService
+	verifier          Verifier
+	waveformService   WaveformService
+	uploadSessions    UploadSessions
+	progress          ProgressHub
+	jobs              JobQueue
+	library           Library
+	filenameTemplate  *naming.Template
+	history           History
+	originals         Originals
+	urlFetcher        URLFetcher
+	parseWorkers      int
+	fileRetention     time.Duration
+	cleanupInterval   time.Duration
+	maxStoredBytes    int64
+	maxStoredFiles    int
+	maxUploadSize     int64
+	maxFileSize       int64
+	multipartMemory   int64
+	subsonicUser      string
+	subsonicPassword  string
+	quotaMu           sync.Mutex
+	static            *staticFiles
+	startedAt         time.Time
+	fs                vfs.FS
 }
 
-func New(audioService AudioService) *Handler {
+// New creates the HTTP handler; optional services may be nil.
+func New(
+	audioService AudioService,
+	storage Storage,
+	lookupService LookupService,
+	identifyService IdentifyService,
+	genreService GenreService,
+	coverSearch CoverSearch,
+	replayGainService ReplayGainService,
+	verifier Verifier,
+	waveformService WaveformService,
+	uploadSessions UploadSessions,
+	progress ProgressHub,
+	jobs JobQueue,
+	library Library,
+	filenameTemplate *naming.Template,
+	history History,
+	originals Originals,
+	urlFetcher URLFetcher,
+	opts Options,
+) *Handler {
+	parseWorkers := opts.ParseWorkers
+	if parseWorkers <= 0 {
+		parseWorkers = runtime.NumCPU()
+	}
+	fileRetention := opts.FileRetention
+	if fileRetention <= 0 {
+		fileRetention = defaultFileRetention
+	}
+	cleanupInterval := opts.CleanupInterval
+	if cleanupInterval <= 0 {
+		cleanupInterval = defaultCleanupInterval
+	}
+	multipartMemory := opts.MultipartMemory
+	if multipartMemory <= 0 {
+		multipartMemory = defaultMultipartMemory
+	}
+	fsys := opts.FS
+	if fsys == nil {
+		fsys = vfs.OS{}
+	}
+
 	h := &Handler{
-		audioService: audioService,
-		files:        make(map[string]*storedFile),
+		audioService:      audioService,
+		storage:           storage,
+		lookupService:     lookupService,
+		identifyService:   identifyService,
+		genreService:      genreService,
+		coverSearch:       coverSearch,
+		replayGainService: replayGainService,
+		verifier:          verifier,
+		waveformService:   waveformService,
+		uploadSessions:    uploadSessions,
+		progress:          progress,
+		jobs:              jobs,
+		library:           library,
+		filenameTemplate:  filenameTemplate,
+		history:           history,
+		originals:         originals,
+		urlFetcher:        urlFetcher,
+		parseWorkers:      parseWorkers,
+		fileRetention:     fileRetention,
+		cleanupInterval:   cleanupInterval,
+		maxStoredBytes:    opts.MaxStoredBytes,
+		maxStoredFiles:    opts.MaxStoredFiles,
+		maxUploadSize:     opts.MaxUploadSize,
+		maxFileSize:       opts.MaxFileSize,
+		multipartMemory:   multipartMemory,
+		subsonicUser:      opts.SubsonicUser,
+		subsonicPassword:  opts.SubsonicPassword,
+		static:            newStaticFiles(opts.Assets),
+		startedAt:         time.Now(),
+		fs:                fsys,
 	}
-	go h.cleanupExpiredFiles()
 	return h
 }
 
@@ -99,396 +330,920 @@ func copyWithFlush(dst io.Writer, src io.Reader, bufWriter *bufio.Writer, zipWri
 	return written, nil
 }
 
-func (h *Handler) cleanupExpiredFiles() {
-	ticker := time.NewTicker(1 * time.Hour)
-	defer ticker.Stop()
-	for range ticker.C {
-		h.mu.Lock()
-		now := time.Now()
-		for id, file := range h.files {
-			if now.After(file.ExpiresAt) {
-				os.Remove(file.Path)
-				delete(h.files, id)
-			}
-		}
-		h.mu.Unlock()
-	}
-}
-
 func (h *Handler) Index(w http.ResponseWriter, r *http.Request) {
+	if h.static != nil && !strings.HasPrefix(r.URL.Path, "/api/") {
+		h.static.ServeHTTP(w, r)
+		return
+	}
 	if r.URL.Path != "/" {
 		http.NotFound(w, r)
 		return
 	}
-	templates.Index().Render(r.Context(), w)
+	templates.Index(h.library != nil).Render(r.Context(), w)
 }
 
 func (h *Handler) Upload(w http.ResponseWriter, r *http.Request) {
-	err := r.ParseMultipartForm(100 << 20)
+	if h.maxUploadSize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, h.maxUploadSize)
+	}
+	err := r.ParseMultipartForm(h.multipartMemory)
 	if err != nil {
-		http.Error(w, "Failed to parse multipart form", http.StatusBadRequest)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(
+				w, http.StatusRequestEntityTooLarge, model.ErrorCodeTooLarge,
+				fmt.Sprintf("Upload is larger than the maximum of %d bytes per request", maxBytesErr.Limit),
+			)
+			return
+		}
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "Failed to parse multipart form")
 		return
 	}
 
 	files := r.MultipartForm.File["files"]
 	if len(files) == 0 {
-		http.Error(w, "No files provided", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "No files provided")
 		return
 	}
-
-	var fileMetadata []model.FileMetadata
-
+	paths := r.MultipartForm.Value["paths"]
 	for _, fileHeader := range files {
-		file, err := fileHeader.Open()
-		if err != nil {
-			continue
-		}
-
-		tempFile, err := os.CreateTemp("", "audio-*"+filepath.Ext(fileHeader.Filename))
-		if err != nil {
-			file.Close()
-			continue
+		if isZip(fileHeader.Filename) {
+			continue // its files are checked one by one
 		}
-
-		_, err = io.Copy(tempFile, file)
-		file.Close()
-		if err != nil {
-			tempFile.Close()
-			os.Remove(tempFile.Name())
-			continue
+		if h.maxFileSize > 0 && fileHeader.Size > h.maxFileSize {
+			writeError(
+				w, http.StatusRequestEntityTooLarge, model.ErrorCodeTooLarge,
+				fmt.Sprintf("%s is larger than the maximum of %d bytes per file", fileHeader.Filename, h.maxFileSize),
+			)
+			return
 		}
-		tempFile.Close()
+	}
+	if err := h.checkZipUploads(files); err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, model.ErrorCodeTooLarge, err.Error())
+		return
+	}
 
-		metadata, err := h.audioService.ParseFile(tempFile.Name())
-		if err == nil {
-			fileID := uuid.New().String()
-			metadata.ID = fileID
-
-			h.mu.Lock()
-			h.files[fileID] = &storedFile{
-				Path:      tempFile.Name(),
-				Filename:  fileHeader.Filename,
-				Metadata:  metadata,
-				ExpiresAt: time.Now().Add(24 * time.Hour),
+	progress := h.progressFor(r, len(files))
+	results := make([][]*model.FileMetadata, len(files))
+	failures := make([][]error, len(files))
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		done int
+	)
+	workers := make(chan struct{}, h.parseWorkers)
+	for i, fileHeader := range files {
+		workers <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-workers }()
+
+			mu.Lock()
+			progress.step(done, fileHeader.Filename)
+			mu.Unlock()
+
+			var name string
+			if i < len(paths) {
+				name = paths[i]
+			}
+			results[i], failures[i] = h.storeUploadPart(r.Context(), fileHeader, name)
+			for _, err := range failures[i] {
+				slog.WarnContext(
+					r.Context(), "Handler.Upload: Skipping file", slog.String("filename", fileHeader.Filename), slog.Any("error", err),
+				)
 			}
-			h.mu.Unlock()
 
+			mu.Lock()
+			done++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	progress.finish()
+
+	var fileMetadata []model.FileMetadata
+	var uploadErrors []model.APIError
+	for i := range files {
+		for _, err := range failures[i] {
+			uploadErrors = append(uploadErrors, fileError("", err))
+		}
+		for _, metadata := range results[i] {
 			fileMetadata = append(fileMetadata, *metadata)
-		} else {
-			os.Remove(tempFile.Name())
 		}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(
-		map[string]interface{}{
-			"files": fileMetadata,
+	json.NewEncoder(w).Encode(filesResult{Files: fileMetadata, Errors: uploadErrors})
+}
+
+func (h *Handler) storeUploadPart(
+	ctx context.Context, fileHeader *multipart.FileHeader, name string,
+) ([]*model.FileMetadata, []error) {
+	if isZip(fileHeader.Filename) {
+		return h.storeZipUpload(ctx, fileHeader)
+	}
+	if name == "" {
+		name = fileHeader.Filename
+	} else {
+		var err error
+		if name, err = relativePath(name); err != nil {
+			return nil, []error{fmt.Errorf("%s: %w", fileHeader.Filename, err)}
+		}
+		if isJunk(name) || !h.audioService.ReadableExtension(path.Ext(name)) {
+			return nil, nil
+		}
+	}
+	metadata, err := h.storeUpload(ctx, fileHeader, name)
+	if err != nil {
+		return nil, []error{fmt.Errorf("%s: %w", name, err)}
+	}
+	return []*model.FileMetadata{metadata}, nil
+}
+
+func (h *Handler) storeUpload(ctx context.Context, fileHeader *multipart.FileHeader, name string) (*model.FileMetadata, error) {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open uploaded file: %w", err)
+	}
+	defer file.Close()
+
+	metadata, err := h.audioService.ParseReader(ctx, file, fileHeader.Size, fileHeader.Filename)
+	if err != nil {
+		return nil, err
+	}
+
+	tempFile, err := h.fs.CreateTemp("", "audio-*"+filepath.Ext(fileHeader.Filename))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	_, err = io.Copy(tempFile, io.NewSectionReader(file, 0, fileHeader.Size))
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		h.fs.Remove(tempFile.Name())
+		return nil, fmt.Errorf("failed to copy uploaded file: %w", err)
+	}
+
+	if err := h.storeFile(ctx, tempFile.Name(), name, metadata); err != nil {
+		h.fs.Remove(tempFile.Name())
+		return nil, err
+	}
+	return metadata, nil
+}
+
+func (h *Handler) storeFile(ctx context.Context, filePath, name string, metadata *model.FileMetadata) error {
+	fileID := uuid.New().String()
+	metadata.ID = fileID
+
+	stored := &model.StoredFile{
+		ID:       fileID,
+		Path:     filePath,
+		Filename: path.Base(name),
+		Metadata: metadata,
+	}
+	if relative, err := relativePath(name); err == nil && path.Dir(relative) != "." {
+		stored.RelativePath = relative
+	}
+	if checksums, err := h.audioService.Checksums(ctx, filePath); err != nil {
+		slog.WarnContext(ctx, "Handler.storeFile: Failed to hash audio stream", slog.String("filename", name), slog.Any("error", err))
+	} else {
+		stored.UploadAudioSHA256 = checksums.AudioSHA256
+	}
+
+	// Copy the upload first: storage may move the file away.
+	if h.originals != nil {
+		if err := h.originals.Keep(fileID, filePath); err != nil {
+			return fmt.Errorf("failed to keep original: %w", err)
+		}
+	}
+
+	err := h.storage.Put(stored, h.fileRetention)
+	if err != nil {
+		h.deleteOriginal(ctx, fileID)
+		return err
+	}
+	h.enforceQuota(fileID)
+	return nil
+}
+
+func (h *Handler) deleteOriginal(ctx context.Context, fileID string) {
+	if h.originals == nil {
+		return
+	}
+	if err := h.originals.Delete(fileID); err != nil {
+		logs.ErrorContext(ctx, "Handler.deleteOriginal: Failed to delete original", err)
+	}
+}
+
+func (h *Handler) enforceQuota(keepID string) {
+	if h.maxStoredBytes <= 0 && h.maxStoredFiles <= 0 {
+		return
+	}
+
+	h.quotaMu.Lock()
+	defer h.quotaMu.Unlock()
+
+	files, err := h.storage.List()
+	if err != nil {
+		logs.Error("Handler.enforceQuota: Failed to list files", err)
+		return
+	}
+	sort.Slice(
+		files, func(i, j int) bool {
+			return files[i].CreatedAt.Before(files[j].CreatedAt)
 		},
 	)
+
+	count := len(files)
+	var size int64
+	for _, file := range files {
+		size += storedSize(file)
+	}
+
+	for _, file := range files {
+		overCount := h.maxStoredFiles > 0 && count > h.maxStoredFiles
+		overSize := h.maxStoredBytes > 0 && size > h.maxStoredBytes
+		if !overCount && !overSize {
+			break
+		}
+		if file.ID == keepID {
+			continue
+		}
+		if err := h.storage.Delete(file.ID); err != nil && !errors.Is(err, model.ErrFileNotFound) {
+			logs.Error("Handler.enforceQuota: Failed to evict file", err)
+			continue
+		}
+		h.deleteOriginal(context.Background(), file.ID)
+		count--
+		size -= storedSize(file)
+		slog.Info(
+			"Handler.enforceQuota: Evicted file", slog.String("fileID", file.ID), slog.String("filename", file.Filename),
+		)
+	}
+}
+
+func storedSize(file *model.StoredFile) int64 {
+	if file.Metadata == nil {
+		return 0
+	}
+	return file.Metadata.Size
 }
 
+// TagUpdateRequest applies the shared fields to every targeted file.
 type TagUpdateRequest struct {
-	FileIds  []string `json:"fileIds"`
-	Title    *string  `json:"title"`
-	Artist   *string  `json:"artist"`
-	Album    *string  `json:"album"`
-	Year     *int     `json:"year"`
-	Genre    *string  `json:"genre"`
-	Track    *int     `json:"track"`
-	CoverArt *string  `json:"coverArt"`
+	FileIds []string                   `json:"fileIds"`
+	Files   map[string]model.TagUpdate `json:"files"`
+	// NormalizeGenres writes genres with canonical names.
+	NormalizeGenres bool `json:"normalizeGenres,omitempty"`
+	// Atomic writes every file or none: if one of them fails, the others are left unchanged too.
+	Atomic bool `json:"atomic,omitempty"`
+	// IfMatch maps file IDs to the etag the client last read.
+	IfMatch map[string]string `json:"ifMatch,omitempty"`
+	model.TagUpdate
+
+	ifMatchAll []string
+}
+
+func (r *TagUpdateRequest) checkETag(stored *model.StoredFile) error {
+	var current string
+	if stored.Metadata != nil {
+		current = stored.Metadata.ETag
+	}
+	expected, ok := r.IfMatch[stored.ID]
+	if ok && strings.Trim(expected, `"`) != current {
+		return fmt.Errorf("%w: etag is now %q", model.ErrETagMismatch, current)
+	}
+	if len(r.ifMatchAll) > 0 && !slices.Contains(r.ifMatchAll, "*") && !slices.Contains(r.ifMatchAll, current) {
+		return fmt.Errorf("%w: etag is now %q", model.ErrETagMismatch, current)
+	}
+	return nil
+}
+
+func parseIfMatch(values []string) []string {
+	var etags []string
+	for _, value := range values {
+		for _, etag := range strings.Split(value, ",") {
+			etag = strings.TrimSpace(etag)
+			if etag == "" || strings.HasPrefix(etag, "W/") {
+				continue
+			}
+			etags = append(etags, strings.Trim(etag, `"`))
+		}
+	}
+	return etags
+}
+
+func (r *TagUpdateRequest) fieldsFor(fileID string) model.TagUpdate {
+	return r.TagUpdate.Merge(r.Files[fileID])
+}
+
+func (r *TagUpdateRequest) targetFileIDs() []string {
+	seen := make(map[string]bool, len(r.FileIds)+len(r.Files))
+	ids := make([]string, 0, len(r.FileIds)+len(r.Files))
+	for _, fileID := range r.FileIds {
+		if !seen[fileID] {
+			seen[fileID] = true
+			ids = append(ids, fileID)
+		}
+	}
+	extra := make([]string, 0, len(r.Files))
+	for fileID := range r.Files {
+		if !seen[fileID] {
+			seen[fileID] = true
+			extra = append(extra, fileID)
+		}
+	}
+	sort.Strings(extra)
+	return append(ids, extra...)
+}
+
+type filesResult struct {
+	Files   []model.FileMetadata `json:"files"`
+	Errors  []model.APIError     `json:"errors,omitempty"`
+	Results []fileResult         `json:"results,omitempty"`
 }
 
 func (h *Handler) UpdateTags(w http.ResponseWriter, r *http.Request) {
 	var req TagUpdateRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "Invalid request body")
 		return
 	}
 
-	if len(req.FileIds) == 0 {
-		http.Error(w, "No file IDs provided", http.StatusBadRequest)
+	fileIDs := req.targetFileIDs()
+	if len(fileIDs) == 0 {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "No file IDs provided")
+		return
+	}
+	if values := r.Header.Values("If-Match"); len(values) > 0 {
+		req.ifMatchAll = parseIfMatch(values)
+		if len(req.ifMatchAll) == 0 {
+			writeError(w, http.StatusPreconditionFailed, model.ErrorCodePrecondition, "If-Match has no strong etag")
+			return
+		}
+	}
+
+	if isAsync(r) {
+		h.submitJob(
+			w, r, "update-tags", len(fileIDs), func(ctx context.Context, step func(int, string)) (any, error) {
+				return h.updateTags(ctx, &req, fileIDs, step), nil
+			},
+		)
 		return
 	}
 
-	var updatedFiles []model.FileMetadata
-	var errors []string
+	progress := h.progressFor(r, len(fileIDs))
+	result := h.updateTags(r.Context(), &req, fileIDs, progress.step)
+	progress.finish()
 
-	h.mu.RLock()
-	filePaths := make(map[string]string)
-	for _, fileID := range req.FileIds {
-		stored, exists := h.files[fileID]
-		if !exists {
-			errMsg := fmt.Sprintf("file %s not found", fileID)
-			errors = append(errors, errMsg)
+	if len(fileIDs) == 1 && len(result.Files) == 1 {
+		w.Header().Set("ETag", `"`+result.Files[0].ETag+`"`)
+	}
+	if len(fileIDs) == 1 && len(req.ifMatchAll) > 0 && len(result.Errors) == 1 &&
+		result.Errors[0].Code == model.ErrorCodePrecondition {
+		writeAPIError(w, http.StatusPreconditionFailed, &result.Errors[0])
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		logs.ErrorContext(r.Context(), "Handler.UpdateTags: Failed to encode response", err)
+		writeError(w, http.StatusInternalServerError, model.ErrorCodeInternal, "Failed to encode response")
+		return
+	}
+}
+
+func (h *Handler) updateTags(
+	ctx context.Context, req *TagUpdateRequest, fileIDs []string, step func(int, string),
+) *filesResult {
+	result := &filesResult{Files: []model.FileMetadata{}}
+
+	files := make(map[string]*model.StoredFile)
+	missing := make(map[string]error)
+	for _, fileID := range fileIDs {
+		stored, err := h.getFile(fileID)
+		if err != nil {
+			missing[fileID] = err
 			continue
 		}
-		filePaths[fileID] = stored.Path
+		files[fileID] = stored
 	}
-	h.mu.RUnlock()
 
-	for fileID, filePath := range filePaths {
-		err := h.audioService.UpdateTags(
-			filePath, req.Title, req.Artist, req.Album, req.Year, req.Track, req.Genre, req.CoverArt,
-		)
+	type resolvedCover struct {
+		data string
+		err  error
+	}
+	resolvedCovers := make(map[string]resolvedCover)
+	resolveCover := func(coverArt *string) (*string, error) {
+		if coverArt == nil {
+			return nil, nil
+		}
+		resolved, ok := resolvedCovers[*coverArt]
+		if !ok {
+			resolved.data, resolved.err = h.audioService.ResolveCoverArt(ctx, *coverArt)
+			resolvedCovers[*coverArt] = resolved
+		}
+		if resolved.err != nil {
+			return nil, resolved.err
+		}
+		return &resolved.data, nil
+	}
+
+	if req.Atomic {
+		return h.updateTagsAtomically(ctx, req, fileIDs, files, missing, resolveCover, step, result)
+	}
+
+	for i, fileID := range fileIDs {
+		stored, ok := files[fileID]
+		if !ok {
+			result.failed(fileID, fileError(fileID, missing[fileID]))
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			result.skipped(fileID, err)
+			continue
+		}
+		step(i, fileID)
+		if err := req.checkETag(stored); err != nil {
+			result.failed(fileID, fileError(fileID, err))
+			continue
+		}
+		fields, err := h.tagFieldsFor(ctx, req, stored, resolveCover)
+		if err != nil {
+			result.failed(fileID, fileError(fileID, err))
+			continue
+		}
+		previous, err := h.writeTags(ctx, stored, &fields)
 		if err != nil {
-			errMsg := fmt.Sprintf("file %s: %v", fileID, err)
-			logs.Error("Handler.UpdateTags: Error updating tags", err)
-			errors = append(errors, errMsg)
+			logs.ErrorContext(ctx, "Handler.UpdateTags: Error updating tags", err)
+			result.failed(fileID, fileErrors(fileID, err)...)
 			continue
 		}
+		h.reparse(ctx, "UpdateTags", fileID, stored, previous, result)
+	}
 
-		var metadata *model.FileMetadata
-		var parseErr error
+	return result
+}
 
-		metadata, parseErr = h.audioService.ParseFile(filePath)
+func (h *Handler) reparse(
+	ctx context.Context, op, fileID string, stored *model.StoredFile, previous *model.FileMetadata, result *filesResult,
+) {
+	metadata, err := h.audioService.ParseFile(ctx, stored.Path)
+	if err != nil {
+		logs.ErrorContext(ctx, "Handler."+op+": Error re-parsing file", err)
+		result.failed(fileID, fileError(fileID, fmt.Errorf("failed to re-parse: %w", err)))
+		return
+	}
+	metadata.ID = fileID
+	result.updated(fileID, previous, metadata)
 
-		if parseErr != nil {
-			errMsg := fmt.Sprintf("file %s: failed to re-parse: %v", fileID, parseErr)
-			logs.Error("Handler.UpdateTags: Error re-parsing file", parseErr)
-			errors = append(errors, errMsg)
+	if err := h.saveFile(fileID, metadata); err != nil {
+		logs.ErrorContext(ctx, "Handler."+op+": Failed to save file", err)
+	}
+}
+
+func (h *Handler) tagFieldsFor(
+	ctx context.Context, req *TagUpdateRequest, stored *model.StoredFile, resolveCover func(*string) (*string, error),
+) (model.TagUpdate, error) {
+	fields := req.fieldsFor(stored.ID)
+	if req.NormalizeGenres {
+		normalizeGenre(&fields, stored)
+	}
+	coverArt, err := resolveCover(fields.CoverArt)
+	if err != nil {
+		logs.ErrorContext(ctx, "Handler.UpdateTags: Error fetching cover art", err)
+		return fields, &model.FieldError{Field: "coverArt", Err: err}
+	}
+	fields.CoverArt = coverArt
+	if len(fields.Pictures) > 0 {
+		pictures := make([]model.PictureUpdate, len(fields.Pictures))
+		for i, picture := range fields.Pictures {
+			data, err := resolveCover(&picture.Data)
+			if err != nil {
+				logs.ErrorContext(ctx, "Handler.UpdateTags: Error fetching picture", err)
+				return fields, &model.FieldError{Field: "pictures", Err: err}
+			}
+			picture.Data = *data
+			pictures[i] = picture
+		}
+		fields.Pictures = pictures
+	}
+	return fields, nil
+}
+
+func (h *Handler) updateTagsAtomically(
+	ctx context.Context, req *TagUpdateRequest, fileIDs []string,
+	files map[string]*model.StoredFile, missing map[string]error,
+	resolveCover func(*string) (*string, error), step func(int, string), result *filesResult,
+) *filesResult {
+	writes := make([]model.TagWrite, 0, len(files))
+	writeIDs := make([]string, 0, len(files))
+	previous := make([]*model.FileMetadata, 0, len(files))
+	for i, fileID := range fileIDs {
+		stored, ok := files[fileID]
+		if !ok {
+			result.failed(fileID, fileError(fileID, missing[fileID]))
+			continue
+		}
+		step(i, fileID)
+		err := req.checkETag(stored)
+		var fields model.TagUpdate
+		if err == nil {
+			fields, err = h.tagFieldsFor(ctx, req, stored, resolveCover)
+		}
+		if err == nil {
+			err = h.audioService.ValidateTagUpdate(&fields)
+		}
+		var current *model.FileMetadata
+		if err == nil {
+			if current, err = h.currentMetadata(ctx, stored); err != nil {
+				err = fmt.Errorf("failed to read current tags: %w", err)
+			}
+		}
+		if err != nil {
+			result.failed(fileID, fileErrors(fileID, err)...)
 			continue
 		}
-		metadata.ID = fileID
-		updatedFiles = append(updatedFiles, *metadata)
+		writes = append(writes, model.TagWrite{Path: stored.Path, Update: &fields})
+		writeIDs = append(writeIDs, fileID)
+		previous = append(previous, current)
+	}
+	if len(result.Errors) > 0 {
+		for _, fileID := range writeIDs {
+			result.skipped(fileID, model.ErrBatchAborted)
+		}
+		return result
+	}
 
-		h.mu.Lock()
-		if stored, exists := h.files[fileID]; exists {
-			stored.Metadata = metadata
+	if err := h.audioService.UpdateTagsAtomically(ctx, writes); err != nil {
+		logs.ErrorContext(ctx, "Handler.UpdateTags: Error updating tags atomically", err)
+		var batchErr *model.BatchError
+		if !errors.As(err, &batchErr) {
+			for _, fileID := range writeIDs {
+				result.failed(fileID, fileError(fileID, err))
+			}
+			return result
 		}
-		h.mu.Unlock()
+		for i, fileID := range writeIDs {
+			if i == batchErr.Index {
+				result.failed(fileID, fileErrors(fileID, batchErr.Err)...)
+				continue
+			}
+			result.skipped(fileID, model.ErrBatchAborted)
+		}
+		return result
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	response := map[string]interface{}{
-		"files": updatedFiles,
+	for i, fileID := range writeIDs {
+		if _, err := h.history.Record(fileID, previous[i]); err != nil {
+			logs.ErrorContext(ctx, "Handler.UpdateTags: Failed to record history", err)
+		}
+		h.reparse(ctx, "UpdateTags", fileID, files[fileID], previous[i], result)
 	}
-	if len(updatedFiles) == 0 {
-		response["files"] = []model.FileMetadata{}
+	return result
+}
+
+type fieldChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type fileChanges struct {
+	FileID string                 `json:"fileId"`
+	Fields map[string]fieldChange `json:"fields"`
+}
+
+type changesPreview struct {
+	Changes []fileChanges    `json:"changes"`
+	Errors  []model.APIError `json:"errors,omitempty"`
+}
+
+func (h *Handler) previewChanges(
+	ctx context.Context, op string, fileIDs []string,
+	changes func(ctx context.Context, stored *model.StoredFile) (map[string]fieldChange, error),
+) *changesPreview {
+	preview := &changesPreview{Changes: []fileChanges{}}
+	for _, fileID := range fileIDs {
+		if ctx.Err() != nil {
+			break
+		}
+		stored, err := h.getFile(fileID)
+		if err != nil {
+			preview.Errors = append(preview.Errors, fileError(fileID, err))
+			continue
+		}
+		fields, err := changes(ctx, stored)
+		if err != nil {
+			if errorCode(err) == model.ErrorCodeInternal {
+				logs.ErrorContext(ctx, "Handler."+op+": Error reading file", err)
+			}
+			preview.Errors = append(preview.Errors, fileError(fileID, err))
+			continue
+		}
+		if len(fields) > 0 {
+			preview.Changes = append(preview.Changes, fileChanges{FileID: fileID, Fields: fields})
+		}
+	}
+	return preview
+}
+
+func (h *Handler) currentTags(ctx context.Context, stored *model.StoredFile) (*model.FileMetadata, error) {
+	metadata, err := h.audioService.ParseFile(ctx, stored.Path)
+	if err != nil {
+		return nil, err
 	}
-	if len(errors) > 0 {
-		response["errors"] = errors
+	if metadata.Title == stored.Filename || metadata.Title == filepath.Base(stored.Path) {
+		metadata.Title = ""
 	}
+	return metadata, nil
+}
 
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		logs.Error("Handler.UpdateTags: Failed to encode response", err)
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
-		return
+func (h *Handler) rewriteTags(
+	ctx context.Context, op string, fileIDs []string, step func(int, string),
+	build func(ctx context.Context, stored *model.StoredFile) (*model.TagUpdate, error),
+) *filesResult {
+	result := &filesResult{Files: []model.FileMetadata{}}
+	for i, fileID := range fileIDs {
+		if err := ctx.Err(); err != nil {
+			result.skipped(fileID, err)
+			continue
+		}
+		step(i, fileID)
+		stored, err := h.getFile(fileID)
+		if err != nil {
+			result.failed(fileID, fileError(fileID, err))
+			continue
+		}
+
+		update, err := build(ctx, stored)
+		if err != nil {
+			if errorCode(err) == model.ErrorCodeInternal {
+				logs.ErrorContext(ctx, "Handler."+op+": Error preparing tags", err)
+			}
+			result.failed(fileID, fileErrors(fileID, err)...)
+			continue
+		}
+		if update == nil {
+			var metadata *model.FileMetadata
+			if stored.Metadata != nil {
+				copied := *stored.Metadata
+				copied.ID = fileID
+				metadata = &copied
+			}
+			result.unchanged(fileID, metadata)
+			continue
+		}
+		previous, err := h.writeTags(ctx, stored, update)
+		if err != nil {
+			logs.ErrorContext(ctx, "Handler."+op+": Error updating tags", err)
+			result.failed(fileID, fileErrors(fileID, err)...)
+			continue
+		}
+		h.reparse(ctx, op, fileID, stored, previous, result)
 	}
+	return result
 }
 
 func (h *Handler) Download(w http.ResponseWriter, r *http.Request) {
 	fileID := strings.TrimPrefix(r.URL.Path, "/api/download/")
 	if fileID == "" {
-		http.Error(w, "File ID required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "File ID required")
 		return
 	}
 
-	h.mu.RLock()
-	stored, exists := h.files[fileID]
-	h.mu.RUnlock()
+	template, err := h.templateFor(r, "")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
 
-	if !exists {
-		http.Error(w, "File not found", http.StatusNotFound)
+	originalName, err := wantsOriginalName(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "originalName must be true or false")
 		return
 	}
 
-	filePath, cleanup, err := h.prepareFileWithCoverArt(stored)
+	stored, err := h.getFile(fileID)
 	if err != nil {
-		slog.Warn(
-			"Handler.Download: Failed to prepare file with cover art, using original file", slog.Any("error", err),
-		)
-		filePath = stored.Path
-		cleanup = func() {}
+		writeFileError(w, fileID, err)
+		return
 	}
-	defer func() {
-		if cleanup != nil {
-			cleanup()
+
+	if value := r.URL.Query().Get("original"); value != "" {
+		original, err := strconv.ParseBool(value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "original must be true or false")
+			return
 		}
-	}()
+		if original {
+			h.downloadOriginal(w, r, stored)
+			return
+		}
+	}
 
-	if _, err := os.Stat(filePath); err != nil {
-		logs.Error("Handler.Download: File does not exist", err)
-		http.Error(w, "File not found", http.StatusNotFound)
+	filePath := stored.Path
+	if _, err := h.fs.Stat(filePath); err != nil {
+		logs.ErrorContext(r.Context(), "Handler.Download: File does not exist", err)
+		writeFileError(w, fileID, model.ErrFileNotFound)
 		return
 	}
 
-	file, err := os.Open(filePath)
+	file, err := h.fs.Open(filePath)
 	if err != nil {
-		logs.Error("Handler.Download: Failed to open file", err)
-		http.Error(w, "Failed to open file", http.StatusInternalServerError)
+		logs.ErrorContext(r.Context(), "Handler.Download: Failed to open file", err)
+		writeError(w, http.StatusInternalServerError, model.ErrorCodeInternal, "Failed to open file")
 		return
 	}
 	defer file.Close()
 
 	stat, err := file.Stat()
 	if err != nil {
-		logs.Error("Handler.Download: Failed to stat file", err)
-		http.Error(w, "Failed to stat file", http.StatusInternalServerError)
+		logs.ErrorContext(r.Context(), "Handler.Download: Failed to stat file", err)
+		writeError(w, http.StatusInternalServerError, model.ErrorCodeInternal, "Failed to stat file")
 		return
 	}
 
-	downloadFilename := h.buildDownloadFilename(stored)
+	downloadFilename := h.buildDownloadFilename(stored, template)
+	if originalName {
+		downloadFilename = originalFilename(stored)
+	}
 
 	w.Header().Set("Content-Type", "application/octet-stream")
 	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", downloadFilename))
 	w.Header().Set("Content-Length", fmt.Sprintf("%d", stat.Size()))
 
 	io.Copy(w, file)
-	slog.Info(
-		"Handler.Download: File downloaded", slog.String("fileID", fileID), slog.String("filename", downloadFilename),
+	slog.DebugContext(
+		r.Context(), "Handler.Download: File downloaded", slog.String("fileID", fileID),
+		slog.String("filename", downloadFilename),
 	)
 }
 
-func (h *Handler) DownloadAll(w http.ResponseWriter, _ *http.Request) {
-	h.mu.RLock()
-	filesToZip := make([]*storedFile, 0, len(h.files))
-	for _, stored := range h.files {
-		filesToZip = append(filesToZip, stored)
+func (h *Handler) downloadOriginal(w http.ResponseWriter, r *http.Request, stored *model.StoredFile) {
+	if h.originals == nil {
+		writeFileError(w, stored.ID, model.ErrOriginalNotFound)
+		return
 	}
-	h.mu.RUnlock()
-
-	if len(filesToZip) == 0 {
-		http.Error(w, "No files to download", http.StatusNotFound)
+	file, err := h.originals.Open(stored.ID)
+	if err != nil {
+		if !errors.Is(err, model.ErrFileNotFound) {
+			logs.ErrorContext(r.Context(), "Handler.downloadOriginal: Failed to open original", err)
+		}
+		writeFileError(w, stored.ID, err)
 		return
 	}
+	defer file.Close()
 
-	zipFilename := h.buildZipFilename(filesToZip)
-
-	w.Header().Set("Content-Type", "application/zip")
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", zipFilename))
-
-	var zipWriter *zip.Writer
-	var bufWriter *bufio.Writer
-	var flusher http.Flusher
-
-	if f, ok := w.(http.Flusher); ok {
-		flusher = f
-		bufWriter = bufio.NewWriterSize(w, 64*1024)
-		zipWriter = zip.NewWriter(bufWriter)
-	} else {
-		zipWriter = zip.NewWriter(w)
-	}
-	defer zipWriter.Close()
-	if bufWriter != nil {
-		defer bufWriter.Flush()
+	stat, err := file.Stat()
+	if err != nil {
+		logs.ErrorContext(r.Context(), "Handler.downloadOriginal: Failed to stat original", err)
+		writeError(w, http.StatusInternalServerError, model.ErrorCodeInternal, "Failed to stat file")
+		return
 	}
 
-	successCount := 0
-	for _, stored := range filesToZip {
-		filePath, cleanup, err := h.prepareFileWithCoverArt(stored)
-		if err != nil {
-			slog.Warn(
-				"Handler.DownloadAll: Failed to prepare file, using original file", slog.String("path", stored.Path),
-				slog.Any("error", err),
-			)
-			filePath = stored.Path
-			cleanup = func() {}
-		}
-
-		if _, err := os.Stat(filePath); err != nil {
-			if cleanup != nil {
-				cleanup()
-			}
-			logs.Error("Handler.DownloadAll: File does not exist", err, slog.String("path", filePath))
-			continue
-		}
-
-		file, err := os.Open(filePath)
-		if err != nil {
-			if cleanup != nil {
-				cleanup()
-			}
-			logs.Error("Handler.DownloadAll: Failed to open file", err, slog.String("path", filePath))
-			continue
-		}
-
-		fileStat, err := file.Stat()
-		if err != nil {
-			file.Close()
-			if cleanup != nil {
-				cleanup()
-			}
-			logs.Error("Handler.DownloadAll: Failed to stat file", err, slog.String("path", filePath))
-			continue
-		}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", path.Base(stored.Filename)))
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", stat.Size()))
+	io.Copy(w, file)
+}
 
-		downloadFilename := h.buildDownloadFilename(stored)
-		zipHeader := &zip.FileHeader{
-			Name:               downloadFilename,
-			Method:             zip.Deflate,
-			Modified:           fileStat.ModTime(),
-			UncompressedSize64: uint64(fileStat.Size()),
-		}
-		zipEntry, err := zipWriter.CreateHeader(zipHeader)
-		if err != nil {
-			file.Close()
-			if cleanup != nil {
-				cleanup()
-			}
-			logs.Error(
-				"Handler.DownloadAll: Failed to create zip entry", err, slog.String("filename", downloadFilename),
-			)
-			continue
-		}
+func (h *Handler) DownloadAll(w http.ResponseWriter, r *http.Request) {
+	storedFiles, err := h.storage.List()
+	if err != nil {
+		h.progressFor(r, 0).fail(err)
+		logs.ErrorContext(r.Context(), "Handler.DownloadAll: Failed to list files", err)
+		writeError(w, http.StatusInternalServerError, model.ErrorCodeInternal, "Failed to list files")
+		return
+	}
 
-		_, err = copyWithFlush(zipEntry, file, bufWriter, zipWriter, flusher)
-		file.Close()
-		if cleanup != nil {
-			cleanup()
-		}
-		if err != nil {
-			logs.Error(
-				"Handler.DownloadAll: Failed to write file to zip", err, slog.String("filename", downloadFilename),
-			)
-			continue
+	filesToZip := make([]*model.StoredFile, 0, len(storedFiles))
+	for _, listed := range storedFiles {
+		if stored, err := h.storage.Get(listed.ID); err == nil {
+			filesToZip = append(filesToZip, stored)
 		}
+	}
 
-		if bufWriter != nil && flusher != nil {
-			zipWriter.Flush()
-			bufWriter.Flush()
-			flusher.Flush()
-		}
-		successCount++
+	if len(filesToZip) == 0 {
+		writeError(w, http.StatusNotFound, model.ErrorCodeNotFound, "No files to download")
+		return
 	}
 
-	slog.Info("Handler.DownloadAll: ZIP file created", slog.Int("fileCount", successCount), slog.Int("requestedCount", len(filesToZip)))
+	h.sendZip(w, r, filesToZip, "")
+}
+
+type DownloadSelectedRequest struct {
+	FileIds  []string `json:"fileIds"`
+	Template string   `json:"template"` // filename template, wins over ?template=
 }
 
 func (h *Handler) DownloadSelected(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		FileIds []string `json:"fileIds"`
-	}
+	var req DownloadSelectedRequest
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		logs.Error("Handler.DownloadSelected: Failed to decode request", err)
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		logs.ErrorContext(r.Context(), "Handler.DownloadSelected: Failed to decode request", err)
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "Invalid request body")
 		return
 	}
 
 	if len(req.FileIds) == 0 {
-		http.Error(w, "No file IDs provided", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "No file IDs provided")
 		return
 	}
 
-	h.mu.RLock()
-	filesToZip := make([]*storedFile, 0, len(req.FileIds))
+	filesToZip := make([]*model.StoredFile, 0, len(req.FileIds))
 	for _, fileID := range req.FileIds {
-		if stored, exists := h.files[fileID]; exists {
+		if stored, err := h.getFile(fileID); err == nil {
 			filesToZip = append(filesToZip, stored)
 		}
 	}
-	h.mu.RUnlock()
 
 	if len(filesToZip) == 0 {
-		http.Error(w, "No files found", http.StatusNotFound)
+		writeError(w, http.StatusNotFound, model.ErrorCodeNotFound, "No files found")
+		return
+	}
+
+	h.sendZip(w, r, filesToZip, req.Template)
+}
+
+func (h *Handler) sendZip(w http.ResponseWriter, r *http.Request, files []*model.StoredFile, pattern string) {
+	layout, err := h.zipLayoutFor(r, pattern)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	if isAsync(r) {
+		h.submitJob(
+			w, r, "zip", len(files), func(ctx context.Context, step func(int, string)) (any, error) {
+				return h.buildZip(ctx, files, layout, step)
+			},
+		)
 		return
 	}
 
-	zipFilename := h.buildZipFilename(filesToZip)
+	zipFilename := h.buildZipFilename(files)
 
 	w.Header().Set("Content-Type", "application/zip")
 	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", zipFilename))
 
+	progress := h.progressFor(r, len(files))
+	progress.expectBytes(audioSize(files))
+	defer progress.finish()
+
+	successCount, err := h.writeZip(r.Context(), w, files, layout, progress.step, progress.transfer)
+	if err != nil {
+		logs.ErrorContext(r.Context(), "Handler.sendZip: Failed to write ZIP file", err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Handler.sendZip: ZIP file created", slog.Int("fileCount", successCount), slog.Int("requestedCount", len(files)))
+}
+
+const albumFolders = "[{albumArtist|artist}/][{album}/]"
+
+type zipLayout struct {
+	template *naming.Template
+	uploaded bool // put files into the folders they were uploaded in
+	covers   bool // add the front cover of each folder as cover.jpg
+	playlist bool // add an M3U8 playlist, and a cue sheet for a single album
+	original bool // name files after their recorded original names instead
+}
+
+func (h *Handler) zipLayoutFor(r *http.Request, pattern string) (zipLayout, error) {
+	template, err := h.templateFor(r, pattern)
+	if err != nil {
+		return zipLayout{}, err
+	}
+	query := r.URL.Query()
+	uploaded := query.Get("folders") == "original"
+	if folders, _ := strconv.ParseBool(query.Get("folders")); folders {
+		if template, err = naming.Parse(albumFolders + template.String()); err != nil {
+			return zipLayout{}, err
+		}
+	}
+	covers, _ := strconv.ParseBool(query.Get("covers"))
+	withPlaylist, _ := strconv.ParseBool(query.Get("playlist"))
+	originalName, _ := wantsOriginalName(r)
+	return zipLayout{
+		template: template, uploaded: uploaded, covers: covers, playlist: withPlaylist, original: originalName,
+	}, nil
+}
+
+func (h *Handler) writeZip(
+	ctx context.Context, w io.Writer, files []*model.StoredFile, layout zipLayout, step func(int, string),
+	transferred func(int64),
+) (successCount int, err error) {
+	ctx, span := tracer.Start(ctx, "handler.writeZip", trace.WithAttributes(attribute.Int("zip.requested", len(files))))
+	defer func() {
+		span.SetAttributes(attribute.Int("zip.files", successCount))
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
 	var zipWriter *zip.Writer
 	var bufWriter *bufio.Writer
 	var flusher http.Flusher
@@ -500,81 +1255,70 @@ func (h *Handler) DownloadSelected(w http.ResponseWriter, r *http.Request) {
 	} else {
 		zipWriter = zip.NewWriter(w)
 	}
-	defer zipWriter.Close()
-	if bufWriter != nil {
-		defer bufWriter.Flush()
-	}
 
-	successCount := 0
-	for _, stored := range filesToZip {
-		filePath, cleanup, err := h.prepareFileWithCoverArt(stored)
-		if err != nil {
-			slog.Warn(
-				"Handler.DownloadSelected: Failed to prepare file, using original file",
-				slog.String("path", stored.Path), slog.Any("error", err),
-			)
-			filePath = stored.Path
-			cleanup = func() {}
+	entryNames := make(map[string]bool, len(files))
+	coverFolders := make(map[string]bool)
+	var tracks []playlist.Track
+	for i, stored := range files {
+		if err := ctx.Err(); err != nil {
+			return successCount, err
 		}
+		step(i, stored.ID)
 
-		if _, err := os.Stat(filePath); err != nil {
-			if cleanup != nil {
-				cleanup()
-			}
-			logs.Error("Handler.DownloadSelected: File does not exist", err, slog.String("path", filePath))
-			continue
-		}
-
-		file, err := os.Open(filePath)
+		file, err := h.fs.Open(stored.Path)
 		if err != nil {
-			if cleanup != nil {
-				cleanup()
-			}
-			logs.Error("Handler.DownloadSelected: Failed to open file", err, slog.String("path", filePath))
+			logs.ErrorContext(ctx, "Handler.writeZip: Failed to open file", err, slog.String("path", stored.Path))
 			continue
 		}
 
 		fileStat, err := file.Stat()
 		if err != nil {
 			file.Close()
-			if cleanup != nil {
-				cleanup()
-			}
-			logs.Error("Handler.DownloadSelected: Failed to stat file", err, slog.String("path", filePath))
+			logs.ErrorContext(ctx, "Handler.writeZip: Failed to stat file", err, slog.String("path", stored.Path))
 			continue
 		}
 
-		downloadFilename := h.buildDownloadFilename(stored)
+		entryName := layout.template.Execute(stored.Metadata, stored.Filename)
+		if layout.original {
+			entryName = originalFilename(stored)
+		}
+		if layout.uploaded && stored.RelativePath != "" {
+			entryName = path.Join(path.Dir(stored.RelativePath), entryName)
+		}
+		downloadFilename := uniqueEntryName(entryName, entryNames)
 		zipHeader := &zip.FileHeader{
 			Name:               downloadFilename,
-			Method:             zip.Deflate,
+			Method:             zipMethod(stored.Metadata),
 			Modified:           fileStat.ModTime(),
 			UncompressedSize64: uint64(fileStat.Size()),
 		}
 		zipEntry, err := zipWriter.CreateHeader(zipHeader)
 		if err != nil {
 			file.Close()
-			if cleanup != nil {
-				cleanup()
-			}
-			logs.Error(
-				"Handler.DownloadSelected: Failed to create zip entry", err, slog.String("filename", downloadFilename),
+			logs.ErrorContext(
+				ctx, "Handler.writeZip: Failed to create zip entry", err, slog.String("filename", downloadFilename),
 			)
 			continue
 		}
 
-		_, err = copyWithFlush(zipEntry, file, bufWriter, zipWriter, flusher)
-		file.Close()
-		if cleanup != nil {
-			cleanup()
+		var src io.Reader = file
+		if transferred != nil {
+			src = &countingReader{r: file, count: transferred}
 		}
+		_, err = copyWithFlush(zipEntry, src, bufWriter, zipWriter, flusher)
+		file.Close()
 		if err != nil {
-			logs.Error(
-				"Handler.DownloadSelected: Failed to write file to zip", err, slog.String("filename", downloadFilename),
+			logs.ErrorContext(
+				ctx, "Handler.writeZip: Failed to write file to zip", err, slog.String("filename", downloadFilename),
 			)
 			continue
 		}
 
+		tracks = append(tracks, playlist.Track{Path: downloadFilename, Metadata: stored.Metadata})
+		if folder := path.Dir(downloadFilename); layout.covers && !coverFolders[folder] {
+			coverFolders[folder] = h.writeZipCover(ctx, zipWriter, stored, folder, entryNames)
+		}
+
 		if bufWriter != nil && flusher != nil {
 			zipWriter.Flush()
 			bufWriter.Flush()
@@ -583,158 +1327,137 @@ func (h *Handler) DownloadSelected(w http.ResponseWriter, r *http.Request) {
 		successCount++
 	}
 
-	slog.Info("Handler.DownloadSelected: ZIP file created", slog.Int("fileCount", successCount), slog.Int("requestedCount", len(filesToZip)))
-}
-
-func (h *Handler) buildDownloadFilename(stored *storedFile) string {
-	if stored.Metadata == nil {
-		return stored.Filename
-	}
-
-	meta := stored.Metadata
-	var filename string
-
-	artist := meta.Artist
-	album := meta.Album
-	disc := meta.Disc
-	track := meta.Track
-	title := meta.Title
-
-	if title == "" {
-		title = stored.Filename
-		ext := filepath.Ext(title)
-		title = strings.TrimSuffix(title, ext)
-	}
-
-	parts := []string{}
-	if artist != "" {
-		parts = append(parts, artist)
-	}
-	if album != "" {
-		parts = append(parts, album)
-	}
-
-	discTrackPart := ""
-	if track > 0 {
-		if disc > 0 {
-			discTrackPart = fmt.Sprintf("%d-%02d", disc, track)
-		} else {
-			discTrackPart = fmt.Sprintf("%02d", track)
+	if layout.playlist && len(tracks) > 0 {
+		if err := writeZipPlaylists(zipWriter, strings.TrimSuffix(h.buildZipFilename(files), ".zip"), tracks, entryNames); err != nil {
+			return successCount, err
 		}
 	}
 
-	if discTrackPart != "" {
-		parts = append(parts, discTrackPart)
+	if err := zipWriter.Close(); err != nil {
+		return successCount, fmt.Errorf("failed to finish zip: %w", err)
 	}
-
-	if len(parts) > 0 {
-		filename = strings.Join(parts, " - ")
-		if title != "" {
-			filename += " " + title
-		}
-	} else {
-		if discTrackPart != "" && title != "" {
-			filename = discTrackPart + " " + title
-		} else {
-			filename = title
+	if bufWriter != nil {
+		if err := bufWriter.Flush(); err != nil {
+			return successCount, fmt.Errorf("failed to flush zip: %w", err)
 		}
 	}
-	if filename == "" {
-		filename = stored.Filename
-	}
+	return successCount, nil
+}
 
-	ext := filepath.Ext(stored.Filename)
-	if ext != "" && !strings.HasSuffix(filename, ext) {
-		filename += ext
+func audioSize(files []*model.StoredFile) int64 {
+	var size int64
+	for _, stored := range files {
+		if stored.Metadata != nil {
+			size += stored.Metadata.Size
+		}
 	}
+	return size
+}
 
-	filename = sanitizeFilename(filename)
-	if filename == "" {
-		filename = stored.Filename
+func zipMethod(metadata *model.FileMetadata) uint16 {
+	if metadata != nil && strings.HasPrefix(metadata.Codec, "PCM") {
+		return zip.Deflate
 	}
+	return zip.Store
+}
 
-	return filename
+type countingReader struct {
+	r     io.Reader
+	count func(int64)
 }
 
-func sanitizeFilename(filename string) string {
-	invalidChars := []string{"/", "\\", ":", "*", "?", "\"", "<", ">", "|"}
-	result := filename
-	for _, char := range invalidChars {
-		result = strings.ReplaceAll(result, char, "_")
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if n > 0 {
+		c.count(int64(n))
 	}
-	result = strings.TrimSpace(result)
-	return result
+	return n, err
 }
 
-func (h *Handler) prepareFileWithCoverArt(stored *storedFile) (string, func(), error) {
-	if stored.Metadata == nil || stored.Metadata.CoverArt == "" {
-		return stored.Path, func() {}, nil
+func (h *Handler) writeZipCover(
+	ctx context.Context, zipWriter *zip.Writer, stored *model.StoredFile, folder string, entryNames map[string]bool,
+) bool {
+	if stored.Metadata != nil && !stored.Metadata.HasCoverArt {
+		return false
 	}
-
-	sourceFile, err := os.Open(stored.Path)
+	data, mimeType, err := h.audioService.ExtractCoverArt(ctx, stored.Path)
 	if err != nil {
-		return stored.Path, func() {}, fmt.Errorf("failed to open source file: %w", err)
+		if !errors.Is(err, model.ErrNoCoverArt) {
+			logs.ErrorContext(ctx, "Handler.writeZip: Failed to extract cover", err, slog.String("path", stored.Path))
+		}
+		return false
 	}
-	defer sourceFile.Close()
 
-	sourceStat, err := sourceFile.Stat()
-	if err != nil {
-		return stored.Path, func() {}, fmt.Errorf("failed to stat source file: %w", err)
+	name := "cover.jpg"
+	switch mimeType {
+	case "image/png":
+		name = "cover.png"
+	case "image/webp":
+		name = "cover.webp"
+	case "image/gif":
+		name = "cover.gif"
 	}
-	originalModTime := sourceStat.ModTime()
-
-	tempFile, err := os.CreateTemp("", "download-*"+filepath.Ext(stored.Path))
-	if err != nil {
-		return stored.Path, func() {}, fmt.Errorf("failed to create temp file: %w", err)
+	name = uniqueEntryName(path.Join(folder, name), entryNames)
+	entry, err := zipWriter.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store, Modified: time.Now()})
+	if err == nil {
+		_, err = entry.Write(data)
 	}
-	tempPath := tempFile.Name()
-	tempFile.Close()
-
-	destFile, err := os.Create(tempPath)
 	if err != nil {
-		os.Remove(tempPath)
-		return stored.Path, func() {}, fmt.Errorf("failed to create dest file: %w", err)
+		logs.ErrorContext(ctx, "Handler.writeZip: Failed to write cover", err, slog.String("filename", name))
+		return false
 	}
-	defer destFile.Close()
+	return true
+}
 
-	_, err = io.Copy(destFile, sourceFile)
-	if err != nil {
-		destFile.Close()
-		os.Remove(tempPath)
-		return stored.Path, func() {}, fmt.Errorf("failed to copy file: %w", err)
-	}
-	destFile.Close()
-
-	coverArt := stored.Metadata.CoverArt
-	updateErr := func() (err error) {
-		defer func() {
-			if r := recover(); r != nil {
-				logs.Panic(context.Background(), "Handler.prepareFileWithCoverArt: Panic while embedding cover art", r)
-				err = fmt.Errorf("panic while embedding cover art: %v", r)
-			}
-		}()
-		return h.audioService.UpdateTags(tempPath, nil, nil, nil, nil, nil, nil, &coverArt)
-	}()
-	if updateErr != nil {
-		os.Remove(tempPath)
-		logs.Error("Handler.prepareFileWithCoverArt: Failed to embed cover art", updateErr)
-		return stored.Path, func() {}, fmt.Errorf("failed to embed cover art: %w", updateErr)
+func writeZipPlaylists(zipWriter *zip.Writer, name string, tracks []playlist.Track, entryNames map[string]bool) error {
+	playlist.Sort(tracks)
+	files := map[string][]byte{".m3u8": playlist.M3U8(tracks)}
+	if playlist.SingleAlbum(tracks) {
+		files[".cue"] = playlist.CUE(tracks)
+	}
+	for _, ext := range []string{".m3u8", ".cue"} {
+		data, ok := files[ext]
+		if !ok {
+			continue
+		}
+		entryName := uniqueEntryName(name+ext, entryNames)
+		entry, err := zipWriter.CreateHeader(&zip.FileHeader{Name: entryName, Method: zip.Deflate, Modified: time.Now()})
+		if err != nil {
+			return fmt.Errorf("failed to create playlist entry: %w", err)
+		}
+		if _, err := entry.Write(data); err != nil {
+			return fmt.Errorf("failed to write playlist: %w", err)
+		}
 	}
+	return nil
+}
 
-	if err := os.Chtimes(tempPath, originalModTime, originalModTime); err != nil {
-		slog.Warn("Handler.prepareFileWithCoverArt: Failed to set modification time", slog.Any("error", err))
+func (h *Handler) templateFor(r *http.Request, pattern string) (*naming.Template, error) {
+	if pattern == "" {
+		pattern = r.URL.Query().Get("template")
+	}
+	if pattern == "" {
+		return h.filenameTemplate, nil
 	}
+	return naming.Parse(pattern)
+}
 
-	slog.Info("Handler.prepareFileWithCoverArt: Successfully embedded cover art", slog.String("path", stored.Path))
+func (h *Handler) buildDownloadFilename(stored *model.StoredFile, template *naming.Template) string {
+	return path.Base(template.Execute(stored.Metadata, stored.Filename))
+}
 
-	cleanup := func() {
-		os.Remove(tempPath)
+func uniqueEntryName(name string, used map[string]bool) string {
+	ext := path.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	unique := name
+	for n := 2; used[strings.ToLower(unique)]; n++ {
+		unique = fmt.Sprintf("%s (%d)%s", stem, n, ext)
 	}
-
-	return tempPath, cleanup, nil
+	used[strings.ToLower(unique)] = true
+	return unique
 }
 
-func (h *Handler) buildZipFilename(files []*storedFile) string {
+func (h *Handler) buildZipFilename(files []*model.StoredFile) string {
 	if len(files) == 0 {
 		return "all-tracks.zip"
 	}
@@ -774,12 +1497,12 @@ func (h *Handler) buildZipFilename(files []*storedFile) string {
 
 	if commonArtist != "" && commonAlbum != "" && maxArtistCount == len(files) && maxAlbumCount == len(files) {
 		filename := fmt.Sprintf("%s - %s.zip", commonArtist, commonAlbum)
-		return sanitizeFilename(filename)
+		return naming.SanitizeSegment(filename)
 	}
 
 	if commonArtist != "" && maxArtistCount == len(files) {
 		filename := fmt.Sprintf("%s.zip", commonArtist)
-		return sanitizeFilename(filename)
+		return naming.SanitizeSegment(filename)
 	}
 
 	return "all-tracks.zip"
@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/pkg/logs"
+)
+
+type HistoryResponse struct {
+	FileID    string           `json:"fileId"`
+	Revisions []model.Revision `json:"revisions"`
+}
+
+type RevertRequest struct {
+	Revision int `json:"revision"` // defaults to the newest revision
+}
+
+// History lists the earlier tag states of a file, newest first.
+func (h *Handler) History(w http.ResponseWriter, r *http.Request) {
+	fileID := r.PathValue("fileId")
+	if _, err := h.getFile(fileID); err != nil {
+		writeFileError(w, fileID, err)
+		return
+	}
+
+	revisions, err := h.history.List(fileID)
+	if err != nil {
+		logs.ErrorContext(r.Context(), "Handler.History: Failed to read history", err)
+		writeError(w, http.StatusInternalServerError, model.ErrorCodeInternal, "Failed to read history")
+		return
+	}
+
+	// The covers of the revisions are left out like in every other response.
+	for i := range revisions {
+		revisions[i].CoverArt = ""
+		revisions[i].Pictures = nil
+	}
+	response := HistoryResponse{FileID: fileID, Revisions: revisions}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logs.ErrorContext(r.Context(), "Handler.History: Failed to encode response", err)
+	}
+}
+
+// Revert restores the tags of a file to a revision.
+func (h *Handler) Revert(w http.ResponseWriter, r *http.Request) {
+	fileID := r.PathValue("fileId")
+	stored, err := h.getFile(fileID)
+	if err != nil {
+		writeFileError(w, fileID, err)
+		return
+	}
+
+	var req RevertRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "Invalid request body")
+		return
+	}
+
+	revision, err := h.history.Get(fileID, req.Revision)
+	if errors.Is(err, model.ErrRevisionNotFound) {
+		writeError(w, http.StatusNotFound, model.ErrorCodeNotFound, "Revision not found")
+		return
+	}
+	if err != nil {
+		logs.ErrorContext(r.Context(), "Handler.Revert: Failed to read history", err)
+		writeError(w, http.StatusInternalServerError, model.ErrorCodeInternal, "Failed to read history")
+		return
+	}
+
+	current, err := h.currentMetadata(r.Context(), stored)
+	if err != nil {
+		logs.ErrorContext(r.Context(), "Handler.Revert: Failed to parse file", err)
+		writeError(w, http.StatusInternalServerError, model.ErrorCodeInternal, "Failed to read current tags")
+		return
+	}
+	update := revision.TagUpdate(current)
+	if _, err := h.writeTags(r.Context(), stored, &update); err != nil {
+		logs.ErrorContext(r.Context(), "Handler.Revert: Failed to write tags", err)
+		apiErr := fileError(fileID, err)
+		apiErr.Message = fmt.Sprintf("Failed to revert tags: %v", err)
+		writeAPIError(w, http.StatusUnprocessableEntity, &apiErr)
+		return
+	}
+
+	metadata, err := h.audioService.ParseFile(r.Context(), stored.Path)
+	if err != nil {
+		logs.ErrorContext(r.Context(), "Handler.Revert: Failed to re-parse file", err)
+		writeError(w, http.StatusInternalServerError, model.ErrorCodeInternal, "Failed to re-parse file")
+		return
+	}
+	metadata.ID = fileID
+	if err := h.saveFile(fileID, metadata); err != nil {
+		logs.ErrorContext(r.Context(), "Handler.Revert: Failed to save file", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(metadata); err != nil {
+		logs.ErrorContext(r.Context(), "Handler.Revert: Failed to encode response", err)
+	}
+}
+
+func (h *Handler) writeTags(
+	ctx context.Context, stored *model.StoredFile, update *model.TagUpdate,
+) (*model.FileMetadata, error) {
+	if err := h.audioService.ValidateTagUpdate(update); err != nil {
+		return nil, err
+	}
+	current, err := h.currentMetadata(ctx, stored)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read current tags: %w", err)
+	}
+	if _, err := h.history.Record(stored.ID, current); err != nil {
+		return nil, fmt.Errorf("failed to record history: %w", err)
+	}
+	return current, h.audioService.UpdateTags(ctx, stored.Path, update)
+}
+
+func (h *Handler) currentMetadata(ctx context.Context, stored *model.StoredFile) (*model.FileMetadata, error) {
+	if stored.Metadata != nil && (stored.Metadata.CoverArt != "" || !stored.Metadata.HasCoverArt) {
+		return stored.Metadata, nil
+	}
+	return h.audioService.ParseFile(ctx, stored.Path)
+}
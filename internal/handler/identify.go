@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/internal/service/acoustid"
+	"github.com/iamvkosarev/audio-tag-editor/pkg/logs"
+)
+
+type IdentifyRequest struct {
+	FileID string `json:"fileId"`
+}
+
+// Identify recognises an uploaded file by its audio fingerprint.
+func (h *Handler) Identify(w http.ResponseWriter, r *http.Request) {
+	if h.identifyService == nil {
+		writeError(w, http.StatusServiceUnavailable, model.ErrorCodeUnavailable, "Identification is not configured")
+		return
+	}
+
+	var req IdentifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "Invalid request body")
+		return
+	}
+
+	stored, err := h.getFile(req.FileID)
+	if err != nil {
+		writeFileError(w, req.FileID, err)
+		return
+	}
+
+	candidates, err := h.identifyService.Identify(r.Context(), stored.Path)
+	if errors.Is(err, acoustid.ErrFingerprinterUnavailable) {
+		writeError(w, http.StatusServiceUnavailable, model.ErrorCodeUnavailable, "Identification is not configured")
+		return
+	}
+	if err != nil {
+		logs.ErrorContext(r.Context(), "Handler.Identify: identification failed", err)
+		writeError(w, http.StatusBadGateway, model.ErrorCodeUpstream, "Identification failed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(candidatesResult{Candidates: candidates})
+}
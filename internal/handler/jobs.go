@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/internal/vfs"
+	"github.com/iamvkosarev/audio-tag-editor/pkg/logs"
+)
+
+type zipArchive struct {
+	Filename    string `json:"filename"`
+	FileCount   int    `json:"fileCount"`
+	Size        int64  `json:"size"`
+	DownloadURL string `json:"downloadUrl"`
+	path        string
+	fs          vfs.FS
+}
+
+func (a *zipArchive) Cleanup() {
+	a.fs.Remove(a.path)
+}
+
+func isAsync(r *http.Request) bool {
+	async, _ := strconv.ParseBool(r.URL.Query().Get("async"))
+	return async
+}
+
+func (h *Handler) submitJob(
+	w http.ResponseWriter, r *http.Request, kind string, total int,
+	fn func(ctx context.Context, step func(int, string)) (any, error),
+) {
+	requestID := logs.RequestID(r.Context())
+	job, err := h.jobs.Submit(
+		kind, total, func(ctx context.Context, report func(done, total int, file string)) (any, error) {
+			return fn(
+				logs.WithRequestID(ctx, requestID), func(done int, file string) {
+					report(done, total, file)
+				},
+			)
+		},
+	)
+	if errors.Is(err, model.ErrJobQueueFull) {
+		w.Header().Set("Retry-After", "10")
+		writeError(w, http.StatusServiceUnavailable, model.ErrorCodeUnavailable, "Too many jobs in progress")
+		return
+	}
+	if err != nil {
+		logs.ErrorContext(r.Context(), "Handler.submitJob: Failed to submit job", err)
+		writeError(w, http.StatusInternalServerError, model.ErrorCodeInternal, "Failed to submit job")
+		return
+	}
+
+	w.Header().Set("Location", "/api/jobs/"+job.ID)
+	writeJob(w, http.StatusAccepted, job)
+}
+
+func (h *Handler) Job(w http.ResponseWriter, r *http.Request) {
+	job, err := h.jobs.Get(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, model.ErrorCodeNotFound, "Job not found")
+		return
+	}
+	writeJob(w, http.StatusOK, job)
+}
+
+// CancelJob stops a queued or running job.
+func (h *Handler) CancelJob(w http.ResponseWriter, r *http.Request) {
+	job, err := h.jobs.Cancel(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, model.ErrorCodeNotFound, "Job not found")
+		return
+	}
+	writeJob(w, http.StatusOK, job)
+}
+
+// JobDownload serves the archive built by a finished ZIP job.
+func (h *Handler) JobDownload(w http.ResponseWriter, r *http.Request) {
+	job, err := h.jobs.Get(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, model.ErrorCodeNotFound, "Job not found")
+		return
+	}
+	archive, ok := job.Result.(*zipArchive)
+	if !ok || job.Status != model.JobSucceeded {
+		writeError(w, http.StatusConflict, model.ErrorCodeConflict, "Job has no archive to download")
+		return
+	}
+
+	file, err := h.fs.Open(archive.path)
+	if err != nil {
+		logs.ErrorContext(r.Context(), "Handler.JobDownload: Failed to open archive", err)
+		writeError(w, http.StatusNotFound, model.ErrorCodeNotFound, "Archive not found")
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", archive.Filename))
+	w.Header().Set("Content-Length", strconv.FormatInt(archive.Size, 10))
+	io.Copy(w, file)
+}
+
+func (h *Handler) buildZip(
+	ctx context.Context, files []*model.StoredFile, layout zipLayout, step func(int, string),
+) (any, error) {
+	file, err := h.fs.CreateTemp("", "audio-tag-editor-*.zip")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create zip file: %w", err)
+	}
+	archive := &zipArchive{Filename: h.buildZipFilename(files), path: file.Name(), fs: h.fs}
+
+	archive.FileCount, err = h.writeZip(ctx, file, files, layout, step, nil)
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to close zip file: %w", closeErr)
+	}
+	if err != nil {
+		archive.Cleanup()
+		return nil, err
+	}
+
+	stat, err := h.fs.Stat(archive.path)
+	if err != nil {
+		archive.Cleanup()
+		return nil, fmt.Errorf("failed to stat zip file: %w", err)
+	}
+	archive.Size = stat.Size()
+
+	slog.InfoContext(
+		ctx, "Handler.buildZip: ZIP file created", slog.Int("fileCount", archive.FileCount),
+		slog.Int("requestedCount", len(files)),
+	)
+	return archive, nil
+}
+
+func writeJob(w http.ResponseWriter, status int, job *model.Job) {
+	if archive, ok := job.Result.(*zipArchive); ok && job.Status == model.JobSucceeded {
+		withURL := *archive
+		withURL.DownloadURL = "/api/jobs/" + job.ID + "/download"
+		job.Result = &withURL
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		logs.Error("Handler.writeJob: Failed to encode job", err)
+	}
+}
@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/internal/naming"
+	"github.com/iamvkosarev/audio-tag-editor/pkg/logs"
+)
+
+type LibraryResponse struct {
+	Root     string              `json:"root"`
+	Revision int64               `json:"revision"`
+	Files    []model.LibraryFile `json:"files"`
+}
+
+// Library lists the files of the music directory.
+func (h *Handler) Library(w http.ResponseWriter, r *http.Request) {
+	if h.library == nil {
+		writeError(w, http.StatusNotFound, model.ErrorCodeNotFound, "Library mode is disabled")
+		return
+	}
+
+	files := h.library.List()
+	if rescan, _ := strconv.ParseBool(r.URL.Query().Get("rescan")); rescan || len(files) == 0 {
+		if err := h.library.Scan(r.Context()); err != nil {
+			logs.ErrorContext(r.Context(), "Handler.Library: Failed to scan library", err)
+			writeError(w, http.StatusInternalServerError, model.ErrorCodeInternal, "Failed to scan library")
+			return
+		}
+		files = h.library.List()
+	}
+
+	revision := h.library.Revision()
+	etag := fmt.Sprintf(`"library-%d"`, revision)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	response := LibraryResponse{Root: h.library.Root(), Revision: revision, Files: files}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logs.ErrorContext(r.Context(), "Handler.Library: Failed to encode response", err)
+	}
+}
+
+func (h *Handler) getFile(id string) (*model.StoredFile, error) {
+	if h.library != nil {
+		if stored, err := h.library.Get(id); err == nil {
+			return stored, nil
+		}
+	}
+	return h.storage.Get(id)
+}
+
+func (h *Handler) saveFile(id string, metadata *model.FileMetadata) error {
+	if h.library != nil {
+		if _, err := h.library.Get(id); err == nil {
+			return h.library.Save(id, metadata)
+		}
+	}
+	return h.storage.Save(id, metadata)
+}
+
+type RenameRequest struct {
+	FileIds  []string `json:"fileIds"`
+	Template string   `json:"template"` // defaults to the configured filename template
+	DryRun   bool     `json:"dryRun"`
+}
+
+type RenamedFile struct {
+	PreviousID   string `json:"previousId"`
+	PreviousPath string `json:"previousPath"`
+	model.LibraryFile
+}
+
+type RenameResponse struct {
+	Files  []RenamedFile    `json:"files"`
+	Errors []model.APIError `json:"errors,omitempty"`
+}
+
+// Rename moves library files to the paths their tags produce with the filename template.
+func (h *Handler) Rename(w http.ResponseWriter, r *http.Request) {
+	if h.library == nil {
+		writeError(w, http.StatusNotFound, model.ErrorCodeNotFound, "Library mode is disabled")
+		return
+	}
+
+	var req RenameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "Invalid request body")
+		return
+	}
+	if len(req.FileIds) == 0 {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "No file IDs provided")
+		return
+	}
+
+	template := h.filenameTemplate
+	if req.Template != "" {
+		var err error
+		if template, err = naming.Parse(req.Template); err != nil {
+			writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, err.Error())
+			return
+		}
+	}
+
+	response := RenameResponse{Files: []RenamedFile{}}
+	targets := make(map[string]string, len(req.FileIds))
+	for _, fileID := range req.FileIds {
+		stored, err := h.library.Get(fileID)
+		if err != nil {
+			response.Errors = append(response.Errors, fileError(fileID, err))
+			continue
+		}
+		previousPath, err := filepath.Rel(h.library.Root(), stored.Path)
+		if err != nil {
+			response.Errors = append(response.Errors, fileError(fileID, err))
+			continue
+		}
+		previousPath = filepath.ToSlash(previousPath)
+
+		target := template.Execute(stored.Metadata, stored.Filename)
+		if other, ok := targets[target]; ok {
+			response.Errors = append(
+				response.Errors, model.APIError{
+					Code:    model.ErrorCodeConflict,
+					Message: fmt.Sprintf("%s is also the new path of file %s", target, other),
+					FileID:  fileID,
+				},
+			)
+			continue
+		}
+		targets[target] = fileID
+
+		if req.DryRun {
+			response.Files = append(
+				response.Files, RenamedFile{
+					PreviousID:   fileID,
+					PreviousPath: previousPath,
+					LibraryFile:  model.LibraryFile{Path: target, FileMetadata: *stored.Metadata},
+				},
+			)
+			continue
+		}
+
+		moved, err := h.library.Move(fileID, target)
+		if err != nil {
+			logs.ErrorContext(r.Context(), "Handler.Rename: Failed to move file", err)
+			response.Errors = append(response.Errors, fileError(fileID, err))
+			continue
+		}
+		response.Files = append(response.Files, RenamedFile{PreviousID: fileID, PreviousPath: previousPath, LibraryFile: *moved})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logs.ErrorContext(r.Context(), "Handler.Rename: Failed to encode response", err)
+	}
+}
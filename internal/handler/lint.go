@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path/filepath"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/lint"
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/pkg/logs"
+)
+
+type lintResult struct {
+	Findings []lint.Finding   `json:"findings"`
+	Errors   []model.APIError `json:"errors,omitempty"`
+}
+
+// Lint checks the selected files for common tag problems.
+func (h *Handler) Lint(w http.ResponseWriter, r *http.Request) {
+	var req fileIDsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logs.ErrorContext(r.Context(), "Handler.Lint: Failed to decode request", err)
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "Invalid request body")
+		return
+	}
+	if len(req.FileIds) == 0 {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "No file IDs provided")
+		return
+	}
+
+	var result lintResult
+	files := make([]model.FileMetadata, 0, len(req.FileIds))
+	for _, fileID := range req.FileIds {
+		stored, err := h.getFile(fileID)
+		if err == nil && stored.Metadata == nil {
+			err = model.ErrFileNotFound
+		}
+		if err != nil {
+			if !errors.Is(err, model.ErrFileNotFound) {
+				logs.ErrorContext(r.Context(), "Handler.Lint: Failed to get file", err)
+			}
+			result.Errors = append(result.Errors, fileError(fileID, err))
+			continue
+		}
+		metadata := *stored.Metadata
+		metadata.ID = fileID
+		// Untitled files show their file name as the title, which is not a tag.
+		if metadata.Title == stored.Filename || metadata.Title == filepath.Base(stored.Path) {
+			metadata.Title = ""
+		}
+		files = append(files, metadata)
+	}
+	result.Findings = lint.Check(files)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		logs.ErrorContext(r.Context(), "Handler.Lint: Failed to encode response", err)
+	}
+}
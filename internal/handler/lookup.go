@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/pkg/logs"
+)
+
+type LookupRequest struct {
+	FileID string `json:"fileId"`
+	model.LookupQuery
+}
+
+type candidatesResult struct {
+	Candidates []model.LookupCandidate `json:"candidates"`
+	Genres     []model.GenreSuggestion `json:"genres,omitempty"` // lookup only, when genre suggestions are configured
+}
+
+// Lookup searches MusicBrainz for releases matching the given tags.
+func (h *Handler) Lookup(w http.ResponseWriter, r *http.Request) {
+	var req LookupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "Invalid request body")
+		return
+	}
+
+	query := req.LookupQuery
+	if req.FileID != "" {
+		stored, err := h.getFile(req.FileID)
+		if err != nil {
+			writeFileError(w, req.FileID, err)
+			return
+		}
+		if stored.Metadata != nil {
+			if query.Artist == "" {
+				query.Artist = stored.Metadata.Artist
+			}
+			if query.Title == "" {
+				query.Title = stored.Metadata.Title
+			}
+			if query.Album == "" {
+				query.Album = stored.Metadata.Album
+			}
+		}
+	}
+
+	if query.Artist == "" && query.Title == "" && query.Album == "" {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "Artist, title or album required")
+		return
+	}
+
+	candidates, err := h.lookupService.Lookup(r.Context(), query)
+	if err != nil {
+		logs.ErrorContext(r.Context(), "Handler.Lookup: MusicBrainz lookup failed", err)
+		writeError(w, http.StatusBadGateway, model.ErrorCodeUpstream, "Lookup failed")
+		return
+	}
+
+	result := candidatesResult{Candidates: candidates}
+	if h.genreService != nil {
+		result.Genres, err = h.genreService.Suggest(r.Context(), query)
+		if err != nil {
+			logs.ErrorContext(r.Context(), "Handler.Lookup: Genre suggestion failed", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/pkg/logs"
+)
+
+const (
+	numberBySelection = "selection"
+	numberByFilename  = "filename"
+	numberByTitle     = "title"
+)
+
+type NumberTracksRequest struct {
+	FileIds []string `json:"fileIds"`
+	// OrderBy is selection (the order of FileIds, the default), filename or title.
+	OrderBy    string `json:"orderBy"`
+	Start      int    `json:"start"`      // number of the first track, 1 by default
+	Total      bool   `json:"total"`      // also set totalTracks to the last number
+	Disc       *int   `json:"disc"`       // disc number to set on every file
+	TotalDiscs *int   `json:"totalDiscs"` // number of discs to set on every file
+}
+
+// NumberTracks assigns sequential track numbers to the given files.
+func (h *Handler) NumberTracks(w http.ResponseWriter, r *http.Request) {
+	var req NumberTracksRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "Invalid request body")
+		return
+	}
+	if len(req.FileIds) == 0 {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "No file IDs provided")
+		return
+	}
+	if req.Start < 0 {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "start must not be negative")
+		return
+	}
+	if req.Start == 0 {
+		req.Start = 1
+	}
+
+	files := make([]*model.StoredFile, 0, len(req.FileIds))
+	seen := make(map[string]bool, len(req.FileIds))
+	for _, fileID := range req.FileIds {
+		if seen[fileID] {
+			continue
+		}
+		seen[fileID] = true
+		stored, err := h.getFile(fileID)
+		if err != nil {
+			writeFileError(w, fileID, err)
+			return
+		}
+		files = append(files, stored)
+	}
+
+	var sortKey func(stored *model.StoredFile) string
+	switch req.OrderBy {
+	case "", numberBySelection:
+	case numberByFilename:
+		sortKey = func(stored *model.StoredFile) string { return stored.Filename }
+	case numberByTitle:
+		sortKey = func(stored *model.StoredFile) string {
+			if stored.Metadata == nil {
+				return stored.Filename
+			}
+			return stored.Metadata.Title
+		}
+	default:
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "orderBy must be selection, filename or title")
+		return
+	}
+	if sortKey != nil {
+		sort.SliceStable(
+			files, func(i, j int) bool {
+				return naturalLess(sortKey(files[i]), sortKey(files[j]))
+			},
+		)
+	}
+
+	fileIDs := make([]string, len(files))
+	tracks := make(map[string]int, len(files))
+	for i, stored := range files {
+		fileIDs[i] = stored.ID
+		tracks[stored.ID] = req.Start + i
+	}
+	last := req.Start + len(files) - 1
+
+	number := func(ctx context.Context, stored *model.StoredFile) (*model.TagUpdate, error) {
+		track := tracks[stored.ID]
+		update := &model.TagUpdate{Track: &track, Disc: req.Disc, TotalDiscs: req.TotalDiscs}
+		if req.Total {
+			update.TotalTracks = &last
+		}
+		return update, nil
+	}
+
+	if isAsync(r) {
+		h.submitJob(
+			w, r, "number-tracks", len(fileIDs), func(ctx context.Context, step func(int, string)) (any, error) {
+				return h.rewriteTags(ctx, "NumberTracks", fileIDs, step, number), nil
+			},
+		)
+		return
+	}
+
+	progress := h.progressFor(r, len(fileIDs))
+	result := h.rewriteTags(r.Context(), "NumberTracks", fileIDs, progress.step, number)
+	progress.finish()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		logs.ErrorContext(r.Context(), "Handler.NumberTracks: Failed to encode response", err)
+	}
+}
+
+func naturalLess(a, b string) bool {
+	a, b = strings.ToLower(a), strings.ToLower(b)
+	for a != "" && b != "" {
+		if isDigit(a[0]) && isDigit(b[0]) {
+			numA, restA := splitDigits(a)
+			numB, restB := splitDigits(b)
+			if numA != numB {
+				// Without leading zeros the longer run is the larger number.
+				trimmedA, trimmedB := strings.TrimLeft(numA, "0"), strings.TrimLeft(numB, "0")
+				if len(trimmedA) != len(trimmedB) {
+					return len(trimmedA) < len(trimmedB)
+				}
+				if trimmedA != trimmedB {
+					return trimmedA < trimmedB
+				}
+			}
+			a, b = restA, restB
+			continue
+		}
+		ra, sizeA := utf8.DecodeRuneInString(a)
+		rb, sizeB := utf8.DecodeRuneInString(b)
+		if ra != rb {
+			return ra < rb
+		}
+		a, b = a[sizeA:], b[sizeB:]
+	}
+	return len(a) < len(b)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func splitDigits(s string) (string, string) {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return s[:i], s[i:]
+}
@@ -0,0 +1,380 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/internal/openapi"
+	"github.com/iamvkosarev/audio-tag-editor/internal/tagtable"
+	"github.com/iamvkosarev/audio-tag-editor/internal/templates"
+	"github.com/iamvkosarev/audio-tag-editor/pkg/logs"
+)
+
+const openAPIPath = "/api/openapi.json"
+
+var (
+	asyncParam = openapi.Param{
+		Name: "async", Description: "Run as a background job and answer 202 with the job",
+		Schema: &openapi.Schema{Type: "boolean"},
+	}
+	jobIDParam = openapi.Param{
+		Name: "jobId", Description: "Client-chosen ID to follow progress on GET /api/events/{jobId}",
+	}
+	templateParam = openapi.Param{
+		Name: "template", Description: "Filename template, such as {artist} - {title}",
+	}
+	foldersParam = openapi.Param{
+		Name: "folders", Description: "true for Artist/Album folders, original for the folders the files were uploaded in",
+		Schema: &openapi.Schema{Type: "string", Enum: []any{"true", "false", "original"}},
+	}
+	coversParam = openapi.Param{
+		Name: "covers", Description: "Add the front cover of each folder", Schema: &openapi.Schema{Type: "boolean"},
+	}
+	playlistParam = openapi.Param{
+		Name: "playlist", Description: "Add an M3U8 playlist, and a cue sheet for a single album",
+		Schema: &openapi.Schema{Type: "boolean"},
+	}
+	originalNameParam = openapi.Param{
+		Name: "originalName", Description: "Name files after their ORIGINAL_FILENAME tag instead of the template",
+		Schema: &openapi.Schema{Type: "boolean"},
+	}
+	formatParam = openapi.Param{
+		Name: "format", Description: "csv or json; by default json, or csv for a text/csv body",
+		Schema: &openapi.Schema{Type: "string", Enum: []any{"csv", "json"}},
+	}
+	jobReply = openapi.Reply{Status: http.StatusAccepted, Description: "Job queued", Body: model.Job{}}
+	zipReply = openapi.Reply{Status: http.StatusOK, Description: "ZIP archive", Body: openapi.Binary(), Type: "application/zip"}
+)
+
+var apiRoutes = []openapi.Route{
+	{
+		Method: http.MethodPost, Path: "/api/upload", Tag: "files", Summary: "Upload files",
+		Query: []openapi.Param{jobIDParam},
+		Body: openapi.Object(
+			map[string]*openapi.Schema{
+				"files": {Type: "array", Items: openapi.Binary()},
+				"paths": {Type: "array", Items: &openapi.Schema{Type: "string"}},
+			},
+		),
+		BodyType: "multipart/form-data",
+		Replies:  []openapi.Reply{{Status: http.StatusOK, Body: filesResult{}}},
+	},
+	{
+		Method: http.MethodPost, Path: "/api/upload-from-url", Tag: "files", Summary: "Import a file from a URL",
+		Query: []openapi.Param{asyncParam},
+		Body:  UploadFromURLRequest{},
+		Replies: []openapi.Reply{
+			{Status: http.StatusOK, Body: filesResult{}},
+			jobReply,
+			{Status: http.StatusBadGateway, Description: "The remote server could not be reached or answered with an error"},
+		},
+	},
+	{
+		Method: http.MethodPost, Path: "/api/uploads", Tag: "uploads", Summary: "Start a resumable upload",
+		Body:    CreateUploadRequest{},
+		Replies: []openapi.Reply{{Status: http.StatusCreated, Body: UploadResponse{}}},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/uploads/{id}", Tag: "uploads", Summary: "Get the offset to resume at",
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: UploadResponse{}}},
+	},
+	{
+		Method: http.MethodPatch, Path: "/api/uploads/{id}", Tag: "uploads", Summary: "Upload a chunk",
+		Headers: []openapi.Param{
+			{
+				Name: "Upload-Offset", Description: "Offset of the chunk in the file", Required: true,
+				Schema: &openapi.Schema{Type: "integer", Format: "int64"},
+			},
+		},
+		Body:     openapi.Binary(),
+		BodyType: "application/offset+octet-stream",
+		Replies: []openapi.Reply{
+			{Status: http.StatusOK, Description: "Chunk stored; file is set after the last chunk", Body: UploadResponse{}},
+			{Status: http.StatusConflict, Description: "Offset mismatch; resume at the returned offset", Body: UploadResponse{}},
+		},
+	},
+	{
+		Method: http.MethodDelete, Path: "/api/uploads/{id}", Tag: "uploads", Summary: "Cancel a resumable upload",
+		Replies: []openapi.Reply{{Status: http.StatusNoContent}},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/files", Tag: "files", Summary: "List uploaded files",
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: filesResult{}}},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/albums", Tag: "files", Summary: "Group files into albums",
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: albumsResult{}}},
+	},
+	{
+		Method: http.MethodPost, Path: "/api/lint", Tag: "files", Summary: "Check files for tag problems",
+		Body:    fileIDsRequest{},
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: lintResult{}}},
+	},
+	{
+		Method: http.MethodDelete, Path: "/api/files/{id}", Tag: "files", Summary: "Delete a file",
+		Replies: []openapi.Reply{{Status: http.StatusNoContent}},
+	},
+	{
+		Method: http.MethodPost, Path: "/api/delete-selected", Tag: "files", Summary: "Delete several files",
+		Body:    fileIDsRequest{},
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: deleteFilesResult{}}},
+	},
+	{
+		Method: http.MethodPost, Path: "/api/update-tags", Tag: "tags", Summary: "Update tags",
+		Query: []openapi.Param{asyncParam, jobIDParam},
+		Headers: []openapi.Param{
+			{
+				Name: "If-Match", Description: "Etags the files must still have; others are not written",
+				Schema: &openapi.Schema{Type: "string"},
+			},
+		},
+		Body: TagUpdateRequest{},
+		Replies: []openapi.Reply{
+			{Status: http.StatusOK, Body: filesResult{}}, jobReply,
+			{Status: http.StatusPreconditionFailed, Description: "The only file no longer matches If-Match", Body: model.APIError{}},
+		},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/download/{fileId}", Tag: "downloads", Summary: "Download a file",
+		Query: []openapi.Param{
+			templateParam, originalNameParam,
+			{Name: "original", Description: "The upload as it was before any edit", Schema: &openapi.Schema{Type: "boolean"}},
+		},
+		Replies: []openapi.Reply{
+			{Status: http.StatusOK, Description: "Audio file", Body: openapi.Binary(), Type: "application/octet-stream"},
+		},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/download-all", Tag: "downloads", Summary: "Download every file as a ZIP",
+		Query: []openapi.Param{
+			templateParam, foldersParam, coversParam, playlistParam, originalNameParam, asyncParam, jobIDParam,
+		},
+		Replies: []openapi.Reply{zipReply, jobReply},
+	},
+	{
+		Method: http.MethodPost, Path: "/api/download-selected", Tag: "downloads", Summary: "Download files as a ZIP",
+		Query: []openapi.Param{
+			templateParam, foldersParam, coversParam, playlistParam, originalNameParam, asyncParam, jobIDParam,
+		},
+		Body:    DownloadSelectedRequest{},
+		Replies: []openapi.Reply{zipReply, jobReply},
+	},
+	{
+		Method: http.MethodPost, Path: "/api/lookup", Tag: "metadata", Summary: "Search MusicBrainz",
+		Body:    LookupRequest{},
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: candidatesResult{}}},
+	},
+	{
+		Method: http.MethodPost, Path: "/api/identify", Tag: "metadata", Summary: "Identify a file by fingerprint",
+		Body:    IdentifyRequest{},
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: candidatesResult{}}},
+	},
+	{
+		Method: http.MethodPost, Path: "/api/find-cover", Tag: "metadata", Summary: "Search for album covers",
+		Body:    FindCoverRequest{},
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: coverCandidatesResult{}}},
+	},
+	{
+		Method: http.MethodPost, Path: "/api/replaygain", Tag: "tags", Summary: "Measure and write ReplayGain",
+		Query:   []openapi.Param{asyncParam, jobIDParam},
+		Body:    ReplayGainRequest{},
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: replayGainResult{}}, jobReply},
+	},
+	{
+		Method: http.MethodPost, Path: "/api/fix-encoding", Tag: "tags", Summary: "Repair mojibake text",
+		Query:   []openapi.Param{asyncParam, jobIDParam},
+		Body:    fileIDsRequest{},
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: filesResult{}}, jobReply},
+	},
+	{
+		Method: http.MethodPost, Path: "/api/copy-fields", Tag: "tags", Summary: "Copy, move or swap fields",
+		Query:   []openapi.Param{asyncParam, jobIDParam},
+		Body:    CopyFieldsRequest{},
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: filesResult{}}, jobReply},
+	},
+	{
+		Method: http.MethodPost, Path: "/api/transform", Tag: "tags", Summary: "Transform text fields",
+		Query: []openapi.Param{asyncParam, jobIDParam},
+		Body:  TransformRequest{},
+		Replies: []openapi.Reply{
+			{Status: http.StatusOK, Description: "Changed files, or the changes with dryRun", Body: filesResult{}},
+			jobReply,
+		},
+	},
+	{
+		Method: http.MethodPost, Path: "/api/sort-names", Tag: "tags", Summary: "Generate sort names",
+		Query: []openapi.Param{asyncParam, jobIDParam},
+		Body:  SortNamesRequest{},
+		Replies: []openapi.Reply{
+			{Status: http.StatusOK, Description: "Changed files, or the changes with dryRun", Body: filesResult{}},
+			jobReply,
+		},
+	},
+	{
+		Method: http.MethodPost, Path: "/api/album-cover", Tag: "tags", Summary: "Apply a cover to a whole album",
+		Query:   []openapi.Param{asyncParam, jobIDParam},
+		Body:    AlbumCoverRequest{},
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: filesResult{}}, jobReply},
+	},
+	{
+		Method: http.MethodPost, Path: "/api/number-tracks", Tag: "tags", Summary: "Number tracks in order",
+		Query:   []openapi.Param{asyncParam, jobIDParam},
+		Body:    NumberTracksRequest{},
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: filesResult{}}, jobReply},
+	},
+	{
+		Method: http.MethodPost, Path: "/api/tags-from-filename", Tag: "tags", Summary: "Read tags from file names",
+		Query: []openapi.Param{asyncParam, jobIDParam},
+		Body:  TagsFromFilenameRequest{},
+		Replies: []openapi.Reply{
+			{Status: http.StatusOK, Description: "Changed files, or the changes with dryRun", Body: filesResult{}},
+			jobReply,
+		},
+	},
+	{
+		Method: http.MethodPost, Path: "/api/record-filename", Tag: "tags", Summary: "Record the uploaded file names",
+		Query:   []openapi.Param{asyncParam, jobIDParam},
+		Body:    RecordFilenameRequest{},
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: filesResult{}}, jobReply},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/export", Tag: "tags", Summary: "Export the tag table",
+		Query: []openapi.Param{formatParam},
+		Replies: []openapi.Reply{
+			{Status: http.StatusOK, Description: "Tag table, as CSV with the same columns for format=csv", Body: []tagtable.Row{}},
+		},
+	},
+	{
+		Method: http.MethodPost, Path: "/api/import", Tag: "tags", Summary: "Import an edited tag table",
+		Query:   []openapi.Param{formatParam, asyncParam, jobIDParam},
+		Body:    []tagtable.Row{},
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: filesResult{}}, jobReply},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/events/{jobId}", Tag: "jobs", Summary: "Stream progress events",
+		Replies: []openapi.Reply{
+			{
+				Status: http.StatusOK, Description: "Server-sent progress events", Body: model.ProgressEvent{},
+				Type: "text/event-stream",
+			},
+		},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/cover/{fileId}", Tag: "files", Summary: "Get the cover image",
+		Query: []openapi.Param{
+			{Name: "size", Description: "Longest edge in pixels", Schema: &openapi.Schema{Type: "integer"}},
+			{Name: "format", Description: "jpeg or png", Schema: &openapi.Schema{Type: "string", Enum: []any{"jpeg", "png"}}},
+			{Name: "quality", Description: "JPEG quality", Schema: &openapi.Schema{Type: "integer"}},
+			{Name: "type", Description: "Picture type, front cover by default", Schema: &openapi.Schema{Type: "integer"}},
+		},
+		Replies: []openapi.Reply{{Status: http.StatusOK, Description: "Image", Body: openapi.Binary(), Type: "image/*"}},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/verify/{fileId}", Tag: "files", Summary: "Check file and audio checksums",
+		Query: []openapi.Param{
+			{Name: "decode", Description: "Decode FLAC files to check their STREAMINFO MD5, true by default", Schema: &openapi.Schema{Type: "boolean"}},
+		},
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: verifyResult{}}},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/waveform/{fileId}", Tag: "files", Summary: "Get the waveform peaks",
+		Query: []openapi.Param{
+			{Name: "points", Description: "Number of peaks", Schema: &openapi.Schema{Type: "integer"}},
+			{Name: "format", Description: "json or png", Schema: &openapi.Schema{Type: "string", Enum: []any{"json", "png"}}},
+			{Name: "height", Description: "PNG height in pixels", Schema: &openapi.Schema{Type: "integer"}},
+			{Name: "color", Description: "PNG bar color as hex RGB", Schema: &openapi.Schema{Type: "string"}},
+		},
+		Replies: []openapi.Reply{
+			{Status: http.StatusOK, Description: "Peaks, or an image with ?format=png", Body: waveformResult{}},
+		},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/chapters/{fileId}", Tag: "tags", Summary: "List chapters",
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: ChaptersResponse{}}},
+	},
+	{
+		Method: http.MethodPut, Path: "/api/chapters/{fileId}", Tag: "tags", Summary: "Replace chapters",
+		Body:    ChaptersRequest{},
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: ChaptersResponse{}}},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/history/{fileId}", Tag: "tags", Summary: "List earlier tag states",
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: HistoryResponse{}}},
+	},
+	{
+		Method: http.MethodPost, Path: "/api/revert/{fileId}", Tag: "tags", Summary: "Restore an earlier tag state",
+		Body:    RevertRequest{},
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: model.FileMetadata{}}},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/genres", Tag: "tags", Summary: "List canonical genres for autocomplete",
+		Query: []openapi.Param{
+			{Name: "q", Description: "Start of a genre name in any known spelling or language", Schema: &openapi.Schema{Type: "string"}},
+			{Name: "lang", Description: "Language of the names, such as de or ja", Schema: &openapi.Schema{Type: "string"}},
+			{Name: "limit", Description: "Maximum number of genres", Schema: &openapi.Schema{Type: "integer"}},
+		},
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: GenresResponse{}}},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/library", Tag: "library", Summary: "List library files",
+		Query:   []openapi.Param{{Name: "rescan", Schema: &openapi.Schema{Type: "boolean"}}},
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: LibraryResponse{}}, {Status: http.StatusNotModified}},
+	},
+	{
+		Method: http.MethodPost, Path: "/api/rename", Tag: "library", Summary: "Rename library files from their tags",
+		Body:    RenameRequest{},
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: RenameResponse{}}},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/jobs/{id}", Tag: "jobs", Summary: "Get a job",
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: model.Job{}}},
+	},
+	{
+		Method: http.MethodDelete, Path: "/api/jobs/{id}", Tag: "jobs", Summary: "Cancel a job",
+		Replies: []openapi.Reply{{Status: http.StatusOK, Body: model.Job{}}},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/jobs/{id}/download", Tag: "jobs", Summary: "Download the archive of a ZIP job",
+		Replies: []openapi.Reply{zipReply},
+	},
+	{
+		Method: http.MethodPost, Path: "/api/admin/cleanup", Tag: "admin", Summary: "Delete expired files now",
+		Replies: []openapi.Reply{{Status: http.StatusNoContent}},
+	},
+}
+
+var openAPIDocument = sync.OnceValues(
+	func() ([]byte, error) {
+		document := openapi.Build(
+			openapi.Info{Title: "Audio Tag Editor API", Version: "1.0"},
+			&openapi.Security{
+				Schemes: map[string]*openapi.SecurityScheme{
+					"bearer": {Type: "http", Scheme: "bearer"},
+					"apiKey": {Type: "apiKey", In: "header", Name: "X-API-Key"},
+				},
+				Optional: true,
+			},
+			model.APIError{}, apiRoutes,
+		)
+		return json.Marshal(document)
+	},
+)
+
+// OpenAPI serves the OpenAPI document of the REST API.
+func (h *Handler) OpenAPI(w http.ResponseWriter, r *http.Request) {
+	document, err := openAPIDocument()
+	if err != nil {
+		logs.ErrorContext(r.Context(), "Handler.OpenAPI: Failed to encode document", err)
+		writeError(w, http.StatusInternalServerError, model.ErrorCodeInternal, "Failed to build API document")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(document)
+}
+
+// Docs serves Swagger UI for the OpenAPI document.
+func (h *Handler) Docs(w http.ResponseWriter, r *http.Request) {
+	templates.Docs(openAPIPath).Render(r.Context(), w)
+}
@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/pkg/logs"
+)
+
+const eventsKeepAlive = 15 * time.Second
+
+const transferEventInterval = 500 * time.Millisecond
+
+var jobIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Events streams the progress of a job as Server-Sent Events.
+func (h *Handler) Events(w http.ResponseWriter, r *http.Request) {
+	jobID := r.PathValue("jobId")
+	if !jobIDPattern.MatchString(jobID) {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "Invalid job ID")
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, model.ErrorCodeInternal, "Streaming is not supported")
+		return
+	}
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		logs.ErrorContext(r.Context(), "Handler.Events: Failed to clear write deadline", err)
+	}
+
+	next, _ := strconv.Atoi(r.Header.Get("Last-Event-ID"))
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(eventsKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		events, changed, finished := h.progress.Events(jobID, next)
+		for _, event := range events {
+			data, err := json.Marshal(event)
+			if err != nil {
+				logs.ErrorContext(r.Context(), "Handler.Events: Failed to encode event", err)
+				return
+			}
+			next++
+			fmt.Fprintf(w, "id: %d\ndata: %s\n\n", next, data)
+		}
+		flusher.Flush()
+		if finished {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+type progressReporter struct {
+	hub   ProgressHub
+	jobID string
+	total int
+
+	done       int
+	file       string
+	bytes      int64
+	totalBytes int64
+	started    time.Time
+	lastEvent  time.Time
+}
+
+func (h *Handler) progressFor(r *http.Request, total int) *progressReporter {
+	jobID := r.URL.Query().Get("jobId")
+	if !jobIDPattern.MatchString(jobID) {
+		jobID = ""
+	}
+	return &progressReporter{hub: h.progress, jobID: jobID, total: total}
+}
+
+func (p *progressReporter) step(done int, file string) {
+	p.done, p.file = done, file
+	p.publish(p.progressEvent())
+}
+
+func (p *progressReporter) expectBytes(totalBytes int64) {
+	p.totalBytes = totalBytes
+	p.started = time.Now()
+}
+
+func (p *progressReporter) transfer(n int64) {
+	p.bytes += n
+	if p.jobID == "" || time.Since(p.lastEvent) < transferEventInterval {
+		return
+	}
+	p.publish(p.progressEvent())
+}
+
+func (p *progressReporter) progressEvent() model.ProgressEvent {
+	event := model.ProgressEvent{Type: model.ProgressEventProgress, Done: p.done, Total: p.total, File: p.file}
+	if p.totalBytes > 0 {
+		p.lastEvent = time.Now()
+		event.Bytes, event.TotalBytes = p.bytes, p.totalBytes
+		if elapsed := time.Since(p.started).Seconds(); p.bytes > 0 && p.bytes < p.totalBytes {
+			event.ETA = math.Round(elapsed/float64(p.bytes)*float64(p.totalBytes-p.bytes)*10) / 10
+		}
+	}
+	return event
+}
+
+func (p *progressReporter) finish() {
+	event := model.ProgressEvent{Type: model.ProgressEventDone, Done: p.total, Total: p.total}
+	if p.totalBytes > 0 {
+		event.Bytes, event.TotalBytes = p.bytes, p.totalBytes
+	}
+	p.publish(event)
+}
+
+func (p *progressReporter) fail(err error) {
+	p.publish(model.ProgressEvent{Type: model.ProgressEventError, Total: p.total, Error: err.Error()})
+}
+
+func (p *progressReporter) publish(event model.ProgressEvent) {
+	if p.jobID == "" {
+		return
+	}
+	p.hub.Publish(p.jobID, event)
+}
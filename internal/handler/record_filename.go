@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/pkg/logs"
+)
+
+type RecordFilenameRequest struct {
+	FileIds []string `json:"fileIds"`
+}
+
+// RecordFilename writes the uploaded name of each file into its ORIGINAL_FILENAME tag.
+func (h *Handler) RecordFilename(w http.ResponseWriter, r *http.Request) {
+	var req RecordFilenameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "Invalid request body")
+		return
+	}
+	if len(req.FileIds) == 0 {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "No file IDs provided")
+		return
+	}
+
+	build := func(ctx context.Context, stored *model.StoredFile) (*model.TagUpdate, error) {
+		current, err := h.currentTags(ctx, stored)
+		if err != nil {
+			return nil, err
+		}
+		if current.CustomTags[model.OriginalFilenameTag] != "" {
+			return nil, nil
+		}
+		return &model.TagUpdate{CustomTags: map[string]string{model.OriginalFilenameTag: stored.Filename}}, nil
+	}
+
+	if isAsync(r) {
+		h.submitJob(
+			w, r, "record-filename", len(req.FileIds), func(ctx context.Context, step func(int, string)) (any, error) {
+				return h.rewriteTags(ctx, "RecordFilename", req.FileIds, step, build), nil
+			},
+		)
+		return
+	}
+
+	progress := h.progressFor(r, len(req.FileIds))
+	result := h.rewriteTags(r.Context(), "RecordFilename", req.FileIds, progress.step, build)
+	progress.finish()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		logs.ErrorContext(r.Context(), "Handler.RecordFilename: Failed to encode response", err)
+	}
+}
+
+func wantsOriginalName(r *http.Request) (bool, error) {
+	value := r.URL.Query().Get("originalName")
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+func originalFilename(stored *model.StoredFile) string {
+	if stored.Metadata != nil {
+		switch name := path.Base(stored.Metadata.CustomTags[model.OriginalFilenameTag]); name {
+		case ".", "..", "/":
+		default:
+			return name
+		}
+	}
+	return path.Base(stored.Filename)
+}
@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/internal/service/replaygain"
+	"github.com/iamvkosarev/audio-tag-editor/pkg/logs"
+)
+
+type ReplayGainRequest struct {
+	FileIds []string `json:"fileIds"`
+	Album   bool     `json:"album"`
+}
+
+// ReplayGain measures the loudness of the given files and writes the REPLAYGAIN_* tags.
+func (h *Handler) ReplayGain(w http.ResponseWriter, r *http.Request) {
+	var req ReplayGainRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "Invalid request body")
+		return
+	}
+	if len(req.FileIds) == 0 {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "No file IDs provided")
+		return
+	}
+
+	files := make([]*model.StoredFile, len(req.FileIds))
+	for i, fileID := range req.FileIds {
+		stored, err := h.getFile(fileID)
+		if err != nil {
+			writeFileError(w, fileID, err)
+			return
+		}
+		files[i] = stored
+	}
+
+	if isAsync(r) {
+		h.submitJob(
+			w, r, "replaygain", len(req.FileIds), func(ctx context.Context, step func(int, string)) (any, error) {
+				return h.applyReplayGain(ctx, &req, files, step)
+			},
+		)
+		return
+	}
+
+	progress := h.progressFor(r, len(req.FileIds))
+	result, err := h.applyReplayGain(r.Context(), &req, files, progress.step)
+	if errors.Is(err, replaygain.ErrDecoderUnavailable) {
+		progress.fail(err)
+		writeError(w, http.StatusServiceUnavailable, model.ErrorCodeUnavailable, "ReplayGain analysis is not available")
+		return
+	}
+	if err != nil {
+		progress.fail(err)
+		logs.ErrorContext(r.Context(), "Handler.ReplayGain: analysis failed", err)
+		writeError(w, http.StatusUnprocessableEntity, errorCode(err), "ReplayGain analysis failed")
+		return
+	}
+	progress.finish()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		logs.ErrorContext(r.Context(), "Handler.ReplayGain: Failed to encode response", err)
+	}
+}
+
+type replayGainResult struct {
+	Files      []model.FileMetadata        `json:"files"`
+	ReplayGain map[string]model.ReplayGain `json:"replayGain"`
+	Errors     []model.APIError            `json:"errors,omitempty"`
+}
+
+func (h *Handler) applyReplayGain(
+	ctx context.Context, req *ReplayGainRequest, files []*model.StoredFile, step func(int, string),
+) (*replayGainResult, error) {
+	filePaths := make([]string, len(files))
+	for i, stored := range files {
+		filePaths[i] = stored.Path
+	}
+
+	gains, err := h.replayGainService.Analyze(ctx, filePaths, req.Album)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &replayGainResult{
+		Files:      []model.FileMetadata{},
+		ReplayGain: make(map[string]model.ReplayGain, len(req.FileIds)),
+	}
+	for i, fileID := range req.FileIds {
+		if ctx.Err() != nil {
+			break
+		}
+		step(i, fileID)
+		result.ReplayGain[fileID] = gains[i]
+
+		if _, err := h.writeTags(ctx, files[i], &model.TagUpdate{CustomTags: gains[i].Tags()}); err != nil {
+			logs.ErrorContext(ctx, "Handler.ReplayGain: Error writing tags", err)
+			result.Errors = append(result.Errors, fileError(fileID, err))
+			continue
+		}
+
+		metadata, err := h.audioService.ParseFile(ctx, filePaths[i])
+		if err != nil {
+			logs.ErrorContext(ctx, "Handler.ReplayGain: Error re-parsing file", err)
+			result.Errors = append(result.Errors, fileError(fileID, fmt.Errorf("failed to re-parse: %w", err)))
+			continue
+		}
+		metadata.ID = fileID
+		result.Files = append(result.Files, *metadata)
+
+		if err := h.saveFile(fileID, metadata); err != nil {
+			logs.ErrorContext(ctx, "Handler.ReplayGain: Failed to save file", err)
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"slices"
+	"sort"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+)
+
+const (
+	statusUpdated = "updated" // the tags were written
+	statusSkipped = "skipped" // nothing was written, and the file itself is fine
+	statusFailed  = "failed"  // the file could not be updated
+)
+
+type fileResult struct {
+	FileID        string          `json:"fileId"`
+	Status        string          `json:"status"`
+	Error         *model.APIError `json:"error,omitempty"`
+	ChangedFields []string        `json:"changedFields,omitempty"`
+}
+
+func (r *filesResult) updated(fileID string, before, after *model.FileMetadata) {
+	r.Files = append(r.Files, *after)
+	r.Results = append(
+		r.Results, fileResult{FileID: fileID, Status: statusUpdated, ChangedFields: changedFields(before, after)},
+	)
+}
+
+func (r *filesResult) unchanged(fileID string, metadata *model.FileMetadata) {
+	if metadata != nil {
+		r.Files = append(r.Files, *metadata)
+	}
+	r.Results = append(r.Results, fileResult{FileID: fileID, Status: statusSkipped})
+}
+
+func (r *filesResult) failed(fileID string, apiErrs ...model.APIError) {
+	r.Errors = append(r.Errors, apiErrs...)
+	r.Results = append(r.Results, fileResult{FileID: fileID, Status: statusFailed, Error: &apiErrs[0]})
+}
+
+func (r *filesResult) skipped(fileID string, err error) {
+	apiErr := fileError(fileID, err)
+	r.Errors = append(r.Errors, apiErr)
+	r.Results = append(r.Results, fileResult{FileID: fileID, Status: statusSkipped, Error: &apiErr})
+}
+
+func changedFields(before, after *model.FileMetadata) []string {
+	var fields []string
+	for _, name := range model.TextFields {
+		if before.TextField(name) != after.TextField(name) {
+			fields = append(fields, name)
+		}
+	}
+	others := []struct {
+		name    string
+		changed bool
+	}{
+		{"year", before.Year != after.Year},
+		{"releaseDate", before.ReleaseDate != after.ReleaseDate},
+		{"track", before.Track != after.Track},
+		{"totalTracks", before.TotalTracks != after.TotalTracks},
+		{"disc", before.Disc != after.Disc},
+		{"totalDiscs", before.TotalDiscs != after.TotalDiscs},
+		{"bpm", before.BPM != after.BPM},
+		{"compilation", before.Compilation != after.Compilation},
+		{"syncedLyrics", before.SyncedLyrics != after.SyncedLyrics},
+		{"isrc", before.ISRC != after.ISRC},
+		{"barcode", before.Barcode != after.Barcode},
+		{"catalogNumber", before.CatalogNumber != after.CatalogNumber},
+		{"label", before.Label != after.Label},
+		{"rating", before.Rating != after.Rating},
+		{"playCount", before.PlayCount != after.PlayCount},
+		{"coverArt", before.HasCoverArt != after.HasCoverArt || before.CoverArt != after.CoverArt},
+		{"pictures", !slices.EqualFunc(before.Pictures, after.Pictures, samePicture)},
+		{"chapters", !slices.Equal(before.Chapters, after.Chapters)},
+	}
+	for _, other := range others {
+		if other.changed {
+			fields = append(fields, other.name)
+		}
+	}
+
+	var customTags []string
+	for name, value := range before.CustomTags {
+		if afterValue, ok := after.CustomTags[name]; !ok || afterValue != value {
+			customTags = append(customTags, "customTags."+name)
+		}
+	}
+	for name := range after.CustomTags {
+		if _, ok := before.CustomTags[name]; !ok {
+			customTags = append(customTags, "customTags."+name)
+		}
+	}
+	sort.Strings(customTags)
+	return append(fields, customTags...)
+}
+
+func samePicture(a, b model.Picture) bool {
+	return a.Type == b.Type && a.Description == b.Description && a.MimeType == b.MimeType &&
+		a.Width == b.Width && a.Height == b.Height && a.Size == b.Size
+}
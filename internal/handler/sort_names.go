@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/internal/transform"
+	"github.com/iamvkosarev/audio-tag-editor/pkg/logs"
+)
+
+type SortNamesRequest struct {
+	FileIds   []string `json:"fileIds"`
+	Fields    []string `json:"fields"`    // titleSort, artistSort, albumArtistSort or albumSort; all of them when empty
+	Articles  []string `json:"articles"`  // leading words to move; "The", "A" and "An" when empty
+	Overwrite bool     `json:"overwrite"` // replace sort names that are already set
+	DryRun    bool     `json:"dryRun"`    // preview the changes without writing them
+}
+
+// SortNames fills the sort-order fields of the given files.
+func (h *Handler) SortNames(w http.ResponseWriter, r *http.Request) {
+	var req SortNamesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "Invalid request body")
+		return
+	}
+	if len(req.FileIds) == 0 {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "No file IDs provided")
+		return
+	}
+
+	sources := make(map[string]string)
+	for i, field := range req.Fields {
+		source, ok := model.SortFields[field]
+		if !ok {
+			writeAPIError(
+				w, http.StatusBadRequest, &model.APIError{
+					Code:    model.ErrorCodeInvalidRequest,
+					Message: fmt.Sprintf("unknown sort field %q", field),
+					Field:   fmt.Sprintf("fields[%d]", i),
+				},
+			)
+			return
+		}
+		sources[field] = source
+	}
+	if len(sources) == 0 {
+		sources = model.SortFields
+	}
+	articles := transform.DefaultArticles
+	if len(req.Articles) > 0 {
+		articles = nil
+		for _, article := range req.Articles {
+			if article = strings.TrimSpace(article); article != "" {
+				articles = append(articles, article)
+			}
+		}
+	}
+
+	sorted := func(ctx context.Context, stored *model.StoredFile) (*model.FileMetadata, *model.FileMetadata, error) {
+		current, err := h.currentTags(ctx, stored)
+		if err != nil {
+			return nil, nil, err
+		}
+		changed := *current
+		for field, source := range sources {
+			if !req.Overwrite && changed.TextField(field) != "" {
+				continue
+			}
+			name := changed.TextField(source)
+			sortName := transform.SortName(name, articles)
+			if sortName == strings.TrimSpace(name) {
+				sortName = ""
+			}
+			changed.SetTextField(field, sortName)
+		}
+		return current, &changed, nil
+	}
+
+	if req.DryRun {
+		preview := h.previewChanges(
+			r.Context(), "SortNames", req.FileIds,
+			func(ctx context.Context, stored *model.StoredFile) (map[string]fieldChange, error) {
+				current, changed, err := sorted(ctx, stored)
+				if err != nil {
+					return nil, err
+				}
+				fields := make(map[string]fieldChange)
+				for field := range sources {
+					if from, to := current.TextField(field), changed.TextField(field); from != to {
+						fields[field] = fieldChange{From: from, To: to}
+					}
+				}
+				return fields, nil
+			},
+		)
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(preview); err != nil {
+			logs.ErrorContext(r.Context(), "Handler.SortNames: Failed to encode response", err)
+		}
+		return
+	}
+
+	build := func(ctx context.Context, stored *model.StoredFile) (*model.TagUpdate, error) {
+		current, changed, err := sorted(ctx, stored)
+		if err != nil {
+			return nil, err
+		}
+		return changed.TextChanges(current), nil
+	}
+
+	if isAsync(r) {
+		h.submitJob(
+			w, r, "sort-names", len(req.FileIds), func(ctx context.Context, step func(int, string)) (any, error) {
+				return h.rewriteTags(ctx, "SortNames", req.FileIds, step, build), nil
+			},
+		)
+		return
+	}
+
+	progress := h.progressFor(r, len(req.FileIds))
+	result := h.rewriteTags(r.Context(), "SortNames", req.FileIds, progress.step, build)
+	progress.finish()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		logs.ErrorContext(r.Context(), "Handler.SortNames: Failed to encode response", err)
+	}
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	spaIndex           = "index.html"
+	immutableAssetsDir = "assets/"
+)
+
+type staticFiles struct {
+	fsys  fs.FS
+	mu    sync.Mutex
+	etags map[etagKey]string
+}
+
+type etagKey struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func newStaticFiles(fsys fs.FS) *staticFiles {
+	if fsys == nil {
+		return nil
+	}
+	if _, err := fs.Stat(fsys, spaIndex); err != nil {
+		return nil
+	}
+	return &staticFiles{fsys: fsys, etags: make(map[etagKey]string)}
+}
+
+func (s *staticFiles) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	info, err := fs.Stat(s.fsys, name)
+	if name == "" || err != nil || info.IsDir() {
+		// A missing file with an extension is a broken link, not a route.
+		if path.Ext(name) != "" {
+			http.NotFound(w, r)
+			return
+		}
+		name = spaIndex
+		if info, err = fs.Stat(s.fsys, name); err != nil {
+			http.NotFound(w, r)
+			return
+		}
+	}
+
+	if strings.HasPrefix(name, immutableAssetsDir) {
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+	etag, err := s.etag(name, info)
+	if err != nil {
+		http.Error(w, "Failed to read file", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("ETag", etag)
+
+	http.ServeFileFS(w, r, s.fsys, name)
+}
+
+func (s *staticFiles) etag(name string, info fs.FileInfo) (string, error) {
+	key := etagKey{name: name, size: info.Size(), modTime: info.ModTime()}
+	s.mu.Lock()
+	etag, ok := s.etags[key]
+	s.mu.Unlock()
+	if ok {
+		return etag, nil
+	}
+
+	data, err := fs.ReadFile(s.fsys, name)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	sum := sha256.Sum256(data)
+	etag = `"` + hex.EncodeToString(sum[:8]) + `"`
+	s.mu.Lock()
+	s.etags[key] = etag
+	s.mu.Unlock()
+	return etag, nil
+}
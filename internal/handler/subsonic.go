@@ -0,0 +1,473 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"mime"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/imaging"
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/pkg/logs"
+)
+
+const subsonicVersion = "1.16.1"
+
+const (
+	subsonicErrGeneric      = 0
+	subsonicErrMissingParam = 10
+	subsonicErrWrongAuth    = 40
+	subsonicErrNotFound     = 70
+)
+
+const subsonicMusicFolderID = 1
+
+const subsonicDirPrefix = "dir-"
+
+type subsonicResponse struct {
+	XMLName      xml.Name              `xml:"subsonic-response" json:"-"`
+	Xmlns        string                `xml:"xmlns,attr" json:"-"`
+	Status       string                `xml:"status,attr" json:"status"`
+	Version      string                `xml:"version,attr" json:"version"`
+	Type         string                `xml:"type,attr" json:"type"`
+	OpenSubsonic bool                  `xml:"openSubsonic,attr" json:"openSubsonic"`
+	Error        *subsonicError        `xml:"error,omitempty" json:"error,omitempty"`
+	License      *subsonicLicense      `xml:"license,omitempty" json:"license,omitempty"`
+	MusicFolders *subsonicMusicFolders `xml:"musicFolders,omitempty" json:"musicFolders,omitempty"`
+	Indexes      *subsonicIndexes      `xml:"indexes,omitempty" json:"indexes,omitempty"`
+	Directory    *subsonicDirectory    `xml:"directory,omitempty" json:"directory,omitempty"`
+}
+
+type subsonicError struct {
+	Code    int    `xml:"code,attr" json:"code"`
+	Message string `xml:"message,attr" json:"message"`
+}
+
+type subsonicLicense struct {
+	Valid bool `xml:"valid,attr" json:"valid"`
+}
+
+type subsonicMusicFolders struct {
+	MusicFolder []subsonicMusicFolder `xml:"musicFolder" json:"musicFolder"`
+}
+
+type subsonicMusicFolder struct {
+	ID   int    `xml:"id,attr" json:"id"`
+	Name string `xml:"name,attr" json:"name"`
+}
+
+type subsonicIndexes struct {
+	LastModified    int64           `xml:"lastModified,attr" json:"lastModified"`
+	IgnoredArticles string          `xml:"ignoredArticles,attr" json:"ignoredArticles"`
+	Index           []subsonicIndex `xml:"index" json:"index"`
+	Child           []subsonicChild `xml:"child" json:"child,omitempty"`
+}
+
+type subsonicIndex struct {
+	Name   string           `xml:"name,attr" json:"name"`
+	Artist []subsonicArtist `xml:"artist" json:"artist"`
+}
+
+type subsonicArtist struct {
+	ID   string `xml:"id,attr" json:"id"`
+	Name string `xml:"name,attr" json:"name"`
+}
+
+type subsonicDirectory struct {
+	ID     string          `xml:"id,attr" json:"id"`
+	Parent string          `xml:"parent,attr,omitempty" json:"parent,omitempty"`
+	Name   string          `xml:"name,attr" json:"name"`
+	Child  []subsonicChild `xml:"child" json:"child"`
+}
+
+type subsonicChild struct {
+	ID          string `xml:"id,attr" json:"id"`
+	Parent      string `xml:"parent,attr,omitempty" json:"parent,omitempty"`
+	IsDir       bool   `xml:"isDir,attr" json:"isDir"`
+	Title       string `xml:"title,attr" json:"title"`
+	Album       string `xml:"album,attr,omitempty" json:"album,omitempty"`
+	Artist      string `xml:"artist,attr,omitempty" json:"artist,omitempty"`
+	Track       int    `xml:"track,attr,omitempty" json:"track,omitempty"`
+	DiscNumber  int    `xml:"discNumber,attr,omitempty" json:"discNumber,omitempty"`
+	Year        int    `xml:"year,attr,omitempty" json:"year,omitempty"`
+	Genre       string `xml:"genre,attr,omitempty" json:"genre,omitempty"`
+	CoverArt    string `xml:"coverArt,attr,omitempty" json:"coverArt,omitempty"`
+	Size        int64  `xml:"size,attr,omitempty" json:"size,omitempty"`
+	ContentType string `xml:"contentType,attr,omitempty" json:"contentType,omitempty"`
+	Suffix      string `xml:"suffix,attr,omitempty" json:"suffix,omitempty"`
+	Duration    int    `xml:"duration,attr,omitempty" json:"duration,omitempty"`
+	BitRate     int    `xml:"bitRate,attr,omitempty" json:"bitRate,omitempty"`
+	Path        string `xml:"path,attr,omitempty" json:"path,omitempty"`
+	Type        string `xml:"type,attr,omitempty" json:"type,omitempty"`
+}
+
+var audioContentTypes = map[string]string{
+	"mp3":  "audio/mpeg",
+	"flac": "audio/flac",
+	"ogg":  "audio/ogg",
+	"opus": "audio/ogg",
+	"m4a":  "audio/mp4",
+	"m4b":  "audio/mp4",
+	"aac":  "audio/aac",
+	"wav":  "audio/wav",
+	"aiff": "audio/aiff",
+	"aif":  "audio/aiff",
+	"wma":  "audio/x-ms-wma",
+	"ape":  "audio/x-ape",
+	"wv":   "audio/x-wavpack",
+	"dsf":  "audio/x-dsf",
+	"dff":  "audio/x-dff",
+}
+
+// Subsonic serves the part of the Subsonic API that players need to browse and stream.
+func (h *Handler) Subsonic(w http.ResponseWriter, r *http.Request) {
+	method := strings.TrimSuffix(r.PathValue("method"), ".view")
+	if h.library == nil || h.subsonicPassword == "" {
+		writeError(w, http.StatusNotFound, model.ErrorCodeNotFound, "The Subsonic API is disabled")
+		return
+	}
+	if code, message := h.subsonicAuthenticate(r); code != 0 {
+		writeSubsonicError(w, r, code, message)
+		return
+	}
+
+	switch method {
+	case "ping":
+		writeSubsonic(w, r, newSubsonicResponse())
+	case "getLicense":
+		response := newSubsonicResponse()
+		response.License = &subsonicLicense{Valid: true}
+		writeSubsonic(w, r, response)
+	case "getMusicFolders":
+		response := newSubsonicResponse()
+		response.MusicFolders = &subsonicMusicFolders{
+			MusicFolder: []subsonicMusicFolder{{ID: subsonicMusicFolderID, Name: path.Base(h.library.Root())}},
+		}
+		writeSubsonic(w, r, response)
+	case "getIndexes":
+		h.subsonicIndexes(w, r)
+	case "getMusicDirectory":
+		h.subsonicMusicDirectory(w, r)
+	case "getCoverArt":
+		h.subsonicCoverArt(w, r)
+	case "stream", "download":
+		h.subsonicStream(w, r)
+	default:
+		writeSubsonicError(w, r, subsonicErrGeneric, "Unsupported method "+method)
+	}
+}
+
+func (h *Handler) subsonicAuthenticate(r *http.Request) (int, string) {
+	user, password, token, salt := r.FormValue("u"), r.FormValue("p"), r.FormValue("t"), r.FormValue("s")
+	if user == "" || (password == "" && (token == "" || salt == "")) {
+		return subsonicErrMissingParam, "Required parameter is missing"
+	}
+
+	var ok bool
+	switch {
+	case token != "":
+		sum := md5.Sum([]byte(h.subsonicPassword + salt))
+		ok = subtle.ConstantTimeCompare([]byte(strings.ToLower(token)), []byte(hex.EncodeToString(sum[:]))) == 1
+	case strings.HasPrefix(password, "enc:"):
+		decoded, err := hex.DecodeString(strings.TrimPrefix(password, "enc:"))
+		ok = err == nil && subtle.ConstantTimeCompare(decoded, []byte(h.subsonicPassword)) == 1
+	default:
+		ok = subtle.ConstantTimeCompare([]byte(password), []byte(h.subsonicPassword)) == 1
+	}
+	if subtle.ConstantTimeCompare([]byte(user), []byte(h.subsonicUser)) != 1 || !ok {
+		return subsonicErrWrongAuth, "Wrong username or password"
+	}
+	return 0, ""
+}
+
+func (h *Handler) subsonicIndexes(w http.ResponseWriter, r *http.Request) {
+	files := h.library.List()
+	dirs, children := subsonicListing(files, "")
+
+	groups := map[string][]subsonicArtist{}
+	for _, dir := range dirs {
+		letter := "#"
+		if first, _ := utf8.DecodeRuneInString(dir.Title); unicode.IsLetter(first) {
+			letter = string(unicode.ToUpper(first))
+		}
+		groups[letter] = append(groups[letter], subsonicArtist{ID: dir.ID, Name: dir.Title})
+	}
+	indexes := &subsonicIndexes{
+		LastModified: time.Now().UnixMilli(),
+		Index:        []subsonicIndex{},
+		Child:        children,
+	}
+	for letter, artists := range groups {
+		indexes.Index = append(indexes.Index, subsonicIndex{Name: letter, Artist: artists})
+	}
+	sort.Slice(indexes.Index, func(i, j int) bool { return indexes.Index[i].Name < indexes.Index[j].Name })
+
+	response := newSubsonicResponse()
+	response.Indexes = indexes
+	writeSubsonic(w, r, response)
+}
+
+func (h *Handler) subsonicMusicDirectory(w http.ResponseWriter, r *http.Request) {
+	id := r.FormValue("id")
+	if id == "" {
+		writeSubsonicError(w, r, subsonicErrMissingParam, "Required parameter is missing: id")
+		return
+	}
+	dir, ok := subsonicDirPath(id)
+	if !ok {
+		writeSubsonicError(w, r, subsonicErrNotFound, "Directory not found")
+		return
+	}
+
+	dirs, files := subsonicListing(h.library.List(), dir)
+	if dir != "" && len(dirs) == 0 && len(files) == 0 {
+		writeSubsonicError(w, r, subsonicErrNotFound, "Directory not found")
+		return
+	}
+	directory := &subsonicDirectory{ID: id, Name: path.Base(dir), Child: append(dirs, files...)}
+	if dir == "" {
+		directory.Name = path.Base(h.library.Root())
+	} else {
+		directory.Parent = subsonicDirID(parentDir(dir))
+	}
+
+	response := newSubsonicResponse()
+	response.Directory = directory
+	writeSubsonic(w, r, response)
+}
+
+func (h *Handler) subsonicCoverArt(w http.ResponseWriter, r *http.Request) {
+	id := r.FormValue("id")
+	if id == "" {
+		writeSubsonicError(w, r, subsonicErrMissingParam, "Required parameter is missing: id")
+		return
+	}
+	if dir, ok := subsonicDirPath(id); ok {
+		dirs, files := subsonicListing(h.library.List(), dir)
+		id = ""
+		for _, child := range append(files, dirs...) {
+			if child.CoverArt != "" {
+				id = child.CoverArt
+				break
+			}
+		}
+	}
+	stored, err := h.library.Get(id)
+	if err != nil {
+		writeSubsonicError(w, r, subsonicErrNotFound, "Cover art not found")
+		return
+	}
+
+	data, _, err := h.audioService.ExtractCoverArt(r.Context(), stored.Path)
+	if errors.Is(err, model.ErrNoCoverArt) {
+		writeSubsonicError(w, r, subsonicErrNotFound, "Cover art not found")
+		return
+	}
+	if err != nil {
+		logs.ErrorContext(r.Context(), "Handler.Subsonic: Failed to extract cover art", err)
+		writeSubsonicError(w, r, subsonicErrGeneric, "Failed to read cover art")
+		return
+	}
+
+	var opts imaging.Options
+	if size, err := strconv.Atoi(r.FormValue("size")); err == nil && size > 0 {
+		opts.Size = min(size, imaging.MaxSize)
+	}
+	rendered, mimeType, err := imaging.Render(data, opts)
+	if err != nil {
+		logs.ErrorContext(r.Context(), "Handler.Subsonic: Failed to render cover art", err)
+		writeSubsonicError(w, r, subsonicErrGeneric, "Failed to render cover art")
+		return
+	}
+	w.Header().Set("Content-Type", mimeType)
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(rendered))
+}
+
+func (h *Handler) subsonicStream(w http.ResponseWriter, r *http.Request) {
+	id := r.FormValue("id")
+	if id == "" {
+		writeSubsonicError(w, r, subsonicErrMissingParam, "Required parameter is missing: id")
+		return
+	}
+	stored, err := h.library.Get(id)
+	if err != nil {
+		writeSubsonicError(w, r, subsonicErrNotFound, "Song not found")
+		return
+	}
+
+	file, err := h.fs.Open(stored.Path)
+	if err != nil {
+		logs.ErrorContext(r.Context(), "Handler.Subsonic: Failed to open file", err)
+		writeSubsonicError(w, r, subsonicErrNotFound, "Song not found")
+		return
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil {
+		logs.ErrorContext(r.Context(), "Handler.Subsonic: Failed to stat file", err)
+		writeSubsonicError(w, r, subsonicErrGeneric, "Failed to read song")
+		return
+	}
+
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		logs.ErrorContext(r.Context(), "Handler.Subsonic: Failed to clear write deadline", err)
+	}
+	w.Header().Set("Content-Type", subsonicContentType(stored.Path))
+	http.ServeContent(w, r, "", stat.ModTime(), file)
+}
+
+func subsonicListing(files []model.LibraryFile, dir string) ([]subsonicChild, []subsonicChild) {
+	prefix := ""
+	if dir != "" {
+		prefix = dir + "/"
+	}
+
+	var dirs, songs []subsonicChild
+	seen := map[string]int{}
+	for _, file := range files {
+		if !strings.HasPrefix(file.Path, prefix) {
+			continue
+		}
+		rest := strings.TrimPrefix(file.Path, prefix)
+		name, _, nested := strings.Cut(rest, "/")
+		if !nested {
+			songs = append(songs, subsonicSong(file, dir))
+			continue
+		}
+
+		i, ok := seen[name]
+		if !ok {
+			i = len(dirs)
+			seen[name] = i
+			dirs = append(
+				dirs, subsonicChild{
+					ID: subsonicDirID(prefix + name), Parent: subsonicDirID(dir), IsDir: true, Title: name,
+					Album: file.Album, Artist: file.AlbumArtist,
+				},
+			)
+			if dirs[i].Artist == "" {
+				dirs[i].Artist = file.Artist
+			}
+		}
+		if dirs[i].CoverArt == "" && file.HasCoverArt {
+			dirs[i].CoverArt = file.ID
+		}
+	}
+	return dirs, songs
+}
+
+func subsonicSong(file model.LibraryFile, dir string) subsonicChild {
+	title := file.Title
+	if title == "" {
+		title = strings.TrimSuffix(path.Base(file.Path), path.Ext(file.Path))
+	}
+	song := subsonicChild{
+		ID:          file.ID,
+		Parent:      subsonicDirID(dir),
+		Title:       title,
+		Album:       file.Album,
+		Artist:      file.Artist,
+		Track:       file.Track,
+		DiscNumber:  file.Disc,
+		Year:        file.Year,
+		Genre:       file.Genre,
+		Size:        file.Size,
+		ContentType: subsonicContentType(file.Path),
+		Suffix:      strings.TrimPrefix(strings.ToLower(path.Ext(file.Path)), "."),
+		Duration:    int(file.Duration),
+		BitRate:     file.Bitrate,
+		Path:        file.Path,
+		Type:        "music",
+	}
+	if file.HasCoverArt {
+		song.CoverArt = file.ID
+	}
+	return song
+}
+
+func subsonicDirID(dir string) string {
+	if dir == "" {
+		return strconv.Itoa(subsonicMusicFolderID)
+	}
+	return subsonicDirPrefix + base64.RawURLEncoding.EncodeToString([]byte(dir))
+}
+
+func subsonicDirPath(id string) (string, bool) {
+	if id == strconv.Itoa(subsonicMusicFolderID) {
+		return "", true
+	}
+	encoded, ok := strings.CutPrefix(id, subsonicDirPrefix)
+	if !ok {
+		return "", false
+	}
+	dir, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", false
+	}
+	return string(dir), true
+}
+
+func parentDir(dir string) string {
+	parent := path.Dir(dir)
+	if parent == "." {
+		return ""
+	}
+	return parent
+}
+
+func subsonicContentType(filePath string) string {
+	ext := strings.ToLower(path.Ext(filePath))
+	if contentType, ok := audioContentTypes[strings.TrimPrefix(ext, ".")]; ok {
+		return contentType
+	}
+	if contentType := mime.TypeByExtension(ext); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
+
+func newSubsonicResponse() *subsonicResponse {
+	return &subsonicResponse{
+		Xmlns:        "http://subsonic.org/restapi",
+		Status:       "ok",
+		Version:      subsonicVersion,
+		Type:         "audio-tag-editor",
+		OpenSubsonic: true,
+	}
+}
+
+func writeSubsonicError(w http.ResponseWriter, r *http.Request, code int, message string) {
+	response := newSubsonicResponse()
+	response.Status = "failed"
+	response.Error = &subsonicError{Code: code, Message: message}
+	writeSubsonic(w, r, response)
+}
+
+func writeSubsonic(w http.ResponseWriter, r *http.Request, response *subsonicResponse) {
+	var err error
+	if r.FormValue("f") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		err = json.NewEncoder(w).Encode(map[string]*subsonicResponse{"subsonic-response": response})
+	} else {
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		w.Write([]byte(xml.Header))
+		err = xml.NewEncoder(w).Encode(response)
+	}
+	if err != nil {
+		logs.ErrorContext(r.Context(), "Handler.Subsonic: Failed to encode response", err)
+	}
+}
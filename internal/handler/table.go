@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/internal/tagtable"
+	"github.com/iamvkosarev/audio-tag-editor/pkg/logs"
+)
+
+func tableFormat(r *http.Request) (string, error) {
+	switch format := strings.ToLower(r.URL.Query().Get("format")); format {
+	case "csv", "json":
+		return format, nil
+	case "":
+		if strings.Contains(r.Header.Get("Content-Type"), "csv") {
+			return "csv", nil
+		}
+		return "json", nil
+	default:
+		return "", fmt.Errorf("format must be csv or json")
+	}
+}
+
+// Export returns the tags of every stored file as a CSV or JSON table.
+func (h *Handler) Export(w http.ResponseWriter, r *http.Request) {
+	format, err := tableFormat(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	storedFiles, err := h.storage.List()
+	if err != nil {
+		logs.ErrorContext(r.Context(), "Handler.Export: Failed to list files", err)
+		writeError(w, http.StatusInternalServerError, model.ErrorCodeInternal, "Failed to list files")
+		return
+	}
+	sortByUpload(storedFiles)
+
+	rows := make([]tagtable.Row, 0, len(storedFiles))
+	for _, stored := range storedFiles {
+		if stored.Metadata == nil {
+			continue
+		}
+		tags := stored.Metadata.TagUpdate()
+		// Untitled files show their file name as the title, which is not a tag.
+		if *tags.Title == stored.Filename {
+			untitled := ""
+			tags.Title = &untitled
+		}
+		rows = append(rows, tagtable.Row{ID: stored.ID, Filename: stored.Filename, TagUpdate: tags})
+	}
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="tags.csv"`)
+		err = tagtable.WriteCSV(w, rows)
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", `attachment; filename="tags.json"`)
+		err = json.NewEncoder(w).Encode(rows)
+	}
+	if err != nil {
+		logs.ErrorContext(r.Context(), "Handler.Export: Failed to encode response", err)
+	}
+}
+
+// Import applies an edited export as one batch update.
+func (h *Handler) Import(w http.ResponseWriter, r *http.Request) {
+	format, err := tableFormat(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+	var rows []tagtable.Row
+	if format == "csv" {
+		rows, err = tagtable.ReadCSV(r.Body)
+	} else {
+		rows, err = tagtable.ReadJSON(r.Body)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+	if len(rows) == 0 {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "No rows provided")
+		return
+	}
+
+	storedFiles, err := h.storage.List()
+	if err != nil {
+		logs.ErrorContext(r.Context(), "Handler.Import: Failed to list files", err)
+		writeError(w, http.StatusInternalServerError, model.ErrorCodeInternal, "Failed to list files")
+		return
+	}
+	byFilename := make(map[string][]string)
+	for _, stored := range storedFiles {
+		byFilename[stored.Filename] = append(byFilename[stored.Filename], stored.ID)
+	}
+
+	req := TagUpdateRequest{Files: make(map[string]model.TagUpdate, len(rows))}
+	var rowErrors []model.APIError
+	for i, row := range rows {
+		fileID := row.ID
+		if fileID == "" {
+			switch ids := byFilename[row.Filename]; len(ids) {
+			case 0:
+				rowErrors = append(
+					rowErrors, model.APIError{
+						Code: model.ErrorCodeFileNotFound, Message: "No file named " + row.Filename,
+						Field: fmt.Sprintf("rows[%d]", i),
+					},
+				)
+				continue
+			case 1:
+				fileID = ids[0]
+			default:
+				rowErrors = append(
+					rowErrors, model.APIError{
+						Code: model.ErrorCodeConflict, Message: "Several files are named " + row.Filename,
+						Field: fmt.Sprintf("rows[%d]", i),
+					},
+				)
+				continue
+			}
+		}
+		if _, ok := req.Files[fileID]; !ok {
+			req.FileIds = append(req.FileIds, fileID)
+		}
+		req.Files[fileID] = req.Files[fileID].Merge(row.TagUpdate)
+	}
+
+	importRows := func(ctx context.Context, step func(int, string)) *filesResult {
+		result := h.updateTags(ctx, &req, req.FileIds, step)
+		result.Errors = append(rowErrors, result.Errors...)
+		return result
+	}
+
+	if isAsync(r) {
+		h.submitJob(
+			w, r, "import", len(req.FileIds), func(ctx context.Context, step func(int, string)) (any, error) {
+				return importRows(ctx, step), nil
+			},
+		)
+		return
+	}
+
+	progress := h.progressFor(r, len(req.FileIds))
+	result := importRows(r.Context(), progress.step)
+	progress.finish()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		logs.ErrorContext(r.Context(), "Handler.Import: Failed to encode response", err)
+	}
+}
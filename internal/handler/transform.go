@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/internal/transform"
+	"github.com/iamvkosarev/audio-tag-editor/pkg/logs"
+)
+
+type TransformRequest struct {
+	FileIds []string         `json:"fileIds"`
+	Fields  []string         `json:"fields"` // text fields to transform, see CopyFields
+	Steps   []transform.Step `json:"steps"`  // applied in order
+	DryRun  bool             `json:"dryRun"` // preview the changes without writing them
+}
+
+// Transform rewrites the chosen text fields of the given files.
+func (h *Handler) Transform(w http.ResponseWriter, r *http.Request) {
+	var req TransformRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "Invalid request body")
+		return
+	}
+	if len(req.FileIds) == 0 {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "No file IDs provided")
+		return
+	}
+	if len(req.Fields) == 0 {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "No fields provided")
+		return
+	}
+	for i, field := range req.Fields {
+		if err := model.ValidateTextField(field); err != nil {
+			writeAPIError(
+				w, http.StatusBadRequest, &model.APIError{
+					Code: model.ErrorCodeInvalidRequest, Message: err.Error(), Field: fmt.Sprintf("fields[%d]", i),
+				},
+			)
+			return
+		}
+	}
+	if len(req.Steps) == 0 {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "No steps provided")
+		return
+	}
+	t, err := transform.New(req.Steps)
+	if err != nil {
+		writeAPIError(
+			w, http.StatusBadRequest, &model.APIError{Code: model.ErrorCodeInvalidRequest, Message: err.Error(), Field: "steps"},
+		)
+		return
+	}
+
+	transformed := func(ctx context.Context, stored *model.StoredFile) (*model.FileMetadata, *model.FileMetadata, error) {
+		current, err := h.currentTags(ctx, stored)
+		if err != nil {
+			return nil, nil, err
+		}
+		changed := *current
+		for _, field := range req.Fields {
+			changed.SetTextField(field, t.Apply(changed.TextField(field)))
+		}
+		return current, &changed, nil
+	}
+
+	if req.DryRun {
+		preview := h.previewChanges(
+			r.Context(), "Transform", req.FileIds,
+			func(ctx context.Context, stored *model.StoredFile) (map[string]fieldChange, error) {
+				current, changed, err := transformed(ctx, stored)
+				if err != nil {
+					return nil, err
+				}
+				fields := make(map[string]fieldChange)
+				for _, name := range model.TextFields {
+					if from, to := current.TextField(name), changed.TextField(name); from != to {
+						fields[name] = fieldChange{From: from, To: to}
+					}
+				}
+				return fields, nil
+			},
+		)
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(preview); err != nil {
+			logs.ErrorContext(r.Context(), "Handler.Transform: Failed to encode response", err)
+		}
+		return
+	}
+
+	build := func(ctx context.Context, stored *model.StoredFile) (*model.TagUpdate, error) {
+		current, changed, err := transformed(ctx, stored)
+		if err != nil {
+			return nil, err
+		}
+		return changed.TextChanges(current), nil
+	}
+
+	if isAsync(r) {
+		h.submitJob(
+			w, r, "transform", len(req.FileIds), func(ctx context.Context, step func(int, string)) (any, error) {
+				return h.rewriteTags(ctx, "Transform", req.FileIds, step, build), nil
+			},
+		)
+		return
+	}
+
+	progress := h.progressFor(r, len(req.FileIds))
+	result := h.rewriteTags(r.Context(), "Transform", req.FileIds, progress.step, build)
+	progress.finish()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		logs.ErrorContext(r.Context(), "Handler.Transform: Failed to encode response", err)
+	}
+}
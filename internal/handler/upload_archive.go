@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"path"
+	"strings"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+)
+
+const maxZipEntries = 10_000
+
+var junkNames = map[string]bool{"thumbs.db": true, "desktop.ini": true}
+
+func isJunk(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if part == "__MACOSX" || strings.HasPrefix(part, ".") {
+			return true
+		}
+	}
+	return junkNames[strings.ToLower(path.Base(name))]
+}
+
+func relativePath(name string) (string, error) {
+	cleaned := path.Clean(strings.ReplaceAll(name, `\`, "/"))
+	if path.IsAbs(cleaned) || cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("invalid path %q", name)
+	}
+	return cleaned, nil
+}
+
+func isZip(filename string) bool {
+	return strings.EqualFold(path.Ext(filename), ".zip")
+}
+
+func (h *Handler) checkZipUploads(files []*multipart.FileHeader) error {
+	var total uint64
+	for _, fileHeader := range files {
+		if !isZip(fileHeader.Filename) {
+			continue
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			continue
+		}
+		archive, err := zip.NewReader(file, fileHeader.Size)
+		file.Close()
+		if err != nil {
+			continue
+		}
+		if len(archive.File) > maxZipEntries {
+			return fmt.Errorf("%s has more than the maximum of %d entries", fileHeader.Filename, maxZipEntries)
+		}
+		for _, entry := range archive.File {
+			total += entry.UncompressedSize64
+			if h.maxUploadSize > 0 && total > uint64(h.maxUploadSize) {
+				return fmt.Errorf("the archives unpack to more than the maximum of %d bytes per request", h.maxUploadSize)
+			}
+		}
+	}
+	return nil
+}
+
+func (h *Handler) storeZipUpload(ctx context.Context, fileHeader *multipart.FileHeader) ([]*model.FileMetadata, []error) {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, []error{fmt.Errorf("failed to open uploaded file: %w", err)}
+	}
+	defer file.Close()
+
+	archive, err := zip.NewReader(file, fileHeader.Size)
+	if err != nil {
+		return nil, []error{fmt.Errorf("%w: failed to read ZIP archive: %w", model.ErrUnsupportedFormat, err)}
+	}
+
+	var stored []*model.FileMetadata
+	var failures []error
+	for _, entry := range archive.File {
+		if err := ctx.Err(); err != nil {
+			return stored, append(failures, err)
+		}
+		if entry.FileInfo().IsDir() || isJunk(entry.Name) || !h.audioService.ReadableExtension(path.Ext(entry.Name)) {
+			continue
+		}
+		metadata, err := h.storeZipEntry(ctx, entry)
+		if err != nil {
+			failures = append(failures, fmt.Errorf("%s: %w", entry.Name, err))
+			continue
+		}
+		stored = append(stored, metadata)
+	}
+	if len(stored) == 0 && len(failures) == 0 {
+		return nil, []error{fmt.Errorf("%w: the archive has no audio files", model.ErrUnsupportedFormat)}
+	}
+	return stored, failures
+}
+
+func (h *Handler) storeZipEntry(ctx context.Context, entry *zip.File) (*model.FileMetadata, error) {
+	name, err := relativePath(entry.Name)
+	if err != nil {
+		return nil, err
+	}
+	if h.maxFileSize > 0 && entry.UncompressedSize64 > uint64(h.maxFileSize) {
+		return nil, fmt.Errorf("larger than the maximum of %d bytes per file", h.maxFileSize)
+	}
+
+	src, err := entry.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive entry: %w", err)
+	}
+	defer src.Close()
+
+	tempFile, err := h.fs.CreateTemp("", "audio-*"+path.Ext(name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	size, err := io.Copy(tempFile, src)
+	if err != nil {
+		tempFile.Close()
+		h.fs.Remove(tempFile.Name())
+		return nil, fmt.Errorf("failed to extract archive entry: %w", err)
+	}
+
+	metadata, err := h.audioService.ParseReader(ctx, tempFile, size, path.Base(name))
+	if closeErr := tempFile.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to extract archive entry: %w", closeErr)
+	}
+	if err == nil {
+		err = h.storeFile(ctx, tempFile.Name(), name, metadata)
+	}
+	if err != nil {
+		h.fs.Remove(tempFile.Name())
+		return nil, err
+	}
+	return metadata, nil
+}
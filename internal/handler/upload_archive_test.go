@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func zipUpload(t *testing.T, entries map[string]int) (*bytes.Buffer, string) {
+	t.Helper()
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	for name, size := range entries {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(make([]byte, size)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("files", "album.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(archive.Bytes())
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &body, mw.FormDataContentType()
+}
+
+// Archives that unpack to more than an upload may carry are refused.
+func TestUploadRefusesZipBombs(t *testing.T) {
+	manyEntries := make(map[string]int, maxZipEntries+1)
+	for i := range maxZipEntries + 1 {
+		manyEntries[fmt.Sprintf("%05d.mp3", i)] = 0
+	}
+	tests := []struct {
+		name    string
+		entries map[string]int
+	}{
+		{name: "one large entry", entries: map[string]int{"a.mp3": 8 << 20}},
+		{name: "entries adding up", entries: map[string]int{"a.mp3": 3 << 20, "b.mp3": 3 << 20}},
+		{name: "too many entries", entries: manyEntries},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := New(
+				nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil,
+				Options{MaxUploadSize: 4 << 20},
+			)
+			body, contentType := zipUpload(t, tt.entries)
+			if body.Len() >= 4<<20 {
+				t.Fatalf("upload of %d bytes is over the limit itself", body.Len())
+			}
+			r := httptest.NewRequest(http.MethodPost, "/api/upload", body)
+			r.Header.Set("Content-Type", contentType)
+			w := httptest.NewRecorder()
+
+			h.Upload(w, r)
+			if w.Code != http.StatusRequestEntityTooLarge {
+				t.Errorf("status = %d, want 413: %s", w.Code, w.Body)
+			}
+		})
+	}
+}
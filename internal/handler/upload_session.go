@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/pkg/logs"
+)
+
+type CreateUploadRequest struct {
+	Filename string `json:"filename"`
+	Size     int64  `json:"size"`
+}
+
+// UploadResponse describes the state of a chunked upload.
+type UploadResponse struct {
+	*model.UploadSession
+	File *model.FileMetadata `json:"file,omitempty"`
+}
+
+// CreateUpload starts a resumable upload.
+func (h *Handler) CreateUpload(w http.ResponseWriter, r *http.Request) {
+	var req CreateUploadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "Invalid request body")
+		return
+	}
+	if req.Filename == "" || req.Size <= 0 {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "Filename and a positive size are required")
+		return
+	}
+
+	session, err := h.uploadSessions.Create(req.Filename, req.Size)
+	if errors.Is(err, model.ErrUploadTooLarge) {
+		writeError(
+			w, http.StatusRequestEntityTooLarge, model.ErrorCodeTooLarge,
+			fmt.Sprintf("%s is larger than the maximum of %d bytes per file", req.Filename, h.maxFileSize),
+		)
+		return
+	}
+	if err != nil {
+		logs.ErrorContext(r.Context(), "Handler.CreateUpload: Failed to create upload session", err)
+		writeError(w, http.StatusInternalServerError, model.ErrorCodeInternal, "Failed to create upload session")
+		return
+	}
+
+	w.Header().Set("Location", "/api/uploads/"+session.ID)
+	writeUploadResponse(w, http.StatusCreated, UploadResponse{UploadSession: session})
+}
+
+func (h *Handler) UploadStatus(w http.ResponseWriter, r *http.Request) {
+	session, err := h.uploadSessions.Get(r.PathValue("id"))
+	if err != nil {
+		h.writeUploadError(w, "Handler.UploadStatus", err)
+		return
+	}
+	writeUploadResponse(w, http.StatusOK, UploadResponse{UploadSession: session})
+}
+
+// UploadChunk appends the request body at the offset given in the Upload-Offset header.
+func (h *Handler) UploadChunk(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "Upload-Offset header is required")
+		return
+	}
+
+	session, err := h.uploadSessions.Get(id)
+	if err != nil {
+		h.writeUploadError(w, "Handler.UploadChunk", err)
+		return
+	}
+	if r.ContentLength > session.Size-offset {
+		writeError(w, http.StatusRequestEntityTooLarge, model.ErrorCodeTooLarge, "Chunk exceeds the declared file size")
+		return
+	}
+
+	session, err = h.uploadSessions.Append(id, offset, r.Body)
+	if errors.Is(err, model.ErrUploadOffsetMismatch) {
+		writeUploadResponse(w, http.StatusConflict, UploadResponse{UploadSession: session})
+		return
+	}
+	if err != nil {
+		h.writeUploadError(w, "Handler.UploadChunk", err)
+		return
+	}
+
+	response := UploadResponse{UploadSession: session}
+	if session.Complete() {
+		response.File, err = h.finishUpload(r.Context(), id)
+		if err != nil {
+			logs.ErrorContext(r.Context(), "Handler.UploadChunk: Failed to finish upload", err)
+			writeError(
+				w, http.StatusUnprocessableEntity, errorCode(err),
+				fmt.Sprintf("Failed to process %s: %v", session.Filename, err),
+			)
+			return
+		}
+	}
+	writeUploadResponse(w, http.StatusOK, response)
+}
+
+func (h *Handler) CancelUpload(w http.ResponseWriter, r *http.Request) {
+	if err := h.uploadSessions.Delete(r.PathValue("id")); err != nil {
+		h.writeUploadError(w, "Handler.CancelUpload", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) finishUpload(ctx context.Context, id string) (*model.FileMetadata, error) {
+	session, path, err := h.uploadSessions.Finish(id)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := h.fs.Open(path)
+	if err != nil {
+		h.fs.Remove(path)
+		return nil, fmt.Errorf("failed to open uploaded file: %w", err)
+	}
+	metadata, err := h.audioService.ParseReader(ctx, file, session.Size, filepath.Base(session.Filename))
+	file.Close()
+	if err != nil {
+		h.fs.Remove(path)
+		return nil, err
+	}
+
+	if err := h.storeFile(ctx, path, session.Filename, metadata); err != nil {
+		h.fs.Remove(path)
+		return nil, err
+	}
+	return metadata, nil
+}
+
+func (h *Handler) writeUploadError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, model.ErrUploadNotFound):
+		writeError(w, http.StatusNotFound, model.ErrorCodeNotFound, "Upload not found")
+	case errors.Is(err, model.ErrUploadBusy):
+		writeError(w, http.StatusConflict, model.ErrorCodeConflict, "Upload is receiving another chunk")
+	default:
+		logs.Error(op+": Upload failed", err)
+		writeError(w, http.StatusInternalServerError, model.ErrorCodeInternal, "Upload failed")
+	}
+}
+
+func writeUploadResponse(w http.ResponseWriter, status int, response UploadResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Upload-Offset", strconv.FormatInt(response.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(response.Size, 10))
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logs.Error("Handler.writeUploadResponse: Failed to encode response", err)
+	}
+}
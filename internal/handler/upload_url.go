@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/internal/service/urlimport"
+	"github.com/iamvkosarev/audio-tag-editor/pkg/logs"
+)
+
+type UploadFromURLRequest struct {
+	URL string `json:"url"`
+}
+
+// UploadFromURL downloads the audio file a link leads to and stores it like an uploaded file.
+func (h *Handler) UploadFromURL(w http.ResponseWriter, r *http.Request) {
+	if h.urlFetcher == nil {
+		writeError(w, http.StatusServiceUnavailable, model.ErrorCodeUnavailable, "Imports from URLs are turned off")
+		return
+	}
+
+	var req UploadFromURLRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "Invalid request body")
+		return
+	}
+	if req.URL == "" {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "URL is required")
+		return
+	}
+
+	if isAsync(r) {
+		h.submitJob(
+			w, r, "upload-from-url", 1, func(ctx context.Context, step func(int, string)) (any, error) {
+				step(0, req.URL)
+				metadata, err := h.importURL(ctx, req.URL)
+				if err != nil {
+					return nil, err
+				}
+				step(1, req.URL)
+				return filesResult{Files: []model.FileMetadata{*metadata}}, nil
+			},
+		)
+		return
+	}
+
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		logs.ErrorContext(r.Context(), "Handler.UploadFromURL: Failed to clear write deadline", err)
+	}
+	metadata, err := h.importURL(r.Context(), req.URL)
+	if err != nil {
+		status, code := importErrorStatus(err)
+		if status == http.StatusBadGateway {
+			logs.ErrorContext(r.Context(), "Handler.UploadFromURL: Download failed", err)
+		}
+		writeError(w, status, code, fmt.Sprintf("Failed to import %s: %v", req.URL, err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(filesResult{Files: []model.FileMetadata{*metadata}})
+}
+
+func (h *Handler) importURL(ctx context.Context, rawURL string) (*model.FileMetadata, error) {
+	tempFile, err := h.fs.CreateTemp("", "audio-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tempPath := tempFile.Name()
+
+	name, err := h.urlFetcher.Fetch(ctx, rawURL, tempFile)
+	if err != nil {
+		tempFile.Close()
+		h.fs.Remove(tempPath)
+		return nil, err
+	}
+	info, err := tempFile.Stat()
+	if err != nil {
+		tempFile.Close()
+		h.fs.Remove(tempPath)
+		return nil, fmt.Errorf("failed to read downloaded file: %w", err)
+	}
+
+	metadata, err := h.audioService.ParseReader(ctx, tempFile, info.Size(), name)
+	tempFile.Close()
+	if err != nil {
+		h.fs.Remove(tempPath)
+		return nil, err
+	}
+
+	if path.Ext(name) == "" && metadata.Format != "" {
+		name += "." + strings.ToLower(metadata.Format)
+	}
+	// Tags are written by the extension of the stored file, like for uploads.
+	if err := h.fs.Rename(tempPath, tempPath+path.Ext(name)); err != nil {
+		h.fs.Remove(tempPath)
+		return nil, fmt.Errorf("failed to rename downloaded file: %w", err)
+	}
+	tempPath += path.Ext(name)
+	if err := h.storeFile(ctx, tempPath, name, metadata); err != nil {
+		h.fs.Remove(tempPath)
+		return nil, err
+	}
+	return metadata, nil
+}
+
+func importErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, urlimport.ErrInvalidURL):
+		return http.StatusBadRequest, model.ErrorCodeInvalidRequest
+	case errors.Is(err, urlimport.ErrTooLarge):
+		return http.StatusRequestEntityTooLarge, model.ErrorCodeTooLarge
+	case errors.Is(err, urlimport.ErrNotAudio):
+		return http.StatusUnsupportedMediaType, model.ErrorCodeUnsupportedFormat
+	}
+	if code := errorCode(err); code != model.ErrorCodeInternal {
+		return errorStatus(err), code
+	}
+	if errors.Is(err, urlimport.ErrDownloadFailed) {
+		return http.StatusBadGateway, model.ErrorCodeUpstream
+	}
+	return http.StatusInternalServerError, model.ErrorCodeInternal
+}
@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/internal/service/integrity"
+	"github.com/iamvkosarev/audio-tag-editor/pkg/logs"
+)
+
+type verifyResult struct {
+	FileID string `json:"fileId"`
+	model.Checksums
+	// UploadAudioSHA256 is the audio hash from before any edit.
+	UploadAudioSHA256 string `json:"uploadAudioSha256,omitempty"`
+	AudioUnchanged    *bool  `json:"audioUnchanged,omitempty"`
+	// DecodedMD5 is the MD5 of the decoded samples of a FLAC file.
+	DecodedMD5      string `json:"decodedMd5,omitempty"`
+	StreamInfoMatch *bool  `json:"streamInfoMatch,omitempty"`
+	DecodeError     string `json:"decodeError,omitempty"`
+}
+
+// Verify returns the checksums of a file and whether its audio is unchanged.
+func (h *Handler) Verify(w http.ResponseWriter, r *http.Request) {
+	decode := true
+	if value := r.URL.Query().Get("decode"); value != "" {
+		var err error
+		if decode, err = strconv.ParseBool(value); err != nil {
+			writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, "decode must be true or false")
+			return
+		}
+	}
+
+	fileID := r.PathValue("fileId")
+	stored, err := h.getFile(fileID)
+	if err != nil {
+		writeFileError(w, fileID, err)
+		return
+	}
+
+	checksums, err := h.audioService.Checksums(r.Context(), stored.Path)
+	if err != nil {
+		logs.ErrorContext(r.Context(), "Handler.Verify: Failed to hash file", err)
+		writeError(w, http.StatusInternalServerError, model.ErrorCodeInternal, "Failed to hash file")
+		return
+	}
+
+	result := verifyResult{FileID: fileID, Checksums: *checksums, UploadAudioSHA256: stored.UploadAudioSHA256}
+	if result.UploadAudioSHA256 != "" && result.AudioSHA256 != "" {
+		unchanged := result.UploadAudioSHA256 == result.AudioSHA256
+		result.AudioUnchanged = &unchanged
+	}
+
+	if decode && result.StreamInfoMD5 != "" && stored.Metadata != nil {
+		result.DecodedMD5, err = h.verifier.DecodedMD5(r.Context(), stored.Path, stored.Metadata.BitsPerSample)
+		switch {
+		case errors.Is(err, integrity.ErrDecoderUnavailable), errors.Is(err, integrity.ErrUnsupportedDepth):
+			result.DecodeError = err.Error()
+		case err != nil:
+			// A stream that does not decode fails the check.
+			result.DecodeError = err.Error()
+			match := false
+			result.StreamInfoMatch = &match
+		default:
+			match := result.DecodedMD5 == result.StreamInfoMD5
+			result.StreamInfoMatch = &match
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		logs.ErrorContext(r.Context(), "Handler.Verify: Failed to encode response", err)
+	}
+}
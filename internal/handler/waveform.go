@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"image/png"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/internal/service/waveform"
+	"github.com/iamvkosarev/audio-tag-editor/pkg/logs"
+)
+
+const (
+	defaultWaveformPoints = 800
+	maxWaveformPoints     = 10000
+	defaultWaveformHeight = 100
+	maxWaveformHeight     = 1000
+)
+
+var defaultWaveformColor = color.NRGBA{R: 0x4a, G: 0x55, B: 0x68, A: 0xff}
+
+type waveformResult struct {
+	FileID   string    `json:"fileId"`
+	Duration float64   `json:"duration"` // seconds
+	Peaks    []float64 `json:"peaks"`    // highest absolute sample of each slice, 0 to 1
+}
+
+type waveformOptions struct {
+	points int
+	png    bool
+	height int
+	color  color.NRGBA
+}
+
+func parseWaveformOptions(r *http.Request) (waveformOptions, error) {
+	opts := waveformOptions{points: defaultWaveformPoints, height: defaultWaveformHeight, color: defaultWaveformColor}
+	query := r.URL.Query()
+
+	if value := query.Get("points"); value != "" {
+		points, err := strconv.Atoi(value)
+		if err != nil || points < 1 || points > maxWaveformPoints {
+			return opts, fmt.Errorf("points must be between 1 and %d", maxWaveformPoints)
+		}
+		opts.points = points
+	}
+	switch strings.ToLower(query.Get("format")) {
+	case "", "json":
+	case "png":
+		opts.png = true
+	default:
+		return opts, fmt.Errorf("format must be json or png")
+	}
+	if value := query.Get("height"); value != "" {
+		height, err := strconv.Atoi(value)
+		if err != nil || height < 1 || height > maxWaveformHeight {
+			return opts, fmt.Errorf("height must be between 1 and %d", maxWaveformHeight)
+		}
+		opts.height = height
+	}
+	if value := query.Get("color"); value != "" {
+		rgb, err := hex.DecodeString(strings.TrimPrefix(value, "#"))
+		if err != nil || len(rgb) != 3 {
+			return opts, fmt.Errorf("color must be a hex RGB value such as 4a5568")
+		}
+		opts.color = color.NRGBA{R: rgb[0], G: rgb[1], B: rgb[2], A: 0xff}
+	}
+	return opts, nil
+}
+
+// Waveform returns the peaks of a file as JSON or a PNG image.
+func (h *Handler) Waveform(w http.ResponseWriter, r *http.Request) {
+	opts, err := parseWaveformOptions(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	fileID := r.PathValue("fileId")
+	stored, err := h.getFile(fileID)
+	if err != nil {
+		writeFileError(w, fileID, err)
+		return
+	}
+
+	peaks, err := h.waveformService.Peaks(r.Context(), stored.Path, opts.points)
+	if errors.Is(err, waveform.ErrDecoderUnavailable) {
+		writeError(w, http.StatusServiceUnavailable, model.ErrorCodeUnavailable, "Waveform analysis is not available")
+		return
+	}
+	if err != nil {
+		logs.ErrorContext(r.Context(), "Handler.Waveform: analysis failed", err)
+		writeError(w, http.StatusUnprocessableEntity, errorCode(err), "Waveform analysis failed")
+		return
+	}
+
+	if opts.png {
+		w.Header().Set("Content-Type", "image/png")
+		if err := png.Encode(w, waveform.Render(peaks, opts.height, opts.color)); err != nil {
+			logs.ErrorContext(r.Context(), "Handler.Waveform: Failed to encode image", err)
+		}
+		return
+	}
+
+	result := waveformResult{FileID: fileID, Peaks: make([]float64, len(peaks))}
+	if stored.Metadata != nil {
+		result.Duration = stored.Metadata.Duration
+	}
+	for i, peak := range peaks {
+		result.Peaks[i] = math.Round(peak*1000) / 1000
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		logs.ErrorContext(r.Context(), "Handler.Waveform: Failed to encode response", err)
+	}
+}
@@ -0,0 +1,129 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"strings"
+
+	_ "golang.org/x/image/bmp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	MaxSize        = 4096
+	DefaultQuality = 85
+	// MaxPixels is the largest image, in pixels, that is decoded.
+	MaxPixels = 50_000_000
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	ErrTooLarge          = errors.New("image is too large")
+)
+
+// Options describe how an image is served.
+type Options struct {
+	Size    int    // longest edge in pixels; images are never enlarged
+	Format  string // jpeg, png or webp
+	Quality int    // JPEG quality from 1 to 100
+}
+
+// ParseFormat maps a format name or MIME type to jpeg, png or webp.
+func ParseFormat(value string) (string, error) {
+	switch strings.TrimPrefix(strings.ToLower(value), "image/") {
+	case "":
+		return "", nil
+	case "jpeg", "jpg":
+		return "jpeg", nil
+	case "png":
+		return "png", nil
+	case "webp":
+		return "webp", nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedFormat, value)
+	}
+}
+
+// Render scales and re-encodes an image.
+func Render(data []byte, opts Options) ([]byte, string, error) {
+	mimeType := http.DetectContentType(data)
+	original, _ := ParseFormat(mimeType)
+	format := opts.Format
+	if format == "" {
+		format = original
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode image: %w", err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > MaxPixels {
+		return nil, "", fmt.Errorf("%w: %dx%d pixels", ErrTooLarge, config.Width, config.Height)
+	}
+	width, height := fit(config.Width, config.Height, opts.Size)
+	resize := width != config.Width || height != config.Height
+
+	if !resize && format == original {
+		return data, mimeType, nil
+	}
+	if format == "" {
+		format = "jpeg"
+	}
+	if format == "webp" {
+		return nil, "", fmt.Errorf("%w: cannot encode webp", ErrUnsupportedFormat)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode image: %w", err)
+	}
+	if resize {
+		scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.CatmullRom.Scale(scaled, scaled.Bounds(), img, img.Bounds(), draw.Src, nil)
+		img = scaled
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case "png":
+		err = png.Encode(&buf, img)
+	default:
+		img = flatten(img)
+		quality := opts.Quality
+		if quality <= 0 || quality > 100 {
+			quality = DefaultQuality
+		}
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality})
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode image: %w", err)
+	}
+	return buf.Bytes(), "image/" + format, nil
+}
+
+func flatten(img image.Image) image.Image {
+	if opaque, ok := img.(interface{ Opaque() bool }); ok && opaque.Opaque() {
+		return img
+	}
+	flat := image.NewRGBA(img.Bounds())
+	draw.Draw(flat, flat.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), img, img.Bounds().Min, draw.Over)
+	return flat
+}
+
+func fit(width, height, size int) (int, int) {
+	if size <= 0 || (width <= size && height <= size) {
+		return width, height
+	}
+	if width >= height {
+		return size, max(1, height*size/width)
+	}
+	return max(1, width*size/height), size
+}
@@ -0,0 +1,36 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"testing"
+)
+
+func bombPNG(t *testing.T, width, height uint32) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	binary.BigEndian.PutUint32(data[16:], width)
+	binary.BigEndian.PutUint32(data[20:], height)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+	return data
+}
+
+func TestRenderRefusesHugeImages(t *testing.T) {
+	data := bombPNG(t, 20000, 20000)
+	if _, _, err := Render(data, Options{Size: 300}); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Render = %v, want ErrTooLarge", err)
+	}
+
+	data = bombPNG(t, 1, 1)
+	if _, _, err := Render(data, Options{Format: "jpeg"}); err != nil {
+		t.Errorf("Render of a small image = %v", err)
+	}
+}
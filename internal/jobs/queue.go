@@ -0,0 +1,260 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/pkg/logs"
+)
+
+const sweepInterval = time.Minute
+
+// Func is the work of a job.
+type Func func(ctx context.Context, report func(done, total int, file string)) (any, error)
+
+// Publisher receives the progress events of every job, keyed by job ID.
+type Publisher interface {
+	Publish(jobID string, event model.ProgressEvent)
+}
+
+// Cleaner is implemented by results that own resources to release.
+type Cleaner interface {
+	Cleanup()
+}
+
+// Queue runs jobs on a fixed number of workers.
+type Queue struct {
+	pending   chan *entry
+	workers   int
+	retention time.Duration
+	publisher Publisher
+
+	mu      sync.Mutex
+	entries map[string]*entry
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+type entry struct {
+	job    model.Job
+	fn     Func
+	cancel context.CancelFunc
+}
+
+func NewQueue(workers, queueSize int, retention time.Duration, publisher Publisher) *Queue {
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &Queue{
+		pending:   make(chan *entry, queueSize),
+		workers:   max(workers, 1),
+		retention: retention,
+		publisher: publisher,
+		entries:   make(map[string]*entry),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+
+	for range q.workers {
+		q.wg.Add(1)
+		go q.work()
+	}
+	q.wg.Add(1)
+	go q.sweep()
+
+	return q
+}
+
+// Submit queues fn, which works through total items, and returns the queued job.
+func (q *Queue) Submit(kind string, total int, fn Func) (*model.Job, error) {
+	e := &entry{
+		job: model.Job{
+			ID:        uuid.New().String(),
+			Kind:      kind,
+			Status:    model.JobQueued,
+			Total:     total,
+			CreatedAt: time.Now(),
+		},
+		fn: fn,
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	select {
+	case q.pending <- e:
+	default:
+		return nil, model.ErrJobQueueFull
+	}
+	q.entries[e.job.ID] = e
+
+	job := e.job
+	return &job, nil
+}
+
+func (q *Queue) Get(id string) (*model.Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	e, ok := q.entries[id]
+	if !ok {
+		return nil, model.ErrJobNotFound
+	}
+	job := e.job
+	return &job, nil
+}
+
+// Cancel stops a running job or drops a queued one.
+func (q *Queue) Cancel(id string) (*model.Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	e, ok := q.entries[id]
+	if !ok {
+		return nil, model.ErrJobNotFound
+	}
+	switch e.job.Status {
+	case model.JobQueued:
+		q.finish(e, nil, context.Canceled)
+	case model.JobRunning:
+		e.cancel()
+	}
+	job := e.job
+	return &job, nil
+}
+
+// Shutdown cancels all jobs and waits for the workers to return.
+func (q *Queue) Shutdown(ctx context.Context) error {
+	q.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to stop job workers: %w", ctx.Err())
+	}
+}
+
+func (q *Queue) work() {
+	defer q.wg.Done()
+	for {
+		select {
+		case <-q.ctx.Done():
+			return
+		case e := <-q.pending:
+			q.run(e)
+		}
+	}
+}
+
+func (q *Queue) run(e *entry) {
+	q.mu.Lock()
+	if e.job.Status != model.JobQueued {
+		q.mu.Unlock()
+		return
+	}
+	ctx, cancel := context.WithCancel(q.ctx)
+	defer cancel()
+	now := time.Now()
+	e.cancel = cancel
+	e.job.Status = model.JobRunning
+	e.job.StartedAt = &now
+	q.mu.Unlock()
+
+	report := func(done, total int, file string) {
+		q.mu.Lock()
+		e.job.Done, e.job.Total = done, total
+		q.mu.Unlock()
+		q.publisher.Publish(
+			e.job.ID, model.ProgressEvent{Type: model.ProgressEventProgress, Done: done, Total: total, File: file},
+		)
+	}
+
+	var result any
+	var err error
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				logs.Panic(ctx, "Queue.run: job panicked", r, slog.String("kind", e.job.Kind))
+				err = fmt.Errorf("job panicked: %v", r)
+			}
+		}()
+		result, err = e.fn(ctx, report)
+	}()
+	if ctx.Err() != nil {
+		err = ctx.Err()
+	}
+
+	q.mu.Lock()
+	q.finish(e, result, err)
+	q.mu.Unlock()
+}
+
+func (q *Queue) finish(e *entry, result any, err error) {
+	now := time.Now()
+	e.job.FinishedAt = &now
+	e.job.Result = result
+
+	event := model.ProgressEvent{Type: model.ProgressEventDone, Done: e.job.Total, Total: e.job.Total}
+	switch {
+	case errors.Is(err, context.Canceled):
+		e.job.Status = model.JobCanceled
+		e.job.Error = "job was canceled"
+	case err != nil:
+		e.job.Status = model.JobFailed
+		e.job.Error = err.Error()
+	default:
+		e.job.Status = model.JobSucceeded
+		e.job.Done = e.job.Total
+	}
+	if err != nil {
+		event = model.ProgressEvent{Type: model.ProgressEventError, Done: e.job.Done, Total: e.job.Total, Error: e.job.Error}
+	}
+	q.publisher.Publish(e.job.ID, event)
+}
+
+func (q *Queue) sweep() {
+	defer q.wg.Done()
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-q.ctx.Done():
+			q.mu.Lock()
+			for _, e := range q.entries {
+				cleanup(e)
+			}
+			q.mu.Unlock()
+			return
+		case <-ticker.C:
+		}
+
+		q.mu.Lock()
+		now := time.Now()
+		for id, e := range q.entries {
+			if e.job.Finished() && now.Sub(*e.job.FinishedAt) > q.retention {
+				cleanup(e)
+				delete(q.entries, id)
+			}
+		}
+		q.mu.Unlock()
+	}
+}
+
+func cleanup(e *entry) {
+	if cleaner, ok := e.job.Result.(Cleaner); ok {
+		cleaner.Cleanup()
+	}
+}
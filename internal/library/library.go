@@ -0,0 +1,391 @@
+package library
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/internal/service/audio"
+	"github.com/iamvkosarev/audio-tag-editor/internal/vfs"
+)
+
+type Parser interface {
+	ParseFile(ctx context.Context, filePath string) (*model.FileMetadata, error)
+}
+
+// Library indexes the audio files under a music directory so that they can be edited in place.
+type Library struct {
+	root   string
+	parser Parser
+
+	scanMu   sync.Mutex
+	mu       sync.RWMutex
+	files    map[string]*entry
+	revision int64
+}
+
+type entry struct {
+	file    model.StoredFile
+	relPath string
+	modTime time.Time
+	size    int64
+}
+
+func New(root string, parser Parser) (*Library, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve music directory: %w", err)
+	}
+	info, err := os.Stat(absRoot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open music directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("music directory %s is not a directory", absRoot)
+	}
+
+	return &Library{
+		root:   absRoot,
+		parser: parser,
+		files:  make(map[string]*entry),
+	}, nil
+}
+
+func (l *Library) Root() string {
+	return l.root
+}
+
+// Scan walks the music directory and updates the index.
+func (l *Library) Scan(ctx context.Context) error {
+	l.scanMu.Lock()
+	defer l.scanMu.Unlock()
+
+	l.mu.RLock()
+	previous := make(map[string]*entry, len(l.files))
+	for id, e := range l.files {
+		previous[id] = e
+	}
+	l.mu.RUnlock()
+
+	found := make(map[string]*entry, len(previous))
+	err := filepath.WalkDir(
+		l.root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				slog.Warn("Library.Scan: Skipping unreadable path", slog.String("path", path), slog.Any("error", err))
+				if d != nil && d.IsDir() && path != l.root {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if d.IsDir() || !indexable(path) {
+				return nil
+			}
+
+			e, err := l.index(ctx, path, previous)
+			if err != nil {
+				slog.Warn("Library.Scan: Skipping file", slog.String("path", path), slog.Any("error", err))
+				return nil
+			}
+			found[e.file.ID] = e
+			return nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to scan music directory: %w", err)
+	}
+
+	l.mu.Lock()
+	if changed(previous, found) {
+		l.revision++
+	}
+	l.files = found
+	l.mu.Unlock()
+	return nil
+}
+
+// Refresh brings the index in line with path after it was created, changed or removed on disk.
+func (l *Library) Refresh(ctx context.Context, path string) error {
+	l.scanMu.Lock()
+	defer l.scanMu.Unlock()
+
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		l.remove(path)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	l.mu.RLock()
+	previous := make(map[string]*entry)
+	for id, e := range l.files {
+		if e.file.Path == path || strings.HasPrefix(e.file.Path, path+string(filepath.Separator)) {
+			previous[id] = e
+		}
+	}
+	l.mu.RUnlock()
+
+	found := make(map[string]*entry)
+	if !info.IsDir() {
+		if indexable(path) {
+			e, err := l.index(ctx, path, previous)
+			if err != nil {
+				return err
+			}
+			found[e.file.ID] = e
+		}
+	} else {
+		filepath.WalkDir(
+			path, func(path string, d fs.DirEntry, err error) error {
+				if err != nil || d.IsDir() || !indexable(path) {
+					return nil
+				}
+				if e, err := l.index(ctx, path, previous); err == nil {
+					found[e.file.ID] = e
+				}
+				return nil
+			},
+		)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !changed(previous, found) {
+		return nil
+	}
+	for id := range previous {
+		delete(l.files, id)
+	}
+	for id, e := range found {
+		l.files[id] = e
+	}
+	l.revision++
+	return nil
+}
+
+func (l *Library) remove(path string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	removed := false
+	for id, e := range l.files {
+		if e.file.Path == path || strings.HasPrefix(e.file.Path, path+string(filepath.Separator)) {
+			delete(l.files, id)
+			removed = true
+		}
+	}
+	if removed {
+		l.revision++
+	}
+}
+
+// Revision changes whenever the set of files or their metadata changes.
+func (l *Library) Revision() int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.revision
+}
+
+func changed(previous, found map[string]*entry) bool {
+	if len(previous) != len(found) {
+		return true
+	}
+	for id, e := range found {
+		if previous[id] != e {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *Library) index(ctx context.Context, path string, previous map[string]*entry) (*entry, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+	relPath, err := filepath.Rel(l.root, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve relative path: %w", err)
+	}
+	relPath = filepath.ToSlash(relPath)
+	id := fileID(relPath)
+
+	if e, ok := previous[id]; ok && e.size == info.Size() && e.modTime.Equal(info.ModTime()) {
+		return e, nil
+	}
+
+	metadata, err := l.parser.ParseFile(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	metadata.ID = id
+
+	return &entry{
+		file: model.StoredFile{
+			ID:       id,
+			Path:     path,
+			Filename: filepath.Base(path),
+			Metadata: metadata,
+		},
+		relPath: relPath,
+		modTime: info.ModTime(),
+		size:    info.Size(),
+	}, nil
+}
+
+// List returns the indexed files ordered by path.
+func (l *Library) List() []model.LibraryFile {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	files := make([]model.LibraryFile, 0, len(l.files))
+	for _, e := range l.files {
+		files = append(files, model.LibraryFile{Path: e.relPath, FileMetadata: *e.file.Metadata})
+	}
+	sort.Slice(
+		files, func(i, j int) bool {
+			return files[i].Path < files[j].Path
+		},
+	)
+	return files
+}
+
+func (l *Library) Get(id string) (*model.StoredFile, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	e, ok := l.files[id]
+	if !ok {
+		return nil, model.ErrFileNotFound
+	}
+	file := e.file
+	return &file, nil
+}
+
+// Save records the metadata of a file whose tags were just written in place.
+func (l *Library) Save(id string, metadata *model.FileMetadata) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.files[id]
+	if !ok {
+		return model.ErrFileNotFound
+	}
+	info, err := os.Stat(e.file.Path)
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	updated := *e
+	updated.file.Metadata = metadata
+	updated.modTime = info.ModTime()
+	updated.size = info.Size()
+	l.files[id] = &updated
+	l.revision++
+	return nil
+}
+
+// Move renames a file to relPath and returns it under its new ID.
+func (l *Library) Move(id, relPath string) (*model.LibraryFile, error) {
+	l.scanMu.Lock()
+	defer l.scanMu.Unlock()
+
+	l.mu.RLock()
+	e, ok := l.files[id]
+	l.mu.RUnlock()
+	if !ok {
+		return nil, model.ErrFileNotFound
+	}
+
+	target := filepath.Join(l.root, filepath.FromSlash(relPath))
+	if !strings.HasPrefix(target, l.root+string(filepath.Separator)) {
+		return nil, fmt.Errorf("path %s is outside the music directory", relPath)
+	}
+	if target == e.file.Path {
+		return &model.LibraryFile{Path: e.relPath, FileMetadata: *e.file.Metadata}, nil
+	}
+
+	// A case-only rename on a case-insensitive filesystem finds the file itself.
+	if targetInfo, err := os.Stat(target); err == nil {
+		sourceInfo, err := os.Stat(e.file.Path)
+		if err != nil || !os.SameFile(sourceInfo, targetInfo) {
+			return nil, model.ErrFileExists
+		}
+	}
+	correction, correctionTarget := audio.CorrectionFile(vfs.OS{}, e.file.Path), ""
+	if correction != "" {
+		correctionTarget = strings.TrimSuffix(target, filepath.Ext(target)) + filepath.Ext(correction)
+		if _, err := os.Lstat(correctionTarget); err == nil && !strings.EqualFold(correctionTarget, correction) {
+			return nil, model.ErrFileExists
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.Rename(e.file.Path, target); err != nil {
+		return nil, fmt.Errorf("failed to rename file: %w", err)
+	}
+	if correction != "" {
+		if err := os.Rename(correction, correctionTarget); err != nil {
+			slog.Warn("Library.Move: Failed to move WavPack correction file", slog.String("path", correction), slog.Any("error", err))
+		}
+	}
+	l.removeEmptyDirs(filepath.Dir(e.file.Path))
+
+	newRelPath := filepath.ToSlash(strings.TrimPrefix(target, l.root+string(filepath.Separator)))
+	newID := fileID(newRelPath)
+	metadata := *e.file.Metadata
+	metadata.ID = newID
+	moved := &entry{
+		file: model.StoredFile{
+			ID:       newID,
+			Path:     target,
+			Filename: filepath.Base(target),
+			Metadata: &metadata,
+		},
+		relPath: newRelPath,
+		modTime: e.modTime,
+		size:    e.size,
+	}
+
+	l.mu.Lock()
+	delete(l.files, id)
+	l.files[newID] = moved
+	l.revision++
+	l.mu.Unlock()
+
+	return &model.LibraryFile{Path: newRelPath, FileMetadata: metadata}, nil
+}
+
+func (l *Library) removeEmptyDirs(dir string) {
+	for dir != l.root && strings.HasPrefix(dir, l.root) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+func indexable(path string) bool {
+	return !strings.HasPrefix(filepath.Base(path), ".") && audio.SupportedExtension(filepath.Ext(path))
+}
+
+func fileID(relPath string) string {
+	sum := sha1.Sum([]byte(relPath))
+	return hex.EncodeToString(sum[:])
+}
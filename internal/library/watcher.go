@@ -0,0 +1,83 @@
+package library
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/iamvkosarev/audio-tag-editor/pkg/logs"
+)
+
+// Watcher keeps a library index current while other programs change files.
+type Watcher struct {
+	library  *Library
+	debounce time.Duration
+}
+
+func NewWatcher(library *Library, debounce time.Duration) *Watcher {
+	return &Watcher{library: library, debounce: debounce}
+}
+
+// Run watches the music directory until ctx is done.
+func (w *Watcher) Run(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	// fsnotify does not watch recursively, so every directory is added on its own.
+	if err := w.addTree(watcher, w.library.Root()); err != nil {
+		return err
+	}
+
+	pending := make(map[string]bool)
+	timer := time.NewTimer(w.debounce)
+	timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Has(fsnotify.Create) {
+				w.addTree(watcher, event.Name)
+			}
+			pending[event.Name] = true
+			timer.Reset(w.debounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			logs.Error("Watcher.Run: Watch error", err)
+		case <-timer.C:
+			for path := range pending {
+				if err := w.library.Refresh(ctx, path); err != nil {
+					slog.Warn("Watcher.Run: Failed to refresh path", slog.String("path", path), slog.Any("error", err))
+				}
+			}
+			clear(pending)
+		}
+	}
+}
+
+func (w *Watcher) addTree(watcher *fsnotify.Watcher, path string) error {
+	return filepath.WalkDir(
+		path, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || !d.IsDir() {
+				return nil
+			}
+			if err := watcher.Add(path); err != nil {
+				return fmt.Errorf("failed to watch %s: %w", path, err)
+			}
+			return nil
+		},
+	)
+}
@@ -0,0 +1,276 @@
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+)
+
+// Rules of findings.
+const (
+	RuleSpelling       = "spelling"        // one name written in several ways
+	RuleMissingTracks  = "missing_tracks"  // gaps in the track numbers of an album
+	RuleNoTrack        = "no_track"        // files of an album without a track number
+	RuleDuplicateTrack = "duplicate_track" // track numbers used twice in an album
+	RuleNoCover        = "no_cover"        // files without a front cover
+	RuleNoAlbum        = "no_album"        // files without an album
+	RuleYearMismatch   = "year_mismatch"   // files of an album with different years
+	RuleWhitespace     = "whitespace"      // text with stray or doubled whitespace
+)
+
+// Finding is one problem shared by some files.
+type Finding struct {
+	Rule    string                     `json:"rule"`
+	Message string                     `json:"message"`
+	Field   string                     `json:"field,omitempty"`
+	FileIds []string                   `json:"fileIds"`
+	Fixes   map[string]model.TagUpdate `json:"fixes,omitempty"`
+}
+
+// Check returns the findings for files, whose IDs must be set.
+func Check(files []model.FileMetadata) []Finding {
+	findings := []Finding{}
+	findings = append(findings, spellings(files, "artist")...)
+	findings = append(findings, spellings(files, "albumArtist")...)
+	findings = append(findings, spellings(files, "album")...)
+	findings = append(findings, albumFindings(files)...)
+	findings = append(findings, fileFindings(files)...)
+	findings = append(findings, whitespace(files)...)
+	return findings
+}
+
+func spellingKey(s string) string {
+	s = strings.ToLower(s)
+	s = strings.ReplaceAll(s, "&", " and ")
+	s = strings.TrimPrefix(strings.TrimSpace(s), "the ")
+	var b strings.Builder
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func spellings(files []model.FileMetadata, field string) []Finding {
+	type variant struct {
+		value   string
+		fileIDs []string
+	}
+	var keys []string
+	variants := make(map[string][]*variant)
+	for _, file := range files {
+		value := file.TextField(field)
+		key := spellingKey(value)
+		if key == "" {
+			continue
+		}
+		if _, ok := variants[key]; !ok {
+			keys = append(keys, key)
+		}
+		found := false
+		for _, v := range variants[key] {
+			if v.value == value {
+				v.fileIDs = append(v.fileIDs, file.ID)
+				found = true
+				break
+			}
+		}
+		if !found {
+			variants[key] = append(variants[key], &variant{value: value, fileIDs: []string{file.ID}})
+		}
+	}
+
+	var findings []Finding
+	for _, key := range keys {
+		group := variants[key]
+		if len(group) < 2 {
+			continue
+		}
+		best := group[0]
+		values := make([]string, len(group))
+		for i, v := range group {
+			values[i] = fmt.Sprintf("%q", v.value)
+			if len(v.fileIDs) > len(best.fileIDs) {
+				best = v
+			}
+		}
+		finding := Finding{
+			Rule:    RuleSpelling,
+			Message: fmt.Sprintf("%s is written as %s; use %q", field, strings.Join(values, ", "), best.value),
+			Field:   field,
+			Fixes:   make(map[string]model.TagUpdate),
+		}
+		for _, v := range group {
+			if v == best {
+				continue
+			}
+			for _, fileID := range v.fileIDs {
+				finding.FileIds = append(finding.FileIds, fileID)
+				var fix model.TagUpdate
+				fix.SetTextField(field, best.value)
+				finding.Fixes[fileID] = fix
+			}
+		}
+		findings = append(findings, finding)
+	}
+	return findings
+}
+
+func albumFindings(files []model.FileMetadata) []Finding {
+	byID := make(map[string]*model.FileMetadata, len(files))
+	for i := range files {
+		byID[files[i].ID] = &files[i]
+	}
+	albums, _ := model.GroupAlbums(files)
+
+	var findings []Finding
+	for _, album := range albums {
+		name := album.Album
+		if album.Disc > 0 {
+			name = fmt.Sprintf("%s (disc %d)", name, album.Disc)
+		}
+		if len(album.MissingTracks) > 0 {
+			findings = append(
+				findings, Finding{
+					Rule:    RuleMissingTracks,
+					Message: fmt.Sprintf("%q has no track %s", name, joinInts(album.MissingTracks)),
+					Field:   "track",
+					FileIds: album.FileIds,
+				},
+			)
+		}
+		if album.UnnumberedFiles > 0 {
+			findings = append(
+				findings, Finding{
+					Rule:    RuleNoTrack,
+					Message: fmt.Sprintf("files of %q have no track number; number them with POST /api/number-tracks", name),
+					Field:   "track",
+					FileIds: album.FileIds[len(album.FileIds)-album.UnnumberedFiles:],
+				},
+			)
+		}
+		for _, track := range album.DuplicateTracks {
+			var fileIDs []string
+			for _, fileID := range album.FileIds {
+				if byID[fileID].Track == track {
+					fileIDs = append(fileIDs, fileID)
+				}
+			}
+			findings = append(
+				findings, Finding{
+					Rule:    RuleDuplicateTrack,
+					Message: fmt.Sprintf("%q has %d files numbered %d", name, len(fileIDs), track),
+					Field:   "track",
+					FileIds: fileIDs,
+				},
+			)
+		}
+		if !album.ConsistentYear && len(album.Years) > 0 {
+			findings = append(findings, yearFinding(name, album, byID))
+		}
+	}
+	return findings
+}
+
+func yearFinding(name string, album model.Album, byID map[string]*model.FileMetadata) Finding {
+	counts := make(map[int]int)
+	for _, fileID := range album.FileIds {
+		if year := byID[fileID].Year; year > 0 {
+			counts[year]++
+		}
+	}
+	best := album.Years[0]
+	for _, year := range album.Years {
+		if counts[year] > counts[best] {
+			best = year
+		}
+	}
+
+	finding := Finding{
+		Rule:    RuleYearMismatch,
+		Message: fmt.Sprintf("files of %q have the years %s; use %d", name, joinInts(album.Years), best),
+		Field:   "year",
+		Fixes:   make(map[string]model.TagUpdate),
+	}
+	for _, fileID := range album.FileIds {
+		if byID[fileID].Year != best {
+			year := best
+			finding.FileIds = append(finding.FileIds, fileID)
+			finding.Fixes[fileID] = model.TagUpdate{Year: &year}
+		}
+	}
+	return finding
+}
+
+func fileFindings(files []model.FileMetadata) []Finding {
+	noCover := Finding{Rule: RuleNoCover, Message: "files without cover art", Field: "coverArt"}
+	noAlbum := Finding{Rule: RuleNoAlbum, Message: "files without an album", Field: "album"}
+	for _, file := range files {
+		if !file.HasCoverArt {
+			noCover.FileIds = append(noCover.FileIds, file.ID)
+		}
+		if file.Album == "" {
+			noAlbum.FileIds = append(noAlbum.FileIds, file.ID)
+		}
+	}
+
+	var findings []Finding
+	for _, finding := range []Finding{noCover, noAlbum} {
+		if len(finding.FileIds) > 0 {
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+func whitespace(files []model.FileMetadata) []Finding {
+	var findings []Finding
+	for _, field := range model.TextFields {
+		finding := Finding{
+			Rule:    RuleWhitespace,
+			Message: "stray whitespace in " + field,
+			Field:   field,
+			Fixes:   make(map[string]model.TagUpdate),
+		}
+		for _, file := range files {
+			value := file.TextField(field)
+			fixed := trimWhitespace(value, field == "lyrics")
+			if fixed == value {
+				continue
+			}
+			var fix model.TagUpdate
+			fix.SetTextField(field, fixed)
+			finding.FileIds = append(finding.FileIds, file.ID)
+			finding.Fixes[file.ID] = fix
+		}
+		if len(finding.FileIds) > 0 {
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+func trimWhitespace(s string, multiline bool) string {
+	if multiline {
+		lines := strings.Split(s, "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimRightFunc(line, unicode.IsSpace)
+		}
+		return strings.TrimSpace(strings.Join(lines, "\n"))
+	}
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func joinInts(numbers []int) string {
+	sorted := append([]int(nil), numbers...)
+	sort.Ints(sorted)
+	parts := make([]string, len(sorted))
+	for i, n := range sorted {
+		parts[i] = fmt.Sprint(n)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,112 @@
+package model
+
+import "sort"
+
+// Album is the files of one disc of an album, grouped by album artist, album and disc.
+type Album struct {
+	AlbumArtist string   `json:"albumArtist"`
+	Album       string   `json:"album"`
+	Disc        int      `json:"disc"`
+	FileIds     []string `json:"fileIds"` // by track number, unnumbered files last
+	TrackCount  int      `json:"trackCount"`
+	TotalTracks int      `json:"totalTracks"` // the largest track total in the tags
+	Duration    float64  `json:"duration"`    // seconds
+	// Years lists the distinct years of the files.
+	Years          []int `json:"years"`
+	ConsistentYear bool  `json:"consistentYear"`
+	// MissingTracks are the track numbers up to the total that no file has.
+	MissingTracks   []int `json:"missingTracks"`
+	DuplicateTracks []int `json:"duplicateTracks"`
+	UnnumberedFiles int   `json:"unnumberedFiles"`
+}
+
+type albumKey struct {
+	albumArtist string
+	album       string
+	disc        int
+}
+
+// GroupAlbums groups files by album artist, album and disc.
+func GroupAlbums(files []FileMetadata) ([]Album, []string) {
+	var keys []albumKey
+	groups := make(map[albumKey][]FileMetadata)
+	ungrouped := []string{}
+	for _, file := range files {
+		if file.Album == "" {
+			ungrouped = append(ungrouped, file.ID)
+			continue
+		}
+		key := albumKey{albumArtist: file.AlbumArtist, album: file.Album, disc: file.Disc}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], file)
+	}
+
+	albums := make([]Album, 0, len(keys))
+	for _, key := range keys {
+		albums = append(albums, newAlbum(key, groups[key]))
+	}
+	return albums, ungrouped
+}
+
+func newAlbum(key albumKey, files []FileMetadata) Album {
+	sort.SliceStable(
+		files, func(i, j int) bool {
+			if files[i].Track == 0 || files[j].Track == 0 {
+				return files[j].Track == 0 && files[i].Track != 0
+			}
+			return files[i].Track < files[j].Track
+		},
+	)
+
+	album := Album{
+		AlbumArtist:     key.albumArtist,
+		Album:           key.album,
+		Disc:            key.disc,
+		FileIds:         make([]string, len(files)),
+		TrackCount:      len(files),
+		Years:           []int{},
+		MissingTracks:   []int{},
+		DuplicateTracks: []int{},
+	}
+	tracks := make(map[int]int)
+	years := make(map[int]bool)
+	highest := 0
+	for i, file := range files {
+		album.FileIds[i] = file.ID
+		album.Duration += file.Duration
+		album.TotalTracks = max(album.TotalTracks, file.TotalTracks)
+		if file.Year > 0 && !years[file.Year] {
+			years[file.Year] = true
+			album.Years = append(album.Years, file.Year)
+		}
+		if file.Track == 0 {
+			album.UnnumberedFiles++
+			continue
+		}
+		tracks[file.Track]++
+		if tracks[file.Track] == 2 {
+			album.DuplicateTracks = append(album.DuplicateTracks, file.Track)
+		}
+		highest = max(highest, file.Track)
+	}
+	sort.Ints(album.Years)
+	album.ConsistentYear = len(album.Years) == 1 && allHaveYear(files)
+
+	for track := 1; track <= max(album.TotalTracks, highest); track++ {
+		if tracks[track] == 0 {
+			album.MissingTracks = append(album.MissingTracks, track)
+		}
+	}
+	return album
+}
+
+func allHaveYear(files []FileMetadata) bool {
+	for _, file := range files {
+		if file.Year == 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,101 @@
+package model
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported audio format")
+	ErrInvalidCoverArt   = errors.New("invalid cover art")
+	ErrInvalidTag        = errors.New("invalid tag")
+	ErrPatternMismatch   = errors.New("file name does not match the pattern")
+	ErrBatchAborted      = errors.New("not written: another file of the batch failed")
+	ErrETagMismatch      = errors.New("file was changed since it was read")
+	ErrReadOnly          = errors.New("file is read-only")
+)
+
+// Error codes of API error responses.
+const (
+	ErrorCodeInvalidRequest    = "invalid_request"
+	ErrorCodeUnauthorized      = "unauthorized"
+	ErrorCodeForbidden         = "forbidden"
+	ErrorCodeRateLimited       = "rate_limited"
+	ErrorCodeFileNotFound      = "file_not_found"
+	ErrorCodeFileExpired       = "file_expired"
+	ErrorCodeUnsupportedFormat = "unsupported_format"
+	ErrorCodeInvalidCoverArt   = "invalid_cover_art"
+	ErrorCodeInvalidTag        = "invalid_tag"
+	ErrorCodeNoCoverArt        = "no_cover_art"
+	ErrorCodePatternMismatch   = "pattern_mismatch"
+	ErrorCodeBatchAborted      = "batch_aborted"
+	ErrorCodePrecondition      = "precondition_failed"
+	ErrorCodeReadOnly          = "read_only"
+	ErrorCodeNotFound          = "not_found"
+	ErrorCodeConflict          = "conflict"
+	ErrorCodeTooLarge          = "too_large"
+	ErrorCodeUnavailable       = "unavailable"
+	ErrorCodeUpstream          = "upstream_error"
+	ErrorCodeInternal          = "internal_error"
+)
+
+// APIError is the body of every error response.
+type APIError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	FileID  string `json:"fileId,omitempty"`
+	Field   string `json:"field,omitempty"`
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// FieldError ties an error to the request field that caused it.
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError lists every invalid field of a request.
+type ValidationError struct {
+	Fields []*FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, len(e.Fields))
+	for i, field := range e.Fields {
+		errs[i] = field
+	}
+	return errs
+}
+
+// BatchError says which write of an all-or-nothing batch failed.
+type BatchError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,19 @@
+package model
+
+// Broadcast is what the bext and iXML chunks of a Broadcast Wave file say about the recording.
+type Broadcast struct {
+	Description         string `json:"description,omitempty"`
+	Originator          string `json:"originator,omitempty"`
+	OriginatorReference string `json:"originatorReference,omitempty"`
+	OriginationDate     string `json:"originationDate,omitempty"` // YYYY-MM-DD
+	OriginationTime     string `json:"originationTime,omitempty"` // HH:MM:SS
+	TimeReference       int64  `json:"timeReference"`             // samples since midnight at the start of the file
+	Timecode            string `json:"timecode,omitempty"`        // the time reference as HH:MM:SS:FF, or HH:MM:SS.mmm without an iXML frame rate
+	CodingHistory       string `json:"codingHistory,omitempty"`
+	Project             string `json:"project,omitempty"` // the iXML fields from here on
+	Scene               string `json:"scene,omitempty"`
+	Take                string `json:"take,omitempty"`
+	Tape                string `json:"tape,omitempty"`
+	Note                string `json:"note,omitempty"`
+	TimecodeRate        string `json:"timecodeRate,omitempty"` // frames per second as a fraction, such as "30000/1001"
+}
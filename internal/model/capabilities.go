@@ -0,0 +1,14 @@
+package model
+
+// Capabilities says which fields the editor can write for the format of a file.
+type Capabilities struct {
+	Read          bool `json:"read"`
+	Write         bool `json:"write"` // the basic tags: title, artist, album, year, track, genre
+	CoverArt      bool `json:"coverArt"`
+	Pictures      bool `json:"pictures"` // pictures other than the front cover
+	Lyrics        bool `json:"lyrics"`
+	SyncedLyrics  bool `json:"syncedLyrics"`
+	Chapters      bool `json:"chapters"`
+	CustomTags    bool `json:"customTags"`
+	ExactDuration bool `json:"exactDuration"` // false when the duration is estimated
+}
@@ -0,0 +1,14 @@
+package model
+
+// MaxChapters is the most chapters an ID3v2 table of contents can hold.
+const MaxChapters = 255
+
+// Chapter is a named section of a file, such as a podcast segment.
+type Chapter struct {
+	ID    string `json:"id,omitempty"` // ID3v2 element ID, generated when empty
+	Title string `json:"title"`
+	Start int64  `json:"start"`           // milliseconds
+	End   int64  `json:"end,omitempty"`   // milliseconds; 0 runs to the next chapter or the end of the file
+	URL   string `json:"url,omitempty"`   // ID3v2 WXXX subframe, CHAPTERnnnURL
+	Image string `json:"image,omitempty"` // data URI, ID3v2 only
+}
@@ -0,0 +1,8 @@
+package model
+
+// Checksums fingerprint a file.
+type Checksums struct {
+	SHA256        string `json:"sha256"`
+	AudioSHA256   string `json:"audioSha256,omitempty"`   // empty when the stream cannot be told from the tags
+	StreamInfoMD5 string `json:"streamInfoMd5,omitempty"` // FLAC: MD5 of the decoded samples, as recorded by the encoder
+}
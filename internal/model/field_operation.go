@@ -0,0 +1,54 @@
+package model
+
+import "fmt"
+
+const (
+	FieldCopy = "copy" // set To to the value of From
+	FieldMove = "move" // set To to the value of From and clear From
+	FieldSwap = "swap" // exchange the values of From and To
+)
+
+// FieldOperation copies, moves or swaps one text field into another.
+type FieldOperation struct {
+	Op   string `json:"op"`
+	From string `json:"from"`
+	To   string `json:"to"`
+	// KeepExisting makes copy and move leave a To field that already has a value alone.
+	KeepExisting bool `json:"keepExisting,omitempty"`
+}
+
+func (o *FieldOperation) Validate() error {
+	switch o.Op {
+	case FieldCopy, FieldMove, FieldSwap:
+	default:
+		return fmt.Errorf("unsupported operation %q", o.Op)
+	}
+	if err := ValidateTextField(o.From); err != nil {
+		return err
+	}
+	if err := ValidateTextField(o.To); err != nil {
+		return err
+	}
+	if o.From == o.To {
+		return fmt.Errorf("%s %s onto itself", o.Op, o.From)
+	}
+	return nil
+}
+
+// Apply performs the operation on m.
+func (o *FieldOperation) Apply(m *FileMetadata) {
+	from, to := m.TextField(o.From), m.TextField(o.To)
+	switch o.Op {
+	case FieldSwap:
+		m.SetTextField(o.From, to)
+		m.SetTextField(o.To, from)
+	case FieldCopy, FieldMove:
+		if o.KeepExisting && to != "" {
+			return
+		}
+		m.SetTextField(o.To, from)
+		if o.Op == FieldMove {
+			m.SetTextField(o.From, "")
+		}
+	}
+}
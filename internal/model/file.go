@@ -1,16 +1,144 @@
 package model
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"maps"
+	"slices"
+)
+
+// CoverArtInfo describes the embedded cover without its data.
+type CoverArtInfo struct {
+	MimeType string `json:"mimeType"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	Size     int    `json:"size"` // bytes
+}
+
 type FileMetadata struct {
-	ID       string  `json:"id"`
-	CoverArt string  `json:"coverArt"`
-	Title    string  `json:"title"`
-	Artist   string  `json:"artist"`
-	Album    string  `json:"album"`
-	Year     int     `json:"year"`
-	Genre    string  `json:"genre"`
-	Track    int     `json:"track"`
-	Disc     int     `json:"disc"`
-	Duration float64 `json:"duration"`
-	Size     int64   `json:"size"`
-	Format   string  `json:"format"`
+	ID              string            `json:"id"`
+	CoverArt        string            `json:"-"` // data URI, kept out of responses because of its size
+	HasCoverArt     bool              `json:"hasCoverArt"`
+	CoverArtInfo    *CoverArtInfo     `json:"coverArtInfo,omitempty"`
+	Pictures        []Picture         `json:"pictures,omitempty"` // every embedded image, the cover included
+	Title           string            `json:"title"`
+	Artist          string            `json:"artist"`
+	Album           string            `json:"album"`
+	AlbumArtist     string            `json:"albumArtist"`
+	Composer        string            `json:"composer"`
+	Comment         string            `json:"comment"`
+	Year            int               `json:"year"`
+	ReleaseDate     string            `json:"releaseDate"` // YYYY-MM-DD, YYYY-MM or YYYY, as precise as the tag
+	TitleSort       string            `json:"titleSort"`
+	ArtistSort      string            `json:"artistSort"`
+	AlbumArtistSort string            `json:"albumArtistSort"`
+	AlbumSort       string            `json:"albumSort"`
+	Genre           string            `json:"genre"`
+	Track           int               `json:"track"`
+	TotalTracks     int               `json:"totalTracks"`
+	Disc            int               `json:"disc"`
+	TotalDiscs      int               `json:"totalDiscs"`
+	BPM             int               `json:"bpm"`
+	Compilation     bool              `json:"compilation"`
+	Lyrics          string            `json:"lyrics"`
+	SyncedLyrics    string            `json:"syncedLyrics"` // LRC
+	ISRC            string            `json:"isrc"`
+	Barcode         string            `json:"barcode"` // UPC or EAN
+	CatalogNumber   string            `json:"catalogNumber"`
+	Label           string            `json:"label"`
+	Rating          int               `json:"rating"` // 0 to 100, 20 per star; 0 is unrated
+	PlayCount       int               `json:"playCount"`
+	Chapters        []Chapter         `json:"chapters,omitempty"`
+	CustomTags      map[string]string `json:"customTags"` // Vorbis comments and TXXX frames without a dedicated field
+	Duration        float64           `json:"duration"`
+	Bitrate         int               `json:"bitrate"` // kbit/s, averaged over the file for VBR streams
+	SampleRate      int               `json:"sampleRate"`
+	Channels        int               `json:"channels"`
+	BitsPerSample   int               `json:"bitsPerSample"` // 0 for lossy codecs
+	Codec           string            `json:"codec"`
+	Lossless        bool              `json:"lossless"`
+	Size            int64             `json:"size"`
+	Format          string            `json:"format"`
+	Gapless         *Gapless          `json:"gapless,omitempty"`   // MP3 only
+	Broadcast       *Broadcast        `json:"broadcast,omitempty"` // WAV only
+	Capabilities    *Capabilities     `json:"capabilities,omitempty"`
+	ETag            string            `json:"etag,omitempty"` // changes with every edit, see ComputeETag
+}
+
+// OriginalFilenameTag is the custom tag that keeps the uploaded file name.
+const OriginalFilenameTag = "ORIGINAL_FILENAME"
+
+// Clone returns a copy of m that shares no slices or maps with it, image bytes aside.
+func (m *FileMetadata) Clone() *FileMetadata {
+	clone := *m
+	clone.Pictures = slices.Clone(m.Pictures)
+	clone.Chapters = slices.Clone(m.Chapters)
+	clone.CustomTags = maps.Clone(m.CustomTags)
+	if m.CoverArtInfo != nil {
+		info := *m.CoverArtInfo
+		clone.CoverArtInfo = &info
+	}
+	if m.Gapless != nil {
+		gapless := *m.Gapless
+		clone.Gapless = &gapless
+	}
+	if m.Broadcast != nil {
+		broadcast := *m.Broadcast
+		clone.Broadcast = &broadcast
+	}
+	if m.Capabilities != nil {
+		capabilities := *m.Capabilities
+		clone.Capabilities = &capabilities
+	}
+	return &clone
+}
+
+// ComputeETag hashes everything m says about the file, images included.
+func (m *FileMetadata) ComputeETag() string {
+	hashed := *m
+	hashed.ID, hashed.ETag = "", ""
+	hash := sha256.New()
+	json.NewEncoder(hash).Encode(hashed)
+	hash.Write([]byte(m.CoverArt))
+	for _, picture := range m.Pictures {
+		hash.Write(picture.Data)
+	}
+	return hex.EncodeToString(hash.Sum(nil)[:12])
+}
+
+// TagUpdate returns the update that writes every tag field of m, pictures aside.
+func (m *FileMetadata) TagUpdate() TagUpdate {
+	tags := *m
+	return TagUpdate{
+		Title:           &tags.Title,
+		Artist:          &tags.Artist,
+		Album:           &tags.Album,
+		AlbumArtist:     &tags.AlbumArtist,
+		Composer:        &tags.Composer,
+		Comment:         &tags.Comment,
+		Year:            &tags.Year,
+		ReleaseDate:     &tags.ReleaseDate,
+		TitleSort:       &tags.TitleSort,
+		ArtistSort:      &tags.ArtistSort,
+		AlbumArtistSort: &tags.AlbumArtistSort,
+		AlbumSort:       &tags.AlbumSort,
+		Genre:           &tags.Genre,
+		Track:           &tags.Track,
+		TotalTracks:     &tags.TotalTracks,
+		Disc:            &tags.Disc,
+		TotalDiscs:      &tags.TotalDiscs,
+		BPM:             &tags.BPM,
+		Compilation:     &tags.Compilation,
+		Lyrics:          &tags.Lyrics,
+		SyncedLyrics:    &tags.SyncedLyrics,
+		ISRC:            &tags.ISRC,
+		Barcode:         &tags.Barcode,
+		CatalogNumber:   &tags.CatalogNumber,
+		Label:           &tags.Label,
+		Rating:          &tags.Rating,
+		PlayCount:       &tags.PlayCount,
+		Chapters:        &tags.Chapters,
+		CustomTags:      tags.CustomTags,
+	}
 }
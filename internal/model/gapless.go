@@ -0,0 +1,11 @@
+package model
+
+// Gapless is the encoder delay and padding of an MP3.
+type Gapless struct {
+	Source       string `json:"source"`            // "lame" for the LAME header, "iTunSMPB" for the iTunes comment
+	Encoder      string `json:"encoder,omitempty"` // from the LAME header, such as "LAME3.100"
+	EncoderDelay int    `json:"encoderDelay"`      // samples
+	Padding      int    `json:"padding"`           // samples
+	TotalSamples int64  `json:"totalSamples,omitempty"`
+	ITunSMPB     string `json:"iTunSMPB,omitempty"` // the raw iTunes comment, when there is one
+}
@@ -0,0 +1,11 @@
+package model
+
+import "errors"
+
+var ErrFileExists = errors.New("file already exists")
+
+// LibraryFile is an audio file found in the music directory.
+type LibraryFile struct {
+	Path string `json:"path"`
+	FileMetadata
+}
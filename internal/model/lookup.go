@@ -0,0 +1,57 @@
+package model
+
+type LookupQuery struct {
+	Artist string `json:"artist"`
+	Title  string `json:"title"`
+	Album  string `json:"album"`
+}
+
+// LookupCandidate is a recording on a specific release.
+type LookupCandidate struct {
+	Score       int               `json:"score"`
+	Title       string            `json:"title"`
+	Artist      string            `json:"artist"`
+	Album       string            `json:"album"`
+	Year        int               `json:"year"`
+	Track       int               `json:"track"`
+	TotalTracks int               `json:"totalTracks"`
+	Disc        int               `json:"disc"`
+	Duration    float64           `json:"duration"`
+	RecordingID string            `json:"recordingId"`
+	ReleaseID   string            `json:"releaseId"`
+	CustomTags  map[string]string `json:"customTags"`
+}
+
+// Genre is a canonical genre name with its name in the requested language.
+type Genre struct {
+	Genre string `json:"genre"`
+	Name  string `json:"name"`
+}
+
+// GenreSuggestion is a genre proposed for the artist and title of a lookup.
+type GenreSuggestion struct {
+	Genre      string  `json:"genre"`
+	Confidence float64 `json:"confidence"`
+	Source     string  `json:"source"`
+}
+
+const (
+	CoverSourceCoverArtArchive = "coverartarchive"
+	CoverSourceITunes          = "itunes"
+)
+
+type CoverQuery struct {
+	Artist string `json:"artist"`
+	Album  string `json:"album"`
+}
+
+// CoverCandidate is an album cover found online.
+type CoverCandidate struct {
+	Source       string `json:"source"` // CoverSourceCoverArtArchive or CoverSourceITunes
+	Artist       string `json:"artist"`
+	Album        string `json:"album"`
+	Year         int    `json:"year,omitempty"`
+	ThumbnailURL string `json:"thumbnailUrl"`
+	ImageURL     string `json:"imageUrl"`
+	ReleaseID    string `json:"releaseId,omitempty"` // MusicBrainz release, Cover Art Archive only
+}
@@ -0,0 +1,26 @@
+package model
+
+import "fmt"
+
+// MergePolicy decides what a tag update does with existing values.
+type MergePolicy string
+
+const (
+	// MergePreserve keeps the current value of unset fields.
+	MergePreserve MergePolicy = "preserve"
+	// MergeClear removes every unset field, so the update describes the whole tag.
+	MergeClear MergePolicy = "clear"
+	// MergeOverwriteIfEmpty writes set fields only where the file has no value yet.
+	MergeOverwriteIfEmpty MergePolicy = "overwrite-if-empty"
+)
+
+// ParseMergePolicy accepts the policy names; an empty name is MergePreserve.
+func ParseMergePolicy(name string) (MergePolicy, error) {
+	switch policy := MergePolicy(name); policy {
+	case "":
+		return MergePreserve, nil
+	case MergePreserve, MergeClear, MergeOverwriteIfEmpty:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unsupported merge policy: %s", name)
+}
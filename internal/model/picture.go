@@ -0,0 +1,54 @@
+package model
+
+import (
+	"encoding/base64"
+	"fmt"
+)
+
+// Picture types shared by ID3v2 APIC frames and FLAC PICTURE blocks.
+const (
+	PictureTypeOther      = 0
+	PictureTypeFrontCover = 3
+	PictureTypeBackCover  = 4
+	PictureTypeArtist     = 8
+	PictureTypeMax        = 20
+)
+
+var pictureTypeNames = [...]string{
+	"Other", "File icon", "Other file icon", "Front cover", "Back cover", "Leaflet page", "Media",
+	"Lead artist", "Artist", "Conductor", "Band", "Composer", "Lyricist", "Recording location",
+	"During recording", "During performance", "Screen capture", "Bright coloured fish", "Illustration",
+	"Band logotype", "Publisher logotype",
+}
+
+// PictureTypeName returns the display name of a picture type.
+func PictureTypeName(pictureType int) string {
+	if pictureType < 0 || pictureType >= len(pictureTypeNames) {
+		return pictureTypeNames[PictureTypeOther]
+	}
+	return pictureTypeNames[pictureType]
+}
+
+// Picture is an embedded image.
+type Picture struct {
+	Type        int    `json:"type"`
+	TypeName    string `json:"typeName"`
+	Description string `json:"description"`
+	MimeType    string `json:"mimeType"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	Size        int    `json:"size"` // bytes
+	Data        []byte `json:"-"`
+}
+
+// DataURI returns the picture as a data URI.
+func (p Picture) DataURI() string {
+	return fmt.Sprintf("data:%s;base64,%s", p.MimeType, base64.StdEncoding.EncodeToString(p.Data))
+}
+
+// PictureUpdate replaces all pictures of one type.
+type PictureUpdate struct {
+	Type        int    `json:"type"`
+	Description string `json:"description"`
+	Data        string `json:"data"` // data URI or http(s) URL
+}
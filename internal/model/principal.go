@@ -0,0 +1,14 @@
+package model
+
+import "errors"
+
+var (
+	ErrUnauthenticated    = errors.New("no credentials provided")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Principal is the caller a request was authenticated as.
+type Principal struct {
+	Name  string
+	Admin bool
+}
@@ -0,0 +1,20 @@
+package model
+
+const (
+	ProgressEventProgress = "progress"
+	ProgressEventDone     = "done"
+	ProgressEventError    = "error"
+)
+
+// ProgressEvent reports how far a batch operation got.
+type ProgressEvent struct {
+	Type  string `json:"type"`
+	Done  int    `json:"done"`
+	Total int    `json:"total"`
+	File  string `json:"file,omitempty"`
+	Error string `json:"error,omitempty"`
+	// Bytes, TotalBytes and ETA report the progress of a ZIP download.
+	Bytes      int64   `json:"bytes,omitempty"`
+	TotalBytes int64   `json:"totalBytes,omitempty"`
+	ETA        float64 `json:"eta,omitempty"`
+}
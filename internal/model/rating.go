@@ -0,0 +1,7 @@
+package model
+
+// Ratings run from 0, unrated, to MaxRating in steps of RatingPerStar.
+const (
+	MaxRating     = 100
+	RatingPerStar = 20
+)
@@ -0,0 +1,24 @@
+package model
+
+import "fmt"
+
+// ReplayGain holds gains in dB relative to -18 LUFS and linear sample peaks.
+type ReplayGain struct {
+	TrackGain float64  `json:"trackGain"`
+	TrackPeak float64  `json:"trackPeak"`
+	AlbumGain *float64 `json:"albumGain"`
+	AlbumPeak *float64 `json:"albumPeak"`
+}
+
+// Tags formats the values the way foobar2000 and other taggers write them.
+func (r ReplayGain) Tags() map[string]string {
+	tags := map[string]string{
+		"REPLAYGAIN_TRACK_GAIN": fmt.Sprintf("%.2f dB", r.TrackGain),
+		"REPLAYGAIN_TRACK_PEAK": fmt.Sprintf("%.6f", r.TrackPeak),
+	}
+	if r.AlbumGain != nil && r.AlbumPeak != nil {
+		tags["REPLAYGAIN_ALBUM_GAIN"] = fmt.Sprintf("%.2f dB", *r.AlbumGain)
+		tags["REPLAYGAIN_ALBUM_PEAK"] = fmt.Sprintf("%.6f", *r.AlbumPeak)
+	}
+	return tags
+}
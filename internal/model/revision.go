@@ -0,0 +1,95 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// Revision is the tag state of a file before one of its edits.
+type Revision struct {
+	ID        int             `json:"id"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Metadata  FileMetadata    `json:"metadata"`
+	CoverArt  string          `json:"coverArt,omitempty"` // data URI; FileMetadata does not serialize it
+	Pictures  []PictureUpdate `json:"pictures,omitempty"` // with data URIs, for the same reason
+}
+
+// TagUpdate returns the update that brings a file from current back to the revision.
+func (r *Revision) TagUpdate(current *FileMetadata) TagUpdate {
+	previous := r.Metadata
+	update := TagUpdate{
+		Title:           &previous.Title,
+		Artist:          &previous.Artist,
+		Album:           &previous.Album,
+		AlbumArtist:     &previous.AlbumArtist,
+		Composer:        &previous.Composer,
+		Comment:         &previous.Comment,
+		Year:            &previous.Year,
+		ReleaseDate:     &previous.ReleaseDate,
+		TitleSort:       &previous.TitleSort,
+		ArtistSort:      &previous.ArtistSort,
+		AlbumArtistSort: &previous.AlbumArtistSort,
+		AlbumSort:       &previous.AlbumSort,
+		Genre:           &previous.Genre,
+		Track:           &previous.Track,
+		TotalTracks:     &previous.TotalTracks,
+		Disc:            &previous.Disc,
+		TotalDiscs:      &previous.TotalDiscs,
+		BPM:             &previous.BPM,
+		Compilation:     &previous.Compilation,
+		Lyrics:          &previous.Lyrics,
+		SyncedLyrics:    &previous.SyncedLyrics,
+		ISRC:            &previous.ISRC,
+		Barcode:         &previous.Barcode,
+		CatalogNumber:   &previous.CatalogNumber,
+		Label:           &previous.Label,
+		Rating:          &previous.Rating,
+		PlayCount:       &previous.PlayCount,
+		Chapters:        &previous.Chapters,
+	}
+	if len(r.Pictures) > 0 {
+		update.Pictures = r.pictureUpdates(current)
+	} else if r.CoverArt != "" && r.CoverArt != current.CoverArt {
+		update.CoverArt = &r.CoverArt
+	}
+
+	customTags := make(map[string]string)
+	for key := range current.CustomTags {
+		if _, ok := previous.CustomTags[key]; !ok {
+			customTags[key] = ""
+		}
+	}
+	for key, value := range previous.CustomTags {
+		if current.CustomTags[key] != value {
+			customTags[key] = value
+		}
+	}
+	if len(customTags) > 0 {
+		update.CustomTags = customTags
+	}
+	return update
+}
+
+func (r *Revision) pictureUpdates(current *FileMetadata) []PictureUpdate {
+	currentPictures := make(map[int]string)
+	for _, picture := range current.Pictures {
+		currentPictures[picture.Type] = picture.DataURI()
+	}
+
+	var updates []PictureUpdate
+	restored := make(map[int]bool)
+	for _, picture := range r.Pictures {
+		restored[picture.Type] = true
+		if currentPictures[picture.Type] != picture.Data {
+			updates = append(updates, picture)
+		}
+	}
+	for pictureType := range currentPictures {
+		if !restored[pictureType] {
+			updates = append(updates, PictureUpdate{Type: pictureType})
+		}
+	}
+	return updates
+}
@@ -0,0 +1,28 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrFileNotFound = errors.New("file not found")
+	ErrFileExpired  = fmt.Errorf("file expired: %w", ErrFileNotFound) // still matches ErrFileNotFound
+	ErrNoCoverArt   = errors.New("file has no cover art")
+	// ErrOriginalNotFound is returned for files whose upload was not kept.
+	ErrOriginalNotFound = fmt.Errorf("original not found: %w", ErrFileNotFound)
+)
+
+type StoredFile struct {
+	ID        string        `json:"id"`
+	Path      string        `json:"path"`
+	Filename  string        `json:"filename"`
+	Metadata  *FileMetadata `json:"metadata"`
+	CreatedAt time.Time     `json:"createdAt"`
+	ExpiresAt time.Time     `json:"expiresAt"`
+	// UploadAudioSHA256 is the audio stream hash taken on upload, before any edit.
+	UploadAudioSHA256 string `json:"uploadAudioSha256,omitempty"`
+	// RelativePath is where the file was inside an uploaded folder or ZIP archive.
+	RelativePath string `json:"relativePath,omitempty"`
+}
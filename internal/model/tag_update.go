@@ -0,0 +1,189 @@
+package model
+
+import "slices"
+
+// TagUpdate lists the tag changes to apply to a file.
+type TagUpdate struct {
+	Title           *string           `json:"title"`
+	Artist          *string           `json:"artist"`
+	Album           *string           `json:"album"`
+	AlbumArtist     *string           `json:"albumArtist"`
+	Composer        *string           `json:"composer"`
+	Comment         *string           `json:"comment"`
+	Year            *int              `json:"year"`
+	ReleaseDate     *string           `json:"releaseDate"` // YYYY-MM-DD, YYYY-MM or YYYY; wins over Year when not empty
+	TitleSort       *string           `json:"titleSort"`   // e.g. "Beatles, The" for "The Beatles"
+	ArtistSort      *string           `json:"artistSort"`
+	AlbumArtistSort *string           `json:"albumArtistSort"`
+	AlbumSort       *string           `json:"albumSort"`
+	Genre           *string           `json:"genre"`
+	Track           *int              `json:"track"`
+	TotalTracks     *int              `json:"totalTracks"`
+	Disc            *int              `json:"disc"`
+	TotalDiscs      *int              `json:"totalDiscs"`
+	BPM             *int              `json:"bpm"`
+	Compilation     *bool             `json:"compilation"`
+	Lyrics          *string           `json:"lyrics"`
+	SyncedLyrics    *string           `json:"syncedLyrics"` // LRC, e.g. "[00:12.50]line"
+	ISRC            *string           `json:"isrc"`         // e.g. "USRC17607839"; hyphens are dropped
+	Barcode         *string           `json:"barcode"`      // 12-digit UPC-A or 13-digit EAN-13
+	CatalogNumber   *string           `json:"catalogNumber"`
+	Label           *string           `json:"label"`
+	Rating          *int              `json:"rating"` // 0 to 100, 20 per star; 0 removes the rating
+	PlayCount       *int              `json:"playCount"`
+	Chapters        *[]Chapter        `json:"chapters"`   // replaces every chapter; an empty list removes them
+	CustomTags      map[string]string `json:"customTags"` // an empty value removes the tag
+	CoverArt        *string           `json:"coverArt"`   // data URI or http(s) URL; replaces every picture with a front cover, empty removes the front cover
+	Pictures        []PictureUpdate   `json:"pictures"`   // applied after CoverArt, in order
+
+	// FLACID3 is keep, strip or sync: what happens to an ID3v2 tag in front of a FLAC stream.
+	FLACID3 string `json:"flacId3,omitempty"`
+	// TagProfile is the tagger whose Vorbis comment names are written: picard, beets or foobar2000.
+	TagProfile string `json:"tagProfile,omitempty"`
+	// MergePolicy is preserve, clear or overwrite-if-empty: what happens to the fields left unset.
+	MergePolicy MergePolicy `json:"mergePolicy,omitempty"`
+	// Clear names the fields to remove, by their JSON names.
+	Clear []string `json:"clear,omitempty"`
+	// KeepEmpty writes empty TextFields as empty values instead of removing them.
+	KeepEmpty bool `json:"keepEmpty,omitempty"`
+}
+
+// KeepsEmpty reports whether an empty value of the text field is written as such.
+func (u *TagUpdate) KeepsEmpty(field string) bool {
+	return u.KeepEmpty && slices.Contains(TextFields, field) && !slices.Contains(u.Clear, field)
+}
+
+// Merge returns u with every field set in override replacing its own.
+func (u TagUpdate) Merge(override TagUpdate) TagUpdate {
+	if override.Title != nil {
+		u.Title = override.Title
+	}
+	if override.Artist != nil {
+		u.Artist = override.Artist
+	}
+	if override.Album != nil {
+		u.Album = override.Album
+	}
+	if override.AlbumArtist != nil {
+		u.AlbumArtist = override.AlbumArtist
+	}
+	if override.Composer != nil {
+		u.Composer = override.Composer
+	}
+	if override.Comment != nil {
+		u.Comment = override.Comment
+	}
+	if override.Year != nil {
+		u.Year = override.Year
+	}
+	if override.ReleaseDate != nil {
+		u.ReleaseDate = override.ReleaseDate
+	}
+	if override.TitleSort != nil {
+		u.TitleSort = override.TitleSort
+	}
+	if override.ArtistSort != nil {
+		u.ArtistSort = override.ArtistSort
+	}
+	if override.AlbumArtistSort != nil {
+		u.AlbumArtistSort = override.AlbumArtistSort
+	}
+	if override.AlbumSort != nil {
+		u.AlbumSort = override.AlbumSort
+	}
+	if override.Genre != nil {
+		u.Genre = override.Genre
+	}
+	if override.Track != nil {
+		u.Track = override.Track
+	}
+	if override.TotalTracks != nil {
+		u.TotalTracks = override.TotalTracks
+	}
+	if override.Disc != nil {
+		u.Disc = override.Disc
+	}
+	if override.TotalDiscs != nil {
+		u.TotalDiscs = override.TotalDiscs
+	}
+	if override.BPM != nil {
+		u.BPM = override.BPM
+	}
+	if override.Compilation != nil {
+		u.Compilation = override.Compilation
+	}
+	if override.Lyrics != nil {
+		u.Lyrics = override.Lyrics
+	}
+	if override.SyncedLyrics != nil {
+		u.SyncedLyrics = override.SyncedLyrics
+	}
+	if override.ISRC != nil {
+		u.ISRC = override.ISRC
+	}
+	if override.Barcode != nil {
+		u.Barcode = override.Barcode
+	}
+	if override.CatalogNumber != nil {
+		u.CatalogNumber = override.CatalogNumber
+	}
+	if override.Label != nil {
+		u.Label = override.Label
+	}
+	if override.Rating != nil {
+		u.Rating = override.Rating
+	}
+	if override.PlayCount != nil {
+		u.PlayCount = override.PlayCount
+	}
+	if override.Chapters != nil {
+		u.Chapters = override.Chapters
+	}
+	if override.CoverArt != nil {
+		u.CoverArt = override.CoverArt
+	}
+	if override.FLACID3 != "" {
+		u.FLACID3 = override.FLACID3
+	}
+	if override.TagProfile != "" {
+		u.TagProfile = override.TagProfile
+	}
+	if override.MergePolicy != "" {
+		u.MergePolicy = override.MergePolicy
+	}
+	if len(override.Clear) > 0 {
+		u.Clear = append(slices.Clone(u.Clear), override.Clear...)
+	}
+	if override.KeepEmpty {
+		u.KeepEmpty = true
+	}
+	if len(override.Pictures) > 0 {
+		u.Pictures = append(append([]PictureUpdate(nil), u.Pictures...), override.Pictures...)
+	}
+	if len(override.CustomTags) > 0 {
+		customTags := make(map[string]string, len(u.CustomTags)+len(override.CustomTags))
+		for key, value := range u.CustomTags {
+			customTags[key] = value
+		}
+		for key, value := range override.CustomTags {
+			customTags[key] = value
+		}
+		u.CustomTags = customTags
+	}
+	return u
+}
+
+// HasExtendedFields reports whether any field beyond the basic set is changed.
+func (u *TagUpdate) HasExtendedFields() bool {
+	return u.AlbumArtist != nil || u.Composer != nil || u.Comment != nil || u.TotalTracks != nil ||
+		u.TitleSort != nil || u.ArtistSort != nil || u.AlbumArtistSort != nil || u.AlbumSort != nil ||
+		u.Disc != nil || u.TotalDiscs != nil || u.BPM != nil || u.Compilation != nil || u.Lyrics != nil || u.SyncedLyrics != nil ||
+		u.ISRC != nil || u.Barcode != nil || u.CatalogNumber != nil || u.Label != nil || u.Rating != nil || u.PlayCount != nil ||
+		u.Chapters != nil || len(u.CustomTags) > 0 || len(u.Pictures) > 0
+}
+
+// TagWrite is one file of a batch of tag writes that succeed or fail together.
+type TagWrite struct {
+	Path   string
+	Update *TagUpdate
+}
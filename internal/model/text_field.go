@@ -0,0 +1,123 @@
+package model
+
+import "fmt"
+
+// TextFields are the JSON names of the text fields that bulk field operations can read and write.
+var TextFields = []string{
+	"title", "artist", "album", "albumArtist", "composer", "comment", "genre", "lyrics",
+	"titleSort", "artistSort", "albumArtistSort", "albumSort",
+}
+
+// SortFields maps the sort-order fields to the fields they sort.
+var SortFields = map[string]string{
+	"titleSort":       "title",
+	"artistSort":      "artist",
+	"albumArtistSort": "albumArtist",
+	"albumSort":       "album",
+}
+
+func textField(m *FileMetadata, name string) *string {
+	switch name {
+	case "title":
+		return &m.Title
+	case "artist":
+		return &m.Artist
+	case "album":
+		return &m.Album
+	case "albumArtist":
+		return &m.AlbumArtist
+	case "composer":
+		return &m.Composer
+	case "comment":
+		return &m.Comment
+	case "genre":
+		return &m.Genre
+	case "lyrics":
+		return &m.Lyrics
+	case "titleSort":
+		return &m.TitleSort
+	case "artistSort":
+		return &m.ArtistSort
+	case "albumArtistSort":
+		return &m.AlbumArtistSort
+	case "albumSort":
+		return &m.AlbumSort
+	}
+	return nil
+}
+
+func updateTextField(u *TagUpdate, name string) **string {
+	switch name {
+	case "title":
+		return &u.Title
+	case "artist":
+		return &u.Artist
+	case "album":
+		return &u.Album
+	case "albumArtist":
+		return &u.AlbumArtist
+	case "composer":
+		return &u.Composer
+	case "comment":
+		return &u.Comment
+	case "genre":
+		return &u.Genre
+	case "lyrics":
+		return &u.Lyrics
+	case "titleSort":
+		return &u.TitleSort
+	case "artistSort":
+		return &u.ArtistSort
+	case "albumArtistSort":
+		return &u.AlbumArtistSort
+	case "albumSort":
+		return &u.AlbumSort
+	}
+	return nil
+}
+
+// ValidateTextField returns an error for names that are not in TextFields.
+func ValidateTextField(name string) error {
+	if textField(&FileMetadata{}, name) == nil {
+		return fmt.Errorf("unknown text field %q", name)
+	}
+	return nil
+}
+
+// TextField returns the value of the text field with the given JSON name.
+func (m *FileMetadata) TextField(name string) string {
+	if field := textField(m, name); field != nil {
+		return *field
+	}
+	return ""
+}
+
+// SetTextField sets the text field with the given JSON name.
+func (m *FileMetadata) SetTextField(name, value string) {
+	if field := textField(m, name); field != nil {
+		*field = value
+	}
+}
+
+// SetTextField sets the text field with the given JSON name.
+func (u *TagUpdate) SetTextField(name, value string) {
+	if field := updateTextField(u, name); field != nil {
+		*field = &value
+	}
+}
+
+// TextChanges returns the update that writes the text fields that differ from previous.
+func (m *FileMetadata) TextChanges(previous *FileMetadata) *TagUpdate {
+	var update TagUpdate
+	changed := false
+	for _, name := range TextFields {
+		if value := m.TextField(name); value != previous.TextField(name) {
+			update.SetTextField(name, value)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return &update
+}
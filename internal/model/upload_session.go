@@ -0,0 +1,26 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrUploadNotFound       = errors.New("upload session not found")
+	ErrUploadOffsetMismatch = errors.New("upload offset does not match the received size")
+	ErrUploadBusy           = errors.New("upload session is receiving another chunk")
+	ErrUploadTooLarge       = errors.New("upload exceeds the maximum file size")
+)
+
+// UploadSession tracks a file uploaded in chunks.
+type UploadSession struct {
+	ID        string    `json:"uploadId"`
+	Filename  string    `json:"filename"`
+	Size      int64     `json:"size"`
+	Offset    int64     `json:"offset"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+func (s *UploadSession) Complete() bool {
+	return s.Offset >= s.Size
+}
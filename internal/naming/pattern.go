@@ -0,0 +1,123 @@
+package naming
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+)
+
+// Pattern reads tags out of file names.
+type Pattern struct {
+	pattern string
+	re      *regexp.Regexp
+	fields  []string
+}
+
+var patternFields = map[string]bool{
+	"title": true, "artist": true, "album": true, "albumArtist": true, "composer": true, "genre": true,
+	"comment": true, "year": true, "track": true, "totalTracks": true, "disc": true, "totalDiscs": true,
+	"ignore": true,
+}
+
+var numericFields = map[string]bool{"year": true, "track": true, "totalTracks": true, "disc": true, "totalDiscs": true}
+
+func ParsePattern(pattern string) (*Pattern, error) {
+	var expr strings.Builder
+	var fields []string
+	seen := make(map[string]bool)
+	expr.WriteString("^")
+	rest := pattern
+	for rest != "" {
+		start := strings.IndexByte(rest, '%')
+		if start < 0 {
+			expr.WriteString(regexp.QuoteMeta(rest))
+			break
+		}
+		expr.WriteString(regexp.QuoteMeta(rest[:start]))
+		end := strings.IndexByte(rest[start+1:], '%')
+		if end < 0 {
+			return nil, fmt.Errorf("invalid filename pattern %q: unterminated %%", pattern)
+		}
+		name := rest[start+1 : start+1+end]
+		if !patternFields[name] {
+			return nil, fmt.Errorf("invalid filename pattern %q: unknown field %%%s%%", pattern, name)
+		}
+		switch {
+		case name == "ignore":
+			expr.WriteString(`.*?`)
+		case seen[name]:
+			return nil, fmt.Errorf("invalid filename pattern %q: %%%s%% appears twice", pattern, name)
+		case numericFields[name]:
+			expr.WriteString(`\s*(\d+)\s*`)
+			fields = append(fields, name)
+		default:
+			expr.WriteString(`\s*(.+?)\s*`)
+			fields = append(fields, name)
+		}
+		seen[name] = true
+		rest = rest[start+end+2:]
+	}
+	expr.WriteString("$")
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("invalid filename pattern %q: no fields", pattern)
+	}
+
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid filename pattern %q: %w", pattern, err)
+	}
+	return &Pattern{pattern: pattern, re: re, fields: fields}, nil
+}
+
+func (p *Pattern) String() string {
+	return p.pattern
+}
+
+// Match returns the values of the fields in filename by field name.
+func (p *Pattern) Match(filename string) (map[string]string, bool) {
+	stem := strings.TrimSuffix(filename, filepath.Ext(filename))
+	groups := p.re.FindStringSubmatch(stem)
+	if groups == nil {
+		return nil, false
+	}
+	values := make(map[string]string, len(p.fields))
+	for i, name := range p.fields {
+		value := groups[i+1]
+		if numericFields[name] {
+			// "01" is track 1.
+			n, _ := strconv.Atoi(value)
+			value = strconv.Itoa(n)
+		}
+		values[name] = value
+	}
+	return values, true
+}
+
+// TagUpdate returns the update that writes values as returned by Match.
+func TagUpdate(values map[string]string) *model.TagUpdate {
+	update := &model.TagUpdate{}
+	for name, value := range values {
+		if !numericFields[name] {
+			update.SetTextField(name, value)
+			continue
+		}
+		n, _ := strconv.Atoi(value)
+		switch name {
+		case "year":
+			update.Year = &n
+		case "track":
+			update.Track = &n
+		case "totalTracks":
+			update.TotalTracks = &n
+		case "disc":
+			update.Disc = &n
+		case "totalDiscs":
+			update.TotalDiscs = &n
+		}
+	}
+	return update
+}
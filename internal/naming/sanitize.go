@@ -0,0 +1,51 @@
+package naming
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const maxSegmentLength = 255
+
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// SanitizeSegment makes name usable as a file or directory name on any OS.
+func SanitizeSegment(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r < 0x20 || r == 0x7f:
+			continue
+		case strings.ContainsRune(`/\:*?"<>|`, r):
+			b.WriteRune('_')
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	result := strings.TrimSpace(b.String())
+	result = strings.TrimRight(result, ". ")
+	if result == "" {
+		return ""
+	}
+
+	base, _, _ := strings.Cut(result, ".")
+	if reservedNames[strings.ToUpper(strings.TrimSpace(base))] {
+		result = "_" + result
+	}
+	return truncate(result, maxSegmentLength)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return strings.TrimRight(s[:n], ". ")
+}
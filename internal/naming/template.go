@@ -0,0 +1,207 @@
+package naming
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+)
+
+// Template renders file paths from tags.
+type Template struct {
+	pattern string
+	root    group
+}
+
+type node interface {
+	render(values map[string]string) (string, bool)
+}
+
+type literal string
+
+type placeholder struct {
+	names []string
+	width int
+}
+
+type group []node
+
+var placeholders = map[string]bool{
+	"title": true, "artist": true, "album": true, "albumArtist": true, "composer": true, "genre": true,
+	"year": true, "track": true, "totalTracks": true, "disc": true, "totalDiscs": true, "format": true,
+	"filename": true,
+}
+
+func Parse(pattern string) (*Template, error) {
+	root, rest, err := parseGroup(pattern, false)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filename template %q: %w", pattern, err)
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("invalid filename template %q: unexpected %q", pattern, rest)
+	}
+	return &Template{pattern: pattern, root: root}, nil
+}
+
+func (t *Template) String() string {
+	return t.pattern
+}
+
+func parseGroup(s string, nested bool) (group, string, error) {
+	var nodes group
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			nodes = append(nodes, literal(text.String()))
+			text.Reset()
+		}
+	}
+
+	for len(s) > 0 {
+		switch s[0] {
+		case '[':
+			flush()
+			inner, rest, err := parseGroup(s[1:], true)
+			if err != nil {
+				return nil, "", err
+			}
+			nodes = append(nodes, inner)
+			s = rest
+		case ']':
+			if !nested {
+				return nil, "", fmt.Errorf("unmatched ]")
+			}
+			flush()
+			return nodes, s[1:], nil
+		case '{':
+			end := strings.IndexByte(s, '}')
+			if end < 0 {
+				return nil, "", fmt.Errorf("unclosed {")
+			}
+			p, err := parsePlaceholder(s[1:end])
+			if err != nil {
+				return nil, "", err
+			}
+			flush()
+			nodes = append(nodes, p)
+			s = s[end+1:]
+		case '}':
+			return nil, "", fmt.Errorf("unmatched }")
+		default:
+			text.WriteByte(s[0])
+			s = s[1:]
+		}
+	}
+
+	if nested {
+		return nil, "", fmt.Errorf("unclosed [")
+	}
+	flush()
+	return nodes, "", nil
+}
+
+func parsePlaceholder(s string) (placeholder, error) {
+	names, format, hasFormat := strings.Cut(s, ":")
+	p := placeholder{names: strings.Split(names, "|")}
+	for _, name := range p.names {
+		if !placeholders[name] {
+			return placeholder{}, fmt.Errorf("unknown placeholder {%s}", name)
+		}
+	}
+	if hasFormat {
+		width, err := strconv.Atoi(format)
+		if err != nil || width < 1 || width > 9 {
+			return placeholder{}, fmt.Errorf("invalid width in {%s}", s)
+		}
+		p.width = width
+	}
+	return p, nil
+}
+
+func (l literal) render(map[string]string) (string, bool) {
+	return string(l), true
+}
+
+func (p placeholder) render(values map[string]string) (string, bool) {
+	var value string
+	for _, name := range p.names {
+		if value = values[name]; value != "" {
+			break
+		}
+	}
+	if value == "" {
+		return "", false
+	}
+	if p.width > 0 && len(value) < p.width {
+		if _, err := strconv.Atoi(value); err == nil {
+			value = strings.Repeat("0", p.width-len(value)) + value
+		}
+	}
+	return value, true
+}
+
+func (g group) render(values map[string]string) (string, bool) {
+	var b strings.Builder
+	for _, n := range g {
+		text, ok := n.render(values)
+		if _, optional := n.(group); !ok && !optional {
+			return "", false
+		}
+		b.WriteString(text)
+	}
+	return b.String(), true
+}
+
+// Execute renders the relative path for a file, with "/" between directories.
+func (t *Template) Execute(metadata *model.FileMetadata, filename string) string {
+	ext := filepath.Ext(filename)
+	stem := strings.TrimSuffix(filename, ext)
+	values := map[string]string{"filename": stem, "title": stem}
+	if metadata != nil {
+		number := func(n int) string {
+			if n <= 0 {
+				return ""
+			}
+			return strconv.Itoa(n)
+		}
+		for name, value := range map[string]string{
+			"title":       metadata.Title,
+			"artist":      metadata.Artist,
+			"album":       metadata.Album,
+			"albumArtist": metadata.AlbumArtist,
+			"composer":    metadata.Composer,
+			"genre":       metadata.Genre,
+			"format":      metadata.Format,
+			"year":        number(metadata.Year),
+			"track":       number(metadata.Track),
+			"totalTracks": number(metadata.TotalTracks),
+			"disc":        number(metadata.Disc),
+			"totalDiscs":  number(metadata.TotalDiscs),
+		} {
+			if value != "" {
+				values[name] = value
+			}
+		}
+	}
+	// Tag values are plain text; a slash in an artist name must not nest directories.
+	for name, value := range values {
+		values[name] = strings.ReplaceAll(strings.ReplaceAll(value, "/", "_"), "\\", "_")
+	}
+
+	rendered, _ := t.root.render(values)
+	var segments []string
+	for _, segment := range strings.Split(rendered, "/") {
+		if segment = SanitizeSegment(segment); segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	if len(segments) == 0 {
+		segments = []string{SanitizeSegment(stem)}
+	}
+
+	last := len(segments) - 1
+	segments[last] = truncate(segments[last], maxSegmentLength-len(ext)) + ext
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,45 @@
+// Package netguard keeps user-controlled requests from reaching the server's own network.
+package netguard
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+)
+
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "this network"
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+}
+
+// Control refuses connections to private, loopback, link-local and other non-public addresses.
+func Control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if !Public(host) {
+		return fmt.Errorf("host %s is not allowed", host)
+	}
+	return nil
+}
+
+// Public reports whether host is an IP address that may be reached from the server.
+func Public(host string) bool {
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsUnspecified() || addr.IsMulticast() || addr.IsInterfaceLocalMulticast() {
+		return false
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
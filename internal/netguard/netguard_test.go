@@ -0,0 +1,49 @@
+package netguard
+
+import "testing"
+
+func TestPublic(t *testing.T) {
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"100.63.255.255", true},
+		{"100.128.0.0", true},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"100.127.255.255", false},
+		{"0.0.0.0", false},
+		{"0.1.2.3", false},
+		{"224.0.0.1", false},
+		{"::1", false},
+		{"::", false},
+		{"fc00::1", false},
+		{"fe80::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:100.64.0.1", false},
+		{"example.com", false},
+	}
+	for _, tt := range tests {
+		if got := Public(tt.host); got != tt.want {
+			t.Errorf("Public(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestControl(t *testing.T) {
+	if err := Control("tcp4", "93.184.216.34:443", nil); err != nil {
+		t.Errorf("public address refused: %v", err)
+	}
+	if err := Control("tcp4", "100.64.0.1:80", nil); err == nil {
+		t.Error("carrier-grade NAT address allowed")
+	}
+	if err := Control("tcp4", "100.64.0.1", nil); err == nil {
+		t.Error("address without port allowed")
+	}
+}
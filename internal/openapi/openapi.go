@@ -0,0 +1,206 @@
+// Package openapi describes an HTTP API as an OpenAPI 3 document.
+package openapi
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	version  = "3.0.3"
+	jsonType = "application/json"
+)
+
+type Document struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       Info                            `json:"info"`
+	Paths      map[string]map[string]Operation `json:"paths"`
+	Components Components                      `json:"components"`
+	Security   []map[string][]string           `json:"security,omitempty"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme,omitempty"`
+	In     string `json:"in,omitempty"`
+	Name   string `json:"name,omitempty"`
+}
+
+type Operation struct {
+	Summary     string              `json:"summary,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+// Route is one operation of the API.
+type Route struct {
+	Method      string
+	Path        string // path parameters are written as {name}
+	Summary     string
+	Description string
+	Tag         string
+	Query       []Param
+	Headers     []Param
+	Body        any
+	BodyType    string // defaults to application/json
+	Replies     []Reply
+}
+
+type Param struct {
+	Name        string
+	Description string
+	Required    bool
+	Schema      *Schema // defaults to a string
+}
+
+type Reply struct {
+	Status      int
+	Description string // defaults to the status text
+	Body        any
+	Type        string // defaults to application/json
+}
+
+// Security names the credentials every operation accepts.
+type Security struct {
+	Schemes  map[string]*SecurityScheme
+	Optional bool
+}
+
+var pathParam = regexp.MustCompile(`\{([^}.]+)(\.\.\.)?\}`)
+
+// Build describes routes as an OpenAPI document.
+func Build(info Info, security *Security, errorBody any, routes []Route) *Document {
+	g := newGenerator()
+	doc := &Document{
+		OpenAPI: version,
+		Info:    info,
+		Paths:   make(map[string]map[string]Operation),
+	}
+
+	errorResponse := Response{
+		Description: "Error",
+		Content:     map[string]MediaType{jsonType: {Schema: g.schemaOf(errorBody)}},
+	}
+
+	for _, route := range routes {
+		op := Operation{
+			Summary:     route.Summary,
+			Description: route.Description,
+			Responses:   map[string]Response{"default": errorResponse},
+		}
+		if route.Tag != "" {
+			op.Tags = []string{route.Tag}
+		}
+
+		for _, match := range pathParam.FindAllStringSubmatch(route.Path, -1) {
+			op.Parameters = append(
+				op.Parameters, Parameter{Name: match[1], In: "path", Required: true, Schema: &Schema{Type: "string"}},
+			)
+		}
+		op.Parameters = append(op.Parameters, parameters("query", route.Query)...)
+		op.Parameters = append(op.Parameters, parameters("header", route.Headers)...)
+
+		if route.Body != nil {
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content:  map[string]MediaType{mediaType(route.BodyType): {Schema: g.schemaOf(route.Body)}},
+			}
+		}
+
+		for _, reply := range route.Replies {
+			response := Response{Description: reply.Description}
+			if response.Description == "" {
+				response.Description = http.StatusText(reply.Status)
+			}
+			if reply.Body != nil {
+				response.Content = map[string]MediaType{mediaType(reply.Type): {Schema: g.schemaOf(reply.Body)}}
+			}
+			op.Responses[strconv.Itoa(reply.Status)] = response
+		}
+
+		path := pathParam.ReplaceAllString(route.Path, "{$1}")
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(map[string]Operation)
+		}
+		doc.Paths[path][strings.ToLower(route.Method)] = op
+	}
+
+	doc.Components.Schemas = g.schemas
+	if security != nil {
+		doc.Components.SecuritySchemes = security.Schemes
+		names := make([]string, 0, len(security.Schemes))
+		for name := range security.Schemes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			doc.Security = append(doc.Security, map[string][]string{name: {}})
+		}
+		if security.Optional {
+			doc.Security = append(doc.Security, map[string][]string{})
+		}
+	}
+	return doc
+}
+
+func parameters(in string, params []Param) []Parameter {
+	result := make([]Parameter, 0, len(params))
+	for _, param := range params {
+		schema := param.Schema
+		if schema == nil {
+			schema = &Schema{Type: "string"}
+		}
+		result = append(
+			result, Parameter{
+				Name: param.Name, In: in, Description: param.Description, Required: param.Required, Schema: schema,
+			},
+		)
+	}
+	return result
+}
+
+func mediaType(contentType string) string {
+	if contentType == "" {
+		return jsonType
+	}
+	return contentType
+}
@@ -0,0 +1,127 @@
+package playlist
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+)
+
+// Track is a file in a playlist. Path is relative to the playlist.
+type Track struct {
+	Path     string
+	Metadata *model.FileMetadata
+}
+
+// Sort orders tracks by disc and track number.
+func Sort(tracks []Track) {
+	key := func(t Track) (int, int) {
+		if t.Metadata == nil || t.Metadata.Track == 0 {
+			return math.MaxInt, math.MaxInt
+		}
+		return max(t.Metadata.Disc, 1), t.Metadata.Track
+	}
+	sort.SliceStable(
+		tracks, func(i, j int) bool {
+			discI, trackI := key(tracks[i])
+			discJ, trackJ := key(tracks[j])
+			if discI != discJ {
+				return discI < discJ
+			}
+			return trackI < trackJ
+		},
+	)
+}
+
+// SingleAlbum reports whether all tracks have the same, non-empty album.
+func SingleAlbum(tracks []Track) bool {
+	if len(tracks) == 0 {
+		return false
+	}
+	album := ""
+	for _, t := range tracks {
+		if t.Metadata == nil || t.Metadata.Album == "" || (album != "" && t.Metadata.Album != album) {
+			return false
+		}
+		album = t.Metadata.Album
+	}
+	return true
+}
+
+// M3U8 renders an extended M3U playlist in UTF-8.
+func M3U8(tracks []Track) []byte {
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n")
+	for _, t := range tracks {
+		if t.Metadata != nil {
+			title := t.Metadata.Title
+			if t.Metadata.Artist != "" {
+				title = t.Metadata.Artist + " - " + title
+			}
+			fmt.Fprintf(&b, "#EXTINF:%d,%s\n", int(math.Round(t.Metadata.Duration)), oneLine(title))
+		}
+		b.WriteString(t.Path)
+		b.WriteString("\n")
+	}
+	return []byte(b.String())
+}
+
+// CUE renders a cue sheet with one FILE per track, for a single album.
+func CUE(tracks []Track) []byte {
+	var b strings.Builder
+	if len(tracks) > 0 && tracks[0].Metadata != nil {
+		first := tracks[0].Metadata
+		if first.Genre != "" {
+			fmt.Fprintf(&b, "REM GENRE %s\n", quote(first.Genre))
+		}
+		if first.Year > 0 {
+			fmt.Fprintf(&b, "REM DATE %d\n", first.Year)
+		}
+		performer := first.AlbumArtist
+		if performer == "" {
+			performer = first.Artist
+		}
+		if performer != "" {
+			fmt.Fprintf(&b, "PERFORMER %s\n", quote(performer))
+		}
+		fmt.Fprintf(&b, "TITLE %s\n", quote(first.Album))
+	}
+	for i, t := range tracks {
+		fmt.Fprintf(&b, "FILE %s %s\n", quote(t.Path), fileType(t.Metadata))
+		fmt.Fprintf(&b, "  TRACK %02d AUDIO\n", i+1)
+		if t.Metadata != nil {
+			if t.Metadata.Title != "" {
+				fmt.Fprintf(&b, "    TITLE %s\n", quote(t.Metadata.Title))
+			}
+			if t.Metadata.Artist != "" {
+				fmt.Fprintf(&b, "    PERFORMER %s\n", quote(t.Metadata.Artist))
+			}
+		}
+		b.WriteString("    INDEX 01 00:00:00\n")
+	}
+	return []byte(b.String())
+}
+
+func fileType(metadata *model.FileMetadata) string {
+	if metadata == nil {
+		return "WAVE"
+	}
+	switch metadata.Format {
+	case "MP3":
+		return "MP3"
+	case "AIFF":
+		return "AIFF"
+	default:
+		return "WAVE"
+	}
+}
+
+func quote(s string) string {
+	return `"` + strings.ReplaceAll(oneLine(s), `"`, "'") + `"`
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,87 @@
+package progress
+
+import (
+	"sync"
+	"time"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+)
+
+const sweepInterval = time.Minute
+
+// Hub collects progress events per job ID.
+type Hub struct {
+	retention time.Duration
+	mu        sync.Mutex
+	jobs      map[string]*job
+	lastSweep time.Time
+}
+
+type job struct {
+	events    []model.ProgressEvent
+	changed   chan struct{}
+	finished  bool
+	updatedAt time.Time
+}
+
+func NewHub(retention time.Duration) *Hub {
+	return &Hub{
+		retention: retention,
+		jobs:      make(map[string]*job),
+		lastSweep: time.Now(),
+	}
+}
+
+func (h *Hub) Publish(jobID string, event model.ProgressEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sweep()
+
+	j := h.job(jobID)
+	if j.finished {
+		return
+	}
+	j.events = append(j.events, event)
+	j.finished = event.Type == model.ProgressEventDone || event.Type == model.ProgressEventError
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// Events returns the events of jobID starting at index from.
+func (h *Hub) Events(jobID string, from int) ([]model.ProgressEvent, <-chan struct{}, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sweep()
+
+	j := h.job(jobID)
+	var events []model.ProgressEvent
+	if from < len(j.events) {
+		events = append(events, j.events[max(from, 0):]...)
+	}
+	return events, j.changed, j.finished
+}
+
+func (h *Hub) job(jobID string) *job {
+	j, ok := h.jobs[jobID]
+	if !ok {
+		j = &job{changed: make(chan struct{})}
+		h.jobs[jobID] = j
+	}
+	j.updatedAt = time.Now()
+	return j
+}
+
+func (h *Hub) sweep() {
+	now := time.Now()
+	if now.Sub(h.lastSweep) < sweepInterval {
+		return
+	}
+	h.lastSweep = now
+
+	for jobID, j := range h.jobs {
+		if now.Sub(j.updatedAt) > h.retention {
+			close(j.changed)
+			delete(h.jobs, jobID)
+		}
+	}
+}
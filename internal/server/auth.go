@@ -0,0 +1,57 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/auth"
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/internal/ratelimit"
+	"github.com/iamvkosarev/audio-tag-editor/pkg/logs"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	adminPathPrefix = "/api/admin/"
+	openAPIPath     = "/api/openapi.json"
+	docsPath        = "/api/docs"
+)
+
+func withAuth(authenticator *auth.Authenticator, limiter *ratelimit.Limiter, next http.Handler) http.Handler {
+	if authenticator == nil {
+		return next
+	}
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasPrefix(r.URL.Path, "/api/") || r.URL.Path == openAPIPath || r.URL.Path == docsPath {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			principal, err := authenticator.Authenticate(r)
+			if err != nil {
+				if !errors.Is(err, model.ErrUnauthenticated) {
+					logs.ErrorContext(r.Context(), "Server.withAuth: Rejected credentials", err)
+				}
+				w.Header().Set("WWW-Authenticate", `Bearer realm="audio-tag-editor"`)
+				writeError(w, http.StatusUnauthorized, model.ErrorCodeUnauthorized, "Authentication required")
+				return
+			}
+			trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("enduser.id", principal.Name))
+
+			if strings.HasPrefix(r.URL.Path, adminPathPrefix) && !principal.Admin {
+				writeError(w, http.StatusForbidden, model.ErrorCodeForbidden, "Admin access required")
+				return
+			}
+
+			if ok, wait := limiter.Allow(principal.Name); !ok {
+				writeRateLimited(w, wait)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		},
+	)
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/internal/ratelimit"
+)
+
+const busyRetryAfter = "5"
+
+func withIPRateLimit(limiter *ratelimit.Limiter, trustProxy bool, next http.Handler) http.Handler {
+	if limiter == nil {
+		return next
+	}
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasPrefix(r.URL.Path, "/api/") || strings.HasPrefix(r.URL.Path, "/rest/") {
+				if ok, wait := limiter.Allow(clientIP(r, trustProxy)); !ok {
+					writeRateLimited(w, wait)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		},
+	)
+}
+
+func clientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			addresses := strings.Split(forwarded, ",")
+			return strings.TrimSpace(addresses[len(addresses)-1])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func limitConcurrency(slots chan struct{}, next http.HandlerFunc) http.HandlerFunc {
+	if slots == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case slots <- struct{}{}:
+		default:
+			w.Header().Set("Retry-After", busyRetryAfter)
+			writeError(w, http.StatusServiceUnavailable, model.ErrorCodeUnavailable, "Server is busy, try again shortly")
+			return
+		}
+		defer func() { <-slots }()
+		next(w, r)
+	}
+}
+
+func limitSubsonicStreams(slots chan struct{}, next http.HandlerFunc) http.HandlerFunc {
+	limited := limitConcurrency(slots, next)
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch strings.TrimSuffix(r.PathValue("method"), ".view") {
+		case "stream", "download":
+			limited(w, r)
+		default:
+			next(w, r)
+		}
+	}
+}
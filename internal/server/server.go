@@ -4,8 +4,10 @@ import (
 	"context"
 	"net/http"
 
+	"github.com/iamvkosarev/audio-tag-editor/internal/auth"
 	"github.com/iamvkosarev/audio-tag-editor/internal/config"
 	"github.com/iamvkosarev/audio-tag-editor/internal/handler"
+	"github.com/iamvkosarev/audio-tag-editor/internal/ratelimit"
 )
 
 type Server struct {
@@ -13,18 +15,74 @@ type Server struct {
 	config     *config.ServerConfig
 }
 
-func New(cfg *config.Config, h *handler.Handler) *Server {
+func New(cfg *config.Config, h *handler.Handler, authenticator *auth.Authenticator) *Server {
+	var heavySlots chan struct{}
+	if cfg.Server.MaxHeavyRequests > 0 {
+		heavySlots = make(chan struct{}, cfg.Server.MaxHeavyRequests)
+	}
+
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", h.Index)
-	mux.HandleFunc("POST /api/upload", h.Upload)
+	mux.HandleFunc("POST /api/upload", limitConcurrency(heavySlots, h.Upload))
+	mux.HandleFunc("POST /api/upload-from-url", limitConcurrency(heavySlots, h.UploadFromURL))
+	mux.HandleFunc("POST /api/uploads", h.CreateUpload)
+	mux.HandleFunc("GET /api/uploads/{id}", h.UploadStatus)
+	mux.HandleFunc("PATCH /api/uploads/{id}", h.UploadChunk)
+	mux.HandleFunc("DELETE /api/uploads/{id}", h.CancelUpload)
+	mux.HandleFunc("GET /api/files", h.ListFiles)
+	mux.HandleFunc("GET /api/albums", h.Albums)
+	mux.HandleFunc("GET /api/genres", h.Genres)
+	mux.HandleFunc("POST /api/lint", h.Lint)
+	mux.HandleFunc("DELETE /api/files/{id}", h.DeleteFile)
+	mux.HandleFunc("POST /api/delete-selected", h.DeleteSelected)
 	mux.HandleFunc("POST /api/update-tags", h.UpdateTags)
-	mux.HandleFunc("GET /api/download/", h.Download)
-	mux.HandleFunc("GET /api/download-all", h.DownloadAll)
-	mux.HandleFunc("POST /api/download-selected", h.DownloadSelected)
+	mux.HandleFunc("GET /api/download/", limitConcurrency(heavySlots, h.Download))
+	mux.HandleFunc("GET /api/download-all", limitConcurrency(heavySlots, h.DownloadAll))
+	mux.HandleFunc("POST /api/download-selected", limitConcurrency(heavySlots, h.DownloadSelected))
+	mux.HandleFunc("POST /api/lookup", h.Lookup)
+	mux.HandleFunc("POST /api/identify", h.Identify)
+	mux.HandleFunc("POST /api/find-cover", h.FindCover)
+	mux.HandleFunc("POST /api/album-cover", h.AlbumCover)
+	mux.HandleFunc("POST /api/replaygain", h.ReplayGain)
+	mux.HandleFunc("POST /api/fix-encoding", h.FixEncoding)
+	mux.HandleFunc("POST /api/copy-fields", h.CopyFields)
+	mux.HandleFunc("POST /api/transform", h.Transform)
+	mux.HandleFunc("POST /api/sort-names", h.SortNames)
+	mux.HandleFunc("POST /api/number-tracks", h.NumberTracks)
+	mux.HandleFunc("POST /api/tags-from-filename", h.TagsFromFilename)
+	mux.HandleFunc("POST /api/record-filename", h.RecordFilename)
+	mux.HandleFunc("GET /api/export", h.Export)
+	mux.HandleFunc("POST /api/import", h.Import)
+	mux.HandleFunc("GET /api/events/{jobId}", h.Events)
+	mux.HandleFunc("GET /api/cover/{fileId}", h.Cover)
+	mux.HandleFunc("GET /api/verify/{fileId}", limitConcurrency(heavySlots, h.Verify))
+	mux.HandleFunc("GET /api/waveform/{fileId}", limitConcurrency(heavySlots, h.Waveform))
+	mux.HandleFunc("GET /api/chapters/{fileId}", h.Chapters)
+	mux.HandleFunc("PUT /api/chapters/{fileId}", h.SetChapters)
+	mux.HandleFunc("GET /api/history/{fileId}", h.History)
+	mux.HandleFunc("POST /api/revert/{fileId}", h.Revert)
+	mux.HandleFunc("GET /api/library", h.Library)
+	mux.HandleFunc("POST /api/rename", h.Rename)
+	mux.HandleFunc("GET /api/jobs/{id}", h.Job)
+	mux.HandleFunc("DELETE /api/jobs/{id}", h.CancelJob)
+	mux.HandleFunc("GET /api/jobs/{id}/download", h.JobDownload)
+	mux.HandleFunc("POST /api/admin/cleanup", h.Cleanup)
+	mux.HandleFunc("/rest/{method}", limitSubsonicStreams(heavySlots, h.Subsonic))
+	mux.HandleFunc("GET /api/openapi.json", h.OpenAPI)
+	mux.HandleFunc("GET /api/docs", h.Docs)
+
+	keyLimiter := ratelimit.New(cfg.Auth.KeyRateLimit, cfg.Auth.KeyRateBurst)
+	ipLimiter := ratelimit.New(cfg.Server.RateLimit, cfg.Server.RateBurst)
+	var handler http.Handler = mux
+	handler = withAuth(authenticator, keyLimiter, handler)
+	handler = withIPRateLimit(ipLimiter, cfg.Server.TrustProxy, handler)
+	handler = withCORS(cfg.CORS, handler)
+	handler = withTracing(handler)
+	handler = withRequestLogging(handler)
 
 	srv := &http.Server{
 		Addr:         cfg.Server.Address(),
-		Handler:      mux,
+		Handler:      handler,
 		ReadTimeout:  cfg.Server.ReadTimeout,
 		WriteTimeout: cfg.Server.WriteTimeout,
 		IdleTimeout:  cfg.Server.IdleTimeout,
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/config"
+	"github.com/iamvkosarev/audio-tag-editor/internal/handler"
+)
+
+type stubLibrary struct {
+	handler.Library
+}
+
+// Failed Subsonic logins count against the rate limit of the client.
+func TestSubsonicLoginsAreRateLimited(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Server.RateLimit = 60
+	cfg.Server.RateBurst = 3
+	h := handler.New(
+		nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, stubLibrary{}, nil, nil, nil, nil,
+		handler.Options{SubsonicUser: "admin", SubsonicPassword: "secret"},
+	)
+	srv := New(cfg, h, nil)
+
+	for i := range 5 {
+		r := httptest.NewRequest(http.MethodGet, "/rest/ping.view?u=admin&p=guess&v=1.16.1&c=test&f=json", nil)
+		w := httptest.NewRecorder()
+		srv.httpServer.Handler.ServeHTTP(w, r)
+		limited := w.Code == http.StatusTooManyRequests
+		if i < cfg.Server.RateBurst && limited {
+			t.Fatalf("login %d rate limited, want the burst of %d allowed", i+1, cfg.Server.RateBurst)
+		}
+		if i >= cfg.Server.RateBurst && !limited {
+			t.Fatalf("login %d got %d, want 429", i+1, w.Code)
+		}
+	}
+}
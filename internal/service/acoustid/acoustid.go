@@ -0,0 +1,160 @@
+package acoustid
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+)
+
+const minRequestInterval = time.Second / 3
+
+type Client struct {
+	httpClient    *http.Client
+	baseURL       string
+	apiKey        string
+	fingerprinter *Fingerprinter
+	mu            sync.Mutex
+	lastRequest   time.Time
+}
+
+func NewClient(baseURL, apiKey string, timeout time.Duration, fingerprinter *Fingerprinter) *Client {
+	return &Client{
+		httpClient:    &http.Client{Timeout: timeout},
+		baseURL:       strings.TrimSuffix(baseURL, "/"),
+		apiKey:        apiKey,
+		fingerprinter: fingerprinter,
+	}
+}
+
+type lookupResponse struct {
+	Status string `json:"status"`
+	Error  struct {
+		Message string `json:"message"`
+	} `json:"error"`
+	Results []struct {
+		ID         string  `json:"id"`
+		Score      float64 `json:"score"`
+		Recordings []struct {
+			ID       string  `json:"id"`
+			Title    string  `json:"title"`
+			Duration float64 `json:"duration"`
+			Artists  []struct {
+				ID         string `json:"id"`
+				Name       string `json:"name"`
+				JoinPhrase string `json:"joinphrase"`
+			} `json:"artists"`
+			ReleaseGroups []struct {
+				ID    string `json:"id"`
+				Title string `json:"title"`
+			} `json:"releasegroups"`
+		} `json:"recordings"`
+	} `json:"results"`
+}
+
+// Identify fingerprints the file and returns the recordings AcoustID matched.
+func (c *Client) Identify(ctx context.Context, filePath string) ([]model.LookupCandidate, error) {
+	fingerprint, err := c.fingerprinter.Fingerprint(ctx, filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("format", "json")
+	form.Set("client", c.apiKey)
+	form.Set("duration", strconv.Itoa(int(fingerprint.Duration)))
+	form.Set("fingerprint", fingerprint.Fingerprint)
+	form.Set("meta", "recordings releasegroups")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/lookup", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AcoustID request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("AcoustID request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var response lookupResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode AcoustID response (status %d): %w", resp.StatusCode, err)
+	}
+	if response.Status != "ok" {
+		return nil, fmt.Errorf("AcoustID returned an error: %s", response.Error.Message)
+	}
+
+	var candidates []model.LookupCandidate
+	for _, result := range response.Results {
+		for _, rec := range result.Recordings {
+			var artist strings.Builder
+			artistIDs := make([]string, 0, len(rec.Artists))
+			for _, a := range rec.Artists {
+				artist.WriteString(a.Name)
+				artist.WriteString(a.JoinPhrase)
+				artistIDs = append(artistIDs, a.ID)
+			}
+
+			base := model.LookupCandidate{
+				Score:       int(result.Score * 100),
+				Title:       rec.Title,
+				Artist:      artist.String(),
+				Duration:    rec.Duration,
+				RecordingID: rec.ID,
+				CustomTags: map[string]string{
+					"ACOUSTID_ID":          result.ID,
+					"MUSICBRAINZ_TRACKID":  rec.ID,
+					"MUSICBRAINZ_ARTISTID": strings.Join(artistIDs, "; "),
+				},
+			}
+
+			if len(rec.ReleaseGroups) == 0 {
+				candidates = append(candidates, base)
+				continue
+			}
+			for _, group := range rec.ReleaseGroups {
+				candidate := base
+				candidate.Album = group.Title
+				candidate.CustomTags = make(map[string]string, len(base.CustomTags)+1)
+				for key, value := range base.CustomTags {
+					candidate.CustomTags[key] = value
+				}
+				candidate.CustomTags["MUSICBRAINZ_RELEASEGROUPID"] = group.ID
+				candidates = append(candidates, candidate)
+			}
+		}
+	}
+
+	return candidates, nil
+}
+
+func (c *Client) wait(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delay := minRequestInterval - time.Since(c.lastRequest)
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	c.lastRequest = time.Now()
+	return nil
+}
@@ -0,0 +1,50 @@
+package acoustid
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+)
+
+var ErrFingerprinterUnavailable = errors.New("fpcalc binary not found")
+
+type Fingerprint struct {
+	Duration    float64 `json:"duration"`
+	Fingerprint string  `json:"fingerprint"`
+}
+
+// Fingerprinter computes Chromaprint fingerprints with fpcalc.
+type Fingerprinter struct {
+	binary string
+}
+
+func NewFingerprinter(binary string) *Fingerprinter {
+	return &Fingerprinter{binary: binary}
+}
+
+func (f *Fingerprinter) Fingerprint(ctx context.Context, filePath string) (*Fingerprint, error) {
+	path, err := exec.LookPath(f.binary)
+	if err != nil {
+		return nil, ErrFingerprinterUnavailable
+	}
+
+	output, err := exec.CommandContext(ctx, path, "-json", filePath).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("fpcalc failed: %s", exitErr.Stderr)
+		}
+		return nil, fmt.Errorf("failed to run fpcalc: %w", err)
+	}
+
+	var fingerprint Fingerprint
+	if err := json.Unmarshal(output, &fingerprint); err != nil {
+		return nil, fmt.Errorf("failed to decode fpcalc output: %w", err)
+	}
+	if fingerprint.Fingerprint == "" {
+		return nil, fmt.Errorf("fpcalc returned an empty fingerprint")
+	}
+	return &fingerprint, nil
+}
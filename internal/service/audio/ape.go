@@ -0,0 +1,147 @@
+package audio
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/internal/vfs"
+)
+
+type monkeysAudioHeader struct {
+	sampleRate    int
+	channels      int
+	bitsPerSample int
+	totalBlocks   int64
+}
+
+type apeHandler struct{}
+
+func newAPEHandler() *apeHandler {
+	return &apeHandler{}
+}
+
+func (h *apeHandler) Format() string {
+	return "APE"
+}
+
+func (h *apeHandler) Capabilities() model.Capabilities {
+	return apeCapabilities
+}
+
+func (h *apeHandler) ExtractDuration(ctx context.Context, r io.ReaderAt, size int64) (float64, error) {
+	header, err := readMonkeysAudioHeader(r, size)
+	if err != nil {
+		return 0, err
+	}
+	if header.sampleRate == 0 || header.totalBlocks == 0 {
+		return 0, fmt.Errorf("could not determine APE duration")
+	}
+	return float64(header.totalBlocks) / float64(header.sampleRate), nil
+}
+
+func (h *apeHandler) ExtractAudioInfo(r io.ReaderAt, size int64, result *model.FileMetadata) error {
+	header, err := readMonkeysAudioHeader(r, size)
+	if err != nil {
+		return err
+	}
+	start, end, err := apeTaggedAudioRange(r, size)
+	if err != nil {
+		return err
+	}
+	result.Codec = "Monkey's Audio"
+	result.Lossless = true
+	result.SampleRate = header.sampleRate
+	result.Channels = header.channels
+	result.BitsPerSample = header.bitsPerSample
+	result.Bitrate = averageBitrate(end-start, result.Duration)
+	return nil
+}
+
+func (h *apeHandler) Parse(r io.ReaderAt, size int64, name string) (*model.FileMetadata, error) {
+	result, err := readAPETaggedMetadata(r, size, name, h.Format())
+	if err != nil {
+		return result, err
+	}
+	if duration, err := h.ExtractDuration(context.Background(), r, size); err == nil {
+		result.Duration = duration
+	}
+	return result, nil
+}
+
+func (h *apeHandler) UpdateTags(ctx context.Context, fsys vfs.FS, filePath string, update *model.TagUpdate) error {
+	return updateTrailingAPETag(fsys, filePath, update)
+}
+
+func (h *apeHandler) writeAudioStream(r io.ReaderAt, size int64, w io.Writer) error {
+	return writeAPETaggedAudioStream(r, size, w)
+}
+
+func readMonkeysAudioHeader(r io.ReaderAt, size int64) (*monkeysAudioHeader, error) {
+	start, err := skipID3v2Tags(r, size)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 32)
+	if _, err := r.ReadAt(buf, start); err != nil {
+		return nil, fmt.Errorf("failed to read APE header: %w", unexpectedEOF(err))
+	}
+	if string(buf[0:4]) != "MAC " {
+		return nil, fmt.Errorf("not a valid APE file")
+	}
+
+	version := binary.LittleEndian.Uint16(buf[4:6])
+	var blocksPerFrame, finalFrameBlocks, totalFrames uint32
+	header := &monkeysAudioHeader{}
+	if version >= 3980 {
+		descriptorSize := int64(binary.LittleEndian.Uint32(buf[8:12]))
+		data := make([]byte, 24)
+		if _, err := r.ReadAt(data, start+descriptorSize); err != nil {
+			return nil, fmt.Errorf("failed to read APE header: %w", unexpectedEOF(err))
+		}
+		blocksPerFrame = binary.LittleEndian.Uint32(data[4:8])
+		finalFrameBlocks = binary.LittleEndian.Uint32(data[8:12])
+		totalFrames = binary.LittleEndian.Uint32(data[12:16])
+		header.bitsPerSample = int(binary.LittleEndian.Uint16(data[16:18]))
+		header.channels = int(binary.LittleEndian.Uint16(data[18:20]))
+		header.sampleRate = int(binary.LittleEndian.Uint32(data[20:24]))
+	} else {
+		compression := binary.LittleEndian.Uint16(buf[6:8])
+		flags := binary.LittleEndian.Uint16(buf[8:10])
+		header.channels = int(binary.LittleEndian.Uint16(buf[10:12]))
+		header.sampleRate = int(binary.LittleEndian.Uint32(buf[12:16]))
+		totalFrames = binary.LittleEndian.Uint32(buf[24:28])
+		finalFrameBlocks = binary.LittleEndian.Uint32(buf[28:32])
+		switch {
+		case version >= 3950:
+			blocksPerFrame = 73728 * 4
+		case version >= 3900 || version >= 3800 && compression == 4000:
+			blocksPerFrame = 73728
+		default:
+			blocksPerFrame = 9216
+		}
+		switch {
+		case flags&0x01 != 0:
+			header.bitsPerSample = 8
+		case flags&0x08 != 0:
+			header.bitsPerSample = 24
+		default:
+			header.bitsPerSample = 16
+		}
+	}
+	if totalFrames > 0 {
+		header.totalBlocks = int64(totalFrames-1)*int64(blocksPerFrame) + int64(finalFrameBlocks)
+	}
+	return header, nil
+}
+
+func getAPEHandler(ext string) FormatHandler {
+	ext = strings.ToUpper(ext)
+	if ext == "APE" {
+		return newAPEHandler()
+	}
+	return nil
+}
@@ -0,0 +1,539 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/internal/vfs"
+)
+
+const (
+	apeTagFooterSize = 32
+	apeTagVersion    = 2000
+
+	apeFlagHasHeader = 1 << 31
+	apeFlagIsHeader  = 1 << 29
+
+	apeItemTypeMask = 3 << 1
+	apeItemBinary   = 1 << 1
+)
+
+var apeCapabilities = func() model.Capabilities {
+	capabilities := fullCapabilities
+	capabilities.Chapters = false
+	capabilities.SyncedLyrics = false
+	return capabilities
+}()
+
+var apePictureKeys = [...]string{
+	"Other", "Icon", "Other Icon", "Front", "Back", "Leaflet", "Media", "Lead Artist", "Artist",
+	"Conductor", "Band", "Composer", "Lyricist", "Recording Location", "During Recording",
+	"During Performance", "Video Capture", "Fish", "Illustration", "Band Logotype", "Publisher Logotype",
+}
+
+type apeItem struct {
+	key   string
+	flags uint32
+	value []byte
+}
+
+type apeTag struct {
+	items []apeItem
+}
+
+func findAPETag(r io.ReaderAt, start, end int64) (int64, *apeTag, error) {
+	if end-start < apeTagFooterSize {
+		return end, nil, nil
+	}
+	footer := make([]byte, apeTagFooterSize)
+	if _, err := r.ReadAt(footer, end-apeTagFooterSize); err != nil {
+		return 0, nil, fmt.Errorf("failed to read APE tag footer: %w", err)
+	}
+	flags := binary.LittleEndian.Uint32(footer[20:24])
+	if string(footer[0:8]) != "APETAGEX" || flags&apeFlagIsHeader != 0 {
+		return end, nil, nil
+	}
+
+	size := int64(binary.LittleEndian.Uint32(footer[12:16]))
+	tagStart := end - size
+	if flags&apeFlagHasHeader != 0 {
+		tagStart -= apeTagFooterSize
+	}
+	if size < apeTagFooterSize || tagStart < start {
+		return 0, nil, fmt.Errorf("APE tag is truncated")
+	}
+	data := make([]byte, size-apeTagFooterSize)
+	if _, err := r.ReadAt(data, end-size); err != nil {
+		return 0, nil, fmt.Errorf("failed to read APE tag: %w", err)
+	}
+	items, err := parseAPEItems(data, binary.LittleEndian.Uint32(footer[16:20]))
+	if err != nil {
+		return 0, nil, err
+	}
+	return tagStart, &apeTag{items: items}, nil
+}
+
+func parseAPEItems(data []byte, count uint32) ([]apeItem, error) {
+	var items []apeItem
+	for range count {
+		if len(data) < 8 {
+			return nil, fmt.Errorf("APE tag item is truncated")
+		}
+		size := int64(binary.LittleEndian.Uint32(data[0:4]))
+		flags := binary.LittleEndian.Uint32(data[4:8])
+		data = data[8:]
+		keyEnd := bytes.IndexByte(data, 0)
+		if keyEnd < 0 || size > int64(len(data)-keyEnd-1) {
+			return nil, fmt.Errorf("APE tag item is truncated")
+		}
+		items = append(items, apeItem{key: string(data[:keyEnd]), flags: flags, value: data[keyEnd+1 : int64(keyEnd+1)+size]})
+		data = data[int64(keyEnd+1)+size:]
+	}
+	return items, nil
+}
+
+func readTrailingAPETag(r io.ReaderAt, start, size int64) (int64, int64, *apeTag, error) {
+	end, _, err := findID3v1Tags(r, start, size)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	tagStart, tag, err := findAPETag(r, start, end)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	return tagStart, end, tag, nil
+}
+
+func (t *apeTag) marshal() []byte {
+	if len(t.items) == 0 {
+		return nil
+	}
+	var items bytes.Buffer
+	for _, item := range t.items {
+		items.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(item.value))))
+		items.Write(binary.LittleEndian.AppendUint32(nil, item.flags))
+		items.WriteString(item.key)
+		items.WriteByte(0)
+		items.Write(item.value)
+	}
+	size := items.Len() + apeTagFooterSize
+	var buf bytes.Buffer
+	buf.Write(apeTagHeader(size, len(t.items), apeFlagHasHeader|apeFlagIsHeader))
+	buf.Write(items.Bytes())
+	buf.Write(apeTagHeader(size, len(t.items), apeFlagHasHeader))
+	return buf.Bytes()
+}
+
+func apeTagHeader(size, count int, flags uint32) []byte {
+	header := make([]byte, apeTagFooterSize)
+	copy(header, "APETAGEX")
+	binary.LittleEndian.PutUint32(header[8:12], apeTagVersion)
+	binary.LittleEndian.PutUint32(header[12:16], uint32(size))
+	binary.LittleEndian.PutUint32(header[16:20], uint32(count))
+	binary.LittleEndian.PutUint32(header[20:24], flags)
+	return header
+}
+
+func (t *apeTag) text(key string) string {
+	for _, item := range t.items {
+		if strings.EqualFold(item.key, key) && item.isText() {
+			return item.text()
+		}
+	}
+	return ""
+}
+
+func (t *apeTag) setText(key, value string, keepEmpty bool) {
+	t.remove(key)
+	if value != "" || keepEmpty {
+		t.items = append(t.items, apeItem{key: key, value: []byte(value)})
+	}
+}
+
+func (t *apeTag) remove(keys ...string) {
+	items := t.items[:0]
+	for _, item := range t.items {
+		removed := false
+		for _, key := range keys {
+			if strings.EqualFold(item.key, key) {
+				removed = true
+				break
+			}
+		}
+		if !removed {
+			items = append(items, item)
+		}
+	}
+	t.items = items
+}
+
+func (i apeItem) isText() bool {
+	return i.flags&apeItemTypeMask == 0
+}
+
+func (i apeItem) text() string {
+	return strings.TrimSpace(strings.Join(strings.FieldsFunc(string(i.value), func(r rune) bool { return r == 0 }), "; "))
+}
+
+func extractAPEMetadata(tag *apeTag, result *model.FileMetadata) {
+	for _, item := range tag.items {
+		key := strings.ToUpper(item.key)
+		if !item.isText() {
+			if item.flags&apeItemTypeMask == apeItemBinary {
+				if picture, ok := parseAPEPicture(key, item.value); ok {
+					result.Pictures = append(result.Pictures, picture)
+				}
+			}
+			continue
+		}
+		value := item.text()
+		switch key {
+		case "TITLE":
+			result.Title = value
+		case "ARTIST":
+			result.Artist = value
+		case "ALBUM":
+			result.Album = value
+		case "ALBUM ARTIST", "ALBUMARTIST":
+			result.AlbumArtist = value
+		case "COMPOSER":
+			result.Composer = value
+		case "COMMENT":
+			result.Comment = value
+		case "YEAR":
+			result.Year = parseLeadingInt(value)
+			result.ReleaseDate = normalizeReleaseDate(value)
+		case "GENRE":
+			result.Genre = value
+		case "TRACK":
+			result.Track, result.TotalTracks = splitNumberPair(value)
+		case "DISC":
+			result.Disc, result.TotalDiscs = splitNumberPair(value)
+		case "BPM":
+			result.BPM = parseLeadingInt(value)
+		case "COMPILATION":
+			result.Compilation = value == "1"
+		case "LYRICS":
+			result.Lyrics = value
+		case "ISRC":
+			result.ISRC = value
+		case "BARCODE":
+			result.Barcode = value
+		case "CATALOGNUMBER":
+			result.CatalogNumber = value
+		case "LABEL", "PUBLISHER":
+			result.Label = value
+		case "TITLESORT":
+			result.TitleSort = value
+		case "ARTISTSORT":
+			result.ArtistSort = value
+		case "ALBUMARTISTSORT":
+			result.AlbumArtistSort = value
+		case "ALBUMSORT":
+			result.AlbumSort = value
+		case "RATING":
+			result.Rating = parseVorbisRating(value)
+		case "PLAYCOUNT":
+			result.PlayCount = parseLeadingInt(value)
+		default:
+			if standardVorbisKeys[key] {
+				continue
+			}
+			for _, part := range strings.Split(string(item.value), "\x00") {
+				addCustomTag(result, key, part)
+			}
+		}
+	}
+}
+
+func (t *apeTag) apply(update *model.TagUpdate) error {
+	for _, field := range []struct {
+		key   string
+		field string
+		value *string
+	}{
+		{"Title", "title", update.Title},
+		{"Artist", "artist", update.Artist},
+		{"Album", "album", update.Album},
+		{"Album Artist", "albumArtist", update.AlbumArtist},
+		{"Composer", "composer", update.Composer},
+		{"Comment", "comment", update.Comment},
+		{"Genre", "genre", update.Genre},
+		{"TitleSort", "titleSort", update.TitleSort},
+		{"ArtistSort", "artistSort", update.ArtistSort},
+		{"AlbumArtistSort", "albumArtistSort", update.AlbumArtistSort},
+		{"AlbumSort", "albumSort", update.AlbumSort},
+		{"Lyrics", "lyrics", update.Lyrics},
+		{"ISRC", "isrc", update.ISRC},
+		{"Barcode", "barcode", update.Barcode},
+		{"CatalogNumber", "catalogNumber", update.CatalogNumber},
+		{"Label", "label", update.Label},
+	} {
+		if field.value != nil {
+			t.setText(field.key, *field.value, update.KeepsEmpty(field.field))
+		}
+	}
+	if update.AlbumArtist != nil {
+		t.remove("AlbumArtist")
+	}
+	if update.Label != nil {
+		t.remove("Publisher")
+	}
+	if date, ok := updatedReleaseDate(t.text("Year"), update); ok {
+		t.setText("Year", date, false)
+	}
+	t.setNumber("Track", update.Track, update.TotalTracks)
+	t.setNumber("Disc", update.Disc, update.TotalDiscs)
+	if update.BPM != nil {
+		t.setText("BPM", formatPositive(*update.BPM), false)
+	}
+	if update.Compilation != nil {
+		value := ""
+		if *update.Compilation {
+			value = "1"
+		}
+		t.setText("Compilation", value, false)
+	}
+	if update.Rating != nil {
+		t.setText("Rating", formatPositive(*update.Rating), false)
+	}
+	if update.PlayCount != nil {
+		t.setText("PlayCount", formatPositive(*update.PlayCount), false)
+	}
+	for _, key := range sortedCustomTagKeys(update.CustomTags) {
+		key, value := strings.ToUpper(key), update.CustomTags[key]
+		if isMultiValuedID(key) {
+			value = strings.Join(splitCustomTagValues(value), "\x00")
+		}
+		t.setText(key, value, false)
+	}
+
+	if update.CoverArt != nil && *update.CoverArt != "" {
+		for pictureType := range apePictureKeys {
+			t.remove(apePictureKey(pictureType))
+		}
+		if err := t.addPicture(model.PictureTypeFrontCover, *update.CoverArt); err != nil {
+			return fmt.Errorf("failed to parse cover art data: %w", err)
+		}
+	}
+	// There is one item per picture type, so the last update of a type wins.
+	for _, picture := range update.Pictures {
+		t.remove(apePictureKey(picture.Type))
+		if picture.Data == "" {
+			continue
+		}
+		if err := t.addPicture(picture.Type, picture.Data); err != nil {
+			return fmt.Errorf("failed to parse picture data: %w", err)
+		}
+	}
+	return nil
+}
+
+func (t *apeTag) setNumber(key string, number, total *int) {
+	if number == nil && total == nil {
+		return
+	}
+	currentNumber, currentTotal := splitNumberPair(t.text(key))
+	if number != nil {
+		currentNumber = *number
+	}
+	if total != nil {
+		currentTotal = *total
+	}
+	t.setText(key, formatNumberPair(currentNumber, currentTotal), false)
+}
+
+func (t *apeTag) addPicture(pictureType int, dataURI string) error {
+	data, mimeType, err := decodeCover(dataURI)
+	if err != nil {
+		return err
+	}
+	extension := ".jpg"
+	switch normalizeMimeType(mimeType) {
+	case "image/png":
+		extension = ".png"
+	case "image/gif":
+		extension = ".gif"
+	case "image/webp":
+		extension = ".webp"
+	}
+	value := append([]byte("cover"+extension+"\x00"), data...)
+	t.items = append(t.items, apeItem{key: apePictureKey(pictureType), flags: apeItemBinary, value: value})
+	return nil
+}
+
+func apePictureKey(pictureType int) string {
+	if pictureType < 0 || pictureType >= len(apePictureKeys) {
+		pictureType = model.PictureTypeOther
+	}
+	return "Cover Art (" + apePictureKeys[pictureType] + ")"
+}
+
+func parseAPEPicture(key string, value []byte) (model.Picture, bool) {
+	name, ok := strings.CutPrefix(key, "COVER ART (")
+	if !ok {
+		return model.Picture{}, false
+	}
+	name = strings.TrimSuffix(name, ")")
+	pictureType := model.PictureTypeOther
+	for i, candidate := range apePictureKeys {
+		if strings.EqualFold(candidate, name) {
+			pictureType = i
+			break
+		}
+	}
+	_, data, ok := bytes.Cut(value, []byte{0})
+	if !ok || len(data) == 0 {
+		return model.Picture{}, false
+	}
+	return newPicture(pictureType, "", http.DetectContentType(data), data), true
+}
+
+func readAPETaggedMetadata(r io.ReaderAt, size int64, name, format string) (*model.FileMetadata, error) {
+	result := &model.FileMetadata{
+		Size:   size,
+		Format: format,
+	}
+	start, err := skipID3v2Tags(r, size)
+	if err != nil {
+		result.Title = name
+		return result, err
+	}
+	_, _, tag, err := readTrailingAPETag(r, start, size)
+	if err != nil {
+		result.Title = name
+		return result, err
+	}
+	if tag != nil {
+		extractAPEMetadata(tag, result)
+	} else if start > 0 {
+		data := make([]byte, start)
+		if _, err := r.ReadAt(data, 0); err != nil {
+			result.Title = name
+			return result, fmt.Errorf("failed to read ID3v2 tag: %w", err)
+		}
+		extractID3ChunkMetadata(data, result)
+	}
+	if result.Title == "" {
+		result.Title = name
+	}
+	return result, nil
+}
+
+func apeTaggedAudioRange(r io.ReaderAt, size int64) (int64, int64, error) {
+	start, err := skipID3v2Tags(r, size)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, _, _, err := readTrailingAPETag(r, start, size)
+	if err != nil {
+		return 0, 0, err
+	}
+	return start, end, nil
+}
+
+func writeAPETaggedAudioStream(r io.ReaderAt, size int64, w io.Writer) error {
+	start, end, err := apeTaggedAudioRange(r, size)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, io.NewSectionReader(r, start, end-start))
+	return err
+}
+
+func updateTrailingAPETag(fsys vfs.FS, filePath string, update *model.TagUpdate) error {
+	file, err := fsys.OpenFile(filePath, os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+	start, err := skipID3v2Tags(file, stat.Size())
+	if err != nil {
+		return err
+	}
+	tagStart, tagEnd, tag, err := readTrailingAPETag(file, start, stat.Size())
+	if err != nil {
+		return err
+	}
+	if tag == nil {
+		if start > 0 {
+			file.Close()
+			return updateLeadingID3Tag(fsys, filePath, start, update)
+		}
+		tag = &apeTag{}
+	}
+	if err := tag.apply(update); err != nil {
+		return err
+	}
+
+	tail := make([]byte, stat.Size()-tagEnd)
+	if _, err := file.ReadAt(tail, tagEnd); err != nil {
+		return fmt.Errorf("failed to read ID3v1 tag: %w", err)
+	}
+	if err := file.Truncate(tagStart); err != nil {
+		return fmt.Errorf("failed to truncate file: %w", err)
+	}
+	if _, err := file.WriteAt(append(tag.marshal(), tail...), tagStart); err != nil {
+		return fmt.Errorf("failed to write APE tag: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close file: %w", err)
+	}
+	if start > 0 {
+		return updateLeadingID3Tag(fsys, filePath, start, update)
+	}
+	return nil
+}
+
+func updateLeadingID3Tag(fsys vfs.FS, filePath string, start int64, update *model.TagUpdate) error {
+	src, err := fsys.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer src.Close()
+
+	existing := make([]byte, start)
+	if _, err := src.ReadAt(existing, 0); err != nil {
+		return fmt.Errorf("failed to read ID3v2 tag: %w", err)
+	}
+	id3Data, err := buildID3Chunk(existing, update)
+	if err != nil {
+		return err
+	}
+
+	tempFile := filePath + ".tmp"
+	dst, err := vfs.Create(fsys, tempFile)
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer fsys.Remove(tempFile)
+	defer dst.Close()
+
+	if _, err := dst.Write(id3Data); err != nil {
+		return fmt.Errorf("failed to write ID3v2 tag: %w", err)
+	}
+	if _, err := io.Copy(dst, io.NewSectionReader(src, start, math.MaxInt64-start)); err != nil {
+		return fmt.Errorf("failed to copy audio data: %w", err)
+	}
+	if err := dst.Close(); err != nil {
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+	src.Close()
+
+	if err := fsys.Rename(tempFile, filePath); err != nil {
+		return fmt.Errorf("failed to rename temp file: %w", err)
+	}
+	return nil
+}
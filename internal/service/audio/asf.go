@@ -0,0 +1,952 @@
+package audio
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/internal/vfs"
+)
+
+type asfGUID [16]byte
+
+func mustASFGUID(s string) asfGUID {
+	raw, err := hex.DecodeString(strings.ReplaceAll(s, "-", ""))
+	if err != nil || len(raw) != 16 {
+		panic("invalid ASF GUID " + s)
+	}
+	var guid asfGUID
+	binary.LittleEndian.PutUint32(guid[0:4], binary.BigEndian.Uint32(raw[0:4]))
+	binary.LittleEndian.PutUint16(guid[4:6], binary.BigEndian.Uint16(raw[4:6]))
+	binary.LittleEndian.PutUint16(guid[6:8], binary.BigEndian.Uint16(raw[6:8]))
+	copy(guid[8:], raw[8:])
+	return guid
+}
+
+var (
+	asfHeaderObject                     = mustASFGUID("75B22630-668E-11CF-A6D9-00AA0062CE6C")
+	asfFilePropertiesObject             = mustASFGUID("8CABDCA1-A947-11CF-8EE4-00C00C205365")
+	asfStreamPropertiesObject           = mustASFGUID("B7DC0791-A9B7-11CF-8EE6-00C00C205365")
+	asfHeaderExtensionObject            = mustASFGUID("5FBF03B5-A92E-11CF-8EE3-00C00C205365")
+	asfContentDescriptionObject         = mustASFGUID("75B22633-668E-11CF-A6D9-00AA0062CE6C")
+	asfExtendedContentDescriptionObject = mustASFGUID("D2D0A440-E307-11D2-97F0-00A0C95EA850")
+	asfMetadataLibraryObject            = mustASFGUID("44231C94-9498-49D1-A141-1D134E457054")
+	asfAudioMedia                       = mustASFGUID("F8699E40-5B4D-11CF-A8FD-00805F5C442B")
+	asfHeaderExtensionReserved          = mustASFGUID("ABD3D211-A9BA-11CF-8EE6-00C00C205365")
+)
+
+const (
+	asfUnicode uint16 = iota
+	asfBytes
+	asfBool
+	asfDWord
+	asfQWord
+	asfWord
+)
+
+const (
+	asfTitle = iota
+	asfAuthor
+	asfCopyright
+	asfDescription
+	asfRating
+)
+
+var asfSharedRatings = [...]int{1, 25, 50, 75, 99}
+
+var asfTechnicalAttributes = map[string]bool{
+	"WMFSDKVersion": true, "WMFSDKNeeded": true, "IsVBR": true, "DeviceConformanceTemplate": true,
+	"WM/ToolName": true, "WM/ToolVersion": true, "WM/EncodingSettings": true, "WM/EncodingTime": true,
+	"WM/MediaClassPrimaryID": true, "WM/MediaClassSecondaryID": true, "WM/WMContentID": true,
+	"WM/WMCollectionID": true, "WM/WMCollectionGroupID": true, "WM/UniqueFileIdentifier": true,
+	"WM/Provider": true, "WM/ProviderRating": true, "WM/ProviderStyle": true, "WM/MCDI": true,
+}
+
+var asfCodecs = map[uint16]string{
+	0x0001: "PCM",
+	0x000A: "WMA Voice",
+	0x0055: "MP3",
+	0x0160: "WMA v1",
+	0x0161: "WMA",
+	0x0162: "WMA Pro",
+	0x0163: "WMA Lossless",
+}
+
+type asfObject struct {
+	guid asfGUID
+	data []byte // the object without its 24-byte header
+}
+
+type asfAttribute struct {
+	name     string
+	kind     uint16
+	value    []byte
+	library  bool // kept in the Metadata Library object, which allows values over 64 KiB
+	language uint16
+	stream   uint16
+}
+
+type asfFile struct {
+	headerSize  int64
+	objects     []asfObject // children of the header object
+	extension   []asfObject // children of the header extension object
+	description [5]string
+	attributes  []asfAttribute
+}
+
+type asfHandler struct{}
+
+func newASFHandler() *asfHandler {
+	return &asfHandler{}
+}
+
+func (h *asfHandler) Format() string {
+	return "WMA"
+}
+
+func (h *asfHandler) Capabilities() model.Capabilities {
+	capabilities := fullCapabilities
+	capabilities.Chapters = false
+	capabilities.SyncedLyrics = false
+	return capabilities
+}
+
+func (h *asfHandler) ExtractDuration(ctx context.Context, r io.ReaderAt, size int64) (float64, error) {
+	asf, err := h.readHeader(r, size)
+	if err != nil {
+		return 0, err
+	}
+	return h.duration(asf)
+}
+
+func (h *asfHandler) duration(asf *asfFile) (float64, error) {
+	data := asf.object(asfFilePropertiesObject)
+	if len(data) < 80 {
+		return 0, fmt.Errorf("ASF file properties object not found")
+	}
+	if binary.LittleEndian.Uint32(data[64:68])&1 != 0 {
+		return 0, fmt.Errorf("ASF file is a broadcast without a duration")
+	}
+	playDuration := binary.LittleEndian.Uint64(data[40:48])
+	preroll := binary.LittleEndian.Uint64(data[56:64])
+	duration := float64(playDuration)/1e7 - float64(preroll)/1000
+	if duration <= 0 {
+		return 0, fmt.Errorf("could not determine ASF duration")
+	}
+	return duration, nil
+}
+
+func (h *asfHandler) ExtractAudioInfo(r io.ReaderAt, size int64, result *model.FileMetadata) error {
+	asf, err := h.readHeader(r, size)
+	if err != nil {
+		return err
+	}
+
+	for _, object := range asf.objects {
+		if object.guid != asfStreamPropertiesObject || len(object.data) < 54 || asfGUID(object.data[0:16]) != asfAudioMedia {
+			continue
+		}
+		typeSpecificSize := int(binary.LittleEndian.Uint32(object.data[40:44]))
+		if typeSpecificSize < 16 || 54+typeSpecificSize > len(object.data) {
+			return fmt.Errorf("ASF audio stream properties too small")
+		}
+		// The type-specific data of audio streams is a WAVEFORMATEX.
+		format := object.data[54 : 54+typeSpecificSize]
+		formatTag := binary.LittleEndian.Uint16(format[0:2])
+		result.Codec = asfCodecs[formatTag]
+		if result.Codec == "" {
+			result.Codec = fmt.Sprintf("ASF format 0x%04X", formatTag)
+		}
+		result.Lossless = formatTag == 0x0001 || formatTag == 0x0163
+		result.Channels = int(binary.LittleEndian.Uint16(format[2:4]))
+		result.SampleRate = int(binary.LittleEndian.Uint32(format[4:8]))
+		result.Bitrate = int(binary.LittleEndian.Uint32(format[8:12])) * 8 / 1000
+		if result.Lossless {
+			result.BitsPerSample = int(binary.LittleEndian.Uint16(format[14:16]))
+		}
+		return nil
+	}
+
+	return fmt.Errorf("ASF audio stream not found")
+}
+
+func (h *asfHandler) Parse(r io.ReaderAt, size int64, name string) (*model.FileMetadata, error) {
+	result := &model.FileMetadata{
+		Size:   size,
+		Format: "WMA",
+	}
+
+	asf, err := h.readHeader(r, size)
+	if err != nil {
+		result.Title = name
+		return result, err
+	}
+
+	result.Title = asf.description[asfTitle]
+	result.Artist = asf.description[asfAuthor]
+	result.Comment = asf.description[asfDescription]
+	for _, attribute := range asf.attributes {
+		if !attribute.isTag() {
+			continue
+		}
+		value := strings.TrimSpace(attribute.text())
+		switch attribute.name {
+		case "WM/AlbumTitle":
+			result.Album = value
+		case "WM/AlbumArtist":
+			result.AlbumArtist = value
+		case "WM/Composer":
+			result.Composer = value
+		case "WM/Genre":
+			result.Genre = value
+		case "WM/Year":
+			result.Year = parseLeadingInt(value)
+			result.ReleaseDate = normalizeReleaseDate(value)
+		case "WM/TrackNumber":
+			var total int
+			result.Track, total = splitNumberPair(value)
+			if result.TotalTracks == 0 {
+				result.TotalTracks = total
+			}
+		case "WM/Track":
+			// The legacy track attribute counts from zero.
+			if result.Track == 0 && value != "" {
+				result.Track = parseLeadingInt(value) + 1
+			}
+		case "TotalTracks":
+			result.TotalTracks = parseLeadingInt(value)
+		case "WM/PartOfSet":
+			var total int
+			result.Disc, total = splitNumberPair(value)
+			if result.TotalDiscs == 0 {
+				result.TotalDiscs = total
+			}
+		case "TotalDiscs":
+			result.TotalDiscs = parseLeadingInt(value)
+		case "WM/BeatsPerMinute":
+			result.BPM = parseLeadingInt(value)
+		case "WM/IsCompilation":
+			result.Compilation = value == "1" || strings.EqualFold(value, "true")
+		case "WM/Lyrics":
+			result.Lyrics = value
+		case "WM/ISRC":
+			result.ISRC = value
+		case "WM/Barcode":
+			result.Barcode = value
+		case "WM/CatalogNo":
+			result.CatalogNumber = value
+		case "WM/Publisher":
+			result.Label = value
+		case "WM/TitleSortOrder":
+			result.TitleSort = value
+		case "WM/ArtistSortOrder":
+			result.ArtistSort = value
+		case "WM/AlbumArtistSortOrder":
+			result.AlbumArtistSort = value
+		case "WM/AlbumSortOrder":
+			result.AlbumSort = value
+		case "WM/SharedUserRating":
+			result.Rating = ratingFromASF(parseLeadingInt(value))
+		case "FMPS/Playcount":
+			result.PlayCount = parseLeadingInt(value)
+		case "WM/Picture":
+			if picture, ok := parseASFPicture(attribute.value); ok {
+				result.Pictures = append(result.Pictures, picture)
+			}
+		default:
+			if attribute.kind != asfUnicode || asfTechnicalAttributes[attribute.name] {
+				continue
+			}
+			key, ok := asfCustomTagKeys[strings.ToUpper(attribute.name)]
+			if !ok {
+				key = attribute.name
+			}
+			addCustomTag(result, key, value)
+		}
+	}
+
+	if result.Title == "" {
+		result.Title = name
+	}
+	if duration, err := h.duration(asf); err == nil && duration > 0 {
+		result.Duration = duration
+	}
+
+	return result, nil
+}
+
+func (h *asfHandler) UpdateTags(ctx context.Context, fsys vfs.FS, filePath string, update *model.TagUpdate) error {
+	src, err := fsys.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open WMA file: %w", err)
+	}
+	defer src.Close()
+
+	stat, err := src.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	asf, err := h.readHeader(src, stat.Size())
+	if err != nil {
+		return err
+	}
+	if err := asf.apply(update); err != nil {
+		return err
+	}
+	bodySize := stat.Size() - asf.headerSize
+	header, err := asf.marshal(bodySize)
+	if err != nil {
+		return err
+	}
+
+	tempFile := filePath + ".tmp"
+	dst, err := vfs.Create(fsys, tempFile)
+	if err != nil {
+		return fmt.Errorf("failed to create temp WMA file: %w", err)
+	}
+	defer fsys.Remove(tempFile)
+	defer dst.Close()
+
+	writer := bufio.NewWriter(dst)
+	if _, err := writer.Write(header); err != nil {
+		return fmt.Errorf("failed to write ASF header: %w", err)
+	}
+	if _, err := io.Copy(writer, io.NewSectionReader(src, asf.headerSize, bodySize)); err != nil {
+		return fmt.Errorf("failed to copy ASF data: %w", err)
+	}
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to flush temp WMA file: %w", err)
+	}
+	if err := dst.Close(); err != nil {
+		return fmt.Errorf("failed to close temp WMA file: %w", err)
+	}
+	src.Close()
+
+	if err := fsys.Rename(tempFile, filePath); err != nil {
+		return fmt.Errorf("failed to rename temp file: %w", err)
+	}
+
+	return nil
+}
+
+func (h *asfHandler) writeAudioStream(r io.ReaderAt, size int64, w io.Writer) error {
+	asf, err := h.readHeader(r, size)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, io.NewSectionReader(r, asf.headerSize, size-asf.headerSize))
+	return err
+}
+
+func (h *asfHandler) readHeader(r io.ReaderAt, size int64) (*asfFile, error) {
+	prefix := make([]byte, 30)
+	if _, err := r.ReadAt(prefix, 0); err != nil {
+		return nil, fmt.Errorf("failed to read ASF header: %w", err)
+	}
+	if asfGUID(prefix[0:16]) != asfHeaderObject {
+		return nil, fmt.Errorf("not a valid ASF file")
+	}
+	headerSize := binary.LittleEndian.Uint64(prefix[16:24])
+	if headerSize < 30 || headerSize > uint64(size) {
+		return nil, fmt.Errorf("ASF header object is truncated")
+	}
+	header := make([]byte, headerSize-30)
+	if _, err := r.ReadAt(header, 30); err != nil {
+		return nil, fmt.Errorf("failed to read ASF header: %w", err)
+	}
+	objects, err := parseASFObjects(header)
+	if err != nil {
+		return nil, err
+	}
+
+	asf := &asfFile{headerSize: int64(headerSize)}
+	for _, object := range objects {
+		switch object.guid {
+		case asfContentDescriptionObject:
+			if err := asf.parseContentDescription(object.data); err != nil {
+				return nil, err
+			}
+		case asfExtendedContentDescriptionObject:
+			if err := asf.parseExtendedContentDescription(object.data); err != nil {
+				return nil, err
+			}
+		case asfHeaderExtensionObject:
+			if len(object.data) < 22 {
+				return nil, fmt.Errorf("ASF header extension object is truncated")
+			}
+			children, err := parseASFObjects(object.data[22:])
+			if err != nil {
+				return nil, err
+			}
+			for _, child := range children {
+				if child.guid != asfMetadataLibraryObject {
+					asf.extension = append(asf.extension, child)
+				} else if err := asf.parseMetadataLibrary(child.data); err != nil {
+					return nil, err
+				}
+			}
+		}
+		asf.objects = append(asf.objects, object)
+	}
+	return asf, nil
+}
+
+func parseASFObjects(data []byte) ([]asfObject, error) {
+	var objects []asfObject
+	for len(data) > 0 {
+		if len(data) < 24 {
+			return nil, fmt.Errorf("ASF object header is truncated")
+		}
+		size := binary.LittleEndian.Uint64(data[16:24])
+		if size < 24 || size > uint64(len(data)) {
+			return nil, fmt.Errorf("ASF object is truncated")
+		}
+		objects = append(objects, asfObject{guid: asfGUID(data[0:16]), data: data[24:size]})
+		data = data[size:]
+	}
+	return objects, nil
+}
+
+func (f *asfFile) parseContentDescription(data []byte) error {
+	if len(data) < 10 {
+		return fmt.Errorf("ASF content description object is truncated")
+	}
+	offset := 10
+	for i := range f.description {
+		length := int(binary.LittleEndian.Uint16(data[2*i:]))
+		if offset+length > len(data) {
+			return fmt.Errorf("ASF content description object is truncated")
+		}
+		f.description[i] = strings.TrimSpace(asfString(data[offset : offset+length]))
+		offset += length
+	}
+	return nil
+}
+
+func (f *asfFile) parseExtendedContentDescription(data []byte) error {
+	if len(data) < 2 {
+		return fmt.Errorf("ASF extended content description object is truncated")
+	}
+	count := int(binary.LittleEndian.Uint16(data[0:2]))
+	data = data[2:]
+	for range count {
+		if len(data) < 2 {
+			return fmt.Errorf("ASF extended content description object is truncated")
+		}
+		nameLength := int(binary.LittleEndian.Uint16(data[0:2]))
+		if len(data) < 2+nameLength+4 {
+			return fmt.Errorf("ASF extended content description object is truncated")
+		}
+		name := asfString(data[2 : 2+nameLength])
+		data = data[2+nameLength:]
+		kind := binary.LittleEndian.Uint16(data[0:2])
+		valueLength := int(binary.LittleEndian.Uint16(data[2:4]))
+		if len(data) < 4+valueLength {
+			return fmt.Errorf("ASF attribute %q is truncated", name)
+		}
+		f.attributes = append(f.attributes, asfAttribute{name: name, kind: kind, value: data[4 : 4+valueLength]})
+		data = data[4+valueLength:]
+	}
+	return nil
+}
+
+func (f *asfFile) parseMetadataLibrary(data []byte) error {
+	if len(data) < 2 {
+		return fmt.Errorf("ASF metadata library object is truncated")
+	}
+	count := int(binary.LittleEndian.Uint16(data[0:2]))
+	data = data[2:]
+	for range count {
+		if len(data) < 12 {
+			return fmt.Errorf("ASF metadata library object is truncated")
+		}
+		nameLength := int(binary.LittleEndian.Uint16(data[4:6]))
+		valueLength := int64(binary.LittleEndian.Uint32(data[8:12]))
+		if int64(len(data)) < 12+int64(nameLength)+valueLength {
+			return fmt.Errorf("ASF metadata library object is truncated")
+		}
+		attribute := asfAttribute{
+			name:     asfString(data[12 : 12+nameLength]),
+			kind:     binary.LittleEndian.Uint16(data[6:8]),
+			library:  true,
+			language: binary.LittleEndian.Uint16(data[0:2]),
+			stream:   binary.LittleEndian.Uint16(data[2:4]),
+		}
+		data = data[12+nameLength:]
+		attribute.value = data[:valueLength]
+		data = data[valueLength:]
+		f.attributes = append(f.attributes, attribute)
+	}
+	return nil
+}
+
+func (f *asfFile) object(guid asfGUID) []byte {
+	for _, object := range f.objects {
+		if object.guid == guid {
+			return object.data
+		}
+	}
+	return nil
+}
+
+func (f *asfFile) apply(update *model.TagUpdate) error {
+	if update.Title != nil {
+		f.description[asfTitle] = *update.Title
+	}
+	if update.Artist != nil {
+		f.description[asfAuthor] = *update.Artist
+	}
+	if update.Comment != nil {
+		f.description[asfDescription] = *update.Comment
+	}
+	if update.Album != nil {
+		f.setText("WM/AlbumTitle", *update.Album, update.KeepsEmpty("album"))
+	}
+	if date, ok := updatedReleaseDate(f.text("WM/Year"), update); ok {
+		f.setText("WM/Year", date, false)
+	}
+	if update.Genre != nil {
+		f.setText("WM/Genre", *update.Genre, update.KeepsEmpty("genre"))
+	}
+	if update.Track != nil {
+		f.remove("WM/Track")
+	}
+	f.setNumber("WM/TrackNumber", "TotalTracks", update.Track, update.TotalTracks)
+	f.setNumber("WM/PartOfSet", "TotalDiscs", update.Disc, update.TotalDiscs)
+	for _, field := range []struct {
+		name  string
+		field string
+		value *string
+	}{
+		{"WM/AlbumArtist", "albumArtist", update.AlbumArtist},
+		{"WM/Composer", "composer", update.Composer},
+		{"WM/TitleSortOrder", "titleSort", update.TitleSort},
+		{"WM/ArtistSortOrder", "artistSort", update.ArtistSort},
+		{"WM/AlbumArtistSortOrder", "albumArtistSort", update.AlbumArtistSort},
+		{"WM/AlbumSortOrder", "albumSort", update.AlbumSort},
+		{"WM/Lyrics", "lyrics", update.Lyrics},
+		{"WM/ISRC", "isrc", update.ISRC},
+		{"WM/Barcode", "barcode", update.Barcode},
+		{"WM/CatalogNo", "catalogNumber", update.CatalogNumber},
+		{"WM/Publisher", "label", update.Label},
+	} {
+		if field.value != nil {
+			f.setText(field.name, *field.value, update.KeepsEmpty(field.field))
+		}
+	}
+	if update.BPM != nil {
+		f.setText("WM/BeatsPerMinute", formatPositive(*update.BPM), false)
+	}
+	if update.Compilation != nil {
+		f.remove("WM/IsCompilation")
+		if *update.Compilation {
+			f.add("WM/IsCompilation", asfBool, []byte{1, 0, 0, 0})
+		}
+	}
+	if update.Rating != nil {
+		f.remove("WM/SharedUserRating")
+		if *update.Rating > 0 {
+			f.add("WM/SharedUserRating", asfDWord, binary.LittleEndian.AppendUint32(nil, uint32(ratingToASF(*update.Rating))))
+		}
+	}
+	if update.PlayCount != nil {
+		f.setText("FMPS/Playcount", formatPositive(*update.PlayCount), false)
+	}
+	for _, key := range sortedCustomTagKeys(update.CustomTags) {
+		f.setCustomTag(strings.ToUpper(key), update.CustomTags[key])
+	}
+
+	if update.CoverArt != nil && *update.CoverArt != "" {
+		data, mimeType, err := decodeCover(*update.CoverArt)
+		if err != nil {
+			return fmt.Errorf("failed to parse cover art data: %w", err)
+		}
+		f.remove("WM/Picture")
+		f.add("WM/Picture", asfBytes, marshalASFPicture(model.PictureTypeFrontCover, "Front Cover", normalizeMimeType(mimeType), data))
+	}
+	return f.applyPictures(update.Pictures)
+}
+
+func (f *asfFile) applyPictures(pictures []model.PictureUpdate) error {
+	replaced := make(map[int]bool)
+	for _, update := range pictures {
+		if !replaced[update.Type] {
+			replaced[update.Type] = true
+			f.attributes = slices.DeleteFunc(
+				f.attributes, func(attribute asfAttribute) bool {
+					return attribute.isTag() && attribute.name == "WM/Picture" &&
+						len(attribute.value) > 0 && int(attribute.value[0]) == update.Type
+				},
+			)
+		}
+
+		if update.Data == "" {
+			continue
+		}
+		data, mimeType, err := decodeCover(update.Data)
+		if err != nil {
+			return fmt.Errorf("failed to parse picture data: %w", err)
+		}
+		f.add("WM/Picture", asfBytes, marshalASFPicture(update.Type, update.Description, normalizeMimeType(mimeType), data))
+	}
+	return nil
+}
+
+func (f *asfFile) setNumber(numberName, totalName string, number, total *int) {
+	if number == nil && total == nil {
+		return
+	}
+	if number != nil {
+		if _, existing := splitNumberPair(f.text(numberName)); existing > 0 && total == nil && f.text(totalName) == "" {
+			f.setText(totalName, formatPositive(existing), false)
+		}
+		f.setText(numberName, formatPositive(*number), false)
+	}
+	if total != nil {
+		f.setText(totalName, formatPositive(*total), false)
+	}
+}
+
+func (f *asfFile) setCustomTag(key, value string) {
+	name := asfCustomTagName(key)
+	for _, attribute := range f.attributes {
+		if attribute.isTag() && strings.EqualFold(attribute.name, name) {
+			name = attribute.name
+			break
+		}
+	}
+	f.remove(key, name)
+	values := []string{value}
+	if isMultiValuedID(key) {
+		values = splitCustomTagValues(value)
+	}
+	for _, value := range values {
+		if value != "" {
+			f.add(name, asfUnicode, encodeASFString(value))
+		}
+	}
+}
+
+func (f *asfFile) text(name string) string {
+	for _, attribute := range f.attributes {
+		if attribute.isTag() && strings.EqualFold(attribute.name, name) {
+			return attribute.text()
+		}
+	}
+	return ""
+}
+
+func (f *asfFile) setText(name, value string, keepEmpty bool) {
+	f.remove(name)
+	if value != "" || keepEmpty {
+		f.add(name, asfUnicode, encodeASFString(value))
+	}
+}
+
+func (f *asfFile) remove(names ...string) {
+	f.attributes = slices.DeleteFunc(
+		f.attributes, func(attribute asfAttribute) bool {
+			if !attribute.isTag() {
+				return false
+			}
+			for _, name := range names {
+				if strings.EqualFold(attribute.name, name) {
+					return true
+				}
+			}
+			return false
+		},
+	)
+}
+
+func (f *asfFile) add(name string, kind uint16, value []byte) {
+	f.attributes = append(f.attributes, asfAttribute{name: name, kind: kind, value: value, library: len(value) > math.MaxUint16})
+}
+
+func (f *asfFile) marshal(bodySize int64) ([]byte, error) {
+	contentDescription, err := f.marshalContentDescription()
+	if err != nil {
+		return nil, err
+	}
+	extendedContentDescription, err := f.marshalExtendedContentDescription()
+	if err != nil {
+		return nil, err
+	}
+	headerExtension, err := f.marshalHeaderExtension()
+	if err != nil {
+		return nil, err
+	}
+	rebuilt := map[asfGUID][]byte{
+		asfContentDescriptionObject:         contentDescription,
+		asfExtendedContentDescriptionObject: extendedContentDescription,
+		asfHeaderExtensionObject:            headerExtension,
+	}
+
+	var buf bytes.Buffer
+	buf.Write(asfHeaderObject[:])
+	buf.Write(make([]byte, 8+4))
+	buf.Write([]byte{0x01, 0x02})
+	count := 0
+	filePropertiesAt := -1
+	writeObject := func(guid asfGUID, data []byte) {
+		if guid == asfFilePropertiesObject && len(data) >= 24 {
+			filePropertiesAt = buf.Len() + 24
+		}
+		buf.Write(guid[:])
+		buf.Write(binary.LittleEndian.AppendUint64(nil, uint64(24+len(data))))
+		buf.Write(data)
+		count++
+	}
+	for _, object := range f.objects {
+		data, ok := rebuilt[object.guid]
+		if !ok {
+			writeObject(object.guid, object.data)
+			continue
+		}
+		if data != nil {
+			writeObject(object.guid, data)
+		}
+		rebuilt[object.guid] = nil
+	}
+	for _, guid := range []asfGUID{asfHeaderExtensionObject, asfContentDescriptionObject, asfExtendedContentDescriptionObject} {
+		if data := rebuilt[guid]; data != nil {
+			writeObject(guid, data)
+		}
+	}
+
+	header := buf.Bytes()
+	binary.LittleEndian.PutUint64(header[16:24], uint64(len(header)))
+	binary.LittleEndian.PutUint32(header[24:28], uint32(count))
+	if filePropertiesAt >= 0 {
+		binary.LittleEndian.PutUint64(header[filePropertiesAt+16:], uint64(int64(len(header))+bodySize))
+	}
+	return header, nil
+}
+
+func (f *asfFile) marshalContentDescription() ([]byte, error) {
+	if f.description == [5]string{} {
+		return nil, nil
+	}
+	var lengths, values bytes.Buffer
+	for _, text := range f.description {
+		var value []byte
+		if text != "" {
+			value = encodeASFString(text)
+		}
+		if len(value) > math.MaxUint16 {
+			return nil, fmt.Errorf("ASF content description text is too long")
+		}
+		lengths.Write(binary.LittleEndian.AppendUint16(nil, uint16(len(value))))
+		values.Write(value)
+	}
+	return append(lengths.Bytes(), values.Bytes()...), nil
+}
+
+func (f *asfFile) marshalExtendedContentDescription() ([]byte, error) {
+	var buf bytes.Buffer
+	count := 0
+	for _, attribute := range f.attributes {
+		if attribute.library {
+			continue
+		}
+		name := encodeASFString(attribute.name)
+		if len(name) > math.MaxUint16 || len(attribute.value) > math.MaxUint16 {
+			return nil, fmt.Errorf("ASF attribute %q is too long", attribute.name)
+		}
+		buf.Write(binary.LittleEndian.AppendUint16(nil, uint16(len(name))))
+		buf.Write(name)
+		buf.Write(binary.LittleEndian.AppendUint16(nil, attribute.kind))
+		buf.Write(binary.LittleEndian.AppendUint16(nil, uint16(len(attribute.value))))
+		buf.Write(attribute.value)
+		count++
+	}
+	if count == 0 {
+		return nil, nil
+	}
+	if count > math.MaxUint16 {
+		return nil, fmt.Errorf("too many ASF attributes")
+	}
+	return append(binary.LittleEndian.AppendUint16(nil, uint16(count)), buf.Bytes()...), nil
+}
+
+func (f *asfFile) marshalHeaderExtension() ([]byte, error) {
+	var library bytes.Buffer
+	count := 0
+	for _, attribute := range f.attributes {
+		if !attribute.library {
+			continue
+		}
+		name := encodeASFString(attribute.name)
+		if len(name) > math.MaxUint16 || int64(len(attribute.value)) > math.MaxUint32 {
+			return nil, fmt.Errorf("ASF attribute %q is too long", attribute.name)
+		}
+		library.Write(binary.LittleEndian.AppendUint16(nil, attribute.language))
+		library.Write(binary.LittleEndian.AppendUint16(nil, attribute.stream))
+		library.Write(binary.LittleEndian.AppendUint16(nil, uint16(len(name))))
+		library.Write(binary.LittleEndian.AppendUint16(nil, attribute.kind))
+		library.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(attribute.value))))
+		library.Write(name)
+		library.Write(attribute.value)
+		count++
+	}
+	if count > math.MaxUint16 {
+		return nil, fmt.Errorf("too many ASF attributes")
+	}
+	if count == 0 && f.object(asfHeaderExtensionObject) == nil {
+		return nil, nil
+	}
+
+	var children bytes.Buffer
+	for _, object := range f.extension {
+		children.Write(object.guid[:])
+		children.Write(binary.LittleEndian.AppendUint64(nil, uint64(24+len(object.data))))
+		children.Write(object.data)
+	}
+	if count > 0 {
+		children.Write(asfMetadataLibraryObject[:])
+		children.Write(binary.LittleEndian.AppendUint64(nil, uint64(24+2+library.Len())))
+		children.Write(binary.LittleEndian.AppendUint16(nil, uint16(count)))
+		children.Write(library.Bytes())
+	}
+	if int64(children.Len()) > math.MaxUint32 {
+		return nil, fmt.Errorf("ASF header extension is too large")
+	}
+
+	var buf bytes.Buffer
+	buf.Write(asfHeaderExtensionReserved[:])
+	buf.Write(binary.LittleEndian.AppendUint16(nil, 6))
+	buf.Write(binary.LittleEndian.AppendUint32(nil, uint32(children.Len())))
+	buf.Write(children.Bytes())
+	return buf.Bytes(), nil
+}
+
+func (a asfAttribute) isTag() bool {
+	return a.stream == 0
+}
+
+func (a asfAttribute) text() string {
+	switch a.kind {
+	case asfUnicode:
+		return asfString(a.value)
+	case asfBool:
+		if slices.ContainsFunc(a.value, func(b byte) bool { return b != 0 }) {
+			return "1"
+		}
+		return "0"
+	case asfDWord:
+		if len(a.value) >= 4 {
+			return strconv.FormatUint(uint64(binary.LittleEndian.Uint32(a.value)), 10)
+		}
+	case asfQWord:
+		if len(a.value) >= 8 {
+			return strconv.FormatUint(binary.LittleEndian.Uint64(a.value), 10)
+		}
+	case asfWord:
+		if len(a.value) >= 2 {
+			return strconv.FormatUint(uint64(binary.LittleEndian.Uint16(a.value)), 10)
+		}
+	}
+	return ""
+}
+
+func parseASFPicture(value []byte) (model.Picture, bool) {
+	if len(value) < 5 {
+		return model.Picture{}, false
+	}
+	pictureType := int(value[0])
+	size := int64(binary.LittleEndian.Uint32(value[1:5]))
+	mimeType, rest, ok := cutASFString(value[5:])
+	if !ok {
+		return model.Picture{}, false
+	}
+	description, rest, ok := cutASFString(rest)
+	if !ok || size == 0 || size > int64(len(rest)) {
+		return model.Picture{}, false
+	}
+	return newPicture(pictureType, description, mimeType, rest[:size]), true
+}
+
+func marshalASFPicture(pictureType int, description, mimeType string, data []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte(byte(pictureType))
+	buf.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(data))))
+	buf.Write(encodeASFString(mimeType))
+	buf.Write(encodeASFString(description))
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+func cutASFString(data []byte) (string, []byte, bool) {
+	for i := 0; i+1 < len(data); i += 2 {
+		if data[i] == 0 && data[i+1] == 0 {
+			return decodeUTF16(data[:i], false), data[i+2:], true
+		}
+	}
+	return "", nil, false
+}
+
+func asfString(data []byte) string {
+	return strings.TrimRight(decodeUTF16(data, false), "\x00")
+}
+
+func encodeASFString(s string) []byte {
+	units := utf16.Encode([]rune(s))
+	data := make([]byte, 0, 2*len(units)+2)
+	for _, unit := range units {
+		data = binary.LittleEndian.AppendUint16(data, unit)
+	}
+	return append(data, 0, 0)
+}
+
+func asfCustomTagName(key string) string {
+	if key == musicBrainzTrackID {
+		return "MusicBrainz/Track Id"
+	}
+	if description, ok := picardID3Descriptions[key]; ok {
+		return strings.Replace(description, " ", "/", 1)
+	}
+	return key
+}
+
+var asfCustomTagKeys = func() map[string]string {
+	keys := map[string]string{strings.ToUpper(asfCustomTagName(musicBrainzTrackID)): musicBrainzTrackID}
+	for key := range picardID3Descriptions {
+		keys[strings.ToUpper(asfCustomTagName(key))] = key
+	}
+	return keys
+}()
+
+func ratingToASF(rating int) int {
+	if rating%model.RatingPerStar == 0 && rating > 0 && rating <= model.MaxRating {
+		return asfSharedRatings[rating/model.RatingPerStar-1]
+	}
+	return int(math.Round(float64(rating) * 99 / model.MaxRating))
+}
+
+func ratingFromASF(value int) int {
+	for i, stars := range asfSharedRatings {
+		if value == stars {
+			return (i + 1) * model.RatingPerStar
+		}
+	}
+	return min(int(math.Round(float64(value)*model.MaxRating/99)), model.MaxRating)
+}
+
+func getASFHandler(ext string) FormatHandler {
+	ext = strings.ToUpper(ext)
+	if ext == "WMA" {
+		return newASFHandler()
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package audio
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/vfs"
+)
+
+func (s *AudioService) writeAtomically(
+	ctx context.Context, filePath, format string, write func(path string) error,
+) error {
+	staged, err := s.stageWrite(ctx, filePath, format, write)
+	if err != nil {
+		return err
+	}
+	defer staged.discard()
+	if err := replaceFile(s.fs, staged.tempPath, filePath); err != nil {
+		return fmt.Errorf("failed to replace file: %w", err)
+	}
+	// Without syncing the directory the rename itself may be lost in a crash.
+	syncFile(s.fs, filepath.Dir(filePath))
+	return nil
+}
+
+type stagedWrite struct {
+	fs       vfs.FS
+	path     string
+	tempPath string
+}
+
+func (s *AudioService) stageWrite(
+	ctx context.Context, filePath, format string, write func(path string) error,
+) (_ *stagedWrite, err error) {
+	stat, err := s.fs.Stat(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	temp, err := createHiddenTemp(s.fs, filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tempPath := temp.Name()
+	defer func() {
+		if err != nil {
+			s.fs.Remove(tempPath)
+		}
+	}()
+
+	err = copyFile(s.fs, temp, filePath)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy file: %w", err)
+	}
+
+	if err := write(tempPath); err != nil {
+		return nil, err
+	}
+	if err := s.verifyWritten(ctx, tempPath, format); err != nil {
+		return nil, fmt.Errorf("written file is damaged, original kept: %w", err)
+	}
+
+	if err := s.restoreMode(tempPath, stat); err != nil {
+		return nil, err
+	}
+	if vfs.IsOS(s.fs) {
+		if err := copyXattrs(filePath, tempPath); err != nil {
+			return nil, fmt.Errorf("failed to copy extended attributes: %w", err)
+		}
+	}
+	if err := s.fs.Chtimes(tempPath, stat.ModTime(), stat.ModTime()); err != nil {
+		return nil, fmt.Errorf("failed to restore modification time: %w", err)
+	}
+	if err := syncFile(s.fs, tempPath); err != nil {
+		return nil, fmt.Errorf("failed to sync temp file: %w", err)
+	}
+	return &stagedWrite{fs: s.fs, path: filePath, tempPath: tempPath}, nil
+}
+
+func (w *stagedWrite) discard() {
+	w.fs.Remove(w.tempPath)
+}
+
+func createHiddenTemp(fsys vfs.FS, filePath string) (vfs.File, error) {
+	dir, base := filepath.Split(filePath)
+	ext := filepath.Ext(base)
+	return fsys.CreateTemp(dir, "."+strings.TrimSuffix(base, ext)+".*"+ext)
+}
+
+func copyFile(fsys vfs.FS, dst io.Writer, srcPath string) error {
+	src, err := fsys.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	_, err = io.Copy(dst, src)
+	return err
+}
+
+func syncFile(fsys vfs.FS, path string) error {
+	file, err := fsys.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return file.Sync()
+}
+
+func (s *AudioService) verifyWritten(ctx context.Context, path, format string) error {
+	if detected := detectFormatFromFilePath(s.fs, path); detected != format {
+		return fmt.Errorf("format changed from %s to %s", format, detected)
+	}
+
+	file, err := s.fs.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if _, err := parseReader(ctx, file, stat.Size(), filepath.Base(path), s.parse); err != nil {
+		return err
+	}
+	return nil
+}
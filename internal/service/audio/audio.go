@@ -1,95 +1,284 @@
 package audio
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"log/slog"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/dhowden/tag"
 	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/internal/vfs"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/text/encoding"
 )
 
-type AudioService struct{}
+var tracer = otel.Tracer("github.com/iamvkosarev/audio-tag-editor/internal/service/audio")
 
-func NewAudioService() *AudioService {
-	return &AudioService{}
+type Options struct {
+	CoverFetchTimeout      time.Duration
+	CoverMaxSize           int64
+	CoverAllowPrivateHosts bool
+	CoverMaxDimension      int   // longest edge of embedded covers in pixels; larger ones are scaled down
+	CoverMaxBytes          int64 // embedded covers above this size are re-encoded as JPEG
+	CoverQuality           int   // JPEG quality of re-encoded covers
+	CustomTagsAllow        []string
+	CustomTagsDeny         []string
+	ID3                    ID3Options
+	MP3ExactDuration       bool
+	FLACPadding            int // bytes reserved after rewritten FLAC metadata
+	FLACID3                FLACID3Mode
+	TagProfile             TagProfile          // names of the fields taggers disagree on; Picard's when empty
+	TextEncodings          []encoding.Encoding // code pages tried on mojibake in MP3 and WAV tags
+	ParseCacheSize         int                 // parsed files kept in memory; 0 turns the cache off
+	PreserveOwnership      bool                // keep the owner and special mode bits of rewritten files
+	FS                     vfs.FS              // where files are read and written; the local disk when nil
 }
 
-func (s *AudioService) ParseFile(filePath string) (*model.FileMetadata, error) {
-	result, err := parseFileWithTag(filePath)
+type AudioService struct {
+	coverFetcher    *coverFetcher
+	coverLimits     coverLimits
+	customTagPolicy *customTagPolicy
+	id3             ID3Options
+	flacPadding     int
+	flacID3         FLACID3Mode
+	tagProfile      TagProfile
+	encodings       *encodingRepairer
+	parse           parseOptions
+	parsed          *parseCache
+	keepOwner       bool
+	fs              vfs.FS
+}
+
+func NewAudioService(opts Options) *AudioService {
+	fsys := opts.FS
+	if fsys == nil {
+		fsys = vfs.OS{}
+	}
+	return &AudioService{
+		coverFetcher:    newCoverFetcher(opts.CoverFetchTimeout, opts.CoverMaxSize, opts.CoverAllowPrivateHosts),
+		coverLimits:     coverLimits{maxDimension: opts.CoverMaxDimension, maxBytes: opts.CoverMaxBytes, quality: opts.CoverQuality},
+		customTagPolicy: newCustomTagPolicy(opts.CustomTagsAllow, opts.CustomTagsDeny),
+		id3:             opts.ID3,
+		flacPadding:     opts.FLACPadding,
+		flacID3:         opts.FLACID3,
+		tagProfile:      opts.TagProfile,
+		encodings:       &encodingRepairer{encodings: opts.TextEncodings},
+		parse:           parseOptions{exactMP3Duration: opts.MP3ExactDuration},
+		parsed:          newParseCache(opts.ParseCacheSize),
+		keepOwner:       opts.PreserveOwnership,
+		fs:              fsys,
+	}
+}
+
+func (s *AudioService) ParseFile(ctx context.Context, filePath string) (*model.FileMetadata, error) {
+	file, err := s.fs.Open(filePath)
 	if err != nil {
-		return result, fmt.Errorf("failed to parse file: %w", err)
+		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
+	defer file.Close()
 
-	formatToUse := result.Format
-	if formatToUse == "" || formatToUse == "UNKNOWN" {
-		formatToUse = detectFormatFromFilePath(filePath)
-		if formatToUse == "" {
-			formatToUse = strings.ToUpper(strings.TrimPrefix(filepath.Ext(filePath), "."))
-		}
-		result.Format = formatToUse
-	}
-
-	var duration float64
-	var durationErr error
-
-	handler := getFormatHandlerByExtension(formatToUse)
-	if handler != nil {
-		duration, durationErr = handler.ExtractDuration(filePath)
-	} else {
-		file, err := openFile(filePath)
-		if err == nil {
-			defer file.Close()
-			metadata, err := tag.ReadFrom(file)
-			if err == nil {
-				handler = getFormatHandlerByFileType(metadata.FileType())
-				if handler != nil {
-					duration, durationErr = handler.ExtractDuration(filePath)
-				}
-			}
-		}
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file stats: %w", err)
 	}
 
-	if durationErr == nil && duration > 0 {
-		result.Duration = duration
+	if s.parsed == nil {
+		return s.ParseReader(ctx, file, stat.Size(), stat.Name())
+	}
+	key := parsedFile{path: filePath, size: stat.Size(), modTime: stat.ModTime()}
+	if key.fingerprint, err = fingerprintFile(file, stat.Size()); err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	if metadata, ok := s.parsed.get(key); ok {
+		return metadata, nil
+	}
+	metadata, err := s.ParseReader(ctx, file, stat.Size(), stat.Name())
+	if err != nil {
+		return metadata, err
+	}
+	s.parsed.put(key, metadata)
+	return metadata, nil
+}
+
+// ParseReader parses audio that is not necessarily on disk yet.
+func (s *AudioService) ParseReader(ctx context.Context, r io.ReaderAt, size int64, name string) (*model.FileMetadata, error) {
+	ctx, span := tracer.Start(ctx, "audio.Parse", trace.WithAttributes(attribute.Int64("file.size", size)))
+	defer span.End()
+	r = newBlockReader(r, size)
+
+	result, err := parseReader(ctx, r, size, name, s.parse)
+	if err != nil {
+		err = fmt.Errorf("%w: failed to parse file: %w", model.ErrUnsupportedFormat, err)
+		recordError(span, err)
+		return result, err
 	}
 
+	if result.Format == "" || result.Format == "UNKNOWN" {
+		result.Format = strings.ToUpper(strings.TrimPrefix(filepath.Ext(name), "."))
+	}
+	if extractor, ok := getFormatHandlerByExtension(result.Format).(audioInfoExtractor); ok {
+		if err := extractor.ExtractAudioInfo(r, size, result); err != nil {
+			slog.WarnContext(ctx, "AudioService.ParseReader: Failed to read audio properties", slog.String("name", name), slog.Any("error", err))
+		}
+	}
+	s.encodings.repairMetadata(result, name)
+	setCoverFromPictures(result)
+	describeCoverArt(result)
+	result.Capabilities = s.capabilities(result.Format)
+	result.ETag = result.ComputeETag()
+	span.SetAttributes(attribute.String("audio.format", result.Format))
+
 	return result, nil
 }
 
-func (s *AudioService) ParseReader(reader io.ReadSeeker, filename string, size int64) (*model.FileMetadata, error) {
-	return parseReaderWithTag(reader, filename, size)
+// EncodingRepairs returns the update that fixes the mojibake text of a file.
+func (s *AudioService) EncodingRepairs(ctx context.Context, filePath string) (*model.TagUpdate, error) {
+	file, err := s.fs.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file stats: %w", err)
+	}
+	metadata, err := parseReader(ctx, newBlockReader(file, stat.Size()), stat.Size(), stat.Name(), s.parse)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to parse file: %w", model.ErrUnsupportedFormat, err)
+	}
+	return s.encodings.repairMetadata(metadata, stat.Name()), nil
+}
+
+func (s *AudioService) UpdateTags(ctx context.Context, filePath string, update *model.TagUpdate) (err error) {
+	ctx, span := tracer.Start(ctx, "audio.UpdateTags")
+	defer func() {
+		recordError(span, err)
+		span.End()
+	}()
+
+	handler, detectedFormat, update, err := s.prepareUpdate(ctx, filePath, update)
+	if err != nil {
+		return err
+	}
+	span.SetAttributes(attribute.String("audio.format", detectedFormat))
+	// A patch in place can keep the size and, on coarse clocks, the mtime.
+	defer s.parsed.forget(filePath)
+	if patcher, ok := handler.(tagPatcher); ok {
+		patched, err := patcher.PatchTags(ctx, s.fs, filePath, update)
+		if err != nil || patched {
+			return err
+		}
+	}
+	return s.writeAtomically(
+		ctx, filePath, detectedFormat, func(path string) error {
+			return handler.UpdateTags(ctx, s.fs, path, update)
+		},
+	)
 }
 
-func (s *AudioService) UpdateTags(
-	filePath string,
-	title, artist, album *string,
-	year, track *int,
-	genre *string,
-	coverArt *string,
-) error {
-	detectedFormat := detectFormatFromFilePath(filePath)
+func (s *AudioService) prepareUpdate(
+	ctx context.Context, filePath string, update *model.TagUpdate,
+) (FormatHandler, string, *model.TagUpdate, error) {
+	detectedFormat := detectFormatFromFilePath(s.fs, filePath)
 	if detectedFormat == "" {
 		detectedFormat = strings.ToUpper(strings.TrimPrefix(filepath.Ext(filePath), "."))
 	}
 	if detectedFormat == "" {
-		return fmt.Errorf("could not determine file format for: %s", filePath)
+		return nil, "", nil, fmt.Errorf("%w: could not determine the format of %s", model.ErrUnsupportedFormat, filePath)
 	}
 
 	handler := getFormatHandlerByExtension(detectedFormat)
+	if handler == nil || !handler.Capabilities().Write {
+		return nil, "", nil, fmt.Errorf("%w: tag writing not yet supported for %s", model.ErrUnsupportedFormat, detectedFormat)
+	}
+	if err := s.ValidateTagUpdate(update); err != nil {
+		return nil, "", nil, err
+	}
+	if err := s.checkWritable(filePath); err != nil {
+		return nil, "", nil, err
+	}
+	if policy, _ := model.ParseMergePolicy(string(update.MergePolicy)); policy != model.MergePreserve {
+		current, err := s.ParseFile(ctx, filePath)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("failed to read current tags: %w", err)
+		}
+		merged := s.applyMergePolicy(*update, current)
+		update = &merged
+	}
+	if update.TagProfile == "" && s.tagProfile != "" {
+		withProfile := *update
+		withProfile.TagProfile = string(s.tagProfile)
+		update = &withProfile
+	}
+	if mp3, ok := handler.(*mp3Handler); ok {
+		mp3.id3 = s.id3
+	}
+	if flac, ok := handler.(*flacHandler); ok {
+		flac.padding = s.flacPadding
+		flac.id3 = s.flacID3
+		if update.FLACID3 != "" {
+			flac.id3, _ = ParseFLACID3Mode(update.FLACID3)
+		}
+	}
+	return handler, detectedFormat, update, nil
+}
+
+func (s *AudioService) capabilities(format string) *model.Capabilities {
+	handler := getFormatHandlerByExtension(format)
 	if handler == nil {
-		return fmt.Errorf("tag writing not yet supported for format: %s", detectedFormat)
+		return &model.Capabilities{Read: true}
+	}
+	if mp3, ok := handler.(*mp3Handler); ok {
+		mp3.exactDuration = s.parse.exactMP3Duration
+	}
+	capabilities := handler.Capabilities()
+	return &capabilities
+}
+
+// ResolveCoverArt turns an http(s) cover URL into a data URI by downloading the image.
+func (s *AudioService) ResolveCoverArt(ctx context.Context, coverArt string) (string, error) {
+	if !isRemoteCoverArt(coverArt) {
+		return coverArt, nil
 	}
-	return handler.UpdateTags(filePath, title, artist, album, year, track, genre, coverArt)
+	data, err := s.coverFetcher.fetch(ctx, coverArt)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", model.ErrInvalidCoverArt, err)
+	}
+	return data, nil
 }
 
-func (s *AudioService) ParseFLACWithAudiometa(filePath string) (*model.FileMetadata, error) {
-	handler := getFLACHandler("FLAC")
-	if flacHandler, ok := handler.(*flacHandler); ok {
-		return flacHandler.ParseWithAudiometa(filePath)
+// ExtractCoverArt returns the embedded front cover of a file and its MIME type.
+func (s *AudioService) ExtractCoverArt(ctx context.Context, filePath string) ([]byte, string, error) {
+	metadata, err := s.ParseFile(ctx, filePath)
+	if err != nil {
+		return nil, "", err
 	}
-	return nil, fmt.Errorf("failed to get FLAC handler")
+	if metadata.CoverArt == "" {
+		return nil, "", model.ErrNoCoverArt
+	}
+	return parseCoverArtData(metadata.CoverArt)
+}
+
+// ExtractPicture returns the first embedded picture of the given type and its MIME type.
+func (s *AudioService) ExtractPicture(ctx context.Context, filePath string, pictureType int) ([]byte, string, error) {
+	metadata, err := s.ParseFile(ctx, filePath)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, picture := range metadata.Pictures {
+		if picture.Type == pictureType {
+			return picture.Data, picture.MimeType, nil
+		}
+	}
+	return nil, "", model.ErrNoCoverArt
 }
 
 func getFormatHandlerByExtension(ext string) FormatHandler {
@@ -103,6 +292,36 @@ func getFormatHandlerByExtension(ext string) FormatHandler {
 	if handler := getOGGHandler(ext); handler != nil {
 		return handler
 	}
+	if handler := getOPUSHandler(ext); handler != nil {
+		return handler
+	}
+	if handler := getWAVHandler(ext); handler != nil {
+		return handler
+	}
+	if handler := getASFHandler(ext); handler != nil {
+		return handler
+	}
+	if handler := getAPEHandler(ext); handler != nil {
+		return handler
+	}
+	if handler := getMPCHandler(ext); handler != nil {
+		return handler
+	}
+	if handler := getWavPackHandler(ext); handler != nil {
+		return handler
+	}
+	if handler := getTTAHandler(ext); handler != nil {
+		return handler
+	}
+	if handler := getTAKHandler(ext); handler != nil {
+		return handler
+	}
+	if handler := getDSFHandler(ext); handler != nil {
+		return handler
+	}
+	if handler := getDFFHandler(ext); handler != nil {
+		return handler
+	}
 	return nil
 }
 
@@ -116,5 +335,27 @@ func getFormatHandlerByFileType(fileType tag.FileType) FormatHandler {
 	if handler := getOGGHandlerByFileType(fileType); handler != nil {
 		return handler
 	}
+	if handler := getOPUSHandlerByFileType(fileType); handler != nil {
+		return handler
+	}
 	return nil
 }
+
+// SupportedExtension reports whether tags of files with the extension can be written.
+func SupportedExtension(ext string) bool {
+	handler := getFormatHandlerByExtension(strings.TrimPrefix(ext, "."))
+	return handler != nil && handler.Capabilities().Write
+}
+
+// ReadableExtension reports whether files with the extension can be parsed.
+func (s *AudioService) ReadableExtension(ext string) bool {
+	return getFormatHandlerByExtension(strings.TrimPrefix(ext, ".")) != nil
+}
+
+func recordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,95 @@
+package audio
+
+import (
+	"io"
+	"sync"
+)
+
+const (
+	readBlockSize  = 64 * 1024
+	readBlockCount = 8 // blocks kept per open file, 512 KiB
+)
+
+type blockReader struct {
+	r    io.ReaderAt
+	size int64
+
+	mu     sync.Mutex
+	blocks []readBlock // most recently used first
+}
+
+type readBlock struct {
+	offset int64
+	data   []byte
+}
+
+func newBlockReader(r io.ReaderAt, size int64) *blockReader {
+	if br, ok := r.(*blockReader); ok {
+		return br
+	}
+	return &blockReader{r: r, size: size}
+}
+
+func (b *blockReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if off >= b.size {
+		return 0, io.EOF
+	}
+	if len(p) >= readBlockSize {
+		return b.r.ReadAt(p, off)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := 0
+	for n < len(p) && off < b.size {
+		block, err := b.block(off - off%readBlockSize)
+		if err != nil {
+			return n, err
+		}
+		copied := copy(p[n:], block.data[off-block.offset:])
+		if copied == 0 {
+			break
+		}
+		n += copied
+		off += int64(copied)
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (b *blockReader) block(offset int64) (readBlock, error) {
+	for i, block := range b.blocks {
+		if block.offset == offset {
+			copy(b.blocks[1:i+1], b.blocks[:i])
+			b.blocks[0] = block
+			return block, nil
+		}
+	}
+
+	var data []byte
+	if len(b.blocks) == readBlockCount {
+		// Reuse the buffer of the least recently used block.
+		data = b.blocks[len(b.blocks)-1].data[:cap(b.blocks[len(b.blocks)-1].data)]
+		b.blocks = b.blocks[:len(b.blocks)-1]
+	} else {
+		data = make([]byte, readBlockSize)
+	}
+	data = data[:min(int64(readBlockSize), b.size-offset)]
+	if n, err := b.r.ReadAt(data, offset); n < len(data) {
+		if err == nil || err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return readBlock{}, err
+	}
+
+	block := readBlock{offset: offset, data: data}
+	b.blocks = append(b.blocks, readBlock{})
+	copy(b.blocks[1:], b.blocks)
+	b.blocks[0] = block
+	return block, nil
+}
@@ -0,0 +1,103 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+)
+
+const (
+	bextDescription         = 0
+	bextOriginator          = 256
+	bextOriginatorReference = 288
+	bextOriginationDate     = 320
+	bextOriginationTime     = 330
+	bextTimeReference       = 338
+	bextCodingHistory       = 602
+)
+
+type ixmlDocument struct {
+	Project      string `xml:"PROJECT"`
+	Scene        string `xml:"SCENE"`
+	Take         string `xml:"TAKE"`
+	Tape         string `xml:"TAPE"`
+	Note         string `xml:"NOTE"`
+	TimecodeRate string `xml:"SPEED>TIMECODE_RATE"`
+}
+
+func parseBroadcast(bext, ixml []byte, sampleRate int) *model.Broadcast {
+	broadcast := &model.Broadcast{}
+	found := false
+	if len(bext) >= bextTimeReference+8 {
+		found = true
+		broadcast.Description = bextText(bext[bextDescription:bextOriginator])
+		broadcast.Originator = bextText(bext[bextOriginator:bextOriginatorReference])
+		broadcast.OriginatorReference = bextText(bext[bextOriginatorReference:bextOriginationDate])
+		broadcast.OriginationDate = bextText(bext[bextOriginationDate:bextOriginationTime])
+		broadcast.OriginationTime = bextText(bext[bextOriginationTime:bextTimeReference])
+		broadcast.TimeReference = int64(binary.LittleEndian.Uint64(bext[bextTimeReference:]))
+		if len(bext) > bextCodingHistory {
+			broadcast.CodingHistory = strings.TrimSpace(bextText(bext[bextCodingHistory:]))
+		}
+	}
+	if len(ixml) > 0 {
+		var document ixmlDocument
+		if err := xml.Unmarshal(bytes.TrimRight(ixml, "\x00"), &document); err == nil {
+			found = true
+			broadcast.Project = strings.TrimSpace(document.Project)
+			broadcast.Scene = strings.TrimSpace(document.Scene)
+			broadcast.Take = strings.TrimSpace(document.Take)
+			broadcast.Tape = strings.TrimSpace(document.Tape)
+			broadcast.Note = strings.TrimSpace(document.Note)
+			broadcast.TimecodeRate = strings.TrimSpace(document.TimecodeRate)
+		}
+	}
+	if !found {
+		return nil
+	}
+	if sampleRate > 0 {
+		broadcast.Timecode = formatTimecode(broadcast.TimeReference, sampleRate, broadcast.TimecodeRate)
+	}
+	return broadcast
+}
+
+func bextText(field []byte) string {
+	if i := bytes.IndexByte(field, 0); i >= 0 {
+		field = field[:i]
+	}
+	return strings.TrimRight(string(field), " ")
+}
+
+func formatTimecode(samples int64, sampleRate int, frameRate string) string {
+	seconds := float64(samples) / float64(sampleRate)
+	whole := int64(seconds)
+	clock := fmt.Sprintf("%02d:%02d:%02d", whole/3600%24, whole/60%60, whole%60)
+
+	if fps := parseFrameRate(frameRate); fps > 0 {
+		frame := int((seconds - float64(whole)) * fps)
+		return fmt.Sprintf("%s:%02d", clock, min(frame, int(math.Ceil(fps))-1))
+	}
+	return fmt.Sprintf("%s.%03d", clock, int((seconds-float64(whole))*1000))
+}
+
+func parseFrameRate(value string) float64 {
+	numerator, denominator, found := strings.Cut(value, "/")
+	n, err := strconv.ParseFloat(strings.TrimSpace(numerator), 64)
+	if err != nil || n <= 0 {
+		return 0
+	}
+	if !found {
+		return n
+	}
+	d, err := strconv.ParseFloat(strings.TrimSpace(denominator), 64)
+	if err != nil || d <= 0 {
+		return 0
+	}
+	return n / d
+}
@@ -0,0 +1,347 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bogem/id3v2/v2"
+	"github.com/go-flac/flacvorbis"
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+)
+
+const (
+	id3TOCElementID  = "toc"
+	id3TOCFlags      = 0x03
+	id3IgnoredOffset = 0xFFFFFFFF
+)
+
+var vorbisChapterKey = regexp.MustCompile(`^CHAPTER(\d{3})(NAME|URL)?$`)
+
+func sortedChapters(chapters []model.Chapter) []model.Chapter {
+	sorted := append([]model.Chapter(nil), chapters...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+
+	used := make(map[string]bool, len(sorted))
+	for _, chapter := range sorted {
+		used[chapter.ID] = true
+	}
+	next := 0
+	for i := range sorted {
+		for sorted[i].ID == "" {
+			if id := "chp" + strconv.Itoa(next); !used[id] {
+				sorted[i].ID = id
+				used[id] = true
+			}
+			next++
+		}
+		if sorted[i].End == 0 && i+1 < len(sorted) {
+			sorted[i].End = sorted[i+1].Start
+		}
+	}
+	return sorted
+}
+
+func setID3Chapters(id3Tag *id3v2.Tag, chapters []model.Chapter) {
+	id3Tag.DeleteFrames("CHAP")
+	id3Tag.DeleteFrames("CTOC")
+	if len(chapters) == 0 {
+		return
+	}
+
+	chapters = sortedChapters(chapters)
+	toc := append([]byte(id3TOCElementID), 0, id3TOCFlags, byte(len(chapters)))
+	for _, chapter := range chapters {
+		id3Tag.AddFrame("CHAP", id3v2.UnknownFrame{Body: encodeCHAP(chapter, id3Tag.Version())})
+		toc = append(append(toc, chapter.ID...), 0)
+	}
+	id3Tag.AddFrame("CTOC", id3v2.UnknownFrame{Body: toc})
+}
+
+func encodeCHAP(chapter model.Chapter, version byte) []byte {
+	end := chapter.End
+	if end < chapter.Start {
+		end = chapter.Start
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(chapter.ID)
+	buf.WriteByte(0)
+	fields := make([]byte, 16)
+	binary.BigEndian.PutUint32(fields[0:], uint32(chapter.Start))
+	binary.BigEndian.PutUint32(fields[4:], uint32(end))
+	binary.BigEndian.PutUint32(fields[8:], id3IgnoredOffset)
+	binary.BigEndian.PutUint32(fields[12:], id3IgnoredOffset)
+	buf.Write(fields)
+
+	if chapter.Title != "" {
+		writeID3Subframe(&buf, "TIT2", encodeID3Text(chapter.Title, version), version)
+	}
+	if chapter.URL != "" {
+		// An empty Latin-1 description followed by the URL.
+		writeID3Subframe(&buf, "WXXX", append([]byte{0, 0}, chapter.URL...), version)
+	}
+	if chapter.Image != "" {
+		if data, mimeType, err := decodeCover(chapter.Image); err == nil {
+			body := append([]byte{0}, normalizeMimeType(mimeType)...)
+			body = append(body, 0, model.PictureTypeOther, 0)
+			writeID3Subframe(&buf, "APIC", append(body, data...), version)
+		}
+	}
+	return buf.Bytes()
+}
+
+func encodeID3Text(text string, version byte) []byte {
+	switch {
+	case version != 3:
+		return append([]byte{syltEncodingUTF8}, text...)
+	case isLatin1(text):
+		body := []byte{syltEncodingLatin1}
+		for _, r := range text {
+			body = append(body, byte(r))
+		}
+		return body
+	default:
+		return append([]byte{syltEncodingUTF16}, encodeID3UTF16(text)...)
+	}
+}
+
+func writeID3Subframe(buf *bytes.Buffer, id string, body []byte, version byte) {
+	header := make([]byte, 10)
+	copy(header, id)
+	size := uint32(len(body))
+	if version == 4 {
+		size = size&0x7F | (size>>7&0x7F)<<8 | (size>>14&0x7F)<<16 | (size>>21&0x7F)<<24
+	}
+	binary.BigEndian.PutUint32(header[4:], size)
+	buf.Write(header)
+	buf.Write(body)
+}
+
+func readID3Chapters(r io.ReaderAt, size int64) []model.Chapter {
+	header := make([]byte, 10)
+	if _, err := r.ReadAt(header, 0); err != nil || string(header[:3]) != "ID3" {
+		return nil
+	}
+	version := header[3]
+	if version != 3 && version != 4 {
+		return nil
+	}
+	tagSize := int64(header[6]&0x7F)<<21 | int64(header[7]&0x7F)<<14 | int64(header[8]&0x7F)<<7 | int64(header[9]&0x7F)
+	data := make([]byte, min(tagSize, size-10))
+	if _, err := r.ReadAt(data, 10); err != nil {
+		return nil
+	}
+	if header[5]&0x80 != 0 && version == 3 {
+		data = bytes.ReplaceAll(data, []byte{0xFF, 0x00}, []byte{0xFF})
+	}
+	if header[5]&0x40 != 0 && len(data) >= 4 {
+		// The extended header counts its own size in ID3v2.4 only.
+		extended := int(readID3FrameSize(data, version == 4))
+		if version == 3 {
+			extended += 4
+		}
+		data = data[min(extended, len(data)):]
+	}
+
+	var chapters []model.Chapter
+	for len(data) >= 10 && data[0] != 0 {
+		id := string(data[:4])
+		frameSize := readID3FrameSize(data[4:], version == 4)
+		if int64(frameSize) > int64(len(data)-10) {
+			break
+		}
+		body := data[10 : 10+frameSize]
+		flags := data[9]
+		data = data[10+frameSize:]
+		// Compressed or encrypted frames cannot be read as they are.
+		if id != "CHAP" || (version == 3 && flags&0xC0 != 0) || (version == 4 && flags&0x0E != 0) {
+			continue
+		}
+		if chapter, err := decodeCHAP(body, version == 4); err == nil {
+			chapters = append(chapters, chapter)
+		}
+	}
+	sort.SliceStable(chapters, func(i, j int) bool { return chapters[i].Start < chapters[j].Start })
+	return chapters
+}
+
+func readID3FrameSize(data []byte, syncsafe bool) uint32 {
+	size := binary.BigEndian.Uint32(data)
+	if syncsafe {
+		size = size&0x7F | (size>>8&0x7F)<<7 | (size>>16&0x7F)<<14 | (size>>24&0x7F)<<21
+	}
+	return size
+}
+
+func keepID3Chapters(update *model.TagUpdate, existing io.ReaderAt, size int64) *model.TagUpdate {
+	if update.Chapters != nil {
+		return update
+	}
+	chapters := readID3Chapters(existing, size)
+	if len(chapters) == 0 {
+		return update
+	}
+	kept := *update
+	kept.Chapters = &chapters
+	return &kept
+}
+
+func decodeCHAP(body []byte, syncsafe bool) (model.Chapter, error) {
+	end := bytes.IndexByte(body, 0)
+	if end < 0 || len(body) < end+17 {
+		return model.Chapter{}, fmt.Errorf("CHAP frame too short")
+	}
+	chapter := model.Chapter{
+		ID:    string(body[:end]),
+		Start: int64(binary.BigEndian.Uint32(body[end+1:])),
+		End:   int64(binary.BigEndian.Uint32(body[end+5:])),
+	}
+
+	rest := body[end+17:]
+	for len(rest) >= 10 && rest[0] != 0 {
+		id := string(rest[:4])
+		size := readID3FrameSize(rest[4:], syncsafe)
+		if int(size) > len(rest)-10 {
+			break
+		}
+		sub := rest[10 : 10+size]
+		rest = rest[10+size:]
+		if len(sub) == 0 {
+			continue
+		}
+
+		switch id {
+		case "TIT2":
+			chapter.Title = strings.TrimSpace(readID3Text(sub[1:], sub[0]))
+		case "WXXX":
+			if _, url, err := readSYLTString(sub[1:], sub[0]); err == nil {
+				chapter.URL = strings.TrimRight(string(url), "\x00")
+			}
+		case "APIC":
+			mimeEnd := bytes.IndexByte(sub[1:], 0)
+			if mimeEnd < 0 || len(sub) < mimeEnd+3 {
+				continue
+			}
+			mimeType := normalizeMimeType(string(sub[1 : 1+mimeEnd]))
+			if _, data, err := readSYLTString(sub[mimeEnd+3:], sub[0]); err == nil && len(data) > 0 {
+				chapter.Image = fmt.Sprintf("data:%s;base64,%s", mimeType, base64.StdEncoding.EncodeToString(data))
+			}
+		}
+	}
+	return chapter, nil
+}
+
+func readID3Text(data []byte, encoding byte) string {
+	if text, _, err := readSYLTString(data, encoding); err == nil {
+		return text
+	}
+	switch encoding {
+	case syltEncodingUTF16, syltEncodingUTF16BE:
+		return decodeUTF16(data, encoding == syltEncodingUTF16BE)
+	case syltEncodingLatin1:
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		return string(runes)
+	default:
+		return string(data)
+	}
+}
+
+func setVorbisChapters(vorbisComment *flacvorbis.MetaDataBlockVorbisComment, chapters []model.Chapter) {
+	comments := vorbisComment.Comments[:0]
+	for _, comment := range vorbisComment.Comments {
+		name, _, _ := strings.Cut(comment, "=")
+		if !vorbisChapterKey.MatchString(strings.ToUpper(name)) {
+			comments = append(comments, comment)
+		}
+	}
+	vorbisComment.Comments = comments
+
+	for i, chapter := range sortedChapters(chapters) {
+		key := fmt.Sprintf("CHAPTER%03d", i+1)
+		vorbisComment.Comments = append(vorbisComment.Comments, key+"="+formatChapterTime(chapter.Start))
+		if chapter.Title != "" {
+			vorbisComment.Comments = append(vorbisComment.Comments, key+"NAME="+chapter.Title)
+		}
+		if chapter.URL != "" {
+			vorbisComment.Comments = append(vorbisComment.Comments, key+"URL="+chapter.URL)
+		}
+	}
+}
+
+func addVorbisChapterComment(chapters map[int]*model.Chapter, key, value string) bool {
+	match := vorbisChapterKey.FindStringSubmatch(strings.ToUpper(key))
+	if match == nil {
+		return false
+	}
+	number, _ := strconv.Atoi(match[1])
+	chapter := chapters[number]
+	if chapter == nil {
+		chapter = &model.Chapter{Start: -1}
+		chapters[number] = chapter
+	}
+	switch match[2] {
+	case "NAME":
+		chapter.Title = value
+	case "URL":
+		chapter.URL = value
+	default:
+		if start, ok := parseChapterTime(value); ok {
+			chapter.Start = start
+		}
+	}
+	return true
+}
+
+func vorbisChapters(collected map[int]*model.Chapter) []model.Chapter {
+	numbers := make([]int, 0, len(collected))
+	for number := range collected {
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+
+	var chapters []model.Chapter
+	for _, number := range numbers {
+		if chapter := collected[number]; chapter.Start >= 0 {
+			chapters = append(chapters, *chapter)
+		}
+	}
+	sort.SliceStable(chapters, func(i, j int) bool { return chapters[i].Start < chapters[j].Start })
+	return chapters
+}
+
+func formatChapterTime(ms int64) string {
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+func parseChapterTime(value string) (int64, bool) {
+	parts := strings.Split(strings.TrimSpace(value), ":")
+	if len(parts) != 3 {
+		return 0, false
+	}
+	hours, err1 := strconv.ParseInt(parts[0], 10, 64)
+	minutes, err2 := strconv.ParseInt(parts[1], 10, 64)
+	seconds, fraction, _ := strings.Cut(parts[2], ".")
+	secs, err3 := strconv.ParseInt(seconds, 10, 64)
+	if err1 != nil || err2 != nil || err3 != nil || hours < 0 || minutes < 0 || minutes > 59 || secs < 0 || secs > 59 {
+		return 0, false
+	}
+	ms := int64(0)
+	if fraction != "" {
+		digits, err := strconv.ParseInt((fraction + "00")[:3], 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		ms = digits
+	}
+	return ((hours*60+minutes)*60+secs)*1000 + ms, true
+}
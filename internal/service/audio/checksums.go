@@ -0,0 +1,147 @@
+package audio
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+)
+
+type audioStreamWriter interface {
+	writeAudioStream(r io.ReaderAt, size int64, w io.Writer) error
+}
+
+// Checksums hashes the file at filePath as a whole and its audio stream.
+func (s *AudioService) Checksums(ctx context.Context, filePath string) (result *model.Checksums, err error) {
+	_, span := tracer.Start(ctx, "audio.Checksums")
+	defer func() {
+		recordError(span, err)
+		span.End()
+	}()
+
+	file, err := s.fs.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file stats: %w", err)
+	}
+
+	fileHash := sha256.New()
+	if _, err := io.Copy(fileHash, bufio.NewReaderSize(file, 64*1024)); err != nil {
+		return nil, fmt.Errorf("failed to hash file: %w", err)
+	}
+	result = &model.Checksums{SHA256: hex.EncodeToString(fileHash.Sum(nil))}
+
+	format, err := detectFormatFromContent(file)
+	if err != nil {
+		return result, nil
+	}
+	if streamWriter, ok := getFormatHandlerByExtension(format).(audioStreamWriter); ok {
+		audioHash := sha256.New()
+		if err := streamWriter.writeAudioStream(file, stat.Size(), audioHash); err != nil {
+			return nil, fmt.Errorf("failed to hash audio stream: %w", err)
+		}
+		result.AudioSHA256 = hex.EncodeToString(audioHash.Sum(nil))
+	}
+	if format == "FLAC" {
+		if result.StreamInfoMD5, err = flacStreamInfoMD5(file); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (h *mp3Handler) writeAudioStream(r io.ReaderAt, size int64, w io.Writer) error {
+	start, end, err := mp3AudioRange(r, size)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, io.NewSectionReader(r, start, end-start))
+	return err
+}
+
+func (h *flacHandler) writeAudioStream(r io.ReaderAt, size int64, w io.Writer) error {
+	flacStartPos, err := flacStreamOffset(r)
+	if err != nil {
+		return err
+	}
+	start, err := flacAudioOffset(r, flacStartPos)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, io.NewSectionReader(r, start, size-start))
+	return err
+}
+
+func (h *wavHandler) writeAudioStream(r io.ReaderAt, size int64, w io.Writer) error {
+	wav, err := h.readChunks(r, size)
+	if err != nil {
+		return err
+	}
+	for _, chunk := range wav.chunks {
+		if chunk.id == "data" {
+			_, err = io.Copy(w, io.NewSectionReader(r, chunk.offset, int64(chunk.size)))
+			return err
+		}
+	}
+	return fmt.Errorf("WAV data chunk not found")
+}
+
+func (h *oggHandler) writeAudioStream(r io.ReaderAt, size int64, w io.Writer) error {
+	return writeOggAudioStream(r, size, vorbisCommentCodec.headerPackets, w)
+}
+
+func (h *opusHandler) writeAudioStream(r io.ReaderAt, size int64, w io.Writer) error {
+	return writeOggAudioStream(r, size, opusCommentCodec.headerPackets, w)
+}
+
+func writeOggAudioStream(r io.ReaderAt, size int64, headerPackets int, w io.Writer) error {
+	headerSize, err := oggHeaderSize(r, size, headerPackets)
+	if err != nil {
+		return err
+	}
+	reader := bufio.NewReader(io.NewSectionReader(r, headerSize, size-headerSize))
+	granule := make([]byte, 8)
+	for {
+		page, err := readOggPage(reader)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read Ogg page: %w", unexpectedEOF(err))
+		}
+		binary.LittleEndian.PutUint64(granule, page.granule)
+		for _, part := range [][]byte{granule, page.segments, page.data} {
+			if _, err := w.Write(part); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func flacStreamInfoMD5(r io.ReaderAt) (string, error) {
+	flacStartPos, err := flacStreamOffset(r)
+	if err != nil {
+		return "", err
+	}
+	// "fLaC", the block header and 18 bytes of STREAMINFO come before the MD5.
+	sum := make([]byte, 16)
+	if _, err := r.ReadAt(sum, flacStartPos+4+4+18); err != nil {
+		return "", fmt.Errorf("failed to read FLAC STREAMINFO MD5: %w", err)
+	}
+	if bytes.Equal(sum, make([]byte, 16)) {
+		return "", nil
+	}
+	return hex.EncodeToString(sum), nil
+}
@@ -0,0 +1,137 @@
+package audio
+
+import (
+	"strings"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+)
+
+const clearCustomTagPrefix = "customTags."
+
+func (v *tagValidator) clear(update *model.TagUpdate) {
+	if update.CoverArt != nil && *update.CoverArt == "" {
+		update.CoverArt = nil
+		clearPictures(update, model.PictureTypeFrontCover)
+	}
+	for _, name := range update.Clear {
+		switch name {
+		case "title":
+			clearField(v, name, &update.Title)
+		case "artist":
+			clearField(v, name, &update.Artist)
+		case "album":
+			clearField(v, name, &update.Album)
+		case "albumArtist":
+			clearField(v, name, &update.AlbumArtist)
+		case "composer":
+			clearField(v, name, &update.Composer)
+		case "comment":
+			clearField(v, name, &update.Comment)
+		case "genre":
+			clearField(v, name, &update.Genre)
+		case "titleSort":
+			clearField(v, name, &update.TitleSort)
+		case "artistSort":
+			clearField(v, name, &update.ArtistSort)
+		case "albumArtistSort":
+			clearField(v, name, &update.AlbumArtistSort)
+		case "albumSort":
+			clearField(v, name, &update.AlbumSort)
+		case "lyrics":
+			clearField(v, name, &update.Lyrics)
+		case "syncedLyrics":
+			clearField(v, name, &update.SyncedLyrics)
+		case "isrc":
+			clearField(v, name, &update.ISRC)
+		case "barcode":
+			clearField(v, name, &update.Barcode)
+		case "catalogNumber":
+			clearField(v, name, &update.CatalogNumber)
+		case "label":
+			clearField(v, name, &update.Label)
+		case "year", "releaseDate":
+			// The year is read from the date, so either one removes both.
+			clearField(v, name, &update.Year)
+			clearField(v, name, &update.ReleaseDate)
+		case "track":
+			clearField(v, name, &update.Track)
+		case "totalTracks":
+			clearField(v, name, &update.TotalTracks)
+		case "disc":
+			clearField(v, name, &update.Disc)
+		case "totalDiscs":
+			clearField(v, name, &update.TotalDiscs)
+		case "bpm":
+			clearField(v, name, &update.BPM)
+		case "compilation":
+			clearField(v, name, &update.Compilation)
+		case "rating":
+			clearField(v, name, &update.Rating)
+		case "playCount":
+			clearField(v, name, &update.PlayCount)
+		case "chapters":
+			if update.Chapters != nil && len(*update.Chapters) > 0 {
+				v.add(name, model.ErrInvalidTag, "%s is both set and cleared", name)
+				continue
+			}
+			update.Chapters = &[]model.Chapter{}
+		case "coverArt":
+			if update.CoverArt != nil && *update.CoverArt != "" {
+				v.add(name, model.ErrInvalidTag, "%s is both set and cleared", name)
+				continue
+			}
+			update.CoverArt = nil
+			clearPictures(update, model.PictureTypeFrontCover)
+		case "pictures":
+			if update.CoverArt != nil && *update.CoverArt != "" || hasPictureData(update.Pictures) {
+				v.add(name, model.ErrInvalidTag, "%s is both set and cleared", name)
+				continue
+			}
+			update.CoverArt = nil
+			for pictureType := range model.PictureTypeMax + 1 {
+				clearPictures(update, pictureType)
+			}
+		default:
+			key, ok := strings.CutPrefix(name, clearCustomTagPrefix)
+			if !ok || key == "" {
+				v.add("clear", model.ErrInvalidTag, "unknown field %q", name)
+				continue
+			}
+			if update.CustomTags[key] != "" {
+				v.add(name, model.ErrInvalidTag, "%s is both set and cleared", name)
+				continue
+			}
+			if update.CustomTags == nil {
+				update.CustomTags = make(map[string]string)
+			}
+			update.CustomTags[key] = ""
+		}
+	}
+}
+
+func clearField[T comparable](v *tagValidator, name string, field **T) {
+	var zero T
+	if *field != nil && **field != zero {
+		v.add(name, model.ErrInvalidTag, "%s is both set and cleared", name)
+		return
+	}
+	*field = &zero
+}
+
+func clearPictures(update *model.TagUpdate, pictureType int) {
+	for _, picture := range update.Pictures {
+		if picture.Type == pictureType && picture.Data == "" {
+			return
+		}
+	}
+	update.Pictures = append(update.Pictures, model.PictureUpdate{Type: pictureType})
+}
+
+func hasPictureData(pictures []model.PictureUpdate) bool {
+	for _, picture := range pictures {
+		if picture.Data != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package audio
+
+import (
+	"context"
+	"testing"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+)
+
+// An empty CoverArt removes the front cover and leaves the other pictures alone.
+func TestUpdateTagsEmptyCoverArtRemovesCover(t *testing.T) {
+	for _, name := range []string{"sample.id3v11.mp3", "sample.flac", "sample.ogg"} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			path := copyTestdata(t, name)
+			service := NewAudioService(Options{})
+
+			cover := testImage(t, 4)
+			update := &model.TagUpdate{
+				CoverArt: &cover,
+				Pictures: []model.PictureUpdate{{Type: model.PictureTypeBackCover, Data: cover}},
+			}
+			if err := service.UpdateTags(ctx, path, update); err != nil {
+				t.Fatal(err)
+			}
+			metadata, err := service.ParseFile(ctx, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !metadata.HasCoverArt || len(metadata.Pictures) != 2 {
+				t.Fatalf("cover written: %v, pictures = %d, want a front and a back cover",
+					metadata.HasCoverArt, len(metadata.Pictures))
+			}
+
+			empty := ""
+			if err := service.UpdateTags(ctx, path, &model.TagUpdate{CoverArt: &empty}); err != nil {
+				t.Fatal(err)
+			}
+			metadata, err = service.ParseFile(ctx, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(metadata.Pictures) != 1 || metadata.Pictures[0].Type != model.PictureTypeBackCover {
+				t.Errorf("picture types = %v, want only the back cover", pictureTypes(metadata.Pictures))
+			}
+		})
+	}
+}
+
+func TestClearEmptyCoverArt(t *testing.T) {
+	empty := ""
+	update := &model.TagUpdate{CoverArt: &empty}
+	v := &tagValidator{}
+	v.clear(update)
+	v.clear(update)
+	if err := v.err(); err != nil {
+		t.Fatal(err)
+	}
+	want := []model.PictureUpdate{{Type: model.PictureTypeFrontCover}}
+	if update.CoverArt != nil || len(update.Pictures) != 1 || update.Pictures[0] != want[0] {
+		t.Errorf("cover art, pictures = %v, %+v, want nil, %+v", update.CoverArt, update.Pictures, want)
+	}
+}
@@ -0,0 +1,85 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"strings"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/webp"
+)
+
+func parseCoverArtData(dataURI string) ([]byte, string, error) {
+	if !strings.HasPrefix(dataURI, "data:") {
+		return nil, "", fmt.Errorf("invalid data URI format")
+	}
+
+	parts := strings.SplitN(dataURI, ",", 2)
+	if len(parts) != 2 {
+		return nil, "", fmt.Errorf("invalid data URI format")
+	}
+
+	header := parts[0]
+	data := parts[1]
+
+	mimeType := "image/jpeg"
+	if strings.HasPrefix(header, "data:image/") {
+		mimeParts := strings.Split(header, ";")
+		if len(mimeParts) > 0 {
+			mimePart := strings.TrimPrefix(mimeParts[0], "data:")
+			if mimePart != "" {
+				mimeType = mimePart
+			}
+		}
+	}
+
+	coverData, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode base64: %w", err)
+	}
+
+	return coverData, mimeType, nil
+}
+
+func normalizeMimeType(mimeType string) string {
+	mimeType = strings.ToLower(strings.TrimSpace(mimeType))
+	switch mimeType {
+	case "image/jpg":
+		return "image/jpeg"
+	case "image/png":
+		return "image/png"
+	case "image/gif":
+		return "image/gif"
+	case "image/bmp":
+		return "image/bmp"
+	default:
+		if strings.HasPrefix(mimeType, "image/") {
+			return mimeType
+		}
+		return "image/jpeg"
+	}
+}
+
+func describeCoverArt(metadata *model.FileMetadata) {
+	metadata.HasCoverArt = metadata.CoverArt != ""
+	metadata.CoverArtInfo = nil
+	if !metadata.HasCoverArt {
+		return
+	}
+
+	data, mimeType, err := parseCoverArtData(metadata.CoverArt)
+	if err != nil {
+		return
+	}
+	info := &model.CoverArtInfo{MimeType: normalizeMimeType(mimeType), Size: len(data)}
+	if config, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		info.Width, info.Height = config.Width, config.Height
+	}
+	metadata.CoverArtInfo = info
+}
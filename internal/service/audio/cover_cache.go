@@ -0,0 +1,100 @@
+package audio
+
+import (
+	"encoding/base64"
+	"sync"
+
+	"github.com/go-flac/go-flac"
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+)
+
+const coverCacheSize = 4
+
+var covers = &coverCache{entries: make(map[string]*cachedCover)}
+
+type coverCache struct {
+	mu      sync.Mutex
+	entries map[string]*cachedCover
+	order   []string // oldest first
+}
+
+type cachedCover struct {
+	dataURI string
+
+	decodeOnce sync.Once
+	data       []byte
+	mimeType   string
+	decodeErr  error
+
+	blockOnce   sync.Once
+	block       flac.MetaDataBlock // front cover PICTURE block
+	vorbisBlock string             // block as a METADATA_BLOCK_PICTURE comment
+	blockErr    error
+
+	fitMu sync.Mutex
+	fits  map[coverPolicy]fittedCover
+}
+
+type coverPolicy struct {
+	maxSize int64
+	limits  coverLimits
+}
+
+type fittedCover struct {
+	dataURI string
+	err     error
+}
+
+func (c *coverCache) get(dataURI string) *cachedCover {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.entries[dataURI]; ok {
+		return entry
+	}
+	if len(c.order) == coverCacheSize {
+		delete(c.entries, c.order[0])
+		c.order = c.order[1:]
+	}
+	entry := &cachedCover{dataURI: dataURI}
+	c.entries[dataURI] = entry
+	c.order = append(c.order, dataURI)
+	return entry
+}
+
+func decodeCover(dataURI string) ([]byte, string, error) {
+	entry := covers.get(dataURI)
+	entry.decodeOnce.Do(
+		func() {
+			entry.data, entry.mimeType, entry.decodeErr = parseCoverArtData(entry.dataURI)
+		},
+	)
+	return entry.data, entry.mimeType, entry.decodeErr
+}
+
+func frontCoverBlock(dataURI string) (flac.MetaDataBlock, string, error) {
+	entry := covers.get(dataURI)
+	entry.blockOnce.Do(
+		func() {
+			entry.block, entry.blockErr = newPictureBlock(model.PictureTypeFrontCover, "Front Cover", entry.dataURI)
+			if entry.blockErr == nil {
+				entry.vorbisBlock = base64.StdEncoding.EncodeToString(entry.block.Data)
+			}
+		},
+	)
+	return entry.block, entry.vorbisBlock, entry.blockErr
+}
+
+func fitCover(dataURI string, policy coverPolicy, fit func(string) (string, error)) (string, error) {
+	entry := covers.get(dataURI)
+	entry.fitMu.Lock()
+	defer entry.fitMu.Unlock()
+	if fitted, ok := entry.fits[policy]; ok {
+		return fitted.dataURI, fitted.err
+	}
+	fitted, err := fit(dataURI)
+	if entry.fits == nil {
+		entry.fits = make(map[coverPolicy]fittedCover)
+	}
+	entry.fits[policy] = fittedCover{dataURI: fitted, err: err}
+	return fitted, err
+}
@@ -0,0 +1,89 @@
+package audio
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/netguard"
+)
+
+var allowedCoverMimeTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+	"image/bmp":  true,
+}
+
+type coverFetcher struct {
+	httpClient *http.Client
+	maxSize    int64
+}
+
+func newCoverFetcher(timeout time.Duration, maxSize int64, allowPrivateHosts bool) *coverFetcher {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivateHosts {
+		dialer.Control = netguard.Control
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &coverFetcher{
+		httpClient: &http.Client{Timeout: timeout, Transport: transport},
+		maxSize:    maxSize,
+	}
+}
+
+func isRemoteCoverArt(coverArt string) bool {
+	return strings.HasPrefix(coverArt, "http://") || strings.HasPrefix(coverArt, "https://")
+}
+
+func (f *coverFetcher) fetch(ctx context.Context, rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("invalid cover art URL")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create cover art request: %w", err)
+	}
+	req.Header.Set("Accept", "image/*")
+
+	resp, err := f.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to download cover art: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("cover art download returned status %d", resp.StatusCode)
+	}
+	if resp.ContentLength > f.maxSize {
+		return "", fmt.Errorf("cover art is larger than %d bytes", f.maxSize)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, f.maxSize+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read cover art: %w", err)
+	}
+	if int64(len(data)) > f.maxSize {
+		return "", fmt.Errorf("cover art is larger than %d bytes", f.maxSize)
+	}
+
+	// The server's Content-Type is not trusted; the bytes decide.
+	mimeType := http.DetectContentType(data)
+	if !allowedCoverMimeTypes[mimeType] {
+		return "", fmt.Errorf("unsupported cover art type: %s", mimeType)
+	}
+
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+}
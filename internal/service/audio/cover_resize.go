@@ -0,0 +1,65 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"net/http"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/imaging"
+)
+
+const minCoverDimension = 300
+
+type coverLimits struct {
+	maxDimension int   // longest edge in pixels
+	maxBytes     int64 // size of the embedded image
+	quality      int   // JPEG quality of re-encoded covers
+}
+
+func (l coverLimits) fit(data []byte) ([]byte, string, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("cover art is not a readable image: %w", err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > imaging.MaxPixels {
+		return nil, "", fmt.Errorf("cover art of %dx%d pixels is too large", config.Width, config.Height)
+	}
+
+	size := max(config.Width, config.Height)
+	tooLarge := l.maxDimension > 0 && size > l.maxDimension
+	tooHeavy := l.maxBytes > 0 && int64(len(data)) > l.maxBytes
+	if !tooLarge && !tooHeavy {
+		return data, http.DetectContentType(data), nil
+	}
+	if tooLarge {
+		size = l.maxDimension
+	}
+
+	for {
+		resized, mimeType, err := imaging.Render(data, imaging.Options{Size: size, Format: "jpeg", Quality: l.quality})
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to downscale cover art: %w", err)
+		}
+		if l.maxBytes <= 0 || int64(len(resized)) <= l.maxBytes || size <= minCoverDimension {
+			return resized, mimeType, nil
+		}
+		size = max(size*3/4, minCoverDimension)
+	}
+}
+
+func (s *AudioService) fitCoverData(dataURI string) (string, error) {
+	data, _, err := decodeCover(dataURI)
+	if err != nil {
+		return "", err
+	}
+	fitted, mimeType, err := s.coverLimits.fit(data)
+	if err != nil {
+		return "", err
+	}
+	if bytes.Equal(fitted, data) {
+		return dataURI, nil
+	}
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(fitted), nil
+}
@@ -0,0 +1,299 @@
+package audio
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bogem/id3v2/v2"
+	"github.com/dhowden/tag"
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+)
+
+var standardVorbisKeys = map[string]bool{
+	"TITLE": true, "ARTIST": true, "ALBUM": true, "DATE": true, "YEAR": true, "GENRE": true,
+	"TRACKNUMBER": true, "DISCNUMBER": true, "ALBUMARTIST": true, "ALBUM ARTIST": true, "ALBUM_ARTIST": true,
+	"COMPOSER": true, "COMMENT": true, "DESCRIPTION": true, "BPM": true, "COMPILATION": true,
+	"TRACKTOTAL": true, "TOTALTRACKS": true, "TRACKC": true, "DISCTOTAL": true, "TOTALDISCS": true, "DISCC": true,
+	"LYRICS": true, "UNSYNCEDLYRICS": true, "SYNCEDLYRICS": true,
+	"ISRC": true, "BARCODE": true, "CATALOGNUMBER": true, "LABEL": true, "ORGANIZATION": true, "PUBLISHER": true,
+	"ORIGINAL RELEASE DATE": true, "ORIGINALYEAR": true, // spellings of the ORIGINALDATE custom tag
+	"TITLESORT": true, "ARTISTSORT": true, "ALBUMARTISTSORT": true, "ALBUMSORT": true,
+	"RATING": true, "FMPS_RATING": true, "FMPS_PLAYCOUNT": true, "PLAYCOUNT": true,
+	"METADATA_BLOCK_PICTURE": true, "COVERART": true, "COVERARTMIME": true, "VENDOR": true,
+	originalFilenameVorbisKey: true, // the ORIGINAL_FILENAME custom tag
+}
+
+type customTagPolicy struct {
+	allow []string
+	deny  []string
+}
+
+func newCustomTagPolicy(allow, deny []string) *customTagPolicy {
+	return &customTagPolicy{allow: normalizePatterns(allow), deny: normalizePatterns(deny)}
+}
+
+func normalizePatterns(patterns []string) []string {
+	var result []string
+	for _, pattern := range patterns {
+		if pattern = strings.ToUpper(strings.TrimSpace(pattern)); pattern != "" {
+			result = append(result, pattern)
+		}
+	}
+	return result
+}
+
+func (p *customTagPolicy) validate(customTags map[string]string) error {
+	for key := range customTags {
+		if err := p.check(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *customTagPolicy) check(key string) error {
+	name := strings.ToUpper(key)
+	if err := validateCustomTagKey(name); err != nil {
+		return err
+	}
+	if matchesAnyPattern(name, p.deny) || (len(p.allow) > 0 && !matchesAnyPattern(name, p.allow)) {
+		return fmt.Errorf("custom tag %q is not allowed", key)
+	}
+	return nil
+}
+
+func (p *customTagPolicy) allows(key string) bool {
+	return p.check(key) == nil
+}
+
+func validateCustomTagKey(name string) error {
+	if name == "" {
+		return fmt.Errorf("custom tag name is empty")
+	}
+	for i := 0; i < len(name); i++ {
+		if name[i] < 0x20 || name[i] > 0x7D || name[i] == '=' {
+			return fmt.Errorf("custom tag name %q contains invalid characters", name)
+		}
+	}
+	if standardVorbisKeys[name] || vorbisChapterKey.MatchString(name) {
+		return fmt.Errorf("%s is a standard field, not a custom tag", name)
+	}
+	return nil
+}
+
+func matchesAnyPattern(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedCustomTagKeys(customTags map[string]string) []string {
+	keys := make([]string, 0, len(customTags))
+	for key := range customTags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func addCustomTag(result *model.FileMetadata, key, value string) {
+	if result.CustomTags == nil {
+		result.CustomTags = make(map[string]string)
+	}
+	key = strings.ToUpper(key)
+	if existing, ok := result.CustomTags[key]; ok {
+		value = existing + "; " + value
+	}
+	result.CustomTags[key] = value
+}
+
+const originalFilenameVorbisKey = "ORIGFILENAME"
+
+const (
+	musicBrainzTrackID   = "MUSICBRAINZ_TRACKID"
+	musicBrainzUFIDOwner = "http://musicbrainz.org"
+)
+
+var picardID3Descriptions = map[string]string{
+	"MUSICBRAINZ_ALBUMID":          "MusicBrainz Album Id",
+	"MUSICBRAINZ_ARTISTID":         "MusicBrainz Artist Id",
+	"MUSICBRAINZ_ALBUMARTISTID":    "MusicBrainz Album Artist Id",
+	"MUSICBRAINZ_RELEASEGROUPID":   "MusicBrainz Release Group Id",
+	"MUSICBRAINZ_RELEASETRACKID":   "MusicBrainz Release Track Id",
+	"MUSICBRAINZ_WORKID":           "MusicBrainz Work Id",
+	"MUSICBRAINZ_DISCID":           "MusicBrainz Disc Id",
+	"MUSICBRAINZ_ORIGINALALBUMID":  "MusicBrainz Original Album Id",
+	"MUSICBRAINZ_ORIGINALARTISTID": "MusicBrainz Original Artist Id",
+	"MUSICBRAINZ_TRMID":            "MusicBrainz TRM Id",
+	"RELEASESTATUS":                "MusicBrainz Album Status",
+	"RELEASETYPE":                  "MusicBrainz Album Type",
+	"RELEASECOUNTRY":               "MusicBrainz Album Release Country",
+	"ACOUSTID_ID":                  "Acoustid Id",
+	"ACOUSTID_FINGERPRINT":         "Acoustid Fingerprint",
+	"MUSICIP_PUID":                 "MusicIP PUID",
+}
+
+var picardCustomTagKeys = func() map[string]string {
+	keys := make(map[string]string, len(picardID3Descriptions))
+	for key, description := range picardID3Descriptions {
+		keys[strings.ToUpper(description)] = key
+	}
+	return keys
+}()
+
+func isMultiValuedID(key string) bool {
+	return strings.HasPrefix(key, "MUSICBRAINZ_") && strings.HasSuffix(key, "ID")
+}
+
+func setID3CustomTag(id3Tag *id3v2.Tag, key, value string) {
+	key = strings.ToUpper(key)
+	if key == originalDateKey {
+		setID3OriginalDate(id3Tag, value)
+		return
+	}
+	if key == musicBrainzTrackID {
+		setID3UserText(id3Tag, key, "")
+		frames := id3Tag.GetFrames("UFID")
+		id3Tag.DeleteFrames("UFID")
+		for _, frame := range frames {
+			if ufid, ok := frame.(id3v2.UFIDFrame); !ok || ufid.OwnerIdentifier != musicBrainzUFIDOwner {
+				id3Tag.AddFrame("UFID", frame)
+			}
+		}
+		if value != "" {
+			id3Tag.AddFrame("UFID", id3v2.UFIDFrame{OwnerIdentifier: musicBrainzUFIDOwner, Identifier: []byte(value)})
+		}
+		return
+	}
+	description, ok := picardID3Descriptions[key]
+	if !ok {
+		setID3UserText(id3Tag, key, value)
+		return
+	}
+	setID3UserText(id3Tag, key, "")
+	if isMultiValuedID(key) && id3Tag.Version() == 4 {
+		// ID3v2.4 separates the values of a text frame with null characters.
+		value = strings.Join(splitCustomTagValues(value), "\x00")
+	}
+	setID3UserText(id3Tag, description, value)
+}
+
+func setID3OriginalDate(id3Tag *id3v2.Tag, value string) {
+	id3Tag.DeleteFrames("TDOR")
+	id3Tag.DeleteFrames("TORY")
+	setID3UserText(id3Tag, originalDateKey, "")
+	switch {
+	case value == "":
+	case id3Tag.Version() == 4:
+		setID3TextFrame(id3Tag, "TDOR", value)
+	default:
+		if len(value) >= 4 {
+			setID3TextFrame(id3Tag, "TORY", value[:4])
+		}
+		if len(value) != 4 {
+			setID3UserText(id3Tag, originalDateKey, value)
+		}
+	}
+}
+
+func addID3OriginalDate(result *model.FileMetadata, frame func(id string) string) {
+	if _, ok := result.CustomTags[originalDateKey]; ok {
+		return
+	}
+	for _, id := range []string{"TDOR", "TORY", "TOR"} {
+		if value := strings.TrimSpace(frame(id)); value != "" {
+			addCustomTag(result, originalDateKey, value)
+			return
+		}
+	}
+}
+
+func splitCustomTagValues(value string) []string {
+	var values []string
+	for _, part := range strings.Split(value, ";") {
+		if part = strings.TrimSpace(part); part != "" {
+			values = append(values, part)
+		}
+	}
+	return values
+}
+
+func setID3UserText(id3Tag *id3v2.Tag, description, value string) {
+	frames := id3Tag.GetFrames("TXXX")
+	id3Tag.DeleteFrames("TXXX")
+	for _, frame := range frames {
+		if userText, ok := frame.(id3v2.UserDefinedTextFrame); ok && strings.EqualFold(userText.Description, description) {
+			continue
+		}
+		id3Tag.AddFrame("TXXX", frame)
+	}
+	if value != "" {
+		id3Tag.AddUserDefinedTextFrame(
+			id3v2.UserDefinedTextFrame{
+				Encoding:    id3v2.EncodingUTF8,
+				Description: description,
+				Value:       value,
+			},
+		)
+	}
+}
+
+func extractID3CustomTags(id3Tag *id3v2.Tag, result *model.FileMetadata) {
+	for _, frame := range id3Tag.GetFrames("TXXX") {
+		if userText, ok := frame.(id3v2.UserDefinedTextFrame); ok && userText.Description != "" {
+			addID3UserText(result, userText.Description, userText.Value)
+		}
+	}
+	for _, frame := range id3Tag.GetFrames("UFID") {
+		if ufid, ok := frame.(id3v2.UFIDFrame); ok && ufid.OwnerIdentifier == musicBrainzUFIDOwner {
+			addCustomTag(result, musicBrainzTrackID, string(ufid.Identifier))
+		}
+	}
+	addID3OriginalDate(result, func(id string) string { return id3Tag.GetTextFrame(id).Text })
+}
+
+func addID3UserText(result *model.FileMetadata, description, value string) {
+	value = strings.Join(strings.FieldsFunc(value, func(r rune) bool { return r == 0 }), "; ")
+	switch strings.ToUpper(description) {
+	case "BARCODE":
+		result.Barcode = value
+	case "CATALOGNUMBER":
+		result.CatalogNumber = value
+	default:
+		if key, ok := picardCustomTagKeys[strings.ToUpper(description)]; ok {
+			description = key
+		}
+		addCustomTag(result, description, value)
+	}
+}
+
+func extractRawID3CustomTags(raw map[string]interface{}, result *model.FileMetadata) {
+	for key, value := range raw {
+		if !strings.HasPrefix(key, "TXXX") && !strings.HasPrefix(key, "TXX") {
+			continue
+		}
+		if comm, ok := value.(*tag.Comm); ok && comm.Description != "" {
+			addID3UserText(result, comm.Description, comm.Text)
+		}
+	}
+	for key, value := range raw {
+		if !strings.HasPrefix(key, "UFID") && !strings.HasPrefix(key, "UFI") {
+			continue
+		}
+		if ufid, ok := value.(*tag.UFID); ok && ufid.Provider == musicBrainzUFIDOwner {
+			addCustomTag(result, musicBrainzTrackID, string(ufid.Identifier))
+		}
+	}
+	addID3OriginalDate(result, func(id string) string {
+		value, _ := raw[id].(string)
+		return value
+	})
+}
@@ -0,0 +1,75 @@
+package audio
+
+import (
+	"strings"
+	"time"
+
+	"github.com/bogem/id3v2/v2"
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+)
+
+var releaseDateLayouts = []string{"2006-01-02", "2006-01", "2006"}
+
+func normalizeReleaseDate(value string) string {
+	value = strings.TrimSpace(value)
+	for _, layout := range releaseDateLayouts {
+		if len(value) < len(layout) {
+			continue
+		}
+		if _, err := time.Parse(layout, value[:len(layout)]); err == nil {
+			return value[:len(layout)]
+		}
+	}
+	return ""
+}
+
+func updatedReleaseDate(existing string, update *model.TagUpdate) (string, bool) {
+	if update.ReleaseDate != nil && *update.ReleaseDate != "" {
+		return *update.ReleaseDate, true
+	}
+	if update.Year != nil {
+		if *update.Year > 0 && parseLeadingInt(normalizeReleaseDate(existing)) == *update.Year {
+			return existing, false
+		}
+		return formatPositive(*update.Year), true
+	}
+	if update.ReleaseDate != nil {
+		return "", true
+	}
+	return "", false
+}
+
+func id3ReleaseDate(get func(id string) string) string {
+	if date := normalizeReleaseDate(get("TDRC")); date != "" {
+		return date
+	}
+	year, day := get("TYER"), get("TDAT")
+	if year == "" {
+		year, day = get("TYE"), get("TDA")
+	}
+	if len(year) >= 4 && len(day) == 4 {
+		if date := normalizeReleaseDate(year[:4] + "-" + day[2:4] + "-" + day[0:2]); date != "" {
+			return date
+		}
+	}
+	return normalizeReleaseDate(year)
+}
+
+func setID3ReleaseDate(id3Tag *id3v2.Tag, date string) {
+	if id3Tag.Version() == 4 {
+		id3Tag.DeleteFrames("TYER")
+		id3Tag.DeleteFrames("TDAT")
+		setID3TextFrame(id3Tag, "TDRC", date)
+		return
+	}
+	id3Tag.DeleteFrames("TDRC")
+	year, day := date, ""
+	if len(date) >= 4 {
+		year = date[:4]
+	}
+	if len(date) == 10 {
+		day = date[8:10] + date[5:7]
+	}
+	setID3TextFrame(id3Tag, "TYER", year)
+	setID3TextFrame(id3Tag, "TDAT", day)
+}
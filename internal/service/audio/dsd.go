@@ -0,0 +1,425 @@
+package audio
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/internal/vfs"
+)
+
+const (
+	dsfHeaderSize   = 28
+	dffHeaderSize   = 16
+	dsdBaseRate     = 44100
+	dsfTotalSizePos = 12
+	dsfMetadataPos  = 20
+
+	maxDFFChunkSize = 64 << 20
+)
+
+type dsdStream struct {
+	sampleRate int
+	channels   int
+	duration   float64
+	compressed bool
+	start, end int64
+}
+
+func dsdCodec(stream *dsdStream) string {
+	codec := fmt.Sprintf("DSD%d", stream.sampleRate/dsdBaseRate)
+	if stream.compressed {
+		codec += " (DST)"
+	}
+	return codec
+}
+
+func (s *dsdStream) audioInfo(result *model.FileMetadata) {
+	result.Codec = dsdCodec(s)
+	result.Lossless = true
+	result.SampleRate = s.sampleRate
+	result.Channels = s.channels
+	result.BitsPerSample = 1
+	result.Bitrate = averageBitrate(s.end-s.start, result.Duration)
+}
+
+type dsfFile struct {
+	dsdStream
+	metadataOffset int64 // where the ID3v2 tag starts, 0 when there is none
+}
+
+type dsfHandler struct{}
+
+func newDSFHandler() *dsfHandler {
+	return &dsfHandler{}
+}
+
+func (h *dsfHandler) Format() string {
+	return "DSF"
+}
+
+func (h *dsfHandler) Capabilities() model.Capabilities {
+	return fullCapabilities
+}
+
+func (h *dsfHandler) ExtractDuration(ctx context.Context, r io.ReaderAt, size int64) (float64, error) {
+	dsf, err := readDSFFile(r, size)
+	if err != nil {
+		return 0, err
+	}
+	return dsf.duration, nil
+}
+
+func (h *dsfHandler) ExtractAudioInfo(r io.ReaderAt, size int64, result *model.FileMetadata) error {
+	dsf, err := readDSFFile(r, size)
+	if err != nil {
+		return err
+	}
+	dsf.audioInfo(result)
+	return nil
+}
+
+func (h *dsfHandler) Parse(r io.ReaderAt, size int64, name string) (*model.FileMetadata, error) {
+	result := &model.FileMetadata{
+		Size:   size,
+		Format: h.Format(),
+	}
+	dsf, err := readDSFFile(r, size)
+	if err != nil {
+		result.Title = name
+		return result, err
+	}
+	if dsf.metadataOffset > 0 {
+		data := make([]byte, size-dsf.metadataOffset)
+		if _, err := r.ReadAt(data, dsf.metadataOffset); err != nil {
+			result.Title = name
+			return result, fmt.Errorf("failed to read DSF ID3v2 tag: %w", unexpectedEOF(err))
+		}
+		extractID3ChunkMetadata(data, result)
+	}
+	if result.Title == "" {
+		result.Title = name
+	}
+	result.Duration = dsf.duration
+	return result, nil
+}
+
+func (h *dsfHandler) UpdateTags(ctx context.Context, fsys vfs.FS, filePath string, update *model.TagUpdate) error {
+	file, err := fsys.OpenFile(filePath, os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+	dsf, err := readDSFFile(file, stat.Size())
+	if err != nil {
+		return err
+	}
+	tagStart := dsf.end
+	var existing []byte
+	if dsf.metadataOffset > 0 {
+		tagStart = dsf.metadataOffset
+		existing = make([]byte, stat.Size()-dsf.metadataOffset)
+		if _, err := file.ReadAt(existing, dsf.metadataOffset); err != nil {
+			return fmt.Errorf("failed to read DSF ID3v2 tag: %w", err)
+		}
+	}
+	id3Data, err := buildID3Chunk(existing, update)
+	if err != nil {
+		return err
+	}
+
+	if err := file.Truncate(tagStart); err != nil {
+		return fmt.Errorf("failed to truncate file: %w", err)
+	}
+	if _, err := file.WriteAt(id3Data, tagStart); err != nil {
+		return fmt.Errorf("failed to write DSF ID3v2 tag: %w", err)
+	}
+	header := make([]byte, 16)
+	binary.LittleEndian.PutUint64(header[0:8], uint64(tagStart)+uint64(len(id3Data)))
+	binary.LittleEndian.PutUint64(header[8:16], uint64(tagStart))
+	if _, err := file.WriteAt(header, dsfTotalSizePos); err != nil {
+		return fmt.Errorf("failed to write DSF header: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close file: %w", err)
+	}
+	return nil
+}
+
+func (h *dsfHandler) writeAudioStream(r io.ReaderAt, size int64, w io.Writer) error {
+	dsf, err := readDSFFile(r, size)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, io.NewSectionReader(r, dsf.start, dsf.end-dsf.start))
+	return err
+}
+
+func readDSFFile(r io.ReaderAt, size int64) (*dsfFile, error) {
+	header := make([]byte, dsfHeaderSize+12+40)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, fmt.Errorf("failed to read DSF header: %w", unexpectedEOF(err))
+	}
+	if string(header[0:4]) != "DSD " || string(header[28:32]) != "fmt " {
+		return nil, fmt.Errorf("not a valid DSF file")
+	}
+	dsf := &dsfFile{metadataOffset: int64(binary.LittleEndian.Uint64(header[dsfMetadataPos:]))}
+	if dsf.metadataOffset >= size {
+		dsf.metadataOffset = 0
+	}
+
+	format := header[40:]
+	dsf.channels = int(binary.LittleEndian.Uint32(format[12:16]))
+	dsf.sampleRate = int(binary.LittleEndian.Uint32(format[16:20]))
+	samples := binary.LittleEndian.Uint64(format[24:32])
+	if dsf.sampleRate == 0 {
+		return nil, fmt.Errorf("DSF fmt chunk has no sample rate")
+	}
+	dsf.duration = float64(samples) / float64(dsf.sampleRate)
+
+	dataOffset := dsfHeaderSize + int64(binary.LittleEndian.Uint64(header[32:40]))
+	chunk := make([]byte, 12)
+	if _, err := r.ReadAt(chunk, dataOffset); err != nil {
+		return nil, fmt.Errorf("failed to read DSF data chunk: %w", unexpectedEOF(err))
+	}
+	if string(chunk[0:4]) != "data" {
+		return nil, fmt.Errorf("DSF data chunk not found")
+	}
+	dsf.start = dataOffset + 12
+	dsf.end = dataOffset + int64(binary.LittleEndian.Uint64(chunk[4:12]))
+	if dsf.end < dsf.start || dsf.end > size {
+		return nil, fmt.Errorf("DSF data chunk is truncated")
+	}
+	if dsf.metadataOffset > 0 && dsf.metadataOffset < dsf.end {
+		return nil, fmt.Errorf("DSF metadata overlaps the data chunk")
+	}
+	return dsf, nil
+}
+
+type dffHandler struct{}
+
+func newDFFHandler() *dffHandler {
+	return &dffHandler{}
+}
+
+func (h *dffHandler) Format() string {
+	return "DFF"
+}
+
+func (h *dffHandler) Capabilities() model.Capabilities {
+	return model.Capabilities{Read: true, ExactDuration: true}
+}
+
+func (h *dffHandler) ExtractDuration(ctx context.Context, r io.ReaderAt, size int64) (float64, error) {
+	stream, _, err := readDFFFile(r, size)
+	if err != nil {
+		return 0, err
+	}
+	return stream.duration, nil
+}
+
+func (h *dffHandler) ExtractAudioInfo(r io.ReaderAt, size int64, result *model.FileMetadata) error {
+	stream, _, err := readDFFFile(r, size)
+	if err != nil {
+		return err
+	}
+	stream.audioInfo(result)
+	return nil
+}
+
+func (h *dffHandler) Parse(r io.ReaderAt, size int64, name string) (*model.FileMetadata, error) {
+	result := &model.FileMetadata{
+		Size:   size,
+		Format: h.Format(),
+	}
+	stream, tags, err := readDFFFile(r, size)
+	if err != nil {
+		result.Title = name
+		return result, err
+	}
+	result.Title = tags.title
+	result.Artist = tags.artist
+	if len(tags.id3) > 0 {
+		extractID3ChunkMetadata(tags.id3, result)
+	}
+	if result.Title == "" {
+		result.Title = name
+	}
+	result.Duration = stream.duration
+	return result, nil
+}
+
+func (h *dffHandler) UpdateTags(ctx context.Context, fsys vfs.FS, filePath string, update *model.TagUpdate) error {
+	return fmt.Errorf("%w: tag writing not yet supported for DFF", model.ErrUnsupportedFormat)
+}
+
+func (h *dffHandler) writeAudioStream(r io.ReaderAt, size int64, w io.Writer) error {
+	stream, _, err := readDFFFile(r, size)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, io.NewSectionReader(r, stream.start, stream.end-stream.start))
+	return err
+}
+
+type dffTags struct {
+	title  string
+	artist string
+	id3    []byte
+}
+
+func readDFFFile(r io.ReaderAt, size int64) (*dsdStream, *dffTags, error) {
+	header := make([]byte, dffHeaderSize)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, nil, fmt.Errorf("failed to read DFF header: %w", unexpectedEOF(err))
+	}
+	if string(header[0:4]) != "FRM8" || string(header[12:16]) != "DSD " {
+		return nil, nil, fmt.Errorf("not a valid DFF file")
+	}
+	end := min(size, 12+int64(binary.BigEndian.Uint64(header[4:12])))
+
+	stream := &dsdStream{}
+	tags := &dffTags{}
+	var dataSize int64
+	var frames, frameRate int
+	err := walkDFFChunks(r, dffHeaderSize, end, func(id string, offset, length int64) error {
+		switch id {
+		case "PROP":
+			return walkDFFChunks(r, offset+4, offset+length, func(id string, offset, length int64) error {
+				data, err := readDFFChunk(r, offset, length, 4)
+				if err != nil {
+					return err
+				}
+				switch id {
+				case "FS  ":
+					stream.sampleRate = int(binary.BigEndian.Uint32(data))
+				case "CHNL":
+					stream.channels = int(binary.BigEndian.Uint16(data))
+				case "CMPR":
+					stream.compressed = string(data[0:4]) == "DST "
+				}
+				return nil
+			})
+		case "DSD ":
+			stream.start, stream.end = offset, offset+length
+			dataSize = length
+		case "DST ":
+			stream.start, stream.end = offset, offset+length
+			return walkDFFChunks(r, offset, offset+length, func(id string, offset, length int64) error {
+				if id != "FRTE" {
+					return nil
+				}
+				data, err := readDFFChunk(r, offset, length, 6)
+				if err != nil {
+					return err
+				}
+				frames = int(binary.BigEndian.Uint32(data[0:4]))
+				frameRate = int(binary.BigEndian.Uint16(data[4:6]))
+				return errStopDFFWalk
+			})
+		case "DIIN":
+			return walkDFFChunks(r, offset, offset+length, func(id string, offset, length int64) error {
+				if id != "DITI" && id != "DIAR" {
+					return nil
+				}
+				data, err := readDFFChunk(r, offset, length, 4)
+				if err != nil {
+					return err
+				}
+				text := data[4:]
+				if count := int(binary.BigEndian.Uint32(data[0:4])); count < len(text) {
+					text = text[:count]
+				}
+				if id == "DITI" {
+					tags.title = strings.TrimRight(string(text), "\x00 ")
+				} else {
+					tags.artist = strings.TrimRight(string(text), "\x00 ")
+				}
+				return nil
+			})
+		case "ID3 ":
+			data, err := readDFFChunk(r, offset, length, 0)
+			if err != nil {
+				return err
+			}
+			tags.id3 = data
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if stream.sampleRate == 0 || stream.channels == 0 {
+		return nil, nil, fmt.Errorf("DFF property chunk not found")
+	}
+	if stream.end == 0 {
+		return nil, nil, fmt.Errorf("DFF sound data not found")
+	}
+	switch {
+	case stream.compressed && frameRate > 0:
+		stream.duration = float64(frames) / float64(frameRate)
+	case !stream.compressed:
+		stream.duration = float64(dataSize*8/int64(stream.channels)) / float64(stream.sampleRate)
+	}
+	return stream, tags, nil
+}
+
+var errStopDFFWalk = errors.New("stop")
+
+func walkDFFChunks(r io.ReaderAt, offset, end int64, visit func(id string, offset, length int64) error) error {
+	header := make([]byte, 12)
+	for offset+12 <= end {
+		if _, err := r.ReadAt(header, offset); err != nil {
+			return fmt.Errorf("failed to read DFF chunk header: %w", unexpectedEOF(err))
+		}
+		length := int64(binary.BigEndian.Uint64(header[4:12]))
+		if length < 0 || offset+12+length > end {
+			return fmt.Errorf("DFF chunk %q is truncated", header[0:4])
+		}
+		if err := visit(string(header[0:4]), offset+12, length); err != nil {
+			if errors.Is(err, errStopDFFWalk) {
+				return nil
+			}
+			return err
+		}
+		offset += 12 + length + length%2
+	}
+	return nil
+}
+
+func readDFFChunk(r io.ReaderAt, offset, length int64, minLength int) ([]byte, error) {
+	if length < int64(minLength) || length > maxDFFChunkSize {
+		return nil, fmt.Errorf("DFF chunk at offset %d has an invalid size", offset)
+	}
+	data := make([]byte, length)
+	if _, err := r.ReadAt(data, offset); err != nil {
+		return nil, fmt.Errorf("failed to read DFF chunk: %w", unexpectedEOF(err))
+	}
+	return data, nil
+}
+
+func getDSFHandler(ext string) FormatHandler {
+	ext = strings.ToUpper(ext)
+	if ext == "DSF" {
+		return newDSFHandler()
+	}
+	return nil
+}
+
+func getDFFHandler(ext string) FormatHandler {
+	ext = strings.ToUpper(ext)
+	if ext == "DFF" {
+		return newDFFHandler()
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package audio
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+var legacyTextFormats = map[string]bool{"MP3": true, "WAV": true}
+
+// ParseTextEncodings looks up code pages by their WHATWG names, such as windows-1251 or gbk.
+func ParseTextEncodings(names []string) ([]encoding.Encoding, error) {
+	encodings := make([]encoding.Encoding, 0, len(names))
+	for _, name := range names {
+		enc, err := htmlindex.Get(strings.TrimSpace(name))
+		if err != nil {
+			return nil, fmt.Errorf("unsupported text encoding %q: %w", name, err)
+		}
+		encodings = append(encodings, enc)
+	}
+	return encodings, nil
+}
+
+type encodingRepairer struct {
+	encodings []encoding.Encoding
+}
+
+func (r *encodingRepairer) repairMetadata(metadata *model.FileMetadata, name string) *model.TagUpdate {
+	if !legacyTextFormats[metadata.Format] {
+		return nil
+	}
+	var update model.TagUpdate
+	repaired := false
+	type field struct {
+		value  *string
+		update **string
+	}
+	fields := []field{
+		{&metadata.Artist, &update.Artist},
+		{&metadata.Album, &update.Album},
+		{&metadata.AlbumArtist, &update.AlbumArtist},
+		{&metadata.Composer, &update.Composer},
+		{&metadata.Comment, &update.Comment},
+		{&metadata.Genre, &update.Genre},
+		{&metadata.Lyrics, &update.Lyrics},
+		{&metadata.Label, &update.Label},
+		{&metadata.CatalogNumber, &update.CatalogNumber},
+		{&metadata.TitleSort, &update.TitleSort},
+		{&metadata.ArtistSort, &update.ArtistSort},
+		{&metadata.AlbumArtistSort, &update.AlbumArtistSort},
+		{&metadata.AlbumSort, &update.AlbumSort},
+	}
+	if metadata.Title != name {
+		fields = append(fields, field{&metadata.Title, &update.Title})
+	}
+	for _, field := range fields {
+		if text, ok := r.repair(*field.value); ok {
+			*field.value = text
+			*field.update = &text
+			repaired = true
+		}
+	}
+	for key, value := range metadata.CustomTags {
+		if text, ok := r.repair(value); ok {
+			metadata.CustomTags[key] = text
+			if update.CustomTags == nil {
+				update.CustomTags = make(map[string]string)
+			}
+			update.CustomTags[key] = text
+			repaired = true
+		}
+	}
+	if !repaired {
+		return nil
+	}
+	return &update
+}
+
+func (r *encodingRepairer) repair(text string) (string, bool) {
+	raw, ok := latin1Bytes(text)
+	if !ok {
+		return text, false
+	}
+	if utf8.Valid(raw) {
+		return string(raw), true
+	}
+	for _, enc := range r.encodings {
+		decoded, err := enc.NewDecoder().Bytes(raw)
+		if err == nil && plausibleDecoding(string(decoded)) {
+			return string(decoded), true
+		}
+	}
+	return text, false
+}
+
+func latin1Bytes(text string) ([]byte, bool) {
+	raw := make([]byte, 0, len(text))
+	high := false
+	for _, r := range text {
+		if r > 0xFF {
+			return nil, false
+		}
+		high = high || r >= 0x80
+		raw = append(raw, byte(r))
+	}
+	return raw, high
+}
+
+func plausibleDecoding(text string) bool {
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) }) {
+		latin, other := false, false
+		for _, r := range word {
+			if unicode.Is(unicode.Latin, r) {
+				latin = true
+			} else {
+				other = true
+			}
+		}
+		if latin && other {
+			return false
+		}
+	}
+	for _, r := range text {
+		if r == utf8.RuneError || (unicode.IsControl(r) && r != '\n' && r != '\r' && r != '\t') {
+			return false
+		}
+	}
+	return true
+}
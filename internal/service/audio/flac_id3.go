@@ -0,0 +1,79 @@
+package audio
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/bogem/id3v2/v2"
+	"github.com/go-flac/go-flac"
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+)
+
+// FLACID3Mode controls what happens to an ID3v2 tag in front of a FLAC stream.
+type FLACID3Mode string
+
+const (
+	FLACID3Keep  FLACID3Mode = "keep"  // leave the tag as it is
+	FLACID3Strip FLACID3Mode = "strip" // remove the tag
+	FLACID3Sync  FLACID3Mode = "sync"  // rewrite an existing tag from the Vorbis comments and pictures
+)
+
+func ParseFLACID3Mode(mode string) (FLACID3Mode, error) {
+	switch m := FLACID3Mode(strings.ToLower(mode)); m {
+	case "":
+		return FLACID3Keep, nil
+	case FLACID3Keep, FLACID3Strip, FLACID3Sync:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unsupported FLAC ID3 mode: %s", mode)
+	}
+}
+
+func (h *flacHandler) id3Prefix(existing []byte, blocks []*flac.MetaDataBlock) ([]byte, error) {
+	if len(existing) == 0 {
+		return nil, nil
+	}
+	switch h.id3 {
+	case FLACID3Strip:
+		return nil, nil
+	case FLACID3Sync:
+		return h.syncedID3Tag(existing, blocks)
+	default:
+		return existing, nil
+	}
+}
+
+func (h *flacHandler) syncedID3Tag(existing []byte, blocks []*flac.MetaDataBlock) ([]byte, error) {
+	stream := append([]byte("fLaC"), marshalFLACBlocks(blocks, -1)...)
+	metadata, err := h.Parse(bytes.NewReader(stream), int64(len(stream)), "")
+	if err != nil {
+		return nil, err
+	}
+
+	id3Tag, err := id3v2.ParseReader(bytes.NewReader(existing), id3v2.Options{Parse: true})
+	if err != nil {
+		id3Tag = id3v2.NewEmptyTag()
+	}
+	id3Tag.SetDefaultEncoding(id3v2.EncodingUTF8)
+	tags := metadata.TagUpdate()
+	applyID3Tags(id3Tag, &tags)
+
+	id3Tag.DeleteFrames("APIC")
+	pictures := make([]model.PictureUpdate, 0, len(metadata.Pictures))
+	for _, picture := range metadata.Pictures {
+		pictures = append(
+			pictures, model.PictureUpdate{Type: picture.Type, Description: picture.Description, Data: picture.DataURI()},
+		)
+	}
+	if err := applyID3Pictures(id3Tag, pictures); err != nil {
+		return nil, err
+	}
+
+	fixID3Encodings(id3Tag)
+	var buf bytes.Buffer
+	if _, err := id3Tag.WriteTo(&buf); err != nil {
+		return nil, fmt.Errorf("failed to write ID3v2 tag: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,243 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/go-flac/flacvorbis"
+	"github.com/go-flac/go-flac"
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/internal/vfs"
+)
+
+const maxFLACBlockSize = 1<<24 - 1
+
+type tagPatcher interface {
+	PatchTags(ctx context.Context, fsys vfs.FS, filePath string, update *model.TagUpdate) (bool, error)
+}
+
+func (h *flacHandler) PatchTags(
+	ctx context.Context, fsys vfs.FS, filePath string, update *model.TagUpdate,
+) (bool, error) {
+	file, err := fsys.OpenFile(filePath, os.O_RDWR, 0)
+	if err != nil {
+		return false, fmt.Errorf("failed to open FLAC file: %w", err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return false, fmt.Errorf("failed to stat file: %w", err)
+	}
+	streamOffset, audioOffset, blocks, err := readFLACBlocks(file)
+	if err != nil {
+		return false, err
+	}
+	// Removing or rewriting the ID3v2 tag moves the stream.
+	if streamOffset > 0 && (h.id3 == FLACID3Strip || h.id3 == FLACID3Sync) {
+		return false, nil
+	}
+
+	blocks, err = updateFLACBlocks(blocks, update)
+	if err != nil {
+		return false, err
+	}
+
+	space := audioOffset - streamOffset - 4
+	size := flacBlocksSize(blocks)
+	padding := space - size - 4
+	switch {
+	case size == space:
+		padding = -1
+	case padding < 0 || padding > maxFLACBlockSize:
+		return false, nil
+	}
+
+	region := marshalFLACBlocks(blocks, int(padding))
+	original := make([]byte, len(region))
+	if _, err := file.ReadAt(original, streamOffset+4); err != nil {
+		return false, fmt.Errorf("failed to read FLAC metadata: %w", err)
+	}
+	if _, err := file.WriteAt(region, streamOffset+4); err != nil {
+		file.WriteAt(original, streamOffset+4)
+		return false, fmt.Errorf("failed to write FLAC metadata: %w", err)
+	}
+	if _, err := h.Parse(file, stat.Size(), filePath); err != nil {
+		file.WriteAt(original, streamOffset+4)
+		return false, fmt.Errorf("written metadata is damaged, original restored: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return false, fmt.Errorf("failed to sync FLAC file: %w", err)
+	}
+	file.Close()
+
+	if err := fsys.Chtimes(filePath, stat.ModTime(), stat.ModTime()); err != nil {
+		return false, fmt.Errorf("failed to restore modification time: %w", err)
+	}
+	return true, nil
+}
+
+func (h *flacHandler) UpdateTags(ctx context.Context, fsys vfs.FS, filePath string, update *model.TagUpdate) error {
+	source, err := fsys.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open FLAC file: %w", err)
+	}
+	defer source.Close()
+
+	streamOffset, audioOffset, blocks, err := readFLACBlocks(source)
+	if err != nil {
+		return err
+	}
+	blocks, err = updateFLACBlocks(blocks, update)
+	if err != nil {
+		return err
+	}
+	existing := make([]byte, streamOffset)
+	if _, err := source.ReadAt(existing, 0); err != nil {
+		return fmt.Errorf("failed to read ID3v2 tag: %w", err)
+	}
+	prefix, err := h.id3Prefix(existing, blocks)
+	if err != nil {
+		return err
+	}
+
+	dir, base := filepath.Split(filePath)
+	temp, err := fsys.CreateTemp(dir, "."+base+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tempPath := temp.Name()
+	defer fsys.Remove(tempPath)
+
+	err = writeFLAC(temp, prefix, blocks, h.padding, io.NewSectionReader(source, audioOffset, math.MaxInt64-audioOffset))
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write FLAC file: %w", err)
+	}
+	source.Close()
+
+	if err := fsys.Rename(tempPath, filePath); err != nil {
+		return fmt.Errorf("failed to replace file: %w", err)
+	}
+	return nil
+}
+
+func writeFLAC(w io.Writer, prefix []byte, blocks []*flac.MetaDataBlock, padding int, audio io.Reader) error {
+	if padding <= 0 {
+		padding = -1
+	}
+	header := append(prefix[:len(prefix):len(prefix)], "fLaC"...)
+	header = append(header, marshalFLACBlocks(blocks, min(padding, maxFLACBlockSize))...)
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := io.Copy(w, audio)
+	return err
+}
+
+func readFLACBlocks(r io.ReaderAt) (int64, int64, []*flac.MetaDataBlock, error) {
+	streamOffset, err := flacStreamOffset(r)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	audioOffset, err := flacAudioOffset(r, streamOffset)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	parsed, err := flac.ParseMetadata(io.NewSectionReader(r, streamOffset, audioOffset-streamOffset))
+	if err != nil {
+		return 0, 0, nil, fmt.Errorf("failed to parse FLAC metadata: %w", err)
+	}
+	return streamOffset, audioOffset, parsed.Meta, nil
+}
+
+func updateFLACBlocks(blocks []*flac.MetaDataBlock, update *model.TagUpdate) ([]*flac.MetaDataBlock, error) {
+	result := make([]*flac.MetaDataBlock, 0, len(blocks)+1)
+	var vorbisComment *flacvorbis.MetaDataBlockVorbisComment
+	vorbisIndex := -1
+	for _, block := range blocks {
+		switch block.Type {
+		case flac.Padding:
+			continue
+		case flac.VorbisComment:
+			parsed, err := flacvorbis.ParseFromMetaDataBlock(*block)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse FLAC comments: %w", err)
+			}
+			if vorbisComment != nil {
+				vorbisComment.Comments = appendMissingComments(vorbisComment.Comments, parsed.Comments)
+				continue
+			}
+			vorbisComment = parsed
+			vorbisIndex = len(result)
+		}
+		result = append(result, block)
+	}
+	if vorbisComment == nil {
+		vorbisComment = flacvorbis.New()
+		vorbisIndex = len(result)
+		result = append(result, nil)
+	}
+
+	applyVorbisCommentTags(vorbisComment, update)
+	marshaled := vorbisComment.Marshal()
+	result[vorbisIndex] = &marshaled
+
+	if update.CoverArt != nil && *update.CoverArt != "" {
+		cover, _, err := frontCoverBlock(*update.CoverArt)
+		if err != nil {
+			return nil, err
+		}
+		kept := result[:0]
+		for _, block := range result {
+			if block.Type != flac.Picture {
+				kept = append(kept, block)
+			}
+		}
+		result = append(kept, &cover)
+	}
+	if len(update.Pictures) > 0 {
+		var err error
+		result, err = applyFLACPictures(result, update.Pictures)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func appendMissingComments(comments, extra []string) []string {
+	for _, comment := range extra {
+		if !slices.Contains(comments, comment) {
+			comments = append(comments, comment)
+		}
+	}
+	return comments
+}
+
+func flacBlocksSize(blocks []*flac.MetaDataBlock) int64 {
+	var size int64
+	for _, block := range blocks {
+		size += 4 + int64(len(block.Data))
+	}
+	return size
+}
+
+func marshalFLACBlocks(blocks []*flac.MetaDataBlock, padding int) []byte {
+	if padding >= 0 {
+		blocks = append(blocks[:len(blocks):len(blocks)], &flac.MetaDataBlock{Type: flac.Padding, Data: make([]byte, padding)})
+	}
+	var buffer bytes.Buffer
+	for i, block := range blocks {
+		buffer.Write(block.Marshal(i == len(blocks)-1))
+	}
+	return buffer.Bytes()
+}
@@ -0,0 +1,308 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"image"
+	"image/png"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/bogem/id3v2/v2"
+	"github.com/go-flac/go-flac"
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+)
+
+func flacLayout(t *testing.T, path string) (prefix []byte, blocks []*flac.MetaDataBlock, audio []byte) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	streamOffset, audioOffset, blocks, err := readFLACBlocks(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data[:streamOffset], blocks, data[audioOffset:]
+}
+
+func flacPadding(blocks []*flac.MetaDataBlock) int {
+	for _, block := range blocks {
+		if block.Type == flac.Padding {
+			return len(block.Data)
+		}
+	}
+	return -1
+}
+
+func sameFile(t *testing.T, before os.FileInfo, path string) bool {
+	t.Helper()
+	after, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return os.SameFile(before, after)
+}
+
+func updateAndParse(t *testing.T, service *AudioService, path string, update *model.TagUpdate) *model.FileMetadata {
+	t.Helper()
+	ctx := context.Background()
+	if err := service.UpdateTags(ctx, path, update); err != nil {
+		t.Fatal(err)
+	}
+	metadata, err := service.ParseFile(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return metadata
+}
+
+func testImage(t *testing.T, size int) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, size, size))); err != nil {
+		t.Fatal(err)
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func pictureTypes(pictures []model.Picture) []int {
+	types := make([]int, len(pictures))
+	for i, picture := range pictures {
+		types[i] = picture.Type
+	}
+	return types
+}
+
+// Updates that fit in the padding are written in place.
+func TestFLACUpdateTagsPadding(t *testing.T) {
+	path := copyTestdata(t, "sample.flac")
+	_, blocks, audio := flacLayout(t, path)
+	oldPadding := flacPadding(blocks)
+	service := NewAudioService(Options{FLACPadding: 1024})
+
+	before, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	title := "A Longer Title Than Before"
+	metadata := updateAndParse(t, service, path, &model.TagUpdate{Title: &title})
+	if metadata.Title != title {
+		t.Errorf("title = %q, want %q", metadata.Title, title)
+	}
+	if !sameFile(t, before, path) {
+		t.Error("file was replaced, want the metadata patched in place")
+	}
+	after, _ := os.Stat(path)
+	if after.Size() != before.Size() {
+		t.Errorf("size = %d, want %d", after.Size(), before.Size())
+	}
+	_, blocks, written := flacLayout(t, path)
+	if got, want := flacPadding(blocks), oldPadding-(len(title)-len("Test Title")); got != want {
+		t.Errorf("padding = %d, want %d", got, want)
+	}
+	if !bytes.Equal(written, audio) {
+		t.Error("audio frames changed by the patch")
+	}
+
+	lyrics := strings.Repeat("la ", 4000)
+	metadata = updateAndParse(t, service, path, &model.TagUpdate{Lyrics: &lyrics})
+	if metadata.Lyrics != lyrics || metadata.Title != title {
+		t.Errorf("lyrics written: %v, title = %q", metadata.Lyrics == lyrics, metadata.Title)
+	}
+	if sameFile(t, before, path) {
+		t.Error("file was patched, want it rewritten")
+	}
+	_, blocks, written = flacLayout(t, path)
+	if got := flacPadding(blocks); got != 1024 {
+		t.Errorf("padding = %d, want the configured 1024", got)
+	}
+	if !bytes.Equal(written, audio) {
+		t.Error("audio frames changed by the rewrite")
+	}
+}
+
+func TestFLACUpdateTagsPictures(t *testing.T) {
+	path := copyTestdata(t, "sample.flac")
+	_, _, audio := flacLayout(t, path)
+	service := NewAudioService(Options{})
+
+	cover := testImage(t, 4)
+	metadata := updateAndParse(t, service, path, &model.TagUpdate{
+		Pictures: []model.PictureUpdate{
+			{Type: model.PictureTypeFrontCover, Data: cover},
+			{Type: model.PictureTypeBackCover, Data: cover},
+			{Type: model.PictureTypeArtist, Data: cover},
+		},
+	})
+	if got := pictureTypes(metadata.Pictures); len(got) != 3 {
+		t.Fatalf("picture types = %v, want front, back and artist", got)
+	}
+
+	back := testImage(t, 8)
+	metadata = updateAndParse(t, service, path, &model.TagUpdate{
+		Pictures: []model.PictureUpdate{
+			{Type: model.PictureTypeBackCover, Description: "back", Data: back},
+			{Type: model.PictureTypeArtist},
+		},
+	})
+	if got := pictureTypes(metadata.Pictures); len(got) != 2 {
+		t.Fatalf("picture types = %v, want front and back", got)
+	}
+	for _, picture := range metadata.Pictures {
+		switch picture.Type {
+		case model.PictureTypeFrontCover:
+			if picture.Width != 4 {
+				t.Errorf("front cover width = %d, want it kept", picture.Width)
+			}
+		case model.PictureTypeBackCover:
+			if picture.Width != 8 || picture.Description != "back" {
+				t.Errorf("back cover = %dpx %q, want it replaced", picture.Width, picture.Description)
+			}
+		default:
+			t.Errorf("picture of type %d left", picture.Type)
+		}
+	}
+
+	// A cover replaces every picture.
+	metadata = updateAndParse(t, service, path, &model.TagUpdate{CoverArt: &back})
+	if got := pictureTypes(metadata.Pictures); len(got) != 1 || got[0] != model.PictureTypeFrontCover {
+		t.Fatalf("picture types = %v, want only the front cover", got)
+	}
+	if metadata.Pictures[0].Width != 8 {
+		t.Errorf("front cover width = %d, want 8", metadata.Pictures[0].Width)
+	}
+
+	metadata = updateAndParse(t, service, path, &model.TagUpdate{Clear: []string{"pictures"}})
+	if len(metadata.Pictures) != 0 || metadata.HasCoverArt {
+		t.Errorf("picture types = %v, want none", pictureTypes(metadata.Pictures))
+	}
+	if _, _, written := flacLayout(t, path); !bytes.Equal(written, audio) {
+		t.Error("audio frames changed")
+	}
+}
+
+func TestFLACUpdateTagsMergePolicy(t *testing.T) {
+	tests := []struct {
+		policy     model.MergePolicy
+		title      string
+		artist     string
+		lyrics     string
+		wantTitle  string
+		wantArtist string
+		wantAlbum  string
+		wantLyrics string
+		wantGenre  string
+	}{
+		{
+			policy: model.MergePreserve, title: "New", artist: "", lyrics: "la",
+			wantTitle: "New", wantArtist: "", wantAlbum: "Test Album", wantLyrics: "la", wantGenre: "Jazz",
+		},
+		{
+			policy: model.MergeClear, title: "New", artist: "New Artist", lyrics: "",
+			wantTitle: "New", wantArtist: "New Artist", wantAlbum: "", wantLyrics: "", wantGenre: "",
+		},
+		{
+			policy: model.MergeOverwriteIfEmpty, title: "New", artist: "New Artist", lyrics: "la",
+			wantTitle: "Test Title", wantArtist: "Test Artist", wantAlbum: "Test Album", wantLyrics: "la",
+			wantGenre: "Jazz",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			path := copyTestdata(t, "sample.flac")
+			service := NewAudioService(Options{})
+			update := &model.TagUpdate{MergePolicy: tt.policy, Title: &tt.title, Artist: &tt.artist}
+			if tt.lyrics != "" {
+				update.Lyrics = &tt.lyrics
+			}
+			metadata := updateAndParse(t, service, path, update)
+			if metadata.Title != tt.wantTitle || metadata.Artist != tt.wantArtist || metadata.Album != tt.wantAlbum {
+				t.Errorf("title, artist, album = %q, %q, %q, want %q, %q, %q",
+					metadata.Title, metadata.Artist, metadata.Album, tt.wantTitle, tt.wantArtist, tt.wantAlbum)
+			}
+			if metadata.Lyrics != tt.wantLyrics || metadata.Genre != tt.wantGenre {
+				t.Errorf("lyrics, genre = %q, %q, want %q, %q",
+					metadata.Lyrics, metadata.Genre, tt.wantLyrics, tt.wantGenre)
+			}
+		})
+	}
+}
+
+func withID3Tag(t *testing.T, path string) []byte {
+	t.Helper()
+	id3Tag := id3v2.NewEmptyTag()
+	id3Tag.SetVersion(4)
+	id3Tag.SetDefaultEncoding(id3v2.EncodingUTF8)
+	id3Tag.SetTitle("ID3 Title")
+	id3Tag.SetArtist("ID3 Artist")
+	var prefix bytes.Buffer
+	if _, err := id3Tag.WriteTo(&prefix); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, append(prefix.Bytes(), data...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return prefix.Bytes()
+}
+
+func TestFLACUpdateTagsID3(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     FLACID3Mode
+		override string
+	}{
+		{name: "keep", mode: FLACID3Keep},
+		{name: "strip", mode: FLACID3Strip},
+		{name: "sync", mode: FLACID3Sync},
+		{name: "strip for one update", mode: FLACID3Keep, override: string(FLACID3Strip)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := copyTestdata(t, "sample.flac")
+			original := withID3Tag(t, path)
+			_, _, audio := flacLayout(t, path)
+			service := NewAudioService(Options{FLACID3: tt.mode})
+
+			title := "New Title"
+			metadata := updateAndParse(t, service, path, &model.TagUpdate{Title: &title, FLACID3: tt.override})
+			if metadata.Title != title || metadata.Artist != "Test Artist" {
+				t.Errorf("title, artist = %q, %q, want the Vorbis comments", metadata.Title, metadata.Artist)
+			}
+
+			prefix, _, written := flacLayout(t, path)
+			if !bytes.Equal(written, audio) {
+				t.Error("audio frames changed")
+			}
+			mode := tt.mode
+			if tt.override != "" {
+				mode = FLACID3Mode(tt.override)
+			}
+			switch mode {
+			case FLACID3Keep:
+				if !bytes.Equal(prefix, original) {
+					t.Error("ID3v2 tag changed, want it kept")
+				}
+			case FLACID3Strip:
+				if len(prefix) != 0 {
+					t.Errorf("ID3v2 tag of %d bytes left, want it stripped", len(prefix))
+				}
+			case FLACID3Sync:
+				id3Tag, err := id3v2.ParseReader(bytes.NewReader(prefix), id3v2.Options{Parse: true})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if id3Tag.Title() != title || id3Tag.Artist() != "Test Artist" || id3Tag.Version() != 4 {
+					t.Errorf("synced ID3v2 tag = v2.%d %q by %q, want v2.4 %q by Test Artist",
+						id3Tag.Version(), id3Tag.Title(), id3Tag.Artist(), title)
+				}
+			}
+		})
+	}
+}
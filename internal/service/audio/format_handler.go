@@ -1,8 +1,40 @@
 package audio
 
+import (
+	"context"
+	"io"
+	"math"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/internal/vfs"
+)
+
 type FormatHandler interface {
-	ExtractDuration(filePath string) (float64, error)
-	UpdateTags(filePath string, title, artist, album *string, year, track *int, genre *string, coverArt *string) error
+	ExtractDuration(ctx context.Context, r io.ReaderAt, size int64) (float64, error)
+	UpdateTags(ctx context.Context, fsys vfs.FS, filePath string, update *model.TagUpdate) error
 	Format() string
+	Capabilities() model.Capabilities
+}
+
+var fullCapabilities = model.Capabilities{
+	Read:          true,
+	Write:         true,
+	CoverArt:      true,
+	Pictures:      true,
+	Lyrics:        true,
+	SyncedLyrics:  true,
+	Chapters:      true,
+	CustomTags:    true,
+	ExactDuration: true,
 }
 
+type audioInfoExtractor interface {
+	ExtractAudioInfo(r io.ReaderAt, size int64, result *model.FileMetadata) error
+}
+
+func averageBitrate(audioBytes int64, duration float64) int {
+	if audioBytes <= 0 || duration <= 0 {
+		return 0
+	}
+	return int(math.Round(float64(audioBytes) * 8 / duration / 1000))
+}
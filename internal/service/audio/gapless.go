@@ -0,0 +1,134 @@
+package audio
+
+import (
+	"encoding/binary"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bogem/id3v2/v2"
+	"github.com/dhowden/tag"
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+)
+
+const iTunSMPB = "iTunSMPB"
+
+func isITunesComment(description string) bool {
+	return strings.HasPrefix(description, "iTun")
+}
+
+func setID3Comment(id3Tag *id3v2.Tag, text string, keepEmpty bool) {
+	frames := id3Tag.GetFrames("COMM")
+	id3Tag.DeleteFrames("COMM")
+	for _, frame := range frames {
+		if comment, ok := frame.(id3v2.CommentFrame); ok && isITunesComment(comment.Description) {
+			id3Tag.AddFrame("COMM", frame)
+		}
+	}
+	if text != "" || keepEmpty {
+		id3Tag.AddCommentFrame(
+			id3v2.CommentFrame{
+				Encoding: id3v2.EncodingUTF8,
+				Language: "eng",
+				Text:     text,
+			},
+		)
+	}
+}
+
+func id3Comments(raw map[string]interface{}) (comment, smpb string, found bool) {
+	keys := make([]string, 0, len(raw))
+	for key := range raw {
+		if strings.HasPrefix(key, "COMM") || strings.HasPrefix(key, "COM_") || key == "COM" {
+			keys = append(keys, key)
+		}
+	}
+	// Repeated frames are named "COMM_1", "COMM_2" and so on after the first.
+	sort.Slice(
+		keys, func(i, j int) bool {
+			if len(keys[i]) != len(keys[j]) {
+				return len(keys[i]) < len(keys[j])
+			}
+			return keys[i] < keys[j]
+		},
+	)
+	for _, key := range keys {
+		comm, ok := raw[key].(*tag.Comm)
+		if !ok {
+			continue
+		}
+		found = true
+		switch {
+		case comm.Description == iTunSMPB:
+			smpb = strings.TrimSpace(comm.Text)
+		case isITunesComment(comm.Description):
+		case comment == "":
+			comment = strings.TrimSpace(comm.Text)
+		}
+	}
+	return comment, smpb, found
+}
+
+func parseITunSMPB(value string) *model.Gapless {
+	fields := strings.Fields(value)
+	if len(fields) < 4 {
+		return nil
+	}
+	var numbers [3]int64
+	for i := range numbers {
+		n, err := strconv.ParseInt(fields[i+1], 16, 64)
+		if err != nil {
+			return nil
+		}
+		numbers[i] = n
+	}
+	return &model.Gapless{
+		Source:       iTunSMPB,
+		EncoderDelay: int(numbers[0]),
+		Padding:      int(numbers[1]),
+		TotalSamples: numbers[2],
+		ITunSMPB:     value,
+	}
+}
+
+func readLAMEHeader(r io.ReaderAt, offset int64, frame mpegFrameHeader) *model.Gapless {
+	buffer := make([]byte, frame.size())
+	n, _ := r.ReadAt(buffer, offset)
+	buffer = buffer[:n]
+
+	pos := frame.xingOffset()
+	if pos+8 > len(buffer) {
+		return nil
+	}
+	if tag := string(buffer[pos : pos+4]); tag != "Xing" && tag != "Info" {
+		return nil
+	}
+	flags := binary.BigEndian.Uint32(buffer[pos+4 : pos+8])
+	pos += 8
+	// Frame count, byte count, seek table and quality, when present.
+	for _, field := range []struct {
+		flag uint32
+		size int
+	}{{0x01, 4}, {0x02, 4}, {0x04, 100}, {0x08, 4}} {
+		if flags&field.flag != 0 {
+			pos += field.size
+		}
+	}
+	if pos+24 > len(buffer) {
+		return nil
+	}
+
+	lame := buffer[pos : pos+24]
+	for _, c := range lame[:4] {
+		if (c < 'A' || c > 'Z') && (c < 'a' || c > 'z') {
+			return nil
+		}
+	}
+	return &model.Gapless{
+		Source:       "lame",
+		Encoder:      strings.TrimRight(string(lame[:9]), "\x00 "),
+		EncoderDelay: int(lame[21])<<4 | int(lame[22])>>4,
+		Padding:      int(lame[22]&0x0F)<<8 | int(lame[23]),
+	}
+}
@@ -0,0 +1,492 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/bogem/id3v2/v2"
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+)
+
+func applyID3Tags(id3Tag *id3v2.Tag, update *model.TagUpdate) {
+	if update.Title != nil {
+		setID3Text(id3Tag, "TIT2", *update.Title, update.KeepsEmpty("title"))
+	}
+	if update.Artist != nil {
+		setID3Text(id3Tag, "TPE1", *update.Artist, update.KeepsEmpty("artist"))
+	}
+	if update.Album != nil {
+		setID3Text(id3Tag, "TALB", *update.Album, update.KeepsEmpty("album"))
+	}
+	existing := id3ReleaseDate(func(id string) string { return id3Tag.GetTextFrame(id).Text })
+	if date, ok := updatedReleaseDate(existing, update); ok {
+		setID3ReleaseDate(id3Tag, date)
+	}
+	if update.Genre != nil {
+		setID3Text(id3Tag, "TCON", *update.Genre, update.KeepsEmpty("genre"))
+	}
+	applyID3ExtendedTags(id3Tag, update)
+}
+
+func applyID3ExtendedTags(id3Tag *id3v2.Tag, update *model.TagUpdate) {
+	if update.AlbumArtist != nil {
+		setID3Text(id3Tag, "TPE2", *update.AlbumArtist, update.KeepsEmpty("albumArtist"))
+	}
+	if update.Composer != nil {
+		setID3Text(id3Tag, "TCOM", *update.Composer, update.KeepsEmpty("composer"))
+	}
+	if update.TitleSort != nil {
+		setID3Text(id3Tag, "TSOT", *update.TitleSort, update.KeepsEmpty("titleSort"))
+	}
+	if update.ArtistSort != nil {
+		setID3Text(id3Tag, "TSOP", *update.ArtistSort, update.KeepsEmpty("artistSort"))
+	}
+	if update.AlbumArtistSort != nil {
+		setID3Text(id3Tag, "TSO2", *update.AlbumArtistSort, update.KeepsEmpty("albumArtistSort"))
+	}
+	if update.AlbumSort != nil {
+		setID3Text(id3Tag, "TSOA", *update.AlbumSort, update.KeepsEmpty("albumSort"))
+	}
+	if update.Comment != nil {
+		setID3Comment(id3Tag, *update.Comment, update.KeepsEmpty("comment"))
+	}
+	if update.BPM != nil {
+		setID3TextFrame(id3Tag, "TBPM", formatPositive(*update.BPM))
+	}
+	if update.ISRC != nil {
+		setID3TextFrame(id3Tag, "TSRC", *update.ISRC)
+	}
+	if update.Label != nil {
+		setID3TextFrame(id3Tag, "TPUB", *update.Label)
+	}
+	if update.Barcode != nil {
+		setID3UserText(id3Tag, "BARCODE", *update.Barcode)
+	}
+	if update.CatalogNumber != nil {
+		setID3UserText(id3Tag, "CATALOGNUMBER", *update.CatalogNumber)
+	}
+	if update.Compilation != nil {
+		value := ""
+		if *update.Compilation {
+			value = "1"
+		}
+		setID3TextFrame(id3Tag, "TCMP", value)
+	}
+	setID3Popularimeter(id3Tag, update.Rating, update.PlayCount)
+	if update.Lyrics != nil {
+		id3Tag.DeleteFrames("USLT")
+		if *update.Lyrics != "" || update.KeepsEmpty("lyrics") {
+			id3Tag.AddUnsynchronisedLyricsFrame(
+				id3v2.UnsynchronisedLyricsFrame{
+					Encoding: id3v2.EncodingUTF8,
+					Language: "eng",
+					Lyrics:   *update.Lyrics,
+				},
+			)
+		}
+	}
+	if update.SyncedLyrics != nil {
+		id3Tag.DeleteFrames("SYLT")
+		if lines, err := parseLRC(*update.SyncedLyrics); err == nil {
+			id3Tag.AddFrame("SYLT", id3v2.UnknownFrame{Body: encodeSYLT(lines, syltEncodingUTF8)})
+		}
+	}
+	if update.Chapters != nil {
+		setID3Chapters(id3Tag, *update.Chapters)
+	}
+	for _, key := range sortedCustomTagKeys(update.CustomTags) {
+		setID3CustomTag(id3Tag, key, update.CustomTags[key])
+	}
+	if update.Track != nil || update.TotalTracks != nil {
+		number, total := splitNumberPair(id3Tag.GetTextFrame("TRCK").Text)
+		if update.Track != nil {
+			number = *update.Track
+		}
+		if update.TotalTracks != nil {
+			total = *update.TotalTracks
+		}
+		setID3TextFrame(id3Tag, "TRCK", formatNumberPair(number, total))
+	}
+	if update.Disc != nil || update.TotalDiscs != nil {
+		number, total := splitNumberPair(id3Tag.GetTextFrame("TPOS").Text)
+		if update.Disc != nil {
+			number = *update.Disc
+		}
+		if update.TotalDiscs != nil {
+			total = *update.TotalDiscs
+		}
+		setID3TextFrame(id3Tag, "TPOS", formatNumberPair(number, total))
+	}
+}
+
+func setID3Cover(id3Tag *id3v2.Tag, dataURI string) error {
+	coverData, mimeType, err := decodeCover(dataURI)
+	if err != nil {
+		return fmt.Errorf("failed to parse cover art data: %w", err)
+	}
+	id3Tag.DeleteFrames("APIC")
+	id3Tag.AddAttachedPicture(
+		id3v2.PictureFrame{
+			Encoding:    id3v2.EncodingUTF8,
+			MimeType:    normalizeMimeType(mimeType),
+			PictureType: id3v2.PTFrontCover,
+			Description: "Front Cover",
+			Picture:     coverData,
+		},
+	)
+	return nil
+}
+
+func extractID3ExtendedMetadata(id3Tag *id3v2.Tag, result *model.FileMetadata) {
+	result.ReleaseDate = id3ReleaseDate(func(id string) string { return id3Tag.GetTextFrame(id).Text })
+	if value := id3Tag.GetTextFrame("TPE2").Text; value != "" {
+		result.AlbumArtist = value
+	}
+	if value := id3Tag.GetTextFrame("TCOM").Text; value != "" {
+		result.Composer = value
+	}
+	result.TitleSort = id3Tag.GetTextFrame("TSOT").Text
+	result.ArtistSort = id3Tag.GetTextFrame("TSOP").Text
+	result.AlbumArtistSort = id3Tag.GetTextFrame("TSO2").Text
+	result.AlbumSort = id3Tag.GetTextFrame("TSOA").Text
+	result.ISRC = id3Tag.GetTextFrame("TSRC").Text
+	result.Label = id3Tag.GetTextFrame("TPUB").Text
+	for _, frame := range id3Tag.GetFrames("COMM") {
+		if comment, ok := frame.(id3v2.CommentFrame); ok && comment.Text != "" && !isITunesComment(comment.Description) {
+			result.Comment = comment.Text
+			break
+		}
+	}
+	for _, frame := range id3Tag.GetFrames("USLT") {
+		if lyrics, ok := frame.(id3v2.UnsynchronisedLyricsFrame); ok && lyrics.Lyrics != "" {
+			result.Lyrics = lyrics.Lyrics
+			break
+		}
+	}
+	for _, frame := range id3Tag.GetFrames("SYLT") {
+		if unknown, ok := frame.(id3v2.UnknownFrame); ok {
+			if lrc, err := decodeSYLT(unknown.Body); err == nil {
+				result.SyncedLyrics = lrc
+				break
+			}
+		}
+	}
+	if bpm := parseLeadingInt(id3Tag.GetTextFrame("TBPM").Text); bpm > 0 {
+		result.BPM = bpm
+	}
+	if id3Tag.GetTextFrame("TCMP").Text == "1" {
+		result.Compilation = true
+	}
+	extractID3Popularimeter(id3Tag, result)
+	extractID3CustomTags(id3Tag, result)
+	if _, total := splitNumberPair(id3Tag.GetTextFrame("TRCK").Text); total > 0 {
+		result.TotalTracks = total
+	}
+	if _, total := splitNumberPair(id3Tag.GetTextFrame("TPOS").Text); total > 0 {
+		result.TotalDiscs = total
+	}
+}
+
+func extractID3ChunkMetadata(data []byte, result *model.FileMetadata) {
+	id3Tag, err := id3v2.ParseReader(bytes.NewReader(data), id3v2.Options{Parse: true})
+	if err != nil {
+		return
+	}
+	if id3Tag.Title() != "" {
+		result.Title = id3Tag.Title()
+	}
+	if id3Tag.Artist() != "" {
+		result.Artist = id3Tag.Artist()
+	}
+	if id3Tag.Album() != "" {
+		result.Album = id3Tag.Album()
+	}
+	if year := parseLeadingInt(id3Tag.Year()); year > 0 {
+		result.Year = year
+	}
+	if id3Tag.Genre() != "" {
+		result.Genre = id3Tag.Genre()
+	}
+	if track := parseLeadingInt(id3Tag.GetTextFrame("TRCK").Text); track > 0 {
+		result.Track = track
+	}
+	if disc := parseLeadingInt(id3Tag.GetTextFrame("TPOS").Text); disc > 0 {
+		result.Disc = disc
+	}
+	extractID3ExtendedMetadata(id3Tag, result)
+	result.Chapters = readID3Chapters(bytes.NewReader(data), int64(len(data)))
+	for _, frame := range id3Tag.GetFrames("APIC") {
+		picture, ok := frame.(id3v2.PictureFrame)
+		if !ok || len(picture.Picture) == 0 {
+			continue
+		}
+		mimeType := picture.MimeType
+		if mimeType == "" {
+			mimeType = "image/jpeg"
+		}
+		base64Data := base64.StdEncoding.EncodeToString(picture.Picture)
+		result.CoverArt = fmt.Sprintf("data:%s;base64,%s", mimeType, base64Data)
+		break
+	}
+	result.Pictures = extractID3Pictures(id3Tag)
+}
+
+func buildID3Chunk(existing []byte, update *model.TagUpdate) ([]byte, error) {
+	id3Tag := id3v2.NewEmptyTag()
+	if len(existing) > 0 {
+		parsed, err := id3v2.ParseReader(bytes.NewReader(existing), id3v2.Options{Parse: true})
+		if err == nil {
+			id3Tag = parsed
+		}
+		update = keepID3Chapters(update, bytes.NewReader(existing), int64(len(existing)))
+	}
+
+	applyID3Tags(id3Tag, update)
+
+	if update.CoverArt != nil && *update.CoverArt != "" {
+		if err := setID3Cover(id3Tag, *update.CoverArt); err != nil {
+			return nil, err
+		}
+	}
+	if err := applyID3Pictures(id3Tag, update.Pictures); err != nil {
+		return nil, err
+	}
+
+	fixID3Encodings(id3Tag)
+	var buf bytes.Buffer
+	if _, err := id3Tag.WriteTo(&buf); err != nil {
+		return nil, fmt.Errorf("failed to write ID3v2 tag: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func setID3TextFrame(id3Tag *id3v2.Tag, id, value string) {
+	setID3Text(id3Tag, id, value, false)
+}
+
+func setID3Text(id3Tag *id3v2.Tag, id, value string, keepEmpty bool) {
+	id3Tag.DeleteFrames(id)
+	if value != "" || keepEmpty {
+		id3Tag.AddTextFrame(id, id3v2.EncodingUTF8, value)
+	}
+}
+
+func splitNumberPair(value string) (int, int) {
+	number, total, _ := strings.Cut(value, "/")
+	return parseLeadingInt(number), parseLeadingInt(total)
+}
+
+func formatNumberPair(number, total int) string {
+	if total > 0 {
+		return strconv.Itoa(number) + "/" + strconv.Itoa(total)
+	}
+	return formatPositive(number)
+}
+
+func formatPositive(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+func setID3Version(id3Tag *id3v2.Tag, version byte) {
+	if version == 0 || version == id3Tag.Version() {
+		return
+	}
+	id3Tag.SetVersion(version)
+
+	if version == 3 {
+		date := id3Tag.GetTextFrame("TDRC").Text
+		id3Tag.DeleteFrames("TDRC")
+		if len(date) >= 4 {
+			setID3TextFrame(id3Tag, "TYER", date[:4])
+		}
+		if len(date) >= 10 {
+			setID3TextFrame(id3Tag, "TDAT", date[8:10]+date[5:7])
+		}
+		if original := id3Tag.GetTextFrame("TDOR").Text; len(original) >= 4 {
+			setID3TextFrame(id3Tag, "TORY", original[:4])
+		}
+		id3Tag.DeleteFrames("TDOR")
+		return
+	}
+
+	date := id3Tag.GetTextFrame("TYER").Text
+	if day := id3Tag.GetTextFrame("TDAT").Text; date != "" && len(day) == 4 {
+		date += "-" + day[2:4] + "-" + day[0:2]
+	}
+	if date != "" {
+		setID3TextFrame(id3Tag, "TDRC", date)
+	}
+	if original := id3Tag.GetTextFrame("TORY").Text; original != "" {
+		setID3TextFrame(id3Tag, "TDOR", original)
+	}
+	for _, id := range []string{"TYER", "TDAT", "TIME", "TORY"} {
+		id3Tag.DeleteFrames(id)
+	}
+}
+
+func fixID3Encodings(id3Tag *id3v2.Tag) {
+	for id, frames := range id3Tag.AllFrames() {
+		converted := make([]id3v2.Framer, 0, len(frames))
+		changed := false
+		for _, frame := range frames {
+			fixed, ok := fixID3FrameEncoding(id, frame, id3Tag.Version())
+			if !ok || fixed != nil {
+				changed = true
+			}
+			if !ok {
+				continue
+			}
+			if fixed == nil {
+				fixed = frame
+			}
+			converted = append(converted, fixed)
+		}
+		if !changed {
+			continue
+		}
+		id3Tag.DeleteFrames(id)
+		for _, frame := range converted {
+			id3Tag.AddFrame(id, frame)
+		}
+	}
+}
+
+func fixID3FrameEncoding(id string, frame id3v2.Framer, version byte) (id3v2.Framer, bool) {
+	if version != 3 {
+		switch f := frame.(type) {
+		case id3v2.TextFrame:
+			if isID3UTF16(f.Encoding) {
+				f.Encoding = id3v2.EncodingUTF8
+				return f, true
+			}
+		case id3v2.CommentFrame:
+			if isID3UTF16(f.Encoding) {
+				f.Encoding = id3v2.EncodingUTF8
+				return f, true
+			}
+		case id3v2.UserDefinedTextFrame:
+			if isID3UTF16(f.Encoding) {
+				f.Encoding = id3v2.EncodingUTF8
+				return f, true
+			}
+		case id3v2.UnsynchronisedLyricsFrame:
+			if isID3UTF16(f.Encoding) {
+				f.Encoding = id3v2.EncodingUTF8
+				return f, true
+			}
+		case id3v2.PictureFrame:
+			if isID3UTF16(f.Encoding) {
+				f.Encoding = id3v2.EncodingUTF8
+				return f, true
+			}
+		}
+		return nil, true
+	}
+
+	switch f := frame.(type) {
+	case id3v2.TextFrame:
+		if f.Text == "" {
+			return nil, false
+		}
+		if f.Encoding.Equals(id3v2.EncodingISO) {
+			return nil, true
+		}
+		if isLatin1(f.Text) {
+			f.Encoding = id3v2.EncodingISO
+			return f, true
+		}
+		return id3v2.UnknownFrame{Body: append([]byte{1}, encodeID3UTF16(f.Text)...)}, true
+	case id3v2.UserDefinedTextFrame:
+		if f.Encoding.Equals(id3v2.EncodingISO) {
+			return nil, true
+		}
+		if isLatin1(f.Description) && isLatin1(f.Value) {
+			f.Encoding = id3v2.EncodingISO
+			return f, true
+		}
+		body := append([]byte{1}, encodeID3UTF16(f.Description)...)
+		body = append(body, 0, 0)
+		return id3v2.UnknownFrame{Body: append(body, encodeID3UTF16(f.Value)...)}, true
+	case id3v2.CommentFrame:
+		if f.Encoding.Equals(id3v2.EncodingISO) {
+			return nil, true
+		}
+		if isLatin1(f.Description) && isLatin1(f.Text) {
+			f.Encoding = id3v2.EncodingISO
+			return f, true
+		}
+		return id3v2.UnknownFrame{Body: encodeID3LanguageText(f.Language, f.Description, f.Text)}, true
+	case id3v2.UnsynchronisedLyricsFrame:
+		if f.Encoding.Equals(id3v2.EncodingISO) {
+			return nil, true
+		}
+		if isLatin1(f.ContentDescriptor) && isLatin1(f.Lyrics) {
+			f.Encoding = id3v2.EncodingISO
+			return f, true
+		}
+		return id3v2.UnknownFrame{Body: encodeID3LanguageText(f.Language, f.ContentDescriptor, f.Lyrics)}, true
+	case id3v2.PictureFrame:
+		if f.Encoding.Equals(id3v2.EncodingISO) {
+			return nil, true
+		}
+		if isLatin1(f.Description) {
+			f.Encoding = id3v2.EncodingISO
+			return f, true
+		}
+		body := append([]byte{1}, f.MimeType...)
+		body = append(body, 0, f.PictureType)
+		body = append(body, encodeID3UTF16(f.Description)...)
+		body = append(body, 0, 0)
+		return id3v2.UnknownFrame{Body: append(body, f.Picture...)}, true
+	case id3v2.UnknownFrame:
+		if id == "SYLT" && len(f.Body) > 0 && f.Body[0] == syltEncodingUTF8 {
+			if lrc, err := decodeSYLT(f.Body); err == nil {
+				if lines, err := parseLRC(lrc); err == nil {
+					f.Body = encodeSYLT(lines, syltEncodingUTF16)
+					return f, true
+				}
+			}
+		}
+	}
+	return nil, true
+}
+
+func isID3UTF16(encoding id3v2.Encoding) bool {
+	return encoding.Equals(id3v2.EncodingUTF16) || encoding.Equals(id3v2.EncodingUTF16BE)
+}
+
+func isLatin1(value string) bool {
+	for _, r := range value {
+		if r > 0xFF {
+			return false
+		}
+	}
+	return true
+}
+
+func encodeID3UTF16(text string) []byte {
+	units := utf16.Encode([]rune(text))
+	buf := make([]byte, 2, 2+2*len(units))
+	buf[0], buf[1] = 0xFF, 0xFE
+	for _, unit := range units {
+		buf = append(buf, byte(unit), byte(unit>>8))
+	}
+	return buf
+}
+
+func encodeID3LanguageText(language, description, text string) []byte {
+	if len(language) != 3 {
+		language = "eng"
+	}
+	body := append([]byte{1}, language...)
+	body = append(body, encodeID3UTF16(description)...)
+	body = append(body, 0, 0)
+	return append(body, encodeID3UTF16(text)...)
+}
@@ -0,0 +1,149 @@
+package audio
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bogem/id3v2/v2"
+	"github.com/iamvkosarev/audio-tag-editor/internal/service/genres"
+)
+
+const id3v1Size = 128
+
+// ID3v1Mode controls what happens to a trailing ID3v1 tag of an MP3 file.
+type ID3v1Mode string
+
+const (
+	ID3v1Keep  ID3v1Mode = "keep"  // leave the tag as it is
+	ID3v1Strip ID3v1Mode = "strip" // remove the tag
+	ID3v1Sync  ID3v1Mode = "sync"  // update an existing tag to match the ID3v2 tag
+)
+
+// ID3Options selects the tag layout of written MP3 files.
+type ID3Options struct {
+	Version byte
+	V1      ID3v1Mode
+}
+
+func NewID3Options(version int, v1 string) (ID3Options, error) {
+	if version != 0 && version != 3 && version != 4 {
+		return ID3Options{}, fmt.Errorf("unsupported ID3v2 version: %d", version)
+	}
+	mode := ID3v1Mode(strings.ToLower(v1))
+	switch mode {
+	case "":
+		mode = ID3v1Keep
+	case ID3v1Keep, ID3v1Strip, ID3v1Sync:
+	default:
+		return ID3Options{}, fmt.Errorf("unsupported ID3v1 mode: %s", v1)
+	}
+	return ID3Options{Version: byte(version), V1: mode}, nil
+}
+
+func buildID3v1(id3Tag *id3v2.Tag) []byte {
+	buf := make([]byte, id3v1Size)
+	copy(buf, "TAG")
+	putID3v1Text(buf[3:33], id3Tag.Title())
+	putID3v1Text(buf[33:63], id3Tag.Artist())
+	putID3v1Text(buf[63:93], id3Tag.Album())
+	putID3v1Text(buf[93:97], id3Tag.Year())
+
+	comment := ""
+	for _, frame := range id3Tag.GetFrames("COMM") {
+		if commentFrame, ok := frame.(id3v2.CommentFrame); ok && commentFrame.Text != "" && !isITunesComment(commentFrame.Description) {
+			comment = commentFrame.Text
+			break
+		}
+	}
+
+	track, _ := splitNumberPair(id3Tag.GetTextFrame("TRCK").Text)
+	if track > 0 && track < 256 {
+		putID3v1Text(buf[97:125], comment)
+		buf[126] = byte(track)
+	} else {
+		putID3v1Text(buf[97:127], comment)
+	}
+
+	buf[127] = id3v1GenreIndex(id3Tag.Genre())
+	return buf
+}
+
+func seedFromID3v1(id3Tag *id3v2.Tag, v1 []byte) {
+	encoding := id3Tag.DefaultEncoding()
+	if title := id3v1Text(v1[3:33]); title != "" {
+		id3Tag.SetTitle(title)
+	}
+	if artist := id3v1Text(v1[33:63]); artist != "" {
+		id3Tag.SetArtist(artist)
+	}
+	if album := id3v1Text(v1[63:93]); album != "" {
+		id3Tag.SetAlbum(album)
+	}
+	if year := id3v1Text(v1[93:97]); year != "" {
+		id3Tag.SetYear(year)
+	}
+
+	// ID3v1.1 keeps the track number in the last byte of the comment.
+	comment := v1[97:127]
+	if v1[125] == 0 && v1[126] != 0 {
+		comment = v1[97:125]
+		id3Tag.AddTextFrame("TRCK", encoding, strconv.Itoa(int(v1[126])))
+	}
+	if text := id3v1Text(comment); text != "" {
+		id3Tag.AddCommentFrame(id3v2.CommentFrame{Encoding: encoding, Language: "eng", Text: text})
+	}
+
+	if index := int(v1[127]); index < len(genres.ID3v1) {
+		id3Tag.SetGenre(genres.ID3v1[index])
+	}
+}
+
+func id3v1Text(field []byte) string {
+	if end := bytes.IndexByte(field, 0); end >= 0 {
+		field = field[:end]
+	}
+	runes := make([]rune, len(field))
+	for i, b := range field {
+		runes[i] = rune(b)
+	}
+	return strings.TrimSpace(string(runes))
+}
+
+func putID3v1Text(field []byte, value string) {
+	i := 0
+	for _, r := range value {
+		if i == len(field) {
+			break
+		}
+		if r > 0xFF {
+			r = '?'
+		}
+		field[i] = byte(r)
+		i++
+	}
+}
+
+func id3v1GenreIndex(genre string) byte {
+	genre = strings.TrimSpace(genre)
+	if strings.HasPrefix(genre, "(") {
+		if end := strings.Index(genre, ")"); end > 0 {
+			if index, err := strconv.Atoi(genre[1:end]); err == nil && index >= 0 && index < len(genres.ID3v1) {
+				return byte(index)
+			}
+			genre = genre[end+1:]
+		}
+	}
+	if index, err := strconv.Atoi(genre); err == nil && index >= 0 && index < len(genres.ID3v1) {
+		return byte(index)
+	}
+	// Compared by canonical name, so "Psychedelic" finds "Psychadelic".
+	name := genres.Canonical(genre)
+	for i, candidate := range genres.ID3v1 {
+		if strings.EqualFold(candidate, genre) || name != "" && genres.Canonical(candidate) == name {
+			return byte(i)
+		}
+	}
+	return 0xFF
+}
@@ -0,0 +1,123 @@
+package audio
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+)
+
+func copyTestdata(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func readID3v1(t *testing.T, path string) []byte {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, v1Tag, err := findID3v1Tags(file, 0, stat.Size())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v1Tag
+}
+
+// A file with only an ID3v1 tag keeps the fields an update leaves alone.
+func TestUpdateTagsKeepsID3v1Fields(t *testing.T) {
+	for _, mode := range []ID3v1Mode{ID3v1Keep, ID3v1Sync, ID3v1Strip} {
+		t.Run(string(mode), func(t *testing.T) {
+			ctx := context.Background()
+			path := copyTestdata(t, "sample.id3v11.mp3")
+			service := NewAudioService(Options{ID3: ID3Options{V1: mode}})
+
+			title := "New Title"
+			if err := service.UpdateTags(ctx, path, &model.TagUpdate{Title: &title}); err != nil {
+				t.Fatal(err)
+			}
+
+			metadata, err := service.ParseFile(ctx, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if metadata.Title != title {
+				t.Errorf("title = %q, want %q", metadata.Title, title)
+			}
+			if metadata.Artist != "Test Artist" || metadata.Album != "Test Album" || metadata.Year != 2000 {
+				t.Errorf("artist, album, year = %q, %q, %d, want the ID3v1 values",
+					metadata.Artist, metadata.Album, metadata.Year)
+			}
+			if metadata.Genre != "Jazz" || metadata.Comment != "Test Comment" || metadata.Track != 3 {
+				t.Errorf("genre, comment, track = %q, %q, %d, want the ID3v1 values",
+					metadata.Genre, metadata.Comment, metadata.Track)
+			}
+
+			v1Tag := readID3v1(t, path)
+			switch mode {
+			case ID3v1Strip:
+				if v1Tag != nil {
+					t.Error("ID3v1 tag not stripped")
+				}
+			case ID3v1Keep:
+				if id3v1Text(v1Tag[3:33]) != "Test Title" {
+					t.Errorf("kept ID3v1 title = %q", id3v1Text(v1Tag[3:33]))
+				}
+			case ID3v1Sync:
+				if got := id3v1Text(v1Tag[3:33]); got != title {
+					t.Errorf("synced ID3v1 title = %q, want %q", got, title)
+				}
+				if got := id3v1Text(v1Tag[33:63]); got != "Test Artist" {
+					t.Errorf("synced ID3v1 artist = %q", got)
+				}
+				if got := id3v1Text(v1Tag[63:93]); got != "Test Album" {
+					t.Errorf("synced ID3v1 album = %q", got)
+				}
+				if got := id3v1Text(v1Tag[93:97]); got != "2000" {
+					t.Errorf("synced ID3v1 year = %q", got)
+				}
+				if v1Tag[126] != 3 || v1Tag[127] != 8 {
+					t.Errorf("synced ID3v1 track, genre = %d, %d, want 3, 8", v1Tag[126], v1Tag[127])
+				}
+			}
+		})
+	}
+}
+
+// Fields an update clears stay cleared; the ID3v1 tag does not bring them back.
+func TestUpdateTagsClearsID3v1Field(t *testing.T) {
+	ctx := context.Background()
+	path := copyTestdata(t, "sample.id3v11.mp3")
+	service := NewAudioService(Options{ID3: ID3Options{V1: ID3v1Sync}})
+
+	empty := ""
+	if err := service.UpdateTags(ctx, path, &model.TagUpdate{Album: &empty}); err != nil {
+		t.Fatal(err)
+	}
+	metadata, err := service.ParseFile(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.Album != "" || metadata.Artist != "Test Artist" {
+		t.Errorf("album, artist = %q, %q, want empty and kept", metadata.Album, metadata.Artist)
+	}
+	if got := id3v1Text(readID3v1(t, path)[63:93]); got != "" {
+		t.Errorf("synced ID3v1 album = %q, want empty", got)
+	}
+}
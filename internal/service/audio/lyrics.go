@@ -0,0 +1,171 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	syltEncodingLatin1  = 0
+	syltEncodingUTF16   = 1
+	syltEncodingUTF16BE = 2
+	syltEncodingUTF8    = 3
+
+	syltTimestampMilliseconds = 2
+	syltContentLyrics         = 1
+)
+
+type syncedLyricLine struct {
+	milliseconds uint32
+	text         string
+}
+
+var lrcTimestamp = regexp.MustCompile(`^\[(\d+):(\d{1,2})(?:[.:](\d{1,3}))?\]`)
+
+func parseLRC(lrc string) ([]syncedLyricLine, error) {
+	var lines []syncedLyricLine
+	for _, raw := range strings.Split(strings.ReplaceAll(lrc, "\r\n", "\n"), "\n") {
+		rest := strings.TrimSpace(raw)
+		var stamps []uint32
+		for {
+			match := lrcTimestamp.FindStringSubmatch(rest)
+			if match == nil {
+				break
+			}
+			minutes, _ := strconv.Atoi(match[1])
+			seconds, _ := strconv.Atoi(match[2])
+			fraction := 0
+			if match[3] != "" {
+				fraction, _ = strconv.Atoi((match[3] + "00")[:3])
+			}
+			stamps = append(stamps, uint32((minutes*60+seconds)*1000+fraction))
+			rest = rest[len(match[0]):]
+		}
+		for _, stamp := range stamps {
+			lines = append(lines, syncedLyricLine{milliseconds: stamp, text: strings.TrimSpace(rest)})
+		}
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("synced lyrics contain no timestamped lines")
+	}
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].milliseconds < lines[j].milliseconds })
+	return lines, nil
+}
+
+func formatLRC(lines []syncedLyricLine) string {
+	var b strings.Builder
+	for _, line := range lines {
+		ms := line.milliseconds
+		fmt.Fprintf(&b, "[%02d:%02d.%02d]%s\n", ms/60000, ms/1000%60, ms%1000/10, line.text)
+	}
+	return b.String()
+}
+
+func encodeSYLT(lines []syncedLyricLine, encoding byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte(encoding)
+	buf.WriteString("eng")
+	buf.WriteByte(syltTimestampMilliseconds)
+	buf.WriteByte(syltContentLyrics)
+	writeSYLTString(&buf, "", encoding)
+	stamp := make([]byte, 4)
+	for _, line := range lines {
+		writeSYLTString(&buf, line.text, encoding)
+		binary.BigEndian.PutUint32(stamp, line.milliseconds)
+		buf.Write(stamp)
+	}
+	return buf.Bytes()
+}
+
+func writeSYLTString(buf *bytes.Buffer, text string, encoding byte) {
+	if encoding != syltEncodingUTF16 {
+		buf.WriteString(text)
+		buf.WriteByte(0)
+		return
+	}
+	buf.Write([]byte{0xFF, 0xFE})
+	for _, unit := range utf16.Encode([]rune(text)) {
+		buf.Write([]byte{byte(unit), byte(unit >> 8)})
+	}
+	buf.Write([]byte{0, 0})
+}
+
+func decodeSYLT(body []byte) (string, error) {
+	if len(body) < 6 {
+		return "", fmt.Errorf("SYLT frame too short")
+	}
+	encoding := body[0]
+	if body[4] != syltTimestampMilliseconds {
+		return "", fmt.Errorf("unsupported SYLT timestamp format: %d", body[4])
+	}
+
+	_, rest, err := readSYLTString(body[6:], encoding)
+	if err != nil {
+		return "", err
+	}
+
+	var lines []syncedLyricLine
+	for len(rest) > 0 {
+		var text string
+		text, rest, err = readSYLTString(rest, encoding)
+		if err != nil {
+			return "", err
+		}
+		if len(rest) < 4 {
+			return "", fmt.Errorf("SYLT frame truncated")
+		}
+		lines = append(lines, syncedLyricLine{milliseconds: binary.BigEndian.Uint32(rest[:4]), text: strings.TrimSpace(text)})
+		rest = rest[4:]
+	}
+	return formatLRC(lines), nil
+}
+
+func readSYLTString(data []byte, encoding byte) (string, []byte, error) {
+	if encoding == syltEncodingUTF16 || encoding == syltEncodingUTF16BE {
+		for i := 0; i+1 < len(data); i += 2 {
+			if data[i] == 0 && data[i+1] == 0 {
+				return decodeUTF16(data[:i], encoding == syltEncodingUTF16BE), data[i+2:], nil
+			}
+		}
+		return "", nil, fmt.Errorf("unterminated SYLT string")
+	}
+
+	end := bytes.IndexByte(data, 0)
+	if end < 0 {
+		return "", nil, fmt.Errorf("unterminated SYLT string")
+	}
+	if encoding == syltEncodingLatin1 {
+		runes := make([]rune, end)
+		for i, b := range data[:end] {
+			runes[i] = rune(b)
+		}
+		return string(runes), data[end+1:], nil
+	}
+	return string(data[:end]), data[end+1:], nil
+}
+
+func decodeUTF16(data []byte, bigEndian bool) string {
+	if len(data) >= 2 {
+		switch {
+		case data[0] == 0xFE && data[1] == 0xFF:
+			bigEndian, data = true, data[2:]
+		case data[0] == 0xFF && data[1] == 0xFE:
+			bigEndian, data = false, data[2:]
+		}
+	}
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		if bigEndian {
+			units[i] = binary.BigEndian.Uint16(data[2*i:])
+		} else {
+			units[i] = binary.LittleEndian.Uint16(data[2*i:])
+		}
+	}
+	return string(utf16.Decode(units))
+}
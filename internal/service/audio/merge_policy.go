@@ -0,0 +1,136 @@
+package audio
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+)
+
+func (s *AudioService) applyMergePolicy(update model.TagUpdate, current *model.FileMetadata) model.TagUpdate {
+	policy, _ := model.ParseMergePolicy(string(update.MergePolicy))
+	if policy == model.MergePreserve {
+		return update
+	}
+	update.Clear = slices.Clone(update.Clear)
+
+	mergeField(&update, "title", &update.Title, current.Title)
+	mergeField(&update, "artist", &update.Artist, current.Artist)
+	mergeField(&update, "album", &update.Album, current.Album)
+	mergeField(&update, "albumArtist", &update.AlbumArtist, current.AlbumArtist)
+	mergeField(&update, "composer", &update.Composer, current.Composer)
+	mergeField(&update, "comment", &update.Comment, current.Comment)
+	mergeField(&update, "year", &update.Year, current.Year)
+	mergeField(&update, "releaseDate", &update.ReleaseDate, current.ReleaseDate)
+	mergeField(&update, "titleSort", &update.TitleSort, current.TitleSort)
+	mergeField(&update, "artistSort", &update.ArtistSort, current.ArtistSort)
+	mergeField(&update, "albumArtistSort", &update.AlbumArtistSort, current.AlbumArtistSort)
+	mergeField(&update, "albumSort", &update.AlbumSort, current.AlbumSort)
+	mergeField(&update, "genre", &update.Genre, current.Genre)
+	mergeField(&update, "track", &update.Track, current.Track)
+	mergeField(&update, "totalTracks", &update.TotalTracks, current.TotalTracks)
+	mergeField(&update, "disc", &update.Disc, current.Disc)
+	mergeField(&update, "totalDiscs", &update.TotalDiscs, current.TotalDiscs)
+	mergeField(&update, "bpm", &update.BPM, current.BPM)
+	mergeField(&update, "compilation", &update.Compilation, current.Compilation)
+	mergeField(&update, "lyrics", &update.Lyrics, current.Lyrics)
+	mergeField(&update, "syncedLyrics", &update.SyncedLyrics, current.SyncedLyrics)
+	mergeField(&update, "isrc", &update.ISRC, current.ISRC)
+	mergeField(&update, "barcode", &update.Barcode, current.Barcode)
+	mergeField(&update, "catalogNumber", &update.CatalogNumber, current.CatalogNumber)
+	mergeField(&update, "label", &update.Label, current.Label)
+	mergeField(&update, "rating", &update.Rating, current.Rating)
+	mergeField(&update, "playCount", &update.PlayCount, current.PlayCount)
+
+	hasChapters := len(current.Chapters) > 0
+	switch {
+	case policy == model.MergeClear && update.Chapters == nil && hasChapters:
+		update.Chapters = &[]model.Chapter{}
+	case policy == model.MergeOverwriteIfEmpty && hasChapters && update.Chapters != nil && len(*update.Chapters) > 0:
+		update.Chapters = nil
+	}
+
+	update.CustomTags = s.mergeCustomTags(policy, update.CustomTags, current.CustomTags)
+	update.CoverArt, update.Pictures = mergePictures(policy, update.CoverArt, update.Pictures, current.Pictures)
+	return update
+}
+
+func mergeField[T comparable](update *model.TagUpdate, name string, field **T, current T) {
+	var zero T
+	if current == zero {
+		return
+	}
+	switch {
+	case update.MergePolicy == model.MergeClear && *field == nil:
+		*field = &zero
+		update.Clear = append(update.Clear, name)
+	case update.MergePolicy == model.MergeOverwriteIfEmpty && *field != nil && **field != zero:
+		*field = nil
+	}
+}
+
+func (s *AudioService) mergeCustomTags(policy model.MergePolicy, update, current map[string]string) map[string]string {
+	existing := make(map[string]bool, len(current))
+	for key, value := range current {
+		if value != "" {
+			existing[strings.ToUpper(key)] = true
+		}
+	}
+	set := make(map[string]bool, len(update))
+	merged := make(map[string]string, len(update)+len(current))
+	for key, value := range update {
+		set[strings.ToUpper(key)] = true
+		if policy == model.MergeOverwriteIfEmpty && existing[strings.ToUpper(key)] {
+			continue
+		}
+		merged[key] = value
+	}
+	if policy == model.MergeClear {
+		for key := range current {
+			if existing[strings.ToUpper(key)] && !set[strings.ToUpper(key)] && s.customTagPolicy.allows(key) {
+				merged[key] = ""
+			}
+		}
+	}
+	if len(merged) == 0 {
+		return nil
+	}
+	return merged
+}
+
+func mergePictures(
+	policy model.MergePolicy, coverArt *string, pictures []model.PictureUpdate, current []model.Picture,
+) (*string, []model.PictureUpdate) {
+	has := make(map[int]bool, len(current))
+	for _, picture := range current {
+		has[picture.Type] = true
+	}
+
+	if policy == model.MergeOverwriteIfEmpty {
+		var kept []model.PictureUpdate
+		if coverArt != nil && *coverArt != "" && !has[model.PictureTypeFrontCover] {
+			kept = append(kept, model.PictureUpdate{Type: model.PictureTypeFrontCover, Data: *coverArt})
+		}
+		for _, picture := range pictures {
+			if !has[picture.Type] {
+				kept = append(kept, picture)
+			}
+		}
+		return nil, kept
+	}
+
+	if coverArt != nil && *coverArt != "" {
+		return coverArt, pictures
+	}
+	touched := make(map[int]bool, len(pictures))
+	for _, picture := range pictures {
+		touched[picture.Type] = true
+	}
+	for _, picture := range current {
+		if !touched[picture.Type] {
+			touched[picture.Type] = true
+			pictures = append(pictures, model.PictureUpdate{Type: picture.Type})
+		}
+	}
+	return coverArt, pictures
+}
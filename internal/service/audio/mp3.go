@@ -1,18 +1,23 @@
 package audio
 
 import (
-	"encoding/base64"
-	"errors"
+	"context"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"strings"
 
 	"github.com/bogem/id3v2/v2"
 	"github.com/dhowden/tag"
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/internal/vfs"
 )
 
-type mp3Handler struct{}
+type mp3Handler struct {
+	id3           ID3Options
+	exactDuration bool
+}
 
 func newMP3Handler() *mp3Handler {
 	return &mp3Handler{}
@@ -22,387 +27,277 @@ func (h *mp3Handler) Format() string {
 	return "MP3"
 }
 
-func (h *mp3Handler) ExtractDuration(filePath string) (float64, error) {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return 0, fmt.Errorf("failed to open MP3 file: %w", err)
-	}
-	defer file.Close()
+func (h *mp3Handler) Capabilities() model.Capabilities {
+	capabilities := fullCapabilities
+	capabilities.ExactDuration = h.exactDuration
+	return capabilities
+}
 
-	stat, err := file.Stat()
+func (h *mp3Handler) ExtractDuration(ctx context.Context, r io.ReaderAt, fileSize int64) (float64, error) {
+	start, end, err := mp3AudioRange(r, fileSize)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get MP3 file stats: %w", err)
+		return 0, err
 	}
-
-	fileSize := stat.Size()
-	if fileSize < 4 {
-		return 0, fmt.Errorf("MP3 file too small")
-	}
-
-	buffer := make([]byte, 8192)
-	_, err = file.ReadAt(buffer, 0)
+	offset, frame, err := findMPEGFrame(r, start, end)
 	if err != nil {
-		return 0, fmt.Errorf("failed to read MP3 file header: %w", err)
+		return 0, err
 	}
 
-	if buffer[0] != 0xFF || (buffer[1]&0xE0) != 0xE0 {
-		return 0, fmt.Errorf("not a valid MP3 file")
+	if frames, ok := readVBRHeader(r, offset, frame); ok && frames > 0 {
+		return float64(frames) * float64(frame.samples()) / float64(frame.sampleRate), nil
 	}
 
-	duration, err := h.extractDurationFromXing(buffer)
-	if err == nil && duration > 0 {
-		return duration, nil
+	maxFrames := mpegEstimateFrames
+	if h.exactDuration {
+		maxFrames = 0
 	}
-
-	duration, err = h.extractDurationFromFrames(file, buffer)
-	if err == nil && duration > 0 {
-		return duration, nil
+	frames, samples, stop, err := walkMPEGFrames(r, offset, end, maxFrames)
+	if err != nil {
+		return 0, err
 	}
-
-	header := buffer[0:4]
-	bitrate := h.getBitrate(header)
-	sampleRate := h.getSampleRate(header)
-
-	if bitrate == 0 || sampleRate == 0 {
-		return 0, fmt.Errorf("could not determine bitrate or sample rate")
+	if frames == 0 {
+		return 0, fmt.Errorf("could not parse MP3 frames")
 	}
-
-	duration = float64(fileSize*8) / float64(bitrate*1000)
-	if duration > 0 {
-		return duration, nil
+	if frames < maxFrames || maxFrames == 0 {
+		return float64(samples) / float64(frame.sampleRate), nil
 	}
 
-	return 0, fmt.Errorf("could not extract duration")
+	bytesPerSample := float64(stop-offset) / float64(samples)
+	return float64(end-offset) / bytesPerSample / float64(frame.sampleRate), nil
 }
 
-func (h *mp3Handler) extractDurationFromXing(buffer []byte) (float64, error) {
-	for i := 0; i < len(buffer)-12; i++ {
-		if string(buffer[i:i+4]) == "Xing" || string(buffer[i:i+4]) == "Info" {
-			frames := uint32(buffer[i+8])<<24 | uint32(buffer[i+9])<<16 | uint32(buffer[i+10])<<8 | uint32(buffer[i+11])
-			if frames > 0 {
-				header := buffer[0:4]
-				sampleRate := h.getSampleRate(header)
-				if sampleRate > 0 {
-					samplesPerFrame := 1152
-					if (header[1]>>3)&0x03 == 3 {
-						samplesPerFrame = 1152
-					} else {
-						samplesPerFrame = 576
-					}
-					duration := float64(frames) * float64(samplesPerFrame) / float64(sampleRate)
-					return duration, nil
-				}
-			}
-		}
-		if string(buffer[i:i+4]) == "VBRI" {
-			frames := uint32(buffer[i+14])<<24 | uint32(buffer[i+15])<<16 | uint32(buffer[i+16])<<8 | uint32(buffer[i+17])
-			if frames > 0 {
-				header := buffer[0:4]
-				sampleRate := h.getSampleRate(header)
-				if sampleRate > 0 {
-					samplesPerFrame := 1152
-					if (header[1]>>3)&0x03 == 3 {
-						samplesPerFrame = 1152
-					} else {
-						samplesPerFrame = 576
-					}
-					duration := float64(frames) * float64(samplesPerFrame) / float64(sampleRate)
-					return duration, nil
-				}
-			}
+var mpegLayerCodecs = [4]string{"", "MP1", "MP2", "MP3"}
+
+func (h *mp3Handler) ExtractAudioInfo(r io.ReaderAt, size int64, result *model.FileMetadata) error {
+	start, end, err := mp3AudioRange(r, size)
+	if err != nil {
+		return err
+	}
+	offset, frame, err := findMPEGFrame(r, start, end)
+	if err != nil {
+		return err
+	}
+
+	result.Codec = mpegLayerCodecs[frame.layer]
+	result.SampleRate = frame.sampleRate
+	result.Channels = frame.channels
+	// The header bitrate of a VBR file only applies to its first frame.
+	result.Bitrate = averageBitrate(end-offset, result.Duration)
+	if result.Bitrate == 0 {
+		result.Bitrate = frame.bitrate
+	}
+	// The LAME header is exact, so it wins over iTunSMPB.
+	if gapless := readLAMEHeader(r, offset, frame); gapless != nil {
+		if result.Gapless != nil {
+			gapless.TotalSamples = result.Gapless.TotalSamples
+			gapless.ITunSMPB = result.Gapless.ITunSMPB
 		}
+		result.Gapless = gapless
 	}
-	return 0, fmt.Errorf("no Xing/VBRI header found")
+	return nil
 }
 
-func (h *mp3Handler) extractDurationFromFrames(file *os.File, buffer []byte) (float64, error) {
-	stat, err := file.Stat()
+func mp3AudioRange(r io.ReaderAt, size int64) (int64, int64, error) {
+	start, err := skipID3v2Tags(r, size)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get file stats for frame extraction: %w", err)
+		return 0, 0, err
 	}
-	fileSize := stat.Size()
-
-	header := buffer[0:4]
-	sampleRate := h.getSampleRate(header)
-	if sampleRate == 0 {
-		return 0, fmt.Errorf("could not determine sample rate")
+	end, _, err := findID3v1Tags(r, start, size)
+	if err != nil {
+		return 0, 0, err
 	}
+	return start, mp3APETagStart(r, start, end), nil
+}
 
-	samplesPerFrame := 1152
-	version := (buffer[1] >> 3) & 0x03
-	if version != 3 {
-		samplesPerFrame = 576
+func mp3APETagStart(r io.ReaderAt, start, end int64) int64 {
+	if tagStart, tag, err := findAPETag(r, start, end); err == nil && tag != nil {
+		return tagStart
 	}
+	return end
+}
 
-	frameCount := 0
-	pos := int64(0)
-	maxPos := fileSize
-	if maxPos > 512*1024 {
-		maxPos = 512 * 1024
+func (h *mp3Handler) UpdateTags(ctx context.Context, fsys vfs.FS, filePath string, update *model.TagUpdate) error {
+	src, err := fsys.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open MP3 file: %w", err)
 	}
+	defer src.Close()
 
-	readBuffer := make([]byte, 4096)
-	for pos < maxPos-4 {
-		readSize := int64(4096)
-		if pos+readSize > maxPos {
-			readSize = maxPos - pos
-		}
-
-		n, err := file.ReadAt(readBuffer[:readSize], pos)
-		if err != nil && !errors.Is(err, io.EOF) {
-			return 0, fmt.Errorf("failed to read MP3 frames: %w", err)
-		}
-		if n == 0 {
-			break
-		}
-
-		for i := 0; i < n-4; i++ {
-			if readBuffer[i] == 0xFF && (readBuffer[i+1]&0xE0) == 0xE0 {
-				frameHeader := readBuffer[i : i+4]
-				frameSize := h.getFrameSize(frameHeader)
-				if frameSize > 0 && frameSize < 1441 {
-					frameCount++
-					pos += int64(i) + int64(frameSize)
-					break
-				}
-			}
-		}
-
-		if pos >= maxPos-4 {
-			break
-		}
+	stat, err := src.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %w", err)
 	}
-
-	if frameCount > 10 {
-		avgFrameSize := float64(pos) / float64(frameCount)
-		estimatedTotalFrames := float64(fileSize) / avgFrameSize
-		duration := estimatedTotalFrames * float64(samplesPerFrame) / float64(sampleRate)
-		if duration > 0 {
-			return duration, nil
-		}
+	if update.Chapters == nil {
+		update = keepID3Chapters(update, src, stat.Size())
 	}
-
-	return 0, fmt.Errorf("could not parse frames")
-}
-
-func (h *mp3Handler) getFrameSize(header []byte) int {
-	bitrate := h.getBitrate(header)
-	sampleRate := h.getSampleRate(header)
-
-	if bitrate == 0 || sampleRate == 0 {
-		return 0
+	v1Tag, err := onlyID3v1Tag(src, stat.Size())
+	if err != nil {
+		return err
 	}
 
-	padding := 0
-	if (header[2]>>1)&0x01 == 1 {
-		padding = 1
+	id3Tag, err := id3v2.ParseReader(io.NewSectionReader(src, 0, stat.Size()), id3v2.Options{Parse: true})
+	if err != nil {
+		return fmt.Errorf("failed to read ID3v2 tag: %w", err)
 	}
-
-	version := (header[1] >> 3) & 0x03
-	samplesPerFrame := 1152
-	if version != 3 {
-		samplesPerFrame = 576
+	setID3Version(id3Tag, h.id3.Version)
+	id3Tag.SetDefaultEncoding(id3v2.EncodingUTF8)
+	if v1Tag != nil {
+		seedFromID3v1(id3Tag, v1Tag)
 	}
+	applyID3Tags(id3Tag, update)
 
-	frameSize := ((samplesPerFrame / 8) * bitrate * 1000 / sampleRate) + padding
-	return frameSize
-}
-
-func (h *mp3Handler) getBitrate(header []byte) int {
-	bitrateTable := [][]int{
-		{0, 0, 0, 0, 0},
-		{32, 32, 32, 32, 8},
-		{64, 48, 40, 48, 16},
-		{96, 56, 48, 56, 24},
-		{128, 64, 56, 64, 32},
-		{160, 80, 64, 80, 40},
-		{192, 96, 80, 96, 48},
-		{224, 112, 96, 112, 56},
-		{256, 128, 112, 128, 64},
-		{288, 160, 128, 160, 80},
-		{320, 192, 160, 192, 96},
-		{352, 224, 192, 224, 112},
-		{384, 256, 224, 256, 128},
-		{416, 320, 256, 320, 144},
-		{448, 384, 320, 384, 160},
-	}
-
-	version := (header[1] >> 3) & 0x03
-	layer := (header[1] >> 1) & 0x03
-	bitrateIndex := (header[2] >> 4) & 0x0F
-
-	if version == 0 || layer != 1 || bitrateIndex == 0 || bitrateIndex == 15 {
-		return 0
-	}
-
-	if version == 3 {
-		idx := int(bitrateIndex)
-		if idx < len(bitrateTable) {
-			return bitrateTable[idx][0]
+	if update.CoverArt != nil && *update.CoverArt != "" {
+		if err := setID3Cover(id3Tag, *update.CoverArt); err != nil {
+			return err
 		}
 	}
-
-	return 0
-}
-
-func (h *mp3Handler) getSampleRate(header []byte) int {
-	sampleRateTable := [][]int{
-		{44100, 22050, 11025},
-		{48000, 24000, 12000},
-		{32000, 16000, 8000},
-		{0, 0, 0},
+	if err := applyID3Pictures(id3Tag, update.Pictures); err != nil {
+		return err
 	}
+	src.Close()
 
-	version := (header[1] >> 3) & 0x03
-	sampleRateIndex := (header[2] >> 2) & 0x03
-
-	idx := int(sampleRateIndex)
-	if version == 3 {
-		if idx < len(sampleRateTable) {
-			return sampleRateTable[idx][0]
-		}
-	} else if version == 2 {
-		if idx < len(sampleRateTable) {
-			return sampleRateTable[idx][1]
-		}
-	}
-
-	return 0
+	return h.writeFile(fsys, filePath, stat.Mode(), id3Tag, update)
 }
 
-func (h *mp3Handler) UpdateTags(
-	filePath string,
-	title, artist, album *string,
-	year, track *int,
-	genre *string,
-	coverArt *string,
+func (h *mp3Handler) writeFile(
+	fsys vfs.FS, filePath string, mode fs.FileMode, id3Tag *id3v2.Tag, update *model.TagUpdate,
 ) error {
-	stat, err := os.Stat(filePath)
+	src, err := fsys.Open(filePath)
 	if err != nil {
-		return fmt.Errorf("failed to stat file: %w", err)
+		return fmt.Errorf("failed to open MP3 file: %w", err)
 	}
-	originalModTime := stat.ModTime()
+	defer src.Close()
 
-	tagFile, err := id3v2.Open(filePath, id3v2.Options{Parse: true})
+	stat, err := src.Stat()
 	if err != nil {
-		return fmt.Errorf("failed to open MP3 file: %w", err)
+		return fmt.Errorf("failed to stat file: %w", err)
 	}
-	defer tagFile.Close()
 
-	if title != nil {
-		if *title == "" {
-			tagFile.SetTitle("")
-		} else {
-			tagFile.SetTitle(*title)
-		}
-	}
-	if artist != nil {
-		if *artist == "" {
-			tagFile.SetArtist("")
-		} else {
-			tagFile.SetArtist(*artist)
-		}
-	}
-	if album != nil {
-		if *album == "" {
-			tagFile.SetAlbum("")
-		} else {
-			tagFile.SetAlbum(*album)
-		}
-	}
-	if year != nil {
-		tagFile.SetYear(fmt.Sprintf("%d", *year))
+	start, err := skipID3v2Tags(src, stat.Size())
+	if err != nil {
+		return err
 	}
-	if track != nil {
-		tagFile.AddTextFrame("TRCK", id3v2.EncodingUTF8, fmt.Sprintf("%d", *track))
+	v1Start, v1Tag, err := findID3v1Tags(src, start, stat.Size())
+	if err != nil {
+		return err
 	}
-	if genre != nil {
-		if *genre == "" {
-			tagFile.SetGenre("")
-		} else {
-			tagFile.SetGenre(*genre)
-		}
+	end := mp3APETagStart(src, start, v1Start)
+	apeData := make([]byte, v1Start-end)
+	if _, err := src.ReadAt(apeData, end); err != nil {
+		return fmt.Errorf("failed to read APE tag: %w", err)
 	}
 
-	if coverArt != nil && *coverArt != "" {
-		tagFile.DeleteFrames("APIC")
-		coverData, mimeType, err := h.parseCoverArtData(*coverArt)
-		if err != nil {
-			return fmt.Errorf("failed to parse cover art data: %w", err)
+	switch h.id3.V1 {
+	case ID3v1Strip:
+		v1Tag = nil
+		apeData = nil
+	case ID3v1Sync:
+		if v1Tag != nil {
+			v1Tag = buildID3v1(id3Tag)
 		}
-		mimeType = h.normalizeMimeType(mimeType)
-		pic := id3v2.PictureFrame{
-			Encoding:    id3v2.EncodingUTF8,
-			MimeType:    mimeType,
-			PictureType: id3v2.PTFrontCover,
-			Description: "Front Cover",
-			Picture:     coverData,
+		if len(apeData) > 0 {
+			if _, apeTag, err := findAPETag(src, end, v1Start); err == nil && apeTag != nil {
+				if err := apeTag.apply(apeSyncUpdate(update)); err != nil {
+					return err
+				}
+				apeData = apeTag.marshal()
+			}
 		}
-		tagFile.AddAttachedPicture(pic)
 	}
 
-	tagFile.DeleteFrames("TXXX")
+	fixID3Encodings(id3Tag)
 
-	if err := tagFile.Save(); err != nil {
-		return fmt.Errorf("failed to save tags: %w", err)
+	tempFile := filePath + ".tmp"
+	dst, err := fsys.OpenFile(tempFile, os.O_RDWR|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return fmt.Errorf("failed to create temp MP3 file: %w", err)
 	}
+	defer fsys.Remove(tempFile)
+	defer dst.Close()
 
-	if err := os.Chtimes(filePath, originalModTime, originalModTime); err != nil {
-		return fmt.Errorf("failed to set modification time: %w", err)
+	if _, err := id3Tag.WriteTo(dst); err != nil {
+		return fmt.Errorf("failed to write ID3v2 tag: %w", err)
+	}
+	if _, err := io.Copy(dst, io.NewSectionReader(src, start, end-start)); err != nil {
+		return fmt.Errorf("failed to copy MP3 audio: %w", err)
+	}
+	if _, err := dst.Write(apeData); err != nil {
+		return fmt.Errorf("failed to write APE tag: %w", err)
+	}
+	if _, err := dst.Write(v1Tag); err != nil {
+		return fmt.Errorf("failed to write ID3v1 tag: %w", err)
 	}
+	if err := dst.Close(); err != nil {
+		return fmt.Errorf("failed to close temp MP3 file: %w", err)
+	}
+	src.Close()
 
+	if err := fsys.Rename(tempFile, filePath); err != nil {
+		return fmt.Errorf("failed to rename temp file: %w", err)
+	}
 	return nil
 }
 
-func (h *mp3Handler) parseCoverArtData(dataURI string) ([]byte, string, error) {
-	if !strings.HasPrefix(dataURI, "data:") {
-		return nil, "", fmt.Errorf("invalid data URI format")
-	}
-
-	parts := strings.SplitN(dataURI, ",", 2)
-	if len(parts) != 2 {
-		return nil, "", fmt.Errorf("invalid data URI format")
+func onlyID3v1Tag(r io.ReaderAt, size int64) ([]byte, error) {
+	start, err := skipID3v2Tags(r, size)
+	if err != nil || start > 0 {
+		return nil, err
 	}
+	_, v1Tag, err := findID3v1Tags(r, 0, size)
+	return v1Tag, err
+}
 
-	header := parts[0]
-	data := parts[1]
-
-	mimeType := "image/jpeg"
-	if strings.HasPrefix(header, "data:image/") {
-		mimeParts := strings.Split(header, ";")
-		if len(mimeParts) > 0 {
-			mimePart := strings.TrimPrefix(mimeParts[0], "data:")
-			if mimePart != "" {
-				mimeType = mimePart
-			}
+func apeSyncUpdate(update *model.TagUpdate) *model.TagUpdate {
+	sync := *update
+	sync.CoverArt = nil
+	sync.Pictures = nil
+	if update.CoverArt != nil && *update.CoverArt != "" {
+		for pictureType := 0; pictureType <= model.PictureTypeMax; pictureType++ {
+			sync.Pictures = append(sync.Pictures, model.PictureUpdate{Type: pictureType})
 		}
 	}
-
-	coverData, err := base64.StdEncoding.DecodeString(data)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to decode base64: %w", err)
+	for _, picture := range update.Pictures {
+		sync.Pictures = append(sync.Pictures, model.PictureUpdate{Type: picture.Type})
 	}
+	return &sync
+}
 
-	return coverData, mimeType, nil
+func skipID3v2Tags(r io.ReaderAt, size int64) (int64, error) {
+	offset := int64(0)
+	header := make([]byte, 10)
+	for offset+10 <= size {
+		if _, err := r.ReadAt(header, offset); err != nil {
+			return 0, fmt.Errorf("failed to read ID3v2 header: %w", err)
+		}
+		if string(header[0:3]) != "ID3" {
+			break
+		}
+		tagSize := int64(header[6]&0x7F)<<21 | int64(header[7]&0x7F)<<14 | int64(header[8]&0x7F)<<7 | int64(header[9]&0x7F)
+		offset += 10 + tagSize
+		if header[5]&0x10 != 0 {
+			offset += 10 // footer
+		}
+	}
+	return min(offset, size), nil
 }
 
-func (h *mp3Handler) normalizeMimeType(mimeType string) string {
-	mimeType = strings.ToLower(strings.TrimSpace(mimeType))
-	switch mimeType {
-	case "image/jpg":
-		return "image/jpeg"
-	case "image/png":
-		return "image/png"
-	case "image/gif":
-		return "image/gif"
-	case "image/bmp":
-		return "image/bmp"
-	default:
-		if strings.HasPrefix(mimeType, "image/") {
-			return mimeType
+func findID3v1Tags(r io.ReaderAt, start, size int64) (int64, []byte, error) {
+	end := size
+	var last []byte
+	for end-start >= id3v1Size {
+		buf := make([]byte, id3v1Size)
+		if _, err := r.ReadAt(buf, end-id3v1Size); err != nil {
+			return 0, nil, fmt.Errorf("failed to read ID3v1 tag: %w", err)
+		}
+		if string(buf[0:3]) != "TAG" || string(buf[id3v1Size-apeTagFooterSize:][:8]) == "APETAGEX" {
+			break
+		}
+		if last == nil {
+			last = buf
 		}
-		return "image/jpeg"
+		end -= id3v1Size
 	}
+	return end, last, nil
 }
 
 func getMP3Handler(ext string) FormatHandler {
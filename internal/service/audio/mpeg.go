@@ -0,0 +1,186 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	mpegVersion25 = 0
+	mpegVersion2  = 2
+	mpegVersion1  = 3
+
+	mpegSyncSearchLimit = 64 * 1024
+	mpegEstimateFrames  = 2000
+)
+
+var mpegBitrates = [5][16]int{
+	{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448, 0},
+	{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384, 0},
+	{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0},
+	{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256, 0},
+	{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+}
+
+var mpegSampleRates = map[byte][3]int{
+	mpegVersion1:  {44100, 48000, 32000},
+	mpegVersion2:  {22050, 24000, 16000},
+	mpegVersion25: {11025, 12000, 8000},
+}
+
+type mpegFrameHeader struct {
+	version    byte
+	layer      int
+	bitrate    int // kbit/s
+	sampleRate int
+	padding    bool
+	channels   int
+}
+
+func parseMPEGFrameHeader(header []byte) (mpegFrameHeader, bool) {
+	if len(header) < 4 || header[0] != 0xFF || header[1]&0xE0 != 0xE0 {
+		return mpegFrameHeader{}, false
+	}
+
+	version := (header[1] >> 3) & 0x03
+	layerBits := (header[1] >> 1) & 0x03
+	bitrateIndex := header[2] >> 4
+	sampleRateIndex := (header[2] >> 2) & 0x03
+	if version == 1 || layerBits == 0 || bitrateIndex == 0 || bitrateIndex == 15 || sampleRateIndex == 3 {
+		return mpegFrameHeader{}, false
+	}
+
+	frame := mpegFrameHeader{
+		version:    version,
+		layer:      4 - int(layerBits),
+		sampleRate: mpegSampleRates[version][sampleRateIndex],
+		padding:    header[2]&0x02 != 0,
+		channels:   2,
+	}
+	if header[3]>>6 == 3 {
+		frame.channels = 1
+	}
+
+	table := frame.layer - 1
+	if version != mpegVersion1 {
+		table = 3
+		if frame.layer > 1 {
+			table = 4
+		}
+	}
+	frame.bitrate = mpegBitrates[table][bitrateIndex]
+	return frame, true
+}
+
+func (f mpegFrameHeader) samples() int {
+	switch {
+	case f.layer == 1:
+		return 384
+	case f.layer == 3 && f.version != mpegVersion1:
+		return 576
+	default:
+		return 1152
+	}
+}
+
+func (f mpegFrameHeader) size() int {
+	padding := 0
+	if f.padding {
+		padding = 1
+	}
+	if f.layer == 1 {
+		return (12*f.bitrate*1000/f.sampleRate + padding) * 4
+	}
+	return f.samples()/8*f.bitrate*1000/f.sampleRate + padding
+}
+
+func (f mpegFrameHeader) xingOffset() int {
+	switch {
+	case f.version == mpegVersion1 && f.channels == 2:
+		return 4 + 32
+	case f.version == mpegVersion1, f.channels == 2:
+		return 4 + 17
+	default:
+		return 4 + 9
+	}
+}
+
+func findMPEGFrame(r io.ReaderAt, start, end int64) (int64, mpegFrameHeader, error) {
+	if start >= end {
+		return 0, mpegFrameHeader{}, fmt.Errorf("no MPEG frame header found")
+	}
+	buffer := make([]byte, min(mpegSyncSearchLimit, end-start))
+	n, err := r.ReadAt(buffer, start)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return 0, mpegFrameHeader{}, fmt.Errorf("failed to read MP3 frames: %w", err)
+	}
+	buffer = buffer[:n]
+
+	next := make([]byte, 4)
+	for i := 0; i+4 <= len(buffer); i++ {
+		frame, ok := parseMPEGFrameHeader(buffer[i:])
+		if !ok {
+			continue
+		}
+		nextOffset := start + int64(i) + int64(frame.size())
+		if nextOffset+4 <= end {
+			if _, err := r.ReadAt(next, nextOffset); err != nil {
+				continue
+			}
+			if _, ok := parseMPEGFrameHeader(next); !ok {
+				continue
+			}
+		}
+		return start + int64(i), frame, nil
+	}
+	return 0, mpegFrameHeader{}, fmt.Errorf("no MPEG frame header found")
+}
+
+func readVBRHeader(r io.ReaderAt, offset int64, frame mpegFrameHeader) (uint32, bool) {
+	buffer := make([]byte, min(frame.size(), 192))
+	if _, err := r.ReadAt(buffer, offset); err != nil {
+		return 0, false
+	}
+
+	if xing := frame.xingOffset(); xing+12 <= len(buffer) {
+		tag := string(buffer[xing : xing+4])
+		flags := binary.BigEndian.Uint32(buffer[xing+4 : xing+8])
+		if (tag == "Xing" || tag == "Info") && flags&0x01 != 0 {
+			return binary.BigEndian.Uint32(buffer[xing+8 : xing+12]), true
+		}
+	}
+	if len(buffer) >= 36+18 && string(buffer[36:40]) == "VBRI" {
+		return binary.BigEndian.Uint32(buffer[36+14 : 36+18]), true
+	}
+	return 0, false
+}
+
+func walkMPEGFrames(r io.ReaderAt, start, end int64, maxFrames int) (int, int64, int64, error) {
+	frames, samples, offset := 0, int64(0), start
+	header := make([]byte, 4)
+	for offset+4 <= end && (maxFrames <= 0 || frames < maxFrames) {
+		if _, err := r.ReadAt(header, offset); err != nil {
+			return 0, 0, 0, fmt.Errorf("failed to read MP3 frame header: %w", err)
+		}
+
+		frame, ok := parseMPEGFrameHeader(header)
+		if !ok {
+			next, _, err := findMPEGFrame(r, offset+1, end)
+			if err != nil {
+				break
+			}
+			offset = next
+			continue
+		}
+		if offset+int64(frame.size()) > end {
+			break
+		}
+
+		offset += int64(frame.size())
+		frames++
+		samples += int64(frame.samples())
+	}
+	return frames, samples, offset, nil
+}
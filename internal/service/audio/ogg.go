@@ -1,11 +1,19 @@
 package audio
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
 	"fmt"
-	"os"
+	"io"
 	"strings"
 
 	"github.com/dhowden/tag"
+	"github.com/go-flac/flacvorbis"
+	"github.com/go-flac/go-flac"
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/internal/vfs"
 )
 
 type oggHandler struct{}
@@ -18,50 +26,478 @@ func (h *oggHandler) Format() string {
 	return "OGG"
 }
 
-func (h *oggHandler) ExtractDuration(filePath string) (float64, error) {
-	file, err := os.Open(filePath)
+func (h *oggHandler) Capabilities() model.Capabilities {
+	return fullCapabilities
+}
+
+func (h *oggHandler) ExtractDuration(ctx context.Context, r io.ReaderAt, size int64) (float64, error) {
+	firstPage, err := readVorbisHeaderPage(r, size)
+	if err != nil {
+		return 0, err
+	}
+	sampleRate := binary.LittleEndian.Uint32(firstPage.data[12:16])
+	if sampleRate == 0 {
+		return 0, fmt.Errorf("could not determine Vorbis sample rate")
+	}
+
+	granule, err := lastOggGranule(r, size, firstPage.serial)
+	if err != nil {
+		return 0, err
+	}
+	if granule == 0 {
+		return 0, fmt.Errorf("could not determine OGG duration")
+	}
+
+	return float64(granule) / float64(sampleRate), nil
+}
+
+func readVorbisHeaderPage(r io.ReaderAt, size int64) (*oggPage, error) {
+	page, err := readOggPage(bufio.NewReader(io.NewSectionReader(r, 0, size)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Vorbis header page: %w", unexpectedEOF(err))
+	}
+	if len(page.data) < 24 || !bytes.HasPrefix(page.data, []byte("\x01vorbis")) {
+		return nil, fmt.Errorf("not a valid Vorbis file")
+	}
+	return page, nil
+}
+
+func (h *oggHandler) ExtractAudioInfo(r io.ReaderAt, size int64, result *model.FileMetadata) error {
+	page, err := readVorbisHeaderPage(r, size)
+	if err != nil {
+		return err
+	}
+
+	result.Codec = "Vorbis"
+	result.Channels = int(page.data[11])
+	result.SampleRate = int(binary.LittleEndian.Uint32(page.data[12:16]))
+	if headerSize, err := oggHeaderSize(r, size, vorbisCommentCodec.headerPackets); err == nil {
+		result.Bitrate = averageBitrate(size-headerSize, result.Duration)
+	}
+	if nominal := int32(binary.LittleEndian.Uint32(page.data[20:24])); result.Bitrate == 0 && nominal > 0 {
+		result.Bitrate = int(nominal) / 1000
+	}
+	return nil
+}
+
+func (h *oggHandler) UpdateTags(ctx context.Context, fsys vfs.FS, filePath string, update *model.TagUpdate) error {
+	return updateOggComments(fsys, filePath, vorbisCommentCodec, update)
+}
+
+type oggCommentCodec struct {
+	name          string
+	prefix        []byte
+	framingBit    bool
+	headerPackets int
+}
+
+var vorbisCommentCodec = oggCommentCodec{
+	name:          "Vorbis",
+	prefix:        []byte("\x03vorbis"),
+	framingBit:    true,
+	headerPackets: 3,
+}
+
+var oggCommentCodecs = map[string]oggCommentCodec{
+	"OGG":  vorbisCommentCodec,
+	"OPUS": opusCommentCodec,
+}
+
+func readOggComments(r io.ReaderAt, size int64, codec oggCommentCodec) ([]string, error) {
+	packet, err := readOggHeaderPacket(bufio.NewReader(io.NewSectionReader(r, 0, size)), 1)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(packet, codec.prefix) {
+		return nil, fmt.Errorf("not an Ogg %s stream", codec.name)
+	}
+	vorbisComment, err := flacvorbis.ParseFromMetaDataBlock(
+		flac.MetaDataBlock{Type: flac.VorbisComment, Data: packet[len(codec.prefix):]},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s comment header: %w", codec.name, err)
+	}
+	return vorbisComment.Comments, nil
+}
+
+func updateOggComments(fsys vfs.FS, filePath string, codec oggCommentCodec, update *model.TagUpdate) error {
+	var pictureBlock string
+	var err error
+	if update.CoverArt != nil && *update.CoverArt != "" {
+		pictureBlock, err = buildVorbisPictureBlock(*update.CoverArt)
+		if err != nil {
+			return err
+		}
+	}
+	pictures, err := buildVorbisPictures(update.Pictures)
 	if err != nil {
-		return 0, fmt.Errorf("failed to open OGG file: %w", err)
+		return err
 	}
-	defer file.Close()
 
-	stat, err := file.Stat()
+	err = rewriteOggHeaderPacket(
+		fsys, filePath, codec.headerPackets, func(packet []byte) ([]byte, error) {
+			if !bytes.HasPrefix(packet, codec.prefix) {
+				return nil, fmt.Errorf("not an Ogg %s stream", codec.name)
+			}
+			data := packet[len(codec.prefix):]
+			vorbisComment, err := flacvorbis.ParseFromMetaDataBlock(
+				flac.MetaDataBlock{Type: flac.VorbisComment, Data: data},
+			)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %s comment header: %w", codec.name, err)
+			}
+			end, err := vorbisCommentsEnd(data)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %s comment header: %w", codec.name, err)
+			}
+
+			applyVorbisCommentTags(vorbisComment, update)
+			if pictureBlock != "" {
+				removeVorbisComments(vorbisComment, "METADATA_BLOCK_PICTURE", "COVERART", "COVERARTMIME")
+				vorbisComment.Comments = append(vorbisComment.Comments, "METADATA_BLOCK_PICTURE="+pictureBlock)
+			}
+			applyVorbisPictures(vorbisComment, pictures)
+
+			marshaled := vorbisComment.Marshal()
+			result := make([]byte, 0, len(codec.prefix)+len(marshaled.Data)+len(data)-end+1)
+			result = append(result, codec.prefix...)
+			result = append(result, marshaled.Data...)
+			if codec.framingBit {
+				result = append(result, 0x01)
+			} else {
+				// Opus allows binary data after the comments, which is kept.
+				result = append(result, data[end:]...)
+			}
+			return result, nil
+		},
+	)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get OGG file stats: %w", err)
+		return fmt.Errorf("failed to save tags: %w", err)
 	}
 
-	buffer := make([]byte, 8192)
-	readPos := stat.Size() - 8192
-	if readPos < 0 {
-		readPos = 0
+	return nil
+}
+
+func vorbisCommentsEnd(data []byte) (int, error) {
+	offset := 0
+	field := func() error {
+		if len(data)-offset < 4 {
+			return io.ErrUnexpectedEOF
+		}
+		n := binary.LittleEndian.Uint32(data[offset:])
+		offset += 4
+		if uint64(n) > uint64(len(data)-offset) {
+			return io.ErrUnexpectedEOF
+		}
+		offset += int(n)
+		return nil
+	}
+	if err := field(); err != nil {
+		return 0, err
+	}
+	if len(data)-offset < 4 {
+		return 0, io.ErrUnexpectedEOF
 	}
-	_, err = file.ReadAt(buffer, readPos)
+	count := binary.LittleEndian.Uint32(data[offset:])
+	offset += 4
+	for range count {
+		if err := field(); err != nil {
+			return 0, err
+		}
+	}
+	return offset, nil
+}
+
+func buildVorbisPictureBlock(dataURI string) (string, error) {
+	_, pictureBlock, err := frontCoverBlock(dataURI)
 	if err != nil {
-		return 0, fmt.Errorf("failed to read OGG file tail: %w", err)
+		return "", fmt.Errorf("failed to create picture block: %w", err)
+	}
+	return pictureBlock, nil
+}
+
+func applyVorbisCommentTags(vorbisComment *flacvorbis.MetaDataBlockVorbisComment, update *model.TagUpdate) {
+	if update.Title != nil {
+		setVorbisText(vorbisComment, flacvorbis.FIELD_TITLE, *update.Title, update.KeepsEmpty("title"))
+	}
+	if update.Artist != nil {
+		setVorbisText(vorbisComment, flacvorbis.FIELD_ARTIST, *update.Artist, update.KeepsEmpty("artist"))
 	}
+	if update.Album != nil {
+		setVorbisText(vorbisComment, flacvorbis.FIELD_ALBUM, *update.Album, update.KeepsEmpty("album"))
+	}
+	if date, ok := updatedReleaseDate(vorbisCommentValue(vorbisComment, flacvorbis.FIELD_DATE), update); ok {
+		setVorbisComment(vorbisComment, flacvorbis.FIELD_DATE, date)
+	}
+	if update.Track != nil {
+		if update.TotalTracks == nil {
+			splitVorbisTotal(vorbisComment, flacvorbis.FIELD_TRACKNUMBER, namesFor(update.TagProfile).totalTracks)
+		}
+		setVorbisComment(vorbisComment, flacvorbis.FIELD_TRACKNUMBER, formatPositive(*update.Track))
+	}
+	if update.Genre != nil {
+		setVorbisText(vorbisComment, flacvorbis.FIELD_GENRE, *update.Genre, update.KeepsEmpty("genre"))
+	}
+	applyExtendedVorbisCommentTags(vorbisComment, update)
+}
+
+func applyExtendedVorbisCommentTags(vorbisComment *flacvorbis.MetaDataBlockVorbisComment, update *model.TagUpdate) {
+	names := namesFor(update.TagProfile)
+	if update.AlbumArtist != nil {
+		setVorbisNames(
+			vorbisComment, vorbisAliases.albumArtist, names.albumArtist, *update.AlbumArtist,
+			update.KeepsEmpty("albumArtist"),
+		)
+	}
+	if update.Composer != nil {
+		setVorbisText(vorbisComment, "COMPOSER", *update.Composer, update.KeepsEmpty("composer"))
+	}
+	if update.Comment != nil {
+		setVorbisNames(vorbisComment, vorbisAliases.comment, names.comment, *update.Comment, update.KeepsEmpty("comment"))
+	}
+	if update.TitleSort != nil {
+		setVorbisText(vorbisComment, "TITLESORT", *update.TitleSort, update.KeepsEmpty("titleSort"))
+	}
+	if update.ArtistSort != nil {
+		setVorbisText(vorbisComment, "ARTISTSORT", *update.ArtistSort, update.KeepsEmpty("artistSort"))
+	}
+	if update.AlbumArtistSort != nil {
+		setVorbisText(vorbisComment, "ALBUMARTISTSORT", *update.AlbumArtistSort, update.KeepsEmpty("albumArtistSort"))
+	}
+	if update.AlbumSort != nil {
+		setVorbisText(vorbisComment, "ALBUMSORT", *update.AlbumSort, update.KeepsEmpty("albumSort"))
+	}
+	if update.BPM != nil {
+		setVorbisComment(vorbisComment, "BPM", formatPositive(*update.BPM))
+	}
+	if update.ISRC != nil {
+		setVorbisComment(vorbisComment, "ISRC", *update.ISRC)
+	}
+	if update.Barcode != nil {
+		setVorbisComment(vorbisComment, "BARCODE", *update.Barcode)
+	}
+	if update.CatalogNumber != nil {
+		setVorbisComment(vorbisComment, "CATALOGNUMBER", *update.CatalogNumber)
+	}
+	if update.Label != nil {
+		setVorbisNames(vorbisComment, vorbisAliases.label, names.label, *update.Label, false)
+	}
+	if update.Compilation != nil {
+		value := ""
+		if *update.Compilation {
+			value = "1"
+		}
+		setVorbisComment(vorbisComment, "COMPILATION", value)
+	}
+	if update.Rating != nil {
+		setVorbisComment(vorbisComment, "RATING", formatPositive(*update.Rating))
+		setVorbisComment(vorbisComment, "FMPS_RATING", formatFMPSRating(*update.Rating))
+	}
+	if update.PlayCount != nil {
+		removeVorbisComments(vorbisComment, "PLAYCOUNT")
+		setVorbisComment(vorbisComment, "FMPS_PLAYCOUNT", formatPositive(*update.PlayCount))
+	}
+	if update.Lyrics != nil {
+		removeVorbisComments(vorbisComment, "UNSYNCEDLYRICS")
+		setVorbisText(vorbisComment, "LYRICS", *update.Lyrics, update.KeepsEmpty("lyrics"))
+	}
+	if update.SyncedLyrics != nil {
+		setVorbisComment(vorbisComment, "SYNCEDLYRICS", *update.SyncedLyrics)
+	}
+	if update.Chapters != nil {
+		setVorbisChapters(vorbisComment, *update.Chapters)
+	}
+	for _, key := range sortedCustomTagKeys(update.CustomTags) {
+		if key := strings.ToUpper(key); key == originalDateKey {
+			setVorbisOriginalDate(vorbisComment, names, update.CustomTags[key])
+		} else {
+			setVorbisCustomTag(vorbisComment, key, update.CustomTags[key])
+		}
+	}
+	if update.TotalTracks != nil {
+		setVorbisNames(vorbisComment, vorbisAliases.totalTracks, names.totalTracks, formatPositive(*update.TotalTracks), false)
+	}
+	if update.Disc != nil {
+		if update.TotalDiscs == nil {
+			splitVorbisTotal(vorbisComment, "DISCNUMBER", names.totalDiscs)
+		}
+		setVorbisComment(vorbisComment, "DISCNUMBER", formatPositive(*update.Disc))
+	}
+	if update.TotalDiscs != nil {
+		setVorbisNames(vorbisComment, vorbisAliases.totalDiscs, names.totalDiscs, formatPositive(*update.TotalDiscs), false)
+	}
+}
 
-	for i := len(buffer) - 5; i >= 0; i-- {
-		if string(buffer[i:i+5]) == "vorbis" {
-			if i+12 < len(buffer) {
-				sampleRate := uint32(buffer[i+11])<<24 | uint32(buffer[i+10])<<16 | uint32(buffer[i+9])<<8 | uint32(buffer[i+8])
-				if sampleRate > 0 {
-					estimatedDuration := float64(stat.Size()*8) / float64(sampleRate*16)
-					return estimatedDuration, nil
-				}
+func extractExtendedVorbisMetadata(comments []string, result *model.FileMetadata) {
+	chapters := make(map[int]*model.Chapter)
+	fmpsRating := false
+	var originalDate, originalYear string
+	for _, comment := range comments {
+		key, value, ok := strings.Cut(comment, "=")
+		if !ok || value == "" {
+			continue
+		}
+		switch strings.ToUpper(key) {
+		case "ALBUMARTIST", "ALBUM ARTIST", "ALBUM_ARTIST":
+			result.AlbumArtist = value
+		case "COMPOSER":
+			result.Composer = value
+		case "COMMENT":
+			result.Comment = value
+		case "DESCRIPTION":
+			// beets writes the comment under both names.
+			if result.Comment == "" {
+				result.Comment = value
+			}
+		case "BPM":
+			result.BPM = parseLeadingInt(value)
+		case "TITLESORT":
+			result.TitleSort = value
+		case "ARTISTSORT":
+			result.ArtistSort = value
+		case "ALBUMARTISTSORT":
+			result.AlbumArtistSort = value
+		case "ALBUMSORT":
+			result.AlbumSort = value
+		case "ISRC":
+			result.ISRC = value
+		case "BARCODE":
+			result.Barcode = value
+		case "CATALOGNUMBER":
+			result.CatalogNumber = value
+		case "LABEL":
+			result.Label = value
+		case "ORGANIZATION", "PUBLISHER":
+			// The older name of LABEL in the Vorbis comment spec, and the one foobar2000 uses.
+			if result.Label == "" {
+				result.Label = value
+			}
+		case "COMPILATION":
+			result.Compilation = value == "1"
+		case "FMPS_RATING":
+			result.Rating = parseVorbisRating(value)
+			fmpsRating = true
+		case "RATING":
+			// FMPS_RATING has an unambiguous scale, so it wins.
+			if !fmpsRating {
+				result.Rating = parseVorbisRating(value)
 			}
+		case "FMPS_PLAYCOUNT", "PLAYCOUNT":
+			result.PlayCount = max(result.PlayCount, parseLeadingInt(value))
+		case "DATE":
+			result.ReleaseDate = normalizeReleaseDate(value)
+		case "TRACKNUMBER":
+			var total int
+			result.Track, total = splitNumberPair(value)
+			if result.TotalTracks == 0 {
+				result.TotalTracks = total
+			}
+		case "DISCNUMBER":
+			var total int
+			result.Disc, total = splitNumberPair(value)
+			if result.TotalDiscs == 0 {
+				result.TotalDiscs = total
+			}
+		case "TRACKTOTAL", "TOTALTRACKS", "TRACKC":
+			result.TotalTracks = parseLeadingInt(value)
+		case "DISCTOTAL", "TOTALDISCS", "DISCC":
+			result.TotalDiscs = parseLeadingInt(value)
+		case originalDateKey, "ORIGINAL RELEASE DATE":
+			originalDate = value
+		case "ORIGINALYEAR":
+			originalYear = value
+		case originalFilenameVorbisKey:
+			addCustomTag(result, model.OriginalFilenameTag, value)
+		case "LYRICS", "UNSYNCEDLYRICS":
+			result.Lyrics = value
+		case "SYNCEDLYRICS":
+			result.SyncedLyrics = value
+		default:
+			if !standardVorbisKeys[strings.ToUpper(key)] && !addVorbisChapterComment(chapters, key, value) {
+				addCustomTag(result, key, value)
+			}
+		}
+	}
+	result.Chapters = vorbisChapters(chapters)
+	if originalDate == "" {
+		originalDate = originalYear
+	}
+	if originalDate != "" {
+		addCustomTag(result, originalDateKey, originalDate)
+	}
+}
+
+func vorbisCommentValue(vorbisComment *flacvorbis.MetaDataBlockVorbisComment, key string) string {
+	for _, comment := range vorbisComment.Comments {
+		if name, value, _ := strings.Cut(comment, "="); strings.EqualFold(name, key) {
+			return value
+		}
+	}
+	return ""
+}
+
+func setVorbisComment(vorbisComment *flacvorbis.MetaDataBlockVorbisComment, key, value string) {
+	setVorbisText(vorbisComment, key, value, false)
+}
+
+func setVorbisText(vorbisComment *flacvorbis.MetaDataBlockVorbisComment, key, value string, keepEmpty bool) {
+	removeVorbisComments(vorbisComment, key)
+	if value != "" || keepEmpty {
+		vorbisComment.Comments = append(vorbisComment.Comments, key+"="+value)
+	}
+}
+
+func splitVorbisTotal(vorbisComment *flacvorbis.MetaDataBlockVorbisComment, numberKey string, totalNames []string) {
+	aliases := vorbisAliases.totalTracks
+	if numberKey == "DISCNUMBER" {
+		aliases = vorbisAliases.totalDiscs
+	}
+	for _, key := range aliases {
+		if vorbisCommentValue(vorbisComment, key) != "" {
+			return
 		}
 	}
+	if _, total := splitNumberPair(vorbisCommentValue(vorbisComment, numberKey)); total > 0 {
+		setVorbisNames(vorbisComment, aliases, totalNames, formatPositive(total), false)
+	}
+}
 
-	return 0, fmt.Errorf("could not determine OGG duration")
+func setVorbisCustomTag(vorbisComment *flacvorbis.MetaDataBlockVorbisComment, key, value string) {
+	if key == model.OriginalFilenameTag {
+		removeVorbisComments(vorbisComment, key)
+		key = originalFilenameVorbisKey
+	}
+	if !isMultiValuedID(key) {
+		setVorbisComment(vorbisComment, key, value)
+		return
+	}
+	removeVorbisComments(vorbisComment, key)
+	for _, id := range splitCustomTagValues(value) {
+		vorbisComment.Comments = append(vorbisComment.Comments, key+"="+id)
+	}
 }
 
-func (h *oggHandler) UpdateTags(string, *string, *string, *string, *int, *int, *string, *string) error {
-	return fmt.Errorf("tag writing not yet supported for format: OGG")
+func removeVorbisComments(vorbisComment *flacvorbis.MetaDataBlockVorbisComment, keys ...string) {
+	comments := vorbisComment.Comments[:0]
+	for _, comment := range vorbisComment.Comments {
+		name, _, _ := strings.Cut(comment, "=")
+		keep := true
+		for _, key := range keys {
+			if strings.EqualFold(name, key) {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			comments = append(comments, comment)
+		}
+	}
+	vorbisComment.Comments = comments
 }
 
 func getOGGHandler(ext string) FormatHandler {
 	ext = strings.ToUpper(ext)
-	if ext == "OGG" || ext == "OGV" || ext == "OPUS" {
+	if ext == "OGG" || ext == "OGV" || ext == "OGA" {
 		return newOGGHandler()
 	}
 	return nil
@@ -69,7 +505,7 @@ func getOGGHandler(ext string) FormatHandler {
 
 func getOGGHandlerByFileType(fileType tag.FileType) FormatHandler {
 	fileTypeStr := string(fileType)
-	if fileTypeStr == "OGG" || fileTypeStr == "OGV" || fileTypeStr == "OPUS" {
+	if fileTypeStr == "OGG" || fileTypeStr == "OGV" {
 		return newOGGHandler()
 	}
 	return nil
@@ -0,0 +1,352 @@
+package audio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/vfs"
+)
+
+const (
+	oggPageHeaderSize = 27
+	oggMaxSegments    = 255
+	oggMaxPageSize    = oggPageHeaderSize + oggMaxSegments + oggMaxSegments*255
+
+	oggHeaderTypeContinued = 0x01
+	oggHeaderTypeBOS       = 0x02
+)
+
+type oggPage struct {
+	headerType byte
+	granule    uint64
+	serial     uint32
+	sequence   uint32
+	segments   []byte
+	data       []byte
+}
+
+var oggCRCTable = func() [256]uint32 {
+	var table [256]uint32
+	for i := range table {
+		r := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if r&0x80000000 != 0 {
+				r = r<<1 ^ 0x04C11DB7
+			} else {
+				r <<= 1
+			}
+		}
+		table[i] = r
+	}
+	return table
+}()
+
+func oggChecksum(data []byte) uint32 {
+	var crc uint32
+	for _, b := range data {
+		crc = crc<<8 ^ oggCRCTable[byte(crc>>24)^b]
+	}
+	return crc
+}
+
+func readOggPage(r io.Reader) (*oggPage, error) {
+	header := make([]byte, oggPageHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if string(header[0:4]) != "OggS" {
+		return nil, fmt.Errorf("invalid Ogg page capture pattern")
+	}
+	if header[4] != 0 {
+		return nil, fmt.Errorf("unsupported Ogg stream structure version: %d", header[4])
+	}
+
+	page := &oggPage{
+		headerType: header[5],
+		granule:    binary.LittleEndian.Uint64(header[6:14]),
+		serial:     binary.LittleEndian.Uint32(header[14:18]),
+		sequence:   binary.LittleEndian.Uint32(header[18:22]),
+		segments:   make([]byte, header[26]),
+	}
+	checksum := binary.LittleEndian.Uint32(header[22:26])
+
+	if _, err := io.ReadFull(r, page.segments); err != nil {
+		return nil, fmt.Errorf("failed to read Ogg segment table: %w", unexpectedEOF(err))
+	}
+
+	size := 0
+	for _, lacing := range page.segments {
+		size += int(lacing)
+	}
+	page.data = make([]byte, size)
+	if _, err := io.ReadFull(r, page.data); err != nil {
+		return nil, fmt.Errorf("failed to read Ogg page data: %w", unexpectedEOF(err))
+	}
+
+	if oggChecksum(page.marshalWithoutChecksum()) != checksum {
+		return nil, fmt.Errorf("Ogg page %d checksum mismatch", page.sequence)
+	}
+
+	return page, nil
+}
+
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func (p *oggPage) marshalWithoutChecksum() []byte {
+	buf := make([]byte, oggPageHeaderSize+len(p.segments)+len(p.data))
+	copy(buf[0:4], "OggS")
+	buf[5] = p.headerType
+	binary.LittleEndian.PutUint64(buf[6:14], p.granule)
+	binary.LittleEndian.PutUint32(buf[14:18], p.serial)
+	binary.LittleEndian.PutUint32(buf[18:22], p.sequence)
+	buf[26] = byte(len(p.segments))
+	copy(buf[oggPageHeaderSize:], p.segments)
+	copy(buf[oggPageHeaderSize+len(p.segments):], p.data)
+	return buf
+}
+
+func (p *oggPage) marshal() []byte {
+	buf := p.marshalWithoutChecksum()
+	binary.LittleEndian.PutUint32(buf[22:26], oggChecksum(buf))
+	return buf
+}
+
+func lastOggGranule(r io.ReaderAt, size int64, serial uint32) (uint64, error) {
+	const window = 4 * oggMaxPageSize
+	buf := make([]byte, window)
+	for end := size; ; end -= window - oggMaxPageSize {
+		start := max(end-window, 0)
+		buf := buf[:end-start]
+		if _, err := r.ReadAt(buf, start); err != nil && !errors.Is(err, io.EOF) {
+			return 0, fmt.Errorf("failed to read Ogg file tail: %w", err)
+		}
+
+		for i := len(buf) - oggPageHeaderSize; i >= 0; i-- {
+			if string(buf[i:i+4]) != "OggS" {
+				continue
+			}
+			page, err := readOggPage(bytes.NewReader(buf[i:]))
+			if err != nil || page.serial != serial || page.granule == ^uint64(0) {
+				continue
+			}
+			return page.granule, nil
+		}
+
+		if start == 0 {
+			break
+		}
+	}
+
+	return 0, fmt.Errorf("no Ogg page with a granule position found")
+}
+
+func readOggHeaderPacket(r io.Reader, index int) ([]byte, error) {
+	var current []byte
+	var serial uint32
+	for pageIndex, packets := 0, 0; ; pageIndex++ {
+		page, err := readOggPage(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Ogg header page: %w", unexpectedEOF(err))
+		}
+		if pageIndex == 0 {
+			serial = page.serial
+		} else if page.serial != serial {
+			continue
+		}
+
+		offset := 0
+		for _, lacing := range page.segments {
+			current = append(current, page.data[offset:offset+int(lacing)]...)
+			offset += int(lacing)
+			if lacing < 255 {
+				if packets == index {
+					return current, nil
+				}
+				packets++
+				current = nil
+			}
+		}
+	}
+}
+
+func oggHeaderSize(r io.ReaderAt, size int64, headerPackets int) (int64, error) {
+	reader := bufio.NewReader(io.NewSectionReader(r, 0, size))
+	var serial uint32
+	offset := int64(0)
+	packets := 0
+	for pageIndex := 0; packets < headerPackets; pageIndex++ {
+		page, err := readOggPage(reader)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read Ogg header page: %w", unexpectedEOF(err))
+		}
+		offset += int64(oggPageHeaderSize + len(page.segments) + len(page.data))
+		if pageIndex == 0 {
+			serial = page.serial
+		} else if page.serial != serial {
+			continue
+		}
+		for _, lacing := range page.segments {
+			if lacing < 255 {
+				packets++
+			}
+		}
+	}
+	return offset, nil
+}
+
+func paginateOggPackets(packets [][]byte, serial uint32, firstSequence uint32) []*oggPage {
+	var pages []*oggPage
+	page := &oggPage{serial: serial, sequence: firstSequence}
+	packetEnded := false
+
+	flush := func(continued bool) {
+		if !packetEnded {
+			page.granule = ^uint64(0)
+		}
+		pages = append(pages, page)
+		page = &oggPage{serial: serial, sequence: page.sequence + 1}
+		if continued {
+			page.headerType = oggHeaderTypeContinued
+		}
+		packetEnded = false
+	}
+
+	for _, packet := range packets {
+		remaining := packet
+		for {
+			n := min(len(remaining), 255)
+			page.segments = append(page.segments, byte(n))
+			page.data = append(page.data, remaining[:n]...)
+			remaining = remaining[n:]
+
+			done := n < 255
+			if done {
+				packetEnded = true
+			}
+			if len(page.segments) == oggMaxSegments {
+				flush(!done)
+			}
+			if done {
+				break
+			}
+		}
+	}
+	if len(page.segments) > 0 {
+		flush(false)
+	}
+
+	return pages
+}
+
+func rewriteOggHeaderPacket(
+	fsys vfs.FS, filePath string, headerPacketCount int, rewrite func([]byte) ([]byte, error),
+) error {
+	src, err := fsys.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open Ogg file: %w", err)
+	}
+	defer src.Close()
+
+	reader := bufio.NewReader(src)
+
+	var packets [][]byte
+	var current []byte
+	var serial uint32
+	headerPages := 0
+	for len(packets) < headerPacketCount {
+		page, err := readOggPage(reader)
+		if err != nil {
+			return fmt.Errorf("failed to read Ogg header page: %w", unexpectedEOF(err))
+		}
+		if headerPages == 0 {
+			serial = page.serial
+		} else if page.serial != serial {
+			return fmt.Errorf("multiplexed Ogg streams are not supported")
+		}
+		headerPages++
+
+		offset := 0
+		for _, lacing := range page.segments {
+			current = append(current, page.data[offset:offset+int(lacing)]...)
+			offset += int(lacing)
+			if lacing < 255 {
+				packets = append(packets, current)
+				current = nil
+			}
+		}
+	}
+	if len(packets) != headerPacketCount || len(current) > 0 {
+		return fmt.Errorf("Ogg header pages contain audio data")
+	}
+
+	commentPacket, err := rewrite(packets[1])
+	if err != nil {
+		return err
+	}
+	packets[1] = commentPacket
+
+	newPages := paginateOggPackets(packets[:1], serial, 0)
+	newPages[0].headerType |= oggHeaderTypeBOS
+	newPages = append(newPages, paginateOggPackets(packets[1:], serial, uint32(len(newPages)))...)
+	sequenceShift := len(newPages) - headerPages
+
+	tempFile := filePath + ".tmp"
+	dst, err := vfs.Create(fsys, tempFile)
+	if err != nil {
+		return fmt.Errorf("failed to create temp Ogg file: %w", err)
+	}
+	defer fsys.Remove(tempFile)
+	defer dst.Close()
+
+	writer := bufio.NewWriter(dst)
+	for _, page := range newPages {
+		if _, err := writer.Write(page.marshal()); err != nil {
+			return fmt.Errorf("failed to write Ogg header page: %w", err)
+		}
+	}
+
+	if sequenceShift == 0 {
+		if _, err := io.Copy(writer, reader); err != nil {
+			return fmt.Errorf("failed to copy Ogg audio pages: %w", err)
+		}
+	} else {
+		for {
+			page, err := readOggPage(reader)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read Ogg audio page: %w", err)
+			}
+			if page.serial == serial {
+				page.sequence = uint32(int(page.sequence) + sequenceShift)
+			}
+			if _, err := writer.Write(page.marshal()); err != nil {
+				return fmt.Errorf("failed to write Ogg audio page: %w", err)
+			}
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to flush temp Ogg file: %w", err)
+	}
+	if err := dst.Close(); err != nil {
+		return fmt.Errorf("failed to close temp Ogg file: %w", err)
+	}
+	src.Close()
+
+	if err := fsys.Rename(tempFile, filePath); err != nil {
+		return fmt.Errorf("failed to rename temp file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package audio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"path/filepath"
+	"testing"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/internal/vfs"
+)
+
+func testOpus(extra []byte) []byte {
+	head := []byte("OpusHead\x01\x02\x38\x01\x80\xbb\x00\x00\x00\x00\x00")
+
+	tags := []byte("OpusTags")
+	tags = binary.LittleEndian.AppendUint32(tags, 4)
+	tags = append(tags, "test"...)
+	tags = binary.LittleEndian.AppendUint32(tags, 1)
+	tags = binary.LittleEndian.AppendUint32(tags, uint32(len("TITLE=Old")))
+	tags = append(tags, "TITLE=Old"...)
+	tags = append(tags, extra...)
+
+	var pages []*oggPage
+	pages = append(pages, paginateOggPackets([][]byte{head}, 1, 0)...)
+	pages[0].headerType = oggHeaderTypeBOS
+	pages = append(pages, paginateOggPackets([][]byte{tags}, 1, 1)...)
+	audio := paginateOggPackets([][]byte{{0xfc, 0xff, 0xfe}}, 1, uint32(len(pages)))
+	audio[0].granule = 960
+	pages = append(pages, audio...)
+
+	var data []byte
+	for _, page := range pages {
+		data = append(data, page.marshal()...)
+	}
+	return data
+}
+
+func TestUpdateOpusTagsKeepsTrailingData(t *testing.T) {
+	tests := []struct {
+		name  string
+		extra []byte
+	}{
+		{name: "no extra data"},
+		{name: "binary data", extra: []byte{0x01, 0xde, 0xad, 0xbe, 0xef}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := vfs.NewMem()
+			path := filepath.FromSlash("/music/song.opus")
+			if err := vfs.WriteFile(fsys, path, testOpus(tt.extra), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			title := "New"
+			if err := updateOggComments(fsys, path, opusCommentCodec, &model.TagUpdate{Title: &title}); err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := vfs.ReadFile(fsys, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			packet, err := readOggHeaderPacket(bufio.NewReader(bytes.NewReader(data)), 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			comments, err := readOggComments(bytes.NewReader(data), int64(len(data)), opusCommentCodec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(comments) != 1 || comments[0] != "TITLE=New" {
+				t.Errorf("comments = %q, want the new title", comments)
+			}
+			if !bytes.HasSuffix(packet, append([]byte("TITLE=New"), tt.extra...)) {
+				t.Errorf("OpusTags packet = %q, want the comments followed by %q", packet, tt.extra)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package audio
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/dhowden/tag"
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/internal/vfs"
+)
+
+const opusGranuleRate = 48000
+
+var opusCommentCodec = oggCommentCodec{
+	name:          "Opus",
+	prefix:        []byte("OpusTags"),
+	headerPackets: 2,
+}
+
+type opusHandler struct{}
+
+func newOPUSHandler() *opusHandler {
+	return &opusHandler{}
+}
+
+func (h *opusHandler) Format() string {
+	return "OPUS"
+}
+
+func (h *opusHandler) Capabilities() model.Capabilities {
+	return fullCapabilities
+}
+
+func (h *opusHandler) ExtractDuration(ctx context.Context, r io.ReaderAt, size int64) (float64, error) {
+	firstPage, err := readOggPage(bufio.NewReader(io.NewSectionReader(r, 0, size)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read Opus header page: %w", unexpectedEOF(err))
+	}
+	if len(firstPage.data) < 19 || !bytes.HasPrefix(firstPage.data, []byte("OpusHead")) {
+		return 0, fmt.Errorf("not a valid Opus file")
+	}
+	preSkip := uint64(binary.LittleEndian.Uint16(firstPage.data[10:12]))
+
+	granule, err := lastOggGranule(r, size, firstPage.serial)
+	if err != nil {
+		return 0, err
+	}
+	if granule <= preSkip {
+		return 0, fmt.Errorf("could not determine Opus duration")
+	}
+
+	return float64(granule-preSkip) / opusGranuleRate, nil
+}
+
+func (h *opusHandler) ExtractAudioInfo(r io.ReaderAt, size int64, result *model.FileMetadata) error {
+	firstPage, err := readOggPage(bufio.NewReader(io.NewSectionReader(r, 0, size)))
+	if err != nil {
+		return fmt.Errorf("failed to read Opus header page: %w", unexpectedEOF(err))
+	}
+	if len(firstPage.data) < 19 || !bytes.HasPrefix(firstPage.data, []byte("OpusHead")) {
+		return fmt.Errorf("not a valid Opus file")
+	}
+
+	result.Codec = "Opus"
+	result.Channels = int(firstPage.data[9])
+	result.SampleRate = opusGranuleRate
+	if headerSize, err := oggHeaderSize(r, size, opusCommentCodec.headerPackets); err == nil {
+		result.Bitrate = averageBitrate(size-headerSize, result.Duration)
+	}
+	return nil
+}
+
+func (h *opusHandler) UpdateTags(ctx context.Context, fsys vfs.FS, filePath string, update *model.TagUpdate) error {
+	return updateOggComments(fsys, filePath, opusCommentCodec, update)
+}
+
+func getOPUSHandler(ext string) FormatHandler {
+	ext = strings.ToUpper(ext)
+	if ext == "OPUS" {
+		return newOPUSHandler()
+	}
+	return nil
+}
+
+func getOPUSHandlerByFileType(fileType tag.FileType) FormatHandler {
+	if string(fileType) == "OPUS" {
+		return newOPUSHandler()
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package audio
+
+import "io/fs"
+
+// Files have no Unix owner to keep on other platforms.
+
+func canChown(fs.FileInfo) bool {
+	return true
+}
+
+func chownLike(string, fs.FileInfo) error {
+	return nil
+}
@@ -0,0 +1,37 @@
+//go:build unix
+
+package audio
+
+import (
+	"io/fs"
+	"os"
+	"slices"
+	"syscall"
+)
+
+func canChown(stat fs.FileInfo) bool {
+	owner, ok := stat.Sys().(*syscall.Stat_t)
+	if !ok {
+		return true
+	}
+	uid := os.Getuid()
+	if uid == 0 {
+		return true
+	}
+	if int(owner.Uid) != uid {
+		return false
+	}
+	if int(owner.Gid) == os.Getgid() {
+		return true
+	}
+	groups, err := os.Getgroups()
+	return err == nil && slices.Contains(groups, int(owner.Gid))
+}
+
+func chownLike(path string, stat fs.FileInfo) error {
+	owner, ok := stat.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	return os.Lchown(path, int(owner.Uid), int(owner.Gid))
+}
@@ -0,0 +1,102 @@
+package audio
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+)
+
+const parseFingerprintSize = 64 * 1024
+
+type parseCache struct {
+	mu      sync.Mutex
+	limit   int
+	entries map[string]*list.Element
+	order   *list.List // most recently used first
+}
+
+type parsedFile struct {
+	path        string
+	size        int64
+	modTime     time.Time
+	fingerprint [sha256.Size]byte
+	metadata    *model.FileMetadata
+}
+
+func newParseCache(limit int) *parseCache {
+	if limit <= 0 {
+		return nil
+	}
+	return &parseCache{limit: limit, entries: make(map[string]*list.Element), order: list.New()}
+}
+
+func (c *parseCache) get(key parsedFile) (*model.FileMetadata, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key.path]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*parsedFile)
+	if entry.size != key.size || !entry.modTime.Equal(key.modTime) || entry.fingerprint != key.fingerprint {
+		c.order.Remove(element)
+		delete(c.entries, key.path)
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return entry.metadata.Clone(), true
+}
+
+func (c *parseCache) put(key parsedFile, metadata *model.FileMetadata) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key.path]; ok {
+		c.order.Remove(element)
+	}
+	key.metadata = metadata.Clone()
+	c.entries[key.path] = c.order.PushFront(&key)
+	for c.order.Len() > c.limit {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*parsedFile).path)
+	}
+}
+
+func (c *parseCache) forget(path string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[path]; ok {
+		c.order.Remove(element)
+		delete(c.entries, path)
+	}
+}
+
+func fingerprintFile(r io.ReaderAt, size int64) ([sha256.Size]byte, error) {
+	hash := sha256.New()
+	head := min(size, parseFingerprintSize)
+	tail := min(size-head, parseFingerprintSize)
+	for _, section := range []*io.SectionReader{
+		io.NewSectionReader(r, 0, head), io.NewSectionReader(r, size-tail, tail),
+	} {
+		if _, err := io.Copy(hash, section); err != nil && !errors.Is(err, io.EOF) {
+			return [sha256.Size]byte{}, err
+		}
+	}
+	var sum [sha256.Size]byte
+	hash.Sum(sum[:0])
+	return sum, nil
+}
@@ -1,18 +1,20 @@
 package audio
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
 	"fmt"
 	"io"
 	"log/slog"
-	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/dhowden/tag"
 	"github.com/iamvkosarev/audio-tag-editor/internal/model"
-	"github.com/iamvkosarev/audio-tag-editor/pkg/logs"
+	"github.com/iamvkosarev/audio-tag-editor/internal/vfs"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func extractMetadata(metadata tag.Metadata, filename string, size int64) *model.FileMetadata {
@@ -41,6 +43,8 @@ func extractMetadata(metadata tag.Metadata, filename string, size int64) *model.
 	disc, _ := metadata.Disc()
 	result.Disc = disc
 
+	extractExtendedMetadata(metadata, result)
+
 	picture := metadata.Picture()
 	if picture != nil && len(picture.Data) > 0 {
 		mimeType := picture.MIMEType
@@ -50,10 +54,95 @@ func extractMetadata(metadata tag.Metadata, filename string, size int64) *model.
 		base64Data := base64.StdEncoding.EncodeToString(picture.Data)
 		result.CoverArt = fmt.Sprintf("data:%s;base64,%s", mimeType, base64Data)
 	}
+	result.Pictures = extractRawID3Pictures(metadata.Raw())
 
 	return result
 }
 
+func extractExtendedMetadata(metadata tag.Metadata, result *model.FileMetadata) {
+	raw := metadata.Raw()
+
+	if metadata.Format() == tag.VORBIS {
+		comments := make([]string, 0, len(raw))
+		for key, value := range raw {
+			if text, ok := value.(string); ok {
+				comments = append(comments, key+"="+text)
+			}
+		}
+		extractExtendedVorbisMetadata(comments, result)
+		return
+	}
+
+	result.AlbumArtist = metadata.AlbumArtist()
+	result.Composer = metadata.Composer()
+	result.Comment = metadata.Comment()
+	result.Lyrics = metadata.Lyrics()
+	_, result.TotalTracks = metadata.Track()
+	_, result.TotalDiscs = metadata.Disc()
+	result.ReleaseDate = id3ReleaseDate(
+		func(id string) string {
+			value, _ := raw[id].(string)
+			return value
+		},
+	)
+	if day, ok := raw["\xa9day"].(string); ok && result.ReleaseDate == "" {
+		result.ReleaseDate = normalizeReleaseDate(day)
+	}
+
+	// ID3v2.2 has no sort frames, but iTunes writes them with these IDs.
+	for _, sortField := range []struct {
+		keys  []string
+		value *string
+	}{
+		{[]string{"TSOT", "TST"}, &result.TitleSort},
+		{[]string{"TSOP", "TSP"}, &result.ArtistSort},
+		{[]string{"TSO2", "TS2"}, &result.AlbumArtistSort},
+		{[]string{"TSOA", "TSA"}, &result.AlbumSort},
+	} {
+		for _, key := range sortField.keys {
+			if value, ok := raw[key].(string); ok {
+				*sortField.value = strings.TrimSpace(value)
+			}
+		}
+	}
+	for _, key := range []string{"TSRC", "TRC"} {
+		if value, ok := raw[key].(string); ok {
+			result.ISRC = strings.TrimSpace(value)
+		}
+	}
+	for _, key := range []string{"TPUB", "TPB"} {
+		if value, ok := raw[key].(string); ok {
+			result.Label = strings.TrimSpace(value)
+		}
+	}
+	for _, key := range []string{"TBPM", "TBP"} {
+		if value, ok := raw[key].(string); ok {
+			result.BPM = parseLeadingInt(value)
+		}
+	}
+	for _, key := range []string{"TCMP", "TCP"} {
+		if value, ok := raw[key].(string); ok {
+			result.Compilation = strings.TrimSpace(value) == "1"
+		}
+	}
+	readRawID3Popularimeter(raw, result)
+	extractRawID3CustomTags(raw, result)
+	// tag returns the first COMM frame as the comment, which may be iTunes data instead.
+	comment, smpb, ok := id3Comments(raw)
+	if ok {
+		result.Comment = comment
+	}
+	if smpb == "" {
+		smpb = result.CustomTags[strings.ToUpper(iTunSMPB)]
+	}
+	result.Gapless = parseITunSMPB(smpb)
+	if body, ok := raw["SYLT"].([]byte); ok {
+		if lrc, err := decodeSYLT(body); err == nil {
+			result.SyncedLyrics = lrc
+		}
+	}
+}
+
 func getFormat(fileType tag.FileType) string {
 	fileTypeStr := string(fileType)
 	switch fileTypeStr {
@@ -61,8 +150,10 @@ func getFormat(fileType tag.FileType) string {
 		return "MP3"
 	case "FLAC":
 		return "FLAC"
-	case "OGG", "OGV", "OPUS":
+	case "OGG", "OGV":
 		return "OGG"
+	case "OPUS":
+		return "OPUS"
 	default:
 		if fileTypeStr == "" {
 			return "UNKNOWN"
@@ -71,132 +162,97 @@ func getFormat(fileType tag.FileType) string {
 	}
 }
 
-func parseFileWithTag(filePath string) (*model.FileMetadata, error) {
-	file, err := openFile(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open FLAC file: %w", err)
-	}
-	defer file.Close()
+type metadataParser interface {
+	Parse(r io.ReaderAt, size int64, name string) (*model.FileMetadata, error)
+}
 
-	stat, err := file.Stat()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get file stats: %w", err)
-	}
+type parseOptions struct {
+	exactMP3Duration bool
+}
 
-	contentFormat, _ := detectFormatFromContent(file)
+func parseReader(ctx context.Context, r io.ReaderAt, size int64, name string, opts parseOptions) (*model.FileMetadata, error) {
+	ext := strings.ToUpper(strings.TrimPrefix(filepath.Ext(name), "."))
 
-	detectedFormat := contentFormat
+	detectedFormat, _ := detectFormatFromContent(r)
 	if detectedFormat == "" {
-		detectedFormat = detectFormatFromFilePath(filePath)
+		detectedFormat = ext
 	}
-	if detectedFormat == "" {
-		detectedFormat = strings.ToUpper(strings.TrimPrefix(filepath.Ext(stat.Name()), "."))
-	}
-
-	if detectedFormat == "FLAC" {
-		handler := getFLACHandler("FLAC")
-		if flacHandler, ok := handler.(*flacHandler); ok {
-			var flacResult *model.FileMetadata
-			var flacErr error
-			func() {
-				defer func() {
-					if r := recover(); r != nil {
-						logs.Panic(context.Background(), "parseFileWithTag: audiometa panicked, falling back to tag library", r, slog.String("filePath", filePath))
-						flacErr = fmt.Errorf("audiometa panic: %v", r)
-					}
-				}()
-				flacResult, flacErr = flacHandler.ParseWithAudiometa(filePath)
-			}()
-			if flacErr == nil && flacResult != nil {
-				return flacResult, nil
-			}
+
+	switch handler := getFormatHandlerByExtension(detectedFormat).(type) {
+	case *flacHandler:
+		result, err := handler.Parse(r, size, name)
+		if err == nil {
+			return result, nil
 		}
+		slog.WarnContext(ctx, "parseReader: failed to read FLAC metadata blocks, falling back to tag library", slog.String("name", name), slog.Any("error", err))
+	case metadataParser:
+		return handler.Parse(r, size, name)
 	}
 
-	_, err = file.Seek(0, 0)
-	if err != nil {
-		return nil, fmt.Errorf("failed to rewind file: %w", err)
-	}
-	metadata, err := tag.ReadFrom(file)
+	metadata, err := tag.ReadFrom(io.NewSectionReader(r, 0, size))
 	if err != nil {
 		return &model.FileMetadata{
-			Title:    stat.Name(),
+			Title:    name,
 			Duration: 0,
-			Size:     stat.Size(),
+			Size:     size,
 			Format:   detectedFormat,
 		}, fmt.Errorf("failed to read tags from file: %w", err)
 	}
 
-	result := extractMetadata(metadata, stat.Name(), stat.Size())
+	result := extractMetadata(metadata, name, size)
 	tagFormat := getFormat(metadata.FileType())
 
-	if detectedFormat != "" && detectedFormat != "UNKNOWN" {
+	switch {
+	case detectedFormat != "" && detectedFormat != "UNKNOWN":
 		result.Format = detectedFormat
-		return result, nil
-	}
-
-	ext := strings.ToUpper(strings.TrimPrefix(filepath.Ext(stat.Name()), "."))
-	if ext != "" {
-		result.Format = ext
-		return result, nil
-	}
-
-	if tagFormat != "UNKNOWN" && tagFormat != "" {
+	case tagFormat != "UNKNOWN" && tagFormat != "":
 		result.Format = tagFormat
-		return result, nil
+	default:
+		result.Format = "UNKNOWN"
 	}
 
-	result.Format = "UNKNOWN"
-	return result, nil
-}
-
-func parseReaderWithTag(reader io.ReadSeeker, filename string, size int64) (*model.FileMetadata, error) {
-	contentFormat, _ := detectFormatFromReader(reader)
-
-	_, err := reader.Seek(0, 0)
-	if err != nil {
-		return nil, fmt.Errorf("failed to seek to start of file: %w", err)
+	if result.Format == "MP3" {
+		result.Chapters = readID3Chapters(r, size)
 	}
-	metadata, err := tag.ReadFrom(reader)
-	if err != nil {
-		detectedFormat := contentFormat
-		if detectedFormat == "" {
-			detectedFormat = strings.ToUpper(strings.TrimPrefix(filepath.Ext(filename), "."))
+	if codec, ok := oggCommentCodecs[result.Format]; ok {
+		if comments, err := readOggComments(r, size, codec); err == nil {
+			result.Pictures = extractVorbisPictures(comments)
+			result.CustomTags = nil
+			extractExtendedVorbisMetadata(comments, result)
 		}
-		return &model.FileMetadata{
-			Title:    filename,
-			Duration: 0,
-			Size:     size,
-			Format:   detectedFormat,
-		}, fmt.Errorf("failed to read tags from reader: %w", err)
 	}
 
-	result := extractMetadata(metadata, filename, size)
-
-	detectedFormat := contentFormat
-	if detectedFormat == "" {
-		tagFormat := getFormat(metadata.FileType())
-		if tagFormat != "UNKNOWN" && tagFormat != "" {
-			detectedFormat = tagFormat
-		}
+	handler := getFormatHandlerByExtension(result.Format)
+	if handler == nil {
+		handler = getFormatHandlerByFileType(metadata.FileType())
 	}
-
-	if detectedFormat == "" {
-		detectedFormat = strings.ToUpper(strings.TrimPrefix(filepath.Ext(filename), "."))
+	if mp3, ok := handler.(*mp3Handler); ok {
+		mp3.exactDuration = opts.exactMP3Duration
+	}
+	if handler != nil {
+		duration, err := extractDuration(ctx, handler, r, size)
+		if err == nil && duration > 0 {
+			result.Duration = duration
+		}
 	}
-
-	result.Format = detectedFormat
 
 	return result, nil
 }
 
-func openFile(filePath string) (*os.File, error) {
-	return os.Open(filePath)
+func extractDuration(ctx context.Context, handler FormatHandler, r io.ReaderAt, size int64) (float64, error) {
+	ctx, span := tracer.Start(
+		ctx, "audio.ExtractDuration", trace.WithAttributes(attribute.String("audio.format", handler.Format())),
+	)
+	defer span.End()
+
+	duration, err := handler.ExtractDuration(ctx, r, size)
+	recordError(span, err)
+	return duration, err
 }
 
-func detectFormatFromContent(file *os.File) (string, error) {
+func detectFormatFromContent(r io.ReaderAt) (string, error) {
 	header := make([]byte, 4096)
-	n, err := file.ReadAt(header, 0)
+	n, err := r.ReadAt(header, 0)
 	if err != nil && n < 4 {
 		return "", fmt.Errorf("failed to read file header: %w", err)
 	}
@@ -204,22 +260,21 @@ func detectFormatFromContent(file *os.File) (string, error) {
 		return "", fmt.Errorf("file too small")
 	}
 
+	// FLAC, APE, Musepack, TTA and TAK streams sometimes carry an ID3v2 tag in front.
 	if n >= 10 && string(header[0:3]) == "ID3" {
 		id3Size := int(header[6])<<21 | int(header[7])<<14 | int(header[8])<<7 | int(header[9])
-		flacOffset := 10 + id3Size
+		streamOffset := 10 + id3Size
 
-		if flacOffset > n {
-			flacHeader := make([]byte, 4)
-			readN, readErr := file.ReadAt(flacHeader, int64(flacOffset))
+		if streamOffset > n {
+			streamHeader := make([]byte, 4)
+			readN, readErr := r.ReadAt(streamHeader, int64(streamOffset))
 			if readErr == nil && readN == 4 {
-				if string(flacHeader) == "fLaC" {
-					return "FLAC", nil
+				if format := formatFromMagic(streamHeader); format != "" {
+					return format, nil
 				}
 			}
-		} else {
-			if flacOffset+4 <= n && string(header[flacOffset:flacOffset+4]) == "fLaC" {
-				return "FLAC", nil
-			}
+		} else if format := formatFromMagic(header[streamOffset:n]); format != "" {
+			return format, nil
 		}
 	}
 
@@ -230,29 +285,31 @@ func detectFormatFromContent(file *os.File) (string, error) {
 	return format, nil
 }
 
-func detectFormatFromReader(reader io.ReadSeeker) (string, error) {
-	reader.Seek(0, 0)
-	header := make([]byte, 4096)
-	n, err := reader.Read(header)
-	if err != nil && err != io.EOF {
-		return "", fmt.Errorf("failed to read file header: %w", err)
-	}
-	if n < 4 {
-		return "", fmt.Errorf("file too small")
-	}
-	return detectFormatFromHeader(header, n)
-}
-
 func detectFormatFromHeader(header []byte, readLen int) (string, error) {
 	if readLen < 4 {
 		return "", fmt.Errorf("header too short")
 	}
 
+	if readLen >= 12 && string(header[0:4]) == "RIFF" && string(header[8:12]) == "WAVE" {
+		return "WAV", nil
+	}
+
+	if readLen >= 16 && asfGUID(header[0:16]) == asfHeaderObject {
+		return "WMA", nil
+	}
+
+	if format := formatFromMagic(header[:readLen]); format != "" {
+		return format, nil
+	}
+
 	for i := 0; i <= readLen-4; i++ {
 		if string(header[i:i+4]) == "fLaC" {
 			return "FLAC", nil
 		}
 		if string(header[i:i+4]) == "OggS" {
+			if bytes.Contains(header[i:min(i+64, readLen)], []byte("OpusHead")) {
+				return "OPUS", nil
+			}
 			return "OGG", nil
 		}
 	}
@@ -277,8 +334,31 @@ func detectFormatFromHeader(header []byte, readLen int) (string, error) {
 	return "", fmt.Errorf("unknown file format")
 }
 
-func detectFormatFromFilePath(filePath string) string {
-	file, err := os.Open(filePath)
+func formatFromMagic(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("fLaC")):
+		return "FLAC"
+	case bytes.HasPrefix(data, []byte("MAC ")):
+		return "APE"
+	case bytes.HasPrefix(data, []byte("MPCK")), bytes.HasPrefix(data, []byte("MP+")):
+		return "MPC"
+	case bytes.HasPrefix(data, []byte("wvpk")):
+		return "WV"
+	case bytes.HasPrefix(data, []byte("TTA1")):
+		return "TTA"
+	case bytes.HasPrefix(data, []byte("tBaK")):
+		return "TAK"
+	case bytes.HasPrefix(data, []byte("DSD ")):
+		return "DSF"
+	case bytes.HasPrefix(data, []byte("FRM8")):
+		return "DFF"
+	default:
+		return ""
+	}
+}
+
+func detectFormatFromFilePath(fsys vfs.FS, filePath string) string {
+	file, err := fsys.Open(filePath)
 	if err != nil {
 		return ""
 	}
@@ -0,0 +1,65 @@
+package audio
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/internal/vfs"
+)
+
+func (s *AudioService) checkWritable(filePath string) error {
+	stat, err := s.fs.Stat(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+	if stat.Mode().Perm()&0o200 == 0 {
+		return fmt.Errorf("%w: %s has no write permission", model.ErrReadOnly, filepath.Base(filePath))
+	}
+
+	file, err := s.fs.OpenFile(filePath, os.O_WRONLY, 0)
+	if err != nil {
+		if errors.Is(err, fs.ErrPermission) {
+			return fmt.Errorf("%w: %s cannot be opened for writing", model.ErrReadOnly, filepath.Base(filePath))
+		}
+		return fmt.Errorf("failed to open file for writing: %w", err)
+	}
+	file.Close()
+
+	// Rewrites replace the file with a copy created next to it.
+	probe, err := createHiddenTemp(s.fs, filePath)
+	if err != nil {
+		if errors.Is(err, fs.ErrPermission) {
+			return fmt.Errorf("%w: the folder of %s is not writable", model.ErrReadOnly, filepath.Base(filePath))
+		}
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	probe.Close()
+	s.fs.Remove(probe.Name())
+
+	if s.keepOwner && !canChown(stat) {
+		return fmt.Errorf("%w: the owner of %s could not be kept", model.ErrReadOnly, filepath.Base(filePath))
+	}
+	return nil
+}
+
+func (s *AudioService) restoreMode(tempPath string, stat fs.FileInfo) error {
+	if !s.keepOwner || !vfs.IsOS(s.fs) {
+		if err := s.fs.Chmod(tempPath, stat.Mode().Perm()); err != nil {
+			return fmt.Errorf("failed to restore file mode: %w", err)
+		}
+		return nil
+	}
+	// Changing the owner clears the setuid and setgid bits, so it goes first.
+	if err := chownLike(tempPath, stat); err != nil {
+		return fmt.Errorf("failed to restore file owner: %w", err)
+	}
+	mode := stat.Mode() & (fs.ModePerm | fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky)
+	if err := s.fs.Chmod(tempPath, mode); err != nil {
+		return fmt.Errorf("failed to restore file mode: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"strconv"
+	"strings"
+
+	"github.com/bogem/id3v2/v2"
+	"github.com/dhowden/tag"
+	"github.com/go-flac/flacpicture"
+	"github.com/go-flac/flacvorbis"
+	"github.com/go-flac/go-flac"
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+)
+
+var id3PictureTypes = [...]string{
+	"Other", "32x32 pixels 'file icon' (PNG only)", "Other file icon", "Cover (front)", "Cover (back)",
+	"Leaflet page", "Media (e.g. lable side of CD)", "Lead artist/lead performer/soloist", "Artist/performer",
+	"Conductor", "Band/Orchestra", "Composer", "Lyricist/text writer", "Recording Location", "During recording",
+	"During performance", "Movie/video screen capture", "A bright coloured fish", "Illustration",
+	"Band/artist logotype", "Publisher/Studio logotype",
+}
+
+func newPicture(pictureType int, description, mimeType string, data []byte) model.Picture {
+	if mimeType == "" {
+		mimeType = "image/jpeg"
+	}
+	picture := model.Picture{
+		Type:        pictureType,
+		TypeName:    model.PictureTypeName(pictureType),
+		Description: description,
+		MimeType:    normalizeMimeType(mimeType),
+		Size:        len(data),
+		Data:        data,
+	}
+	if config, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		picture.Width, picture.Height = config.Width, config.Height
+	}
+	return picture
+}
+
+func setCoverFromPictures(metadata *model.FileMetadata) {
+	if len(metadata.Pictures) == 0 {
+		return
+	}
+	cover := metadata.Pictures[0]
+	for _, picture := range metadata.Pictures {
+		if picture.Type == model.PictureTypeFrontCover {
+			cover = picture
+			break
+		}
+	}
+	metadata.CoverArt = cover.DataURI()
+}
+
+func extractRawID3Pictures(raw map[string]interface{}) []model.Picture {
+	var pictures []model.Picture
+	for _, name := range []string{"APIC", "PIC"} {
+		for i := 0; ; i++ {
+			key := name
+			if i > 0 {
+				key = name + "_" + strconv.Itoa(i-1)
+			}
+			value, ok := raw[key]
+			if !ok {
+				break
+			}
+			picture, ok := value.(*tag.Picture)
+			if !ok || len(picture.Data) == 0 {
+				continue
+			}
+			pictureType := model.PictureTypeOther
+			for index, typeName := range id3PictureTypes {
+				if typeName == picture.Type {
+					pictureType = index
+					break
+				}
+			}
+			pictures = append(pictures, newPicture(pictureType, picture.Description, picture.MIMEType, picture.Data))
+		}
+	}
+	return pictures
+}
+
+func extractID3Pictures(id3Tag *id3v2.Tag) []model.Picture {
+	var pictures []model.Picture
+	for _, frame := range id3Tag.GetFrames("APIC") {
+		picture, ok := frame.(id3v2.PictureFrame)
+		if !ok || len(picture.Picture) == 0 {
+			continue
+		}
+		pictures = append(pictures, newPicture(int(picture.PictureType), picture.Description, picture.MimeType, picture.Picture))
+	}
+	return pictures
+}
+
+func extractFLACPictures(blocks []*flac.MetaDataBlock) []model.Picture {
+	var pictures []model.Picture
+	for _, meta := range blocks {
+		if meta.Type != flac.Picture {
+			continue
+		}
+		picture, err := flacpicture.ParseFromMetaDataBlock(*meta)
+		if err != nil || len(picture.ImageData) == 0 {
+			continue
+		}
+		pictures = append(pictures, newPicture(int(picture.PictureType), picture.Description, picture.MIME, picture.ImageData))
+	}
+	return pictures
+}
+
+func extractVorbisPictures(comments []string) []model.Picture {
+	var pictures []model.Picture
+	for _, comment := range comments {
+		picture, ok := parseVorbisPicture(comment)
+		if !ok || len(picture.ImageData) == 0 {
+			continue
+		}
+		pictures = append(pictures, newPicture(int(picture.PictureType), picture.Description, picture.MIME, picture.ImageData))
+	}
+	return pictures
+}
+
+func parseVorbisPicture(comment string) (*flacpicture.MetadataBlockPicture, bool) {
+	name, value, _ := strings.Cut(comment, "=")
+	if !strings.EqualFold(name, "METADATA_BLOCK_PICTURE") {
+		return nil, false
+	}
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, false
+	}
+	picture, err := flacpicture.ParseFromMetaDataBlock(flac.MetaDataBlock{Type: flac.Picture, Data: data})
+	if err != nil {
+		return nil, false
+	}
+	return picture, true
+}
+
+func newPictureBlock(pictureType int, description, dataURI string) (flac.MetaDataBlock, error) {
+	data, mimeType, err := decodeCover(dataURI)
+	if err != nil {
+		return flac.MetaDataBlock{}, fmt.Errorf("failed to parse picture data: %w", err)
+	}
+	if len(data) == 0 {
+		return flac.MetaDataBlock{}, fmt.Errorf("picture data is empty")
+	}
+
+	picture := &flacpicture.MetadataBlockPicture{
+		PictureType: flacpicture.PictureType(pictureType),
+		MIME:        normalizeMimeType(mimeType),
+		Description: description,
+		ImageData:   data,
+	}
+	if config, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		picture.Width, picture.Height = uint32(config.Width), uint32(config.Height)
+		picture.ColorDepth = 24
+	}
+	return picture.Marshal(), nil
+}
+
+func applyID3Pictures(id3Tag *id3v2.Tag, pictures []model.PictureUpdate) error {
+	replaced := make(map[int]bool)
+	for _, update := range pictures {
+		if !replaced[update.Type] {
+			replaced[update.Type] = true
+			kept := make([]id3v2.Framer, 0)
+			for _, frame := range id3Tag.GetFrames("APIC") {
+				if picture, ok := frame.(id3v2.PictureFrame); ok && int(picture.PictureType) == update.Type {
+					continue
+				}
+				kept = append(kept, frame)
+			}
+			id3Tag.DeleteFrames("APIC")
+			for _, frame := range kept {
+				id3Tag.AddFrame("APIC", frame)
+			}
+		}
+
+		if update.Data == "" {
+			continue
+		}
+		data, mimeType, err := decodeCover(update.Data)
+		if err != nil {
+			return fmt.Errorf("failed to parse picture data: %w", err)
+		}
+		id3Tag.AddAttachedPicture(
+			id3v2.PictureFrame{
+				Encoding:    id3v2.EncodingUTF8,
+				MimeType:    normalizeMimeType(mimeType),
+				PictureType: byte(update.Type),
+				Description: update.Description,
+				Picture:     data,
+			},
+		)
+	}
+	return nil
+}
+
+func applyFLACPictures(blocks []*flac.MetaDataBlock, pictures []model.PictureUpdate) ([]*flac.MetaDataBlock, error) {
+	replaced := make(map[int]bool)
+	for _, update := range pictures {
+		if !replaced[update.Type] {
+			replaced[update.Type] = true
+			kept := make([]*flac.MetaDataBlock, 0, len(blocks)+1)
+			for _, meta := range blocks {
+				if meta.Type == flac.Picture {
+					if picture, err := flacpicture.ParseFromMetaDataBlock(*meta); err == nil && int(picture.PictureType) == update.Type {
+						continue
+					}
+				}
+				kept = append(kept, meta)
+			}
+			blocks = kept
+		}
+
+		if update.Data == "" {
+			continue
+		}
+		block, err := newPictureBlock(update.Type, update.Description, update.Data)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, &block)
+	}
+	return blocks, nil
+}
+
+type vorbisPicture struct {
+	pictureType int
+	block       string
+}
+
+func buildVorbisPictures(pictures []model.PictureUpdate) ([]vorbisPicture, error) {
+	encoded := make([]vorbisPicture, len(pictures))
+	for i, update := range pictures {
+		encoded[i].pictureType = update.Type
+		if update.Data == "" {
+			continue
+		}
+		block, err := newPictureBlock(update.Type, update.Description, update.Data)
+		if err != nil {
+			return nil, err
+		}
+		encoded[i].block = base64.StdEncoding.EncodeToString(block.Data)
+	}
+	return encoded, nil
+}
+
+func applyVorbisPictures(vorbisComment *flacvorbis.MetaDataBlockVorbisComment, pictures []vorbisPicture) {
+	replaced := make(map[int]bool)
+	for _, update := range pictures {
+		if !replaced[update.pictureType] {
+			replaced[update.pictureType] = true
+			comments := vorbisComment.Comments[:0]
+			for _, comment := range vorbisComment.Comments {
+				if picture, ok := parseVorbisPicture(comment); ok && int(picture.PictureType) == update.pictureType {
+					continue
+				}
+				comments = append(comments, comment)
+			}
+			vorbisComment.Comments = comments
+		}
+		if update.block != "" {
+			vorbisComment.Comments = append(vorbisComment.Comments, "METADATA_BLOCK_PICTURE="+update.block)
+		}
+	}
+}
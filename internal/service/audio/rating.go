@@ -0,0 +1,153 @@
+package audio
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/bogem/id3v2/v2"
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+)
+
+const popmEmail = "no@email"
+
+var popmStars = [...]byte{1, 64, 128, 196, 255}
+
+func ratingToPOPM(rating int) byte {
+	if rating <= 0 {
+		return 0
+	}
+	if rating%model.RatingPerStar == 0 && rating <= model.MaxRating {
+		return popmStars[rating/model.RatingPerStar-1]
+	}
+	value := int(math.Round(float64(rating) * 255 / model.MaxRating))
+	for _, candidate := range []int{value, value - 1, value + 1} {
+		if candidate > 0 && candidate < 255 && ratingFromPOPM(byte(candidate)) == rating {
+			return byte(candidate)
+		}
+	}
+	return byte(value)
+}
+
+func ratingFromPOPM(value byte) int {
+	for i, stars := range popmStars {
+		if value == stars {
+			return (i + 1) * model.RatingPerStar
+		}
+	}
+	return int(math.Round(float64(value) * model.MaxRating / 255))
+}
+
+func setID3Popularimeter(id3Tag *id3v2.Tag, rating, playCount *int) {
+	if rating == nil && playCount == nil {
+		return
+	}
+	var frames []id3v2.PopularimeterFrame
+	for _, frame := range id3Tag.GetFrames("POPM") {
+		if popm, ok := frame.(id3v2.PopularimeterFrame); ok {
+			frames = append(frames, popm)
+		}
+	}
+	id3Tag.DeleteFrames("POPM")
+	if len(frames) == 0 {
+		frames = append(frames, id3v2.PopularimeterFrame{Email: popmEmail})
+	}
+	for _, popm := range frames {
+		if rating != nil {
+			popm.Rating = ratingToPOPM(*rating)
+		}
+		if playCount != nil {
+			popm.Counter = big.NewInt(int64(*playCount))
+		}
+		if popm.Counter == nil {
+			popm.Counter = new(big.Int)
+		}
+		if popm.Rating != 0 || popm.Counter.Sign() != 0 {
+			id3Tag.AddFrame("POPM", popm)
+		}
+	}
+	if playCount != nil {
+		// The count now lives in POPM; a stale PCNT would contradict it.
+		id3Tag.DeleteFrames("PCNT")
+	}
+}
+
+func decodePOPM(body []byte) (rating byte, count int, ok bool) {
+	end := strings.IndexByte(string(body), 0)
+	if end < 0 || end+1 >= len(body) {
+		return 0, 0, false
+	}
+	return body[end+1], decodePlayCounter(body[end+2:]), true
+}
+
+func decodePlayCounter(body []byte) int {
+	counter := new(big.Int).SetBytes(body)
+	if !counter.IsInt64() || counter.Int64() > math.MaxInt32 {
+		return math.MaxInt32
+	}
+	return int(counter.Int64())
+}
+
+func readRawID3Popularimeter(raw map[string]interface{}, result *model.FileMetadata) {
+	for key, value := range raw {
+		body, ok := value.([]byte)
+		if !ok {
+			continue
+		}
+		switch name, _, _ := strings.Cut(key, "_"); name {
+		case "POPM", "POP":
+			rating, count, ok := decodePOPM(body)
+			if !ok {
+				continue
+			}
+			if result.Rating == 0 {
+				result.Rating = ratingFromPOPM(rating)
+			}
+			result.PlayCount = max(result.PlayCount, count)
+		case "PCNT", "CNT":
+			result.PlayCount = max(result.PlayCount, decodePlayCounter(body))
+		}
+	}
+}
+
+func extractID3Popularimeter(id3Tag *id3v2.Tag, result *model.FileMetadata) {
+	for _, frame := range id3Tag.GetFrames("POPM") {
+		popm, ok := frame.(id3v2.PopularimeterFrame)
+		if !ok {
+			continue
+		}
+		if result.Rating == 0 {
+			result.Rating = ratingFromPOPM(popm.Rating)
+		}
+		if popm.Counter != nil {
+			result.PlayCount = max(result.PlayCount, decodePlayCounter(popm.Counter.Bytes()))
+		}
+	}
+	for _, frame := range id3Tag.GetFrames("PCNT") {
+		if unknown, ok := frame.(id3v2.UnknownFrame); ok {
+			result.PlayCount = max(result.PlayCount, decodePlayCounter(unknown.Body))
+		}
+	}
+}
+
+func formatFMPSRating(rating int) string {
+	if rating <= 0 {
+		return ""
+	}
+	return strconv.FormatFloat(float64(rating)/model.MaxRating, 'f', -1, 64)
+}
+
+func parseVorbisRating(value string) int {
+	number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || number <= 0 {
+		return 0
+	}
+	switch {
+	case number <= 1 && strings.Contains(value, "."):
+		number *= model.MaxRating
+	case number <= model.MaxRating/model.RatingPerStar && number == math.Trunc(number):
+		number *= model.RatingPerStar
+	}
+	return min(int(math.Round(number)), model.MaxRating)
+}
@@ -0,0 +1,120 @@
+package audio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/vfs"
+)
+
+const replaceAttempts = 6
+
+var (
+	replaceFirstDelay = 50 * time.Millisecond
+	isFileInUse       = fileInUse
+)
+
+func renameRetrying(fsys vfs.FS, oldPath, newPath string) error {
+	delay := replaceFirstDelay
+	for attempt := 1; ; attempt++ {
+		err := fsys.Rename(oldPath, newPath)
+		if err == nil || !isFileInUse(err) || attempt == replaceAttempts {
+			return err
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+func replaceFile(fsys vfs.FS, tempPath, filePath string) error {
+	err := renameRetrying(fsys, tempPath, filePath)
+	if err == nil || !isFileInUse(err) {
+		return err
+	}
+
+	original, err := fsys.Stat(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to stat file in use: %w", err)
+	}
+	backup, err := copyAside(fsys, filePath)
+	if err != nil {
+		return fmt.Errorf("failed to back up file in use: %w", err)
+	}
+	err = overwriteFile(fsys, filePath, fsys, tempPath)
+	if err == nil {
+		err = copyAttributes(fsys, tempPath, filePath)
+	}
+	if err != nil {
+		restoreErr := overwriteFile(fsys, filePath, fsys, backup)
+		if restoreErr == nil {
+			restoreErr = fsys.Chtimes(filePath, original.ModTime(), original.ModTime())
+		}
+		if restoreErr != nil {
+			return fmt.Errorf("failed to overwrite file in use, original kept at %s: %w", backup, errors.Join(err, restoreErr))
+		}
+		fsys.Remove(backup)
+		return fmt.Errorf("failed to overwrite file in use: %w", err)
+	}
+	fsys.Remove(backup)
+	fsys.Remove(tempPath)
+	return nil
+}
+
+func copyAside(fsys vfs.FS, filePath string) (_ string, err error) {
+	backup, err := createHiddenTemp(fsys, filePath)
+	if err != nil {
+		return "", err
+	}
+	err = copyFile(fsys, backup, filePath)
+	if closeErr := backup.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		fsys.Remove(backup.Name())
+		return "", err
+	}
+	return backup.Name(), nil
+}
+
+func copyAttributes(fsys vfs.FS, srcPath, dstPath string) error {
+	stat, err := fsys.Stat(srcPath)
+	if err != nil {
+		return err
+	}
+	if vfs.IsOS(fsys) {
+		if err := copyXattrs(srcPath, dstPath); err != nil {
+			return fmt.Errorf("failed to copy extended attributes: %w", err)
+		}
+	}
+	if err := fsys.Chtimes(dstPath, stat.ModTime(), stat.ModTime()); err != nil {
+		return fmt.Errorf("failed to restore modification time: %w", err)
+	}
+	return nil
+}
+
+func overwriteFile(dstFS vfs.FS, filePath string, srcFS vfs.FS, srcPath string) error {
+	src, err := srcFS.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := dstFS.OpenFile(filePath, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(dst, src)
+	if err == nil {
+		err = dst.Truncate(n)
+	}
+	if err == nil {
+		err = dst.Sync()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
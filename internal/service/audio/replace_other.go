@@ -0,0 +1,7 @@
+//go:build !windows
+
+package audio
+
+func fileInUse(error) bool {
+	return false
+}
@@ -0,0 +1,275 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/internal/vfs"
+)
+
+var errInUse = errors.New("the process cannot access the file because it is being used by another process")
+
+type lockedFS struct {
+	*vfs.Mem
+	locked    map[string]int // renames left to fail, -1 for all of them
+	failSyncs map[string]int // synced writes left to fail
+	renames   int
+}
+
+func newLockedFS() *lockedFS {
+	return &lockedFS{Mem: vfs.NewMem(), locked: map[string]int{}, failSyncs: map[string]int{}}
+}
+
+func (l *lockedFS) Rename(oldPath, newPath string) error {
+	l.renames++
+	for _, path := range []string{oldPath, newPath} {
+		if left := l.locked[path]; left != 0 {
+			if left > 0 {
+				l.locked[path]--
+			}
+			return &os.LinkError{Op: "rename", Old: oldPath, New: newPath, Err: errInUse}
+		}
+	}
+	return l.Mem.Rename(oldPath, newPath)
+}
+
+func (l *lockedFS) OpenFile(name string, flag int, perm os.FileMode) (vfs.File, error) {
+	file, err := l.Mem.OpenFile(name, flag, perm)
+	if err != nil || flag&(os.O_WRONLY|os.O_RDWR) == 0 {
+		return file, err
+	}
+	return &failingSyncFile{File: file, fs: l, name: name}, nil
+}
+
+type failingSyncFile struct {
+	vfs.File
+	fs   *lockedFS
+	name string
+}
+
+func (f *failingSyncFile) Sync() error {
+	if f.fs.failSyncs[f.name] > 0 {
+		f.fs.failSyncs[f.name]--
+		return errors.New("no space left on device")
+	}
+	return f.File.Sync()
+}
+
+func actLikeWindows(t *testing.T) {
+	t.Helper()
+	inUse, delay := isFileInUse, replaceFirstDelay
+	isFileInUse = func(err error) bool { return errors.Is(err, errInUse) }
+	replaceFirstDelay = 0
+	t.Cleanup(func() { isFileInUse, replaceFirstDelay = inUse, delay })
+}
+
+var (
+	songPath     = filepath.FromSlash("/music/song.mp3")
+	songTempPath = filepath.FromSlash("/music/.song.tmp")
+)
+
+func dirNames(t *testing.T, fsys vfs.FS, dir string) []string {
+	t.Helper()
+	entries, err := fsys.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func TestRenameRetrying(t *testing.T) {
+	actLikeWindows(t)
+	tests := []struct {
+		name     string
+		locked   int
+		wantErr  bool
+		attempts int
+	}{
+		{name: "free", locked: 0, attempts: 1},
+		{name: "released while retrying", locked: 3, attempts: 4},
+		{name: "released on the last attempt", locked: replaceAttempts - 1, attempts: replaceAttempts},
+		{name: "never released", locked: -1, wantErr: true, attempts: replaceAttempts},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := newLockedFS()
+			mustWriteFile(t, fsys, songTempPath, "new")
+			mustWriteFile(t, fsys, songPath, "old")
+			fsys.locked[songPath] = tt.locked
+
+			err := renameRetrying(fsys, songTempPath, songPath)
+			if tt.wantErr {
+				if !isFileInUse(err) {
+					t.Errorf("err = %v, want the file in use error", err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if fsys.renames != tt.attempts {
+				t.Errorf("renames = %d, want %d", fsys.renames, tt.attempts)
+			}
+		})
+	}
+
+	t.Run("other errors are not retried", func(t *testing.T) {
+		fsys := newLockedFS()
+		if err := renameRetrying(fsys, filepath.FromSlash("/music/missing"), songPath); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("err = %v, want ErrNotExist", err)
+		}
+		if fsys.renames != 1 {
+			t.Errorf("renames = %d, want 1", fsys.renames)
+		}
+	})
+}
+
+func TestReplaceFileInUse(t *testing.T) {
+	actLikeWindows(t)
+	tests := []struct {
+		name        string
+		locked      int
+		failSyncs   int
+		wantErr     bool
+		wantContent string
+	}{
+		{name: "renamed", locked: 0, wantContent: "new"},
+		{name: "renamed after retries", locked: 2, wantContent: "new"},
+		{name: "overwritten and truncated", locked: -1, wantContent: "new"},
+		{name: "failed overwrite restored", locked: -1, failSyncs: 1, wantErr: true, wantContent: "original content"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := newLockedFS()
+			mustWriteFile(t, fsys, songTempPath, "new")
+			mustWriteFile(t, fsys, songPath, "original content")
+			fsys.locked[songPath] = tt.locked
+			fsys.failSyncs[songPath] = tt.failSyncs
+			// The temp copy carries the time the write should keep.
+			originalTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+			tempTime := time.Date(2021, 6, 7, 8, 9, 10, 0, time.UTC)
+			if err := fsys.Chtimes(songPath, originalTime, originalTime); err != nil {
+				t.Fatal(err)
+			}
+			if err := fsys.Chtimes(songTempPath, tempTime, tempTime); err != nil {
+				t.Fatal(err)
+			}
+
+			err := replaceFile(fsys, songTempPath, songPath)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if got := mustReadFile(t, fsys, songPath); got != tt.wantContent {
+				t.Errorf("content = %q, want %q", got, tt.wantContent)
+			}
+			wantTime := tempTime
+			if tt.wantErr {
+				wantTime = originalTime
+			}
+			stat, err := fsys.Stat(songPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !stat.ModTime().Equal(wantTime) {
+				t.Errorf("modification time = %v, want %v", stat.ModTime(), wantTime)
+			}
+			// The caller removes the temp file of a failed replacement.
+			want := []string{"song.mp3"}
+			if tt.wantErr {
+				want = []string{".song.tmp", "song.mp3"}
+			}
+			if got := dirNames(t, fsys, filepath.Dir(songPath)); !slices.Equal(got, want) {
+				t.Errorf("files left = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// A batch of files held open is committed by overwriting them.
+func TestUpdateTagsAtomicallyInUse(t *testing.T) {
+	actLikeWindows(t)
+	tests := []struct {
+		name      string
+		locked    []string
+		failSyncs []string
+		wantErr   bool
+	}{
+		{name: "originals in use", locked: []string{"a.wav", "b.wav"}},
+		{name: "second file cannot be overwritten", locked: []string{"b.wav"}, failSyncs: []string{"b.wav"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			fsys := newLockedFS()
+			dir := filepath.FromSlash("/music")
+			var writes []model.TagWrite
+			title := "Locked Title"
+			for _, name := range []string{"a.wav", "b.wav"} {
+				path := filepath.Join(dir, name)
+				if err := vfs.WriteFile(fsys, path, testWAV(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				writes = append(writes, model.TagWrite{Path: path, Update: &model.TagUpdate{Title: &title}})
+			}
+			for _, name := range tt.locked {
+				fsys.locked[filepath.Join(dir, name)] = -1
+			}
+			for _, name := range tt.failSyncs {
+				fsys.failSyncs[filepath.Join(dir, name)] = 1
+			}
+			service := NewAudioService(Options{FS: fsys})
+
+			err := service.UpdateTagsAtomically(ctx, writes)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			for _, write := range writes {
+				if tt.wantErr {
+					data, err := vfs.ReadFile(fsys, write.Path)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if !bytes.Equal(data, testWAV()) {
+						t.Errorf("%s was not restored", write.Path)
+					}
+					continue
+				}
+				metadata, err := service.ParseFile(ctx, write.Path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if metadata.Title != title {
+					t.Errorf("%s title = %q, want %q", write.Path, metadata.Title, title)
+				}
+			}
+			if got := dirNames(t, fsys, dir); !slices.Equal(got, []string{"a.wav", "b.wav"}) {
+				t.Errorf("files left = %v, want only the originals", got)
+			}
+		})
+	}
+}
+
+func mustWriteFile(t *testing.T, fsys vfs.FS, name, content string) {
+	t.Helper()
+	if err := vfs.WriteFile(fsys, name, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func mustReadFile(t *testing.T, fsys vfs.FS, name string) string {
+	t.Helper()
+	data, err := vfs.ReadFile(fsys, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -0,0 +1,104 @@
+package audio
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-flac/flacvorbis"
+)
+
+// TagProfile selects the Vorbis comment names of one tagger.
+type TagProfile string
+
+const (
+	TagProfilePicard     TagProfile = "picard"     // MusicBrainz Picard
+	TagProfileBeets      TagProfile = "beets"      // beets, through mediafile
+	TagProfileFoobar2000 TagProfile = "foobar2000" // foobar2000
+)
+
+func ParseTagProfile(profile string) (TagProfile, error) {
+	switch p := TagProfile(strings.ToLower(profile)); p {
+	case "":
+		return TagProfilePicard, nil
+	case TagProfilePicard, TagProfileBeets, TagProfileFoobar2000:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unsupported tag profile: %s", profile)
+	}
+}
+
+const originalDateKey = "ORIGINALDATE"
+
+type vorbisNames struct {
+	albumArtist  []string
+	totalTracks  []string
+	totalDiscs   []string
+	comment      []string
+	label        []string
+	originalDate []string
+	originalYear bool // also write the year alone as ORIGINALYEAR
+}
+
+var profileVorbisNames = map[TagProfile]vorbisNames{
+	TagProfilePicard: {
+		albumArtist:  []string{"ALBUMARTIST"},
+		totalTracks:  []string{"TRACKTOTAL"},
+		totalDiscs:   []string{"DISCTOTAL"},
+		comment:      []string{"COMMENT"},
+		label:        []string{"LABEL"},
+		originalDate: []string{originalDateKey},
+		originalYear: true,
+	},
+	TagProfileBeets: {
+		albumArtist:  []string{"ALBUMARTIST", "ALBUM ARTIST"},
+		totalTracks:  []string{"TRACKTOTAL", "TOTALTRACKS", "TRACKC"},
+		totalDiscs:   []string{"DISCTOTAL", "TOTALDISCS", "DISCC"},
+		comment:      []string{"COMMENT", "DESCRIPTION"},
+		label:        []string{"LABEL", "PUBLISHER"},
+		originalDate: []string{originalDateKey},
+	},
+	TagProfileFoobar2000: {
+		albumArtist:  []string{"ALBUM ARTIST"},
+		totalTracks:  []string{"TOTALTRACKS"},
+		totalDiscs:   []string{"TOTALDISCS"},
+		comment:      []string{"COMMENT"},
+		label:        []string{"PUBLISHER"},
+		originalDate: []string{"ORIGINAL RELEASE DATE"},
+	},
+}
+
+var vorbisAliases = vorbisNames{
+	albumArtist:  []string{"ALBUMARTIST", "ALBUM ARTIST", "ALBUM_ARTIST"},
+	totalTracks:  []string{"TRACKTOTAL", "TOTALTRACKS", "TRACKC"},
+	totalDiscs:   []string{"DISCTOTAL", "TOTALDISCS", "DISCC"},
+	comment:      []string{"COMMENT", "DESCRIPTION"},
+	label:        []string{"LABEL", "ORGANIZATION", "PUBLISHER"},
+	originalDate: []string{originalDateKey, "ORIGINAL RELEASE DATE", "ORIGINALYEAR"},
+}
+
+func namesFor(profile string) vorbisNames {
+	p, err := ParseTagProfile(profile)
+	if err != nil {
+		p = TagProfilePicard
+	}
+	return profileVorbisNames[p]
+}
+
+func setVorbisNames(
+	vorbisComment *flacvorbis.MetaDataBlockVorbisComment, aliases, names []string, value string, keepEmpty bool,
+) {
+	removeVorbisComments(vorbisComment, aliases...)
+	if value == "" && !keepEmpty {
+		return
+	}
+	for _, name := range names {
+		vorbisComment.Comments = append(vorbisComment.Comments, name+"="+value)
+	}
+}
+
+func setVorbisOriginalDate(vorbisComment *flacvorbis.MetaDataBlockVorbisComment, names vorbisNames, value string) {
+	setVorbisNames(vorbisComment, vorbisAliases.originalDate, names.originalDate, value, false)
+	if names.originalYear && len(value) >= 4 {
+		vorbisComment.Comments = append(vorbisComment.Comments, "ORIGINALYEAR="+value[:4])
+	}
+}
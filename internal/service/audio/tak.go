@@ -0,0 +1,157 @@
+package audio
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/internal/vfs"
+)
+
+const (
+	takBlockEnd        = 0
+	takBlockStreamInfo = 1
+
+	takMinSampleRate = 6000
+	takMinBits       = 8
+)
+
+type takStreamInfo struct {
+	samples       int64
+	sampleRate    int
+	bitsPerSample int
+	channels      int
+}
+
+type takHandler struct{}
+
+func newTAKHandler() *takHandler {
+	return &takHandler{}
+}
+
+func (h *takHandler) Format() string {
+	return "TAK"
+}
+
+func (h *takHandler) Capabilities() model.Capabilities {
+	return apeCapabilities
+}
+
+func (h *takHandler) ExtractDuration(ctx context.Context, r io.ReaderAt, size int64) (float64, error) {
+	info, err := readTAKStreamInfo(r, size)
+	if err != nil {
+		return 0, err
+	}
+	if info.samples == 0 {
+		return 0, fmt.Errorf("could not determine TAK duration")
+	}
+	return float64(info.samples) / float64(info.sampleRate), nil
+}
+
+func (h *takHandler) ExtractAudioInfo(r io.ReaderAt, size int64, result *model.FileMetadata) error {
+	info, err := readTAKStreamInfo(r, size)
+	if err != nil {
+		return err
+	}
+	start, end, err := apeTaggedAudioRange(r, size)
+	if err != nil {
+		return err
+	}
+	result.Codec = "TAK"
+	result.Lossless = true
+	result.SampleRate = info.sampleRate
+	result.Channels = info.channels
+	result.BitsPerSample = info.bitsPerSample
+	result.Bitrate = averageBitrate(end-start, result.Duration)
+	return nil
+}
+
+func (h *takHandler) Parse(r io.ReaderAt, size int64, name string) (*model.FileMetadata, error) {
+	result, err := readAPETaggedMetadata(r, size, name, h.Format())
+	if err != nil {
+		return result, err
+	}
+	if duration, err := h.ExtractDuration(context.Background(), r, size); err == nil {
+		result.Duration = duration
+	}
+	return result, nil
+}
+
+func (h *takHandler) UpdateTags(ctx context.Context, fsys vfs.FS, filePath string, update *model.TagUpdate) error {
+	return updateTrailingAPETag(fsys, filePath, update)
+}
+
+func (h *takHandler) writeAudioStream(r io.ReaderAt, size int64, w io.Writer) error {
+	return writeAPETaggedAudioStream(r, size, w)
+}
+
+func readTAKStreamInfo(r io.ReaderAt, size int64) (*takStreamInfo, error) {
+	start, err := skipID3v2Tags(r, size)
+	if err != nil {
+		return nil, err
+	}
+	magic := make([]byte, 4)
+	if _, err := r.ReadAt(magic, start); err != nil {
+		return nil, fmt.Errorf("failed to read TAK header: %w", unexpectedEOF(err))
+	}
+	if string(magic) != "tBaK" {
+		return nil, fmt.Errorf("not a valid TAK file")
+	}
+
+	header := make([]byte, 4)
+	for offset := start + 4; offset+4 <= size; {
+		if _, err := r.ReadAt(header, offset); err != nil {
+			return nil, fmt.Errorf("failed to read TAK metadata block: %w", unexpectedEOF(err))
+		}
+		blockType := int(header[0] & 0x7F)
+		length := int64(header[1]) | int64(header[2])<<8 | int64(header[3])<<16
+		switch blockType {
+		case takBlockEnd:
+			return nil, fmt.Errorf("TAK stream info not found")
+		case takBlockStreamInfo:
+			data := make([]byte, min(length, 16))
+			if _, err := r.ReadAt(data, offset+4); err != nil {
+				return nil, fmt.Errorf("failed to read TAK stream info: %w", unexpectedEOF(err))
+			}
+			return parseTAKStreamInfo(data)
+		}
+		offset += 4 + length
+	}
+	return nil, fmt.Errorf("TAK stream info not found")
+}
+
+func parseTAKStreamInfo(data []byte) (*takStreamInfo, error) {
+	if len(data) < 10 {
+		return nil, fmt.Errorf("TAK stream info is truncated")
+	}
+	position := 0
+	bits := func(n int) int64 {
+		var value int64
+		for i := 0; i < n; i++ {
+			bit := int64(data[position/8]>>(position%8)) & 1
+			value |= bit << i
+			position++
+		}
+		return value
+	}
+
+	bits(6) // codec
+	bits(4) // profile
+	bits(4) // frame size
+	info := &takStreamInfo{samples: bits(35)}
+	bits(3) // data type
+	info.sampleRate = int(bits(18)) + takMinSampleRate
+	info.bitsPerSample = int(bits(5)) + takMinBits
+	info.channels = int(bits(4)) + 1
+	return info, nil
+}
+
+func getTAKHandler(ext string) FormatHandler {
+	ext = strings.ToUpper(ext)
+	if ext == "TAK" {
+		return newTAKHandler()
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package audio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// UpdateTagsAtomically writes the tags of several files all or nothing.
+func (s *AudioService) UpdateTagsAtomically(ctx context.Context, writes []model.TagWrite) (err error) {
+	ctx, span := tracer.Start(ctx, "audio.UpdateTagsAtomically", trace.WithAttributes(attribute.Int("audio.files", len(writes))))
+	defer func() {
+		recordError(span, err)
+		span.End()
+	}()
+
+	tx := &transaction{service: s}
+	defer tx.rollback()
+	for i, write := range writes {
+		if err := ctx.Err(); err != nil {
+			return &model.BatchError{Index: i, Err: err}
+		}
+		if err := tx.stage(ctx, write.Path, write.Update); err != nil {
+			return &model.BatchError{Index: i, Err: err}
+		}
+	}
+	return tx.commit()
+}
+
+type transaction struct {
+	service *AudioService
+	staged  []*stagedWrite
+}
+
+func (t *transaction) stage(ctx context.Context, filePath string, update *model.TagUpdate) error {
+	handler, format, update, err := t.service.prepareUpdate(ctx, filePath, update)
+	if err != nil {
+		return err
+	}
+	staged, err := t.service.stageWrite(
+		ctx, filePath, format, func(path string) error {
+			return handler.UpdateTags(ctx, t.service.fs, path, update)
+		},
+	)
+	if err != nil {
+		return err
+	}
+	t.staged = append(t.staged, staged)
+	return nil
+}
+
+func (t *transaction) commit() error {
+	defer t.rollback()
+
+	backups := make([]string, 0, len(t.staged))
+	var err error
+	for _, staged := range t.staged {
+		var backup string
+		backup, err = t.swap(staged)
+		if err != nil {
+			break
+		}
+		backups = append(backups, backup)
+	}
+	if err != nil {
+		var restoreErrs []error
+		for i, backup := range backups {
+			if restoreErr := replaceFile(t.service.fs, backup, t.staged[i].path); restoreErr != nil {
+				restoreErrs = append(restoreErrs, fmt.Errorf("original of %s kept at %s: %w", t.staged[i].path, backup, restoreErr))
+			}
+		}
+		return errors.Join(append([]error{err}, restoreErrs...)...)
+	}
+
+	for i, backup := range backups {
+		t.service.fs.Remove(backup)
+		syncFile(t.service.fs, filepath.Dir(t.staged[i].path))
+	}
+	return nil
+}
+
+func (t *transaction) swap(staged *stagedWrite) (string, error) {
+	defer t.service.parsed.forget(staged.path)
+
+	fsys := t.service.fs
+	backupFile, err := createHiddenTemp(fsys, staged.path)
+	if err != nil {
+		return "", fmt.Errorf("failed to create backup of %s: %w", staged.path, err)
+	}
+	backup := backupFile.Name()
+	backupFile.Close()
+	if err := renameRetrying(fsys, staged.path, backup); err != nil {
+		fsys.Remove(backup)
+		if !isFileInUse(err) {
+			return "", fmt.Errorf("failed to back up %s: %w", staged.path, err)
+		}
+		if backup, err = copyAside(fsys, staged.path); err != nil {
+			return "", fmt.Errorf("failed to back up %s: %w", staged.path, err)
+		}
+	}
+	if err := replaceFile(fsys, staged.tempPath, staged.path); err != nil {
+		if restoreErr := replaceFile(fsys, backup, staged.path); restoreErr != nil {
+			return "", fmt.Errorf("failed to replace %s, original kept at %s: %w", staged.path, backup, errors.Join(err, restoreErr))
+		}
+		return "", fmt.Errorf("failed to replace %s: %w", staged.path, err)
+	}
+	return backup, nil
+}
+
+func (t *transaction) rollback() {
+	for _, staged := range t.staged {
+		staged.discard()
+	}
+	t.staged = nil
+}
@@ -0,0 +1,109 @@
+package audio
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/internal/vfs"
+)
+
+type ttaHeader struct {
+	channels      int
+	bitsPerSample int
+	sampleRate    int
+	samples       int64
+}
+
+type ttaHandler struct{}
+
+func newTTAHandler() *ttaHandler {
+	return &ttaHandler{}
+}
+
+func (h *ttaHandler) Format() string {
+	return "TTA"
+}
+
+func (h *ttaHandler) Capabilities() model.Capabilities {
+	return apeCapabilities
+}
+
+func (h *ttaHandler) ExtractDuration(ctx context.Context, r io.ReaderAt, size int64) (float64, error) {
+	header, err := readTTAHeader(r, size)
+	if err != nil {
+		return 0, err
+	}
+	if header.sampleRate == 0 || header.samples == 0 {
+		return 0, fmt.Errorf("could not determine TTA duration")
+	}
+	return float64(header.samples) / float64(header.sampleRate), nil
+}
+
+func (h *ttaHandler) ExtractAudioInfo(r io.ReaderAt, size int64, result *model.FileMetadata) error {
+	header, err := readTTAHeader(r, size)
+	if err != nil {
+		return err
+	}
+	start, end, err := apeTaggedAudioRange(r, size)
+	if err != nil {
+		return err
+	}
+	result.Codec = "True Audio"
+	result.Lossless = true
+	result.SampleRate = header.sampleRate
+	result.Channels = header.channels
+	result.BitsPerSample = header.bitsPerSample
+	result.Bitrate = averageBitrate(end-start, result.Duration)
+	return nil
+}
+
+func (h *ttaHandler) Parse(r io.ReaderAt, size int64, name string) (*model.FileMetadata, error) {
+	result, err := readAPETaggedMetadata(r, size, name, h.Format())
+	if err != nil {
+		return result, err
+	}
+	if duration, err := h.ExtractDuration(context.Background(), r, size); err == nil {
+		result.Duration = duration
+	}
+	return result, nil
+}
+
+func (h *ttaHandler) UpdateTags(ctx context.Context, fsys vfs.FS, filePath string, update *model.TagUpdate) error {
+	return updateTrailingAPETag(fsys, filePath, update)
+}
+
+func (h *ttaHandler) writeAudioStream(r io.ReaderAt, size int64, w io.Writer) error {
+	return writeAPETaggedAudioStream(r, size, w)
+}
+
+func readTTAHeader(r io.ReaderAt, size int64) (*ttaHeader, error) {
+	start, err := skipID3v2Tags(r, size)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 18)
+	if _, err := r.ReadAt(buf, start); err != nil {
+		return nil, fmt.Errorf("failed to read TTA header: %w", unexpectedEOF(err))
+	}
+	if string(buf[0:4]) != "TTA1" {
+		return nil, fmt.Errorf("not a valid TTA file")
+	}
+	return &ttaHeader{
+		channels:      int(binary.LittleEndian.Uint16(buf[6:8])),
+		bitsPerSample: int(binary.LittleEndian.Uint16(buf[8:10])),
+		sampleRate:    int(binary.LittleEndian.Uint32(buf[10:14])),
+		samples:       int64(binary.LittleEndian.Uint32(buf[14:18])),
+	}, nil
+}
+
+func getTTAHandler(ext string) FormatHandler {
+	ext = strings.ToUpper(ext)
+	if ext == "TTA" {
+		return newTTAHandler()
+	}
+	return nil
+}
@@ -0,0 +1,291 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+)
+
+var isrcPattern = regexp.MustCompile(`^[A-Z]{2}[A-Z0-9]{3}[0-9]{7}$`)
+
+const (
+	minYear = 1000
+	maxYear = 9999
+
+	maxTextLength     = 1024
+	maxLongTextLength = 64 * 1024
+)
+
+type tagValidator struct {
+	fields []*model.FieldError
+}
+
+func (v *tagValidator) add(field string, sentinel error, format string, args ...any) {
+	v.fields = append(
+		v.fields, &model.FieldError{Field: field, Err: fmt.Errorf("%w: "+format, append([]any{sentinel}, args...)...)},
+	)
+}
+
+func (v *tagValidator) err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return &model.ValidationError{Fields: v.fields}
+}
+
+// ValidateTagUpdate sanitizes update in place and checks it against the tag formats.
+func (s *AudioService) ValidateTagUpdate(update *model.TagUpdate) error {
+	v := &tagValidator{}
+	v.clear(update)
+
+	v.text("title", update.Title, false)
+	v.text("artist", update.Artist, false)
+	v.text("album", update.Album, false)
+	v.text("albumArtist", update.AlbumArtist, false)
+	v.text("composer", update.Composer, false)
+	v.text("genre", update.Genre, false)
+	v.text("titleSort", update.TitleSort, false)
+	v.text("artistSort", update.ArtistSort, false)
+	v.text("albumArtistSort", update.AlbumArtistSort, false)
+	v.text("albumSort", update.AlbumSort, false)
+	v.text("comment", update.Comment, true)
+	v.text("lyrics", update.Lyrics, true)
+	v.text("syncedLyrics", update.SyncedLyrics, true)
+	v.text("catalogNumber", update.CatalogNumber, false)
+	v.text("label", update.Label, false)
+	v.isrc(update.ISRC)
+	v.barcode(update.Barcode)
+
+	if update.Year != nil && *update.Year != 0 && (*update.Year < minYear || *update.Year > maxYear) {
+		v.add("year", model.ErrInvalidTag, "year must be between %d and %d", minYear, maxYear)
+	}
+	if update.ReleaseDate != nil && *update.ReleaseDate != "" {
+		*update.ReleaseDate = strings.TrimSpace(*update.ReleaseDate)
+		date := *update.ReleaseDate
+		year := parseLeadingInt(date)
+		switch {
+		case normalizeReleaseDate(date) != date:
+			v.add("releaseDate", model.ErrInvalidTag, "release date must be YYYY-MM-DD, YYYY-MM or YYYY")
+		case year < minYear || year > maxYear:
+			v.add("releaseDate", model.ErrInvalidTag, "year must be between %d and %d", minYear, maxYear)
+		case update.Year != nil && *update.Year != 0 && *update.Year != year:
+			v.add("year", model.ErrInvalidTag, "year %d does not match the release date %s", *update.Year, date)
+		}
+	}
+	v.positive("track", update.Track)
+	v.positive("totalTracks", update.TotalTracks)
+	v.positive("disc", update.Disc)
+	v.positive("totalDiscs", update.TotalDiscs)
+	v.positive("bpm", update.BPM)
+	v.positive("playCount", update.PlayCount)
+	if update.PlayCount != nil && *update.PlayCount > math.MaxInt32 {
+		v.add("playCount", model.ErrInvalidTag, "playCount must be at most %d", math.MaxInt32)
+	}
+	if update.Rating != nil && (*update.Rating < 0 || *update.Rating > model.MaxRating) {
+		v.add("rating", model.ErrInvalidTag, "rating must be between 0 and %d", model.MaxRating)
+	}
+
+	if update.SyncedLyrics != nil && *update.SyncedLyrics != "" {
+		if _, err := parseLRC(*update.SyncedLyrics); err != nil {
+			v.add("syncedLyrics", model.ErrInvalidTag, "invalid synced lyrics: %v", err)
+		}
+	}
+
+	if update.Chapters != nil {
+		s.validateChapters(v, *update.Chapters)
+	}
+
+	if len(update.CustomTags) > 0 {
+		if err := s.customTagPolicy.validate(update.CustomTags); err != nil {
+			v.add("customTags", model.ErrInvalidTag, "%v", err)
+		}
+		sanitized := make(map[string]string, len(update.CustomTags))
+		for name, value := range update.CustomTags {
+			value = sanitizeText(value, true)
+			if utf8.RuneCountInString(value) > maxLongTextLength {
+				v.add("customTags", model.ErrInvalidTag, "%s is longer than %d characters", name, maxLongTextLength)
+			}
+			sanitized[name] = value
+		}
+		update.CustomTags = sanitized
+	}
+
+	if update.CoverArt != nil && *update.CoverArt != "" {
+		s.coverData(v, "coverArt", update.CoverArt)
+	}
+	for i, picture := range update.Pictures {
+		field := fmt.Sprintf("pictures[%d]", i)
+		if picture.Type < 0 || picture.Type > model.PictureTypeMax {
+			v.add(field, model.ErrInvalidCoverArt, "invalid picture type: %d", picture.Type)
+		}
+		if picture.Data != "" {
+			s.coverData(v, field, &update.Pictures[i].Data)
+		}
+		update.Pictures[i].Description = sanitizeText(picture.Description, false)
+	}
+
+	if _, err := ParseFLACID3Mode(update.FLACID3); err != nil {
+		v.add("flacId3", model.ErrInvalidTag, "%v", err)
+	}
+	if _, err := ParseTagProfile(update.TagProfile); err != nil {
+		v.add("tagProfile", model.ErrInvalidTag, "%v", err)
+	}
+	if _, err := model.ParseMergePolicy(string(update.MergePolicy)); err != nil {
+		v.add("mergePolicy", model.ErrInvalidTag, "%v", err)
+	}
+
+	return v.err()
+}
+
+func (s *AudioService) validateChapters(v *tagValidator, chapters []model.Chapter) {
+	if len(chapters) > model.MaxChapters {
+		v.add("chapters", model.ErrInvalidTag, "a file can have at most %d chapters", model.MaxChapters)
+	}
+	ids := make(map[string]bool, len(chapters))
+	for i := range chapters {
+		chapter := &chapters[i]
+		field := fmt.Sprintf("chapters[%d]", i)
+		v.text(field+".title", &chapter.Title, false)
+		v.text(field+".url", &chapter.URL, false)
+
+		if chapter.ID != "" {
+			if len(chapter.ID) > 64 || strings.IndexFunc(chapter.ID, func(r rune) bool { return r <= 0x20 || r > 0x7E }) >= 0 {
+				v.add(field+".id", model.ErrInvalidTag, "id must be up to 64 printable ASCII characters")
+			}
+			if ids[chapter.ID] {
+				v.add(field+".id", model.ErrInvalidTag, "id %q is used by another chapter", chapter.ID)
+			}
+			ids[chapter.ID] = true
+		}
+		if chapter.URL != "" && !isLatin1(chapter.URL) {
+			v.add(field+".url", model.ErrInvalidTag, "url must be Latin-1 text; percent-encode other characters")
+		}
+		switch {
+		case chapter.Start < 0 || chapter.Start >= math.MaxUint32:
+			v.add(field+".start", model.ErrInvalidTag, "start must be between 0 and %d ms", int64(math.MaxUint32)-1)
+		case chapter.End != 0 && (chapter.End <= chapter.Start || chapter.End >= math.MaxUint32):
+			v.add(field+".end", model.ErrInvalidTag, "end must be after the start and below %d ms", int64(math.MaxUint32))
+		}
+		if chapter.Image != "" {
+			s.coverData(v, field+".image", &chapter.Image)
+		}
+	}
+}
+
+func (v *tagValidator) text(field string, value *string, multiline bool) {
+	if value == nil {
+		return
+	}
+	*value = sanitizeText(*value, multiline)
+	limit := maxTextLength
+	if multiline {
+		limit = maxLongTextLength
+	}
+	if utf8.RuneCountInString(*value) > limit {
+		v.add(field, model.ErrInvalidTag, "%s is longer than %d characters", field, limit)
+	}
+}
+
+func (v *tagValidator) isrc(value *string) {
+	if value == nil {
+		return
+	}
+	*value = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(*value))
+	if *value != "" && !isrcPattern.MatchString(*value) {
+		v.add("isrc", model.ErrInvalidTag, "isrc must be 12 characters such as USRC17607839")
+	}
+}
+
+func (v *tagValidator) barcode(value *string) {
+	if value == nil {
+		return
+	}
+	*value = strings.ReplaceAll(strings.TrimSpace(*value), " ", "")
+	if *value == "" {
+		return
+	}
+	if len(*value) != 12 && len(*value) != 13 || strings.Trim(*value, "0123456789") != "" {
+		v.add("barcode", model.ErrInvalidTag, "barcode must be a 12-digit UPC or a 13-digit EAN")
+		return
+	}
+	// Digits are weighted 3 and 1 alternately from the right, check digit aside.
+	digits := *value
+	sum := 0
+	for i := len(digits) - 2; i >= 0; i-- {
+		weight := 1
+		if (len(digits)-2-i)%2 == 0 {
+			weight = 3
+		}
+		sum += int(digits[i]-'0') * weight
+	}
+	if check := (10 - sum%10) % 10; int(digits[len(digits)-1]-'0') != check {
+		v.add("barcode", model.ErrInvalidTag, "barcode check digit should be %d", check)
+	}
+}
+
+func (v *tagValidator) positive(field string, value *int) {
+	if value != nil && *value < 0 {
+		v.add(field, model.ErrInvalidTag, "%s must not be negative", field)
+	}
+}
+
+func (s *AudioService) coverData(v *tagValidator, field string, dataURI *string) {
+	policy := coverPolicy{maxSize: s.coverFetcher.maxSize, limits: s.coverLimits}
+	fitted, err := fitCover(
+		*dataURI, policy, func(dataURI string) (string, error) {
+			if err := s.validateCoverData(dataURI); err != nil {
+				return "", err
+			}
+			return s.fitCoverData(dataURI)
+		},
+	)
+	if err != nil {
+		v.add(field, model.ErrInvalidCoverArt, "%v", err)
+		return
+	}
+	*dataURI = fitted
+}
+
+func (s *AudioService) validateCoverData(dataURI string) error {
+	data, _, err := decodeCover(dataURI)
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return fmt.Errorf("image data is empty")
+	}
+	if s.coverFetcher.maxSize > 0 && int64(len(data)) > s.coverFetcher.maxSize {
+		return fmt.Errorf("cover art is larger than %d bytes", s.coverFetcher.maxSize)
+	}
+	if mimeType := http.DetectContentType(data); !allowedCoverMimeTypes[mimeType] {
+		return fmt.Errorf("unsupported cover art type: %s", mimeType)
+	}
+	return nil
+}
+
+func sanitizeText(value string, multiline bool) string {
+	value = strings.ToValidUTF8(value, string(utf8.RuneError))
+	return strings.Map(
+		func(r rune) rune {
+			switch {
+			case r == '\n' || r == '\r':
+				if multiline {
+					return r
+				}
+				return ' '
+			case r == '\t':
+				return r
+			case unicode.IsControl(r):
+				return -1
+			default:
+				return r
+			}
+		}, value,
+	)
+}
@@ -0,0 +1,114 @@
+package audio
+
+import (
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/internal/vfs"
+)
+
+func testWAV() []byte {
+	const samples = 4410
+	wav := make([]byte, 44+samples*2)
+	copy(wav[0:], "RIFF")
+	binary.LittleEndian.PutUint32(wav[4:], uint32(len(wav)-8))
+	copy(wav[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(wav[16:], 16)
+	binary.LittleEndian.PutUint16(wav[20:], 1) // PCM
+	binary.LittleEndian.PutUint16(wav[22:], 1)
+	binary.LittleEndian.PutUint32(wav[24:], 44100)
+	binary.LittleEndian.PutUint32(wav[28:], 44100*2)
+	binary.LittleEndian.PutUint16(wav[32:], 2)
+	binary.LittleEndian.PutUint16(wav[34:], 16)
+	copy(wav[36:], "data")
+	binary.LittleEndian.PutUint32(wav[40:], samples*2)
+	return wav
+}
+
+// Files in memory are written without touching the local disk.
+func TestUpdateTagsOnMemFS(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   func(t *testing.T) []byte
+		lyrics bool // large enough to force a FLAC rewrite
+	}{
+		{name: "song.mp3", data: testdataFile("sample.id3v11.mp3")},
+		{name: "patched.flac", data: testdataFile("sample.flac")},
+		{name: "rewritten.flac", data: testdataFile("sample.flac"), lyrics: true},
+		{name: "song.ogg", data: testdataFile("sample.ogg")},
+		{name: "song.wav", data: func(*testing.T) []byte { return testWAV() }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mem := vfs.NewMem()
+			dir := filepath.Join(t.TempDir(), "music")
+			path := filepath.Join(dir, tt.name)
+			if err := vfs.WriteFile(mem, path, tt.data(t), 0o640); err != nil {
+				t.Fatal(err)
+			}
+			mtime := time.Date(2021, 5, 6, 7, 8, 9, 0, time.UTC)
+			if err := mem.Chtimes(path, mtime, mtime); err != nil {
+				t.Fatal(err)
+			}
+			service := NewAudioService(Options{FS: mem})
+
+			title, artist := "Mem Title", "Mem Artist"
+			update := &model.TagUpdate{Title: &title, Artist: &artist}
+			if tt.lyrics {
+				lyrics := strings.Repeat("la ", 4000)
+				update.Lyrics = &lyrics
+			}
+			if err := service.UpdateTags(ctx, path, update); err != nil {
+				t.Fatal(err)
+			}
+
+			metadata, err := service.ParseFile(ctx, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if metadata.Title != title || metadata.Artist != artist {
+				t.Errorf("title, artist = %q, %q, want %q, %q", metadata.Title, metadata.Artist, title, artist)
+			}
+
+			stat, err := mem.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stat.Mode().Perm() != 0o640 || !stat.ModTime().Equal(mtime) {
+				t.Errorf("mode, mtime = %v, %v, want them kept", stat.Mode(), stat.ModTime())
+			}
+			entries, err := mem.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 {
+				var names []string
+				for _, entry := range entries {
+					names = append(names, entry.Name())
+				}
+				t.Errorf("files left next to the written one: %v", names)
+			}
+			if _, err := os.Stat(dir); !os.IsNotExist(err) {
+				t.Errorf("local directory %s exists: %v", dir, err)
+			}
+		})
+	}
+}
+
+func testdataFile(name string) func(t *testing.T) []byte {
+	return func(t *testing.T) []byte {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+}
@@ -0,0 +1,503 @@
+package audio
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/internal/vfs"
+)
+
+const (
+	wavInfoTitle   = "INAM"
+	wavInfoArtist  = "IART"
+	wavInfoAlbum   = "IPRD"
+	wavInfoDate    = "ICRD"
+	wavInfoGenre   = "IGNR"
+	wavInfoTrack   = "ITRK"
+	wavInfoPart    = "IPRT"
+	wavInfoComment = "ICMT"
+)
+
+type riffChunk struct {
+	id     string
+	offset int64
+	size   uint32
+}
+
+type wavInfoEntry struct {
+	id    string
+	value string
+}
+
+type wavFile struct {
+	chunks     []riffChunk
+	info       []wavInfoEntry
+	id3        []byte
+	bext       []byte // Broadcast Wave metadata, kept as is by tag writes
+	ixml       []byte
+	sampleRate int
+}
+
+type wavHandler struct{}
+
+func newWAVHandler() *wavHandler {
+	return &wavHandler{}
+}
+
+func (h *wavHandler) Format() string {
+	return "WAV"
+}
+
+func (h *wavHandler) Capabilities() model.Capabilities {
+	return fullCapabilities
+}
+
+func (h *wavHandler) ExtractDuration(ctx context.Context, r io.ReaderAt, size int64) (float64, error) {
+	wav, err := h.readChunks(r, size)
+	if err != nil {
+		return 0, err
+	}
+	return h.duration(r, wav)
+}
+
+func (h *wavHandler) duration(r io.ReaderAt, wav *wavFile) (float64, error) {
+	var byteRate uint32
+	var dataSize uint32
+	for _, chunk := range wav.chunks {
+		switch chunk.id {
+		case "fmt ":
+			if chunk.size < 16 {
+				return 0, fmt.Errorf("WAV fmt chunk too small")
+			}
+			fmtData := make([]byte, 16)
+			if _, err := r.ReadAt(fmtData, chunk.offset); err != nil {
+				return 0, fmt.Errorf("failed to read WAV fmt chunk: %w", err)
+			}
+			byteRate = binary.LittleEndian.Uint32(fmtData[8:12])
+		case "data":
+			dataSize = chunk.size
+		}
+	}
+
+	if byteRate == 0 {
+		return 0, fmt.Errorf("could not determine WAV byte rate")
+	}
+	if dataSize == 0 {
+		return 0, fmt.Errorf("WAV data chunk not found")
+	}
+
+	return float64(dataSize) / float64(byteRate), nil
+}
+
+var wavCodecs = map[uint16]string{
+	0x0001: "PCM",
+	0x0003: "PCM float",
+	0x0006: "A-law",
+	0x0007: "µ-law",
+	0x0055: "MP3",
+}
+
+func (h *wavHandler) ExtractAudioInfo(r io.ReaderAt, size int64, result *model.FileMetadata) error {
+	wav, err := h.readChunks(r, size)
+	if err != nil {
+		return err
+	}
+
+	for _, chunk := range wav.chunks {
+		if chunk.id != "fmt " {
+			continue
+		}
+		if chunk.size < 16 {
+			return fmt.Errorf("WAV fmt chunk too small")
+		}
+		fmtData := make([]byte, min(chunk.size, 40))
+		if _, err := r.ReadAt(fmtData, chunk.offset); err != nil {
+			return fmt.Errorf("failed to read WAV fmt chunk: %w", err)
+		}
+
+		formatTag := binary.LittleEndian.Uint16(fmtData[0:2])
+		if formatTag == 0xFFFE && len(fmtData) >= 26 {
+			// WAVE_FORMAT_EXTENSIBLE keeps the real format in its sub-format GUID.
+			formatTag = binary.LittleEndian.Uint16(fmtData[24:26])
+		}
+		result.Codec = wavCodecs[formatTag]
+		if result.Codec == "" {
+			result.Codec = fmt.Sprintf("WAV format 0x%04X", formatTag)
+		}
+		result.Lossless = formatTag == 0x0001 || formatTag == 0x0003
+		result.Channels = int(binary.LittleEndian.Uint16(fmtData[2:4]))
+		result.SampleRate = int(binary.LittleEndian.Uint32(fmtData[4:8]))
+		result.Bitrate = int(binary.LittleEndian.Uint32(fmtData[8:12])) * 8 / 1000
+		result.BitsPerSample = int(binary.LittleEndian.Uint16(fmtData[14:16]))
+		return nil
+	}
+
+	return fmt.Errorf("WAV fmt chunk not found")
+}
+
+func (h *wavHandler) Parse(r io.ReaderAt, size int64, name string) (*model.FileMetadata, error) {
+	result := &model.FileMetadata{
+		Size:   size,
+		Format: "WAV",
+	}
+
+	wav, err := h.readChunks(r, size)
+	if err != nil {
+		result.Title = name
+		return result, err
+	}
+
+	for _, entry := range wav.info {
+		switch entry.id {
+		case wavInfoTitle:
+			result.Title = entry.value
+		case wavInfoArtist:
+			result.Artist = entry.value
+		case wavInfoAlbum:
+			result.Album = entry.value
+		case wavInfoDate:
+			result.Year = parseLeadingInt(entry.value)
+			result.ReleaseDate = normalizeReleaseDate(entry.value)
+		case wavInfoGenre:
+			result.Genre = entry.value
+		case wavInfoTrack:
+			var total int
+			result.Track, total = splitNumberPair(entry.value)
+			if result.TotalTracks == 0 {
+				result.TotalTracks = total
+			}
+		case wavInfoPart:
+			if result.Track == 0 {
+				result.Track = parseLeadingInt(entry.value)
+			}
+		case wavInfoComment:
+			result.Comment = entry.value
+		}
+	}
+
+	if len(wav.id3) > 0 {
+		extractID3ChunkMetadata(wav.id3, result)
+	}
+	result.Broadcast = parseBroadcast(wav.bext, wav.ixml, wav.sampleRate)
+
+	if result.Title == "" {
+		result.Title = name
+	}
+	if duration, err := h.duration(r, wav); err == nil && duration > 0 {
+		result.Duration = duration
+	}
+
+	return result, nil
+}
+
+func (h *wavHandler) UpdateTags(ctx context.Context, fsys vfs.FS, filePath string, update *model.TagUpdate) error {
+	src, err := fsys.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open WAV file: %w", err)
+	}
+	defer src.Close()
+
+	stat, err := src.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	wav, err := h.readChunks(src, stat.Size())
+	if err != nil {
+		return err
+	}
+
+	if update.Title != nil {
+		wav.setInfo(wavInfoTitle, *update.Title, update.KeepsEmpty("title"))
+	}
+	if update.Artist != nil {
+		wav.setInfo(wavInfoArtist, *update.Artist, update.KeepsEmpty("artist"))
+	}
+	if update.Album != nil {
+		wav.setInfo(wavInfoAlbum, *update.Album, update.KeepsEmpty("album"))
+	}
+	if date, ok := updatedReleaseDate(wav.infoValue(wavInfoDate), update); ok {
+		wav.setInfo(wavInfoDate, date, false)
+	}
+	if update.Track != nil || update.TotalTracks != nil {
+		number, total := splitNumberPair(wav.infoValue(wavInfoTrack))
+		if update.Track != nil {
+			number = *update.Track
+		}
+		if update.TotalTracks != nil {
+			total = *update.TotalTracks
+		}
+		wav.setInfo(wavInfoTrack, formatNumberPair(number, total), false)
+	}
+	if update.Genre != nil {
+		wav.setInfo(wavInfoGenre, *update.Genre, update.KeepsEmpty("genre"))
+	}
+	if update.Comment != nil {
+		wav.setInfo(wavInfoComment, *update.Comment, update.KeepsEmpty("comment"))
+	}
+
+	hasCoverArt := update.CoverArt != nil && *update.CoverArt != ""
+	var id3Data []byte
+	if len(wav.id3) > 0 || hasCoverArt || update.HasExtendedFields() {
+		id3Data, err = buildID3Chunk(wav.id3, update)
+		if err != nil {
+			return err
+		}
+	}
+
+	tempFile := filePath + ".tmp"
+	dst, err := vfs.Create(fsys, tempFile)
+	if err != nil {
+		return fmt.Errorf("failed to create temp WAV file: %w", err)
+	}
+	defer fsys.Remove(tempFile)
+	defer dst.Close()
+
+	writer := bufio.NewWriter(dst)
+	riffSize := int64(4)
+	if _, err := writer.Write([]byte("RIFF\x00\x00\x00\x00WAVE")); err != nil {
+		return fmt.Errorf("failed to write RIFF header: %w", err)
+	}
+
+	for _, chunk := range wav.chunks {
+		if h.isID3Chunk(chunk.id) || (chunk.id == "LIST" && h.isInfoList(src, chunk)) {
+			continue
+		}
+		written, err := writeRIFFChunk(writer, chunk.id, io.NewSectionReader(src, chunk.offset, int64(chunk.size)), chunk.size)
+		if err != nil {
+			return err
+		}
+		riffSize += written
+	}
+
+	if infoData := wav.marshalInfo(); len(infoData) > 0 {
+		written, err := writeRIFFChunk(writer, "LIST", bytes.NewReader(infoData), uint32(len(infoData)))
+		if err != nil {
+			return err
+		}
+		riffSize += written
+	}
+	if len(id3Data) > 0 {
+		written, err := writeRIFFChunk(writer, "id3 ", bytes.NewReader(id3Data), uint32(len(id3Data)))
+		if err != nil {
+			return err
+		}
+		riffSize += written
+	}
+
+	if riffSize > 0xFFFFFFFF {
+		return fmt.Errorf("WAV file exceeds the 4 GiB RIFF size limit")
+	}
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to flush temp WAV file: %w", err)
+	}
+	sizeField := make([]byte, 4)
+	binary.LittleEndian.PutUint32(sizeField, uint32(riffSize))
+	if _, err := dst.WriteAt(sizeField, 4); err != nil {
+		return fmt.Errorf("failed to write RIFF size: %w", err)
+	}
+	if err := dst.Close(); err != nil {
+		return fmt.Errorf("failed to close temp WAV file: %w", err)
+	}
+	src.Close()
+
+	if err := fsys.Rename(tempFile, filePath); err != nil {
+		return fmt.Errorf("failed to rename temp file: %w", err)
+	}
+
+	return nil
+}
+
+func (h *wavHandler) readChunks(r io.ReaderAt, size int64) (*wavFile, error) {
+	header := make([]byte, 12)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, fmt.Errorf("failed to read WAV header: %w", err)
+	}
+	if string(header[0:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return nil, fmt.Errorf("not a valid WAV file")
+	}
+
+	wav := &wavFile{}
+	chunkHeader := make([]byte, 8)
+	pos := int64(12)
+	for pos+8 <= size {
+		if _, err := r.ReadAt(chunkHeader, pos); err != nil {
+			return nil, fmt.Errorf("failed to read RIFF chunk header: %w", err)
+		}
+		chunk := riffChunk{
+			id:     string(chunkHeader[0:4]),
+			offset: pos + 8,
+			size:   binary.LittleEndian.Uint32(chunkHeader[4:8]),
+		}
+		if chunk.offset+int64(chunk.size) > size {
+			if chunk.id != "data" {
+				return nil, fmt.Errorf("RIFF chunk %q is truncated", chunk.id)
+			}
+			// Streaming recorders sometimes leave a bogus data size behind.
+			chunk.size = uint32(size - chunk.offset)
+		}
+		wav.chunks = append(wav.chunks, chunk)
+
+		switch {
+		case chunk.id == "LIST" && h.isInfoList(r, chunk):
+			data := make([]byte, chunk.size)
+			if _, err := r.ReadAt(data, chunk.offset); err != nil {
+				return nil, fmt.Errorf("failed to read LIST chunk: %w", err)
+			}
+			wav.info = append(wav.info, parseWAVInfo(data[4:])...)
+		case h.isID3Chunk(chunk.id):
+			wav.id3 = make([]byte, chunk.size)
+			if _, err := r.ReadAt(wav.id3, chunk.offset); err != nil {
+				return nil, fmt.Errorf("failed to read id3 chunk: %w", err)
+			}
+		case chunk.id == "bext" || chunk.id == "iXML":
+			data := make([]byte, chunk.size)
+			if _, err := r.ReadAt(data, chunk.offset); err != nil {
+				return nil, fmt.Errorf("failed to read %s chunk: %w", chunk.id, err)
+			}
+			if chunk.id == "bext" {
+				wav.bext = data
+			} else {
+				wav.ixml = data
+			}
+		case chunk.id == "fmt " && chunk.size >= 8:
+			rate := make([]byte, 4)
+			if _, err := r.ReadAt(rate, chunk.offset+4); err != nil {
+				return nil, fmt.Errorf("failed to read WAV fmt chunk: %w", err)
+			}
+			wav.sampleRate = int(binary.LittleEndian.Uint32(rate))
+		}
+
+		pos = chunk.offset + int64(chunk.size) + int64(chunk.size&1)
+	}
+
+	return wav, nil
+}
+
+func (h *wavHandler) isInfoList(r io.ReaderAt, chunk riffChunk) bool {
+	if chunk.size < 4 {
+		return false
+	}
+	listType := make([]byte, 4)
+	if _, err := r.ReadAt(listType, chunk.offset); err != nil {
+		return false
+	}
+	return string(listType) == "INFO"
+}
+
+func (h *wavHandler) isID3Chunk(id string) bool {
+	return id == "id3 " || id == "ID3 "
+}
+
+func parseWAVInfo(data []byte) []wavInfoEntry {
+	var entries []wavInfoEntry
+	for len(data) >= 8 {
+		id := string(data[0:4])
+		size := int(binary.LittleEndian.Uint32(data[4:8]))
+		data = data[8:]
+		if size > len(data) {
+			break
+		}
+		value := strings.TrimRight(string(data[:size]), "\x00")
+		entries = append(entries, wavInfoEntry{id: id, value: strings.TrimSpace(value)})
+		data = data[min(size+size&1, len(data)):]
+	}
+	return entries
+}
+
+func (w *wavFile) infoValue(id string) string {
+	for _, entry := range w.info {
+		if entry.id == id {
+			return entry.value
+		}
+	}
+	return ""
+}
+
+func (w *wavFile) setInfo(id, value string, keepEmpty bool) {
+	keep := value != "" || keepEmpty
+	entries := w.info[:0]
+	replaced := false
+	for _, entry := range w.info {
+		if entry.id != id {
+			entries = append(entries, entry)
+			continue
+		}
+		if !replaced && keep {
+			entries = append(entries, wavInfoEntry{id: id, value: value})
+			replaced = true
+		}
+	}
+	if !replaced && keep {
+		entries = append(entries, wavInfoEntry{id: id, value: value})
+	}
+	w.info = entries
+}
+
+func (w *wavFile) marshalInfo() []byte {
+	if len(w.info) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	buf.WriteString("INFO")
+	for _, entry := range w.info {
+		value := append([]byte(entry.value), 0)
+		size := make([]byte, 4)
+		binary.LittleEndian.PutUint32(size, uint32(len(value)))
+		buf.WriteString(entry.id)
+		buf.Write(size)
+		buf.Write(value)
+		if len(value)&1 == 1 {
+			buf.WriteByte(0)
+		}
+	}
+	return buf.Bytes()
+}
+
+func writeRIFFChunk(w io.Writer, id string, data io.Reader, size uint32) (int64, error) {
+	header := make([]byte, 8)
+	copy(header[0:4], id)
+	binary.LittleEndian.PutUint32(header[4:8], size)
+	if _, err := w.Write(header); err != nil {
+		return 0, fmt.Errorf("failed to write %q chunk header: %w", id, err)
+	}
+	if _, err := io.CopyN(w, data, int64(size)); err != nil {
+		return 0, fmt.Errorf("failed to write %q chunk: %w", id, err)
+	}
+	written := int64(8) + int64(size)
+	if size&1 == 1 {
+		if _, err := w.Write([]byte{0}); err != nil {
+			return 0, fmt.Errorf("failed to write %q chunk padding: %w", id, err)
+		}
+		written++
+	}
+	return written, nil
+}
+
+func parseLeadingInt(value string) int {
+	value = strings.TrimSpace(value)
+	end := 0
+	for end < len(value) && value[end] >= '0' && value[end] <= '9' {
+		end++
+	}
+	number, err := strconv.Atoi(value[:end])
+	if err != nil {
+		return 0
+	}
+	return number
+}
+
+func getWAVHandler(ext string) FormatHandler {
+	ext = strings.ToUpper(ext)
+	if ext == "WAV" || ext == "WAVE" {
+		return newWAVHandler()
+	}
+	return nil
+}
@@ -0,0 +1,277 @@
+package audio
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/internal/vfs"
+)
+
+const (
+	wavPackBlockHeaderSize = 32
+
+	wavPackFlagMono    = 1 << 2
+	wavPackFlagHybrid  = 1 << 3
+	wavPackFlagFloat   = 1 << 7
+	wavPackFlagInitial = 1 << 11
+	wavPackFlagFinal   = 1 << 12
+
+	wavPackIDMask         = 0x3F
+	wavPackIDOddSize      = 0x40
+	wavPackIDLarge        = 0x80
+	wavPackIDWVCBitstream = 0x0B
+	wavPackIDSampleRate   = 0x27
+)
+
+var wavPackSampleRates = [...]int{
+	6000, 8000, 9600, 11025, 12000, 16000, 22050, 24000, 32000, 44100, 48000, 64000, 88200, 96000, 192000,
+}
+
+type wavPackBlockHeader struct {
+	size         int64 // the whole block, header included
+	totalSamples int64 // -1 when the encoder did not know it
+	blockSamples int64
+	flags        uint32
+}
+
+type wavPackStream struct {
+	samples       int64
+	sampleRate    int
+	channels      int
+	bitsPerSample int
+	hybrid        bool
+	correction    bool
+}
+
+type wavPackHandler struct{}
+
+func newWavPackHandler() *wavPackHandler {
+	return &wavPackHandler{}
+}
+
+func (h *wavPackHandler) Format() string {
+	return "WV"
+}
+
+func (h *wavPackHandler) Capabilities() model.Capabilities {
+	return apeCapabilities
+}
+
+func (h *wavPackHandler) ExtractDuration(ctx context.Context, r io.ReaderAt, size int64) (float64, error) {
+	stream, err := readWavPackStream(r, size)
+	if err != nil {
+		return 0, err
+	}
+	if stream.sampleRate == 0 || stream.samples <= 0 {
+		return 0, fmt.Errorf("could not determine WavPack duration")
+	}
+	return float64(stream.samples) / float64(stream.sampleRate), nil
+}
+
+func (h *wavPackHandler) ExtractAudioInfo(r io.ReaderAt, size int64, result *model.FileMetadata) error {
+	stream, err := readWavPackStream(r, size)
+	if err != nil {
+		return err
+	}
+	start, end, err := apeTaggedAudioRange(r, size)
+	if err != nil {
+		return err
+	}
+	switch {
+	case stream.correction:
+		result.Codec = "WavPack correction"
+	case stream.hybrid:
+		result.Codec = "WavPack hybrid"
+	default:
+		result.Codec = "WavPack"
+	}
+	// A hybrid .wv file is lossless only together with its correction file.
+	result.Lossless = !stream.hybrid
+	result.SampleRate = stream.sampleRate
+	result.Channels = stream.channels
+	result.BitsPerSample = stream.bitsPerSample
+	result.Bitrate = averageBitrate(end-start, result.Duration)
+	return nil
+}
+
+func (h *wavPackHandler) Parse(r io.ReaderAt, size int64, name string) (*model.FileMetadata, error) {
+	stream, err := readWavPackStream(r, size)
+	if err != nil {
+		return &model.FileMetadata{Title: name, Size: size, Format: h.Format()}, err
+	}
+	result, err := readAPETaggedMetadata(r, size, name, h.Format())
+	if err != nil {
+		return result, err
+	}
+	if stream.correction {
+		result.Format = "WVC"
+	}
+	if stream.sampleRate > 0 && stream.samples > 0 {
+		result.Duration = float64(stream.samples) / float64(stream.sampleRate)
+	}
+	return result, nil
+}
+
+func (h *wavPackHandler) UpdateTags(ctx context.Context, fsys vfs.FS, filePath string, update *model.TagUpdate) error {
+	file, err := fsys.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+	stream, err := readWavPackStream(file, stat.Size())
+	file.Close()
+	if err != nil {
+		return err
+	}
+	if stream.correction {
+		return fmt.Errorf("%w: WavPack correction files carry no tags, write them to the .wv file", model.ErrUnsupportedFormat)
+	}
+	return updateTrailingAPETag(fsys, filePath, update)
+}
+
+func (h *wavPackHandler) writeAudioStream(r io.ReaderAt, size int64, w io.Writer) error {
+	return writeAPETaggedAudioStream(r, size, w)
+}
+
+func readWavPackStream(r io.ReaderAt, size int64) (*wavPackStream, error) {
+	start, end, err := apeTaggedAudioRange(r, size)
+	if err != nil {
+		return nil, err
+	}
+	first, err := readWavPackBlockHeader(r, start, end)
+	if err != nil {
+		return nil, err
+	}
+	stream := &wavPackStream{
+		samples:       first.totalSamples,
+		bitsPerSample: int(first.flags&3+1) * 8,
+		hybrid:        first.flags&wavPackFlagHybrid != 0,
+	}
+	if first.flags&wavPackFlagFloat != 0 {
+		stream.bitsPerSample = 32
+	}
+	if index := first.flags >> 23 & 0xF; int(index) < len(wavPackSampleRates) {
+		stream.sampleRate = wavPackSampleRates[index]
+	}
+
+	data := make([]byte, first.size-wavPackBlockHeaderSize)
+	if _, err := r.ReadAt(data, start+wavPackBlockHeaderSize); err != nil {
+		return nil, fmt.Errorf("failed to read WavPack block: %w", unexpectedEOF(err))
+	}
+	readWavPackSubBlocks(data, stream)
+
+	unknownLength := stream.samples < 0
+	if unknownLength {
+		stream.samples = 0
+	}
+	frameDone := false
+	for offset, block := start, first; ; {
+		if !frameDone {
+			stream.channels += 2
+			if block.flags&wavPackFlagMono != 0 {
+				stream.channels--
+			}
+			frameDone = block.flags&wavPackFlagFinal != 0
+		}
+		if unknownLength && block.flags&wavPackFlagInitial != 0 {
+			stream.samples += block.blockSamples
+		}
+		offset += block.size
+		if frameDone && !unknownLength || offset+wavPackBlockHeaderSize > end {
+			break
+		}
+		if block, err = readWavPackBlockHeader(r, offset, end); err != nil {
+			return nil, err
+		}
+	}
+	return stream, nil
+}
+
+func readWavPackBlockHeader(r io.ReaderAt, offset, end int64) (*wavPackBlockHeader, error) {
+	buf := make([]byte, wavPackBlockHeaderSize)
+	if _, err := r.ReadAt(buf, offset); err != nil {
+		return nil, fmt.Errorf("failed to read WavPack block header: %w", unexpectedEOF(err))
+	}
+	if string(buf[0:4]) != "wvpk" {
+		return nil, fmt.Errorf("not a valid WavPack block at offset %d", offset)
+	}
+	block := &wavPackBlockHeader{
+		size:         int64(binary.LittleEndian.Uint32(buf[4:8])) + 8,
+		blockSamples: int64(binary.LittleEndian.Uint32(buf[20:24])),
+		flags:        binary.LittleEndian.Uint32(buf[24:28]),
+	}
+	if block.size < wavPackBlockHeaderSize || offset+block.size > end {
+		return nil, fmt.Errorf("WavPack block at offset %d is truncated", offset)
+	}
+	totalSamples := binary.LittleEndian.Uint32(buf[12:16])
+	if totalSamples == 0xFFFFFFFF {
+		block.totalSamples = -1
+	} else {
+		high := int64(buf[11])
+		block.totalSamples = int64(totalSamples) + high<<32 - high
+	}
+	return block, nil
+}
+
+func readWavPackSubBlocks(data []byte, stream *wavPackStream) {
+	for len(data) >= 2 {
+		id := data[0]
+		length, headerSize := int(data[1])*2, 2
+		if id&wavPackIDLarge != 0 {
+			if len(data) < 4 {
+				return
+			}
+			length = (int(data[1]) | int(data[2])<<8 | int(data[3])<<16) * 2
+			headerSize = 4
+		}
+		if headerSize+length > len(data) {
+			return
+		}
+		value := data[headerSize : headerSize+length]
+		if id&wavPackIDOddSize != 0 && length > 0 {
+			value = value[:length-1]
+		}
+		switch id & wavPackIDMask {
+		case wavPackIDWVCBitstream:
+			stream.correction = true
+		case wavPackIDSampleRate:
+			if len(value) >= 3 {
+				stream.sampleRate = int(value[0]) | int(value[1])<<8 | int(value[2])<<16
+			}
+		}
+		data = data[headerSize+length:]
+	}
+}
+
+// CorrectionFile returns the .wvc file that belongs to path, or "".
+func CorrectionFile(fsys vfs.FS, path string) string {
+	ext := filepath.Ext(path)
+	if !strings.EqualFold(ext, ".wv") {
+		return ""
+	}
+	correction := path + "c"
+	if ext == ".WV" {
+		correction = path + "C"
+	}
+	if _, err := fsys.Stat(correction); err != nil {
+		return ""
+	}
+	return correction
+}
+
+func getWavPackHandler(ext string) FormatHandler {
+	ext = strings.ToUpper(ext)
+	if ext == "WV" {
+		return newWavPackHandler()
+	}
+	return nil
+}
@@ -0,0 +1,7 @@
+//go:build !linux && !darwin
+
+package audio
+
+func copyXattrs(src, dst string) error {
+	return nil
+}
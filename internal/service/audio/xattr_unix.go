@@ -0,0 +1,83 @@
+//go:build linux || darwin
+
+package audio
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+func copyXattrs(src, dst string) error {
+	names, err := listXattrs(src)
+	if err != nil {
+		if xattrUnsupported(err) {
+			return nil
+		}
+		return err
+	}
+	for _, name := range names {
+		value, err := getXattr(src, name)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if current, err := getXattr(dst, name); err == nil && bytes.Equal(current, value) {
+			continue
+		}
+		if err := unix.Setxattr(dst, name, value, 0); err != nil {
+			if xattrUnsupported(err) || errors.Is(err, unix.EPERM) {
+				slog.Debug("copyXattrs: skipping extended attribute", slog.String("name", name), slog.Any("error", err))
+				continue
+			}
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func xattrUnsupported(err error) bool {
+	return errors.Is(err, unix.ENOTSUP) || errors.Is(err, unix.EOPNOTSUPP)
+}
+
+func listXattrs(path string) ([]string, error) {
+	buf, err := readXattr(func(dest []byte) (int, error) { return unix.Listxattr(path, dest) })
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, name := range strings.Split(string(buf), "\x00") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+func getXattr(path, name string) ([]byte, error) {
+	return readXattr(func(dest []byte) (int, error) { return unix.Getxattr(path, name, dest) })
+}
+
+func readXattr(read func(dest []byte) (int, error)) ([]byte, error) {
+	for {
+		size, err := read(nil)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return nil, nil
+		}
+		buf := make([]byte, size)
+		n, err := read(buf)
+		if errors.Is(err, unix.ERANGE) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	}
+}
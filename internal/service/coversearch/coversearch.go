@@ -0,0 +1,203 @@
+package coversearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/internal/service/musicbrainz"
+)
+
+const iTunesImageSize = 1200
+
+// ReleaseSearch finds the MusicBrainz releases whose covers the Cover Art Archive is asked for.
+type ReleaseSearch interface {
+	SearchReleases(ctx context.Context, artist, album string) ([]musicbrainz.Release, error)
+}
+
+// Searcher looks up album covers in the Cover Art Archive and iTunes.
+type Searcher struct {
+	httpClient  *http.Client
+	releases    ReleaseSearch
+	coverArtURL string
+	iTunesURL   string
+	limit       int
+}
+
+func NewSearcher(releases ReleaseSearch, coverArtURL, iTunesURL string, timeout time.Duration, limit int) *Searcher {
+	return &Searcher{
+		httpClient:  &http.Client{Timeout: timeout},
+		releases:    releases,
+		coverArtURL: strings.TrimSuffix(coverArtURL, "/"),
+		iTunesURL:   strings.TrimSuffix(iTunesURL, "/"),
+		limit:       limit,
+	}
+}
+
+// Find returns covers for the artist and album from the given sources.
+func (s *Searcher) Find(ctx context.Context, query model.CoverQuery, sources []string) ([]model.CoverCandidate, error) {
+	if len(sources) == 0 {
+		sources = []string{model.CoverSourceCoverArtArchive, model.CoverSourceITunes}
+	}
+
+	candidates := []model.CoverCandidate{}
+	var errs []error
+	for _, source := range sources {
+		var found []model.CoverCandidate
+		var err error
+		switch source {
+		case model.CoverSourceCoverArtArchive:
+			found, err = s.coverArtArchive(ctx, query)
+		case model.CoverSourceITunes:
+			found, err = s.iTunes(ctx, query)
+		default:
+			err = fmt.Errorf("unsupported cover source %q", source)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		candidates = append(candidates, found...)
+	}
+	if len(errs) == len(sources) {
+		return nil, errors.Join(errs...)
+	}
+	return candidates, nil
+}
+
+type coverArtResponse struct {
+	Images []struct {
+		Front      bool   `json:"front"`
+		Image      string `json:"image"`
+		Thumbnails struct {
+			Small string `json:"250"`
+			Large string `json:"500"`
+		} `json:"thumbnails"`
+	} `json:"images"`
+}
+
+func (s *Searcher) coverArtArchive(ctx context.Context, query model.CoverQuery) ([]model.CoverCandidate, error) {
+	releases, err := s.releases.SearchReleases(ctx, query.Artist, query.Album)
+	if err != nil {
+		return nil, err
+	}
+	if s.limit > 0 && len(releases) > s.limit {
+		releases = releases[:s.limit]
+	}
+
+	var candidates []model.CoverCandidate
+	for _, release := range releases {
+		var response coverArtResponse
+		found, err := s.get(ctx, s.coverArtURL+"/release/"+url.PathEscape(release.ID), &response)
+		if err != nil {
+			return nil, fmt.Errorf("Cover Art Archive: %w", err)
+		}
+		if !found {
+			continue
+		}
+		for _, image := range response.Images {
+			if !image.Front {
+				continue
+			}
+			thumbnail := image.Thumbnails.Small
+			if thumbnail == "" {
+				thumbnail = image.Image
+			}
+			candidates = append(
+				candidates, model.CoverCandidate{
+					Source:       model.CoverSourceCoverArtArchive,
+					Artist:       release.Artist,
+					Album:        release.Title,
+					Year:         release.Year,
+					ThumbnailURL: secureURL(thumbnail),
+					ImageURL:     secureURL(image.Image),
+					ReleaseID:    release.ID,
+				},
+			)
+			break
+		}
+	}
+	return candidates, nil
+}
+
+type iTunesResponse struct {
+	Results []struct {
+		ArtistName     string `json:"artistName"`
+		CollectionName string `json:"collectionName"`
+		ArtworkURL100  string `json:"artworkUrl100"`
+		ReleaseDate    string `json:"releaseDate"`
+	} `json:"results"`
+}
+
+func (s *Searcher) iTunes(ctx context.Context, query model.CoverQuery) ([]model.CoverCandidate, error) {
+	params := url.Values{}
+	params.Set("term", strings.TrimSpace(query.Artist+" "+query.Album))
+	params.Set("entity", "album")
+	if s.limit > 0 {
+		params.Set("limit", strconv.Itoa(s.limit))
+	}
+
+	var response iTunesResponse
+	if _, err := s.get(ctx, s.iTunesURL+"/search?"+params.Encode(), &response); err != nil {
+		return nil, fmt.Errorf("iTunes Search: %w", err)
+	}
+
+	var candidates []model.CoverCandidate
+	size := strconv.Itoa(iTunesImageSize)
+	for _, result := range response.Results {
+		if result.ArtworkURL100 == "" {
+			continue
+		}
+		year, _ := strconv.Atoi(result.ReleaseDate[:min(4, len(result.ReleaseDate))])
+		candidates = append(
+			candidates, model.CoverCandidate{
+				Source:       model.CoverSourceITunes,
+				Artist:       result.ArtistName,
+				Album:        result.CollectionName,
+				Year:         year,
+				ThumbnailURL: result.ArtworkURL100,
+				ImageURL:     strings.Replace(result.ArtworkURL100, "100x100", size+"x"+size, 1),
+			},
+		)
+	}
+	return candidates, nil
+}
+
+func (s *Searcher) get(ctx context.Context, rawURL string, out any) (found bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("returned status %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return false, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return true, nil
+}
+
+func secureURL(rawURL string) string {
+	if rest, ok := strings.CutPrefix(rawURL, "http://"); ok {
+		return "https://" + rest
+	}
+	return rawURL
+}
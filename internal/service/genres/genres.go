@@ -0,0 +1,131 @@
+package genres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+)
+
+const (
+	SourceMap    = "map"
+	SourceLastFM = "lastfm"
+)
+
+const minTagWeight = 0.1
+
+var notGenres = map[string]bool{
+	"seen live": true, "favorites": true, "favourites": true, "favorite": true, "favourite": true,
+	"love": true, "loved": true, "awesome": true, "beautiful": true, "my favorites": true,
+	"female vocalists": true, "male vocalists": true, "female vocalist": true, "male vocalist": true,
+	"singer-songwriter": true, "under 2000 listeners": true, "albums i own": true, "spotify": true,
+}
+
+// Suggester proposes genres for an artist and title.
+type Suggester struct {
+	lastFM  *LastFM
+	artists map[string][]string
+	limit   int
+}
+
+// NewSuggester loads mapFile when it is not empty; lastFM may be nil.
+func NewSuggester(lastFM *LastFM, mapFile string, limit int) (*Suggester, error) {
+	s := &Suggester{lastFM: lastFM, artists: make(map[string][]string), limit: limit}
+	if mapFile == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(mapFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read genre map: %w", err)
+	}
+	var artists map[string][]string
+	if err := json.Unmarshal(data, &artists); err != nil {
+		return nil, fmt.Errorf("failed to parse genre map: %w", err)
+	}
+	for artist, genres := range artists {
+		s.artists[artistKey(artist)] = genres
+	}
+	return s, nil
+}
+
+// Suggest returns genres for the artist and title of query, most likely first.
+func (s *Suggester) Suggest(ctx context.Context, query model.LookupQuery) ([]model.GenreSuggestion, error) {
+	if query.Artist == "" {
+		return nil, nil
+	}
+	suggestions := make(map[string]model.GenreSuggestion)
+	add := func(genre string, confidence float64, source string) {
+		key := strings.ToLower(genre)
+		if existing, ok := suggestions[key]; !ok || existing.Confidence < confidence {
+			suggestions[key] = model.GenreSuggestion{Genre: genre, Confidence: confidence, Source: source}
+		}
+	}
+
+	for _, genre := range s.artists[artistKey(query.Artist)] {
+		if genre = strings.TrimSpace(genre); genre != "" {
+			add(genre, 1, SourceMap)
+		}
+	}
+	if s.lastFM != nil {
+		tags, err := s.lastFM.tags(ctx, query.Artist, query.Title)
+		if err != nil {
+			return nil, err
+		}
+		for _, tag := range tags {
+			name := strings.ToLower(strings.TrimSpace(tag.name))
+			if tag.weight < minTagWeight || name == "" || notGenres[name] || isDecade(name) {
+				continue
+			}
+			add(genreName(name), tag.weight, SourceLastFM)
+		}
+	}
+
+	result := make([]model.GenreSuggestion, 0, len(suggestions))
+	for _, suggestion := range suggestions {
+		result = append(result, suggestion)
+	}
+	sort.Slice(
+		result, func(i, j int) bool {
+			if result[i].Confidence != result[j].Confidence {
+				return result[i].Confidence > result[j].Confidence
+			}
+			return result[i].Genre < result[j].Genre
+		},
+	)
+	if s.limit > 0 && len(result) > s.limit {
+		result = result[:s.limit]
+	}
+	return result, nil
+}
+
+func artistKey(artist string) string {
+	return strings.ToLower(strings.Join(strings.Fields(artist), " "))
+}
+
+func isDecade(tag string) bool {
+	digits := strings.TrimSuffix(strings.TrimSuffix(tag, "s"), "'")
+	if digits == tag || len(digits) != 2 && len(digits) != 4 {
+		return false
+	}
+	return strings.Trim(digits, "0123456789") == "" && strings.HasSuffix(digits, "0")
+}
+
+func genreName(tag string) string {
+	if name := Canonical(tag); name != "" {
+		return name
+	}
+	runes := []rune(tag)
+	start := true
+	for i, r := range runes {
+		if start {
+			runes[i] = unicode.ToUpper(r)
+		}
+		start = r == ' ' || r == '-' || r == '/'
+	}
+	return string(runes)
+}
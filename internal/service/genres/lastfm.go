@@ -0,0 +1,113 @@
+package genres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const minRequestInterval = time.Second / 5
+
+// LastFM reads the top tags of tracks and artists.
+type LastFM struct {
+	httpClient  *http.Client
+	baseURL     string
+	apiKey      string
+	mu          sync.Mutex
+	lastRequest time.Time
+}
+
+func NewLastFM(baseURL, apiKey string, timeout time.Duration) *LastFM {
+	return &LastFM{
+		httpClient: &http.Client{Timeout: timeout},
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		apiKey:     apiKey,
+	}
+}
+
+type topTagsResponse struct {
+	Error   int    `json:"error"`
+	Message string `json:"message"`
+	TopTags struct {
+		Tag []struct {
+			Name  string `json:"name"`
+			Count int    `json:"count"` // weight relative to the top tag, which has 100
+		} `json:"tag"`
+	} `json:"toptags"`
+}
+
+type weightedTag struct {
+	name   string
+	weight float64
+}
+
+func (c *LastFM) tags(ctx context.Context, artist, title string) ([]weightedTag, error) {
+	if title != "" {
+		tags, err := c.topTags(ctx, url.Values{"method": {"track.getTopTags"}, "artist": {artist}, "track": {title}})
+		if err != nil || len(tags) > 0 {
+			return tags, err
+		}
+	}
+	return c.topTags(ctx, url.Values{"method": {"artist.getTopTags"}, "artist": {artist}})
+}
+
+func (c *LastFM) topTags(ctx context.Context, params url.Values) ([]weightedTag, error) {
+	if err := c.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	params.Set("api_key", c.apiKey)
+	params.Set("autocorrect", "1")
+	params.Set("format", "json")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Last.fm request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Last.fm request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var response topTagsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode Last.fm response (status %d): %w", resp.StatusCode, err)
+	}
+	// Error 6 means the track or artist is unknown, which is no failure.
+	if response.Error == 6 {
+		return nil, nil
+	}
+	if response.Error != 0 {
+		return nil, fmt.Errorf("Last.fm returned an error: %s", response.Message)
+	}
+
+	tags := make([]weightedTag, 0, len(response.TopTags.Tag))
+	for _, tag := range response.TopTags.Tag {
+		tags = append(tags, weightedTag{name: tag.Name, weight: float64(tag.Count) / 100})
+	}
+	return tags, nil
+}
+
+func (c *LastFM) wait(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delay := minRequestInterval - time.Since(c.lastRequest)
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	c.lastRequest = time.Now()
+	return nil
+}
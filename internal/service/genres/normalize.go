@@ -0,0 +1,283 @@
+package genres
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+)
+
+// ID3v1 is the genre list of the ID3v1 specification with the Winamp extensions.
+var ID3v1 = [...]string{
+	"Blues", "Classic Rock", "Country", "Dance", "Disco", "Funk", "Grunge",
+	"Hip-Hop", "Jazz", "Metal", "New Age", "Oldies", "Other", "Pop", "R&B",
+	"Rap", "Reggae", "Rock", "Techno", "Industrial", "Alternative", "Ska",
+	"Death Metal", "Pranks", "Soundtrack", "Euro-Techno", "Ambient",
+	"Trip-Hop", "Vocal", "Jazz+Funk", "Fusion", "Trance", "Classical",
+	"Instrumental", "Acid", "House", "Game", "Sound Clip", "Gospel",
+	"Noise", "AlternRock", "Bass", "Soul", "Punk", "Space", "Meditative",
+	"Instrumental Pop", "Instrumental Rock", "Ethnic", "Gothic",
+	"Darkwave", "Techno-Industrial", "Electronic", "Pop-Folk",
+	"Eurodance", "Dream", "Southern Rock", "Comedy", "Cult", "Gangsta",
+	"Top 40", "Christian Rap", "Pop/Funk", "Jungle", "Native American",
+	"Cabaret", "New Wave", "Psychadelic", "Rave", "Showtunes", "Trailer",
+	"Lo-Fi", "Tribal", "Acid Punk", "Acid Jazz", "Polka", "Retro",
+	"Musical", "Rock & Roll", "Hard Rock", "Folk", "Folk-Rock",
+	"National Folk", "Swing", "Fast Fusion", "Bebob", "Latin", "Revival",
+	"Celtic", "Bluegrass", "Avantgarde", "Gothic Rock", "Progressive Rock",
+	"Psychedelic Rock", "Symphonic Rock", "Slow Rock", "Big Band",
+	"Chorus", "Easy Listening", "Acoustic", "Humour", "Speech", "Chanson",
+	"Opera", "Chamber Music", "Sonata", "Symphony", "Booty Bass", "Primus",
+	"Porn Groove", "Satire", "Slow Jam", "Club", "Tango", "Samba",
+	"Folklore", "Ballad", "Power Ballad", "Rhythmic Soul", "Freestyle",
+	"Duet", "Punk Rock", "Drum Solo", "Acapella", "Euro-House", "Dance Hall",
+}
+
+var id3v1Spellings = map[string]string{
+	"AlternRock":  "Alternative Rock",
+	"Psychadelic": "Psychedelic",
+	"Showtunes":   "Show Tunes",
+	"Bebob":       "Bebop",
+	"Avantgarde":  "Avant-Garde",
+	"Acapella":    "A Cappella",
+}
+
+var modernGenres = []string{
+	"Afrobeat", "Alternative Metal", "Anime", "Black Metal", "Bossa Nova", "Breakbeat", "Chillout",
+	"Children's Music", "Christmas", "Contemporary Christian", "Dancehall", "Deep House", "Drum and Bass",
+	"Dub", "Dubstep", "EDM", "Electro", "Emo", "Enka", "Flamenco", "Garage Rock", "Grime", "Hardcore",
+	"Hard Techno", "IDM", "Indie", "Indie Pop", "Indie Rock", "J-Pop", "J-Rock", "K-Pop", "Math Rock",
+	"Metalcore", "MPB", "Neo-Soul", "Nu Metal", "Post-Punk", "Post-Rock", "Power Metal", "Reggaeton",
+	"Salsa", "Schlager", "Shoegaze", "Singer-Songwriter", "Synthpop", "Synthwave", "Thrash Metal", "Trap",
+	"UK Garage", "World",
+}
+
+var aliases = map[string]string{
+	"hip hop":           "Hip-Hop",
+	"rnb":               "R&B",
+	"r'n'b":             "R&B",
+	"rhythm and blues":  "R&B",
+	"rock n roll":       "Rock & Roll",
+	"rock and roll":     "Rock & Roll",
+	"rock'n'roll":       "Rock & Roll",
+	"alt rock":          "Alternative Rock",
+	"drum n bass":       "Drum and Bass",
+	"drum & bass":       "Drum and Bass",
+	"dnb":               "Drum and Bass",
+	"d&b":               "Drum and Bass",
+	"electronica":       "Electronic",
+	"electro pop":       "Synthpop",
+	"electropop":        "Synthpop",
+	"synth pop":         "Synthpop",
+	"classic":           "Classical",
+	"classical music":   "Classical",
+	"raggae":            "Reggae",
+	"regae":             "Reggae",
+	"ost":               "Soundtrack",
+	"soundtracks":       "Soundtrack",
+	"score":             "Soundtrack",
+	"chill out":         "Chillout",
+	"lofi":              "Lo-Fi",
+	"kpop":              "K-Pop",
+	"jpop":              "J-Pop",
+	"psychadelic rock":  "Psychedelic Rock",
+	"rock n' roll":      "Rock & Roll",
+	"world music":       "World",
+	"audiobook":         "Speech",
+	"spoken word":       "Speech",
+	"christmas music":   "Christmas",
+	"children's":        "Children's Music",
+	"kids":              "Children's Music",
+	"singer songwriter": "Singer-Songwriter",
+	"metal core":        "Metalcore",
+	"post punk":         "Post-Punk",
+	"post rock":         "Post-Rock",
+	"neo soul":          "Neo-Soul",
+}
+
+var localized = map[string]map[string]string{
+	"de": {
+		"Classical": "Klassik", "Soundtrack": "Filmmusik", "Electronic": "Elektronische Musik",
+		"Folk": "Volksmusik", "Children's Music": "Kindermusik", "Christmas": "Weihnachtsmusik",
+		"Speech": "Hörbuch", "Comedy": "Kabarett", "Chamber Music": "Kammermusik", "Opera": "Oper",
+		"Symphony": "Sinfonie", "World": "Weltmusik", "Easy Listening": "Unterhaltungsmusik",
+	},
+	"fr": {
+		"Classical": "Musique classique", "Soundtrack": "Bande originale", "Electronic": "Musique électronique",
+		"Chanson": "Chanson française", "Children's Music": "Musique pour enfants", "Christmas": "Musique de Noël",
+		"Speech": "Livre audio", "Chamber Music": "Musique de chambre", "Opera": "Opéra",
+		"Symphony": "Symphonie", "World": "Musiques du monde", "Folk": "Musique folk", "Humour": "Humour",
+	},
+	"es": {
+		"Classical": "Clásica", "Soundtrack": "Banda sonora", "Electronic": "Electrónica",
+		"Latin": "Música latina", "Children's Music": "Música infantil", "Christmas": "Navidad",
+		"Speech": "Audiolibro", "Chamber Music": "Música de cámara", "Opera": "Ópera",
+		"Symphony": "Sinfonía", "World": "Músicas del mundo", "Folk": "Folclore",
+	},
+	"it": {
+		"Classical": "Musica classica", "Soundtrack": "Colonna sonora", "Electronic": "Musica elettronica",
+		"Singer-Songwriter": "Cantautori", "Children's Music": "Musica per bambini", "Christmas": "Musica natalizia",
+		"Speech": "Audiolibro", "Chamber Music": "Musica da camera", "Opera": "Lirica",
+		"Symphony": "Sinfonia", "World": "World music", "Folk": "Musica popolare",
+	},
+	"pt": {
+		"Classical": "Música clássica", "Soundtrack": "Trilha sonora", "Electronic": "Eletrônica",
+		"Children's Music": "Música infantil", "Christmas": "Natal", "Speech": "Audiolivro",
+		"Chamber Music": "Música de câmara", "Opera": "Ópera", "Symphony": "Sinfonia", "World": "World music",
+	},
+	"ru": {
+		"Rock": "Рок", "Pop": "Поп", "Jazz": "Джаз", "Blues": "Блюз", "Classical": "Классическая музыка",
+		"Chanson": "Шансон", "Electronic": "Электроника", "Soundtrack": "Саундтрек", "Rap": "Рэп",
+		"Hip-Hop": "Хип-хоп", "Metal": "Метал", "Folk": "Фолк", "Punk": "Панк", "Dance": "Танцевальная",
+		"Reggae": "Регги", "Country": "Кантри", "Children's Music": "Детская музыка", "Speech": "Аудиокнига",
+		"Alternative": "Альтернатива", "Indie": "Инди", "Opera": "Опера", "World": "Музыка мира",
+	},
+	"ja": {
+		"Rock": "ロック", "Pop": "ポップス", "Jazz": "ジャズ", "Classical": "クラシック", "Soundtrack": "サウンドトラック",
+		"Anime": "アニメ", "Enka": "演歌", "Electronic": "エレクトロニック", "Hip-Hop": "ヒップホップ",
+		"Blues": "ブルース", "Metal": "メタル", "Children's Music": "童謡", "World": "ワールド",
+		"Dance": "ダンス", "Alternative": "オルタナティブ",
+	},
+}
+
+var extraLocalNames = map[string]string{
+	"klassische musik":  "Classical",
+	"musique classique": "Classical",
+	"música clásica":    "Classical",
+	"классика":          "Classical",
+	"эстрада":           "Pop",
+	"поп-музыка":        "Pop",
+	"рок-музыка":        "Rock",
+	"электронная":       "Electronic",
+	"ポップ":               "Pop",
+	"variété française": "Pop",
+	"rap français":      "Rap",
+	"canzone":           "Pop",
+}
+
+var (
+	canonical []string
+	byKey     = make(map[string]string)
+	namesOf   = make(map[string][]string)
+)
+
+func init() {
+	addName := func(name, genre string) {
+		key := genreKey(name)
+		if _, ok := byKey[key]; ok {
+			return
+		}
+		byKey[key] = genre
+		namesOf[genre] = append(namesOf[genre], key)
+	}
+	addGenre := func(genre string) {
+		if _, ok := byKey[genreKey(genre)]; !ok {
+			canonical = append(canonical, genre)
+		}
+		addName(genre, genre)
+	}
+	for _, name := range ID3v1 {
+		if fixed, ok := id3v1Spellings[name]; ok {
+			addGenre(fixed)
+			addName(name, fixed)
+			continue
+		}
+		addGenre(name)
+	}
+	for _, genre := range modernGenres {
+		addGenre(genre)
+	}
+	for alias, genre := range aliases {
+		addName(alias, genre)
+	}
+	for _, names := range localized {
+		for genre, name := range names {
+			addName(name, genre)
+		}
+	}
+	for name, genre := range extraLocalNames {
+		addName(name, genre)
+	}
+	slices.SortFunc(canonical, func(a, b string) int { return strings.Compare(strings.ToLower(a), strings.ToLower(b)) })
+}
+
+func genreKey(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '&' || r == '+' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Canonical returns the canonical name of genre, or "" when it is unknown.
+func Canonical(genre string) string {
+	return byKey[genreKey(genre)]
+}
+
+// Normalize rewrites a genre tag to canonical names.
+func Normalize(genre string) string {
+	var parts []string
+	for _, part := range strings.Split(genre, ";") {
+		part = strings.TrimSpace(id3v1Reference(strings.TrimSpace(part)))
+		if part == "" {
+			continue
+		}
+		if name := Canonical(part); name != "" {
+			part = name
+		}
+		if !slices.Contains(parts, part) {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+func id3v1Reference(genre string) string {
+	if rest, ok := strings.CutPrefix(genre, "("); ok {
+		number, refinement, found := strings.Cut(rest, ")")
+		if found && strings.TrimSpace(refinement) != "" {
+			return refinement
+		}
+		if found {
+			genre = number
+		}
+	}
+	switch genre {
+	case "RX":
+		return "Remix"
+	case "CR":
+		return "Cover"
+	}
+	if index, err := strconv.Atoi(genre); err == nil && index >= 0 && index < len(ID3v1) {
+		return ID3v1[index]
+	}
+	return genre
+}
+
+// List returns the canonical genres starting with query.
+func List(query, lang string, limit int) []model.Genre {
+	key := genreKey(query)
+	names := localized[strings.ToLower(strings.SplitN(lang, "-", 2)[0])]
+	var primary, secondary []model.Genre
+	for _, genre := range canonical {
+		entry := model.Genre{Genre: genre, Name: genre}
+		if name, ok := names[genre]; ok {
+			entry.Name = name
+		}
+		switch {
+		case strings.HasPrefix(genreKey(genre), key) || strings.HasPrefix(genreKey(entry.Name), key):
+			primary = append(primary, entry)
+		case slices.ContainsFunc(namesOf[genre], func(name string) bool { return strings.HasPrefix(name, key) }):
+			secondary = append(secondary, entry)
+		}
+	}
+	result := append(primary, secondary...)
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
@@ -0,0 +1,72 @@
+package integrity
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+var (
+	ErrDecoderUnavailable = errors.New("ffmpeg binary not found")
+	ErrUnsupportedDepth   = errors.New("bit depth cannot be verified")
+)
+
+var pcmFormats = map[int]string{
+	8:  "s8",
+	16: "s16le",
+	24: "s24le",
+	32: "s32le",
+}
+
+// Verifier decodes files with ffmpeg and hashes the samples.
+type Verifier struct {
+	ffmpegPath string
+}
+
+func NewVerifier(ffmpegPath string) *Verifier {
+	return &Verifier{ffmpegPath: ffmpegPath}
+}
+
+// DecodedMD5 returns the hex MD5 of the decoded samples of the file.
+func (v *Verifier) DecodedMD5(ctx context.Context, filePath string, bitsPerSample int) (string, error) {
+	format, ok := pcmFormats[bitsPerSample]
+	if !ok {
+		return "", fmt.Errorf("%w: %d bits", ErrUnsupportedDepth, bitsPerSample)
+	}
+	ffmpeg, err := exec.LookPath(v.ffmpegPath)
+	if err != nil {
+		return "", ErrDecoderUnavailable
+	}
+
+	cmd := exec.CommandContext(
+		ctx, ffmpeg, "-nostdin", "-v", "error", "-i", filePath, "-map", "0:a:0",
+		"-f", format, "-acodec", "pcm_"+format, "-",
+	)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", fmt.Errorf("failed to open ffmpeg output: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("failed to start ffmpeg: %w", err)
+	}
+
+	hash := md5.New()
+	_, readErr := io.Copy(hash, stdout)
+	if err := cmd.Wait(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("ffmpeg failed: %s", msg)
+		}
+		return "", fmt.Errorf("ffmpeg failed: %w", err)
+	}
+	if readErr != nil {
+		return "", fmt.Errorf("failed to read decoded audio: %w", readErr)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
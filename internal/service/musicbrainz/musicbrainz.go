@@ -0,0 +1,273 @@
+package musicbrainz
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+)
+
+const minRequestInterval = time.Second
+
+type Client struct {
+	httpClient  *http.Client
+	baseURL     string
+	userAgent   string
+	limit       int
+	mu          sync.Mutex
+	lastRequest time.Time
+}
+
+func NewClient(baseURL, userAgent string, timeout time.Duration, limit int) *Client {
+	return &Client{
+		httpClient: &http.Client{Timeout: timeout},
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		userAgent:  userAgent,
+		limit:      limit,
+	}
+}
+
+type recordingSearchResponse struct {
+	Recordings []recording `json:"recordings"`
+}
+
+type recording struct {
+	ID           string         `json:"id"`
+	Score        int            `json:"score"`
+	Title        string         `json:"title"`
+	Length       int            `json:"length"`
+	ArtistCredit []artistCredit `json:"artist-credit"`
+	Releases     []release      `json:"releases"`
+}
+
+type artistCredit struct {
+	Name       string `json:"name"`
+	JoinPhrase string `json:"joinphrase"`
+	Artist     struct {
+		ID string `json:"id"`
+	} `json:"artist"`
+}
+
+type release struct {
+	ID           string         `json:"id"`
+	Title        string         `json:"title"`
+	Date         string         `json:"date"`
+	ArtistCredit []artistCredit `json:"artist-credit"`
+	ReleaseGroup struct {
+		ID string `json:"id"`
+	} `json:"release-group"`
+	Media []struct {
+		Position   int `json:"position"`
+		TrackCount int `json:"track-count"`
+		Track      []struct {
+			ID       string `json:"id"`
+			Number   string `json:"number"`
+			Position int    `json:"position"`
+		} `json:"track"`
+	} `json:"media"`
+}
+
+// Lookup searches recordings matching the query, best matches first.
+func (c *Client) Lookup(ctx context.Context, query model.LookupQuery) ([]model.LookupCandidate, error) {
+	luceneQuery := buildQuery(query)
+	if luceneQuery == "" {
+		return nil, fmt.Errorf("lookup query is empty")
+	}
+
+	params := url.Values{}
+	params.Set("query", luceneQuery)
+	params.Set("fmt", "json")
+	params.Set("limit", strconv.Itoa(c.limit))
+
+	var response recordingSearchResponse
+	if err := c.get(ctx, "/recording?"+params.Encode(), &response); err != nil {
+		return nil, err
+	}
+
+	candidates := make([]model.LookupCandidate, 0, len(response.Recordings))
+	for _, rec := range response.Recordings {
+		artist, artistIDs := joinArtistCredit(rec.ArtistCredit)
+		base := model.LookupCandidate{
+			Score:       rec.Score,
+			Title:       rec.Title,
+			Artist:      artist,
+			Duration:    float64(rec.Length) / 1000,
+			RecordingID: rec.ID,
+		}
+
+		if len(rec.Releases) == 0 {
+			base.CustomTags = map[string]string{
+				"MUSICBRAINZ_TRACKID":  rec.ID,
+				"MUSICBRAINZ_ARTISTID": strings.Join(artistIDs, "; "),
+			}
+			candidates = append(candidates, base)
+			continue
+		}
+
+		for _, rel := range rec.Releases {
+			candidate := base
+			candidate.Album = rel.Title
+			candidate.ReleaseID = rel.ID
+			candidate.Year = parseYear(rel.Date)
+			candidate.CustomTags = map[string]string{
+				"MUSICBRAINZ_TRACKID":        rec.ID,
+				"MUSICBRAINZ_ARTISTID":       strings.Join(artistIDs, "; "),
+				"MUSICBRAINZ_ALBUMID":        rel.ID,
+				"MUSICBRAINZ_RELEASEGROUPID": rel.ReleaseGroup.ID,
+			}
+			if _, albumArtistIDs := joinArtistCredit(rel.ArtistCredit); len(albumArtistIDs) > 0 {
+				candidate.CustomTags["MUSICBRAINZ_ALBUMARTISTID"] = strings.Join(albumArtistIDs, "; ")
+			}
+			if len(rel.Media) > 0 {
+				medium := rel.Media[0]
+				candidate.Disc = medium.Position
+				candidate.TotalTracks = medium.TrackCount
+				if len(medium.Track) > 0 {
+					candidate.Track = medium.Track[0].Position
+					candidate.CustomTags["MUSICBRAINZ_RELEASETRACKID"] = medium.Track[0].ID
+				}
+			}
+			candidates = append(candidates, candidate)
+		}
+	}
+
+	return candidates, nil
+}
+
+type releaseSearchResponse struct {
+	Releases []release `json:"releases"`
+}
+
+// Release is a release found by SearchReleases.
+type Release struct {
+	ID     string
+	Title  string
+	Artist string
+	Year   int
+}
+
+// SearchReleases returns the releases matching an artist and album, best matches first.
+func (c *Client) SearchReleases(ctx context.Context, artist, album string) ([]Release, error) {
+	var parts []string
+	if album != "" {
+		parts = append(parts, "release:"+quote(album))
+	}
+	if artist != "" {
+		parts = append(parts, "artist:"+quote(artist))
+	}
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("release query is empty")
+	}
+
+	params := url.Values{}
+	params.Set("query", strings.Join(parts, " AND "))
+	params.Set("fmt", "json")
+	params.Set("limit", strconv.Itoa(c.limit))
+
+	var response releaseSearchResponse
+	if err := c.get(ctx, "/release?"+params.Encode(), &response); err != nil {
+		return nil, err
+	}
+	releases := make([]Release, 0, len(response.Releases))
+	for _, rel := range response.Releases {
+		name, _ := joinArtistCredit(rel.ArtistCredit)
+		releases = append(releases, Release{ID: rel.ID, Title: rel.Title, Artist: name, Year: parseYear(rel.Date)})
+	}
+	return releases, nil
+}
+
+func (c *Client) get(ctx context.Context, path string, out any) error {
+	if err := c.wait(ctx); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create MusicBrainz request: %w", err)
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("MusicBrainz request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("MusicBrainz returned status %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode MusicBrainz response: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) wait(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delay := minRequestInterval - time.Since(c.lastRequest)
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	c.lastRequest = time.Now()
+	return nil
+}
+
+func buildQuery(query model.LookupQuery) string {
+	var parts []string
+	if query.Title != "" {
+		parts = append(parts, "recording:"+quote(query.Title))
+	}
+	if query.Artist != "" {
+		parts = append(parts, "artist:"+quote(query.Artist))
+	}
+	if query.Album != "" {
+		parts = append(parts, "release:"+quote(query.Album))
+	}
+	return strings.Join(parts, " AND ")
+}
+
+func quote(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	return `"` + value + `"`
+}
+
+func joinArtistCredit(credits []artistCredit) (string, []string) {
+	var name strings.Builder
+	ids := make([]string, 0, len(credits))
+	for _, credit := range credits {
+		name.WriteString(credit.Name)
+		name.WriteString(credit.JoinPhrase)
+		if credit.Artist.ID != "" {
+			ids = append(ids, credit.Artist.ID)
+		}
+	}
+	return name.String(), ids
+}
+
+func parseYear(date string) int {
+	if len(date) < 4 {
+		return 0
+	}
+	year, err := strconv.Atoi(date[:4])
+	if err != nil {
+		return 0
+	}
+	return year
+}
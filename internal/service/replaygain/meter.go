@@ -0,0 +1,134 @@
+package replaygain
+
+import "math"
+
+const (
+	blockDuration = 0.4
+	hopsPerBlock  = 4
+
+	absoluteGate = -70.0
+	relativeGate = -10.0
+)
+
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	z1, z2             float64
+}
+
+func (f *biquad) process(x float64) float64 {
+	y := f.b0*x + f.z1
+	f.z1 = f.b1*x - f.a1*y + f.z2
+	f.z2 = f.b2*x - f.a2*y
+	return y
+}
+
+func kWeighting(sampleRate float64) (biquad, biquad) {
+	f0 := 1681.974450955533
+	gain := 3.999843853973347
+	q := 0.7071752369554196
+	k := math.Tan(math.Pi * f0 / sampleRate)
+	vh := math.Pow(10, gain/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/q + k*k
+	shelf := biquad{
+		b0: (vh + vb*k/q + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/q + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+
+	f0 = 38.13547087602444
+	q = 0.5003270373238773
+	k = math.Tan(math.Pi * f0 / sampleRate)
+	a0 = 1 + k/q + k*k
+	highPass := biquad{
+		b0: 1,
+		b1: -2,
+		b2: 1,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+
+	return shelf, highPass
+}
+
+type meter struct {
+	channels   int
+	filters    [][2]biquad
+	hopSize    int
+	hopFill    int
+	hopEnergy  float64
+	hops       []float64
+	blocks     []float64
+	samplePeak float64
+}
+
+func newMeter(sampleRate, channels int) *meter {
+	m := &meter{
+		channels: channels,
+		filters:  make([][2]biquad, channels),
+		hopSize:  int(float64(sampleRate) * blockDuration / hopsPerBlock),
+	}
+	for i := range m.filters {
+		shelf, highPass := kWeighting(float64(sampleRate))
+		m.filters[i] = [2]biquad{shelf, highPass}
+	}
+	return m
+}
+
+func (m *meter) write(samples []float32) {
+	for i := 0; i+m.channels <= len(samples); i += m.channels {
+		for ch := 0; ch < m.channels; ch++ {
+			x := float64(samples[i+ch])
+			m.samplePeak = max(m.samplePeak, math.Abs(x))
+			y := m.filters[ch][1].process(m.filters[ch][0].process(x))
+			m.hopEnergy += y * y
+		}
+		m.hopFill++
+		if m.hopFill == m.hopSize {
+			m.finishHop()
+		}
+	}
+}
+
+func (m *meter) finishHop() {
+	m.hops = append(m.hops, m.hopEnergy/float64(m.hopSize))
+	m.hopEnergy = 0
+	m.hopFill = 0
+	if len(m.hops) >= hopsPerBlock {
+		energy := 0.0
+		for _, hop := range m.hops[len(m.hops)-hopsPerBlock:] {
+			energy += hop
+		}
+		m.blocks = append(m.blocks, energy/hopsPerBlock)
+	}
+}
+
+func blockLoudness(energy float64) float64 {
+	return -0.691 + 10*math.Log10(energy)
+}
+
+func integratedLoudness(blocks []float64) float64 {
+	gated := func(threshold float64) (float64, int) {
+		sum, count := 0.0, 0
+		for _, energy := range blocks {
+			if energy > 0 && blockLoudness(energy) > threshold {
+				sum += energy
+				count++
+			}
+		}
+		return sum, count
+	}
+
+	sum, count := gated(absoluteGate)
+	if count == 0 {
+		return math.Inf(-1)
+	}
+	threshold := blockLoudness(sum/float64(count)) + relativeGate
+	sum, count = gated(threshold)
+	if count == 0 {
+		return math.Inf(-1)
+	}
+	return blockLoudness(sum / float64(count))
+}
@@ -0,0 +1,120 @@
+package replaygain
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"strings"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+)
+
+const referenceLoudness = -18.0
+
+const (
+	decodeSampleRate = 48000
+	decodeChannels   = 2
+)
+
+var ErrDecoderUnavailable = errors.New("ffmpeg binary not found")
+
+// Analyzer decodes files with ffmpeg and measures their loudness.
+type Analyzer struct {
+	ffmpegPath string
+}
+
+func NewAnalyzer(ffmpegPath string) *Analyzer {
+	return &Analyzer{ffmpegPath: ffmpegPath}
+}
+
+// Analyze returns the track gain and peak of every file, in order.
+func (a *Analyzer) Analyze(ctx context.Context, filePaths []string, album bool) ([]model.ReplayGain, error) {
+	ffmpeg, err := exec.LookPath(a.ffmpegPath)
+	if err != nil {
+		return nil, ErrDecoderUnavailable
+	}
+
+	results := make([]model.ReplayGain, len(filePaths))
+	var albumBlocks []float64
+	albumPeak := 0.0
+	for i, filePath := range filePaths {
+		m, err := a.measure(ctx, ffmpeg, filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to analyze %s: %w", filePath, err)
+		}
+		loudness := integratedLoudness(m.blocks)
+		if math.IsInf(loudness, -1) {
+			return nil, fmt.Errorf("failed to analyze %s: audio is silent", filePath)
+		}
+		results[i] = model.ReplayGain{
+			TrackGain: referenceLoudness - loudness,
+			TrackPeak: m.samplePeak,
+		}
+		albumBlocks = append(albumBlocks, m.blocks...)
+		albumPeak = max(albumPeak, m.samplePeak)
+	}
+
+	if album && len(results) > 0 {
+		albumGain := referenceLoudness - integratedLoudness(albumBlocks)
+		for i := range results {
+			results[i].AlbumGain = &albumGain
+			results[i].AlbumPeak = &albumPeak
+		}
+	}
+
+	return results, nil
+}
+
+func (a *Analyzer) measure(ctx context.Context, ffmpeg, filePath string) (*meter, error) {
+	cmd := exec.CommandContext(
+		ctx, ffmpeg, "-nostdin", "-v", "error", "-i", filePath, "-vn",
+		"-f", "f32le", "-acodec", "pcm_f32le", "-ar", fmt.Sprint(decodeSampleRate), "-ac", fmt.Sprint(decodeChannels),
+		"-",
+	)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ffmpeg output: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start ffmpeg: %w", err)
+	}
+
+	m := newMeter(decodeSampleRate, decodeChannels)
+	reader := bufio.NewReaderSize(stdout, 64*1024)
+	raw := make([]byte, 4*decodeChannels*4096)
+	samples := make([]float32, len(raw)/4)
+	var readErr error
+	for {
+		n, err := io.ReadFull(reader, raw)
+		n -= n % (4 * decodeChannels)
+		for i := 0; i < n/4; i++ {
+			samples[i] = math.Float32frombits(binary.LittleEndian.Uint32(raw[4*i:]))
+		}
+		m.write(samples[:n/4])
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			readErr = err
+			break
+		}
+	}
+
+	if err := cmd.Wait(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("ffmpeg failed: %s", msg)
+		}
+		return nil, fmt.Errorf("ffmpeg failed: %w", err)
+	}
+	if readErr != nil {
+		return nil, fmt.Errorf("failed to read decoded audio: %w", readErr)
+	}
+	return m, nil
+}
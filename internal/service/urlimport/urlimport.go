@@ -0,0 +1,124 @@
+package urlimport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/netguard"
+)
+
+var (
+	ErrInvalidURL = errors.New("invalid URL")
+	ErrTooLarge   = errors.New("file is too large")
+	ErrNotAudio   = errors.New("the link does not lead to an audio file")
+	// ErrDownloadFailed is returned when the remote server fails.
+	ErrDownloadFailed = errors.New("download failed")
+)
+
+const dialTimeout = 10 * time.Second
+
+// Fetcher downloads audio files that users link to, such as direct links to files on cloud drives.
+type Fetcher struct {
+	httpClient *http.Client
+	maxSize    int64
+}
+
+// NewFetcher creates a fetcher; maxSize 0 allows any size.
+func NewFetcher(timeout time.Duration, maxSize int64, allowPrivateHosts bool) *Fetcher {
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	if !allowPrivateHosts {
+		dialer.Control = netguard.Control
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &Fetcher{
+		httpClient: &http.Client{Timeout: timeout, Transport: transport},
+		maxSize:    maxSize,
+	}
+}
+
+// Fetch downloads rawURL into dst and returns the name of the file.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string, dst io.Writer) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", ErrInvalidURL
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "audio/*, application/octet-stream;q=0.9")
+
+	resp, err := f.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrDownloadFailed, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%w: the server answered with status %d", ErrDownloadFailed, resp.StatusCode)
+	}
+	if contentType := resp.Header.Get("Content-Type"); !audioContentType(contentType) {
+		return "", fmt.Errorf("%w: the server sent %s", ErrNotAudio, contentType)
+	}
+	if f.maxSize > 0 && resp.ContentLength > f.maxSize {
+		return "", fmt.Errorf("%w: larger than the maximum of %d bytes", ErrTooLarge, f.maxSize)
+	}
+
+	body := io.Reader(resp.Body)
+	if f.maxSize > 0 {
+		body = io.LimitReader(resp.Body, f.maxSize+1)
+	}
+	n, err := io.Copy(dst, body)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrDownloadFailed, err)
+	}
+	if f.maxSize > 0 && n > f.maxSize {
+		return "", fmt.Errorf("%w: larger than the maximum of %d bytes", ErrTooLarge, f.maxSize)
+	}
+	return filename(resp), nil
+}
+
+func audioContentType(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(mediaType, "audio/"), strings.HasPrefix(mediaType, "video/"):
+		return true
+	}
+	switch mediaType {
+	case "application/octet-stream", "binary/octet-stream", "application/ogg", "application/x-flac",
+		"application/force-download", "application/download":
+		return true
+	}
+	return false
+}
+
+func filename(resp *http.Response) string {
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		if name := path.Base(strings.ReplaceAll(params["filename"], `\`, "/")); name != "." && name != "/" {
+			return name
+		}
+	}
+	if name := path.Base(resp.Request.URL.Path); name != "." && name != "/" {
+		return name
+	}
+	return "download"
+}
@@ -0,0 +1,118 @@
+package waveform
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"os/exec"
+	"strings"
+)
+
+const (
+	decodeSampleRate = 8000
+	blockSamples     = 80 // 10 ms
+)
+
+var ErrDecoderUnavailable = errors.New("ffmpeg binary not found")
+
+// Generator decodes files with ffmpeg and reduces them to peaks.
+type Generator struct {
+	ffmpegPath string
+}
+
+func NewGenerator(ffmpegPath string) *Generator {
+	return &Generator{ffmpegPath: ffmpegPath}
+}
+
+// Peaks returns the highest absolute sample of each of points slices of the file.
+func (g *Generator) Peaks(ctx context.Context, filePath string, points int) ([]float64, error) {
+	ffmpeg, err := exec.LookPath(g.ffmpegPath)
+	if err != nil {
+		return nil, ErrDecoderUnavailable
+	}
+
+	cmd := exec.CommandContext(
+		ctx, ffmpeg, "-nostdin", "-v", "error", "-i", filePath, "-vn",
+		"-f", "f32le", "-acodec", "pcm_f32le", "-ar", fmt.Sprint(decodeSampleRate), "-ac", "1",
+		"-",
+	)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ffmpeg output: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start ffmpeg: %w", err)
+	}
+
+	blocks, readErr := blockPeaks(bufio.NewReaderSize(stdout, 64*1024))
+	if err := cmd.Wait(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("ffmpeg failed: %s", msg)
+		}
+		return nil, fmt.Errorf("ffmpeg failed: %w", err)
+	}
+	if readErr != nil {
+		return nil, fmt.Errorf("failed to read decoded audio: %w", readErr)
+	}
+	return resample(blocks, points), nil
+}
+
+func blockPeaks(r io.Reader) ([]float64, error) {
+	raw := make([]byte, 4*blockSamples)
+	var peaks []float64
+	for {
+		n, err := io.ReadFull(r, raw)
+		peak := 0.0
+		for i := 0; i+4 <= n; i += 4 {
+			sample := math.Abs(float64(math.Float32frombits(binary.LittleEndian.Uint32(raw[i:]))))
+			peak = max(peak, min(sample, 1))
+		}
+		if n > 0 {
+			peaks = append(peaks, peak)
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return peaks, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func resample(blocks []float64, points int) []float64 {
+	if len(blocks) <= points {
+		return blocks
+	}
+	peaks := make([]float64, points)
+	for i := range peaks {
+		from := i * len(blocks) / points
+		to := (i + 1) * len(blocks) / points
+		for _, peak := range blocks[from:to] {
+			peaks[i] = max(peaks[i], peak)
+		}
+	}
+	return peaks
+}
+
+// Render draws peaks as bars mirrored around the middle.
+func Render(peaks []float64, height int, fill color.Color) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, max(len(peaks), 1), height))
+	middle := float64(height) / 2
+	for x, peak := range peaks {
+		half := max(peak*middle, 0.5)
+		top := int(math.Floor(middle - half))
+		bottom := int(math.Ceil(middle + half))
+		for y := max(top, 0); y < min(bottom, height); y++ {
+			img.Set(x, y, fill)
+		}
+	}
+	return img
+}
@@ -0,0 +1,248 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+)
+
+const sidecarExt = ".json"
+
+// DiskStorage keeps uploaded files in a directory next to JSON sidecars.
+type DiskStorage struct {
+	dir   string
+	files map[string]*model.StoredFile
+	mu    sync.RWMutex
+}
+
+func NewDiskStorage(dir string) (*DiskStorage, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve storage directory: %w", err)
+	}
+	if err := os.MkdirAll(absDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	s := &DiskStorage{
+		dir:   absDir,
+		files: make(map[string]*model.StoredFile),
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *DiskStorage) load() error {
+	sidecars, err := filepath.Glob(filepath.Join(s.dir, "*"+sidecarExt))
+	if err != nil {
+		return fmt.Errorf("failed to list storage sidecars: %w", err)
+	}
+
+	for _, sidecar := range sidecars {
+		data, err := os.ReadFile(sidecar)
+		if err != nil {
+			return fmt.Errorf("failed to read sidecar %s: %w", sidecar, err)
+		}
+		var stored model.StoredFile
+		if err := json.Unmarshal(data, &stored); err != nil || stored.ID == "" {
+			slog.Warn("DiskStorage.load: Skipping unreadable sidecar", slog.String("path", sidecar))
+			continue
+		}
+		if _, err := os.Stat(stored.Path); err != nil {
+			slog.Warn("DiskStorage.load: Dropping sidecar without file", slog.String("path", sidecar))
+			os.Remove(sidecar)
+			continue
+		}
+		s.files[stored.ID] = &stored
+	}
+
+	slog.Info("DiskStorage.load: Restored files", slog.Int("count", len(s.files)), slog.String("dir", s.dir))
+	return nil
+}
+
+func (s *DiskStorage) Put(file *model.StoredFile, ttl time.Duration) error {
+	stored := *file
+	if stored.CreatedAt.IsZero() {
+		stored.CreatedAt = time.Now()
+	}
+	stored.ExpiresAt = time.Now().Add(ttl)
+
+	destPath := filepath.Join(s.dir, stored.ID+fileExt(stored.Filename))
+	if stored.Path != destPath {
+		if err := moveFile(stored.Path, destPath); err != nil {
+			return fmt.Errorf("failed to move file into storage: %w", err)
+		}
+		stored.Path = destPath
+	}
+
+	if err := s.writeSidecar(&stored); err != nil {
+		os.Remove(destPath)
+		return err
+	}
+
+	s.mu.Lock()
+	s.files[stored.ID] = &stored
+	s.mu.Unlock()
+
+	*file = stored
+	return nil
+}
+
+func (s *DiskStorage) Get(id string) (*model.StoredFile, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stored, exists := s.files[id]
+	if !exists {
+		return nil, model.ErrFileNotFound
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, model.ErrFileExpired
+	}
+	file := *stored
+	return &file, nil
+}
+
+func (s *DiskStorage) List() ([]*model.StoredFile, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	files := make([]*model.StoredFile, 0, len(s.files))
+	for _, stored := range s.files {
+		if now.After(stored.ExpiresAt) {
+			continue
+		}
+		file := *stored
+		files = append(files, &file)
+	}
+	return files, nil
+}
+
+func (s *DiskStorage) Save(id string, metadata *model.FileMetadata) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, exists := s.files[id]
+	if !exists {
+		return model.ErrFileNotFound
+	}
+	updated := *stored
+	updated.Metadata = metadata
+	if err := s.writeSidecar(&updated); err != nil {
+		return err
+	}
+	s.files[id] = &updated
+	return nil
+}
+
+func (s *DiskStorage) Delete(id string) error {
+	s.mu.Lock()
+	stored, exists := s.files[id]
+	delete(s.files, id)
+	s.mu.Unlock()
+
+	if !exists {
+		return model.ErrFileNotFound
+	}
+	return s.remove(stored)
+}
+
+func (s *DiskStorage) DeleteExpired() (int, error) {
+	s.mu.Lock()
+	now := time.Now()
+	var expired []*model.StoredFile
+	for id, stored := range s.files {
+		if now.After(stored.ExpiresAt) {
+			expired = append(expired, stored)
+			delete(s.files, id)
+		}
+	}
+	s.mu.Unlock()
+
+	var firstErr error
+	for _, stored := range expired {
+		if err := s.remove(stored); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return len(expired), firstErr
+}
+
+func (s *DiskStorage) remove(stored *model.StoredFile) error {
+	if err := os.Remove(stored.Path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove file: %w", err)
+	}
+	if err := os.Remove(s.sidecarPath(stored.ID)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove sidecar: %w", err)
+	}
+	return nil
+}
+
+func (s *DiskStorage) sidecarPath(id string) string {
+	return filepath.Join(s.dir, id+sidecarExt)
+}
+
+func (s *DiskStorage) writeSidecar(stored *model.StoredFile) error {
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return fmt.Errorf("failed to encode sidecar: %w", err)
+	}
+
+	sidecarPath := s.sidecarPath(stored.ID)
+	tempPath := sidecarPath + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write sidecar: %w", err)
+	}
+	if err := os.Rename(tempPath, sidecarPath); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to rename sidecar: %w", err)
+	}
+	return nil
+}
+
+func fileExt(filename string) string {
+	ext := filepath.Ext(filename)
+	if strings.EqualFold(ext, sidecarExt) || strings.EqualFold(ext, ".tmp") {
+		return ""
+	}
+	return ext
+}
+
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dst)
+		return err
+	}
+	in.Close()
+	return os.Remove(src)
+}
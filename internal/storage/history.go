@@ -0,0 +1,196 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+)
+
+var historyIDPattern = regexp.MustCompile(`^[A-Za-z0-9-]{1,64}$`)
+
+type historyFile struct {
+	NextID    int              `json:"nextId"`
+	Revisions []model.Revision `json:"revisions"`
+}
+
+// History keeps the previous tag states of files on local disk, one JSON file per file ID.
+type History struct {
+	dir       string
+	limit     int
+	retention time.Duration
+	mu        sync.Mutex
+}
+
+func NewHistory(dir string, limit int, retention time.Duration) (*History, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve history directory: %w", err)
+	}
+	if err := os.MkdirAll(absDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	return &History{
+		dir:       absDir,
+		limit:     max(limit, 1),
+		retention: retention,
+	}, nil
+}
+
+// Record stores metadata as the newest revision of the file.
+func (h *History) Record(fileID string, metadata *model.FileMetadata) (*model.Revision, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	history, err := h.read(fileID)
+	if err != nil && !errors.Is(err, model.ErrRevisionNotFound) {
+		return nil, err
+	}
+	if history == nil {
+		history = &historyFile{NextID: 1}
+	}
+
+	revision := model.Revision{
+		ID:        history.NextID,
+		CreatedAt: time.Now(),
+		Metadata:  *metadata,
+		CoverArt:  metadata.CoverArt,
+	}
+	for _, picture := range metadata.Pictures {
+		revision.Pictures = append(
+			revision.Pictures, model.PictureUpdate{
+				Type: picture.Type, Description: picture.Description, Data: picture.DataURI(),
+			},
+		)
+	}
+	history.NextID++
+	history.Revisions = append(history.Revisions, revision)
+	if len(history.Revisions) > h.limit {
+		history.Revisions = history.Revisions[len(history.Revisions)-h.limit:]
+	}
+
+	if err := h.write(fileID, history); err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// List returns the revisions of a file, newest first.
+func (h *History) List(fileID string) ([]model.Revision, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	history, err := h.read(fileID)
+	if errors.Is(err, model.ErrRevisionNotFound) {
+		return []model.Revision{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := make([]model.Revision, len(history.Revisions))
+	for i, revision := range history.Revisions {
+		revisions[len(revisions)-1-i] = revision
+	}
+	return revisions, nil
+}
+
+// Get returns a single revision, or the newest one when revisionID is 0.
+func (h *History) Get(fileID string, revisionID int) (*model.Revision, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	history, err := h.read(fileID)
+	if err != nil {
+		return nil, err
+	}
+	if revisionID == 0 && len(history.Revisions) > 0 {
+		revision := history.Revisions[len(history.Revisions)-1]
+		return &revision, nil
+	}
+	for _, revision := range history.Revisions {
+		if revision.ID == revisionID {
+			return &revision, nil
+		}
+	}
+	return nil, model.ErrRevisionNotFound
+}
+
+func (h *History) DeleteExpired() (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	paths, err := filepath.Glob(filepath.Join(h.dir, "*"+sidecarExt))
+	if err != nil {
+		return 0, fmt.Errorf("failed to list history files: %w", err)
+	}
+
+	removed := 0
+	var firstErr error
+	for _, path := range paths {
+		stat, err := os.Stat(path)
+		if err != nil || time.Since(stat.ModTime()) <= h.retention {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to remove history file: %w", err)
+			}
+			continue
+		}
+		removed++
+	}
+	return removed, firstErr
+}
+
+func (h *History) read(fileID string) (*historyFile, error) {
+	if !historyIDPattern.MatchString(fileID) {
+		return nil, model.ErrRevisionNotFound
+	}
+
+	data, err := os.ReadFile(h.path(fileID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, model.ErrRevisionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	var history historyFile
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, fmt.Errorf("failed to decode history of %s: %w", fileID, err)
+	}
+	return &history, nil
+}
+
+func (h *History) write(fileID string, history *historyFile) error {
+	if !historyIDPattern.MatchString(fileID) {
+		return fmt.Errorf("invalid file ID %q", fileID)
+	}
+
+	data, err := json.Marshal(history)
+	if err != nil {
+		return fmt.Errorf("failed to encode history: %w", err)
+	}
+
+	path := h.path(fileID)
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to rename history: %w", err)
+	}
+	return nil
+}
+
+func (h *History) path(fileID string) string {
+	return filepath.Join(h.dir, fileID+sidecarExt)
+}
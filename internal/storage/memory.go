@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+)
+
+type MemoryStorage struct {
+	files map[string]*model.StoredFile
+	mu    sync.RWMutex
+}
+
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{
+		files: make(map[string]*model.StoredFile),
+	}
+}
+
+func (s *MemoryStorage) Put(file *model.StoredFile, ttl time.Duration) error {
+	stored := *file
+	if stored.CreatedAt.IsZero() {
+		stored.CreatedAt = time.Now()
+	}
+	stored.ExpiresAt = time.Now().Add(ttl)
+
+	s.mu.Lock()
+	s.files[stored.ID] = &stored
+	s.mu.Unlock()
+
+	*file = stored
+	return nil
+}
+
+func (s *MemoryStorage) Get(id string) (*model.StoredFile, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stored, exists := s.files[id]
+	if !exists {
+		return nil, model.ErrFileNotFound
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, model.ErrFileExpired
+	}
+	file := *stored
+	return &file, nil
+}
+
+func (s *MemoryStorage) List() ([]*model.StoredFile, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	files := make([]*model.StoredFile, 0, len(s.files))
+	for _, stored := range s.files {
+		if now.After(stored.ExpiresAt) {
+			continue
+		}
+		file := *stored
+		files = append(files, &file)
+	}
+	return files, nil
+}
+
+func (s *MemoryStorage) Save(id string, metadata *model.FileMetadata) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, exists := s.files[id]
+	if !exists {
+		return model.ErrFileNotFound
+	}
+	stored.Metadata = metadata
+	return nil
+}
+
+func (s *MemoryStorage) Delete(id string) error {
+	s.mu.Lock()
+	stored, exists := s.files[id]
+	delete(s.files, id)
+	s.mu.Unlock()
+
+	if !exists {
+		return model.ErrFileNotFound
+	}
+	if err := os.Remove(stored.Path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *MemoryStorage) DeleteExpired() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	removed := 0
+	for id, stored := range s.files {
+		if now.After(stored.ExpiresAt) {
+			os.Remove(stored.Path)
+			delete(s.files, id)
+			removed++
+		}
+	}
+	return removed, nil
+}
@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+)
+
+// Originals keeps an untouched copy of every upload on local disk.
+type Originals struct {
+	dir       string
+	retention time.Duration
+}
+
+func NewOriginals(dir string, retention time.Duration) (*Originals, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve originals directory: %w", err)
+	}
+	if err := os.MkdirAll(absDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create originals directory: %w", err)
+	}
+
+	return &Originals{dir: absDir, retention: retention}, nil
+}
+
+// Keep copies the file at path as the original of fileID.
+func (o *Originals) Keep(fileID, path string) error {
+	if !historyIDPattern.MatchString(fileID) {
+		return fmt.Errorf("invalid file ID %q", fileID)
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer src.Close()
+
+	dstPath := o.path(fileID)
+	tempPath := dstPath + ".tmp"
+	dst, err := os.Create(tempPath)
+	if err != nil {
+		return fmt.Errorf("failed to create original: %w", err)
+	}
+	_, err = io.Copy(dst, src)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to copy original: %w", err)
+	}
+	if err := os.Rename(tempPath, dstPath); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to rename original: %w", err)
+	}
+	return nil
+}
+
+// Open returns the original of fileID for reading.
+func (o *Originals) Open(fileID string) (*os.File, error) {
+	if !historyIDPattern.MatchString(fileID) {
+		return nil, model.ErrOriginalNotFound
+	}
+	file, err := os.Open(o.path(fileID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, model.ErrOriginalNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open original: %w", err)
+	}
+	return file, nil
+}
+
+// Delete drops the original of fileID. Files without one are fine.
+func (o *Originals) Delete(fileID string) error {
+	if !historyIDPattern.MatchString(fileID) {
+		return nil
+	}
+	if err := os.Remove(o.path(fileID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove original: %w", err)
+	}
+	return nil
+}
+
+func (o *Originals) DeleteExpired() (int, error) {
+	entries, err := os.ReadDir(o.dir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list originals: %w", err)
+	}
+
+	removed := 0
+	var firstErr error
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || entry.IsDir() || time.Since(info.ModTime()) <= o.retention {
+			continue
+		}
+		if err := os.Remove(filepath.Join(o.dir, entry.Name())); err != nil && !errors.Is(err, os.ErrNotExist) {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to remove original: %w", err)
+			}
+			continue
+		}
+		removed++
+	}
+	return removed, firstErr
+}
+
+func (o *Originals) path(fileID string) string {
+	return filepath.Join(o.dir, fileID)
+}
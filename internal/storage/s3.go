@@ -0,0 +1,358 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+)
+
+const s3RequestTimeout = 5 * time.Minute
+
+type S3Options struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	Prefix    string
+	UseSSL    bool
+	PathStyle bool
+	CacheDir  string
+}
+
+type s3Sidecar struct {
+	File model.StoredFile `json:"file"`
+	ETag string           `json:"etag"`
+}
+
+// S3Storage keeps uploaded files and their metadata in an S3-compatible bucket.
+type S3Storage struct {
+	client   *s3Client
+	prefix   string
+	cacheDir string
+	mu       sync.Mutex
+}
+
+func NewS3Storage(opts S3Options) (*S3Storage, error) {
+	if opts.Endpoint == "" || opts.Bucket == "" {
+		return nil, fmt.Errorf("S3 endpoint and bucket are required")
+	}
+
+	cacheDir, err := filepath.Abs(opts.CacheDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve cache directory: %w", err)
+	}
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	scheme := "http"
+	if opts.UseSSL {
+		scheme = "https"
+	}
+
+	return &S3Storage{
+		client: &s3Client{
+			httpClient: &http.Client{},
+			scheme:     scheme,
+			endpoint:   strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(opts.Endpoint, "https://"), "http://"), "/"),
+			region:     opts.Region,
+			bucket:     opts.Bucket,
+			accessKey:  opts.AccessKey,
+			secretKey:  opts.SecretKey,
+			pathStyle:  opts.PathStyle,
+		},
+		prefix:   opts.Prefix,
+		cacheDir: cacheDir,
+	}, nil
+}
+
+func (s *S3Storage) Put(file *model.StoredFile, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s3RequestTimeout)
+	defer cancel()
+
+	stored := *file
+	if stored.CreatedAt.IsZero() {
+		stored.CreatedAt = time.Now()
+	}
+	stored.ExpiresAt = time.Now().Add(ttl)
+
+	etag, err := s.uploadFile(ctx, &stored, stored.Path)
+	if err != nil {
+		return err
+	}
+
+	cachePath := s.cachePath(&stored)
+	if stored.Path != cachePath {
+		if err := moveFile(stored.Path, cachePath); err != nil {
+			return fmt.Errorf("failed to move file into cache: %w", err)
+		}
+	}
+	stored.Path = cachePath
+
+	if err := s.writeSidecar(ctx, &stored, etag); err != nil {
+		return err
+	}
+	s.writeCachedETag(stored.ID, etag)
+
+	*file = stored
+	return nil
+}
+
+func (s *S3Storage) Get(id string) (*model.StoredFile, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s3RequestTimeout)
+	defer cancel()
+
+	sidecar, err := s.readSidecar(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(sidecar.File.ExpiresAt) {
+		return nil, model.ErrFileExpired
+	}
+	if err := s.ensureCached(ctx, sidecar); err != nil {
+		return nil, err
+	}
+	return &sidecar.File, nil
+}
+
+func (s *S3Storage) List() ([]*model.StoredFile, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s3RequestTimeout)
+	defer cancel()
+
+	sidecars, err := s.listSidecars(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	files := make([]*model.StoredFile, 0, len(sidecars))
+	for _, sidecar := range sidecars {
+		if now.After(sidecar.File.ExpiresAt) {
+			continue
+		}
+		files = append(files, &sidecar.File)
+	}
+	return files, nil
+}
+
+// Save uploads the edited local copy of the file together with its new metadata.
+func (s *S3Storage) Save(id string, metadata *model.FileMetadata) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s3RequestTimeout)
+	defer cancel()
+
+	sidecar, err := s.readSidecar(ctx, id)
+	if err != nil {
+		return err
+	}
+	sidecar.File.Metadata = metadata
+
+	etag, err := s.uploadFile(ctx, &sidecar.File, sidecar.File.Path)
+	if err != nil {
+		return err
+	}
+	if err := s.writeSidecar(ctx, &sidecar.File, etag); err != nil {
+		return err
+	}
+	s.writeCachedETag(id, etag)
+	return nil
+}
+
+func (s *S3Storage) Delete(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s3RequestTimeout)
+	defer cancel()
+
+	sidecar, err := s.readSidecar(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.remove(ctx, sidecar)
+}
+
+func (s *S3Storage) DeleteExpired() (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s3RequestTimeout)
+	defer cancel()
+
+	sidecars, err := s.listSidecars(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	removed := 0
+	var firstErr error
+	for _, sidecar := range sidecars {
+		if !now.After(sidecar.File.ExpiresAt) {
+			continue
+		}
+		if err := s.remove(ctx, sidecar); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		removed++
+	}
+	return removed, firstErr
+}
+
+func (s *S3Storage) remove(ctx context.Context, sidecar *s3Sidecar) error {
+	if err := s.client.deleteObject(ctx, s.objectKey(&sidecar.File)); err != nil && !errors.Is(err, model.ErrFileNotFound) {
+		return err
+	}
+	if err := s.client.deleteObject(ctx, s.sidecarKey(sidecar.File.ID)); err != nil && !errors.Is(err, model.ErrFileNotFound) {
+		return err
+	}
+
+	s.mu.Lock()
+	os.Remove(s.cachePath(&sidecar.File))
+	os.Remove(s.etagPath(sidecar.File.ID))
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *S3Storage) uploadFile(ctx context.Context, stored *model.StoredFile, path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file for upload: %w", err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to stat file for upload: %w", err)
+	}
+
+	return s.client.putObject(ctx, s.objectKey(stored), file, stat.Size(), "application/octet-stream")
+}
+
+func (s *S3Storage) ensureCached(ctx context.Context, sidecar *s3Sidecar) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cachePath := s.cachePath(&sidecar.File)
+	sidecar.File.Path = cachePath
+
+	if _, err := os.Stat(cachePath); err == nil && s.readCachedETag(sidecar.File.ID) == sidecar.ETag {
+		return nil
+	}
+
+	body, err := s.client.getObject(ctx, s.objectKey(&sidecar.File))
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	tempPath := cachePath + ".tmp"
+	out, err := os.Create(tempPath)
+	if err != nil {
+		return fmt.Errorf("failed to create cache file: %w", err)
+	}
+	if _, err := io.Copy(out, body); err != nil {
+		out.Close()
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to download file: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to close cache file: %w", err)
+	}
+	if err := os.Rename(tempPath, cachePath); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to rename cache file: %w", err)
+	}
+
+	os.WriteFile(s.etagPath(sidecar.File.ID), []byte(sidecar.ETag), 0644)
+	return nil
+}
+
+func (s *S3Storage) readSidecar(ctx context.Context, id string) (*s3Sidecar, error) {
+	body, err := s.client.getObject(ctx, s.sidecarKey(id))
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var sidecar s3Sidecar
+	if err := json.NewDecoder(body).Decode(&sidecar); err != nil {
+		return nil, fmt.Errorf("failed to decode sidecar %s: %w", id, err)
+	}
+	sidecar.File.Path = s.cachePath(&sidecar.File)
+	return &sidecar, nil
+}
+
+func (s *S3Storage) writeSidecar(ctx context.Context, stored *model.StoredFile, etag string) error {
+	sidecar := s3Sidecar{File: *stored, ETag: etag}
+	sidecar.File.Path = ""
+	data, err := json.Marshal(sidecar)
+	if err != nil {
+		return fmt.Errorf("failed to encode sidecar: %w", err)
+	}
+	_, err = s.client.putObject(ctx, s.sidecarKey(stored.ID), bytes.NewReader(data), int64(len(data)), "application/json")
+	return err
+}
+
+func (s *S3Storage) listSidecars(ctx context.Context) ([]*s3Sidecar, error) {
+	keys, err := s.client.listObjects(ctx, s.prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var sidecars []*s3Sidecar
+	for _, key := range keys {
+		if !strings.HasSuffix(key, sidecarExt) {
+			continue
+		}
+		id := strings.TrimSuffix(strings.TrimPrefix(key, s.prefix), sidecarExt)
+		sidecar, err := s.readSidecar(ctx, id)
+		if errors.Is(err, model.ErrFileNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		sidecars = append(sidecars, sidecar)
+	}
+	return sidecars, nil
+}
+
+func (s *S3Storage) readCachedETag(id string) string {
+	data, err := os.ReadFile(s.etagPath(id))
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func (s *S3Storage) writeCachedETag(id, etag string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	os.WriteFile(s.etagPath(id), []byte(etag), 0644)
+}
+
+func (s *S3Storage) objectKey(stored *model.StoredFile) string {
+	return s.prefix + stored.ID + fileExt(stored.Filename)
+}
+
+func (s *S3Storage) sidecarKey(id string) string {
+	return s.prefix + id + sidecarExt
+}
+
+func (s *S3Storage) cachePath(stored *model.StoredFile) string {
+	return filepath.Join(s.cacheDir, stored.ID+fileExt(stored.Filename))
+}
+
+func (s *S3Storage) etagPath(id string) string {
+	return filepath.Join(s.cacheDir, id+".etag")
+}
@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+)
+
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	gets    map[string]int
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+
+	switch {
+	case r.Method == http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.objects[key] = data
+		sum := md5.Sum(data)
+		w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+	case r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2":
+		type content struct {
+			Key string `xml:"Key"`
+		}
+		var result struct {
+			XMLName  xml.Name  `xml:"ListBucketResult"`
+			Contents []content `xml:"Contents"`
+		}
+		for key := range f.objects {
+			result.Contents = append(result.Contents, content{Key: key})
+		}
+		xml.NewEncoder(w).Encode(result)
+	case r.Method == http.MethodGet:
+		data, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, "<Error><Code>NoSuchKey</Code></Error>")
+			return
+		}
+		f.gets[key]++
+		w.Write(data)
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (f *fakeS3) downloads(key string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.gets[key]
+}
+
+func TestS3ListDoesNotDownload(t *testing.T) {
+	fake := &fakeS3{objects: map[string][]byte{}, gets: map[string]int{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	newStorage := func() *S3Storage {
+		s, err := NewS3Storage(
+			S3Options{
+				Endpoint:  server.URL,
+				Bucket:    "bucket",
+				PathStyle: true,
+				CacheDir:  t.TempDir(),
+			},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	upload := filepath.Join(t.TempDir(), "upload")
+	if err := os.WriteFile(upload, []byte("audio"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	file := &model.StoredFile{ID: "one", Filename: "song.mp3", Path: upload}
+	if err := newStorage().Put(file, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another instance has nothing cached yet.
+	s := newStorage()
+	files, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].ID != "one" {
+		t.Fatalf("List = %v, want the stored file", files)
+	}
+	if got := fake.downloads("one.mp3"); got != 0 {
+		t.Errorf("List downloaded the audio %d times, want 0", got)
+	}
+
+	stored, err := s.Get("one")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fake.downloads("one.mp3"); got != 1 {
+		t.Errorf("Get downloaded the audio %d times, want 1", got)
+	}
+	data, err := os.ReadFile(stored.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "audio" {
+		t.Errorf("cached copy = %q, want %q", data, "audio")
+	}
+}
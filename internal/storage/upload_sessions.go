@@ -0,0 +1,259 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+)
+
+const partExt = ".part"
+
+// UploadSessions keeps partially uploaded files on local disk.
+type UploadSessions struct {
+	dir     string
+	ttl     time.Duration
+	maxSize int64
+	mu      sync.Mutex
+	busy    map[string]bool
+}
+
+func NewUploadSessions(dir string, ttl time.Duration, maxSize int64) (*UploadSessions, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve upload directory: %w", err)
+	}
+	if err := os.MkdirAll(absDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create upload directory: %w", err)
+	}
+
+	return &UploadSessions{
+		dir:     absDir,
+		ttl:     ttl,
+		maxSize: maxSize,
+		busy:    make(map[string]bool),
+	}, nil
+}
+
+func (s *UploadSessions) Create(filename string, size int64) (*model.UploadSession, error) {
+	if s.maxSize > 0 && size > s.maxSize {
+		return nil, model.ErrUploadTooLarge
+	}
+
+	session := &model.UploadSession{
+		ID:        uuid.New().String(),
+		Filename:  filename,
+		Size:      size,
+		ExpiresAt: time.Now().Add(s.ttl),
+	}
+
+	part, err := os.Create(s.partPath(session.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create upload file: %w", err)
+	}
+	part.Close()
+
+	if err := s.writeSidecar(session); err != nil {
+		os.Remove(s.partPath(session.ID))
+		return nil, err
+	}
+	return session, nil
+}
+
+func (s *UploadSessions) Get(id string) (*model.UploadSession, error) {
+	session, err := s.readSession(id)
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(session.ExpiresAt) {
+		return nil, model.ErrUploadNotFound
+	}
+	return session, nil
+}
+
+// Append writes the chunk read from r at offset.
+func (s *UploadSessions) Append(id string, offset int64, r io.Reader) (*model.UploadSession, error) {
+	if err := s.acquire(id); err != nil {
+		return nil, err
+	}
+	defer s.release(id)
+
+	session, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if offset != session.Offset {
+		return session, model.ErrUploadOffsetMismatch
+	}
+
+	part, err := os.OpenFile(s.partPath(id), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return session, fmt.Errorf("failed to open upload file: %w", err)
+	}
+	written, copyErr := io.Copy(part, io.LimitReader(r, session.Size-session.Offset))
+	if err := part.Close(); err != nil && copyErr == nil {
+		copyErr = fmt.Errorf("failed to close upload file: %w", err)
+	}
+	session.Offset += written
+
+	session.ExpiresAt = time.Now().Add(s.ttl)
+	if err := s.writeSidecar(session); err != nil && copyErr == nil {
+		copyErr = err
+	}
+	if copyErr != nil {
+		return session, fmt.Errorf("failed to write upload chunk: %w", copyErr)
+	}
+	return session, nil
+}
+
+// Finish ends a complete session and hands over the path of the received file.
+func (s *UploadSessions) Finish(id string) (*model.UploadSession, string, error) {
+	if err := s.acquire(id); err != nil {
+		return nil, "", err
+	}
+	defer s.release(id)
+
+	session, err := s.Get(id)
+	if err != nil {
+		return nil, "", err
+	}
+	if !session.Complete() {
+		return session, "", fmt.Errorf("upload %s is incomplete: %d of %d bytes", id, session.Offset, session.Size)
+	}
+
+	if err := os.Remove(s.sidecarPath(id)); err != nil {
+		return nil, "", fmt.Errorf("failed to remove upload sidecar: %w", err)
+	}
+	return session, s.partPath(id), nil
+}
+
+func (s *UploadSessions) Delete(id string) error {
+	if err := s.acquire(id); err != nil {
+		return err
+	}
+	defer s.release(id)
+
+	if _, err := s.readSession(id); err != nil {
+		return err
+	}
+	return s.remove(id)
+}
+
+func (s *UploadSessions) DeleteExpired() (int, error) {
+	sidecars, err := filepath.Glob(filepath.Join(s.dir, "*"+sidecarExt))
+	if err != nil {
+		return 0, fmt.Errorf("failed to list upload sidecars: %w", err)
+	}
+
+	now := time.Now()
+	removed := 0
+	var firstErr error
+	for _, sidecar := range sidecars {
+		id := strings.TrimSuffix(filepath.Base(sidecar), sidecarExt)
+		session, err := s.readSession(id)
+		if err == nil && !now.After(session.ExpiresAt) {
+			continue
+		}
+		if s.acquire(id) != nil {
+			continue
+		}
+		err = s.remove(id)
+		s.release(id)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		removed++
+	}
+	return removed, firstErr
+}
+
+func (s *UploadSessions) acquire(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.busy[id] {
+		return model.ErrUploadBusy
+	}
+	s.busy[id] = true
+	return nil
+}
+
+func (s *UploadSessions) release(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.busy, id)
+}
+
+func (s *UploadSessions) readSession(id string) (*model.UploadSession, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, model.ErrUploadNotFound
+	}
+
+	data, err := os.ReadFile(s.sidecarPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, model.ErrUploadNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload sidecar: %w", err)
+	}
+	var session model.UploadSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("failed to decode upload sidecar %s: %w", id, err)
+	}
+
+	stat, err := os.Stat(s.partPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, model.ErrUploadNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat upload file: %w", err)
+	}
+	session.Offset = stat.Size()
+	return &session, nil
+}
+
+func (s *UploadSessions) writeSidecar(session *model.UploadSession) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to encode upload sidecar: %w", err)
+	}
+
+	sidecarPath := s.sidecarPath(session.ID)
+	tempPath := sidecarPath + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write upload sidecar: %w", err)
+	}
+	if err := os.Rename(tempPath, sidecarPath); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to rename upload sidecar: %w", err)
+	}
+	return nil
+}
+
+func (s *UploadSessions) remove(id string) error {
+	if err := os.Remove(s.partPath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove upload file: %w", err)
+	}
+	if err := os.Remove(s.sidecarPath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove upload sidecar: %w", err)
+	}
+	return nil
+}
+
+func (s *UploadSessions) partPath(id string) string {
+	return filepath.Join(s.dir, id+partExt)
+}
+
+func (s *UploadSessions) sidecarPath(id string) string {
+	return filepath.Join(s.dir, id+sidecarExt)
+}
@@ -0,0 +1,224 @@
+package tagtable
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+)
+
+const customTagPrefix = "tag:"
+
+// Row is the tags of one file.
+type Row struct {
+	ID       string `json:"id,omitempty"`
+	Filename string `json:"filename,omitempty"`
+	model.TagUpdate
+}
+
+type column struct {
+	name string
+	get  func(u *model.TagUpdate) string
+	set  func(u *model.TagUpdate, value string) error
+}
+
+func textColumn(name string, field func(u *model.TagUpdate) **string) column {
+	return column{
+		name: name,
+		get: func(u *model.TagUpdate) string {
+			if value := *field(u); value != nil {
+				return *value
+			}
+			return ""
+		},
+		set: func(u *model.TagUpdate, value string) error {
+			*field(u) = &value
+			return nil
+		},
+	}
+}
+
+func numberColumn(name string, field func(u *model.TagUpdate) **int) column {
+	return column{
+		name: name,
+		get: func(u *model.TagUpdate) string {
+			if value := *field(u); value != nil && *value != 0 {
+				return strconv.Itoa(*value)
+			}
+			return ""
+		},
+		set: func(u *model.TagUpdate, value string) error {
+			n := 0
+			if value = strings.TrimSpace(value); value != "" {
+				var err error
+				if n, err = strconv.Atoi(value); err != nil {
+					return fmt.Errorf("%s: %q is not a number", name, value)
+				}
+			}
+			*field(u) = &n
+			return nil
+		},
+	}
+}
+
+var columns = []column{
+	textColumn("title", func(u *model.TagUpdate) **string { return &u.Title }),
+	textColumn("artist", func(u *model.TagUpdate) **string { return &u.Artist }),
+	textColumn("album", func(u *model.TagUpdate) **string { return &u.Album }),
+	textColumn("albumArtist", func(u *model.TagUpdate) **string { return &u.AlbumArtist }),
+	textColumn("composer", func(u *model.TagUpdate) **string { return &u.Composer }),
+	textColumn("comment", func(u *model.TagUpdate) **string { return &u.Comment }),
+	textColumn("titleSort", func(u *model.TagUpdate) **string { return &u.TitleSort }),
+	textColumn("artistSort", func(u *model.TagUpdate) **string { return &u.ArtistSort }),
+	textColumn("albumArtistSort", func(u *model.TagUpdate) **string { return &u.AlbumArtistSort }),
+	textColumn("albumSort", func(u *model.TagUpdate) **string { return &u.AlbumSort }),
+	numberColumn("year", func(u *model.TagUpdate) **int { return &u.Year }),
+	textColumn("releaseDate", func(u *model.TagUpdate) **string { return &u.ReleaseDate }),
+	textColumn("genre", func(u *model.TagUpdate) **string { return &u.Genre }),
+	numberColumn("track", func(u *model.TagUpdate) **int { return &u.Track }),
+	numberColumn("totalTracks", func(u *model.TagUpdate) **int { return &u.TotalTracks }),
+	numberColumn("disc", func(u *model.TagUpdate) **int { return &u.Disc }),
+	numberColumn("totalDiscs", func(u *model.TagUpdate) **int { return &u.TotalDiscs }),
+	numberColumn("bpm", func(u *model.TagUpdate) **int { return &u.BPM }),
+	{
+		name: "compilation",
+		get: func(u *model.TagUpdate) string {
+			if u.Compilation != nil && *u.Compilation {
+				return "true"
+			}
+			return "false"
+		},
+		set: func(u *model.TagUpdate, value string) error {
+			compilation := false
+			if value = strings.TrimSpace(value); value != "" {
+				var err error
+				if compilation, err = strconv.ParseBool(value); err != nil {
+					return fmt.Errorf("compilation: %q is not true or false", value)
+				}
+			}
+			u.Compilation = &compilation
+			return nil
+		},
+	},
+	textColumn("lyrics", func(u *model.TagUpdate) **string { return &u.Lyrics }),
+	textColumn("syncedLyrics", func(u *model.TagUpdate) **string { return &u.SyncedLyrics }),
+	textColumn("isrc", func(u *model.TagUpdate) **string { return &u.ISRC }),
+	textColumn("barcode", func(u *model.TagUpdate) **string { return &u.Barcode }),
+	textColumn("catalogNumber", func(u *model.TagUpdate) **string { return &u.CatalogNumber }),
+	textColumn("label", func(u *model.TagUpdate) **string { return &u.Label }),
+	numberColumn("rating", func(u *model.TagUpdate) **int { return &u.Rating }),
+	numberColumn("playCount", func(u *model.TagUpdate) **int { return &u.PlayCount }),
+}
+
+// WriteCSV writes rows as a table with a header row.
+func WriteCSV(w io.Writer, rows []Row) error {
+	customKeys := make(map[string]bool)
+	for _, row := range rows {
+		for key := range row.CustomTags {
+			customKeys[key] = true
+		}
+	}
+	keys := make([]string, 0, len(customKeys))
+	for key := range customKeys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	writer := csv.NewWriter(w)
+	header := []string{"id", "filename"}
+	for _, c := range columns {
+		header = append(header, c.name)
+	}
+	for _, key := range keys {
+		header = append(header, customTagPrefix+key)
+	}
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+
+	for _, row := range rows {
+		record := []string{row.ID, row.Filename}
+		for _, c := range columns {
+			record = append(record, c.get(&row.TagUpdate))
+		}
+		for _, key := range keys {
+			record = append(record, row.CustomTags[key])
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write CSV: %w", err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return nil
+}
+
+// ReadCSV reads a table as written by WriteCSV.
+func ReadCSV(r io.Reader) ([]Row, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	byName := make(map[string]column, len(columns))
+	for _, c := range columns {
+		byName[c.name] = c
+	}
+	for i, name := range header {
+		// Spreadsheets save CSV with a byte order mark.
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		header[i] = name
+		_, known := byName[name]
+		if !known && name != "id" && name != "filename" && !strings.HasPrefix(name, customTagPrefix) {
+			return nil, fmt.Errorf("unknown CSV column %q", name)
+		}
+	}
+
+	var rows []Row
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		var row Row
+		for i, value := range record {
+			name := header[i]
+			switch {
+			case name == "id":
+				row.ID = strings.TrimSpace(value)
+			case name == "filename":
+				row.Filename = value
+			case strings.HasPrefix(name, customTagPrefix):
+				if row.CustomTags == nil {
+					row.CustomTags = make(map[string]string)
+				}
+				row.CustomTags[strings.TrimPrefix(name, customTagPrefix)] = value
+			default:
+				if err := byName[name].set(&row.TagUpdate, value); err != nil {
+					return nil, fmt.Errorf("line %d: %w", line, err)
+				}
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// ReadJSON reads an array of rows. Fields a row leaves out are kept.
+func ReadJSON(r io.Reader) ([]Row, error) {
+	var rows []Row
+	if err := json.NewDecoder(r).Decode(&rows); err != nil {
+		return nil, fmt.Errorf("failed to read JSON: %w", err)
+	}
+	return rows, nil
+}
@@ -0,0 +1,160 @@
+package transform
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const (
+	StepCase          = "case"          // convert the case, see Case
+	StepReplace       = "replace"       // replace Find with Replace
+	StepTrim          = "trim"          // trim and collapse whitespace
+	StepStripBrackets = "stripBrackets" // remove bracketed junk such as "(Official Video)"
+)
+
+const (
+	CaseTitle = "title"
+	CaseUpper = "upper"
+	CaseLower = "lower"
+)
+
+// Step is one text transformation. Which fields apply depends on Type.
+type Step struct {
+	Type       string `json:"type"`
+	Case       string `json:"case,omitempty"`       // case: title, upper or lower
+	Find       string `json:"find,omitempty"`       // replace: text, or a regular expression with Regex
+	Replace    string `json:"replace,omitempty"`    // replace: may refer to groups as $1 with Regex
+	Regex      bool   `json:"regex,omitempty"`      // replace
+	IgnoreCase bool   `json:"ignoreCase,omitempty"` // replace
+	All        bool   `json:"all,omitempty"`        // stripBrackets: any bracketed text, not only junk
+}
+
+// Transform applies its steps to text in order.
+type Transform struct {
+	steps []func(string) string
+}
+
+var junkWords = regexp.MustCompile(
+	`(?i)\b(official|video|audio|lyrics?|visuali[sz]er|hd|hq|4k|full album|free download|explicit)\b`,
+)
+
+var (
+	brackets   = regexp.MustCompile(`\s*(\([^()]*\)|\[[^\[\]]*\]|\{[^{}]*\})`)
+	whitespace = regexp.MustCompile(`[ \t]+`)
+)
+
+func New(steps []Step) (*Transform, error) {
+	t := &Transform{}
+	for i, step := range steps {
+		fn, err := compile(step)
+		if err != nil {
+			return nil, fmt.Errorf("step %d: %w", i, err)
+		}
+		t.steps = append(t.steps, fn)
+	}
+	return t, nil
+}
+
+func compile(step Step) (func(string) string, error) {
+	switch step.Type {
+	case StepCase:
+		switch step.Case {
+		case CaseTitle:
+			return titleCase, nil
+		case CaseUpper:
+			return strings.ToUpper, nil
+		case CaseLower:
+			return strings.ToLower, nil
+		}
+		return nil, fmt.Errorf("unsupported case %q", step.Case)
+	case StepReplace:
+		if step.Find == "" {
+			return nil, fmt.Errorf("find is empty")
+		}
+		pattern := step.Find
+		if !step.Regex {
+			pattern = regexp.QuoteMeta(pattern)
+		}
+		if step.IgnoreCase {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression: %w", err)
+		}
+		if !step.Regex {
+			return func(s string) string { return re.ReplaceAllLiteralString(s, step.Replace) }, nil
+		}
+		return func(s string) string { return re.ReplaceAllString(s, step.Replace) }, nil
+	case StepTrim:
+		return trim, nil
+	case StepStripBrackets:
+		return func(s string) string { return stripBrackets(s, step.All) }, nil
+	}
+	return nil, fmt.Errorf("unsupported step %q", step.Type)
+}
+
+func (t *Transform) Apply(s string) string {
+	for _, step := range t.steps {
+		s = step(s)
+	}
+	return s
+}
+
+func titleCase(s string) string {
+	runes := []rune(s)
+	start := true
+	for i, r := range runes {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start {
+				runes[i] = unicode.ToTitle(r)
+			} else {
+				runes[i] = unicode.ToLower(r)
+			}
+			start = false
+			continue
+		}
+		start = r != '\'' && r != '’'
+	}
+	return string(runes)
+}
+
+func trim(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = whitespace.ReplaceAllString(strings.TrimSpace(line), " ")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func stripBrackets(s string, all bool) string {
+	stripped := brackets.ReplaceAllStringFunc(
+		s, func(match string) string {
+			if all || junkWords.MatchString(match) {
+				return ""
+			}
+			return match
+		},
+	)
+	return strings.TrimSpace(stripped)
+}
+
+// DefaultArticles are the leading words SortName moves when none are given.
+var DefaultArticles = []string{"The", "A", "An"}
+
+// SortName moves a leading article to the end: "The Beatles" becomes "Beatles, The".
+func SortName(name string, articles []string) string {
+	name = strings.TrimSpace(name)
+	first, rest, ok := strings.Cut(name, " ")
+	if rest = strings.TrimSpace(rest); !ok || rest == "" {
+		return name
+	}
+	for _, article := range articles {
+		if strings.EqualFold(first, article) {
+			return rest + ", " + first
+		}
+	}
+	return name
+}
@@ -0,0 +1,432 @@
+package vfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	errIsDir       = errors.New("is a directory")
+	errNotDir      = errors.New("not a directory")
+	errNotEmpty    = errors.New("directory not empty")
+	errBadPattern  = errors.New("pattern contains path separator")
+	errNegative    = errors.New("negative offset")
+	errAppendWrite = errors.New("WriteAt with O_APPEND")
+	errAccess      = errors.New("bad file descriptor")
+)
+
+// Mem is a file system that keeps its files in memory, for tests.
+type Mem struct {
+	mu    sync.Mutex
+	files map[string]*memNode
+	temps int
+}
+
+func NewMem() *Mem {
+	return &Mem{files: make(map[string]*memNode)}
+}
+
+type memNode struct {
+	mu      sync.Mutex
+	data    []byte
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (m *Mem) Open(name string) (File, error) {
+	return m.OpenFile(name, os.O_RDONLY, 0)
+}
+
+func (m *Mem) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := filepath.Clean(name)
+	writable := flag&(os.O_WRONLY|os.O_RDWR) != 0
+	node, ok := m.files[key]
+	switch {
+	case !ok && m.isDir(key):
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errIsDir}
+	case !ok && flag&os.O_CREATE == 0:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	case !ok:
+		node = &memNode{mode: perm & fs.ModePerm, modTime: time.Now()}
+		m.files[key] = node
+	case flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+	case writable && node.perm()&0o200 == 0:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	case writable && flag&os.O_TRUNC != 0:
+		node.mu.Lock()
+		node.data = nil
+		node.modTime = time.Now()
+		node.mu.Unlock()
+	}
+	return &memFile{name: name, node: node, flag: flag}, nil
+}
+
+// CreateTemp creates a new temp file, like os.CreateTemp.
+func (m *Mem) CreateTemp(dir, pattern string) (File, error) {
+	if dir == "" {
+		dir = m.TempDir()
+	}
+	if strings.ContainsRune(pattern, filepath.Separator) || strings.ContainsRune(pattern, '/') {
+		return nil, &fs.PathError{Op: "createtemp", Path: pattern, Err: errBadPattern}
+	}
+	prefix, suffix := pattern, ""
+	if i := strings.LastIndex(pattern, "*"); i >= 0 {
+		prefix, suffix = pattern[:i], pattern[i+1:]
+	}
+	for {
+		m.mu.Lock()
+		m.temps++
+		name := filepath.Join(dir, prefix+strconv.Itoa(m.temps)+suffix)
+		m.mu.Unlock()
+
+		file, err := m.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o600)
+		if !errors.Is(err, fs.ErrExist) {
+			return file, err
+		}
+	}
+}
+
+func (m *Mem) Stat(name string) (fs.FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := filepath.Clean(name)
+	if node, ok := m.files[key]; ok {
+		return node.info(filepath.Base(key)), nil
+	}
+	if m.isDir(key) {
+		return dirInfo(filepath.Base(key)), nil
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+func (m *Mem) ReadDir(name string) ([]fs.DirEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dir := filepath.Clean(name)
+	if _, ok := m.files[dir]; ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errNotDir}
+	}
+	if !m.isDir(dir) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	children := make(map[string]fs.FileInfo)
+	for key, node := range m.files {
+		child, ok := childOf(dir, key)
+		switch {
+		case !ok:
+		case child == key:
+			children[filepath.Base(key)] = node.info(filepath.Base(key))
+		default:
+			children[filepath.Base(child)] = dirInfo(filepath.Base(child))
+		}
+	}
+	entries := make([]fs.DirEntry, 0, len(children))
+	for _, info := range children {
+		entries = append(entries, fs.FileInfoToDirEntry(info))
+	}
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int { return strings.Compare(a.Name(), b.Name()) })
+	return entries, nil
+}
+
+// Rename moves a file, replacing the file at newPath.
+func (m *Mem) Rename(oldPath, newPath string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	oldKey, newKey := filepath.Clean(oldPath), filepath.Clean(newPath)
+	node, ok := m.files[oldKey]
+	if !ok {
+		err := fs.ErrNotExist
+		if m.isDir(oldKey) {
+			err = errIsDir
+		}
+		return &os.LinkError{Op: "rename", Old: oldPath, New: newPath, Err: err}
+	}
+	if _, ok := m.files[newKey]; !ok && m.isDir(newKey) {
+		return &os.LinkError{Op: "rename", Old: oldPath, New: newPath, Err: errIsDir}
+	}
+	delete(m.files, oldKey)
+	m.files[newKey] = node
+	return nil
+}
+
+func (m *Mem) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := filepath.Clean(name)
+	if _, ok := m.files[key]; ok {
+		delete(m.files, key)
+		return nil
+	}
+	if m.hasChildren(key) {
+		return &fs.PathError{Op: "remove", Path: name, Err: errNotEmpty}
+	}
+	return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+}
+
+func (m *Mem) Chmod(name string, mode fs.FileMode) error {
+	node, err := m.node("chmod", name)
+	if err != nil || node == nil {
+		return err
+	}
+	node.mu.Lock()
+	defer node.mu.Unlock()
+	node.mode = mode & (fs.ModePerm | fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky)
+	return nil
+}
+
+func (m *Mem) Chtimes(name string, atime, mtime time.Time) error {
+	node, err := m.node("chtimes", name)
+	if err != nil || node == nil {
+		return err
+	}
+	node.mu.Lock()
+	defer node.mu.Unlock()
+	node.modTime = mtime
+	return nil
+}
+
+func (m *Mem) TempDir() string {
+	return string(filepath.Separator) + "tmp"
+}
+
+func (m *Mem) node(op, name string) (*memNode, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := filepath.Clean(name)
+	if node, ok := m.files[key]; ok {
+		return node, nil
+	}
+	if m.isDir(key) {
+		return nil, nil
+	}
+	return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+}
+
+func (m *Mem) isDir(key string) bool {
+	if _, ok := m.files[key]; ok {
+		return false
+	}
+	return filepath.Dir(key) == key || key == filepath.Clean(m.TempDir()) || m.hasChildren(key)
+}
+
+func (m *Mem) hasChildren(dir string) bool {
+	for key := range m.files {
+		if _, ok := childOf(dir, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func childOf(dir, key string) (string, bool) {
+	for {
+		parent := filepath.Dir(key)
+		if parent == dir {
+			return key, true
+		}
+		if parent == key {
+			return "", false
+		}
+		key = parent
+	}
+}
+
+func (n *memNode) perm() fs.FileMode {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.mode
+}
+
+func (n *memNode) info(name string) fs.FileInfo {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return &memInfo{name: name, size: int64(len(n.data)), mode: n.mode, modTime: n.modTime}
+}
+
+type memInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func dirInfo(name string) *memInfo {
+	return &memInfo{name: name, mode: fs.ModeDir | 0o755}
+}
+
+func (i *memInfo) Name() string       { return i.name }
+func (i *memInfo) Size() int64        { return i.size }
+func (i *memInfo) Mode() fs.FileMode  { return i.mode }
+func (i *memInfo) ModTime() time.Time { return i.modTime }
+func (i *memInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i *memInfo) Sys() any           { return nil }
+
+type memFile struct {
+	name   string
+	node   *memNode
+	flag   int
+	offset int64
+	closed bool
+}
+
+func (f *memFile) Name() string {
+	return f.name
+}
+
+func (f *memFile) Read(p []byte) (int, error) {
+	n, err := f.readAt("read", p, f.offset)
+	f.offset += int64(n)
+	return n, err
+}
+
+func (f *memFile) ReadAt(p []byte, off int64) (int, error) {
+	return f.readAt("readat", p, off)
+}
+
+func (f *memFile) readAt(op string, p []byte, off int64) (int, error) {
+	if err := f.check(op, f.flag&os.O_WRONLY == 0); err != nil {
+		return 0, err
+	}
+	if off < 0 {
+		return 0, &fs.PathError{Op: op, Path: f.name, Err: errNegative}
+	}
+	f.node.mu.Lock()
+	defer f.node.mu.Unlock()
+	if off >= int64(len(f.node.data)) {
+		if len(p) == 0 {
+			return 0, nil
+		}
+		return 0, io.EOF
+	}
+	n := copy(p, f.node.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (f *memFile) Write(p []byte) (int, error) {
+	if err := f.check("write", f.writable()); err != nil {
+		return 0, err
+	}
+	if f.flag&os.O_APPEND != 0 {
+		f.node.mu.Lock()
+		f.offset = int64(len(f.node.data))
+		f.node.mu.Unlock()
+	}
+	n := f.writeAt(p, f.offset)
+	f.offset += int64(n)
+	return n, nil
+}
+
+func (f *memFile) WriteAt(p []byte, off int64) (int, error) {
+	if err := f.check("writeat", f.writable()); err != nil {
+		return 0, err
+	}
+	if f.flag&os.O_APPEND != 0 {
+		return 0, errAppendWrite
+	}
+	if off < 0 {
+		return 0, &fs.PathError{Op: "writeat", Path: f.name, Err: errNegative}
+	}
+	return f.writeAt(p, off), nil
+}
+
+func (f *memFile) writeAt(p []byte, off int64) int {
+	f.node.mu.Lock()
+	defer f.node.mu.Unlock()
+	if end := off + int64(len(p)); end > int64(len(f.node.data)) {
+		f.node.data = append(f.node.data, make([]byte, end-int64(len(f.node.data)))...)
+	}
+	copy(f.node.data[off:], p)
+	f.node.modTime = time.Now()
+	return len(p)
+}
+
+func (f *memFile) Seek(offset int64, whence int) (int64, error) {
+	if err := f.check("seek", true); err != nil {
+		return 0, err
+	}
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		f.node.mu.Lock()
+		offset += int64(len(f.node.data))
+		f.node.mu.Unlock()
+	}
+	if offset < 0 {
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: errNegative}
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *memFile) Truncate(size int64) error {
+	if err := f.check("truncate", f.writable()); err != nil {
+		return err
+	}
+	if size < 0 {
+		return &fs.PathError{Op: "truncate", Path: f.name, Err: errNegative}
+	}
+	f.node.mu.Lock()
+	defer f.node.mu.Unlock()
+	if size <= int64(len(f.node.data)) {
+		f.node.data = f.node.data[:size]
+	} else {
+		f.node.data = append(f.node.data, make([]byte, size-int64(len(f.node.data)))...)
+	}
+	f.node.modTime = time.Now()
+	return nil
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) {
+	if err := f.check("stat", true); err != nil {
+		return nil, err
+	}
+	return f.node.info(filepath.Base(f.name)), nil
+}
+
+func (f *memFile) Sync() error {
+	return f.check("sync", true)
+}
+
+func (f *memFile) Close() error {
+	if err := f.check("close", true); err != nil {
+		return err
+	}
+	f.closed = true
+	return nil
+}
+
+func (f *memFile) writable() bool {
+	return f.flag&(os.O_WRONLY|os.O_RDWR) != 0
+}
+
+func (f *memFile) check(op string, allowed bool) error {
+	if f.closed {
+		return &fs.PathError{Op: op, Path: f.name, Err: fs.ErrClosed}
+	}
+	if !allowed {
+		return &fs.PathError{Op: op, Path: f.name, Err: errAccess}
+	}
+	return nil
+}
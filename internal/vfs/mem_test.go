@@ -0,0 +1,235 @@
+package vfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var _ FS = (*Mem)(nil)
+
+func mustWrite(t *testing.T, fsys FS, name, content string) {
+	t.Helper()
+	if err := WriteFile(fsys, name, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func mustRead(t *testing.T, fsys FS, name string) string {
+	t.Helper()
+	data, err := ReadFile(fsys, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestMemReadWrite(t *testing.T) {
+	m := NewMem()
+	name := filepath.FromSlash("/music/a.mp3")
+	mustWrite(t, m, name, "hello world")
+
+	file, err := m.OpenFile(name, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 5)
+	if n, err := file.ReadAt(buf, 6); n != 5 || err != nil || string(buf) != "world" {
+		t.Errorf("ReadAt = %d, %v, %q", n, err, buf)
+	}
+	if n, err := file.ReadAt(buf, 8); n != 3 || err != io.EOF {
+		t.Errorf("ReadAt past the end = %d, %v, want 3, EOF", n, err)
+	}
+	if _, err := file.WriteAt([]byte("WORLD!"), 6); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.Seek(-6, io.SeekEnd); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.Write([]byte("there!!")); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Truncate(11); err != nil {
+		t.Fatal(err)
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Size() != 11 || stat.Name() != "a.mp3" || stat.Mode().Perm() != 0o644 {
+		t.Errorf("stat = %d bytes, %q, %v", stat.Size(), stat.Name(), stat.Mode())
+	}
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Close(); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("second Close = %v, want ErrClosed", err)
+	}
+	if got := mustRead(t, m, name); got != "hello there" {
+		t.Errorf("content = %q", got)
+	}
+
+	if err := WriteFile(m, name, []byte("short"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := mustRead(t, m, name); got != "short" {
+		t.Errorf("content after truncating write = %q", got)
+	}
+}
+
+func TestMemAccessModes(t *testing.T) {
+	m := NewMem()
+	mustWrite(t, m, "/a", "data")
+
+	file, err := m.Open("/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.Write([]byte("x")); err == nil {
+		t.Error("wrote to a file opened for reading")
+	}
+	file.Close()
+
+	if _, err := m.OpenFile("/a", os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o644); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("exclusive create of an existing file = %v, want ErrExist", err)
+	}
+	if _, err := m.Open("/missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Open of a missing file = %v, want ErrNotExist", err)
+	}
+
+	if err := m.Chmod("/a", 0o444); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.OpenFile("/a", os.O_WRONLY, 0); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("opening a read-only file for writing = %v, want ErrPermission", err)
+	}
+	if _, err := m.Open("/a"); err != nil {
+		t.Errorf("opening a read-only file for reading = %v", err)
+	}
+}
+
+func TestMemDirectories(t *testing.T) {
+	m := NewMem()
+	mustWrite(t, m, "/music/b.flac", "b")
+	mustWrite(t, m, "/music/a.mp3", "a")
+	mustWrite(t, m, "/music/album/c.ogg", "c")
+
+	for _, dir := range []string{"/", "/music", "/music/album", m.TempDir()} {
+		stat, err := m.Stat(dir)
+		if err != nil || !stat.IsDir() {
+			t.Errorf("Stat(%q) = %v, %v, want a directory", dir, stat, err)
+		}
+	}
+	if _, err := m.Stat("/other"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Stat of a missing directory = %v, want ErrNotExist", err)
+	}
+
+	entries, err := m.ReadDir("/music")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			name += "/"
+		}
+		names = append(names, name)
+	}
+	if got := strings.Join(names, " "); got != "a.mp3 album/ b.flac" {
+		t.Errorf("ReadDir = %s", got)
+	}
+
+	if err := m.Remove("/music/album"); err == nil {
+		t.Error("removed a directory that is not empty")
+	}
+	if _, err := m.Open("/music"); err == nil {
+		t.Error("opened a directory as a file")
+	}
+}
+
+func TestMemRename(t *testing.T) {
+	m := NewMem()
+	mustWrite(t, m, "/a", "new")
+	mustWrite(t, m, "/b", "old")
+
+	open, err := m.Open("/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer open.Close()
+
+	if err := m.Rename("/a", "/b"); err != nil {
+		t.Fatal(err)
+	}
+	if got := mustRead(t, m, "/b"); got != "new" {
+		t.Errorf("renamed content = %q", got)
+	}
+	if _, err := m.Stat("/a"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("old path still exists: %v", err)
+	}
+	// An open file keeps the content it was opened with.
+	if data, err := io.ReadAll(open); err != nil || string(data) != "old" {
+		t.Errorf("replaced open file = %q, %v, want old", data, err)
+	}
+
+	if err := m.Rename("/missing", "/c"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Rename of a missing file = %v, want ErrNotExist", err)
+	}
+	if err := m.Remove("/b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Remove("/b"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("second Remove = %v, want ErrNotExist", err)
+	}
+}
+
+func TestMemCreateTemp(t *testing.T) {
+	m := NewMem()
+	first, err := m.CreateTemp("/music", ".song.*.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := m.CreateTemp("", "upload-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dir, base := filepath.Split(first.Name()); dir != filepath.FromSlash("/music/") ||
+		!strings.HasPrefix(base, ".song.") || !strings.HasSuffix(base, ".mp3") {
+		t.Errorf("temp file name = %q", first.Name())
+	}
+	if filepath.Dir(second.Name()) != m.TempDir() {
+		t.Errorf("temp file %q not in %q", second.Name(), m.TempDir())
+	}
+	stat, err := first.Stat()
+	if err != nil || stat.Mode().Perm() != 0o600 {
+		t.Errorf("temp file mode = %v, %v, want 0600", stat, err)
+	}
+	if _, err := m.CreateTemp("/music", "a/b*"); err == nil {
+		t.Error("created a temp file with a separator in its pattern")
+	}
+}
+
+func TestMemChtimes(t *testing.T) {
+	m := NewMem()
+	mustWrite(t, m, "/a", "data")
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := m.Chtimes("/a", mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	stat, err := m.Stat("/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stat.ModTime().Equal(mtime) {
+		t.Errorf("mtime = %v, want %v", stat.ModTime(), mtime)
+	}
+	if err := m.Chtimes("/missing", mtime, mtime); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Chtimes of a missing file = %v, want ErrNotExist", err)
+	}
+}
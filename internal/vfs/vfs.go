@@ -0,0 +1,121 @@
+// Package vfs is the file system the audio service and the handlers work on.
+package vfs
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// File is an open file. *os.File implements it.
+type File interface {
+	io.Reader
+	io.ReaderAt
+	io.Writer
+	io.WriterAt
+	io.Seeker
+	io.Closer
+	Name() string
+	Stat() (fs.FileInfo, error)
+	Sync() error
+	Truncate(size int64) error
+}
+
+// FS has the methods of the os package that the app uses, with the same meaning.
+type FS interface {
+	Open(name string) (File, error)
+	OpenFile(name string, flag int, perm fs.FileMode) (File, error)
+	CreateTemp(dir, pattern string) (File, error)
+	Stat(name string) (fs.FileInfo, error)
+	ReadDir(name string) ([]fs.DirEntry, error)
+	Rename(oldPath, newPath string) error
+	Remove(name string) error
+	Chmod(name string, mode fs.FileMode) error
+	Chtimes(name string, atime, mtime time.Time) error
+	TempDir() string
+}
+
+// Create creates or truncates the named file for reading and writing, like os.Create.
+func Create(fsys FS, name string) (File, error) {
+	return fsys.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o666)
+}
+
+// ReadFile returns the content of the named file, like os.ReadFile.
+func ReadFile(fsys FS, name string) ([]byte, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+// WriteFile writes data to the named file, creating it with perm if necessary, like os.WriteFile.
+func WriteFile(fsys FS, name string, data []byte, perm fs.FileMode) error {
+	file, err := fsys.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// OS is the local file system.
+type OS struct{}
+
+// IsOS reports whether fsys is the local file system.
+func IsOS(fsys FS) bool {
+	_, ok := fsys.(OS)
+	return ok
+}
+
+func (OS) Open(name string) (File, error) {
+	return wrap(os.Open(name))
+}
+
+func (OS) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	return wrap(os.OpenFile(name, flag, perm))
+}
+
+func (OS) CreateTemp(dir, pattern string) (File, error) {
+	return wrap(os.CreateTemp(dir, pattern))
+}
+
+func (OS) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (OS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return os.ReadDir(name)
+}
+
+func (OS) Rename(oldPath, newPath string) error {
+	return os.Rename(oldPath, newPath)
+}
+
+func (OS) Remove(name string) error {
+	return os.Remove(name)
+}
+
+func (OS) Chmod(name string, mode fs.FileMode) error {
+	return os.Chmod(name, mode)
+}
+
+func (OS) Chtimes(name string, atime, mtime time.Time) error {
+	return os.Chtimes(name, atime, mtime)
+}
+
+func (OS) TempDir() string {
+	return os.TempDir()
+}
+
+func wrap(file *os.File, err error) (File, error) {
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
@@ -0,0 +1,19 @@
+// Package web embeds the frontend bundle into the binary.
+package web
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed all:dist
+var dist embed.FS
+
+// Dist returns the embedded bundle, rooted at its index.html.
+func Dist() fs.FS {
+	bundle, err := fs.Sub(dist, "dist")
+	if err != nil {
+		panic(err)
+	}
+	return bundle
+}
@@ -1,9 +1,20 @@
 package logs
 
 import (
+	"context"
 	"log/slog"
 )
 
 func Error(message string, err error, attr ...slog.Attr) {
-	slog.Error(message, slog.String("err", err.Error()), attr)
+	ErrorContext(context.Background(), message, err, attr...)
+}
+
+// ErrorContext is Error for code that serves a request, whose ID it logs.
+func ErrorContext(ctx context.Context, message string, err error, attr ...slog.Attr) {
+	args := make([]any, 0, len(attr)+1)
+	args = append(args, slog.String("err", err.Error()))
+	for _, a := range attr {
+		args = append(args, a)
+	}
+	slog.ErrorContext(ctx, message, args...)
 }
@@ -40,7 +40,7 @@ func NewSlogLogger(mode string, prodWriter io.Writer) (*slog.Logger, error) {
 	default:
 		return nil, errors.New("invalid logging mode")
 	}
-	return slog.New(th), nil
+	return slog.New(contextHandler{th}), nil
 }
 
 func replaceAttr(level slog.Level) func(groups []string, a slog.Attr) slog.Attr {
@@ -0,0 +1,250 @@
+// Package tagedit reads and writes the tags of MP3, FLAC, Ogg Vorbis, Opus and WAV files.
+package tagedit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/iamvkosarev/audio-tag-editor/internal/model"
+	"github.com/iamvkosarev/audio-tag-editor/internal/service/audio"
+)
+
+type (
+	// Metadata is what Open reports about a file: its tags, embedded pictures and audio properties.
+	Metadata = model.FileMetadata
+	// Changes lists the tags to write.
+	Changes       = model.TagUpdate
+	Picture       = model.Picture
+	PictureUpdate = model.PictureUpdate
+	CoverArtInfo  = model.CoverArtInfo
+	// Capabilities says which fields can be written to a format.
+	Capabilities = model.Capabilities
+	// ValidationError lists every invalid field of Changes.
+	ValidationError = model.ValidationError
+	FieldError      = model.FieldError
+	// ID3v1Mode controls what happens to the ID3v1 tag of MP3 files.
+	ID3v1Mode = audio.ID3v1Mode
+	// FLACID3Mode controls what happens to an ID3v2 tag in front of a FLAC stream.
+	FLACID3Mode = audio.FLACID3Mode
+	// MergePolicy decides what Changes do with the fields they leave unset.
+	MergePolicy = model.MergePolicy
+)
+
+const (
+	ID3v1Keep  = audio.ID3v1Keep
+	ID3v1Strip = audio.ID3v1Strip
+	ID3v1Sync  = audio.ID3v1Sync
+
+	FLACID3Keep  = audio.FLACID3Keep
+	FLACID3Strip = audio.FLACID3Strip
+	FLACID3Sync  = audio.FLACID3Sync
+
+	MergePreserve         = model.MergePreserve
+	MergeClear            = model.MergeClear
+	MergeOverwriteIfEmpty = model.MergeOverwriteIfEmpty
+)
+
+// Picture types shared by ID3v2 APIC frames and FLAC PICTURE blocks.
+const (
+	PictureTypeOther      = model.PictureTypeOther
+	PictureTypeFrontCover = model.PictureTypeFrontCover
+	PictureTypeBackCover  = model.PictureTypeBackCover
+	PictureTypeArtist     = model.PictureTypeArtist
+)
+
+var (
+	ErrUnsupportedFormat = model.ErrUnsupportedFormat
+	ErrInvalidTag        = model.ErrInvalidTag
+	ErrInvalidCoverArt   = model.ErrInvalidCoverArt
+	ErrNoCoverArt        = model.ErrNoCoverArt
+)
+
+const (
+	defaultCoverFetchTimeout = 15 * time.Second
+	defaultCoverMaxSize      = 10 << 20
+	defaultFLACPadding       = 8 << 10
+)
+
+// Options configure an Editor. The zero value is usable.
+type Options struct {
+	ID3Version int       // ID3v2 version written to MP3 files, 3 or 4; 0 keeps the file's version
+	ID3v1      ID3v1Mode // defaults to ID3v1Keep
+
+	// FLACPadding is the space in bytes reserved when a FLAC file is rewritten.
+	FLACPadding int
+	FLACID3     FLACID3Mode // defaults to FLACID3Keep
+
+	// LegacyEncodings are the code pages mojibake in MP3 and WAV tags is decoded from.
+	LegacyEncodings []string
+
+	// ExactMP3Duration counts every frame of VBR files without a Xing header.
+	ExactMP3Duration bool
+
+	// Cover art and pictures may be given as http(s) URLs, which are downloaded before writing.
+	CoverFetchTimeout      time.Duration // defaults to 15s
+	CoverMaxSize           int64         // bytes, defaults to 10 MiB
+	CoverAllowPrivateHosts bool          // allow URLs on local networks
+
+	// CustomTagsAllow lists the custom tag names Write accepts, "PREFIX_*" allowed; empty allows all.
+	CustomTagsAllow []string
+	CustomTagsDeny  []string
+}
+
+type Editor struct {
+	service *audio.AudioService
+}
+
+func New(opts Options) (*Editor, error) {
+	id3Options, err := audio.NewID3Options(opts.ID3Version, string(opts.ID3v1))
+	if err != nil {
+		return nil, err
+	}
+	flacID3, err := audio.ParseFLACID3Mode(string(opts.FLACID3))
+	if err != nil {
+		return nil, err
+	}
+	textEncodings, err := audio.ParseTextEncodings(opts.LegacyEncodings)
+	if err != nil {
+		return nil, err
+	}
+	if opts.CoverFetchTimeout <= 0 {
+		opts.CoverFetchTimeout = defaultCoverFetchTimeout
+	}
+	if opts.CoverMaxSize <= 0 {
+		opts.CoverMaxSize = defaultCoverMaxSize
+	}
+	if opts.FLACPadding == 0 {
+		opts.FLACPadding = defaultFLACPadding
+	}
+	return &Editor{
+		service: audio.NewAudioService(
+			audio.Options{
+				CoverFetchTimeout:      opts.CoverFetchTimeout,
+				CoverMaxSize:           opts.CoverMaxSize,
+				CoverAllowPrivateHosts: opts.CoverAllowPrivateHosts,
+				CustomTagsAllow:        opts.CustomTagsAllow,
+				CustomTagsDeny:         opts.CustomTagsDeny,
+				ID3:                    id3Options,
+				MP3ExactDuration:       opts.ExactMP3Duration,
+				FLACPadding:            opts.FLACPadding,
+				FLACID3:                flacID3,
+				TextEncodings:          textEncodings,
+			},
+		),
+	}, nil
+}
+
+var defaultEditor, _ = New(Options{})
+
+// Open reads the tags and audio properties of a file with the default options.
+func Open(ctx context.Context, path string) (*Metadata, error) {
+	return defaultEditor.Open(ctx, path)
+}
+
+// Write applies changes to a file in place with the default options.
+func Write(ctx context.Context, path string, changes Changes) error {
+	return defaultEditor.Write(ctx, path, changes)
+}
+
+// Supported reports whether files with the extension of name can be read and written.
+func Supported(name string) bool {
+	return audio.SupportedExtension(filepath.Ext(name))
+}
+
+// Open reads the tags and audio properties of a file.
+func (e *Editor) Open(ctx context.Context, path string) (*Metadata, error) {
+	return e.service.ParseFile(ctx, path)
+}
+
+// Read is Open for audio that is not on disk.
+func (e *Editor) Read(ctx context.Context, r io.ReaderAt, size int64, name string) (*Metadata, error) {
+	return e.service.ParseReader(ctx, r, size, name)
+}
+
+// Write applies changes to a file in place.
+func (e *Editor) Write(ctx context.Context, path string, changes Changes) error {
+	if err := e.resolvePictures(ctx, &changes); err != nil {
+		return err
+	}
+	return e.service.UpdateTags(ctx, path, &changes)
+}
+
+// WriteStream copies the audio read from r to w with changes applied.
+func (e *Editor) WriteStream(ctx context.Context, r io.Reader, name string, changes Changes, w io.Writer) error {
+	temp, err := os.CreateTemp("", "tagedit-*"+filepath.Ext(name))
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(temp.Name())
+	defer temp.Close()
+
+	if _, err := io.Copy(temp, r); err != nil {
+		return fmt.Errorf("failed to buffer audio: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("failed to buffer audio: %w", err)
+	}
+
+	if err := e.Write(ctx, temp.Name(), changes); err != nil {
+		return err
+	}
+
+	result, err := os.Open(temp.Name())
+	if err != nil {
+		return fmt.Errorf("failed to open written audio: %w", err)
+	}
+	defer result.Close()
+	if _, err := io.Copy(w, result); err != nil {
+		return fmt.Errorf("failed to copy written audio: %w", err)
+	}
+	return nil
+}
+
+// Cover returns the front cover of a file, or its first picture.
+func (e *Editor) Cover(ctx context.Context, path string) ([]byte, string, error) {
+	return e.service.ExtractCoverArt(ctx, path)
+}
+
+// Picture returns the first picture of the given type and its MIME type.
+func (e *Editor) Picture(ctx context.Context, path string, pictureType int) ([]byte, string, error) {
+	return e.service.ExtractPicture(ctx, path, pictureType)
+}
+
+// ResolveImage turns an http(s) image URL into a data URI by downloading it.
+func (e *Editor) ResolveImage(ctx context.Context, image string) (string, error) {
+	return e.service.ResolveCoverArt(ctx, image)
+}
+
+// Validate checks changes without writing them.
+func (e *Editor) Validate(changes Changes) error {
+	return e.service.ValidateTagUpdate(&changes)
+}
+
+func (e *Editor) resolvePictures(ctx context.Context, changes *Changes) error {
+	if changes.CoverArt != nil {
+		coverArt, err := e.ResolveImage(ctx, *changes.CoverArt)
+		if err != nil {
+			return &FieldError{Field: "coverArt", Err: err}
+		}
+		changes.CoverArt = &coverArt
+	}
+	if len(changes.Pictures) == 0 {
+		return nil
+	}
+
+	pictures := make([]PictureUpdate, len(changes.Pictures))
+	for i, picture := range changes.Pictures {
+		data, err := e.ResolveImage(ctx, picture.Data)
+		if err != nil {
+			return &FieldError{Field: "pictures", Err: err}
+		}
+		picture.Data = data
+		pictures[i] = picture
+	}
+	changes.Pictures = pictures
+	return nil
+}